@@ -0,0 +1,93 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	"github.com/butlerdotdev/butler-provider-harvester/internal/controller"
+)
+
+// Exit codes of the evacuate-node command.
+const (
+	evacuateDone         = 0
+	evacuateError        = 1
+	evacuatePending      = 2
+	evacuateInvalidFlags = 3
+)
+
+// evacuateNode runs the evacuate-node command: it starts a live migration
+// for every managed VM on the node and prints the VMs still on it. It exits
+// evacuatePending until the node is empty, so it can be rerun until then.
+func evacuateNode(args []string) int {
+	fs := flag.NewFlagSet("evacuate-node", flag.ContinueOnError)
+	var providerConfig, node string
+	var timeout time.Duration
+	fs.StringVar(&providerConfig, "provider-config", "",
+		"namespace/name of the ProviderConfig of the Harvester cluster.")
+	fs.StringVar(&node, "node", "", "The Harvester node to move the managed VMs off.")
+	fs.DurationVar(&timeout, "timeout", time.Minute, "How long to wait for the Harvester API.")
+	if err := fs.Parse(args); err != nil {
+		return evacuateInvalidFlags
+	}
+	ns, name, ok := strings.Cut(providerConfig, "/")
+	if !ok || ns == "" || name == "" || node == "" {
+		fmt.Fprintln(os.Stderr, "evacuate-node requires --provider-config=namespace/name and --node")
+		return evacuateInvalidFlags
+	}
+
+	ctrl.SetLogger(zap.New())
+	config, err := ctrl.GetConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to load the cluster config: %v\n", err)
+		return evacuateError
+	}
+	c, err := client.New(config, client.Options{Scheme: scheme})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to create client: %v\n", err)
+		return evacuateError
+	}
+
+	ctx, cancel := context.WithTimeout(ctrl.SetupSignalHandler(), timeout)
+	defer cancel()
+	evacuation, err := controller.EvacuateNode(ctx, c, types.NamespacedName{Namespace: ns, Name: name}, node)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to evacuate node %s: %v\n", node, err)
+		return evacuateError
+	}
+	for _, vm := range evacuation.Migrating {
+		fmt.Printf("%s: migrating\n", vm)
+	}
+	for _, failure := range evacuation.Failed {
+		fmt.Printf("%s: not migrated: %s\n", failure.VM, failure.Reason)
+	}
+	if len(evacuation.Migrating) > 0 || len(evacuation.Failed) > 0 {
+		return evacuatePending
+	}
+	fmt.Printf("node %s has no managed VMs\n", node)
+	return evacuateDone
+}
@@ -17,15 +17,22 @@ limitations under the License.
 package main
 
 import (
+	"crypto/sha256"
 	"crypto/tls"
+	"encoding/hex"
 	"flag"
 	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -37,7 +44,9 @@ import (
 
 	butlerv1alpha1 "github.com/butlerdotdev/butler-api/api/v1alpha1"
 	"github.com/butlerdotdev/butler-provider-harvester/internal/controller"
+	"github.com/butlerdotdev/butler-provider-harvester/internal/drain"
 	"github.com/butlerdotdev/butler-provider-harvester/internal/imagesync"
+	"github.com/butlerdotdev/butler-provider-harvester/internal/provisioning"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -55,6 +64,10 @@ func init() {
 
 // nolint:gocyclo
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "evacuate-node" {
+		os.Exit(evacuateNode(os.Args[2:]))
+	}
+
 	var metricsAddr string
 	var metricsCertPath, metricsCertName, metricsCertKey string
 	var webhookCertPath, webhookCertName, webhookCertKey string
@@ -62,6 +75,12 @@ func main() {
 	var probeAddr string
 	var secureMetrics bool
 	var enableHTTP2 bool
+	var drainConfigMap string
+	var providerConfigGracePeriod time.Duration
+	var requeueShort, requeueLong time.Duration
+	var provisioningAddr, provisioningCertPath, provisioningCertName, provisioningCertKey string
+	var conditionVocabulary string
+	var watchNamespaces string
 	var tlsOpts []func(*tls.Config)
 	flag.StringVar(&metricsAddr, "metrics-bind-address", "0", "The address the metrics endpoint binds to. "+
 		"Use :8443 for HTTPS or :8080 for HTTP, or leave as 0 to disable the metrics service.")
@@ -80,6 +99,29 @@ func main() {
 	flag.StringVar(&metricsCertKey, "metrics-cert-key", "tls.key", "The name of the metrics server key file.")
 	flag.BoolVar(&enableHTTP2, "enable-http2", false,
 		"If set, HTTP/2 will be enabled for the metrics and webhook servers")
+	flag.StringVar(&drainConfigMap, "drain-configmap", "",
+		"Optional namespace/name of a ConfigMap whose \"drain\" key set to \"true\" stops new VM create/delete operations.")
+	flag.DurationVar(&providerConfigGracePeriod, "provider-config-grace-period", 10*time.Minute,
+		"How long a deleting MachineRequest waits for its missing ProviderConfig before the finalizer is removed, "+
+			"possibly orphaning the VM.")
+	flag.DurationVar(&requeueShort, "requeue-short", 10*time.Second,
+		"How soon a MachineRequest waiting on Harvester, e.g. for a VM to get an IP, is reconciled again.")
+	flag.DurationVar(&requeueLong, "requeue-long", 30*time.Second,
+		"How often a running MachineRequest is re-checked. Both requeue intervals get up to 10% random jitter.")
+	flag.StringVar(&provisioningAddr, "provisioning-bind-address", "",
+		"Optional address for the synchronous provisioning endpoint, which authorizes callers by their "+
+			"bearer token. Empty disables it. Without --provisioning-cert-path it must be a loopback address.")
+	flag.StringVar(&provisioningCertPath, "provisioning-cert-path", "",
+		"The directory that contains the provisioning endpoint certificate. Empty serves plain HTTP.")
+	flag.StringVar(&provisioningCertName, "provisioning-cert-name", "tls.crt",
+		"The name of the provisioning endpoint certificate file.")
+	flag.StringVar(&provisioningCertKey, "provisioning-cert-key", "tls.key",
+		"The name of the provisioning endpoint key file.")
+	flag.StringVar(&conditionVocabulary, "condition-vocabulary", "",
+		"Optional YAML file renaming the MachineRequest condition types and reasons the controller writes.")
+	flag.StringVar(&watchNamespaces, "watch-namespaces", "",
+		"Optional comma-separated namespaces whose MachineRequests this instance reconciles. Empty reconciles all "+
+			"namespaces. Instances with different namespaces use separate leader election locks.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -88,6 +130,17 @@ func main() {
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
+	var namespaces []string
+	if watchNamespaces != "" {
+		for _, ns := range strings.Split(watchNamespaces, ",") {
+			namespaces = append(namespaces, strings.TrimSpace(ns))
+		}
+		if err := controller.ValidateWatchNamespaces(namespaces); err != nil {
+			setupLog.Error(err, "invalid --watch-namespaces")
+			os.Exit(1)
+		}
+	}
+
 	// if the enable-http2 flag is false (the default), http/2 should be disabled
 	// due to its vulnerabilities. More specifically, disabling http/2 will
 	// prevent from being vulnerable to the HTTP/2 Stream Cancellation and
@@ -161,7 +214,8 @@ func main() {
 		WebhookServer:          webhookServer,
 		HealthProbeBindAddress: probeAddr,
 		LeaderElection:         enableLeaderElection,
-		LeaderElectionID:       "20ec1c36.butlerlabs.dev",
+		LeaderElectionID:       leaderElectionID(namespaces),
+		Cache:                  controller.CacheOptions(namespaces),
 		// LeaderElectionReleaseOnCancel defines if the leader should step down voluntarily
 		// when the Manager ends. This requires the binary to immediately end when the
 		// Manager is stopped, otherwise, this setting is unsafe. Setting this significantly
@@ -179,18 +233,61 @@ func main() {
 		os.Exit(1)
 	}
 
+	drainSwitch := &drain.Switch{Reader: mgr.GetAPIReader()}
+	if drainConfigMap != "" {
+		ns, name, ok := strings.Cut(drainConfigMap, "/")
+		if !ok || ns == "" || name == "" {
+			setupLog.Error(nil, "invalid --drain-configmap, expected namespace/name", "value", drainConfigMap)
+			os.Exit(1)
+		}
+		drainSwitch.ConfigMap = types.NamespacedName{Namespace: ns, Name: name}
+	}
+	if err := mgr.Add(drainSwitch); err != nil {
+		setupLog.Error(err, "unable to set up drain switch")
+		os.Exit(1)
+	}
+
+	if provisioningAddr != "" {
+		server := &provisioning.Server{Client: mgr.GetClient(), Addr: provisioningAddr}
+		if provisioningCertPath != "" {
+			server.CertFile = filepath.Join(provisioningCertPath, provisioningCertName)
+			server.KeyFile = filepath.Join(provisioningCertPath, provisioningCertKey)
+		}
+		if err := mgr.Add(server); err != nil {
+			setupLog.Error(err, "unable to set up provisioning endpoint")
+			os.Exit(1)
+		}
+	}
+
+	var conditions *controller.ConditionVocabulary
+	if conditionVocabulary != "" {
+		if conditions, err = controller.LoadConditionVocabulary(conditionVocabulary); err != nil {
+			setupLog.Error(err, "unable to load condition vocabulary")
+			os.Exit(1)
+		}
+	}
+
 	if err := (&controller.MachineRequestReconciler{
-		Client:   mgr.GetClient(),
-		Scheme:   mgr.GetScheme(),
-		Recorder: mgr.GetEventRecorderFor("harvester-provider"),
+		Client:    mgr.GetClient(),
+		Scheme:    mgr.GetScheme(),
+		Recorder:  mgr.GetEventRecorderFor("harvester-provider"),
+		Drain:     drainSwitch,
+		APIReader: mgr.GetAPIReader(),
+
+		ProviderConfigGracePeriod: providerConfigGracePeriod,
+		RequeueShort:              requeueShort,
+		RequeueLong:               requeueLong,
+		Conditions:                conditions,
+		WatchNamespaces:           namespaces,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "MachineRequest")
 		os.Exit(1)
 	}
 	if err := (&imagesync.Reconciler{
-		Client:   mgr.GetClient(),
-		Scheme:   mgr.GetScheme(),
-		Recorder: mgr.GetEventRecorderFor("harvester-provider"),
+		Client:    mgr.GetClient(),
+		Scheme:    mgr.GetScheme(),
+		Recorder:  mgr.GetEventRecorderFor("harvester-provider"),
+		APIReader: mgr.GetAPIReader(),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "ImageSync")
 		os.Exit(1)
@@ -212,3 +309,15 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// leaderElectionID returns the leader election lock name. Instances sharded
+// by namespace each get their own lock so they can all run at once.
+func leaderElectionID(namespaces []string) string {
+	const id = "20ec1c36.butlerlabs.dev"
+	if len(namespaces) == 0 {
+		return id
+	}
+	sorted := slices.Sorted(slices.Values(namespaces))
+	sum := sha256.Sum256([]byte(strings.Join(sorted, ",")))
+	return hex.EncodeToString(sum[:4]) + "-" + id
+}
@@ -0,0 +1,427 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+// MachineRequest annotations that tune how the Harvester VM is built.
+// MachineRequestSpec is provider-agnostic, so Harvester-specific options are
+// expressed as annotations on the MachineRequest.
+const (
+	annotationPrefix = "harvester.butler.butlerlabs.dev/"
+
+	// AnnotationDedicatedCPUPlacement pins each vCPU to a dedicated host CPU ("true"/"false").
+	AnnotationDedicatedCPUPlacement = annotationPrefix + "dedicated-cpu-placement"
+	// AnnotationIsolateEmulatorThread pins the QEMU emulator thread to its own
+	// host CPU ("true"/"false"). Requires dedicated CPU placement.
+	AnnotationIsolateEmulatorThread = annotationPrefix + "isolate-emulator-thread"
+	// AnnotationCPUSockets and AnnotationCPUThreads shape the guest CPU
+	// topology; spec.cpu must be a multiple of sockets * threads (default 1).
+	AnnotationCPUSockets = annotationPrefix + "cpu-sockets"
+	AnnotationCPUThreads = annotationPrefix + "cpu-threads"
+	// AnnotationGPUs lists GPU devices to pass through, comma-separated,
+	// each "<deviceName>[:<name>]" (e.g. "nvidia.com/GP104_GEFORCE").
+	AnnotationGPUs = annotationPrefix + "gpus"
+	// AnnotationHugepagesPageSize backs guest memory with hugepages of the
+	// given size ("2Mi" or "1Gi").
+	AnnotationHugepagesPageSize = annotationPrefix + "hugepages-page-size"
+	// AnnotationNUMACells describes the guest NUMA cells as a comma-separated
+	// list of "<cpus>:<memoryMB>" entries, one per cell.
+	AnnotationNUMACells = annotationPrefix + "numa-cells"
+	// AnnotationMemoryOvercommit requests less memory than the guest sees
+	// and skips the virt-launcher overhead ("true"/"false").
+	AnnotationMemoryOvercommit = annotationPrefix + "memory-overcommit"
+	// AnnotationMemoryRequestPercent is the share of guest memory requested
+	// for overcommitted VMs (1-100, default 50).
+	AnnotationMemoryRequestPercent = annotationPrefix + "memory-request-percent"
+	// AnnotationMemoryOverheadMB is the memory in MiB added on top of guest
+	// memory for the VM memory limit (default 2% of the guest memory).
+	AnnotationMemoryOverheadMB = annotationPrefix + "memory-overhead-mb"
+	// AnnotationMemoryRequestMB is the VM memory request in MiB, at most the
+	// guest memory. It replaces the request derived from the guest memory.
+	AnnotationMemoryRequestMB = annotationPrefix + "memory-request-mb"
+	// AnnotationCPURequestMillicores is the VM CPU request in millicores, at
+	// most spec.cpu cores (default 125).
+	AnnotationCPURequestMillicores = annotationPrefix + "cpu-request-millicores"
+	// AnnotationMemoryLimitMB is the VM memory limit in MiB, at least the
+	// guest memory. It replaces the guest memory plus overhead.
+	AnnotationMemoryLimitMB = annotationPrefix + "memory-limit-mb"
+	// AnnotationStartPaused creates the VM with a paused guest ("true"/"false").
+	AnnotationStartPaused = annotationPrefix + "start-paused"
+	// AnnotationUnpause requests that a paused VM be resumed. The controller
+	// removes the annotation once the VM has been unpaused.
+	AnnotationUnpause = annotationPrefix + "unpause"
+	// AnnotationPowerState is the desired power state of the VM, "Running"
+	// (default) or "Stopped". A stopped VM keeps its disks.
+	AnnotationPowerState = annotationPrefix + "power-state"
+	// AnnotationRecreate requests that the VM and its root disk be deleted and
+	// recreated from the image, e.g. to apply changed user data or recover a
+	// Failed machine. The controller removes the annotation once the old VM
+	// has been deleted.
+	AnnotationRecreate = annotationPrefix + "recreate"
+	// AnnotationRetry returns a Failed MachineRequest to Pending without
+	// deleting its VM, e.g. after a transient ProviderConfig error. The
+	// controller removes the annotation once the failure has been cleared.
+	AnnotationRetry = annotationPrefix + "retry"
+	// AnnotationMigrate requests a live migration of a running VM to another
+	// node. The controller removes the annotation once the migration starts.
+	AnnotationMigrate = annotationPrefix + "migrate"
+	// AnnotationMigration is written by the controller with the name of the
+	// live migration in progress, and removed once it finishes.
+	AnnotationMigration = annotationPrefix + "migration"
+	// AnnotationNode is written by the controller with the Harvester node
+	// the VM runs on, and removed while the VM is not running.
+	AnnotationNode = annotationPrefix + "node"
+	// AnnotationGuestHostname and AnnotationGuestOS are written by the
+	// controller with the hostname and operating system the guest agent
+	// last reported.
+	AnnotationGuestHostname = annotationPrefix + "guest-hostname"
+	AnnotationGuestOS       = annotationPrefix + "guest-os"
+	// AnnotationSnapshot requests a snapshot of the VM's disks named
+	// "<machineName>-snapshot-<value>", or a timestamp when empty. The
+	// controller removes the annotation once the snapshot starts.
+	AnnotationSnapshot = annotationPrefix + "snapshot"
+	// AnnotationSnapshotInProgress is written by the controller with the
+	// name of the snapshot being taken, and removed once it finishes.
+	AnnotationSnapshotInProgress = annotationPrefix + "snapshot-in-progress"
+	// AnnotationRestoreSnapshot requests that the disks of a stopped VM be
+	// restored from its snapshot with the given suffix. The controller
+	// removes the annotation once the restore starts.
+	AnnotationRestoreSnapshot = annotationPrefix + "restore-snapshot"
+	// AnnotationSnapshotRestore is written by the controller with the name
+	// of the snapshot restore in progress, and removed once it finishes.
+	AnnotationSnapshotRestore = annotationPrefix + "snapshot-restore"
+	// AnnotationSnapshotSince is written by the controller with the time the
+	// snapshot or restore in progress started, and removed with it.
+	AnnotationSnapshotSince = annotationPrefix + "snapshot-since"
+	// AnnotationDeleteSnapshots deletes the VM's snapshots together with
+	// the VM ("true"/"false", default false).
+	AnnotationDeleteSnapshots = annotationPrefix + "delete-snapshots"
+	// AnnotationRefreshStatus requests that the IP of a running VM be
+	// re-detected. The controller removes the annotation once it has refreshed.
+	AnnotationRefreshStatus = annotationPrefix + "refresh-status"
+	// AnnotationPersistentCloudInit backs the cloud-init disk with a persistent
+	// PVC instead of an ephemeral NoCloud volume ("true"/"false").
+	AnnotationPersistentCloudInit = annotationPrefix + "persistent-cloud-init"
+	// AnnotationCACerts is a PEM bundle of CA certificates installed in the
+	// guest trust store when spec.userData is empty.
+	AnnotationCACerts = annotationPrefix + "ca-certs"
+	// AnnotationCloudInitDiskSize sets the size of the persistent cloud-init
+	// seed disk as a quantity (e.g. "512Mi").
+	AnnotationCloudInitDiskSize = annotationPrefix + "cloud-init-disk-size"
+	// AnnotationVolumeMode sets the root disk PVC volume mode ("Block" or "Filesystem").
+	AnnotationVolumeMode = annotationPrefix + "volume-mode"
+	// AnnotationStorageClass sets the root disk PVC storage class instead of
+	// the image's storage class. On a ProviderConfig it sets the default for
+	// all of its MachineRequests.
+	AnnotationStorageClass = annotationPrefix + "storage-class"
+	// AnnotationDiskSize overrides spec.diskGB with a quantity (e.g. "20500Mi").
+	AnnotationDiskSize = annotationPrefix + "disk-size"
+	// AnnotationDiskSizeGranularity rounds the root disk size up to a multiple
+	// of this quantity, overriding the StorageClass granularity.
+	AnnotationDiskSizeGranularity = annotationPrefix + "disk-size-granularity"
+	// AnnotationRestoreFromBackup creates the VM by restoring a Harvester
+	// VirtualMachineBackup ("name" or "namespace/name") instead of cloning
+	// spec.image, which must be empty.
+	AnnotationRestoreFromBackup = annotationPrefix + "restore-from-backup"
+
+	// AnnotationImageSelector selects the Harvester image by label query
+	// instead of spec.image, which must be empty.
+	AnnotationImageSelector = annotationPrefix + "image-selector"
+	// AnnotationImageNamespace is the namespace searched by the image
+	// selector. Defaults to the Harvester namespace.
+	AnnotationImageNamespace = annotationPrefix + "image-namespace"
+	// AnnotationImageSelectorOrder picks the newest of several selector
+	// matches by "creationTimestamp" or by the version in the named label.
+	AnnotationImageSelectorOrder = annotationPrefix + "image-selector-order"
+	// AnnotationResolvedImage is set by the controller to the image the
+	// selector resolved to.
+	AnnotationResolvedImage = annotationPrefix + "resolved-image"
+
+	// AnnotationContainerDiskImage boots the VM from an ephemeral container
+	// disk image instead of a Harvester image.
+	AnnotationContainerDiskImage = annotationPrefix + "container-disk-image"
+	// AnnotationImagePullSecret names a docker-config Secret in the Harvester
+	// namespace used to pull the container disk image.
+	AnnotationImagePullSecret = annotationPrefix + "image-pull-secret"
+
+	// AnnotationUserDataConfigMap names a ConfigMap in the MachineRequest
+	// namespace holding the cloud-init user data, instead of spec.userData.
+	AnnotationUserDataConfigMap = annotationPrefix + "user-data-configmap"
+	// AnnotationUserDataSecret names a Secret in the MachineRequest namespace
+	// holding the cloud-init user data, instead of spec.userData.
+	AnnotationUserDataSecret = annotationPrefix + "user-data-secret"
+	// AnnotationUserDataKey is the AnnotationUserDataConfigMap or
+	// AnnotationUserDataSecret key holding the user data. Defaults to
+	// "userData".
+	AnnotationUserDataKey = annotationPrefix + "user-data-key"
+	// AnnotationUserDataValidation selects how strictly the user data is
+	// checked before the VM is created: "cloud-config" (default), "strict"
+	// or "none".
+	AnnotationUserDataValidation = annotationPrefix + "user-data-validation"
+
+	// AnnotationDryRun renders the VM and PVC manifests into
+	// AnnotationDryRunManifest instead of creating them ("true"/"false").
+	// The MachineRequest stays Pending until it is removed.
+	AnnotationDryRun = annotationPrefix + "dry-run"
+
+	// AnnotationSSHKeySecret names a Secret in the Harvester namespace whose
+	// SSH public keys are injected through the guest agent.
+	AnnotationSSHKeySecret = annotationPrefix + "ssh-key-secret"
+	// AnnotationSSHKeyUsers is a comma-separated list of guest users that
+	// receive the keys from AnnotationSSHKeySecret.
+	AnnotationSSHKeyUsers = annotationPrefix + "ssh-key-users"
+	// AnnotationSSHPublicKeys lists SSH public keys, one per line like an
+	// authorized_keys file, authorized for the default guest user through
+	// cloud-init. They are merged into spec.userData when it is set.
+	AnnotationSSHPublicKeys = annotationPrefix + "ssh-public-keys"
+
+	// AnnotationRuntimeClassName is the runtime class the virt-launcher pod
+	// must run with. It must match the KubeVirt default runtime class.
+	AnnotationRuntimeClassName = annotationPrefix + "runtime-class-name"
+
+	// AnnotationCreateStarted is set by the controller when it first tries
+	// to create the VM and removed once a create succeeds. Finding it on
+	// deletion means a create was interrupted and may have left resources
+	// behind.
+	AnnotationCreateStarted = annotationPrefix + "create-started"
+
+	// AnnotationRootDiskReplicas is set by the controller to the healthy and
+	// desired Longhorn replica counts of the root disk ("2/3"), or
+	// "unknown/3" when the replicas cannot be read.
+	AnnotationRootDiskReplicas = annotationPrefix + "root-disk-replicas"
+	// AnnotationRootDiskReplicaNodes is set by the controller to the nodes
+	// holding a root disk replica.
+	AnnotationRootDiskReplicaNodes = annotationPrefix + "root-disk-replica-nodes"
+
+	// AnnotationReplicas turns the MachineRequest into a pool of identical
+	// VMs named "<machineName>-<ordinal>" and sets the desired member count.
+	AnnotationReplicas = annotationPrefix + "replicas"
+	// AnnotationPoolSize is set by the controller to the number of pool
+	// ordinals that may have a VM, so scale-down knows what to delete.
+	AnnotationPoolSize = annotationPrefix + "pool-size"
+	// AnnotationReadyReplicas is set by the controller to the number of pool
+	// members that are ready and have an IP.
+	AnnotationReadyReplicas = annotationPrefix + "ready-replicas"
+
+	// AnnotationFirmwareUUID sets the SMBIOS system UUID of the guest, kept
+	// across recreates.
+	AnnotationFirmwareUUID = annotationPrefix + "firmware-uuid"
+	// AnnotationFirmwareSerial sets the SMBIOS system serial number of the guest.
+	AnnotationFirmwareSerial = annotationPrefix + "firmware-serial"
+	// AnnotationFirmwareEFI boots the guest with UEFI firmware ("true" or
+	// "false"). AnnotationSecureBoot also enables Secure Boot.
+	AnnotationFirmwareEFI = annotationPrefix + "firmware-efi"
+	AnnotationSecureBoot  = annotationPrefix + "secure-boot"
+	// AnnotationSMBIOSManufacturer and AnnotationSMBIOSProduct are the SMBIOS
+	// system manufacturer and product the guest must see. They must match the
+	// KubeVirt SMBIOS configuration.
+	AnnotationSMBIOSManufacturer = annotationPrefix + "smbios-manufacturer"
+	AnnotationSMBIOSProduct      = annotationPrefix + "smbios-product"
+
+	// AnnotationRootDiskPVCName overrides the root disk PVC name. A "{name}"
+	// placeholder is replaced with the machine name.
+	AnnotationRootDiskPVCName = annotationPrefix + "root-disk-pvc-name"
+	// AnnotationRootDiskSerial is the serial number exposed to the guest on
+	// the root disk.
+	AnnotationRootDiskSerial = annotationPrefix + "root-disk-serial"
+	// AnnotationDiskBus is the bus of the root, cloud-init and attached disks
+	// and the default for data disks ("virtio", "scsi" or "sata"). Defaults
+	// to "virtio".
+	AnnotationDiskBus = annotationPrefix + "disk-bus"
+	// AnnotationRootDiskPreallocation asks CDI to preallocate the root disk
+	// volume ("true" or "false"). Requires AnnotationRootDiskImportURL.
+	AnnotationRootDiskPreallocation = annotationPrefix + "root-disk-preallocation"
+	// AnnotationRootDiskShareable marks the root disk as attachable by
+	// several VMs at once ("true" or "false").
+	AnnotationRootDiskShareable = annotationPrefix + "root-disk-shareable"
+	// AnnotationRootDiskImportURL imports the root disk from an http(s) or
+	// docker:// URL through a DataVolume template embedded in the VM instead
+	// of cloning spec.image. Requires CDI.
+	AnnotationRootDiskImportURL = annotationPrefix + "root-disk-import-url"
+	// AnnotationAttachedDisks lists existing PVCs to attach as additional
+	// disks, comma-separated. A ":shareable" suffix marks a disk several VMs
+	// may attach at once.
+	AnnotationAttachedDisks = annotationPrefix + "attached-disks"
+	// AnnotationDataDisks lists blank data disks to provision with the VM,
+	// comma-separated, each "<sizeGB>[:<storageClass>[:<bus>[:<serial>]]]".
+	// When spec.extraDisks is set, the disks come from the spec and each
+	// entry only sets the bus and serial of the matching disk as
+	// "::<bus>[:<serial>]".
+	AnnotationDataDisks = annotationPrefix + "data-disks"
+	// AnnotationEphemeralScratchGB adds a node-local scratch disk of this many
+	// GiB that is wiped whenever the VM stops.
+	AnnotationEphemeralScratchGB = annotationPrefix + "ephemeral-scratch-gb"
+
+	// AnnotationNetworkName overrides the ProviderConfig network with a
+	// NetworkAttachmentDefinition reference ("name" or "namespace/name").
+	AnnotationNetworkName = annotationPrefix + "network-name"
+	// AnnotationNetworks attaches the VM to several networks instead of the
+	// network-name one. It is a comma-separated list of interfaces, each
+	// "<network> [name <name>] [binding <binding>] [mac <mac>]
+	// [address <cidr>]... [gateway <ip>] [acpi-index <n>] [pci-address <addr>]";
+	// the first is the primary interface.
+	// A network of "pod" is the pod network with masquerade binding.
+	AnnotationNetworks = annotationPrefix + "networks"
+
+	// AnnotationDNSServers is a comma-separated list of DNS server addresses
+	// written to synthesized network-data.
+	AnnotationDNSServers = annotationPrefix + "dns-servers"
+	// AnnotationDNSSearch is a comma-separated list of DNS search domains
+	// written to synthesized network-data.
+	AnnotationDNSSearch = annotationPrefix + "dns-search"
+	// AnnotationClusterDNSDomain is the cluster service domain appended to the
+	// search domains in synthesized network-data.
+	AnnotationClusterDNSDomain = annotationPrefix + "cluster-dns-domain"
+	// AnnotationRoutes is a comma-separated list of static routes written to
+	// synthesized network-data, each "<cidr> via <gateway> [metric <n>]".
+	AnnotationRoutes = annotationPrefix + "routes"
+	// AnnotationStaticIP assigns the VM a static IPv4 address in CIDR
+	// notation through synthesized network-data, for networks without DHCP.
+	// AnnotationStaticIPGateway is its default gateway; DNS servers come from
+	// AnnotationDNSServers.
+	AnnotationStaticIP        = annotationPrefix + "static-ip"
+	AnnotationStaticIPGateway = annotationPrefix + "static-ip-gateway"
+	// AnnotationNetworkDataFormat selects the synthesized network-data format
+	// ("v1" or "v2"). Defaults to "v2".
+	AnnotationNetworkDataFormat = annotationPrefix + "network-data-format"
+	// AnnotationNetworkBinding selects the VM interface binding ("bridge",
+	// "masquerade" or "sriov"). Masquerade puts the VM on the pod network.
+	// SR-IOV requires an SR-IOV network and disables live migration.
+	AnnotationNetworkBinding = annotationPrefix + "network-binding"
+	// AnnotationInterfaceACPIIndex and AnnotationInterfacePCIAddress pin the
+	// primary VM interface to an ACPI index or PCI address ("0000:02:01.0") for stable
+	// names in the guest.
+	AnnotationInterfaceACPIIndex  = annotationPrefix + "interface-acpi-index"
+	AnnotationInterfacePCIAddress = annotationPrefix + "interface-pci-address"
+
+	// AnnotationRootDiskPVC is written by the controller with the name of the
+	// root disk PVC it created.
+	AnnotationRootDiskPVC = annotationPrefix + "root-disk-pvc"
+
+	// AnnotationCreateTimeout overrides how long a VM may stay in Creating
+	// before it is marked Failed (a Go duration such as "30m"). It may be set
+	// on the ProviderConfig for a provider-wide default and on the
+	// MachineRequest to override it.
+	AnnotationCreateTimeout = annotationPrefix + "create-timeout"
+	// AnnotationGuestUnresponsiveTimeout is how long a previously connected
+	// guest agent may stay disconnected before the guest is reported
+	// unresponsive (a Go duration, default "5m"). Accepted on the
+	// MachineRequest and the ProviderConfig like AnnotationCreateTimeout.
+	AnnotationGuestUnresponsiveTimeout = annotationPrefix + "guest-unresponsive-timeout"
+	// AnnotationDeepCheckInterval is how often the deep checks of a running
+	// VM run between spec changes (a Go duration, default "5m"). Accepted on
+	// the MachineRequest and the ProviderConfig like AnnotationCreateTimeout.
+	AnnotationDeepCheckInterval = annotationPrefix + "deep-check-interval"
+	// AnnotationInPlaceResize set to "false" stops the controller applying
+	// spec.cpu and spec.memoryMB changes to existing VMs (default "true").
+	// Accepted on the MachineRequest and the ProviderConfig.
+	AnnotationInPlaceResize = annotationPrefix + "in-place-resize"
+	// AnnotationGuestAgentSeen is written by the controller once the guest
+	// agent has connected.
+	AnnotationGuestAgentSeen = annotationPrefix + "guest-agent-seen"
+	// AnnotationGuestAgentDisconnectedSince is written by the controller with
+	// the time the guest agent was first seen disconnected.
+	AnnotationGuestAgentDisconnectedSince = annotationPrefix + "guest-agent-disconnected-since"
+	// AnnotationDeletionsPerMinute is set on a ProviderConfig to pace VM
+	// deletions across all of its MachineRequests. Unset means unpaced.
+	AnnotationDeletionsPerMinute = annotationPrefix + "deletions-per-minute"
+	// AnnotationAllowIPv6 is set on a ProviderConfig to also accept global
+	// unicast IPv6 addresses as VM addresses ("true"/"false", default false).
+	AnnotationAllowIPv6 = annotationPrefix + "allow-ipv6"
+	// AnnotationMaxCloudInitSize is set on a ProviderConfig to change the
+	// limit on the base64-encoded size of inline user data and network data,
+	// as a quantity such as "4Ki" (default 2Ki).
+	AnnotationMaxCloudInitSize = annotationPrefix + "max-cloud-init-size"
+	// AnnotationMaxRetries is set on a ProviderConfig to change how often a
+	// transient Harvester API error is retried (default 4, 0 disables retries).
+	AnnotationMaxRetries = annotationPrefix + "max-retries"
+	// AnnotationRetryBaseDelay is set on a ProviderConfig to change the wait
+	// before the first retry, as a Go duration (default 250ms).
+	AnnotationRetryBaseDelay = annotationPrefix + "retry-base-delay"
+	// AnnotationCallTimeout is set on a ProviderConfig to change how long a
+	// single Harvester API call may take, as a Go duration (default 30s, 0
+	// leaves calls bounded only by the reconcile).
+	AnnotationCallTimeout = annotationPrefix + "call-timeout"
+	// AnnotationCloudInitPopulatorImage is set on a ProviderConfig to change
+	// the image of the Job that populates persistent cloud-init disks
+	// (default busybox:1.36), e.g. to pull it from a local registry.
+	AnnotationCloudInitPopulatorImage = annotationPrefix + "cloud-init-populator-image"
+
+	// AnnotationProviderConfigMissingSince is written by the controller with
+	// the time a deleting MachineRequest first found its ProviderConfig gone.
+	AnnotationProviderConfigMissingSince = annotationPrefix + "provider-config-missing-since"
+	// AnnotationCreatingSince is written by the controller with the time the
+	// VM entered Creating.
+	AnnotationCreatingSince = annotationPrefix + "creating-since"
+	// AnnotationPendingSince is written by the controller with the time the
+	// MachineRequest first entered Pending, and removed once it is Running.
+	AnnotationPendingSince = annotationPrefix + "pending-since"
+	// AnnotationCreateFailures is written by the controller with the number
+	// of consecutive failed VM creates, and removed once a create succeeds.
+	AnnotationCreateFailures = annotationPrefix + "create-failures"
+	// AnnotationLastCreateFailure is written by the controller with the time
+	// of the last failed VM create; the next attempt waits out a backoff
+	// that doubles with each failure.
+	AnnotationLastCreateFailure = annotationPrefix + "last-create-failure"
+
+	// AnnotationDryRunManifest is written by the controller with the YAML
+	// manifests a dry run rendered, cloud-init payloads redacted, and removed
+	// once the VM is created.
+	AnnotationDryRunManifest = annotationPrefix + "dry-run-manifest"
+
+	// AnnotationUserDataHash is written by the controller with a hash of the
+	// user data the VM was created with.
+	AnnotationUserDataHash = annotationPrefix + "user-data-hash"
+	// AnnotationEffectiveOptions is written by the controller with the VM
+	// options the VM was created with after defaulting and image selector
+	// resolution, as JSON with the cloud-init payloads redacted.
+	AnnotationEffectiveOptions = annotationPrefix + "effective-options"
+	// AnnotationSSHKeySecretHash is written by the controller with a hash of
+	// the AnnotationSSHKeySecret keys last confirmed in the guest.
+	AnnotationSSHKeySecretHash = annotationPrefix + "ssh-key-secret-hash"
+
+	// AnnotationImmutableFields is written by the controller with the values
+	// of the settings that cannot change once the VM exists, as JSON.
+	AnnotationImmutableFields = annotationPrefix + "immutable-fields"
+
+	// AnnotationBootSize is written by the controller with the CPU count and
+	// memory the running instance booted with, as "<cpu>:<memoryMB>:<vmiUID>".
+	AnnotationBootSize = annotationPrefix + "boot-size"
+
+	// AnnotationFootprint is written by the controller with the resource
+	// footprint of the VM for chargeback, as JSON. See footprint.
+	AnnotationFootprint = annotationPrefix + "footprint"
+
+	// AnnotationCloudInitGroup names a group of MachineRequests in the same
+	// namespace whose user data is rendered from a shared Go template with
+	// per-member .Group, .Ordinal, .Hostname and .Peers.
+	AnnotationCloudInitGroup = annotationPrefix + "cloud-init-group"
+	// AnnotationCloudInitGroupOrdinal is the member's integer position in the
+	// cloud-init group. Members are created in ordinal order.
+	AnnotationCloudInitGroupOrdinal = annotationPrefix + "cloud-init-group-ordinal"
+	// AnnotationCloudInitGroupSecret names a Secret in the MachineRequest
+	// namespace whose "userData" key holds the group template. Defaults to
+	// spec.userData.
+	AnnotationCloudInitGroupSecret = annotationPrefix + "cloud-init-group-secret"
+
+	// AnnotationTeardownGroup names a group of MachineRequests in the same
+	// namespace that are torn down in ordinal order.
+	AnnotationTeardownGroup = annotationPrefix + "teardown-group"
+	// AnnotationTeardownOrdinal is an integer position within the teardown
+	// group. Members with a higher ordinal finish deleting before members
+	// with a lower ordinal start (e.g. workers 1, control plane 0).
+	AnnotationTeardownOrdinal = annotationPrefix + "teardown-ordinal"
+)
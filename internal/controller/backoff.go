@@ -0,0 +1,106 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	butlerv1alpha1 "github.com/butlerdotdev/butler-api/api/v1alpha1"
+)
+
+const (
+	// createBackoffBase is the delay after the first failed VM create.
+	createBackoffBase = 10 * time.Second
+	// createBackoffMax caps the delay between VM create attempts.
+	createBackoffMax = 5 * time.Minute
+)
+
+// createFailures returns the number of consecutive failed VM creates recorded
+// on the MachineRequest.
+func createFailures(mr *butlerv1alpha1.MachineRequest) int {
+	n, err := strconv.Atoi(mr.Annotations[AnnotationCreateFailures])
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
+
+// createBackoff returns the delay before the next VM create after the given
+// number of consecutive failures, doubling from createBackoffBase up to
+// createBackoffMax.
+func createBackoff(failures int) time.Duration {
+	if failures <= 0 {
+		return 0
+	}
+	delay := createBackoffBase
+	for i := 1; i < failures && delay < createBackoffMax; i++ {
+		delay *= 2
+	}
+	return min(delay, createBackoffMax)
+}
+
+// createBackoffRemaining returns how long the MachineRequest must still wait
+// before the next VM create, or zero when it may try now.
+func createBackoffRemaining(mr *butlerv1alpha1.MachineRequest, now time.Time) time.Duration {
+	failures := createFailures(mr)
+	if failures == 0 {
+		return 0
+	}
+	last, err := time.Parse(time.RFC3339, mr.Annotations[AnnotationLastCreateFailure])
+	if err != nil {
+		return 0
+	}
+	return max(last.Add(createBackoff(failures)).Sub(now), 0)
+}
+
+// backOffCreate records a failed VM create and keeps the MachineRequest in
+// Pending, retrying after an exponentially growing delay instead of failing
+// it outright so transient errors such as exhausted quota recover on their
+// own.
+func (r *MachineRequestReconciler) backOffCreate(
+	ctx context.Context,
+	mr *butlerv1alpha1.MachineRequest,
+	createErr error,
+) (ctrl.Result, error) {
+	failures := createFailures(mr) + 1
+	if err := r.patchAnnotations(ctx, mr, map[string]string{
+		AnnotationCreateFailures:    strconv.Itoa(failures),
+		AnnotationLastCreateFailure: time.Now().UTC().Format(time.RFC3339),
+	}); err != nil {
+		return ctrl.Result{}, err
+	}
+	delay := createBackoff(failures)
+	meta.SetStatusCondition(&mr.Status.Conditions, metav1.Condition{
+		Type:               butlerv1alpha1.ConditionTypeProgressing,
+		Status:             metav1.ConditionTrue,
+		Reason:             ReasonCreateBackoff,
+		Message:            fmt.Sprintf("VM create failed %d time(s), retrying in %s: %v", failures, delay, createErr),
+		ObservedGeneration: mr.Generation,
+	})
+	if err := r.updateStatus(ctx, mr); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: wait.Jitter(delay, requeueJitter)}, nil
+}
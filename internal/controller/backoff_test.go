@@ -0,0 +1,121 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	butlerv1alpha1 "github.com/butlerdotdev/butler-api/api/v1alpha1"
+)
+
+var _ = Describe("VM create backoff", func() {
+	var ctx context.Context
+
+	BeforeEach(func() {
+		ctx = context.Background()
+	})
+
+	DescribeTable("doubles the delay per failure up to the cap",
+		func(failures int, want time.Duration) {
+			Expect(createBackoff(failures)).To(Equal(want))
+		},
+		Entry("no failures", 0, time.Duration(0)),
+		Entry("the first failure", 1, 10*time.Second),
+		Entry("the second failure", 2, 20*time.Second),
+		Entry("the third failure", 3, 40*time.Second),
+		Entry("the fifth failure", 5, 160*time.Second),
+		Entry("the sixth failure, capped", 6, 5*time.Minute),
+		Entry("many failures", 1000, 5*time.Minute),
+	)
+
+	DescribeTable("waits out the delay since the last failure",
+		func(failures, lastFailure string, want time.Duration) {
+			now := time.Date(2026, 10, 14, 9, 0, 0, 0, time.UTC)
+			mr := testMachineRequest(map[string]string{
+				"create-failures":     failures,
+				"last-create-failure": lastFailure,
+			})
+			Expect(createBackoffRemaining(mr, now)).To(Equal(want))
+		},
+		Entry("without failures", "", "2026-10-14T09:00:00Z", time.Duration(0)),
+		Entry("an invalid failure count", "many", "2026-10-14T09:00:00Z", time.Duration(0)),
+		Entry("without a failure time", "2", "", time.Duration(0)),
+		Entry("right after a failure", "2", "2026-10-14T09:00:00Z", 20*time.Second),
+		Entry("partway through the delay", "2", "2026-10-14T08:59:50Z", 10*time.Second),
+		Entry("after the delay", "2", "2026-10-14T08:59:00Z", time.Duration(0)),
+	)
+
+	It("counts failures and reports the retry", func() {
+		mr := testMachineRequest(map[string]string{"create-failures": "2"})
+		r, _ := testReconciler(mr)
+
+		result, err := r.backOffCreate(ctx, mr, errors.New("quota exceeded"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.RequeueAfter).To(BeNumerically(">=", 40*time.Second))
+		Expect(result.RequeueAfter).To(BeNumerically("<=", 40*time.Second+time.Duration(float64(40*time.Second)*requeueJitter)))
+
+		got := &butlerv1alpha1.MachineRequest{}
+		Expect(r.Get(ctx, client.ObjectKeyFromObject(mr), got)).To(Succeed())
+		Expect(createFailures(got)).To(Equal(3))
+		Expect(createBackoffRemaining(got, time.Now())).To(BeNumerically(">", 30*time.Second))
+		progressing := meta.FindStatusCondition(got.Status.Conditions, butlerv1alpha1.ConditionTypeProgressing)
+		Expect(progressing).NotTo(BeNil())
+		Expect(progressing.Reason).To(Equal(ReasonCreateBackoff))
+		Expect(progressing.Message).To(ContainSubstring("failed 3 time(s), retrying in 40s: quota exceeded"))
+	})
+
+	It("does not create while backing off", func() {
+		mr := testMachineRequest(map[string]string{
+			"create-failures":     "1",
+			"last-create-failure": time.Now().UTC().Format(time.RFC3339),
+		})
+		mr.Status.Phase = butlerv1alpha1.MachinePhasePending
+		r, _ := testReconciler(mr)
+		hc := testHarvesterClient()
+
+		result, err := r.reconcilePending(ctx, mr, hc)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.RequeueAfter).To(BeNumerically("~", 10*time.Second, time.Second))
+		_, err = hc.GetVM(ctx, "worker-0")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("clears the failures once a create succeeds", func() {
+		mr := testMachineRequest(map[string]string{
+			"create-failures":     "3",
+			"last-create-failure": time.Now().Add(-time.Hour).UTC().Format(time.RFC3339),
+		})
+		mr.Status.Phase = butlerv1alpha1.MachinePhasePending
+		r, _ := testReconciler(mr)
+		hc := testHarvesterClient()
+
+		_, err := r.reconcilePending(ctx, mr, hc)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(hc.GetVM(ctx, "worker-0")).NotTo(BeNil())
+		got := &butlerv1alpha1.MachineRequest{}
+		Expect(r.Get(ctx, client.ObjectKeyFromObject(mr), got)).To(Succeed())
+		Expect(got.Annotations).NotTo(HaveKey(AnnotationCreateFailures))
+		Expect(got.Annotations).NotTo(HaveKey(AnnotationLastCreateFailure))
+	})
+})
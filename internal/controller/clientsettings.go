@@ -0,0 +1,104 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	butlerv1alpha1 "github.com/butlerdotdev/butler-api/api/v1alpha1"
+	"github.com/butlerdotdev/butler-provider-harvester/internal/harvester"
+)
+
+// clientSettings are the Harvester client settings a ProviderConfig sets
+// through annotations. They are part of the client cache version, so a
+// changed setting builds a new client.
+type clientSettings struct {
+	AllowIPv6        bool
+	MaxCloudInitSize int
+	MaxRetries       int
+	RetryBaseDelay   time.Duration
+	CallTimeout      time.Duration
+	// RootDiskStorageClass is the ProviderConfig storage-class annotation.
+	// spec.harvester.storageClassName is deliberately not used for root
+	// disks: it predates root disk overrides, and existing ProviderConfigs
+	// set it for the cloud-init disk to a class that cannot clone images.
+	RootDiskStorageClass    string
+	CloudInitPopulatorImage string
+}
+
+// parseClientSettings reads the client settings annotations of pc, falling
+// back to the harvester package defaults.
+func parseClientSettings(pc *butlerv1alpha1.ProviderConfig) (clientSettings, error) {
+	settings := clientSettings{
+		MaxCloudInitSize:        harvester.DefaultMaxCloudInitSize,
+		MaxRetries:              harvester.DefaultMaxRetries,
+		RetryBaseDelay:          harvester.DefaultRetryBaseDelay,
+		CallTimeout:             harvester.DefaultCallTimeout,
+		RootDiskStorageClass:    pc.Annotations[AnnotationStorageClass],
+		CloudInitPopulatorImage: pc.Annotations[AnnotationCloudInitPopulatorImage],
+	}
+	if value := pc.Annotations[AnnotationAllowIPv6]; value != "" {
+		var err error
+		if settings.AllowIPv6, err = strconv.ParseBool(value); err != nil {
+			return settings, fmt.Errorf("annotation %s: invalid boolean %q", AnnotationAllowIPv6, value)
+		}
+	}
+	if value := pc.Annotations[AnnotationMaxCloudInitSize]; value != "" {
+		q, err := resource.ParseQuantity(value)
+		if err != nil || q.Sign() <= 0 {
+			return settings, fmt.Errorf("annotation %s: invalid size %q", AnnotationMaxCloudInitSize, value)
+		}
+		settings.MaxCloudInitSize = int(q.Value())
+	}
+	if value := pc.Annotations[AnnotationMaxRetries]; value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return settings, fmt.Errorf("annotation %s: invalid retry count %q", AnnotationMaxRetries, value)
+		}
+		settings.MaxRetries = n
+	}
+	if value := pc.Annotations[AnnotationRetryBaseDelay]; value != "" {
+		d, err := time.ParseDuration(value)
+		if err != nil || d <= 0 {
+			return settings, fmt.Errorf("annotation %s: invalid duration %q", AnnotationRetryBaseDelay, value)
+		}
+		settings.RetryBaseDelay = d
+	}
+	if value := pc.Annotations[AnnotationCallTimeout]; value != "" {
+		d, err := time.ParseDuration(value)
+		if err != nil || d < 0 {
+			return settings, fmt.Errorf("annotation %s: invalid duration %q", AnnotationCallTimeout, value)
+		}
+		settings.CallTimeout = d
+	}
+	return settings, nil
+}
+
+// apply sets the settings on a newly built client.
+func (s clientSettings) apply(hc *harvester.Client) {
+	hc.AllowIPv6 = s.AllowIPv6
+	hc.MaxCloudInitSize = s.MaxCloudInitSize
+	hc.MaxRetries = s.MaxRetries
+	hc.RetryBaseDelay = s.RetryBaseDelay
+	hc.CallTimeout = s.CallTimeout
+	hc.RootDiskStorageClass = s.RootDiskStorageClass
+	hc.CloudInitPopulatorImage = s.CloudInitPopulatorImage
+}
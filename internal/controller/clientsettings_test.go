@@ -0,0 +1,78 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	butlerv1alpha1 "github.com/butlerdotdev/butler-api/api/v1alpha1"
+	"github.com/butlerdotdev/butler-provider-harvester/internal/harvester"
+)
+
+var _ = Describe("Client settings", func() {
+	providerConfig := func(annotations map[string]string) *butlerv1alpha1.ProviderConfig {
+		pc := &butlerv1alpha1.ProviderConfig{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{}}}
+		for key, value := range annotations {
+			pc.Annotations[annotationPrefix+key] = value
+		}
+		return pc
+	}
+
+	It("defaults to the harvester package settings", func() {
+		settings, err := parseClientSettings(providerConfig(nil))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(settings.MaxRetries).To(Equal(harvester.DefaultMaxRetries))
+		Expect(settings.RetryBaseDelay).To(Equal(harvester.DefaultRetryBaseDelay))
+		Expect(settings.CallTimeout).To(Equal(harvester.DefaultCallTimeout))
+		Expect(settings.MaxCloudInitSize).To(Equal(harvester.DefaultMaxCloudInitSize))
+	})
+
+	DescribeTable("parses the call timeout",
+		func(value string, want time.Duration, valid bool) {
+			settings, err := parseClientSettings(providerConfig(map[string]string{"call-timeout": value}))
+			if !valid {
+				Expect(err).To(MatchError(ContainSubstring(AnnotationCallTimeout)))
+				return
+			}
+			Expect(err).NotTo(HaveOccurred())
+			Expect(settings.CallTimeout).To(Equal(want))
+
+			hc := testHarvesterClient()
+			settings.apply(hc)
+			Expect(hc.CallTimeout).To(Equal(want))
+		},
+		Entry("a duration", "1m", time.Minute, true),
+		Entry("zero to disable the limit", "0", time.Duration(0), true),
+		Entry("a negative duration", "-1s", time.Duration(0), false),
+		Entry("a number without unit", "30", time.Duration(0), false),
+	)
+
+	DescribeTable("rejects invalid settings",
+		func(key, value string) {
+			_, err := parseClientSettings(providerConfig(map[string]string{key: value}))
+			Expect(err).To(MatchError(ContainSubstring(annotationPrefix + key)))
+		},
+		Entry("allow-ipv6", "allow-ipv6", "maybe"),
+		Entry("max-cloud-init-size", "max-cloud-init-size", "0"),
+		Entry("max-retries", "max-retries", "-1"),
+		Entry("retry-base-delay", "retry-base-delay", "0s"),
+	)
+})
@@ -0,0 +1,122 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"text/template"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	butlerv1alpha1 "github.com/butlerdotdev/butler-api/api/v1alpha1"
+)
+
+// groupUserDataKey is the Secret key holding a shared cloud-init template.
+const groupUserDataKey = "userData"
+
+// groupPeer is a cloud-init group member as seen by the template.
+type groupPeer struct {
+	Name      string
+	Ordinal   int
+	IPAddress string
+}
+
+// groupTemplateData is the data available to a group cloud-init template.
+type groupTemplateData struct {
+	Group    string
+	Ordinal  int
+	Hostname string
+	// Peers are the other members sorted by ordinal. Members that have no IP
+	// yet have an empty IPAddress.
+	Peers []groupPeer
+}
+
+// groupUserData renders the cloud-init group template for mr. It returns
+// ok=false without an error while a lower-ordinal peer has no IP yet, so
+// members boot in ordinal order and each sees the addresses of the members
+// before it. Returns the MachineRequest's user data unchanged for
+// MachineRequests outside a group.
+func (r *MachineRequestReconciler) groupUserData(ctx context.Context, mr *butlerv1alpha1.MachineRequest) (userData string, ok bool, err error) {
+	source, err := r.userData(ctx, mr)
+	if err != nil {
+		return "", false, err
+	}
+	group := mr.Annotations[AnnotationCloudInitGroup]
+	if group == "" {
+		return source, true, nil
+	}
+	ordinal, err := intAnnotation(mr, AnnotationCloudInitGroupOrdinal)
+	if err != nil {
+		return "", false, err
+	}
+
+	if name := mr.Annotations[AnnotationCloudInitGroupSecret]; name != "" {
+		secret := &corev1.Secret{}
+		if err := r.apiReader().Get(ctx, types.NamespacedName{Namespace: mr.Namespace, Name: name}, secret); err != nil {
+			return "", false, fmt.Errorf("failed to get cloud-init group secret %s: %w", name, err)
+		}
+		data, found := secret.Data[groupUserDataKey]
+		if !found {
+			return "", false, fmt.Errorf("cloud-init group secret %s does not contain key %s", name, groupUserDataKey)
+		}
+		source = string(data)
+	}
+
+	tmpl, err := template.New(group).Option("missingkey=error").Parse(source)
+	if err != nil {
+		return "", false, fmt.Errorf("invalid cloud-init group template: %w", err)
+	}
+
+	list := &butlerv1alpha1.MachineRequestList{}
+	if err := r.List(ctx, list, client.InNamespace(mr.Namespace)); err != nil {
+		return "", false, err
+	}
+	data := groupTemplateData{Group: group, Ordinal: ordinal, Hostname: mr.Spec.MachineName}
+	for i := range list.Items {
+		peer := &list.Items[i]
+		if peer.UID == mr.UID || peer.Annotations[AnnotationCloudInitGroup] != group || !peer.DeletionTimestamp.IsZero() {
+			continue
+		}
+		peerOrdinal, err := intAnnotation(peer, AnnotationCloudInitGroupOrdinal)
+		if err != nil {
+			// A peer with a malformed ordinal reports its own error
+			continue
+		}
+		if peerOrdinal < ordinal && peer.Status.IPAddress == "" {
+			return "", false, nil
+		}
+		data.Peers = append(data.Peers, groupPeer{
+			Name:      peer.Spec.MachineName,
+			Ordinal:   peerOrdinal,
+			IPAddress: peer.Status.IPAddress,
+		})
+	}
+	sort.Slice(data.Peers, func(i, j int) bool {
+		return data.Peers[i].Ordinal < data.Peers[j].Ordinal
+	})
+
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, data); err != nil {
+		return "", false, fmt.Errorf("failed to render cloud-init group template: %w", err)
+	}
+	return out.String(), true, nil
+}
@@ -0,0 +1,184 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/base64"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	butlerv1alpha1 "github.com/butlerdotdev/butler-api/api/v1alpha1"
+)
+
+// groupTemplate renders every field a group template can use.
+const groupTemplate = "{{.Group}}/{{.Ordinal}}/{{.Hostname}}:{{range .Peers}} {{.Ordinal}}={{.Name}}@{{.IPAddress}}{{end}}"
+
+// groupMember returns a MachineRequest in the cloud-init group with the given
+// ordinal and IP address.
+func groupMember(group, ordinal, ip string) *butlerv1alpha1.MachineRequest {
+	mr := testMachineRequest(map[string]string{
+		"cloud-init-group":         group,
+		"cloud-init-group-ordinal": ordinal,
+	})
+	mr.Name = "etcd-" + ordinal
+	mr.UID = types.UID("uid-etcd-" + ordinal)
+	mr.Spec.MachineName = "etcd-" + ordinal
+	mr.Spec.UserData = groupTemplate
+	mr.Status.IPAddress = ip
+	return mr
+}
+
+var _ = Describe("Cloud-init groups", func() {
+	var ctx context.Context
+
+	BeforeEach(func() {
+		ctx = context.Background()
+	})
+
+	DescribeTable("renders the member's user data",
+		func(ordinal string, peers []client.Object, want string, ready bool) {
+			mr := groupMember("etcd", ordinal, "")
+			r, _ := testReconciler(append(peers, mr)...)
+			userData, ok, err := r.groupUserData(ctx, mr)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ok).To(Equal(ready))
+			Expect(userData).To(Equal(want))
+		},
+		Entry("the first member before its peers have addresses", "0",
+			[]client.Object{groupMember("etcd", "1", ""), groupMember("etcd", "2", "")},
+			"etcd/0/etcd-0: 1=etcd-1@ 2=etcd-2@", true),
+		Entry("a later member waits for a lower ordinal", "2",
+			[]client.Object{groupMember("etcd", "0", "10.0.0.10"), groupMember("etcd", "1", "")},
+			"", false),
+		Entry("a later member once lower ordinals have addresses", "2",
+			[]client.Object{groupMember("etcd", "1", "10.0.0.11"), groupMember("etcd", "0", "10.0.0.10")},
+			"etcd/2/etcd-2: 0=etcd-0@10.0.0.10 1=etcd-1@10.0.0.11", true),
+		Entry("members of other groups are not peers", "1",
+			[]client.Object{groupMember("zookeeper", "0", "")},
+			"etcd/1/etcd-1:", true),
+		Entry("a peer with a malformed ordinal is skipped", "1",
+			[]client.Object{groupMember("etcd", "first", "")},
+			"etcd/1/etcd-1:", true),
+	)
+
+	It("skips peers being deleted", func() {
+		peer := groupMember("etcd", "0", "")
+		peer.Finalizers = []string{finalizerName}
+		mr := groupMember("etcd", "1", "")
+		r, _ := testReconciler(peer, mr)
+		Expect(r.Delete(ctx, peer)).To(Succeed())
+
+		userData, ok, err := r.groupUserData(ctx, mr)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeTrue())
+		Expect(userData).To(Equal("etcd/1/etcd-1:"))
+	})
+
+	It("returns the user data unchanged outside a group", func() {
+		mr := testMachineRequest(nil)
+		mr.Spec.UserData = groupTemplate
+		r, _ := testReconciler(mr)
+		userData, ok, err := r.groupUserData(ctx, mr)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeTrue())
+		Expect(userData).To(Equal(groupTemplate))
+	})
+
+	It("reads the template from the group secret", func() {
+		mr := groupMember("etcd", "0", "")
+		mr.Spec.UserData = ""
+		mr.Annotations[AnnotationCloudInitGroupSecret] = "etcd-cloud-init"
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: mr.Namespace, Name: "etcd-cloud-init"},
+			Data:       map[string][]byte{groupUserDataKey: []byte("hostname: {{.Hostname}}")},
+		}
+		r, _ := testReconciler(secret, mr)
+		userData, ok, err := r.groupUserData(ctx, mr)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeTrue())
+		Expect(userData).To(Equal("hostname: etcd-0"))
+	})
+
+	DescribeTable("reports an invalid group configuration",
+		func(configure func(*butlerv1alpha1.MachineRequest), want string) {
+			mr := groupMember("etcd", "0", "")
+			configure(mr)
+			r, _ := testReconciler(mr, &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Namespace: mr.Namespace, Name: "empty"},
+			})
+			_, ok, err := r.groupUserData(ctx, mr)
+			Expect(err).To(MatchError(ContainSubstring(want)))
+			Expect(ok).To(BeFalse())
+		},
+		Entry("a malformed ordinal", func(mr *butlerv1alpha1.MachineRequest) {
+			mr.Annotations[AnnotationCloudInitGroupOrdinal] = "first"
+		}, AnnotationCloudInitGroupOrdinal),
+		Entry("a missing group secret", func(mr *butlerv1alpha1.MachineRequest) {
+			mr.Annotations[AnnotationCloudInitGroupSecret] = "missing"
+		}, "failed to get cloud-init group secret missing"),
+		Entry("a group secret without the template key", func(mr *butlerv1alpha1.MachineRequest) {
+			mr.Annotations[AnnotationCloudInitGroupSecret] = "empty"
+		}, "does not contain key userData"),
+		Entry("a template that does not parse", func(mr *butlerv1alpha1.MachineRequest) {
+			mr.Spec.UserData = "{{.Hostname"
+		}, "invalid cloud-init group template"),
+		Entry("a template using an unknown field", func(mr *butlerv1alpha1.MachineRequest) {
+			mr.Spec.UserData = "{{.Zone}}"
+		}, "failed to render cloud-init group template"),
+	)
+
+	It("updates the VM user data once peers have addresses", func() {
+		peer := groupMember("etcd", "0", "")
+		mr := groupMember("etcd", "1", "")
+		hc := testHarvesterClient()
+		opts, err := vmCreateOptions(mr)
+		Expect(err).NotTo(HaveOccurred())
+		opts.UserData = "etcd/1/etcd-1: 0=etcd-0@"
+		_, _, err = hc.CreateVM(ctx, opts)
+		Expect(err).NotTo(HaveOccurred())
+		mr.Annotations[AnnotationUserDataHash] = userDataHash(opts.UserData)
+		peer.Status.IPAddress = "10.0.0.10"
+		r, recorder := testReconciler(peer, mr)
+
+		changed, err := r.checkUserDataDrift(ctx, mr, hc)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(changed).To(BeFalse())
+		Expect(recorder.Events).To(Receive(ContainSubstring("GroupCloudInitUpdated")))
+		Expect(mr.Annotations).To(HaveKeyWithValue(AnnotationUserDataHash, userDataHash("etcd/1/etcd-1: 0=etcd-0@10.0.0.10")))
+
+		vm, err := hc.GetVM(ctx, mr.Spec.MachineName)
+		Expect(err).NotTo(HaveOccurred())
+		volumes, _, _ := unstructured.NestedSlice(vm.Object, "spec", "template", "spec", "volumes")
+		var userData string
+		for _, v := range volumes {
+			if volume := v.(map[string]interface{}); volume["name"] == "cloudinit" {
+				encoded, _, _ := unstructured.NestedString(volume, "cloudInitNoCloud", "userDataBase64")
+				decoded, err := base64.StdEncoding.DecodeString(encoded)
+				Expect(err).NotTo(HaveOccurred())
+				userData = string(decoded)
+			}
+		}
+		Expect(userData).To(ContainSubstring("0=etcd-0@10.0.0.10"))
+	})
+})
@@ -0,0 +1,195 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+// Harvester provider condition types and reasons set on MachineRequests in
+// addition to the shared Ready/Progressing conditions from butler-api.
+const (
+	// ConditionTypePaused indicates the VM guest is paused.
+	ConditionTypePaused = "Paused"
+	// ConditionTypeNameConflict indicates the VM name is owned by another MachineRequest.
+	ConditionTypeNameConflict = "NameConflict"
+	// ConditionTypeBlocked indicates the request cannot progress until
+	// something outside the controller changes, so it is no longer polled.
+	ConditionTypeBlocked = "Blocked"
+	// ConditionTypeInsufficientCapacity indicates the cluster has no room for
+	// the VM, so creation is deferred.
+	ConditionTypeInsufficientCapacity = "InsufficientCapacity"
+	// ConditionTypeGuestUnresponsive indicates the guest agent of a running
+	// VM has been disconnected for longer than the configured timeout.
+	ConditionTypeGuestUnresponsive = "GuestUnresponsive"
+	// ConditionTypeCloudInitChanged indicates spec.userData differs from the
+	// user data the VM was created with.
+	ConditionTypeCloudInitChanged = "CloudInitChanged"
+	// ConditionTypeNetworkNotFound indicates the network the VM would be
+	// attached to does not exist, so creation is deferred.
+	ConditionTypeNetworkNotFound = "NetworkNotFound"
+	// ConditionTypeReplicasReady indicates every desired pool member is
+	// ready. The message reports the ready and desired counts.
+	ConditionTypeReplicasReady = "ReplicasReady"
+	// ConditionTypeImmutableFieldChanged indicates a setting that cannot be
+	// applied to the existing VM was changed.
+	ConditionTypeImmutableFieldChanged = "ImmutableFieldChanged"
+	// ConditionTypeRestartRequired indicates a CPU or memory change only takes
+	// effect once the VM restarts.
+	ConditionTypeRestartRequired = "RestartRequired"
+	// ConditionTypeSSHKeysRotated reports whether SSH keys rotated in the
+	// ssh-key-secret Secret have been propagated into the guest.
+	ConditionTypeSSHKeysRotated = "SSHKeysRotated"
+	// ConditionTypeStopped indicates the VM was stopped through the
+	// power-state annotation. It is False while the guest shuts down.
+	ConditionTypeStopped = "Stopped"
+	// ConditionTypeMigrating indicates a live migration requested through the
+	// migrate annotation is in progress. It is False once it has finished.
+	ConditionTypeMigrating = "Migrating"
+
+	// Provisioning step conditions, in pipeline order. Each is False until
+	// the step completes during creation, then True. The root disk steps are
+	// only set for VMs cloned from a Harvester image.
+	ConditionTypeImageValidated = "ImageValidated"
+	ConditionTypePVCCreated     = "PVCCreated"
+	ConditionTypePVCBound       = "PVCBound"
+	ConditionTypeVMCreated      = "VMCreated"
+	ConditionTypeVMIScheduled   = "VMIScheduled"
+	ConditionTypeIPAssigned     = "IPAssigned"
+	ConditionTypeGuestReady     = "GuestReady"
+
+	// ReasonStartPaused indicates the VM was created paused on request.
+	ReasonStartPaused = "StartPaused"
+	// ReasonUnpaused indicates a paused VM was resumed.
+	ReasonUnpaused = "Unpaused"
+	// ReasonStopping indicates the guest of a stopped VM is shutting down.
+	ReasonStopping = "Stopping"
+	// ReasonPoweredOff indicates the VM is stopped on request.
+	ReasonPoweredOff = "PoweredOff"
+	// ReasonStarting indicates a stopped VM was started again and is waiting
+	// for an IP.
+	ReasonStarting = "Starting"
+	// ReasonWaitingForCloudInit indicates the persistent cloud-init disk is
+	// still being populated.
+	ReasonWaitingForCloudInit = "WaitingForCloudInit"
+	// ReasonWaitingForTeardownOrder indicates deletion is waiting for
+	// higher-ordinal members of the teardown group.
+	ReasonWaitingForTeardownOrder = "WaitingForTeardownOrder"
+	// ReasonWaitingForStorage indicates the root disk PVC is not bound yet.
+	ReasonWaitingForStorage = "WaitingForStorage"
+	// ReasonNameConflict indicates the VM is owned by another MachineRequest.
+	ReasonNameConflict = "NameConflict"
+	// ReasonInsufficientCapacity indicates no node can fit the VM.
+	ReasonInsufficientCapacity = "InsufficientCapacity"
+	// ReasonGuestAgentDisconnected indicates a previously connected guest
+	// agent stopped responding.
+	ReasonGuestAgentDisconnected = "GuestAgentDisconnected"
+	// ReasonRecreating indicates the VM was deleted on request and a fresh
+	// one is being created.
+	ReasonRecreating = "Recreating"
+	// ReasonRetrying indicates a Failed MachineRequest was reset on request
+	// and creation is being retried.
+	ReasonRetrying = "Retrying"
+	// ReasonUserDataNotFound indicates the ConfigMap or Secret named for the
+	// user data does not exist yet.
+	ReasonUserDataNotFound = "UserDataNotFound"
+	// ReasonInvalidUserData indicates the user data failed validation.
+	ReasonInvalidUserData = "InvalidUserData"
+	// ReasonDryRun indicates the VM manifests were rendered instead of
+	// creating the VM.
+	ReasonDryRun = "DryRun"
+	// ReasonDryRunFailed indicates rendering the VM manifests failed.
+	ReasonDryRunFailed = "DryRunFailed"
+	// ReasonCreateBackoff indicates the last VM create failed and the next
+	// attempt is delayed by an exponential backoff.
+	ReasonCreateBackoff = "CreateBackoff"
+	// ReasonWaitingForGroupPeers indicates creation is waiting for
+	// lower-ordinal cloud-init group members to get an IP.
+	ReasonWaitingForGroupPeers = "WaitingForGroupPeers"
+	// ReasonRestoring indicates the VM is being restored from a backup.
+	ReasonRestoring = "Restoring"
+	// ReasonCreateTimeout indicates the VM did not become ready within the
+	// create timeout.
+	ReasonCreateTimeout = "CreateTimeout"
+	// ReasonCloudInitChangedRequiresRecreate indicates a user data edit only
+	// takes effect once the VM is recreated.
+	ReasonCloudInitChangedRequiresRecreate = "CloudInitChangedRequiresRecreate"
+	// ReasonNoMatchingImage indicates the image selector matches no imported
+	// Harvester image.
+	ReasonNoMatchingImage = "NoMatchingImage"
+	// ReasonImageNotFound indicates the image the root disk clones from does
+	// not exist.
+	ReasonImageNotFound = "ImageNotFound"
+	// ReasonImageImportFailed indicates the image the root disk clones from
+	// failed to import.
+	ReasonImageImportFailed = "ImageImportFailed"
+	// ReasonWaitingForImage indicates creation is waiting for the image the
+	// root disk clones from to finish importing.
+	ReasonWaitingForImage = "WaitingForImage"
+	// ReasonWaitingForCreate indicates deletion is waiting for an
+	// interrupted VM creation to settle.
+	ReasonWaitingForCreate = "WaitingForCreate"
+	// ReasonStorageDegraded indicates the Longhorn volume backing the root
+	// disk has fewer healthy replicas than desired.
+	ReasonStorageDegraded = "StorageDegraded"
+	// ReasonNetworkNotFound indicates the NetworkAttachmentDefinition for
+	// the VM network does not exist.
+	ReasonNetworkNotFound = "NetworkNotFound"
+	// ReasonScaling indicates pool members are being created, deleted or
+	// are not ready yet.
+	ReasonScaling = "Scaling"
+	// ReasonScaledToZero indicates a pool has no desired members and every
+	// member has been deleted.
+	ReasonScaledToZero = "ScaledToZero"
+	// ReasonOrphanedVMI indicates the VM was deleted but its VMI lingers.
+	ReasonOrphanedVMI = "OrphanedVMI"
+	// ReasonImmutableFieldChanged indicates an immutable setting was edited
+	// after the VM was created.
+	ReasonImmutableFieldChanged = "ImmutableFieldChanged"
+	// ReasonGPUUnavailable indicates a requested GPU device is not permitted
+	// or was rejected, which fails the request, or is not offered or free on
+	// any node, which blocks it until one is.
+	ReasonGPUUnavailable = "GPUUnavailable"
+	// ReasonResizeRequiresRestart indicates the VM could not be resized
+	// while running.
+	ReasonResizeRequiresRestart = "ResizeRequiresRestart"
+	// ReasonResizing indicates KubeVirt is hotplugging a CPU or memory
+	// change into the running guest.
+	ReasonResizing = "Resizing"
+	// ReasonDeletionThrottled indicates deletion is waiting for a slot under
+	// the ProviderConfig deletion rate.
+	ReasonDeletionThrottled = "DeletionThrottled"
+	// ReasonRotationPending indicates the SSH key Secret changed and the new
+	// keys have not been confirmed in the guest yet.
+	ReasonRotationPending = "RotationPending"
+	// ReasonSSHKeysPropagated indicates the guest agent applied the rotated
+	// SSH keys.
+	ReasonSSHKeysPropagated = "SSHKeysPropagated"
+	// ReasonStepPending indicates a provisioning step has not completed yet.
+	ReasonStepPending = "StepPending"
+	// ReasonStepCompleted indicates a provisioning step completed.
+	ReasonStepCompleted = "StepCompleted"
+	// ReasonMigrating indicates the VM is being live migrated.
+	ReasonMigrating = "Migrating"
+	// ReasonMigrated indicates the live migration succeeded.
+	ReasonMigrated = "Migrated"
+	// ReasonMigrationFailed indicates KubeVirt could not complete the live
+	// migration.
+	ReasonMigrationFailed = "MigrationFailed"
+	// ReasonStaleDisk indicates a disk PVC of a previous VM instance exists
+	// without a VM. It is kept until the recreate annotation discards it.
+	ReasonStaleDisk = "StaleDisk"
+	// ReasonNotMigratable indicates KubeVirt cannot live migrate the VM, e.g.
+	// because of a ReadWriteOnce disk.
+	ReasonNotMigratable = "NotMigratable"
+)
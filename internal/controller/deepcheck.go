@@ -0,0 +1,174 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	butlerv1alpha1 "github.com/butlerdotdev/butler-api/api/v1alpha1"
+	"github.com/butlerdotdev/butler-provider-harvester/internal/harvester"
+)
+
+// defaultDeepCheckInterval is how often the deep checks of a running VM run
+// when nothing about its MachineRequest spec changed.
+const defaultDeepCheckInterval = 5 * time.Minute
+
+// deepCheck records the last completed deep check of a MachineRequest.
+type deepCheck struct {
+	at         time.Time
+	generation int64
+}
+
+// deepCheckDue reports whether the deep checks of a running MachineRequest
+// should run: on the first reconcile after the controller started, when the
+// spec changed since the last run, or once interval has passed. When they
+// are not due it also returns the time left until they are.
+func (r *MachineRequestReconciler) deepCheckDue(mr *butlerv1alpha1.MachineRequest, interval time.Duration, now time.Time) (bool, time.Duration) {
+	r.deepChecksMu.Lock()
+	defer r.deepChecksMu.Unlock()
+	last, ok := r.deepChecks[client.ObjectKeyFromObject(mr)]
+	if !ok || last.generation != mr.Generation {
+		return true, 0
+	}
+	if left := last.at.Add(interval).Sub(now); left > 0 {
+		return false, left
+	}
+	return true, 0
+}
+
+// firstDeepCheck reports whether the running deep checks are the first for
+// the current generation of the MachineRequest, so warnings about a spec
+// that cannot be applied are emitted once rather than on every run.
+func (r *MachineRequestReconciler) firstDeepCheck(mr *butlerv1alpha1.MachineRequest) bool {
+	r.deepChecksMu.Lock()
+	defer r.deepChecksMu.Unlock()
+	last, ok := r.deepChecks[client.ObjectKeyFromObject(mr)]
+	return !ok || last.generation != mr.Generation
+}
+
+// recordDeepCheck notes that the deep checks of the MachineRequest completed.
+func (r *MachineRequestReconciler) recordDeepCheck(mr *butlerv1alpha1.MachineRequest, now time.Time) {
+	r.deepChecksMu.Lock()
+	defer r.deepChecksMu.Unlock()
+	if r.deepChecks == nil {
+		r.deepChecks = make(map[types.NamespacedName]deepCheck)
+	}
+	r.deepChecks[client.ObjectKeyFromObject(mr)] = deepCheck{at: now, generation: mr.Generation}
+}
+
+// forgetDeepCheck drops the deep check record of a MachineRequest that is
+// gone.
+func (r *MachineRequestReconciler) forgetDeepCheck(key types.NamespacedName) {
+	r.deepChecksMu.Lock()
+	defer r.deepChecksMu.Unlock()
+	delete(r.deepChecks, key)
+}
+
+// runDeepChecks runs the checks of a running VM that read further Harvester
+// resources: provider labels and annotations, the run strategy annotation,
+// the guest hostname, user data drift, SSH key rotation, the storage backend and CPU and memory
+// resizes, unless inPlaceResize is off. It returns whether the status conditions changed.
+func (r *MachineRequestReconciler) runDeepChecks(
+	ctx context.Context,
+	mr *butlerv1alpha1.MachineRequest,
+	hc *harvester.Client,
+	status *harvester.VMStatus,
+	inPlaceResize bool,
+) (bool, error) {
+	log := logf.FromContext(ctx)
+
+	// Re-apply provider-managed labels and annotations edited on the VM
+	if corrected, err := hc.EnsureVMMetadata(ctx, vmName(mr), harvester.VMMetadata{
+		Labels:   mr.Spec.Labels,
+		OwnerUID: string(mr.UID),
+		Owner:    mr.Namespace + "/" + mr.Name,
+	}); err != nil {
+		log.Error(err, "Failed to correct VM metadata drift")
+	} else if len(corrected) > 0 {
+		log.Info("Corrected VM metadata drift", "fields", corrected)
+		r.Recorder.Eventf(mr, corev1.EventTypeNormal, "DriftCorrected", "Reconciled VM fields: %s", strings.Join(corrected, ", "))
+	}
+
+	// Harvester reads the run strategy annotation, KubeVirt the spec field
+	if stale, err := hc.EnsureRunStrategy(ctx, mr.Spec.MachineName); err != nil {
+		log.Error(err, "Failed to reconcile VM run strategy")
+	} else if stale != "" {
+		log.Info("Reconciled VM run strategy annotation", "was", stale)
+		r.Recorder.Eventf(mr, corev1.EventTypeNormal, "RunStrategyReconciled",
+			"Corrected %s annotation from %s to match spec.runStrategy", harvester.AnnotationVMRunStrategy, stale)
+	}
+
+	if err := r.checkGuestHostname(ctx, mr, hc, status); err != nil {
+		return false, err
+	}
+
+	changed, err := r.checkUserDataDrift(ctx, mr, hc)
+	if err != nil {
+		return false, err
+	}
+
+	keysChanged, err := r.checkSSHKeyRotation(ctx, mr, hc, status)
+	if err != nil {
+		return false, err
+	}
+	changed = changed || keysChanged
+
+	storageChanged, err := r.checkStorageBackend(ctx, mr, hc)
+	if err != nil {
+		return false, err
+	}
+	changed = changed || storageChanged
+
+	if !inPlaceResize {
+		return r.finishResizing(mr, "In-place resize is disabled") || changed, nil
+	}
+	resizeChanged, err := r.checkResize(ctx, mr, hc)
+	if err != nil {
+		return false, err
+	}
+	return changed || resizeChanged, nil
+}
+
+// checkGuestHostname records the hostname the guest agent reports in
+// AnnotationGuestHostname. It is kept while the agent is disconnected or
+// cannot be asked, e.g. without RBAC for the guestosinfo subresource.
+func (r *MachineRequestReconciler) checkGuestHostname(
+	ctx context.Context,
+	mr *butlerv1alpha1.MachineRequest,
+	hc *harvester.Client,
+	status *harvester.VMStatus,
+) error {
+	if !status.AgentConnected {
+		return nil
+	}
+	hostname, err := hc.GetGuestHostname(ctx, mr.Spec.MachineName)
+	if err != nil {
+		logf.FromContext(ctx).V(1).Info("Failed to get guest hostname", "error", err.Error())
+		return nil
+	}
+	if hostname == "" || mr.Annotations[AnnotationGuestHostname] == hostname {
+		return nil
+	}
+	return r.patchAnnotations(ctx, mr, map[string]string{AnnotationGuestHostname: hostname})
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	butlerv1alpha1 "github.com/butlerdotdev/butler-api/api/v1alpha1"
+	"github.com/butlerdotdev/butler-provider-harvester/internal/harvester"
+)
+
+var _ = Describe("Guest hostname deep check", func() {
+	var (
+		ctx      context.Context
+		hc       *harvester.Client
+		calls    atomic.Int32
+		hostname string
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		calls.Store(0)
+		hostname = "worker-0.example.com"
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls.Add(1)
+			if hostname == "" {
+				http.Error(w, "guest agent not responding", http.StatusServiceUnavailable)
+				return
+			}
+			_, _ = w.Write([]byte(`{"hostname":"` + hostname + `"}`))
+		}))
+		DeferCleanup(server.Close)
+		hc = harvester.NewClientFromInterfaces(dynamicfake.NewSimpleDynamicClient(runtime.NewScheme()),
+			kubernetes.NewForConfigOrDie(&rest.Config{Host: server.URL}),
+			&butlerv1alpha1.HarvesterProviderConfig{Namespace: "default"})
+	})
+
+	check := func(mr *butlerv1alpha1.MachineRequest, status *harvester.VMStatus) *butlerv1alpha1.MachineRequest {
+		r, _ := testReconciler(mr)
+		Expect(r.checkGuestHostname(ctx, mr, hc, status)).To(Succeed())
+		got := &butlerv1alpha1.MachineRequest{}
+		Expect(r.Get(ctx, client.ObjectKeyFromObject(mr), got)).To(Succeed())
+		return got
+	}
+
+	It("records the hostname the guest agent reports", func() {
+		mr := check(testMachineRequest(nil), &harvester.VMStatus{AgentConnected: true})
+		Expect(mr.Annotations).To(HaveKeyWithValue(AnnotationGuestHostname, "worker-0.example.com"))
+		Expect(calls.Load()).To(BeEquivalentTo(1))
+	})
+
+	It("does not ask a guest without a connected agent", func() {
+		mr := check(testMachineRequest(map[string]string{"guest-hostname": "old"}), &harvester.VMStatus{})
+		Expect(mr.Annotations).To(HaveKeyWithValue(AnnotationGuestHostname, "old"))
+		Expect(calls.Load()).To(BeZero())
+	})
+
+	It("keeps the last hostname when the guest agent cannot be asked", func() {
+		hostname = ""
+		mr := check(testMachineRequest(map[string]string{"guest-hostname": "old"}), &harvester.VMStatus{AgentConnected: true})
+		Expect(mr.Annotations).To(HaveKeyWithValue(AnnotationGuestHostname, "old"))
+		Expect(calls.Load()).To(BeEquivalentTo(1))
+	})
+})
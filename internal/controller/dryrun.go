@@ -0,0 +1,83 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	butlerv1alpha1 "github.com/butlerdotdev/butler-api/api/v1alpha1"
+	"github.com/butlerdotdev/butler-provider-harvester/internal/harvester"
+)
+
+// renderDryRun records the manifests CreateVM would submit in
+// AnnotationDryRunManifest instead of creating the VM. The MachineRequest
+// stays in Pending until AnnotationDryRun is removed.
+func (r *MachineRequestReconciler) renderDryRun(
+	ctx context.Context,
+	mr *butlerv1alpha1.MachineRequest,
+	hc *harvester.Client,
+	opts harvester.VMCreateOptions,
+) (ctrl.Result, error) {
+	log := logf.FromContext(ctx)
+
+	condition := metav1.Condition{
+		Type:               butlerv1alpha1.ConditionTypeProgressing,
+		Status:             metav1.ConditionTrue,
+		Reason:             ReasonDryRun,
+		Message:            fmt.Sprintf("VM manifests rendered into %s; remove %s to create the VM", AnnotationDryRunManifest, AnnotationDryRun),
+		ObservedGeneration: mr.Generation,
+	}
+	result := ctrl.Result{}
+	rendered, err := hc.RenderVM(ctx, opts)
+	if err == nil {
+		manifest, err := rendered.RedactedYAML()
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if mr.Annotations[AnnotationDryRunManifest] != manifest {
+			if err := r.patchAnnotations(ctx, mr, map[string]string{AnnotationDryRunManifest: manifest}); err != nil {
+				return ctrl.Result{}, err
+			}
+			log.Info("Rendered VM manifests for dry run", "pvcs", len(rendered.PVCs))
+			r.Recorder.Eventf(mr, corev1.EventTypeNormal, ReasonDryRun,
+				"Rendered VM %s and %d PVC(s) into %s", mr.Spec.MachineName, len(rendered.PVCs), AnnotationDryRunManifest)
+		}
+	} else {
+		// Errors in a preview must not fail the MachineRequest; retry in
+		// case the cause is outside the spec, such as a missing image
+		condition.Reason = ReasonDryRunFailed
+		condition.Message = fmt.Sprintf("Dry run failed: %v", err)
+		if current := meta.FindStatusCondition(mr.Status.Conditions, condition.Type); current == nil || current.Message != condition.Message {
+			log.Info("Dry run failed", "reason", err.Error())
+			r.Recorder.Event(mr, corev1.EventTypeWarning, ReasonDryRunFailed, condition.Message)
+		}
+		result.RequeueAfter = r.requeueLong()
+	}
+
+	meta.SetStatusCondition(&mr.Status.Conditions, condition)
+	if err := r.updateStatus(ctx, mr); err != nil {
+		return ctrl.Result{}, err
+	}
+	return result, nil
+}
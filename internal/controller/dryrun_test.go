@@ -0,0 +1,91 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	butlerv1alpha1 "github.com/butlerdotdev/butler-api/api/v1alpha1"
+	"github.com/butlerdotdev/butler-provider-harvester/internal/harvester"
+)
+
+var _ = Describe("Dry run", func() {
+	var (
+		ctx context.Context
+		mr  *butlerv1alpha1.MachineRequest
+		hc  *harvester.Client
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		mr = testMachineRequest(map[string]string{"dry-run": "true"})
+		mr.Spec.UserData = "#cloud-config\npassword: hunter2\n"
+		hc = testHarvesterClient()
+	})
+
+	It("records the manifests without creating the VM", func() {
+		r, recorder := testReconciler(mr)
+		opts, err := vmCreateOptions(mr)
+		Expect(err).NotTo(HaveOccurred())
+
+		result, err := r.renderDryRun(ctx, mr, hc, opts)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.RequeueAfter).To(BeZero())
+		Expect(recorder.Events).To(Receive(ContainSubstring("Rendered VM worker-0 and 1 PVC(s)")))
+
+		got := &butlerv1alpha1.MachineRequest{}
+		Expect(r.Get(ctx, client.ObjectKeyFromObject(mr), got)).To(Succeed())
+		manifest := got.Annotations[AnnotationDryRunManifest]
+		Expect(manifest).To(ContainSubstring("kind: VirtualMachine"))
+		Expect(manifest).To(ContainSubstring("kind: PersistentVolumeClaim"))
+		Expect(manifest).NotTo(ContainSubstring("hunter2"))
+		Expect(meta.FindStatusCondition(got.Status.Conditions, butlerv1alpha1.ConditionTypeProgressing).Reason).To(Equal(ReasonDryRun))
+		_, err = hc.GetVM(ctx, opts.Name)
+		Expect(apierrors.IsNotFound(err)).To(BeTrue(), "got %v", err)
+
+		// Rendering the same manifests again is silent
+		_, err = r.renderDryRun(ctx, mr, hc, opts)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(recorder.Events).To(BeEmpty())
+	})
+
+	It("reports a failed render once and retries", func() {
+		mr.Spec.Image = "default/missing"
+		r, recorder := testReconciler(mr)
+		opts, err := vmCreateOptions(mr)
+		Expect(err).NotTo(HaveOccurred())
+
+		for range 2 {
+			result, err := r.renderDryRun(ctx, mr, hc, opts)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(BeNumerically(">", 0))
+		}
+		Expect(recorder.Events).To(HaveLen(1))
+		Expect(recorder.Events).To(Receive(ContainSubstring(ReasonDryRunFailed)))
+		Expect(mr.Annotations).NotTo(HaveKey(AnnotationDryRunManifest))
+		cond := meta.FindStatusCondition(mr.Status.Conditions, butlerv1alpha1.ConditionTypeProgressing)
+		Expect(cond.Reason).To(Equal(ReasonDryRunFailed))
+		Expect(cond.Message).To(ContainSubstring("default/missing"))
+		Expect(mr.Status.Phase).NotTo(Equal(butlerv1alpha1.MachinePhaseFailed))
+	})
+})
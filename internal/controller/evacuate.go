@@ -0,0 +1,44 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	butlerv1alpha1 "github.com/butlerdotdev/butler-api/api/v1alpha1"
+	"github.com/butlerdotdev/butler-provider-harvester/internal/harvester"
+)
+
+// EvacuateNode live migrates the managed VMs of the ProviderConfig's
+// Harvester cluster off the node, with the credentials and client settings
+// the controller uses. It backs the evacuate-node command.
+func EvacuateNode(ctx context.Context, c client.Client, providerConfig types.NamespacedName, node string) (*harvester.NodeEvacuation, error) {
+	pc := &butlerv1alpha1.ProviderConfig{}
+	if err := c.Get(ctx, providerConfig, pc); err != nil {
+		return nil, fmt.Errorf("failed to get ProviderConfig %s: %w", providerConfig, err)
+	}
+	r := &MachineRequestReconciler{Client: c}
+	hc, err := r.createHarvesterClient(ctx, pc)
+	if err != nil {
+		return nil, err
+	}
+	return hc.EvacuateNode(ctx, node)
+}
@@ -0,0 +1,188 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"math"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	butlerv1alpha1 "github.com/butlerdotdev/butler-api/api/v1alpha1"
+	"github.com/butlerdotdev/butler-provider-harvester/internal/harvester"
+)
+
+// footprintInterval is how often the running totals of the footprint are
+// brought up to date.
+const footprintInterval = 5 * time.Minute
+
+// footprint is the resource footprint recorded in the footprint annotation
+// for chargeback. The size fields are derived from the spec; the hour totals
+// accumulate them over the time the VM was running.
+type footprint struct {
+	VCPU       int32   `json:"vcpu"`
+	MemoryGiB  float64 `json:"memoryGiB"`
+	StorageGiB float64 `json:"storageGiB"`
+
+	RunningSeconds  int64   `json:"runningSeconds"`
+	VCPUHours       float64 `json:"vcpuHours"`
+	MemoryGiBHours  float64 `json:"memoryGiBHours"`
+	StorageGiBHours float64 `json:"storageGiBHours"`
+
+	// Updated is when the totals were last brought up to date. It is unset
+	// while the VM is not running, so that time is not accounted.
+	Updated *metav1.Time `json:"updated,omitempty"`
+}
+
+// recordedFootprint returns the footprint recorded on the MachineRequest.
+func recordedFootprint(mr *butlerv1alpha1.MachineRequest) footprint {
+	var fp footprint
+	if value := mr.Annotations[AnnotationFootprint]; value != "" {
+		_ = json.Unmarshal([]byte(value), &fp)
+	}
+	return fp
+}
+
+// footprintSize derives the VM size of the footprint from the spec: the root
+// disk, which a container disk does not have, and the data disks.
+func footprintSize(fp *footprint, mr *butlerv1alpha1.MachineRequest) {
+	fp.VCPU = mr.Spec.CPU
+	fp.MemoryGiB = float64(mr.Spec.MemoryMB) / 1024
+	fp.StorageGiB = 0
+	opts, err := vmCreateOptions(mr)
+	if err != nil {
+		// Options were valid when the VM was created
+		return
+	}
+	if opts.ContainerDiskImage == "" {
+		fp.StorageGiB = float64(mr.Spec.DiskGB)
+		if !opts.DiskSize.IsZero() {
+			fp.StorageGiB = float64(opts.DiskSize.Value()) / (1 << 30)
+		}
+	}
+	for _, disk := range opts.DataDisks {
+		fp.StorageGiB += float64(disk.SizeGB)
+	}
+}
+
+// roundHours rounds an hour total to a microhour, keeping the JSON readable.
+func roundHours(hours float64) float64 {
+	return math.Round(hours*1e6) / 1e6
+}
+
+// accrue adds the time since the last update at the current size.
+func (fp *footprint) accrue(now time.Time) {
+	if fp.Updated == nil {
+		return
+	}
+	elapsed := now.Sub(fp.Updated.Time)
+	if elapsed <= 0 {
+		return
+	}
+	hours := elapsed.Hours()
+	fp.RunningSeconds += int64(elapsed.Seconds())
+	fp.VCPUHours = roundHours(fp.VCPUHours + float64(fp.VCPU)*hours)
+	fp.MemoryGiBHours = roundHours(fp.MemoryGiBHours + fp.MemoryGiB*hours)
+	fp.StorageGiBHours = roundHours(fp.StorageGiBHours + fp.StorageGiB*hours)
+}
+
+// updateFootprint brings the footprint annotation up to date. While the VM
+// runs, the totals are updated every footprintInterval and whenever the spec
+// size changes; once it stops, the totals are closed and accounting pauses
+// until it runs again.
+func (r *MachineRequestReconciler) updateFootprint(ctx context.Context, mr *butlerv1alpha1.MachineRequest, status *harvester.VMStatus) error {
+	fp := recordedFootprint(mr)
+	running := status != nil && status.Phase == "Running"
+	now := time.Now()
+
+	size := fp
+	footprintSize(&size, mr)
+	resized := size.VCPU != fp.VCPU || size.MemoryGiB != fp.MemoryGiB || size.StorageGiB != fp.StorageGiB
+	switch {
+	case running && fp.Updated == nil:
+	case running && !resized && now.Sub(fp.Updated.Time) < footprintInterval:
+		return nil
+	case !running && fp.Updated == nil && !resized && mr.Annotations[AnnotationFootprint] != "":
+		return nil
+	}
+
+	// Time since the last update is billed at the size it ran with
+	fp.accrue(now)
+	footprintSize(&fp, mr)
+	fp.Updated = nil
+	if running {
+		fp.Updated = &metav1.Time{Time: now.Truncate(time.Second)}
+	}
+	data, err := json.Marshal(fp)
+	if err != nil {
+		return err
+	}
+	logf.FromContext(ctx).V(1).Info("Updating resource footprint", "vcpuHours", fp.VCPUHours, "running", running)
+	return r.patchAnnotations(ctx, mr, map[string]string{AnnotationFootprint: string(data)})
+}
+
+// pauseFootprint closes the footprint totals of a VM that is going away, so
+// the time until a replacement runs is not accounted.
+func (r *MachineRequestReconciler) pauseFootprint(ctx context.Context, mr *butlerv1alpha1.MachineRequest) error {
+	if recordedFootprint(mr).Updated == nil {
+		return nil
+	}
+	return r.updateFootprint(ctx, mr, nil)
+}
+
+// closeFootprint accrues the footprint of a MachineRequest whose deletion
+// starts up to now and reports the final totals in a FootprintFinal event,
+// since the annotation goes away with the MachineRequest.
+func (r *MachineRequestReconciler) closeFootprint(ctx context.Context, mr *butlerv1alpha1.MachineRequest) error {
+	if mr.Annotations[AnnotationFootprint] == "" {
+		return nil
+	}
+	if err := r.pauseFootprint(ctx, mr); err != nil {
+		return err
+	}
+	r.Recorder.Eventf(mr, corev1.EventTypeNormal, "FootprintFinal", "Final resource footprint: %s", mr.Annotations[AnnotationFootprint])
+	return nil
+}
+
+// footprintUnchangedPredicate passes on MachineRequest annotation changes
+// other than the footprint, which the controller rewrites every
+// footprintInterval and which needs no reconcile of its own.
+func footprintUnchangedPredicate() predicate.Predicate {
+	return predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			if e.ObjectOld == nil || e.ObjectNew == nil {
+				return false
+			}
+			return !maps.Equal(withoutFootprint(e.ObjectOld), withoutFootprint(e.ObjectNew))
+		},
+	}
+}
+
+// withoutFootprint returns the annotations of obj other than the footprint.
+func withoutFootprint(obj client.Object) map[string]string {
+	annotations := maps.Clone(obj.GetAnnotations())
+	delete(annotations, AnnotationFootprint)
+	return annotations
+}
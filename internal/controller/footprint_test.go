@@ -0,0 +1,133 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	butlerv1alpha1 "github.com/butlerdotdev/butler-api/api/v1alpha1"
+	"github.com/butlerdotdev/butler-provider-harvester/internal/harvester"
+)
+
+var _ = Describe("Resource footprint", func() {
+	var (
+		ctx      context.Context
+		r        *MachineRequestReconciler
+		recorder *record.FakeRecorder
+		mr       *butlerv1alpha1.MachineRequest
+	)
+
+	running := &harvester.VMStatus{Phase: "Running"}
+
+	// withFootprint records fp on mr as if updated ago.
+	// Updated is stored to the second, so accrued totals may be up to a
+	// second's worth high.
+	const hoursTolerance = 0.01
+
+	withFootprint := func(fp footprint, ago time.Duration) {
+		if ago > 0 {
+			fp.Updated = &metav1.Time{Time: time.Now().Add(-ago)}
+		}
+		data, err := json.Marshal(fp)
+		Expect(err).NotTo(HaveOccurred())
+		mr.Annotations[AnnotationFootprint] = string(data)
+	}
+
+	stored := func() footprint {
+		got := &butlerv1alpha1.MachineRequest{}
+		Expect(r.Get(ctx, client.ObjectKeyFromObject(mr), got)).To(Succeed())
+		return recordedFootprint(got)
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		mr = testMachineRequest(nil)
+	})
+
+	DescribeTable("derives the size from the spec",
+		func(annotations map[string]string, storageGiB float64) {
+			mr = testMachineRequest(annotations)
+			var fp footprint
+			footprintSize(&fp, mr)
+			Expect(fp.VCPU).To(BeEquivalentTo(2))
+			Expect(fp.MemoryGiB).To(Equal(4.0))
+			Expect(fp.StorageGiB).To(Equal(storageGiB))
+		},
+		Entry("the root disk", nil, 20.0),
+		Entry("a container disk", map[string]string{"container-disk-image": "registry.example.com/os:1"}, 0.0),
+		Entry("data disks", map[string]string{"data-disks": "10,5:longhorn-ssd"}, 35.0),
+	)
+
+	It("starts accounting when the VM runs", func() {
+		r, _ = testReconciler(mr)
+		Expect(r.updateFootprint(ctx, mr, running)).To(Succeed())
+		fp := stored()
+		Expect(fp.Updated).NotTo(BeNil())
+		Expect(fp.VCPUHours).To(BeZero())
+	})
+
+	DescribeTable("accrues the time since the last update",
+		func(ago time.Duration, cpu int32, vcpuHours float64) {
+			withFootprint(footprint{VCPU: 2, MemoryGiB: 4, StorageGiB: 20}, ago)
+			mr.Spec.CPU = cpu
+			r, _ = testReconciler(mr)
+			Expect(r.updateFootprint(ctx, mr, running)).To(Succeed())
+			Expect(stored().VCPUHours).To(BeNumerically("~", vcpuHours, hoursTolerance))
+		},
+		Entry("not before the interval", time.Minute, int32(2), 0.0),
+		Entry("after the interval", time.Hour, int32(2), 2.0),
+		Entry("at the old size on a resize", 30*time.Minute, int32(4), 1.0),
+	)
+
+	It("pauses accounting while the VM is stopped", func() {
+		withFootprint(footprint{VCPU: 2, MemoryGiB: 4, StorageGiB: 20}, time.Hour)
+		r, _ = testReconciler(mr)
+		Expect(r.updateFootprint(ctx, mr, &harvester.VMStatus{Phase: "Stopped"})).To(Succeed())
+		fp := stored()
+		Expect(fp.Updated).To(BeNil())
+		Expect(fp.VCPUHours).To(BeNumerically("~", 2.0, hoursTolerance))
+	})
+
+	It("closes the totals when deletion starts", func() {
+		withFootprint(footprint{VCPU: 2, MemoryGiB: 4, StorageGiB: 20}, time.Hour)
+		r, recorder = testReconciler(mr)
+		Expect(r.closeFootprint(ctx, mr)).To(Succeed())
+		fp := stored()
+		Expect(fp.Updated).To(BeNil())
+		Expect(fp.MemoryGiBHours).To(BeNumerically("~", 4.0, hoursTolerance))
+		Expect(recorder.Events).To(Receive(ContainSubstring("FootprintFinal")))
+	})
+
+	DescribeTable("does not reconcile footprint updates",
+		func(key, value string, reconciled bool) {
+			updated := mr.DeepCopy()
+			updated.Annotations[key] = value
+			Expect(footprintUnchangedPredicate().Update(event.UpdateEvent{ObjectOld: mr, ObjectNew: updated})).To(Equal(reconciled))
+		},
+		Entry("the footprint", AnnotationFootprint, `{"vcpu":2}`, false),
+		Entry("another annotation", AnnotationRecreate, "true", true),
+	)
+})
@@ -0,0 +1,235 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	butlerv1alpha1 "github.com/butlerdotdev/butler-api/api/v1alpha1"
+	"github.com/butlerdotdev/butler-provider-harvester/internal/harvester"
+)
+
+// fieldMachineName is the immutable field key of spec.machineName.
+const fieldMachineName = "spec.machineName"
+
+// immutableField is a MachineRequest setting that cannot be applied to an
+// existing VM.
+type immutableField struct {
+	name string
+	// annotation is the MachineRequest annotation holding the field, if any.
+	annotation string
+	value      func(mr *butlerv1alpha1.MachineRequest) string
+}
+
+// annotationField returns an immutableField for a Harvester annotation,
+// keyed by the annotation name without the prefix.
+func annotationField(key string) immutableField {
+	return immutableField{
+		name:       strings.TrimPrefix(key, annotationPrefix),
+		annotation: key,
+		value:      func(mr *butlerv1alpha1.MachineRequest) string { return mr.Annotations[key] },
+	}
+}
+
+// immutableFields lists the settings baked into the VM or its root disk at
+// creation. Changing one only takes effect when the VM is recreated.
+var immutableFields = []immutableField{
+	{name: fieldMachineName, value: func(mr *butlerv1alpha1.MachineRequest) string { return mr.Spec.MachineName }},
+	{name: "spec.image", value: func(mr *butlerv1alpha1.MachineRequest) string { return mr.Spec.Image }},
+	annotationField(AnnotationImageSelector),
+	annotationField(AnnotationContainerDiskImage),
+	annotationField(AnnotationRootDiskImportURL),
+	annotationField(AnnotationFirmwareUUID),
+	annotationField(AnnotationFirmwareSerial),
+	annotationField(AnnotationFirmwareEFI),
+	annotationField(AnnotationSecureBoot),
+	annotationField(AnnotationSMBIOSManufacturer),
+	annotationField(AnnotationSMBIOSProduct),
+	annotationField(AnnotationCPUSockets),
+	annotationField(AnnotationCPUThreads),
+	annotationField(AnnotationDedicatedCPUPlacement),
+	annotationField(AnnotationIsolateEmulatorThread),
+	annotationField(AnnotationNUMACells),
+	annotationField(AnnotationMemoryRequestMB),
+	annotationField(AnnotationMemoryLimitMB),
+	annotationField(AnnotationCPURequestMillicores),
+	annotationField(AnnotationGPUs),
+	annotationField(AnnotationRuntimeClassName),
+	annotationField(AnnotationRootDiskPVCName),
+	{name: "spec.extraDisks", value: extraDisksField},
+	annotationField(AnnotationDataDisks),
+	annotationField(AnnotationAttachedDisks),
+	annotationField(AnnotationEphemeralScratchGB),
+	annotationField(AnnotationRootDiskSerial),
+	annotationField(AnnotationRootDiskShareable),
+	annotationField(AnnotationDiskBus),
+	annotationField(AnnotationVolumeMode),
+	annotationField(AnnotationStorageClass),
+	annotationField(AnnotationNetworkName),
+	annotationField(AnnotationNetworks),
+	annotationField(AnnotationNetworkBinding),
+	annotationField(AnnotationInterfaceACPIIndex),
+	annotationField(AnnotationInterfacePCIAddress),
+	annotationField(AnnotationStaticIP),
+	annotationField(AnnotationStaticIPGateway),
+	annotationField(AnnotationDNSServers),
+	annotationField(AnnotationDNSSearch),
+	annotationField(AnnotationClusterDNSDomain),
+	annotationField(AnnotationRoutes),
+	annotationField(AnnotationNetworkDataFormat),
+	annotationField(AnnotationSSHPublicKeys),
+}
+
+// extraDisksField renders spec.extraDisks for the immutable-fields record.
+func extraDisksField(mr *butlerv1alpha1.MachineRequest) string {
+	if len(mr.Spec.ExtraDisks) == 0 {
+		return ""
+	}
+	data, _ := json.Marshal(mr.Spec.ExtraDisks)
+	return string(data)
+}
+
+// immutableSnapshot returns the current value of every immutable field.
+func immutableSnapshot(mr *butlerv1alpha1.MachineRequest) string {
+	values := map[string]string{}
+	for _, field := range immutableFields {
+		values[field.name] = field.value(mr)
+	}
+	data, _ := json.Marshal(values)
+	return string(data)
+}
+
+// recordedImmutableFields returns the immutable field values recorded when
+// the VM was created, or nil if none were recorded.
+func recordedImmutableFields(mr *butlerv1alpha1.MachineRequest) map[string]string {
+	var values map[string]string
+	if err := json.Unmarshal([]byte(mr.Annotations[AnnotationImmutableFields]), &values); err != nil {
+		return nil
+	}
+	return values
+}
+
+// recordedMachineRequest returns a copy of mr with the immutable annotations
+// set back to the values recorded at creation, so options built from it
+// describe the existing VM rather than edits awaiting a recreate.
+func recordedMachineRequest(mr *butlerv1alpha1.MachineRequest) *butlerv1alpha1.MachineRequest {
+	recorded := recordedImmutableFields(mr)
+	out := mr.DeepCopy()
+	for _, field := range immutableFields {
+		original, ok := recorded[field.name]
+		if !ok || field.annotation == "" {
+			continue
+		}
+		if original == "" {
+			delete(out.Annotations, field.annotation)
+		} else {
+			out.Annotations[field.annotation] = original
+		}
+	}
+	return out
+}
+
+// vmName returns the name the VM was created with, which differs from
+// spec.machineName while an edit of it is pending a recreate.
+func vmName(mr *butlerv1alpha1.MachineRequest) string {
+	if name := recordedImmutableFields(mr)[fieldMachineName]; name != "" {
+		return name
+	}
+	return mr.Spec.MachineName
+}
+
+// changedImmutableFields describes each immutable field whose value differs
+// from the recorded one, in immutableFields order.
+func changedImmutableFields(mr *butlerv1alpha1.MachineRequest, recorded map[string]string) []string {
+	var changed []string
+	for _, field := range immutableFields {
+		original, ok := recorded[field.name]
+		if !ok {
+			continue
+		}
+		if field.value(mr) != original {
+			changed = append(changed, fmt.Sprintf("%s (was %q)", field.name, original))
+		}
+	}
+	return changed
+}
+
+// checkImmutableFields compares the immutable fields against the values
+// recorded at creation. Changes are reported with the ImmutableFieldChanged
+// condition instead of being applied, unless the recreate annotation opts in
+// to replacing the VM. It returns done when the caller must not continue,
+// because the VM is being recreated or can no longer be found under
+// spec.machineName.
+func (r *MachineRequestReconciler) checkImmutableFields(
+	ctx context.Context,
+	mr *butlerv1alpha1.MachineRequest,
+	hc *harvester.Client,
+) (result ctrl.Result, done bool, err error) {
+	recorded := recordedImmutableFields(mr)
+	if recorded == nil {
+		// Created before the fields were recorded; take the current values as baseline
+		return ctrl.Result{}, false, r.patchAnnotations(ctx, mr, map[string]string{AnnotationImmutableFields: immutableSnapshot(mr)})
+	}
+
+	changed := changedImmutableFields(mr, recorded)
+	if len(changed) == 0 {
+		if meta.RemoveStatusCondition(&mr.Status.Conditions, ConditionTypeImmutableFieldChanged) {
+			return ctrl.Result{}, false, r.updateStatus(ctx, mr)
+		}
+		return ctrl.Result{}, false, nil
+	}
+
+	if _, ok := mr.Annotations[AnnotationRecreate]; ok {
+		result, err := r.recreateVM(ctx, mr, hc)
+		return result, true, err
+	}
+
+	nameChanged := mr.Spec.MachineName != vmName(mr)
+	message := fmt.Sprintf("%s changed after the VM was created and cannot be applied to it; revert the change or set the %s annotation to recreate the VM",
+		strings.Join(changed, ", "), AnnotationRecreate)
+	existing := meta.FindStatusCondition(mr.Status.Conditions, ConditionTypeImmutableFieldChanged)
+	if existing == nil || existing.Status != metav1.ConditionTrue || existing.Message != message {
+		logf.FromContext(ctx).Info("Immutable fields changed after creation", "fields", changed)
+		meta.SetStatusCondition(&mr.Status.Conditions, metav1.Condition{
+			Type:               ConditionTypeImmutableFieldChanged,
+			Status:             metav1.ConditionTrue,
+			Reason:             ReasonImmutableFieldChanged,
+			Message:            message,
+			ObservedGeneration: mr.Generation,
+		})
+		if err := r.updateStatus(ctx, mr); err != nil {
+			return ctrl.Result{}, true, err
+		}
+		r.Recorder.Event(mr, corev1.EventTypeWarning, ReasonImmutableFieldChanged, message)
+	}
+
+	// The VM is looked up by spec.machineName, so monitoring it would report
+	// it deleted or adopt another VM
+	if nameChanged {
+		return ctrl.Result{RequeueAfter: r.requeueLong()}, true, nil
+	}
+	return ctrl.Result{}, false, nil
+}
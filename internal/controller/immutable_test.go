@@ -0,0 +1,155 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	butlerv1alpha1 "github.com/butlerdotdev/butler-api/api/v1alpha1"
+)
+
+var _ = Describe("Immutable fields", func() {
+	DescribeTable("reports a changed create-time setting",
+		func(key, original, edited string) {
+			mr := testMachineRequest(map[string]string{key: original})
+			mr.Annotations[AnnotationImmutableFields] = immutableSnapshot(mr)
+			recorded := recordedImmutableFields(mr)
+			Expect(changedImmutableFields(mr, recorded)).To(BeEmpty())
+
+			mr.Annotations[annotationPrefix+key] = edited
+			Expect(changedImmutableFields(mr, recorded)).To(ConsistOf(ContainSubstring(key)))
+		},
+		Entry("secure boot", "secure-boot", "true", "false"),
+		Entry("SMBIOS product", "smbios-product", "Butler", "Other"),
+		Entry("CPU sockets", "cpu-sockets", "1", "2"),
+		Entry("CPU threads", "cpu-threads", "1", "2"),
+		Entry("dedicated CPU placement", "dedicated-cpu-placement", "true", "false"),
+		Entry("memory request", "memory-request-mb", "2048", "4096"),
+		Entry("memory limit", "memory-limit-mb", "8192", "4096"),
+		Entry("GPUs", "gpus", "nvidia.com/GP104", ""),
+		Entry("runtime class", "runtime-class-name", "kata", "gvisor"),
+		Entry("root disk shareable", "root-disk-shareable", "true", "false"),
+		Entry("attached disks", "attached-disks", "gfs-data:shareable", "gfs-data"),
+		Entry("interface ACPI index", "interface-acpi-index", "1", "2"),
+		Entry("interface PCI address", "interface-pci-address", "0000:02:01.0", "0000:02:02.0"),
+		Entry("static IP", "static-ip", "10.0.0.5/24", "10.0.0.6/24"),
+		Entry("static IP gateway", "static-ip-gateway", "10.0.0.1", "10.0.0.254"),
+		Entry("DNS servers", "dns-servers", "10.0.0.2", "10.0.0.2,10.0.0.3"),
+		Entry("routes", "routes", "10.1.0.0/16 via 10.0.0.1", ""),
+		Entry("SSH public keys", "ssh-public-keys", "ssh-ed25519 AAAA user@a", "ssh-ed25519 BBBB user@b"),
+	)
+
+	It("ignores fields missing from an older record", func() {
+		mr := testMachineRequest(nil)
+		mr.Annotations[AnnotationImmutableFields] = `{"spec.machineName":"worker-0","spec.image":"default/image-abc12"}`
+		mr.Annotations[AnnotationRuntimeClassName] = "kata"
+		mr.Annotations[AnnotationGPUs] = "nvidia.com/GP104"
+
+		Expect(changedImmutableFields(mr, recordedImmutableFields(mr))).To(BeEmpty())
+	})
+
+	It("describes the original value", func() {
+		mr := testMachineRequest(map[string]string{"runtime-class-name": "kata"})
+		recorded := map[string]string{"spec.machineName": "worker-0", "runtime-class-name": "gvisor"}
+
+		Expect(changedImmutableFields(mr, recorded)).To(Equal([]string{`runtime-class-name (was "gvisor")`}))
+	})
+
+	Context("on a running VM", func() {
+		var (
+			ctx context.Context
+			mr  *butlerv1alpha1.MachineRequest
+		)
+
+		BeforeEach(func() {
+			ctx = context.Background()
+			mr = testMachineRequest(nil)
+			mr.Annotations[AnnotationImmutableFields] = immutableSnapshot(mr)
+			mr.Status.Phase = butlerv1alpha1.MachinePhaseRunning
+		})
+
+		// stored returns the ImmutableFieldChanged condition as persisted.
+		stored := func(r *MachineRequestReconciler) *metav1.Condition {
+			got := &butlerv1alpha1.MachineRequest{}
+			Expect(r.Get(ctx, client.ObjectKeyFromObject(mr), got)).To(Succeed())
+			return meta.FindStatusCondition(got.Status.Conditions, ConditionTypeImmutableFieldChanged)
+		}
+
+		It("reports an edit and keeps monitoring the VM", func() {
+			mr.Spec.Image = "default/image-other"
+			r, recorder := testReconciler(mr)
+
+			_, done, err := r.checkImmutableFields(ctx, mr, testHarvesterClient())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(done).To(BeFalse())
+			condition := stored(r)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+			Expect(condition.Message).To(ContainSubstring(`spec.image (was "default/image-abc12")`))
+			Expect(recorder.Events).To(Receive(ContainSubstring(ReasonImmutableFieldChanged)))
+
+			// Reporting the same edit again emits nothing new
+			_, _, err = r.checkImmutableFields(ctx, mr, testHarvesterClient())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(recorder.Events).To(BeEmpty())
+		})
+
+		It("clears the condition once the edit is reverted", func() {
+			mr.Spec.Image = "default/image-other"
+			r, _ := testReconciler(mr)
+			_, _, err := r.checkImmutableFields(ctx, mr, testHarvesterClient())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stored(r)).NotTo(BeNil())
+
+			mr.Spec.Image = "default/image-abc12"
+			_, done, err := r.checkImmutableFields(ctx, mr, testHarvesterClient())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(done).To(BeFalse())
+			Expect(stored(r)).To(BeNil())
+		})
+
+		It("stops monitoring the VM while the machine name differs", func() {
+			mr.Spec.MachineName = "worker-1"
+			r, _ := testReconciler(mr)
+
+			result, done, err := r.checkImmutableFields(ctx, mr, testHarvesterClient())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(done).To(BeTrue())
+			Expect(result.RequeueAfter).To(BeNumerically(">", 0))
+			Expect(stored(r).Message).To(ContainSubstring(`spec.machineName (was "worker-0")`))
+		})
+
+		It("records the current values when none were recorded", func() {
+			delete(mr.Annotations, AnnotationImmutableFields)
+			r, recorder := testReconciler(mr)
+
+			_, done, err := r.checkImmutableFields(ctx, mr, testHarvesterClient())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(done).To(BeFalse())
+			got := &butlerv1alpha1.MachineRequest{}
+			Expect(r.Get(ctx, client.ObjectKeyFromObject(mr), got)).To(Succeed())
+			Expect(got.Annotations[AnnotationImmutableFields]).To(Equal(immutableSnapshot(mr)))
+			Expect(recorder.Events).To(BeEmpty())
+		})
+	})
+})
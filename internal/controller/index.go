@@ -0,0 +1,124 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	butlerv1alpha1 "github.com/butlerdotdev/butler-api/api/v1alpha1"
+)
+
+// Field indexes registered on MachineRequests. The manager cache re-indexes
+// an object on every change, including status updates, so lookups track the
+// current status.ipAddress.
+const (
+	// IPAddressIndex indexes MachineRequests by status.ipAddress.
+	IPAddressIndex = "status.ipAddress"
+	// LabelIndex indexes MachineRequests by each "<key>=<value>" entry of
+	// spec.labels, the labels applied to the VM.
+	LabelIndex = "spec.labels"
+	// ProviderRefIndex indexes MachineRequests by the "<namespace>/<name>"
+	// of the ProviderConfig in spec.providerRef, defaulting the namespace to
+	// the MachineRequest's own.
+	ProviderRefIndex = "spec.providerRef"
+	// UserDataRefIndex indexes MachineRequests by each "<kind>/<name>" of the
+	// ConfigMaps and Secrets in their namespace that their user data is read
+	// from.
+	UserDataRefIndex = "userDataRef"
+	// SSHKeySecretIndex indexes MachineRequests by the name of their
+	// ssh-key-secret Secret in the Harvester namespace.
+	SSHKeySecretIndex = "sshKeySecret"
+)
+
+// ErrNoMachineRequestForIP is returned when no MachineRequest has the IP.
+var ErrNoMachineRequestForIP = errors.New("no MachineRequest has the IP address")
+
+// IndexFields registers the MachineRequest field indexes with the indexer.
+func IndexFields(ctx context.Context, indexer client.FieldIndexer) error {
+	if err := indexer.IndexField(ctx, &butlerv1alpha1.MachineRequest{}, IPAddressIndex, func(obj client.Object) []string {
+		mr := obj.(*butlerv1alpha1.MachineRequest)
+		if mr.Status.IPAddress == "" {
+			return nil
+		}
+		return []string{mr.Status.IPAddress}
+	}); err != nil {
+		return fmt.Errorf("failed to index %s: %w", IPAddressIndex, err)
+	}
+	if err := indexer.IndexField(ctx, &butlerv1alpha1.MachineRequest{}, LabelIndex, func(obj client.Object) []string {
+		mr := obj.(*butlerv1alpha1.MachineRequest)
+		values := make([]string, 0, len(mr.Spec.Labels))
+		for k, v := range mr.Spec.Labels {
+			values = append(values, LabelIndexValue(k, v))
+		}
+		return values
+	}); err != nil {
+		return fmt.Errorf("failed to index %s: %w", LabelIndex, err)
+	}
+	if err := indexer.IndexField(ctx, &butlerv1alpha1.MachineRequest{}, ProviderRefIndex, func(obj client.Object) []string {
+		return []string{providerConfigKey(obj.(*butlerv1alpha1.MachineRequest)).String()}
+	}); err != nil {
+		return fmt.Errorf("failed to index %s: %w", ProviderRefIndex, err)
+	}
+	if err := indexer.IndexField(ctx, &butlerv1alpha1.MachineRequest{}, UserDataRefIndex, func(obj client.Object) []string {
+		return userDataRefs(obj.(*butlerv1alpha1.MachineRequest))
+	}); err != nil {
+		return fmt.Errorf("failed to index %s: %w", UserDataRefIndex, err)
+	}
+	if err := indexer.IndexField(ctx, &butlerv1alpha1.MachineRequest{}, SSHKeySecretIndex, func(obj client.Object) []string {
+		if name := obj.GetAnnotations()[AnnotationSSHKeySecret]; name != "" {
+			return []string{name}
+		}
+		return nil
+	}); err != nil {
+		return fmt.Errorf("failed to index %s: %w", SSHKeySecretIndex, err)
+	}
+	return nil
+}
+
+// LabelIndexValue returns the LabelIndex value matching a VM label.
+func LabelIndexValue(key, value string) string {
+	return key + "=" + value
+}
+
+// UserDataRefIndexValue returns the UserDataRefIndex value matching a
+// ConfigMap or Secret in the MachineRequest namespace.
+func UserDataRefIndexValue(kind, name string) string {
+	return kind + "/" + name
+}
+
+// MachineRequestByIP returns the MachineRequest whose VM has the IP, in any
+// namespace. The reader must be backed by a cache with IndexFields
+// registered, such as the manager client.
+func MachineRequestByIP(ctx context.Context, c client.Reader, ip string) (*butlerv1alpha1.MachineRequest, error) {
+	var list butlerv1alpha1.MachineRequestList
+	if err := c.List(ctx, &list, client.MatchingFields{IPAddressIndex: ip}); err != nil {
+		return nil, err
+	}
+	switch len(list.Items) {
+	case 0:
+		return nil, fmt.Errorf("%w: %s", ErrNoMachineRequestForIP, ip)
+	case 1:
+		return &list.Items[0], nil
+	default:
+		// A released IP can briefly be reported by both its old and new VM
+		return nil, fmt.Errorf("IP address %s is reported by %d MachineRequests", ip, len(list.Items))
+	}
+}
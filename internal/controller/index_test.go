@@ -0,0 +1,107 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	butlerv1alpha1 "github.com/butlerdotdev/butler-api/api/v1alpha1"
+)
+
+// indexedMachineRequest returns a MachineRequest named name with the IP,
+// VM labels and annotations given.
+func indexedMachineRequest(name, ip string, labels, annotations map[string]string) *butlerv1alpha1.MachineRequest {
+	mr := testMachineRequest(annotations)
+	mr.Name = name
+	mr.UID = types.UID("uid-" + name)
+	mr.Spec.MachineName = name
+	mr.Spec.ProviderRef.Name = "harvester"
+	mr.Spec.Labels = labels
+	mr.Status.IPAddress = ip
+	return mr
+}
+
+var _ = Describe("MachineRequest indexes", func() {
+	var (
+		ctx context.Context
+		r   *MachineRequestReconciler
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		r, _ = testReconciler(
+			indexedMachineRequest("worker-0", "10.0.0.5", map[string]string{"role": "worker"}, map[string]string{
+				"user-data-configmap": "cloud-config",
+			}),
+			indexedMachineRequest("worker-1", "10.0.0.6", map[string]string{"role": "worker"}, map[string]string{
+				"cloud-init-group-secret": "etcd-cloud-init",
+			}),
+			indexedMachineRequest("db-0", "10.0.0.7", map[string]string{"role": "db"}, nil),
+			indexedMachineRequest("db-1", "10.0.0.7", nil, nil),
+			indexedMachineRequest("new-0", "", nil, nil),
+		)
+	})
+
+	DescribeTable("finds the MachineRequest of an IP",
+		func(ip, want, failure string) {
+			mr, err := MachineRequestByIP(ctx, r, ip)
+			if failure != "" {
+				Expect(err).To(MatchError(ContainSubstring(failure)))
+				return
+			}
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mr.Name).To(Equal(want))
+		},
+		Entry("a unique address", "10.0.0.5", "worker-0", ""),
+		Entry("an unknown address", "10.0.0.99", "", ErrNoMachineRequestForIP.Error()),
+		Entry("an address reported twice", "10.0.0.7", "", "reported by 2 MachineRequests"),
+	)
+
+	DescribeTable("lists MachineRequests by indexed field",
+		func(field, value string, want []string) {
+			list := &butlerv1alpha1.MachineRequestList{}
+			Expect(r.List(ctx, list, client.MatchingFields{field: value})).To(Succeed())
+			var names []string
+			for _, mr := range list.Items {
+				names = append(names, mr.Name)
+			}
+			Expect(names).To(ConsistOf(want))
+		},
+		Entry("VM label", LabelIndex, LabelIndexValue("role", "worker"), []string{"worker-0", "worker-1"}),
+		Entry("VM label value", LabelIndex, LabelIndexValue("role", "db"), []string{"db-0"}),
+		Entry("provider config in the own namespace", ProviderRefIndex, "butler-system/harvester",
+			[]string{"worker-0", "worker-1", "db-0", "db-1", "new-0"}),
+		Entry("user data ConfigMap", UserDataRefIndex, UserDataRefIndexValue("ConfigMap", "cloud-config"), []string{"worker-0"}),
+		Entry("cloud-init group Secret", UserDataRefIndex, UserDataRefIndexValue("Secret", "etcd-cloud-init"), []string{"worker-1"}),
+	)
+
+	It("tracks the address through status updates", func() {
+		mr := &butlerv1alpha1.MachineRequest{}
+		Expect(r.Get(ctx, types.NamespacedName{Namespace: "butler-system", Name: "new-0"}, mr)).To(Succeed())
+		mr.Status.IPAddress = "10.0.0.8"
+		Expect(r.Status().Update(ctx, mr)).To(Succeed())
+
+		found, err := MachineRequestByIP(ctx, r, "10.0.0.8")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(found.Name).To(Equal("new-0"))
+	})
+})
@@ -18,32 +18,66 @@ package controller
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
+	"golang.org/x/time/rate"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 
 	butlerv1alpha1 "github.com/butlerdotdev/butler-api/api/v1alpha1"
+	"github.com/butlerdotdev/butler-provider-harvester/internal/drain"
 	"github.com/butlerdotdev/butler-provider-harvester/internal/harvester"
+	"github.com/butlerdotdev/butler-provider-harvester/internal/metrics"
 )
 
 const (
 	finalizerName = "machinerequest.butler.butlerlabs.dev/harvester-finalizer"
 
-	// Requeue intervals.
-	requeueShort = 10 * time.Second
-	requeueLong  = 30 * time.Second
+	// Default requeue intervals.
+	defaultRequeueShort = 10 * time.Second
+	defaultRequeueLong  = 30 * time.Second
+	// requeueJitter is the largest random share added to requeue intervals,
+	// so VMs created together are not all reconciled at the same time.
+	requeueJitter = 0.1
+
+	// defaultCreateTimeout bounds how long a VM may stay in Creating.
+	defaultCreateTimeout = 15 * time.Minute
+	// defaultGuestUnresponsiveTimeout is how long the guest agent may stay
+	// disconnected before the guest is reported unresponsive.
+	defaultGuestUnresponsiveTimeout = 5 * time.Minute
+	// defaultProviderConfigGracePeriod is how long deletion waits for a
+	// missing ProviderConfig before the finalizer is removed.
+	defaultProviderConfigGracePeriod = 10 * time.Minute
+
+	// createSettleTime is how long deletion waits on a create that was
+	// started but never recorded as finished, in case it is still running.
+	createSettleTime = time.Minute
+
+	// requeueBlocked is the safety-net interval for requests that cannot
+	// progress without outside action. Harvester resources live on another
+	// cluster and cannot be watched, and MachineRequest edits re-trigger
+	// reconciliation immediately.
+	requeueBlocked = 10 * time.Minute
 )
 
 // MachineRequestReconciler reconciles a MachineRequest object
@@ -51,6 +85,49 @@ type MachineRequestReconciler struct {
 	client.Client
 	Scheme   *runtime.Scheme
 	Recorder record.EventRecorder
+
+	// Drain holds back new create/delete operations while drained. Optional.
+	Drain *drain.Switch
+
+	// APIReader reads Secrets and ConfigMaps from the API server, since the
+	// manager caches only their metadata. Defaults to the client.
+	APIReader client.Reader
+
+	// ProviderConfigGracePeriod is how long a deleting MachineRequest waits
+	// for its missing ProviderConfig before its finalizer is removed and the
+	// VM possibly orphaned. Defaults to defaultProviderConfigGracePeriod.
+	ProviderConfigGracePeriod time.Duration
+
+	// RequeueShort is how soon a MachineRequest waiting on Harvester, e.g.
+	// for a VM to get an IP, is reconciled again, and RequeueLong how often
+	// a settled one is re-checked. Both get up to 10% random jitter.
+	// Default to defaultRequeueShort and defaultRequeueLong.
+	RequeueShort time.Duration
+	RequeueLong  time.Duration
+
+	// Conditions renames the condition types and reasons written to
+	// MachineRequests. Optional; validated by SetupWithManager.
+	Conditions *ConditionVocabulary
+
+	// WatchNamespaces limits reconciliation to MachineRequests in these
+	// namespaces, so several controller instances can share a cluster. Empty
+	// means all namespaces. Validated by SetupWithManager.
+	WatchNamespaces []string
+
+	// Clients caches the Harvester client of each ProviderConfig, rebuilt
+	// when the ProviderConfig or its credentials secret changes. The last
+	// client is kept to clean up VMs after the ProviderConfig is gone.
+	Clients harvester.ClientCache
+
+	// deleteLimiters pace VM deletions per ProviderConfig so a mass teardown
+	// does not delete every root disk PVC at once.
+	deleteLimitersMu sync.Mutex
+	deleteLimiters   map[types.NamespacedName]*rate.Limiter
+
+	// deepChecks records when the deep checks of each running
+	// MachineRequest last ran.
+	deepChecksMu sync.Mutex
+	deepChecks   map[types.NamespacedName]deepCheck
 }
 
 // +kubebuilder:rbac:groups=butler.butlerlabs.dev,resources=machinerequests,verbs=get;list;watch;update;patch
@@ -59,6 +136,7 @@ type MachineRequestReconciler struct {
 // +kubebuilder:rbac:groups=butler.butlerlabs.dev,resources=providerconfigs,verbs=get;list;watch
 // +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
 // +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch
 
 // Reconcile handles MachineRequest reconciliation.
 func (r *MachineRequestReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
@@ -68,14 +146,20 @@ func (r *MachineRequestReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 	machineRequest := &butlerv1alpha1.MachineRequest{}
 	if err := r.Get(ctx, req.NamespacedName, machineRequest); err != nil {
 		if apierrors.IsNotFound(err) {
+			r.forgetDeepCheck(req.NamespacedName)
+			metrics.ForgetMachine(req.Namespace, req.Name)
 			return ctrl.Result{}, nil
 		}
 		return ctrl.Result{}, err
 	}
+	r.Conditions.toInternal(&machineRequest.Status)
 
 	// Get the ProviderConfig to check if this is a Harvester request
 	providerConfig, err := r.getProviderConfig(ctx, machineRequest)
 	if err != nil {
+		if apierrors.IsNotFound(err) && !machineRequest.DeletionTimestamp.IsZero() {
+			return r.reconcileDeleteWithoutProviderConfig(ctx, machineRequest)
+		}
 		log.Error(err, "Failed to get ProviderConfig")
 		return r.updateStatusError(ctx, machineRequest, "ProviderConfigError", err.Error())
 	}
@@ -85,6 +169,7 @@ func (r *MachineRequestReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 		log.V(1).Info("Skipping non-Harvester MachineRequest", "provider", providerConfig.Spec.Provider)
 		return ctrl.Result{}, nil
 	}
+	recordPhase(machineRequest)
 
 	// Create Harvester client
 	harvesterClient, err := r.createHarvesterClient(ctx, providerConfig)
@@ -93,8 +178,20 @@ func (r *MachineRequestReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 		return r.updateStatusError(ctx, machineRequest, "HarvesterClientError", err.Error())
 	}
 
+	// Deletions and recreates share the ProviderConfig's deletion rate
+	limit, err := deleteRate(providerConfig)
+	if err != nil && !machineRequest.DeletionTimestamp.IsZero() {
+		// Never hold up deletion on a bad setting
+		log.Error(err, "Invalid deletion rate, deleting without pacing")
+	}
+	r.configureDeleteLimiter(providerConfigKey(machineRequest), limit)
+
 	// Handle deletion
 	if !machineRequest.DeletionTimestamp.IsZero() {
+		if r.Drain.Drained() {
+			log.V(1).Info("Controller drained, deferring VM deletion")
+			return ctrl.Result{RequeueAfter: r.requeueLong()}, nil
+		}
 		return r.reconcileDelete(ctx, machineRequest, harvesterClient)
 	}
 
@@ -107,20 +204,156 @@ func (r *MachineRequestReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 		return ctrl.Result{Requeue: true}, nil
 	}
 
+	// Pools maintain a replica count instead of walking the phases
+	if isPool(machineRequest) {
+		return r.reconcilePool(ctx, machineRequest, harvesterClient)
+	}
+
+	// Settings baked into an existing VM are reported rather than applied
+	switch machineRequest.Status.Phase {
+	case butlerv1alpha1.MachinePhaseCreating, butlerv1alpha1.MachinePhaseRunning:
+		if result, done, err := r.checkImmutableFields(ctx, machineRequest, harvesterClient); done || err != nil {
+			return result, err
+		}
+	}
+
 	// Reconcile based on current phase
 	switch machineRequest.Status.Phase {
 	case "", butlerv1alpha1.MachinePhasePending:
+		// A wiped status must not lead to a duplicate VM
+		if machineRequest.Status.Phase == "" && machineRequest.Status.ProviderID == "" {
+			if result, adopted, err := r.adoptExistingVM(ctx, machineRequest, harvesterClient); adopted || err != nil {
+				return result, err
+			}
+		}
+		if r.Drain.Drained() {
+			log.V(1).Info("Controller drained, deferring VM creation")
+			return ctrl.Result{RequeueAfter: r.requeueLong()}, nil
+		}
 		return r.reconcilePending(ctx, machineRequest, harvesterClient)
 	case butlerv1alpha1.MachinePhaseCreating:
-		return r.reconcileCreating(ctx, machineRequest, harvesterClient)
+		return r.reconcileCreating(ctx, machineRequest, providerConfig, harvesterClient)
 	case butlerv1alpha1.MachinePhaseRunning:
-		return r.reconcileRunning(ctx, machineRequest, harvesterClient)
+		return r.reconcileRunning(ctx, machineRequest, providerConfig, harvesterClient)
 	case butlerv1alpha1.MachinePhaseFailed:
 		// Don't reconcile failed machines unless manually reset
+		if _, ok := machineRequest.Annotations[AnnotationRecreate]; ok {
+			return r.recreateVM(ctx, machineRequest, harvesterClient)
+		}
+		if _, ok := machineRequest.Annotations[AnnotationRetry]; ok {
+			return r.retryFailed(ctx, machineRequest, harvesterClient)
+		}
 		return ctrl.Result{}, nil
 	default:
-		log.Info("Unknown phase, resetting to Pending", "phase", machineRequest.Status.Phase)
-		return r.updatePhase(ctx, machineRequest, butlerv1alpha1.MachinePhasePending)
+		return r.recoverUnknownPhase(ctx, machineRequest, harvesterClient)
+	}
+}
+
+// adoptExistingVM rebuilds the status of a MachineRequest whose status was
+// lost, e.g. by an etcd restore, from the VM it already owns. Only a VM
+// stamped with the MachineRequest's UID is adopted. It reports whether the VM
+// was adopted.
+func (r *MachineRequestReconciler) adoptExistingVM(
+	ctx context.Context,
+	mr *butlerv1alpha1.MachineRequest,
+	hc *harvester.Client,
+) (ctrl.Result, bool, error) {
+	log := logf.FromContext(ctx)
+
+	status, err := hc.GetVMStatus(ctx, vmName(mr))
+	if apierrors.IsNotFound(err) {
+		return ctrl.Result{}, false, nil
+	}
+	if err != nil {
+		log.Error(err, "Failed to check for an existing VM before creating")
+		return ctrl.Result{RequeueAfter: r.requeueShort()}, true, nil
+	}
+	if status.OwnerUID != string(mr.UID) {
+		// Unowned and conflicting VMs are left to the create path
+		return ctrl.Result{}, false, nil
+	}
+
+	mr.Status.ProviderID = status.UID
+	mr.Status.IPAddress = status.IPAddress
+	mr.Status.IPAddresses = status.IPAddresses
+	mr.Status.MACAddress = status.MACAddress
+	mr.Status.Phase = butlerv1alpha1.MachinePhaseCreating
+	message := "Adopted existing VM after status loss"
+	if status.IPAddress != "" {
+		mr.Status.Phase = butlerv1alpha1.MachinePhaseRunning
+		meta.SetStatusCondition(&mr.Status.Conditions, metav1.Condition{
+			Type:               butlerv1alpha1.ConditionTypeReady,
+			Status:             metav1.ConditionTrue,
+			Reason:             butlerv1alpha1.ReasonRunning,
+			Message:            fmt.Sprintf("VM is running with IP %s", status.IPAddress),
+			ObservedGeneration: mr.Generation,
+		})
+	} else {
+		meta.SetStatusCondition(&mr.Status.Conditions, metav1.Condition{
+			Type:               butlerv1alpha1.ConditionTypeProgressing,
+			Status:             metav1.ConditionTrue,
+			Reason:             butlerv1alpha1.ReasonCreating,
+			Message:            message,
+			ObservedGeneration: mr.Generation,
+		})
+	}
+	now := metav1.Now()
+	mr.Status.LastUpdated = &now
+	mr.Status.ObservedGeneration = mr.Generation
+	if err := r.updateStatus(ctx, mr); err != nil {
+		return ctrl.Result{}, true, err
+	}
+
+	log.Info("Adopted existing VM after status loss", "providerID", status.UID, "phase", mr.Status.Phase, "ip", status.IPAddress)
+	r.Recorder.Eventf(mr, corev1.EventTypeNormal, "Adopted", "%s %s (provider ID %s)", message, vmName(mr), status.UID)
+	return ctrl.Result{RequeueAfter: r.requeueShort()}, true, nil
+}
+
+// recoverUnknownPhase re-derives the phase of a MachineRequest with an
+// unrecognized status phase from the live VM: Running if it has an IP,
+// Creating if it exists, and Pending only if there is no VM. Resetting a
+// created machine to Pending would create a second VM and orphan the first.
+func (r *MachineRequestReconciler) recoverUnknownPhase(
+	ctx context.Context,
+	mr *butlerv1alpha1.MachineRequest,
+	hc *harvester.Client,
+) (ctrl.Result, error) {
+	log := logf.FromContext(ctx)
+
+	status, err := hc.GetVMStatus(ctx, vmName(mr))
+	switch {
+	case apierrors.IsNotFound(err):
+		log.Info("Unknown phase and no VM exists, resetting to Pending", "phase", mr.Status.Phase)
+		return r.updatePhase(ctx, mr, butlerv1alpha1.MachinePhasePending)
+	case err != nil:
+		log.Error(err, "Unknown phase and VM state could not be read, retrying", "phase", mr.Status.Phase)
+		return ctrl.Result{RequeueAfter: r.requeueShort()}, nil
+	case isNameConflict(mr, status):
+		return r.setNameConflict(ctx, mr, status)
+	}
+
+	if mr.Status.ProviderID == "" {
+		mr.Status.ProviderID = status.UID
+	}
+	switch {
+	case status.IPAddress != "":
+		log.Info("Unknown phase and VM has an IP, resuming as Running", "phase", mr.Status.Phase, "ip", status.IPAddress)
+		mr.Status.IPAddress = status.IPAddress
+		mr.Status.IPAddresses = status.IPAddresses
+		if status.MACAddress != "" {
+			mr.Status.MACAddress = status.MACAddress
+		}
+		meta.SetStatusCondition(&mr.Status.Conditions, metav1.Condition{
+			Type:               butlerv1alpha1.ConditionTypeReady,
+			Status:             metav1.ConditionTrue,
+			Reason:             butlerv1alpha1.ReasonRunning,
+			Message:            fmt.Sprintf("VM is running with IP %s", status.IPAddress),
+			ObservedGeneration: mr.Generation,
+		})
+		return r.updatePhase(ctx, mr, butlerv1alpha1.MachinePhaseRunning)
+	default:
+		log.Info("Unknown phase and VM exists, resuming as Creating", "phase", mr.Status.Phase)
+		return r.updatePhase(ctx, mr, butlerv1alpha1.MachinePhaseCreating)
 	}
 }
 
@@ -131,32 +364,214 @@ func (r *MachineRequestReconciler) reconcilePending(
 	hc *harvester.Client,
 ) (ctrl.Result, error) {
 	log := logf.FromContext(ctx)
+
+	// Wait out the backoff from earlier failed creates; the annotation
+	// patches recording them trigger a reconcile straight away
+	if remaining := createBackoffRemaining(mr, time.Now()); remaining > 0 {
+		log.V(1).Info("Backing off VM create", "failures", createFailures(mr), "remaining", remaining)
+		return ctrl.Result{RequeueAfter: remaining}, nil
+	}
 	log.Info("Creating VM", "name", mr.Spec.MachineName)
 
-	opts := harvester.VMCreateOptions{
-		Name:        mr.Spec.MachineName,
-		CPU:         mr.Spec.CPU,
-		MemoryMB:    mr.Spec.MemoryMB,
-		DiskGB:      mr.Spec.DiskGB,
-		ImageName:   mr.Spec.Image,
-		UserData:    mr.Spec.UserData,
-		NetworkData: mr.Spec.NetworkData,
-		Labels:      mr.Spec.Labels,
+	// Start the create duration timer on the first pass through Pending
+	if _, ok := mr.Annotations[AnnotationPendingSince]; !ok {
+		if err := r.patchAnnotations(ctx, mr, map[string]string{
+			AnnotationPendingSince: time.Now().UTC().Format(time.RFC3339),
+		}); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	opts, err := vmCreateOptions(mr)
+	if err != nil {
+		log.Error(err, "Invalid MachineRequest options")
+		return r.updateStatusError(ctx, mr, butlerv1alpha1.ReasonInvalidConfiguration, err.Error())
+	}
+	resetProvisioningSteps(mr, opts)
+
+	userData, ready, err := r.groupUserData(ctx, mr)
+	if errors.Is(err, errUserDataSourceNotFound) {
+		return r.setBlocked(ctx, mr, ReasonUserDataNotFound, err.Error())
+	}
+	if err != nil {
+		log.Error(err, "Failed to render cloud-init group user data")
+		return r.updateStatusError(ctx, mr, butlerv1alpha1.ReasonInvalidConfiguration, err.Error())
+	}
+	if !ready {
+		log.Info("Waiting for lower-ordinal cloud-init group members to get an IP")
+		meta.SetStatusCondition(&mr.Status.Conditions, metav1.Condition{
+			Type:               butlerv1alpha1.ConditionTypeProgressing,
+			Status:             metav1.ConditionTrue,
+			Reason:             ReasonWaitingForGroupPeers,
+			Message:            "Waiting for lower-ordinal cloud-init group members to get an IP",
+			ObservedGeneration: mr.Generation,
+		})
+		if err := r.updateStatus(ctx, mr); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{RequeueAfter: r.requeueShort()}, nil
+	}
+	opts.UserData = userData
+
+	// Resolve an image selector here so the chosen image is recorded
+	if opts.ImageSelector != "" {
+		image, err := hc.ResolveImageSelector(ctx, opts)
+		if err != nil {
+			if errors.Is(err, harvester.ErrNoMatchingImage) {
+				return r.setBlocked(ctx, mr, ReasonNoMatchingImage, err.Error())
+			}
+			if errors.Is(err, harvester.ErrInvalidOptions) {
+				return r.updateStatusError(ctx, mr, butlerv1alpha1.ReasonInvalidConfiguration, err.Error())
+			}
+			log.Error(err, "Failed to resolve image selector")
+			return ctrl.Result{RequeueAfter: r.requeueShort()}, nil
+		}
+		log.Info("Resolved image selector", "selector", opts.ImageSelector, "image", image)
+		if err := r.patchAnnotations(ctx, mr, map[string]string{AnnotationResolvedImage: image}); err != nil {
+			return ctrl.Result{}, err
+		}
+		opts.ImageName = image
+		opts.ImageSelector, opts.ImageNamespace, opts.ImageSelectorOrder = "", "", ""
+	}
+
+	r.warnUnsupportedFeatures(ctx, mr, hc, opts)
+
+	dryRun, err := boolAnnotation(mr, AnnotationDryRun)
+	if err != nil {
+		return r.updateStatusError(ctx, mr, butlerv1alpha1.ReasonInvalidConfiguration, err.Error())
+	}
+	if dryRun {
+		return r.renderDryRun(ctx, mr, hc, opts)
+	}
+
+	if fits, err := r.checkCapacity(ctx, mr, hc, opts); err != nil || !fits {
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{RequeueAfter: r.requeueLong()}, nil
+	}
+
+	// Record the root disk PVC so later phases target the right claim, and
+	// mark the create before any Harvester resource exists so a delete that
+	// follows an interrupted create knows to clean up after it. The marker
+	// is kept across failed attempts and only cleared once a create succeeds:
+	// every annotation change triggers a reconcile, so setting and clearing
+	// it around each attempt would retry deferred creates in a hot loop
+	rootDisk := harvester.ResolveRootDiskPVCName(opts)
+	if opts.ContainerDiskImage != "" || opts.RestoreFromBackup != "" {
+		rootDisk = ""
+	}
+	marks := map[string]string{AnnotationRootDiskPVC: rootDisk}
+	if _, ok := mr.Annotations[AnnotationCreateStarted]; !ok {
+		marks[AnnotationCreateStarted] = time.Now().UTC().Format(time.RFC3339)
+	}
+	if err := r.patchAnnotations(ctx, mr, marks); err != nil {
+		return ctrl.Result{}, err
 	}
 
-	providerID, err := hc.CreateVM(ctx, opts)
+	providerID, created, err := hc.CreateVM(ctx, opts)
 	if err != nil {
 		if apierrors.IsAlreadyExists(err) {
-			// VM already exists, move to Creating phase to check status
+			// VM already exists, make sure it is ours before adopting it
+			if status, statusErr := hc.GetVMStatus(ctx, mr.Spec.MachineName); statusErr == nil && isNameConflict(mr, status) {
+				return r.setNameConflict(ctx, mr, status)
+			}
 			log.Info("VM already exists, checking status")
 			return r.updatePhase(ctx, mr, butlerv1alpha1.MachinePhaseCreating)
 		}
+		if errors.Is(err, harvester.ErrPreviousInstanceTerminating) {
+			log.Info("Waiting for previous VM instance to be deleted", "reason", err.Error())
+			meta.SetStatusCondition(&mr.Status.Conditions, metav1.Condition{
+				Type:               butlerv1alpha1.ConditionTypeProgressing,
+				Status:             metav1.ConditionTrue,
+				Reason:             ReasonRecreating,
+				Message:            err.Error(),
+				ObservedGeneration: mr.Generation,
+			})
+			if err := r.updateStatus(ctx, mr); err != nil {
+				return ctrl.Result{}, err
+			}
+			return ctrl.Result{RequeueAfter: r.requeueShort()}, nil
+		}
+		if errors.Is(err, harvester.ErrVMINotOwned) {
+			log.Error(err, "VMI of another owner holds the VM name")
+			return r.reportNameConflict(ctx, mr, err.Error())
+		}
+		if errors.Is(err, harvester.ErrNetworkNotFound) {
+			return r.setNetworkNotFound(ctx, mr, err.Error())
+		}
+		if errors.Is(err, harvester.ErrStaleDisk) {
+			log.Error(err, "Disk of a previous VM instance exists without a VM")
+			return r.updateStatusError(ctx, mr, ReasonStaleDisk, fmt.Sprintf(
+				"%v; it may hold the data of a VM deleted outside the controller. Set the %s annotation to delete it and clone a fresh disk",
+				err, AnnotationRecreate))
+		}
+		if errors.Is(err, harvester.ErrImageNotReady) {
+			log.Info("Waiting for image to finish importing", "reason", err.Error())
+			meta.SetStatusCondition(&mr.Status.Conditions, metav1.Condition{
+				Type:               butlerv1alpha1.ConditionTypeProgressing,
+				Status:             metav1.ConditionTrue,
+				Reason:             ReasonWaitingForImage,
+				Message:            err.Error(),
+				ObservedGeneration: mr.Generation,
+			})
+			if err := r.updateStatus(ctx, mr); err != nil {
+				return ctrl.Result{}, err
+			}
+			return ctrl.Result{RequeueAfter: r.requeueShort()}, nil
+		}
+		if errors.Is(err, harvester.ErrImageNotFound) {
+			log.Error(err, "Image not found")
+			return r.updateStatusError(ctx, mr, ReasonImageNotFound, err.Error())
+		}
+		if errors.Is(err, harvester.ErrImageImportFailed) {
+			log.Error(err, "Image failed to import")
+			return r.updateStatusError(ctx, mr, ReasonImageImportFailed, err.Error())
+		}
+		if errors.Is(err, harvester.ErrGPUUnavailable) {
+			log.Error(err, "GPU device unavailable")
+			return r.updateStatusError(ctx, mr, ReasonGPUUnavailable, err.Error())
+		}
+		if errors.Is(err, harvester.ErrGPUCapacity) {
+			return r.setGPUBlocked(ctx, mr, err.Error())
+		}
+		if errors.Is(err, harvester.ErrInvalidUserData) {
+			log.Error(err, "Invalid user data")
+			return r.updateStatusError(ctx, mr, ReasonInvalidUserData, err.Error())
+		}
+		if errors.Is(err, harvester.ErrInvalidOptions) {
+			log.Error(err, "Invalid VM options")
+			return r.updateStatusError(ctx, mr, butlerv1alpha1.ReasonInvalidConfiguration, err.Error())
+		}
 		log.Error(err, "Failed to create VM")
 		r.Recorder.Eventf(mr, corev1.EventTypeWarning, "CreateFailed", "Failed to create VM: %v", err)
-		return r.updateStatusError(ctx, mr, butlerv1alpha1.ReasonProviderError, err.Error())
+		return r.backOffCreate(ctx, mr, err)
+	}
+
+	effective, err := harvester.RedactedOptionsJSON(created)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if err := r.patchAnnotations(ctx, mr, map[string]string{
+		AnnotationUserDataHash:      userDataHash(opts.UserData),
+		AnnotationEffectiveOptions:  effective,
+		AnnotationImmutableFields:   immutableSnapshot(mr),
+		AnnotationCreatingSince:     time.Now().UTC().Format(time.RFC3339),
+		AnnotationCreateStarted:     "",
+		AnnotationCreateFailures:    "",
+		AnnotationLastCreateFailure: "",
+		AnnotationDryRunManifest:    "",
+	}); err != nil {
+		return ctrl.Result{}, err
 	}
 
+	completeProvisioningStep(mr, ConditionTypeImageValidated, fmt.Sprintf("Image %s validated", opts.ImageName))
+	completeProvisioningStep(mr, ConditionTypePVCCreated, fmt.Sprintf("Root disk PVC %s created", rootDisk))
+	completeProvisioningStep(mr, ConditionTypeVMCreated, fmt.Sprintf("VM %s created", mr.Spec.MachineName))
+
 	// Update status with provider ID and move to Creating phase
+	meta.RemoveStatusCondition(&mr.Status.Conditions, ConditionTypeNetworkNotFound)
+	meta.RemoveStatusCondition(&mr.Status.Conditions, ConditionTypeBlocked)
 	mr.Status.ProviderID = providerID
 	mr.Status.Phase = butlerv1alpha1.MachinePhaseCreating
 	mr.Status.FailureReason = ""
@@ -173,23 +588,111 @@ func (r *MachineRequestReconciler) reconcilePending(
 		ObservedGeneration: mr.Generation,
 	})
 
-	if err := r.Status().Update(ctx, mr); err != nil {
+	if err := r.updateStatus(ctx, mr); err != nil {
 		return ctrl.Result{}, err
 	}
 
 	r.Recorder.Event(mr, corev1.EventTypeNormal, "Created", "VM creation initiated")
-	return ctrl.Result{RequeueAfter: requeueShort}, nil
+	return ctrl.Result{RequeueAfter: r.requeueShort()}, nil
+}
+
+// warnUnsupportedFeatures emits a warning for each requested feature the
+// target cluster does not advertise. Detection is best-effort and never
+// blocks VM creation.
+func (r *MachineRequestReconciler) warnUnsupportedFeatures(
+	ctx context.Context,
+	mr *butlerv1alpha1.MachineRequest,
+	hc *harvester.Client,
+	opts harvester.VMCreateOptions,
+) {
+	log := logf.FromContext(ctx)
+
+	info, err := hc.GetClusterInfo(ctx)
+	if err != nil {
+		log.V(1).Info("Unable to detect Harvester capabilities", "error", err.Error())
+		return
+	}
+	log.V(1).Info("Detected Harvester cluster", "harvesterVersion", info.HarvesterVersion, "kubevirtVersion", info.KubeVirtVersion)
+
+	for _, feature := range info.UnsupportedFeatures(opts) {
+		r.Recorder.Eventf(mr, corev1.EventTypeWarning, "FeatureUnsupported",
+			"Requested %s is not enabled on the target cluster (KubeVirt %s)", feature, info.KubeVirtVersion)
+	}
+}
+
+// checkCapacity reports whether the VM fits in the cluster's free capacity,
+// setting the InsufficientCapacity condition when it does not. Capacity
+// detection is best-effort; when it fails the VM is created anyway.
+func (r *MachineRequestReconciler) checkCapacity(
+	ctx context.Context,
+	mr *butlerv1alpha1.MachineRequest,
+	hc *harvester.Client,
+	opts harvester.VMCreateOptions,
+) (bool, error) {
+	log := logf.FromContext(ctx)
+
+	// An existing VM is already counted in the allocated capacity
+	if _, err := hc.GetVM(ctx, opts.Name); err == nil {
+		return true, nil
+	}
+
+	capacity, err := hc.GetClusterCapacity(ctx)
+	if err != nil {
+		log.V(1).Info("Cluster capacity detection failed", "error", err.Error())
+		return true, nil
+	}
+
+	memory := harvester.MemoryRequest(opts)
+	disk := opts.DiskSize
+	if disk.IsZero() {
+		disk = *resource.NewQuantity(int64(opts.DiskGB)*1024*1024*1024, resource.BinarySI)
+	}
+	if opts.ContainerDiskImage != "" {
+		disk = resource.Quantity{}
+	}
+
+	if capacity.Fits(opts.CPU, memory, disk) {
+		meta.RemoveStatusCondition(&mr.Status.Conditions, ConditionTypeInsufficientCapacity)
+		return true, nil
+	}
+
+	freeCPU, freeMemory, freeStorage := capacity.CPU.Free(), capacity.Memory.Free(), capacity.Storage.Free()
+	message := fmt.Sprintf("No node has %d CPU and %s memory free with %s root disk (cluster free: %s CPU, %s memory, %s storage)",
+		opts.CPU, memory.String(), disk.String(), freeCPU.String(), freeMemory.String(), freeStorage.String())
+	log.Info("Insufficient cluster capacity, waiting", "message", message)
+	if !meta.IsStatusConditionTrue(mr.Status.Conditions, ConditionTypeInsufficientCapacity) {
+		r.Recorder.Event(mr, corev1.EventTypeWarning, ReasonInsufficientCapacity, message)
+	}
+	meta.SetStatusCondition(&mr.Status.Conditions, metav1.Condition{
+		Type:               ConditionTypeInsufficientCapacity,
+		Status:             metav1.ConditionTrue,
+		Reason:             ReasonInsufficientCapacity,
+		Message:            message,
+		ObservedGeneration: mr.Generation,
+	})
+	return false, r.updateStatus(ctx, mr)
 }
 
 // reconcileCreating handles the Creating phase - waits for IP.
 func (r *MachineRequestReconciler) reconcileCreating(
 	ctx context.Context,
 	mr *butlerv1alpha1.MachineRequest,
+	pc *butlerv1alpha1.ProviderConfig,
 	hc *harvester.Client,
 ) (ctrl.Result, error) {
 	log := logf.FromContext(ctx)
 	log.Info("Checking VM status", "name", mr.Spec.MachineName)
 
+	timeout, err := durationSetting(pc, mr, AnnotationCreateTimeout, defaultCreateTimeout)
+	if err != nil {
+		return r.updateStatusError(ctx, mr, butlerv1alpha1.ReasonInvalidConfiguration, err.Error())
+	}
+
+	// Restored VMs only exist once Harvester finishes the restore
+	if mr.Annotations[AnnotationRestoreFromBackup] != "" && mr.Status.ProviderID == "" {
+		return r.reconcileRestore(ctx, mr, hc, timeout)
+	}
+
 	status, err := hc.GetVMStatus(ctx, mr.Spec.MachineName)
 	if err != nil {
 		if apierrors.IsNotFound(err) {
@@ -197,18 +700,76 @@ func (r *MachineRequestReconciler) reconcileCreating(
 			log.Info("VM not found, returning to Pending phase")
 			return r.updatePhase(ctx, mr, butlerv1alpha1.MachinePhasePending)
 		}
+		// Other errors, such as a timed out call, say nothing about
+		// whether the VM exists
 		log.Error(err, "Failed to get VM status")
-		return ctrl.Result{RequeueAfter: requeueShort}, nil
+		return ctrl.Result{RequeueAfter: r.requeueShort()}, nil
 	}
 
 	log.V(1).Info("VM status", "ready", status.Ready, "phase", status.Phase, "ip", status.IPAddress)
 
+	if isNameConflict(mr, status) {
+		return r.setNameConflict(ctx, mr, status)
+	}
+	observeProvisioningSteps(mr, status)
+
+	// A VM waiting for a GPU that no node has free comes up once one is
+	// released, so it waits blocked instead of running into the timeout
+	if status.IPAddress == "" && mr.Annotations[AnnotationGPUs] != "" {
+		message, err := hc.GPUSchedulingFailure(ctx, mr.Spec.MachineName)
+		if err != nil {
+			log.Error(err, "Failed to check GPU scheduling")
+		} else if message != "" {
+			return r.setGPUBlocked(ctx, mr, message)
+		}
+	}
+
+	// Fail VMs that never came up; paused guests are waiting on the user
+	if status.IPAddress == "" && !status.Paused {
+		if expired, err := r.createTimedOut(ctx, mr, timeout); err != nil {
+			return ctrl.Result{}, err
+		} else if expired {
+			return r.failCreateTimeout(ctx, mr, hc, status, timeout)
+		}
+	}
+
+	// VMs with a persistent cloud-init disk stay halted until it is populated
+	populated, err := hc.EnsureCloudInitPopulated(ctx, mr.Spec.MachineName)
+	if err != nil {
+		log.Error(err, "Failed to populate cloud-init disk")
+		return r.updateStatusError(ctx, mr, butlerv1alpha1.ReasonProviderError, err.Error())
+	}
+	if !populated {
+		meta.SetStatusCondition(&mr.Status.Conditions, metav1.Condition{
+			Type:               butlerv1alpha1.ConditionTypeProgressing,
+			Status:             metav1.ConditionTrue,
+			Reason:             ReasonWaitingForCloudInit,
+			Message:            "Waiting for the persistent cloud-init disk to be populated",
+			ObservedGeneration: mr.Generation,
+		})
+		if err := r.updateStatus(ctx, mr); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{RequeueAfter: r.requeueShort()}, nil
+	}
+
+	// A paused guest never gets an IP, so report the pause instead of waiting
+	if status.Paused {
+		return r.reconcilePaused(ctx, mr, hc)
+	}
+	if err := r.clearUnpause(ctx, mr); err != nil {
+		return ctrl.Result{}, err
+	}
+
 	// Check if we have an IP address
 	if status.IPAddress != "" {
 		log.Info("VM is ready", "ip", status.IPAddress)
 
 		mr.Status.Phase = butlerv1alpha1.MachinePhaseRunning
+		meta.RemoveStatusCondition(&mr.Status.Conditions, ConditionTypeBlocked)
+		clearProvisioningSteps(mr)
 		mr.Status.IPAddress = status.IPAddress
+		mr.Status.IPAddresses = status.IPAddresses
 		mr.Status.MACAddress = status.MACAddress
 		now := metav1.Now()
 		mr.Status.LastUpdated = &now
@@ -228,7 +789,10 @@ func (r *MachineRequestReconciler) reconcileCreating(
 			ObservedGeneration: mr.Generation,
 		})
 
-		if err := r.Status().Update(ctx, mr); err != nil {
+		if err := r.updateStatus(ctx, mr); err != nil {
+			return ctrl.Result{}, err
+		}
+		if err := r.observeCreateDuration(ctx, mr); err != nil {
 			return ctrl.Result{}, err
 		}
 
@@ -236,6 +800,33 @@ func (r *MachineRequestReconciler) reconcileCreating(
 		return ctrl.Result{}, nil
 	}
 
+	// Report storage problems while the root disk is not bound
+	pvcStatus, err := hc.GetPVCStatus(ctx, rootDiskPVC(mr))
+	if err == nil && pvcStatus.Reason == harvester.PVCReasonBound {
+		completeProvisioningStep(mr, ConditionTypePVCBound, fmt.Sprintf("Root disk PVC %s bound", rootDiskPVC(mr)))
+	}
+	if err == nil && pvcStatus.Reason != harvester.PVCReasonBound {
+		if pvcStatus.Permanent() {
+			return r.setBlocked(ctx, mr, pvcStatus.Reason, "Root disk: "+pvcStatus.Message)
+		}
+		meta.RemoveStatusCondition(&mr.Status.Conditions, ConditionTypeBlocked)
+		changed := meta.SetStatusCondition(&mr.Status.Conditions, metav1.Condition{
+			Type:               butlerv1alpha1.ConditionTypeProgressing,
+			Status:             metav1.ConditionTrue,
+			Reason:             ReasonWaitingForStorage,
+			Message:            fmt.Sprintf("Root disk %s: %s", pvcStatus.Reason, pvcStatus.Message),
+			ObservedGeneration: mr.Generation,
+		})
+		// Warn once per storage problem rather than on every requeue
+		if changed && !pvcStatus.Expected() {
+			r.Recorder.Eventf(mr, corev1.EventTypeWarning, pvcStatus.Reason, "Root disk: %s", pvcStatus.Message)
+		}
+		if err := r.updateStatus(ctx, mr); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{RequeueAfter: r.requeueShort()}, nil
+	}
+
 	// Still waiting for IP, update condition and requeue
 	meta.SetStatusCondition(&mr.Status.Conditions, metav1.Condition{
 		Type:               butlerv1alpha1.ConditionTypeProgressing,
@@ -245,74 +836,848 @@ func (r *MachineRequestReconciler) reconcileCreating(
 		ObservedGeneration: mr.Generation,
 	})
 
-	if err := r.Status().Update(ctx, mr); err != nil {
+	if err := r.updateStatus(ctx, mr); err != nil {
 		return ctrl.Result{}, err
 	}
 
-	return ctrl.Result{RequeueAfter: requeueShort}, nil
+	return ctrl.Result{RequeueAfter: r.requeueShort()}, nil
 }
 
-// reconcileRunning handles the Running phase - monitors for drift.
-func (r *MachineRequestReconciler) reconcileRunning(
+// reconcileRestore waits for a VM restore from backup to complete, then
+// adopts the restored VM and records its UID as the provider ID.
+func (r *MachineRequestReconciler) reconcileRestore(
 	ctx context.Context,
 	mr *butlerv1alpha1.MachineRequest,
 	hc *harvester.Client,
+	timeout time.Duration,
 ) (ctrl.Result, error) {
 	log := logf.FromContext(ctx)
 
-	// Periodically verify the VM still exists and is running
-	status, err := hc.GetVMStatus(ctx, mr.Spec.MachineName)
+	restore, err := hc.GetRestoreStatus(ctx, mr.Spec.MachineName)
 	if err != nil {
 		if apierrors.IsNotFound(err) {
-			log.Info("VM no longer exists, marking as failed")
-			mr.SetFailure("VMDeleted", "VM was deleted externally")
-			if err := r.Status().Update(ctx, mr); err != nil {
-				return ctrl.Result{}, err
-			}
-			r.Recorder.Event(mr, corev1.EventTypeWarning, "VMDeleted", "VM was deleted externally")
-			return ctrl.Result{}, nil
+			log.Info("VM restore not found, returning to Pending phase")
+			return r.updatePhase(ctx, mr, butlerv1alpha1.MachinePhasePending)
 		}
-		return ctrl.Result{RequeueAfter: requeueLong}, nil
+		log.Error(err, "Failed to get VM restore status")
+		return ctrl.Result{RequeueAfter: r.requeueShort()}, nil
+	}
+	if restore.Failed {
+		return r.updateStatusError(ctx, mr, butlerv1alpha1.ReasonProviderError, "VM restore failed: "+restore.Message)
 	}
 
-	// Update IP if it changed
-	if status.IPAddress != "" && status.IPAddress != mr.Status.IPAddress {
-		log.Info("VM IP changed", "old", mr.Status.IPAddress, "new", status.IPAddress)
-		mr.Status.IPAddress = status.IPAddress
-		now := metav1.Now()
-		mr.Status.LastUpdated = &now
-		if err := r.Status().Update(ctx, mr); err != nil {
+	if !restore.Complete {
+		if expired, err := r.createTimedOut(ctx, mr, timeout); err != nil {
+			return ctrl.Result{}, err
+		} else if expired {
+			message := fmt.Sprintf("VM restore did not complete within %s: %s", timeout, restore.Message)
+			r.Recorder.Event(mr, corev1.EventTypeWarning, ReasonCreateTimeout, message)
+			mr.SetFailure(ReasonCreateTimeout, message)
+			metrics.ProviderErrors.WithLabelValues(ReasonCreateTimeout).Inc()
+			return ctrl.Result{}, r.updateStatus(ctx, mr)
+		}
+
+		message := "Restoring VM from backup"
+		if restore.Message != "" {
+			message += ": " + restore.Message
+		}
+		meta.SetStatusCondition(&mr.Status.Conditions, metav1.Condition{
+			Type:               butlerv1alpha1.ConditionTypeProgressing,
+			Status:             metav1.ConditionTrue,
+			Reason:             ReasonRestoring,
+			Message:            message,
+			ObservedGeneration: mr.Generation,
+		})
+		if err := r.updateStatus(ctx, mr); err != nil {
 			return ctrl.Result{}, err
 		}
+		return ctrl.Result{RequeueAfter: r.requeueShort()}, nil
 	}
 
-	return ctrl.Result{RequeueAfter: requeueLong}, nil
+	uid, err := hc.AdoptRestoredVM(ctx, mr.Spec.MachineName, string(mr.UID), mr.Namespace+"/"+mr.Name)
+	if err != nil {
+		log.Error(err, "Failed to adopt restored VM")
+		return ctrl.Result{RequeueAfter: r.requeueShort()}, nil
+	}
+	log.Info("VM restored from backup", "backup", mr.Annotations[AnnotationRestoreFromBackup])
+	r.Recorder.Eventf(mr, corev1.EventTypeNormal, "Restored", "VM restored from backup %s", mr.Annotations[AnnotationRestoreFromBackup])
+
+	mr.Status.ProviderID = uid
+	if err := r.updateStatus(ctx, mr); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: r.requeueShort()}, nil
 }
 
-// reconcileDelete handles VM deletion.
-func (r *MachineRequestReconciler) reconcileDelete(
-	ctx context.Context,
+// setNetworkNotFound keeps the request Pending while the network it would be
+// attached to does not exist, re-checking in case the network is created.
+func (r *MachineRequestReconciler) setNetworkNotFound(ctx context.Context, mr *butlerv1alpha1.MachineRequest, message string) (ctrl.Result, error) {
+	if !meta.IsStatusConditionTrue(mr.Status.Conditions, ConditionTypeNetworkNotFound) {
+		logf.FromContext(ctx).Info("Network not found, deferring VM creation", "message", message)
+		r.Recorder.Event(mr, corev1.EventTypeWarning, ReasonNetworkNotFound, message)
+	}
+	meta.SetStatusCondition(&mr.Status.Conditions, metav1.Condition{
+		Type:               ConditionTypeNetworkNotFound,
+		Status:             metav1.ConditionTrue,
+		Reason:             ReasonNetworkNotFound,
+		Message:            message,
+		ObservedGeneration: mr.Generation,
+	})
+	meta.SetStatusCondition(&mr.Status.Conditions, metav1.Condition{
+		Type:               butlerv1alpha1.ConditionTypeReady,
+		Status:             metav1.ConditionFalse,
+		Reason:             ReasonNetworkNotFound,
+		Message:            message,
+		ObservedGeneration: mr.Generation,
+	})
+	if err := r.updateStatus(ctx, mr); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: r.requeueLong()}, nil
+}
+
+// setBlocked records that the request cannot progress without outside action
+// and backs off to requeueBlocked instead of polling.
+func (r *MachineRequestReconciler) setBlocked(ctx context.Context, mr *butlerv1alpha1.MachineRequest, reason, message string) (ctrl.Result, error) {
+	if !meta.IsStatusConditionTrue(mr.Status.Conditions, ConditionTypeBlocked) {
+		logf.FromContext(ctx).Info("Request blocked until resolved externally", "reason", reason, "message", message)
+		r.Recorder.Event(mr, corev1.EventTypeWarning, reason, message)
+	}
+	meta.SetStatusCondition(&mr.Status.Conditions, metav1.Condition{
+		Type:               ConditionTypeBlocked,
+		Status:             metav1.ConditionTrue,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: mr.Generation,
+	})
+	if err := r.updateStatus(ctx, mr); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: requeueBlocked}, nil
+}
+
+// setGPUBlocked marks the request Blocked on GPU capacity. Unlike other
+// blocks it is rechecked at the long requeue interval, since GPUs are freed
+// by other VMs stopping rather than by edits to this request.
+func (r *MachineRequestReconciler) setGPUBlocked(ctx context.Context, mr *butlerv1alpha1.MachineRequest, message string) (ctrl.Result, error) {
+	result, err := r.setBlocked(ctx, mr, ReasonGPUUnavailable, message)
+	if err != nil {
+		return result, err
+	}
+	return ctrl.Result{RequeueAfter: r.requeueLong()}, nil
+}
+
+// observeCreateDuration records the time since the MachineRequest entered
+// Pending and stops the timer. MachineRequests that entered Pending before
+// the start time was recorded are not observed.
+func (r *MachineRequestReconciler) observeCreateDuration(ctx context.Context, mr *butlerv1alpha1.MachineRequest) error {
+	value, ok := mr.Annotations[AnnotationPendingSince]
+	if !ok {
+		return nil
+	}
+	if since, err := time.Parse(time.RFC3339, value); err == nil {
+		metrics.CreateDuration.Observe(time.Since(since).Seconds())
+	}
+	return r.patchAnnotations(ctx, mr, map[string]string{AnnotationPendingSince: ""})
+}
+
+// createTimedOut reports whether the VM has been in Creating for longer than
+// timeout. MachineRequests created before the start time was recorded start
+// counting now.
+func (r *MachineRequestReconciler) createTimedOut(ctx context.Context, mr *butlerv1alpha1.MachineRequest, timeout time.Duration) (bool, error) {
+	since, err := time.Parse(time.RFC3339, mr.Annotations[AnnotationCreatingSince])
+	if err != nil {
+		return false, r.patchAnnotations(ctx, mr, map[string]string{
+			AnnotationCreatingSince: time.Now().UTC().Format(time.RFC3339),
+		})
+	}
+	return time.Since(since) > timeout, nil
+}
+
+// failCreateTimeout moves a VM stuck in Creating to Failed, naming the last
+// observed VM and root disk state.
+func (r *MachineRequestReconciler) failCreateTimeout(
+	ctx context.Context,
+	mr *butlerv1alpha1.MachineRequest,
+	hc *harvester.Client,
+	status *harvester.VMStatus,
+	timeout time.Duration,
+) (ctrl.Result, error) {
+	message := fmt.Sprintf("VM did not become ready within %s (VM phase: %s", timeout, status.Phase)
+	if pvcStatus, err := hc.GetPVCStatus(ctx, rootDiskPVC(mr)); err == nil {
+		message += fmt.Sprintf(", root disk: %s", pvcStatus.Reason)
+		if pvcStatus.Message != "" {
+			message += ": " + pvcStatus.Message
+		}
+	}
+	message += ")"
+
+	logf.FromContext(ctx).Info("VM creation timed out", "timeout", timeout, "phase", status.Phase)
+	r.Recorder.Event(mr, corev1.EventTypeWarning, ReasonCreateTimeout, message)
+	mr.SetFailure(ReasonCreateTimeout, message)
+	metrics.ProviderErrors.WithLabelValues(ReasonCreateTimeout).Inc()
+	if err := r.updateStatus(ctx, mr); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{}, nil
+}
+
+// reconcilePaused reports a paused VM and resumes it when the unpause
+// annotation is set.
+func (r *MachineRequestReconciler) reconcilePaused(
+	ctx context.Context,
+	mr *butlerv1alpha1.MachineRequest,
+	hc *harvester.Client,
+) (ctrl.Result, error) {
+	log := logf.FromContext(ctx)
+
+	if _, ok := mr.Annotations[AnnotationUnpause]; ok {
+		log.Info("Unpausing VM", "name", mr.Spec.MachineName)
+		if err := hc.UnpauseVM(ctx, mr.Spec.MachineName); err != nil {
+			log.Error(err, "Failed to unpause VM")
+			r.Recorder.Eventf(mr, corev1.EventTypeWarning, "UnpauseFailed", "Failed to unpause VM: %v", err)
+			return ctrl.Result{RequeueAfter: r.requeueShort()}, nil
+		}
+		if err := r.patchAnnotations(ctx, mr, map[string]string{AnnotationUnpause: ""}); err != nil {
+			return ctrl.Result{}, err
+		}
+
+		meta.SetStatusCondition(&mr.Status.Conditions, metav1.Condition{
+			Type:               ConditionTypePaused,
+			Status:             metav1.ConditionFalse,
+			Reason:             ReasonUnpaused,
+			Message:            "VM was unpaused",
+			ObservedGeneration: mr.Generation,
+		})
+		if err := r.updateStatus(ctx, mr); err != nil {
+			return ctrl.Result{}, err
+		}
+		r.Recorder.Event(mr, corev1.EventTypeNormal, "Unpaused", "VM unpaused")
+		return ctrl.Result{RequeueAfter: r.requeueShort()}, nil
+	}
+
+	if !meta.IsStatusConditionTrue(mr.Status.Conditions, ConditionTypePaused) {
+		r.Recorder.Eventf(mr, corev1.EventTypeNormal, "Paused",
+			"VM started paused; set the %s annotation to resume it", AnnotationUnpause)
+	}
+	meta.SetStatusCondition(&mr.Status.Conditions, metav1.Condition{
+		Type:               ConditionTypePaused,
+		Status:             metav1.ConditionTrue,
+		Reason:             ReasonStartPaused,
+		Message:            fmt.Sprintf("VM is paused; set the %s annotation to resume it", AnnotationUnpause),
+		ObservedGeneration: mr.Generation,
+	})
+	if err := r.updateStatus(ctx, mr); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{RequeueAfter: r.requeueLong()}, nil
+}
+
+// clearUnpause removes the unpause annotation of a VM that is not paused. It
+// is left behind when the VM was resumed some other way or removing it failed
+// after the unpause, and would otherwise resume a later start-paused VM.
+func (r *MachineRequestReconciler) clearUnpause(ctx context.Context, mr *butlerv1alpha1.MachineRequest) error {
+	if _, ok := mr.Annotations[AnnotationUnpause]; !ok {
+		return nil
+	}
+	return r.patchAnnotations(ctx, mr, map[string]string{AnnotationUnpause: ""})
+}
+
+// reconcileRunning handles the Running phase - monitors for drift.
+func (r *MachineRequestReconciler) reconcileRunning(
+	ctx context.Context,
+	mr *butlerv1alpha1.MachineRequest,
+	pc *butlerv1alpha1.ProviderConfig,
+	hc *harvester.Client,
+) (ctrl.Result, error) {
+	log := logf.FromContext(ctx)
+
+	// Periodically verify the VM still exists and is running
+	_, refresh := mr.Annotations[AnnotationRefreshStatus]
+	getStatus := hc.GetVMStatus
+	if refresh {
+		getStatus = hc.RefreshVMStatus
+	}
+	status, err := getStatus(ctx, mr.Spec.MachineName)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			reason, message := "VMDeleted", "VM was deleted externally"
+			if status != nil && status.OrphanedVMI {
+				// The guest may still be running; deleting the request
+				// removes the stray VMI
+				reason, message = ReasonOrphanedVMI, "VM was deleted externally but its VMI is still present"
+			}
+			log.Info("VM no longer exists, marking as failed", "orphanedVMI", status != nil && status.OrphanedVMI)
+			if err := r.pauseFootprint(ctx, mr); err != nil {
+				return ctrl.Result{}, err
+			}
+			mr.SetFailure(reason, message)
+			metrics.ProviderErrors.WithLabelValues(reason).Inc()
+			if err := r.updateStatus(ctx, mr); err != nil {
+				return ctrl.Result{}, err
+			}
+			r.Recorder.Event(mr, corev1.EventTypeWarning, reason, message)
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{RequeueAfter: r.requeueLong()}, nil
+	}
+
+	if isNameConflict(mr, status) {
+		return r.setNameConflict(ctx, mr, status)
+	}
+
+	if _, ok := mr.Annotations[AnnotationRecreate]; ok {
+		return r.recreateVM(ctx, mr, hc)
+	}
+
+	if observed := observedAnnotations(mr, status); len(observed) > 0 {
+		if err := r.patchAnnotations(ctx, mr, observed); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	if err := r.clearUnpause(ctx, mr); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	power, err := desiredPowerState(mr)
+	if err != nil {
+		return r.updateStatusError(ctx, mr, butlerv1alpha1.ReasonInvalidConfiguration, err.Error())
+	}
+	if result, wait, err := r.reconcileSnapshots(ctx, mr, hc, status, power); wait || err != nil {
+		return result, err
+	}
+	if power == powerStateStopped {
+		return r.reconcileStopped(ctx, mr, hc, status)
+	}
+	if meta.FindStatusCondition(mr.Status.Conditions, ConditionTypeStopped) != nil {
+		return r.startStoppedVM(ctx, mr, hc)
+	}
+	if result, wait, err := r.reconcileMigration(ctx, mr, hc); wait || err != nil {
+		return result, err
+	}
+
+	if refresh {
+		log.Info("Refreshed VM status", "ip", status.IPAddress)
+		if err := r.patchAnnotations(ctx, mr, map[string]string{AnnotationRefreshStatus: ""}); err != nil {
+			return ctrl.Result{}, err
+		}
+		r.Recorder.Eventf(mr, corev1.EventTypeNormal, "StatusRefreshed", "Re-detected VM IP: %s", status.IPAddress)
+	}
+
+	deepInterval := r.runningDuration(ctx, pc, mr, AnnotationDeepCheckInterval, defaultDeepCheckInterval)
+	inPlaceResize := r.runningBool(ctx, pc, mr, AnnotationInPlaceResize, true)
+	changed := false
+	now := time.Now()
+	requeue := r.requeueLong()
+	if due, left := r.deepCheckDue(mr, deepInterval, now); due {
+		changed, err = r.runDeepChecks(ctx, mr, hc, status, inPlaceResize)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		r.recordDeepCheck(mr, now)
+		requeue = min(requeue, deepInterval)
+	} else {
+		requeue = min(requeue, left)
+		// Follow a hotplug resize without repeating the other deep checks
+		if resizing(mr) && inPlaceResize {
+			changed, err = r.checkResize(ctx, mr, hc)
+			if err != nil {
+				return ctrl.Result{}, err
+			}
+		} else if resizing(mr) {
+			changed = r.finishResizing(mr, "In-place resize is disabled")
+		}
+	}
+	if resizing(mr) {
+		requeue = min(requeue, r.requeueShort())
+	}
+
+	unresponsiveAfter := r.runningDuration(ctx, pc, mr, AnnotationGuestUnresponsiveTimeout, defaultGuestUnresponsiveTimeout)
+	agentChanged, err := r.checkGuestAgent(ctx, mr, status, unresponsiveAfter)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	changed = changed || agentChanged
+	changed = observeProvisioningSteps(mr, status) || changed
+	changed = clearProvisioningSteps(mr) || changed
+
+	if err := r.updateFootprint(ctx, mr, status); err != nil {
+		log.Error(err, "Failed to update resource footprint")
+	}
+
+	// Update the IPs if they changed
+	if status.IPAddress != "" && status.IPAddress != mr.Status.IPAddress {
+		log.Info("VM IP changed", "old", mr.Status.IPAddress, "new", status.IPAddress)
+		mr.Status.IPAddress = status.IPAddress
+		if status.MACAddress != "" {
+			mr.Status.MACAddress = status.MACAddress
+		}
+		changed = true
+	}
+	// Unlike the primary IP, the list follows the VM down to no addresses
+	if !slices.Equal(status.IPAddresses, mr.Status.IPAddresses) {
+		mr.Status.IPAddresses = status.IPAddresses
+		changed = true
+	}
+
+	if changed {
+		now := metav1.Now()
+		mr.Status.LastUpdated = &now
+		if err := r.updateStatus(ctx, mr); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	return ctrl.Result{RequeueAfter: requeue}, nil
+}
+
+// observedAnnotations returns the changes to the annotations reporting what
+// the VM status shows but the MachineRequest status has no fields for. The
+// guest OS is kept while the guest agent reports none, e.g. during a reboot.
+// The guest hostname is a deep check, see checkGuestHostname.
+func observedAnnotations(mr *butlerv1alpha1.MachineRequest, status *harvester.VMStatus) map[string]string {
+	observed := map[string]string{}
+	if mr.Annotations[AnnotationNode] != status.NodeName {
+		observed[AnnotationNode] = status.NodeName
+	}
+	if status.GuestOS != "" && mr.Annotations[AnnotationGuestOS] != status.GuestOS {
+		observed[AnnotationGuestOS] = status.GuestOS
+	}
+	return observed
+}
+
+// retryFailed clears the failure of a Failed MachineRequest and returns it to
+// Pending. An existing VM is kept: the create path picks it up again. An
+// unfinished restore from backup is deleted so the retry starts a new one.
+func (r *MachineRequestReconciler) retryFailed(
+	ctx context.Context,
+	mr *butlerv1alpha1.MachineRequest,
+	hc *harvester.Client,
+) (ctrl.Result, error) {
+	reason, message := mr.Status.FailureReason, mr.Status.FailureMessage
+	logf.FromContext(ctx).Info("Retrying failed MachineRequest", "failureReason", reason)
+
+	// A failed restore would be found again and fail the retry at once
+	if mr.Annotations[AnnotationRestoreFromBackup] != "" && mr.Status.ProviderID == "" {
+		if err := hc.DeleteRestore(ctx, mr.Spec.MachineName); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	if err := r.patchAnnotations(ctx, mr, map[string]string{
+		AnnotationRetry:             "",
+		AnnotationPendingSince:      "",
+		AnnotationCreateFailures:    "",
+		AnnotationLastCreateFailure: "",
+	}); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	mr.Status.Phase = butlerv1alpha1.MachinePhasePending
+	mr.Status.FailureReason = ""
+	mr.Status.FailureMessage = ""
+	now := metav1.Now()
+	mr.Status.LastUpdated = &now
+	meta.SetStatusCondition(&mr.Status.Conditions, metav1.Condition{
+		Type:               butlerv1alpha1.ConditionTypeProgressing,
+		Status:             metav1.ConditionTrue,
+		Reason:             ReasonRetrying,
+		Message:            fmt.Sprintf("Retrying after %s failure", reason),
+		ObservedGeneration: mr.Generation,
+	})
+	if err := r.updateStatus(ctx, mr); err != nil {
+		return ctrl.Result{}, err
+	}
+	r.Recorder.Eventf(mr, corev1.EventTypeNormal, ReasonRetrying,
+		"Failed phase reset through %s; previous failure %s: %s", AnnotationRetry, reason, message)
+	return ctrl.Result{Requeue: true}, nil
+}
+
+// recreateVM deletes the VM and its disks and returns the MachineRequest to
+// Pending so a fresh VM is cloned from the image. Disks left behind without
+// a VM, which the create path keeps, are deleted too. Like a deletion, it
+// waits while the controller is drained and for a slot under the
+// ProviderConfig deletion rate.
+func (r *MachineRequestReconciler) recreateVM(
+	ctx context.Context,
 	mr *butlerv1alpha1.MachineRequest,
 	hc *harvester.Client,
 ) (ctrl.Result, error) {
 	log := logf.FromContext(ctx)
-	log.Info("Deleting VM", "name", mr.Spec.MachineName)
+	if r.Drain.Drained() {
+		log.V(1).Info("Controller drained, deferring VM recreate")
+		return ctrl.Result{RequeueAfter: r.requeueLong()}, nil
+	}
+	if delay := r.deleteDelay(providerConfigKey(mr)); delay > 0 {
+		return r.setDeletionThrottled(ctx, mr, delay)
+	}
+	name := vmName(mr)
+	log.Info("Recreating VM", "name", name)
+
+	if err := r.pauseFootprint(ctx, mr); err != nil {
+		return ctrl.Result{}, err
+	}
+	err := hc.DeleteVM(ctx, name, harvester.DeleteVMOptions{OwnerUID: string(mr.UID)})
+	if apierrors.IsNotFound(err) {
+		err = hc.DeleteCreateLeftovers(ctx, name, rootDiskPVC(mr), string(mr.UID))
+	}
+	if err != nil {
+		log.Error(err, "Failed to delete VM for recreate")
+		r.Recorder.Eventf(mr, corev1.EventTypeWarning, "RecreateFailed", "Failed to delete VM: %v", err)
+		return ctrl.Result{RequeueAfter: r.requeueShort()}, nil
+	}
+	if err := r.patchAnnotations(ctx, mr, map[string]string{
+		AnnotationRecreate:                    "",
+		AnnotationImmutableFields:             "",
+		AnnotationGuestAgentSeen:              "",
+		AnnotationGuestAgentDisconnectedSince: "",
+		AnnotationNode:                        "",
+		AnnotationGuestHostname:               "",
+		AnnotationGuestOS:                     "",
+		AnnotationCreateFailures:              "",
+		AnnotationLastCreateFailure:           "",
+	}); err != nil {
+		return ctrl.Result{}, err
+	}
 
-	// Update phase to Deleting
+	mr.Status.Phase = butlerv1alpha1.MachinePhasePending
+	mr.Status.IPAddress = ""
+	mr.Status.IPAddresses = nil
+	mr.Status.MACAddress = ""
+	mr.Status.FailureReason = ""
+	mr.Status.FailureMessage = ""
+	now := metav1.Now()
+	mr.Status.LastUpdated = &now
+	meta.SetStatusCondition(&mr.Status.Conditions, metav1.Condition{
+		Type:               butlerv1alpha1.ConditionTypeProgressing,
+		Status:             metav1.ConditionTrue,
+		Reason:             ReasonRecreating,
+		Message:            "VM deleted for recreate",
+		ObservedGeneration: mr.Generation,
+	})
+	meta.RemoveStatusCondition(&mr.Status.Conditions, ConditionTypeCloudInitChanged)
+	meta.RemoveStatusCondition(&mr.Status.Conditions, ConditionTypeImmutableFieldChanged)
+	meta.RemoveStatusCondition(&mr.Status.Conditions, ConditionTypeGuestUnresponsive)
+	meta.RemoveStatusCondition(&mr.Status.Conditions, ConditionTypeBlocked)
+	if err := r.updateStatus(ctx, mr); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	r.Recorder.Event(mr, corev1.EventTypeNormal, ReasonRecreating, "VM deleted for recreate")
+	return ctrl.Result{RequeueAfter: r.requeueShort()}, nil
+}
+
+// checkGuestAgent tracks the guest agent connection of a running VM. Once an
+// agent has been seen, a disconnect lasting longer than unresponsiveAfter sets
+// the GuestUnresponsive condition; brief disconnects are ignored. It returns
+// whether the status conditions changed.
+func (r *MachineRequestReconciler) checkGuestAgent(
+	ctx context.Context,
+	mr *butlerv1alpha1.MachineRequest,
+	status *harvester.VMStatus,
+	unresponsiveAfter time.Duration,
+) (bool, error) {
+	if status.Phase != "Running" {
+		return false, nil
+	}
+
+	if status.AgentConnected {
+		updates := map[string]string{}
+		if mr.Annotations[AnnotationGuestAgentSeen] == "" {
+			updates[AnnotationGuestAgentSeen] = "true"
+		}
+		if _, ok := mr.Annotations[AnnotationGuestAgentDisconnectedSince]; ok {
+			updates[AnnotationGuestAgentDisconnectedSince] = ""
+		}
+		if len(updates) > 0 {
+			if err := r.patchAnnotations(ctx, mr, updates); err != nil {
+				return false, err
+			}
+		}
+		if meta.IsStatusConditionTrue(mr.Status.Conditions, ConditionTypeGuestUnresponsive) {
+			r.Recorder.Event(mr, corev1.EventTypeNormal, "GuestResponsive", "Guest agent reconnected")
+		}
+		return meta.RemoveStatusCondition(&mr.Status.Conditions, ConditionTypeGuestUnresponsive), nil
+	}
+
+	// Guests without an agent cannot be monitored
+	if mr.Annotations[AnnotationGuestAgentSeen] == "" {
+		return false, nil
+	}
+
+	since, err := time.Parse(time.RFC3339, mr.Annotations[AnnotationGuestAgentDisconnectedSince])
+	if err != nil {
+		return false, r.patchAnnotations(ctx, mr, map[string]string{
+			AnnotationGuestAgentDisconnectedSince: time.Now().UTC().Format(time.RFC3339),
+		})
+	}
+	if time.Since(since) < unresponsiveAfter || meta.IsStatusConditionTrue(mr.Status.Conditions, ConditionTypeGuestUnresponsive) {
+		return false, nil
+	}
+
+	message := fmt.Sprintf("Guest agent has been disconnected since %s", since.Format(time.RFC3339))
+	logf.FromContext(ctx).Info("Guest unresponsive", "disconnectedSince", since)
+	meta.SetStatusCondition(&mr.Status.Conditions, metav1.Condition{
+		Type:               ConditionTypeGuestUnresponsive,
+		Status:             metav1.ConditionTrue,
+		Reason:             ReasonGuestAgentDisconnected,
+		Message:            message,
+		ObservedGeneration: mr.Generation,
+	})
+	r.Recorder.Event(mr, corev1.EventTypeWarning, ConditionTypeGuestUnresponsive, message)
+	return true, nil
+}
+
+// runningDuration returns durationSetting for a setting that only tunes how
+// a running VM is watched. A malformed value does not fail a healthy
+// machine; the default is used and a warning event names the bad value.
+func (r *MachineRequestReconciler) runningDuration(
+	ctx context.Context,
+	pc *butlerv1alpha1.ProviderConfig,
+	mr *butlerv1alpha1.MachineRequest,
+	key string,
+	def time.Duration,
+) time.Duration {
+	d, err := durationSetting(pc, mr, key, def)
+	if err != nil {
+		r.warnInvalidSetting(ctx, mr, err, def)
+		return def
+	}
+	return d
+}
+
+// runningBool is runningDuration for a boolean setting.
+func (r *MachineRequestReconciler) runningBool(
+	ctx context.Context,
+	pc *butlerv1alpha1.ProviderConfig,
+	mr *butlerv1alpha1.MachineRequest,
+	key string,
+	def bool,
+) bool {
+	b, err := boolSetting(pc, mr, key, def)
+	if err != nil {
+		r.warnInvalidSetting(ctx, mr, err, def)
+		return def
+	}
+	return b
+}
+
+// warnInvalidSetting reports a malformed setting replaced by its default.
+func (r *MachineRequestReconciler) warnInvalidSetting(ctx context.Context, mr *butlerv1alpha1.MachineRequest, err error, def interface{}) {
+	logf.FromContext(ctx).Info("Ignoring invalid setting", "error", err.Error(), "default", def)
+	r.Recorder.Eventf(mr, corev1.EventTypeWarning, butlerv1alpha1.ReasonInvalidConfiguration, "%v; using the default %v", err, def)
+}
+
+// checkStorageBackend records the Longhorn replicas backing the root disk and
+// sets the Degraded condition while fewer replicas are healthy than desired.
+// Storage details are best-effort; when they cannot be read, nothing is
+// recorded. It runs with the deep checks, so the Longhorn reads are limited
+// to once per deep check interval. It returns whether the status conditions
+// changed.
+func (r *MachineRequestReconciler) checkStorageBackend(ctx context.Context, mr *butlerv1alpha1.MachineRequest, hc *harvester.Client) (bool, error) {
+	backend, err := hc.GetStorageBackend(ctx, rootDiskPVC(mr))
+	if err != nil {
+		logf.FromContext(ctx).V(1).Info("Failed to read root disk storage backend", "error", err.Error())
+		return false, nil
+	}
+	if backend == nil {
+		return false, nil
+	}
+	return r.recordStorageBackend(ctx, mr, backend)
+}
+
+// recordStorageBackend records backend on the MachineRequest for
+// checkStorageBackend. When the replicas could not be read, their health is
+// recorded as unknown and the Degraded condition is left as it is.
+func (r *MachineRequestReconciler) recordStorageBackend(ctx context.Context, mr *butlerv1alpha1.MachineRequest, backend *harvester.StorageBackend) (bool, error) {
+	replicas := fmt.Sprintf("%d/%d", backend.HealthyReplicas, backend.Replicas)
+	if backend.ReplicasUnknown {
+		replicas = fmt.Sprintf("unknown/%d", backend.Replicas)
+	}
+	nodes := strings.Join(backend.Nodes, ",")
+	if mr.Annotations[AnnotationRootDiskReplicas] != replicas || mr.Annotations[AnnotationRootDiskReplicaNodes] != nodes {
+		if err := r.patchAnnotations(ctx, mr, map[string]string{
+			AnnotationRootDiskReplicas:     replicas,
+			AnnotationRootDiskReplicaNodes: nodes,
+		}); err != nil {
+			return false, err
+		}
+	}
+	if backend.ReplicasUnknown {
+		return false, nil
+	}
+
+	if backend.HealthyReplicas >= backend.Replicas {
+		if meta.IsStatusConditionTrue(mr.Status.Conditions, butlerv1alpha1.ConditionTypeDegraded) {
+			r.Recorder.Eventf(mr, corev1.EventTypeNormal, "StorageHealthy", "Root disk volume %s has %s healthy replicas", backend.Volume, replicas)
+		}
+		return meta.RemoveStatusCondition(&mr.Status.Conditions, butlerv1alpha1.ConditionTypeDegraded), nil
+	}
+	message := fmt.Sprintf("Root disk volume %s has %s healthy replicas (robustness %s)", backend.Volume, replicas, backend.Robustness)
+	if existing := meta.FindStatusCondition(mr.Status.Conditions, butlerv1alpha1.ConditionTypeDegraded); existing != nil && existing.Message == message {
+		return false, nil
+	}
+	r.Recorder.Event(mr, corev1.EventTypeWarning, ReasonStorageDegraded, message)
+	meta.SetStatusCondition(&mr.Status.Conditions, metav1.Condition{
+		Type:               butlerv1alpha1.ConditionTypeDegraded,
+		Status:             metav1.ConditionTrue,
+		Reason:             ReasonStorageDegraded,
+		Message:            message,
+		ObservedGeneration: mr.Generation,
+	})
+	return true, nil
+}
+
+// checkUserDataDrift compares the desired user data against the hash recorded
+// at creation. Cloud-init only runs on first boot, so an edit is flagged with
+// a condition and an event rather than silently ignored. Cloud-init group
+// members whose rendering changed as peers got IPs have their NoCloud user
+// data updated in place instead. It returns whether the status conditions
+// changed.
+func (r *MachineRequestReconciler) checkUserDataDrift(ctx context.Context, mr *butlerv1alpha1.MachineRequest, hc *harvester.Client) (bool, error) {
+	log := logf.FromContext(ctx)
+
+	desired, ready, err := r.groupUserData(ctx, mr)
+	if err != nil {
+		log.Error(err, "Failed to render cloud-init group user data")
+		return false, nil
+	}
+	if !ready {
+		return false, nil
+	}
+
+	current := userDataHash(desired)
+	recorded, ok := mr.Annotations[AnnotationUserDataHash]
+	if !ok {
+		// Created before the hash was recorded; take the current value as baseline
+		return false, r.patchAnnotations(ctx, mr, map[string]string{AnnotationUserDataHash: current})
+	}
+
+	if recorded == current {
+		return meta.RemoveStatusCondition(&mr.Status.Conditions, ConditionTypeCloudInitChanged), nil
+	}
+
+	if persistent, _ := boolAnnotation(mr, AnnotationPersistentCloudInit); mr.Annotations[AnnotationCloudInitGroup] != "" && !persistent {
+		if err := hc.UpdateCloudInitUserData(ctx, mr.Spec.MachineName, desired, linesAnnotation(mr, AnnotationSSHPublicKeys)); err != nil {
+			log.Error(err, "Failed to update cloud-init group user data")
+			return false, nil
+		}
+		log.Info("Updated cloud-init group user data")
+		r.Recorder.Event(mr, corev1.EventTypeNormal, "GroupCloudInitUpdated",
+			"Re-rendered cloud-init group user data; it takes effect the next time the VM starts")
+		return false, r.patchAnnotations(ctx, mr, map[string]string{AnnotationUserDataHash: current})
+	}
+
+	if meta.IsStatusConditionTrue(mr.Status.Conditions, ConditionTypeCloudInitChanged) {
+		return false, nil
+	}
+
+	logf.FromContext(ctx).Info("User data changed after creation, VM must be recreated to apply it")
+	meta.SetStatusCondition(&mr.Status.Conditions, metav1.Condition{
+		Type:               ConditionTypeCloudInitChanged,
+		Status:             metav1.ConditionTrue,
+		Reason:             ReasonCloudInitChangedRequiresRecreate,
+		Message:            fmt.Sprintf("User data changed after the VM was created; set the %s annotation to apply it", AnnotationRecreate),
+		ObservedGeneration: mr.Generation,
+	})
+	r.Recorder.Eventf(mr, corev1.EventTypeWarning, ReasonCloudInitChangedRequiresRecreate,
+		"User data changed after the VM was created and will not take effect until the machine is recreated (set the %s annotation)", AnnotationRecreate)
+	return true, nil
+}
+
+// reconcileDelete handles VM deletion.
+func (r *MachineRequestReconciler) reconcileDelete(
+	ctx context.Context,
+	mr *butlerv1alpha1.MachineRequest,
+	hc *harvester.Client,
+) (ctrl.Result, error) {
+	log := logf.FromContext(ctx)
+
+	if isPool(mr) {
+		return r.reconcilePoolDelete(ctx, mr, hc)
+	}
+
+	// Respect teardown ordering within a group
+	peers, err := r.pendingTeardownPeers(ctx, mr)
+	if err != nil {
+		log.Error(err, "Failed to check teardown group")
+		return ctrl.Result{RequeueAfter: r.requeueShort()}, nil
+	}
+	if len(peers) > 0 {
+		log.Info("Waiting for teardown group peers", "peers", peers)
+		meta.SetStatusCondition(&mr.Status.Conditions, metav1.Condition{
+			Type:               butlerv1alpha1.ConditionTypeProgressing,
+			Status:             metav1.ConditionTrue,
+			Reason:             ReasonWaitingForTeardownOrder,
+			Message:            fmt.Sprintf("Waiting for %v to finish deleting", peers),
+			ObservedGeneration: mr.Generation,
+		})
+		if err := r.updateStatus(ctx, mr); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{RequeueAfter: r.requeueShort()}, nil
+	}
+
+	// A create interrupted by a restart or leader handover may still be
+	// running elsewhere; give it time to finish or fail before cleaning up
+	if started, err := time.Parse(time.RFC3339, mr.Annotations[AnnotationCreateStarted]); err == nil {
+		if remaining := createSettleTime - time.Since(started); remaining > 0 {
+			log.Info("Waiting for in-flight VM creation before deleting", "remaining", remaining)
+			meta.SetStatusCondition(&mr.Status.Conditions, metav1.Condition{
+				Type:               butlerv1alpha1.ConditionTypeProgressing,
+				Status:             metav1.ConditionTrue,
+				Reason:             ReasonWaitingForCreate,
+				Message:            "Waiting for an in-flight VM creation to settle before deleting",
+				ObservedGeneration: mr.Generation,
+			})
+			if err := r.updateStatus(ctx, mr); err != nil {
+				return ctrl.Result{}, err
+			}
+			return ctrl.Result{RequeueAfter: remaining}, nil
+		}
+	}
+
+	name := vmName(mr)
+	log.Info("Deleting VM", "name", name)
+
+	// Update phase to Deleting, closing the footprint once
 	if mr.Status.Phase != butlerv1alpha1.MachinePhaseDeleting {
+		if err := r.closeFootprint(ctx, mr); err != nil {
+			return ctrl.Result{}, err
+		}
 		mr.Status.Phase = butlerv1alpha1.MachinePhaseDeleting
 		now := metav1.Now()
 		mr.Status.LastUpdated = &now
-		if err := r.Status().Update(ctx, mr); err != nil {
+		if err := r.updateStatus(ctx, mr); err != nil {
 			return ctrl.Result{}, err
 		}
 	}
 
-	// Delete the VM
-	if err := hc.DeleteVM(ctx, mr.Spec.MachineName); err != nil {
-		if !apierrors.IsNotFound(err) {
+	// Delete the VM, unless it belongs to another MachineRequest
+	if status, err := hc.GetVMStatus(ctx, name); err == nil && isNameConflict(mr, status) {
+		log.Info("VM is owned by another MachineRequest, leaving it in place", "owner", status.Owner)
+		r.Recorder.Eventf(mr, corev1.EventTypeWarning, ReasonNameConflict,
+			"Not deleting VM %s: it is owned by MachineRequest %s", name, status.Owner)
+	} else {
+		if delay := r.deleteDelay(providerConfigKey(mr)); delay > 0 {
+			return r.setDeletionThrottled(ctx, mr, delay)
+		}
+		deleteSnapshots, err := boolAnnotation(mr, AnnotationDeleteSnapshots)
+		if err != nil {
+			log.Error(err, "Keeping VM snapshots")
+		}
+		if err := hc.DeleteVM(ctx, name, harvester.DeleteVMOptions{Snapshots: deleteSnapshots, OwnerUID: string(mr.UID)}); err != nil && !apierrors.IsNotFound(err) {
 			log.Error(err, "Failed to delete VM")
-			return ctrl.Result{RequeueAfter: requeueShort}, nil
+			return ctrl.Result{RequeueAfter: r.requeueShort()}, nil
+		}
+		// An interrupted create may have left disks without a VM
+		if _, ok := mr.Annotations[AnnotationCreateStarted]; ok {
+			log.Info("Cleaning up after interrupted VM creation")
+			if err := hc.DeleteCreateLeftovers(ctx, name, rootDiskPVC(mr), string(mr.UID)); err != nil {
+				log.Error(err, "Failed to clean up after interrupted VM creation")
+				return ctrl.Result{RequeueAfter: r.requeueShort()}, nil
+			}
 		}
 	}
 
@@ -322,25 +1687,173 @@ func (r *MachineRequestReconciler) reconcileDelete(
 		return ctrl.Result{}, err
 	}
 
+	metrics.ForgetMachine(mr.Namespace, mr.Name)
+	if mr.DeletionTimestamp != nil {
+		metrics.DeleteDuration.Observe(time.Since(mr.DeletionTimestamp.Time).Seconds())
+	}
 	log.Info("VM deleted successfully")
 	r.Recorder.Event(mr, corev1.EventTypeNormal, "Deleted", "VM deleted")
 	return ctrl.Result{}, nil
 }
 
+// setDeletionThrottled reports that the deletion is waiting for a slot under
+// the ProviderConfig's deletion rate and requeues it.
+func (r *MachineRequestReconciler) setDeletionThrottled(ctx context.Context, mr *butlerv1alpha1.MachineRequest, delay time.Duration) (ctrl.Result, error) {
+	logf.FromContext(ctx).V(1).Info("Deletion paced by the ProviderConfig deletion rate", "retryAfter", delay)
+	meta.SetStatusCondition(&mr.Status.Conditions, metav1.Condition{
+		Type:               butlerv1alpha1.ConditionTypeProgressing,
+		Status:             metav1.ConditionTrue,
+		Reason:             ReasonDeletionThrottled,
+		Message:            fmt.Sprintf("Waiting %s for a slot under the ProviderConfig deletion rate", delay.Round(time.Second)),
+		ObservedGeneration: mr.Generation,
+	})
+	if err := r.updateStatus(ctx, mr); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: delay}, nil
+}
+
+// reconcileDeleteWithoutProviderConfig deletes a MachineRequest whose
+// ProviderConfig no longer exists. The VM is deleted with the last known
+// client for the ProviderConfig when one is cached; otherwise the finalizer
+// is removed after the grace period, possibly orphaning the VM. The cache is
+// in memory only, so a controller restart loses it.
+func (r *MachineRequestReconciler) reconcileDeleteWithoutProviderConfig(ctx context.Context, mr *butlerv1alpha1.MachineRequest) (ctrl.Result, error) {
+	log := logf.FromContext(ctx)
+	key := providerConfigKey(mr)
+
+	if !controllerutil.ContainsFinalizer(mr, finalizerName) {
+		return ctrl.Result{}, nil
+	}
+
+	if hc := r.Clients.Last(key); hc != nil {
+		log.Info("ProviderConfig not found, deleting VM with last known configuration", "providerConfig", key)
+		if r.Drain.Drained() {
+			return ctrl.Result{RequeueAfter: r.requeueLong()}, nil
+		}
+		result, err := r.reconcileDelete(ctx, mr, hc)
+		if err == nil && !controllerutil.ContainsFinalizer(mr, finalizerName) {
+			err = r.forgetUnusedClient(ctx, key)
+		}
+		return result, err
+	}
+
+	since, err := time.Parse(time.RFC3339, mr.Annotations[AnnotationProviderConfigMissingSince])
+	if err != nil {
+		r.Recorder.Eventf(mr, corev1.EventTypeWarning, "ProviderConfigMissing",
+			"ProviderConfig %s not found and no configuration for it is cached (the cache does not survive a controller restart); the finalizer will be removed after %s and the VM may be orphaned",
+			key, r.providerConfigGracePeriod())
+		return ctrl.Result{RequeueAfter: r.requeueLong()}, r.patchAnnotations(ctx, mr, map[string]string{
+			AnnotationProviderConfigMissingSince: time.Now().UTC().Format(time.RFC3339),
+		})
+	}
+	if remaining := r.providerConfigGracePeriod() - time.Since(since); remaining > 0 {
+		log.Info("ProviderConfig not found, waiting before removing finalizer", "providerConfig", key, "remaining", remaining)
+		return ctrl.Result{RequeueAfter: min(remaining, r.requeueLong())}, nil
+	}
+
+	log.Info("ProviderConfig not found after grace period, removing finalizer", "providerConfig", key)
+	r.Recorder.Eventf(mr, corev1.EventTypeWarning, "Orphaned",
+		"ProviderConfig %s not found; removed finalizer without deleting VM %s, which may be orphaned", key, vmName(mr))
+	controllerutil.RemoveFinalizer(mr, finalizerName)
+	if err := r.Update(ctx, mr); err != nil {
+		return ctrl.Result{}, err
+	}
+	metrics.ForgetMachine(mr.Namespace, mr.Name)
+	return ctrl.Result{}, r.forgetUnusedClient(ctx, key)
+}
+
+// forgetUnusedClient drops the cached client of a deleted ProviderConfig once
+// no MachineRequest still holding the finalizer refers to it.
+func (r *MachineRequestReconciler) forgetUnusedClient(ctx context.Context, key types.NamespacedName) error {
+	list := &butlerv1alpha1.MachineRequestList{}
+	if err := r.List(ctx, list, client.MatchingFields{ProviderRefIndex: key.String()}); err != nil {
+		return err
+	}
+	for i := range list.Items {
+		if controllerutil.ContainsFinalizer(&list.Items[i], finalizerName) {
+			return nil
+		}
+	}
+	r.Clients.Forget(key)
+	return nil
+}
+
 // Helper methods
 
-func (r *MachineRequestReconciler) getProviderConfig(ctx context.Context, mr *butlerv1alpha1.MachineRequest) (*butlerv1alpha1.ProviderConfig, error) {
-	pc := &butlerv1alpha1.ProviderConfig{}
+func (r *MachineRequestReconciler) providerConfigGracePeriod() time.Duration {
+	if r.ProviderConfigGracePeriod > 0 {
+		return r.ProviderConfigGracePeriod
+	}
+	return defaultProviderConfigGracePeriod
+}
+
+func (r *MachineRequestReconciler) requeueShort() time.Duration {
+	interval := defaultRequeueShort
+	if r.RequeueShort > 0 {
+		interval = r.RequeueShort
+	}
+	return wait.Jitter(interval, requeueJitter)
+}
+
+func (r *MachineRequestReconciler) requeueLong() time.Duration {
+	interval := defaultRequeueLong
+	if r.RequeueLong > 0 {
+		interval = r.RequeueLong
+	}
+	return wait.Jitter(interval, requeueJitter)
+}
+
+// providerConfigKey returns the ProviderConfig referenced by the MachineRequest.
+func providerConfigKey(mr *butlerv1alpha1.MachineRequest) types.NamespacedName {
 	ns := mr.Spec.ProviderRef.Namespace
 	if ns == "" {
 		ns = mr.Namespace
 	}
-
-	key := types.NamespacedName{
+	return types.NamespacedName{
 		Name:      mr.Spec.ProviderRef.Name,
 		Namespace: ns,
 	}
+}
+
+// pendingTeardownPeers returns the names of MachineRequests in the same
+// teardown group with a higher ordinal that are still being deleted. It
+// returns nil when the MachineRequest is not part of a group.
+func (r *MachineRequestReconciler) pendingTeardownPeers(ctx context.Context, mr *butlerv1alpha1.MachineRequest) ([]string, error) {
+	group := mr.Annotations[AnnotationTeardownGroup]
+	if group == "" {
+		return nil, nil
+	}
+	ordinal, err := intAnnotation(mr, AnnotationTeardownOrdinal)
+	if err != nil {
+		// Deletion must not stall on a typo; tear down as ordinal 0
+		r.Recorder.Eventf(mr, corev1.EventTypeWarning, butlerv1alpha1.ReasonInvalidConfiguration,
+			"%v; tearing down as ordinal 0", err)
+	}
+
+	list := &butlerv1alpha1.MachineRequestList{}
+	if err := r.List(ctx, list, client.InNamespace(mr.Namespace)); err != nil {
+		return nil, err
+	}
+
+	var pending []string
+	for i := range list.Items {
+		peer := &list.Items[i]
+		if peer.UID == mr.UID || peer.Annotations[AnnotationTeardownGroup] != group || peer.DeletionTimestamp.IsZero() {
+			continue
+		}
+		// A peer with a malformed ordinal tears down as ordinal 0 too
+		peerOrdinal, _ := intAnnotation(peer, AnnotationTeardownOrdinal)
+		if peerOrdinal > ordinal {
+			pending = append(pending, peer.Name)
+		}
+	}
+	return pending, nil
+}
 
+func (r *MachineRequestReconciler) getProviderConfig(ctx context.Context, mr *butlerv1alpha1.MachineRequest) (*butlerv1alpha1.ProviderConfig, error) {
+	pc := &butlerv1alpha1.ProviderConfig{}
+	key := providerConfigKey(mr)
 	if err := r.Get(ctx, key, pc); err != nil {
 		return nil, fmt.Errorf("failed to get ProviderConfig %s: %w", key, err)
 	}
@@ -353,8 +1866,7 @@ func (r *MachineRequestReconciler) createHarvesterClient(ctx context.Context, pc
 		return nil, fmt.Errorf("ProviderConfig %s has no Harvester configuration", pc.Name)
 	}
 
-	// Get credentials secret
-	secret := &corev1.Secret{}
+	// Only the metadata of the credentials secret is cached
 	ns := pc.Spec.CredentialsRef.Namespace
 	if ns == "" {
 		ns = pc.Namespace
@@ -365,36 +1877,129 @@ func (r *MachineRequestReconciler) createHarvesterClient(ctx context.Context, pc
 		Namespace: ns,
 	}
 
-	if err := r.Get(ctx, key, secret); err != nil {
+	secretMeta := &metav1.PartialObjectMetadata{}
+	secretMeta.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("Secret"))
+	if err := r.Get(ctx, key, secretMeta); err != nil {
 		return nil, fmt.Errorf("failed to get credentials secret %s: %w", key, err)
 	}
 
-	// Get kubeconfig from secret
-	secretKey := pc.Spec.CredentialsRef.Key
-	if secretKey == "" {
-		secretKey = "kubeconfig"
+	settings, err := parseClientSettings(pc)
+	if err != nil {
+		return nil, err
 	}
 
-	kubeconfig, ok := secret.Data[secretKey]
-	if !ok {
-		return nil, fmt.Errorf("credentials secret %s does not contain key %s", key, secretKey)
-	}
+	// Reuse the cached client until the ProviderConfig spec, its client
+	// settings or the secret change
+	version := fmt.Sprintf("%d/%s/%v", pc.Generation, secretMeta.ResourceVersion, settings)
+	return r.Clients.Get(types.NamespacedName{Namespace: pc.Namespace, Name: pc.Name}, version, func() (*harvester.Client, error) {
+		secret := &corev1.Secret{}
+		if err := r.apiReader().Get(ctx, key, secret); err != nil {
+			return nil, fmt.Errorf("failed to get credentials secret %s: %w", key, err)
+		}
+
+		// Get kubeconfig from secret
+		secretKey := pc.Spec.CredentialsRef.Key
+		if secretKey == "" {
+			secretKey = "kubeconfig"
+		}
 
-	return harvester.NewClient(kubeconfig, pc.Spec.Harvester)
+		kubeconfig, ok := secret.Data[secretKey]
+		if !ok {
+			return nil, fmt.Errorf("credentials secret %s does not contain key %s", key, secretKey)
+		}
+
+		hc, err := harvester.NewClient(kubeconfig, pc.Spec.Harvester)
+		if err != nil {
+			return nil, err
+		}
+		settings.apply(hc)
+		return hc, nil
+	})
 }
 
 func (r *MachineRequestReconciler) updatePhase(ctx context.Context, mr *butlerv1alpha1.MachineRequest, phase butlerv1alpha1.MachinePhase) (ctrl.Result, error) {
 	mr.Status.Phase = phase
 	now := metav1.Now()
 	mr.Status.LastUpdated = &now
-	if err := r.Status().Update(ctx, mr); err != nil {
+	if err := r.updateStatus(ctx, mr); err != nil {
 		return ctrl.Result{}, err
 	}
 	return ctrl.Result{Requeue: true}, nil
 }
 
+// isNameConflict reports whether the VM is stamped with a different owning
+// MachineRequest. Unstamped VMs are not treated as conflicts.
+func isNameConflict(mr *butlerv1alpha1.MachineRequest, status *harvester.VMStatus) bool {
+	return status.OwnerUID != "" && status.OwnerUID != string(mr.UID)
+}
+
+// setNameConflict marks the MachineRequest as conflicting with the VM's
+// recorded owner and stops reconciling it.
+func (r *MachineRequestReconciler) setNameConflict(ctx context.Context, mr *butlerv1alpha1.MachineRequest, status *harvester.VMStatus) (ctrl.Result, error) {
+	return r.reportNameConflict(ctx, mr, fmt.Sprintf("VM %s is already managed by MachineRequest %s (uid %s)",
+		vmName(mr), status.Owner, status.OwnerUID))
+}
+
+// reportNameConflict sets the NameConflict condition with message and stops
+// reconciling the MachineRequest.
+func (r *MachineRequestReconciler) reportNameConflict(ctx context.Context, mr *butlerv1alpha1.MachineRequest, message string) (ctrl.Result, error) {
+	meta.SetStatusCondition(&mr.Status.Conditions, metav1.Condition{
+		Type:               ConditionTypeNameConflict,
+		Status:             metav1.ConditionTrue,
+		Reason:             ReasonNameConflict,
+		Message:            message,
+		ObservedGeneration: mr.Generation,
+	})
+	return r.updateStatusError(ctx, mr, ReasonNameConflict, message)
+}
+
+// patchAnnotations sets the given annotations on the MachineRequest, removing
+// any whose value is empty. In-memory status changes are preserved.
+func (r *MachineRequestReconciler) patchAnnotations(ctx context.Context, mr *butlerv1alpha1.MachineRequest, annotations map[string]string) error {
+	status := mr.Status.DeepCopy()
+	patch := client.MergeFrom(mr.DeepCopy())
+	if mr.Annotations == nil {
+		mr.Annotations = map[string]string{}
+	}
+	for k, v := range annotations {
+		if v == "" {
+			delete(mr.Annotations, k)
+		} else {
+			mr.Annotations[k] = v
+		}
+	}
+	if err := r.Patch(ctx, mr, patch); err != nil {
+		return err
+	}
+	mr.Status = *status
+	return nil
+}
+
+// updateStatus writes the MachineRequest status, translating its conditions
+// to the configured vocabulary.
+func (r *MachineRequestReconciler) updateStatus(ctx context.Context, mr *butlerv1alpha1.MachineRequest) error {
+	r.Conditions.toExternal(&mr.Status)
+	defer r.Conditions.toInternal(&mr.Status)
+	if err := r.Status().Update(ctx, mr); err != nil {
+		return err
+	}
+	recordPhase(mr)
+	return nil
+}
+
+// recordPhase counts the MachineRequest under its current phase in the
+// per-phase gauge. MachineRequests without a phase yet count as Pending.
+func recordPhase(mr *butlerv1alpha1.MachineRequest) {
+	phase := mr.Status.Phase
+	if phase == "" {
+		phase = butlerv1alpha1.MachinePhasePending
+	}
+	metrics.SetMachinePhase(mr.Namespace, mr.Name, string(phase))
+}
+
 func (r *MachineRequestReconciler) updateStatusError(ctx context.Context, mr *butlerv1alpha1.MachineRequest, reason, message string) (ctrl.Result, error) {
 	mr.SetFailure(reason, message)
+	metrics.ProviderErrors.WithLabelValues(reason).Inc()
 	meta.SetStatusCondition(&mr.Status.Conditions, metav1.Condition{
 		Type:               butlerv1alpha1.ConditionTypeReady,
 		Status:             metav1.ConditionFalse,
@@ -402,18 +2007,71 @@ func (r *MachineRequestReconciler) updateStatusError(ctx context.Context, mr *bu
 		Message:            message,
 		ObservedGeneration: mr.Generation,
 	})
-	if err := r.Status().Update(ctx, mr); err != nil {
+	if err := r.updateStatus(ctx, mr); err != nil {
 		return ctrl.Result{}, err
 	}
 	r.Recorder.Event(mr, corev1.EventTypeWarning, reason, message)
 	return ctrl.Result{}, nil
 }
 
+// apiReader returns the reader for Secret and ConfigMap data.
+func (r *MachineRequestReconciler) apiReader() client.Reader {
+	if r.APIReader != nil {
+		return r.APIReader
+	}
+	return r.Client
+}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *MachineRequestReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if r.Conditions != nil {
+		if err := r.Conditions.Validate(); err != nil {
+			return fmt.Errorf("invalid condition vocabulary: %w", err)
+		}
+	}
+	if err := ValidateWatchNamespaces(r.WatchNamespaces); err != nil {
+		return err
+	}
+	if err := IndexFields(context.Background(), mgr.GetFieldIndexer()); err != nil {
+		return err
+	}
+	filter := predicate.Or(predicate.GenerationChangedPredicate{}, predicate.AnnotationChangedPredicate{})
+	machineFilter := predicate.Or(predicate.GenerationChangedPredicate{}, footprintUnchangedPredicate())
+	if len(r.WatchNamespaces) > 0 {
+		machineFilter = predicate.And(machineFilter, predicate.NewPredicateFuncs(r.watchesNamespace))
+	}
+	// Secrets and ConfigMaps have no generation, so every change to one is
+	// passed on and mapped to the MachineRequests using it. Only their
+	// metadata is cached; their data is read through apiReader
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&butlerv1alpha1.MachineRequest{}).
-		WithEventFilter(predicate.GenerationChangedPredicate{}).
+		For(&butlerv1alpha1.MachineRequest{}, builder.WithPredicates(machineFilter)).
+		Watches(&butlerv1alpha1.ProviderConfig{},
+			handler.EnqueueRequestsFromMapFunc(r.machineRequestsForProviderConfig),
+			builder.WithPredicates(filter)).
+		Watches(&corev1.Secret{},
+			handler.EnqueueRequestsFromMapFunc(r.machineRequestsForSecret),
+			builder.OnlyMetadata,
+			builder.WithPredicates(predicate.ResourceVersionChangedPredicate{})).
+		Watches(&corev1.ConfigMap{},
+			handler.EnqueueRequestsFromMapFunc(r.machineRequestsForConfigMap),
+			builder.OnlyMetadata,
+			builder.WithPredicates(predicate.ResourceVersionChangedPredicate{})).
 		Named("machinerequest").
 		Complete(r)
 }
+
+// ValidateWatchNamespaces checks that namespaces are valid, distinct
+// namespace names.
+func ValidateWatchNamespaces(namespaces []string) error {
+	seen := make(map[string]bool, len(namespaces))
+	for _, ns := range namespaces {
+		if errs := validation.IsDNS1123Label(ns); len(errs) > 0 {
+			return fmt.Errorf("invalid watch namespace %q: %s", ns, strings.Join(errs, "; "))
+		}
+		if seen[ns] {
+			return fmt.Errorf("watch namespace %q listed more than once", ns)
+		}
+		seen[ns] = true
+	}
+	return nil
+}
@@ -17,9 +17,124 @@ limitations under the License.
 package controller
 
 import (
+	"context"
+	"strings"
+	"time"
+
 	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	dto "github.com/prometheus/client_model/go"
+	"golang.org/x/time/rate"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	butlerv1alpha1 "github.com/butlerdotdev/butler-api/api/v1alpha1"
+	"github.com/butlerdotdev/butler-provider-harvester/internal/drain"
+	"github.com/butlerdotdev/butler-provider-harvester/internal/harvester"
+	"github.com/butlerdotdev/butler-provider-harvester/internal/metrics"
 )
 
+// builderIndexer registers field indexes on a fake client builder.
+type builderIndexer struct {
+	builder *fake.ClientBuilder
+}
+
+func (b builderIndexer) IndexField(_ context.Context, obj client.Object, field string, extract client.IndexerFunc) error {
+	b.builder.WithIndex(obj, field, extract)
+	return nil
+}
+
+// testReconciler returns a reconciler backed by a fake client holding
+// objects, and the recorder its events go to.
+func testReconciler(objects ...client.Object) (*MachineRequestReconciler, *record.FakeRecorder) {
+	scheme := runtime.NewScheme()
+	Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+	Expect(butlerv1alpha1.AddToScheme(scheme)).To(Succeed())
+	recorder := record.NewFakeRecorder(100)
+	builder := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(objects...).
+		WithStatusSubresource(&butlerv1alpha1.MachineRequest{})
+	Expect(IndexFields(context.Background(), builderIndexer{builder})).To(Succeed())
+	return &MachineRequestReconciler{Client: builder.Build(), Scheme: scheme, Recorder: recorder}, recorder
+}
+
+// testHarvesterClient returns a Harvester client on fakes holding the image
+// referenced by testMachineRequest, the provider config network and a ready
+// node with room for it. Unstructured objects are added to the dynamic
+// client, the others to the clientset.
+func testHarvesterClient(objects ...runtime.Object) *harvester.Client {
+	vmGVR := schema.GroupVersionResource{Group: "kubevirt.io", Version: "v1", Resource: "virtualmachines"}
+	vmiGVR := schema.GroupVersionResource{Group: "kubevirt.io", Version: "v1", Resource: "virtualmachineinstances"}
+	imageGVR := schema.GroupVersionResource{Group: "harvesterhci.io", Version: "v1beta1", Resource: "virtualmachineimages"}
+	nadGVR := schema.GroupVersionResource{Group: "k8s.cni.cncf.io", Version: "v1", Resource: "network-attachment-definitions"}
+	kubevirtGVR := schema.GroupVersionResource{Group: "kubevirt.io", Version: "v1", Resource: "kubevirts"}
+	longhornNodeGVR := schema.GroupVersionResource{Group: "longhorn.io", Version: "v1beta2", Resource: "nodes"}
+	dynamic := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		vmGVR:           "VirtualMachineList",
+		vmiGVR:          "VirtualMachineInstanceList",
+		imageGVR:        "VirtualMachineImageList",
+		nadGVR:          "NetworkAttachmentDefinitionList",
+		kubevirtGVR:     "KubeVirtList",
+		longhornNodeGVR: "NodeList",
+	})
+
+	image := &unstructured.Unstructured{}
+	image.SetAPIVersion("harvesterhci.io/v1beta1")
+	image.SetKind("VirtualMachineImage")
+	image.SetNamespace("default")
+	image.SetName("image-abc12")
+	Expect(unstructured.SetNestedSlice(image.Object, []interface{}{
+		map[string]interface{}{"type": "Imported", "status": "True"},
+	}, "status", "conditions")).To(Succeed())
+	Expect(dynamic.Tracker().Create(imageGVR, image, "default")).To(Succeed())
+	nad := &unstructured.Unstructured{}
+	nad.SetAPIVersion("k8s.cni.cncf.io/v1")
+	nad.SetKind("NetworkAttachmentDefinition")
+	nad.SetNamespace("default")
+	nad.SetName("vlan1")
+	Expect(dynamic.Tracker().Create(nadGVR, nad, "default")).To(Succeed())
+	var typed []runtime.Object
+	for _, obj := range objects {
+		if u, ok := obj.(*unstructured.Unstructured); ok {
+			Expect(dynamic.Tracker().Add(u)).To(Succeed())
+			continue
+		}
+		typed = append(typed, obj)
+	}
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "harvester-0"},
+		Status: corev1.NodeStatus{
+			Allocatable: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("16"),
+				corev1.ResourceMemory: resource.MustParse("64Gi"),
+			},
+			Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionTrue}},
+		},
+	}
+
+	return harvester.NewClientFromInterfaces(dynamic, kubefake.NewClientset(append(typed, node)...), &butlerv1alpha1.HarvesterProviderConfig{
+		Namespace:   "default",
+		NetworkName: "default/vlan1",
+	})
+}
+
 var _ = Describe("MachineRequest Controller", func() {
 	Context("When reconciling a resource", func() {
 
@@ -30,3 +145,1097 @@ var _ = Describe("MachineRequest Controller", func() {
 		})
 	})
 })
+
+var _ = Describe("Root disk storage backend", func() {
+	var (
+		ctx context.Context
+		r   *MachineRequestReconciler
+		mr  *butlerv1alpha1.MachineRequest
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		mr = testMachineRequest(nil)
+		r, _ = testReconciler(mr)
+	})
+
+	It("marks the request Degraded while replicas are unhealthy", func() {
+		changed, err := r.recordStorageBackend(ctx, mr, &harvester.StorageBackend{
+			Volume: "pvc-1", Robustness: "degraded", Replicas: 3, HealthyReplicas: 2, Nodes: []string{"node-1", "node-2"},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(changed).To(BeTrue())
+		Expect(mr.Annotations).To(HaveKeyWithValue(AnnotationRootDiskReplicas, "2/3"))
+		Expect(mr.Annotations).To(HaveKeyWithValue(AnnotationRootDiskReplicaNodes, "node-1,node-2"))
+		Expect(meta.IsStatusConditionTrue(mr.Status.Conditions, butlerv1alpha1.ConditionTypeDegraded)).To(BeTrue())
+	})
+
+	It("leaves the Degraded condition alone when the replicas are unknown", func() {
+		for _, status := range []metav1.ConditionStatus{metav1.ConditionTrue, metav1.ConditionFalse} {
+			meta.SetStatusCondition(&mr.Status.Conditions, metav1.Condition{
+				Type: butlerv1alpha1.ConditionTypeDegraded, Status: status, Reason: ReasonStorageDegraded,
+			})
+			changed, err := r.recordStorageBackend(ctx, mr, &harvester.StorageBackend{
+				Volume: "pvc-1", Replicas: 3, ReplicasUnknown: true,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(changed).To(BeFalse())
+			Expect(mr.Annotations).To(HaveKeyWithValue(AnnotationRootDiskReplicas, "unknown/3"))
+			Expect(meta.FindStatusCondition(mr.Status.Conditions, butlerv1alpha1.ConditionTypeDegraded).Status).To(Equal(status))
+		}
+	})
+})
+
+var _ = Describe("Harvester client from the credentials secret", func() {
+	const kubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: harvester
+  cluster:
+    server: https://harvester.example.com:6443
+contexts:
+- name: harvester
+  context:
+    cluster: harvester
+current-context: harvester
+`
+
+	It("reads the secret data through the API reader and reuses the client", func() {
+		ctx := context.Background()
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "butler-system", Name: "harvester-kubeconfig"},
+			Data:       map[string][]byte{"kubeconfig": []byte(kubeconfig)},
+		}
+		pc := &butlerv1alpha1.ProviderConfig{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "butler-system", Name: "harvester"},
+			Spec: butlerv1alpha1.ProviderConfigSpec{
+				CredentialsRef: butlerv1alpha1.SecretReference{Name: "harvester-kubeconfig"},
+				Harvester:      &butlerv1alpha1.HarvesterProviderConfig{Namespace: "default"},
+			},
+		}
+		r, _ := testReconciler(pc, secret)
+		reads := 0
+		r.APIReader = interceptor.NewClient(r.Client.(client.WithWatch), interceptor.Funcs{
+			Get: func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+				reads++
+				return c.Get(ctx, key, obj, opts...)
+			},
+		})
+
+		first, err := r.createHarvesterClient(ctx, pc)
+		Expect(err).NotTo(HaveOccurred())
+		second, err := r.createHarvesterClient(ctx, pc)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(second).To(BeIdenticalTo(first))
+		Expect(reads).To(Equal(1))
+
+		secret.Data["kubeconfig"] = []byte(strings.ReplaceAll(kubeconfig, "harvester.example.com", "harvester-2.example.com"))
+		Expect(r.Update(ctx, secret)).To(Succeed())
+		third, err := r.createHarvesterClient(ctx, pc)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(third).NotTo(BeIdenticalTo(first))
+		Expect(reads).To(Equal(2))
+	})
+})
+
+var _ = Describe("Running VM addresses", func() {
+	It("empties the address list when the VM reports none", func() {
+		ctx := context.Background()
+		mr := testMachineRequest(nil)
+		mr.Finalizers = []string{finalizerName}
+		mr.Status.Phase = butlerv1alpha1.MachinePhaseRunning
+		mr.Status.IPAddress = "10.0.0.5"
+		mr.Status.IPAddresses = []string{"10.0.0.5", "192.168.50.5"}
+		hc := testHarvesterClient()
+		opts, err := vmCreateOptions(mr)
+		Expect(err).NotTo(HaveOccurred())
+		_, _, err = hc.CreateVM(ctx, opts)
+		Expect(err).NotTo(HaveOccurred())
+		r, _ := testReconciler(mr)
+
+		_, err = r.reconcileRunning(ctx, mr, &butlerv1alpha1.ProviderConfig{}, hc)
+		Expect(err).NotTo(HaveOccurred())
+		got := &butlerv1alpha1.MachineRequest{}
+		Expect(r.Get(ctx, client.ObjectKeyFromObject(mr), got)).To(Succeed())
+		Expect(got.Status.IPAddresses).To(BeEmpty())
+		Expect(got.Status.IPAddress).To(Equal("10.0.0.5"))
+	})
+})
+
+var _ = Describe("Cluster capacity check", func() {
+	var ctx context.Context
+
+	BeforeEach(func() {
+		ctx = context.Background()
+	})
+
+	DescribeTable("defers creation only while the VM cannot fit",
+		func(cpu int32, memoryMB int32, fits bool) {
+			mr := testMachineRequest(nil)
+			mr.Spec.CPU = cpu
+			mr.Spec.MemoryMB = memoryMB
+			r, recorder := testReconciler(mr)
+			opts, err := vmCreateOptions(mr)
+			Expect(err).NotTo(HaveOccurred())
+
+			ok, err := r.checkCapacity(ctx, mr, testHarvesterClient(), opts)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ok).To(Equal(fits))
+			Expect(meta.IsStatusConditionTrue(mr.Status.Conditions, ConditionTypeInsufficientCapacity)).To(Equal(!fits))
+			if fits {
+				Expect(recorder.Events).To(BeEmpty())
+				return
+			}
+			Expect(recorder.Events).To(Receive(ContainSubstring(ReasonInsufficientCapacity)))
+
+			// The event is only emitted when the condition is first set
+			_, err = r.checkCapacity(ctx, mr, testHarvesterClient(), opts)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(recorder.Events).To(BeEmpty())
+		},
+		Entry("a VM the node can hold", int32(2), int32(4096), true),
+		Entry("more CPUs than the node has", int32(32), int32(4096), false),
+		Entry("more memory than the node has", int32(2), int32(128*1024), false),
+	)
+
+	It("clears the condition once the VM fits", func() {
+		mr := testMachineRequest(nil)
+		meta.SetStatusCondition(&mr.Status.Conditions, metav1.Condition{
+			Type: ConditionTypeInsufficientCapacity, Status: metav1.ConditionTrue, Reason: ReasonInsufficientCapacity,
+		})
+		r, _ := testReconciler(mr)
+		opts, err := vmCreateOptions(mr)
+		Expect(err).NotTo(HaveOccurred())
+
+		ok, err := r.checkCapacity(ctx, mr, testHarvesterClient(), opts)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeTrue())
+		Expect(meta.FindStatusCondition(mr.Status.Conditions, ConditionTypeInsufficientCapacity)).To(BeNil())
+	})
+
+	It("lets an existing VM through without checking", func() {
+		mr := testMachineRequest(nil)
+		mr.Spec.CPU = 64
+		r, recorder := testReconciler(mr)
+		hc := testHarvesterClient()
+		opts, err := vmCreateOptions(mr)
+		Expect(err).NotTo(HaveOccurred())
+		_, _, err = hc.CreateVM(ctx, opts)
+		Expect(err).NotTo(HaveOccurred())
+
+		ok, err := r.checkCapacity(ctx, mr, hc, opts)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeTrue())
+		Expect(recorder.Events).To(BeEmpty())
+	})
+})
+
+var _ = Describe("Deletion after the ProviderConfig is removed", func() {
+	var (
+		ctx context.Context
+		mr  *butlerv1alpha1.MachineRequest
+		key types.NamespacedName
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		mr = testMachineRequest(nil)
+		mr.Spec.ProviderRef.Name = "harvester"
+		mr.Finalizers = []string{finalizerName}
+		key = types.NamespacedName{Namespace: "butler-system", Name: "harvester"}
+	})
+
+	// deleteAndReconcile marks mr deleted and reconciles it once.
+	deleteAndReconcile := func(r *MachineRequestReconciler) ctrl.Result {
+		Expect(r.Delete(ctx, mr)).To(Succeed())
+		result, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(mr)})
+		Expect(err).NotTo(HaveOccurred())
+		return result
+	}
+
+	DescribeTable("waits out the grace period before orphaning the VM",
+		func(missingFor time.Duration, event string, removed bool) {
+			if missingFor > 0 {
+				mr.Annotations[AnnotationProviderConfigMissingSince] = time.Now().Add(-missingFor).UTC().Format(time.RFC3339)
+			}
+			r, recorder := testReconciler(mr)
+			r.ProviderConfigGracePeriod = 5 * time.Minute
+
+			result := deleteAndReconcile(r)
+			got := &butlerv1alpha1.MachineRequest{}
+			err := r.Get(ctx, client.ObjectKeyFromObject(mr), got)
+			if removed {
+				Expect(apierrors.IsNotFound(err)).To(BeTrue(), "got %v", err)
+			} else {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(got.Annotations).To(HaveKey(AnnotationProviderConfigMissingSince))
+				Expect(result.RequeueAfter).To(BeNumerically(">", 0))
+				Expect(result.RequeueAfter).To(BeNumerically("<=", 5*time.Minute))
+			}
+			if event == "" {
+				Expect(recorder.Events).To(BeEmpty())
+			} else {
+				Expect(recorder.Events).To(Receive(ContainSubstring(event)))
+			}
+		},
+		Entry("when the ProviderConfig is first found missing", time.Duration(0), "ProviderConfigMissing", false),
+		Entry("within the grace period", 2*time.Minute, "", false),
+		Entry("after the grace period", 6*time.Minute, "Orphaned", true),
+	)
+
+	It("deletes the VM with the last known client and then drops it", func() {
+		hc := testHarvesterClient()
+		opts, err := vmCreateOptions(mr)
+		Expect(err).NotTo(HaveOccurred())
+		_, _, err = hc.CreateVM(ctx, opts)
+		Expect(err).NotTo(HaveOccurred())
+		r, recorder := testReconciler(mr)
+		_, err = r.Clients.Get(key, "1", func() (*harvester.Client, error) { return hc, nil })
+		Expect(err).NotTo(HaveOccurred())
+
+		deleteAndReconcile(r)
+		// Deletion goes through the Deleting phase before the finalizer is removed
+		for range 5 {
+			if apierrors.IsNotFound(r.Get(ctx, client.ObjectKeyFromObject(mr), &butlerv1alpha1.MachineRequest{})) {
+				break
+			}
+			_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(mr)})
+			Expect(err).NotTo(HaveOccurred())
+		}
+		Expect(apierrors.IsNotFound(r.Get(ctx, client.ObjectKeyFromObject(mr), &butlerv1alpha1.MachineRequest{}))).To(BeTrue())
+		Expect(r.Clients.Last(key)).To(BeNil())
+		_, err = hc.GetVM(ctx, opts.Name)
+		Expect(apierrors.IsNotFound(err)).To(BeTrue(), "got %v", err)
+		Expect(recorder.Events).NotTo(Receive(ContainSubstring("Orphaned")))
+	})
+
+	It("leaves a MachineRequest without the finalizer alone", func() {
+		mr.Finalizers = []string{"example.com/other"}
+		r, recorder := testReconciler(mr)
+
+		deleteAndReconcile(r)
+		got := &butlerv1alpha1.MachineRequest{}
+		Expect(r.Get(ctx, client.ObjectKeyFromObject(mr), got)).To(Succeed())
+		Expect(got.Annotations).NotTo(HaveKey(AnnotationProviderConfigMissingSince))
+		Expect(recorder.Events).To(BeEmpty())
+	})
+})
+
+var _ = Describe("Unsupported feature warnings", func() {
+	// kubevirt returns the KubeVirt CR of a cluster enabling the given
+	// feature gates.
+	kubevirt := func(gates ...interface{}) *unstructured.Unstructured {
+		kv := &unstructured.Unstructured{}
+		kv.SetAPIVersion("kubevirt.io/v1")
+		kv.SetKind("KubeVirt")
+		kv.SetNamespace("harvester-system")
+		kv.SetName("kubevirt")
+		Expect(unstructured.SetNestedSlice(kv.Object, gates, "spec", "configuration", "developerConfiguration", "featureGates")).To(Succeed())
+		Expect(unstructured.SetNestedField(kv.Object, "v1.1.0", "status", "observedKubeVirtVersion")).To(Succeed())
+		return kv
+	}
+
+	DescribeTable("warns about requested features the cluster does not enable",
+		func(objects []runtime.Object, want string) {
+			mr := testMachineRequest(map[string]string{"dedicated-cpu-placement": "true"})
+			r, recorder := testReconciler(mr)
+			opts, err := vmCreateOptions(mr)
+			Expect(err).NotTo(HaveOccurred())
+
+			r.warnUnsupportedFeatures(context.Background(), mr, testHarvesterClient(objects...), opts)
+			if want == "" {
+				Expect(recorder.Events).To(BeEmpty())
+				return
+			}
+			Expect(recorder.Events).To(Receive(ContainSubstring(want)))
+		},
+		Entry("a missing feature gate", []runtime.Object{kubevirt("LiveMigration")},
+			"Requested dedicated CPU placement (feature gate CPUManager) is not enabled on the target cluster (KubeVirt v1.1.0)"),
+		Entry("an enabled feature gate", []runtime.Object{kubevirt("CPUManager")}, ""),
+		Entry("undetected capabilities", nil, ""),
+	)
+})
+
+var _ = Describe("Recreating a VM", func() {
+	var (
+		ctx   context.Context
+		mr    *butlerv1alpha1.MachineRequest
+		stale *corev1.PersistentVolumeClaim
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		mr = testMachineRequest(nil)
+		stale = &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        harvester.RootDiskPVCName(mr.Spec.MachineName),
+				Namespace:   "default",
+				Annotations: map[string]string{harvester.AnnotationOwnerUID: string(mr.UID)},
+			},
+		}
+	})
+
+	// failStale runs a create on the disk left behind and expects it to fail.
+	failStale := func(r *MachineRequestReconciler, hc *harvester.Client) {
+		_, err := r.reconcilePending(ctx, mr, hc)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(mr.Status.Phase).To(Equal(butlerv1alpha1.MachinePhaseFailed))
+		Expect(mr.Status.FailureReason).To(Equal(ReasonStaleDisk))
+		Expect(mr.Status.FailureMessage).To(ContainSubstring(AnnotationRecreate))
+		_, err = hc.GetPVCStatus(ctx, stale.Name)
+		Expect(err).NotTo(HaveOccurred(), "the create path keeps the disk")
+	}
+
+	It("deletes a disk the create path kept and returns to Pending", func() {
+		r, recorder := testReconciler(mr)
+		hc := testHarvesterClient(stale)
+		failStale(r, hc)
+		Expect(recorder.Events).To(Receive(ContainSubstring(ReasonStaleDisk)))
+
+		_, err := r.recreateVM(ctx, mr, hc)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(mr.Status.Phase).To(Equal(butlerv1alpha1.MachinePhasePending))
+		_, err = hc.GetPVCStatus(ctx, stale.Name)
+		Expect(apierrors.IsNotFound(err)).To(BeTrue(), "got %v", err)
+		Expect(recorder.Events).To(Receive(ContainSubstring(ReasonRecreating)))
+	})
+
+	It("waits for a slot under the deletion rate", func() {
+		r, recorder := testReconciler(mr)
+		hc := testHarvesterClient(stale)
+		failStale(r, hc)
+		r.configureDeleteLimiter(providerConfigKey(mr), rate.Every(time.Hour))
+		Expect(r.deleteDelay(providerConfigKey(mr))).To(BeZero())
+
+		result, err := r.recreateVM(ctx, mr, hc)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.RequeueAfter).To(BeNumerically(">", 0))
+		Expect(meta.FindStatusCondition(mr.Status.Conditions, butlerv1alpha1.ConditionTypeProgressing).Reason).To(Equal(ReasonDeletionThrottled))
+		_, err = hc.GetPVCStatus(ctx, stale.Name)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(recorder.Events).NotTo(Receive(ContainSubstring(ReasonRecreating)))
+	})
+
+	It("waits while the controller is drained", func() {
+		configMap := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "butler-system", Name: "drain"},
+			Data:       map[string]string{drain.ConfigMapKey: "true"},
+		}
+		r, _ := testReconciler(mr, configMap)
+		hc := testHarvesterClient(stale)
+		failStale(r, hc)
+		r.Drain = &drain.Switch{Reader: r.Client, ConfigMap: client.ObjectKeyFromObject(configMap)}
+		// Start reads the ConfigMap once before returning on the cancelled context
+		stopped, cancel := context.WithCancel(ctx)
+		cancel()
+		Expect(r.Drain.Start(stopped)).To(Succeed())
+		Expect(r.Drain.Drained()).To(BeTrue())
+
+		result, err := r.recreateVM(ctx, mr, hc)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.RequeueAfter).To(BeNumerically(">", 0))
+		Expect(mr.Status.Phase).To(Equal(butlerv1alpha1.MachinePhaseFailed))
+		_, err = hc.GetPVCStatus(ctx, stale.Name)
+		Expect(err).NotTo(HaveOccurred())
+	})
+})
+
+var _ = Describe("Guest agent monitoring", func() {
+	var (
+		ctx     context.Context
+		running *harvester.VMStatus
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		running = &harvester.VMStatus{Phase: "Running"}
+	})
+
+	It("does not monitor guests whose agent never connected", func() {
+		mr := testMachineRequest(nil)
+		r, recorder := testReconciler(mr)
+		changed, err := r.checkGuestAgent(ctx, mr, running, time.Minute)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(changed).To(BeFalse())
+		Expect(mr.Annotations).NotTo(HaveKey(AnnotationGuestAgentDisconnectedSince))
+		Expect(recorder.Events).To(BeEmpty())
+	})
+
+	It("records a connected agent", func() {
+		mr := testMachineRequest(map[string]string{
+			"guest-agent-disconnected-since": time.Now().UTC().Format(time.RFC3339),
+		})
+		r, _ := testReconciler(mr)
+		running.AgentConnected = true
+		_, err := r.checkGuestAgent(ctx, mr, running, time.Minute)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(mr.Annotations).To(HaveKeyWithValue(AnnotationGuestAgentSeen, "true"))
+		Expect(mr.Annotations).NotTo(HaveKey(AnnotationGuestAgentDisconnectedSince))
+	})
+
+	DescribeTable("reports a disconnected agent once the timeout passes",
+		func(disconnected time.Duration, unresponsive bool) {
+			annotations := map[string]string{"guest-agent-seen": "true"}
+			if disconnected > 0 {
+				annotations["guest-agent-disconnected-since"] = time.Now().Add(-disconnected).UTC().Format(time.RFC3339)
+			}
+			mr := testMachineRequest(annotations)
+			r, recorder := testReconciler(mr)
+			changed, err := r.checkGuestAgent(ctx, mr, running, 5*time.Minute)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(changed).To(Equal(unresponsive))
+			Expect(mr.Annotations).To(HaveKey(AnnotationGuestAgentDisconnectedSince))
+			Expect(meta.IsStatusConditionTrue(mr.Status.Conditions, ConditionTypeGuestUnresponsive)).To(Equal(unresponsive))
+			if unresponsive {
+				Expect(recorder.Events).To(Receive(ContainSubstring("Guest agent has been disconnected since")))
+			} else {
+				Expect(recorder.Events).To(BeEmpty())
+			}
+		},
+		Entry("just disconnected", time.Duration(0), false),
+		Entry("within the timeout", time.Minute, false),
+		Entry("past the timeout", 10*time.Minute, true),
+	)
+
+	It("clears the condition when the agent reconnects", func() {
+		mr := testMachineRequest(map[string]string{"guest-agent-seen": "true"})
+		meta.SetStatusCondition(&mr.Status.Conditions, metav1.Condition{
+			Type:   ConditionTypeGuestUnresponsive,
+			Status: metav1.ConditionTrue,
+			Reason: ReasonGuestAgentDisconnected,
+		})
+		r, recorder := testReconciler(mr)
+		running.AgentConnected = true
+		changed, err := r.checkGuestAgent(ctx, mr, running, time.Minute)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(changed).To(BeTrue())
+		Expect(meta.FindStatusCondition(mr.Status.Conditions, ConditionTypeGuestUnresponsive)).To(BeNil())
+		Expect(recorder.Events).To(Receive(ContainSubstring("Guest agent reconnected")))
+	})
+
+	DescribeTable("keeps a running VM running on a malformed setting",
+		func(key, value, event string) {
+			mr := testMachineRequest(map[string]string{key: value})
+			mr.Finalizers = []string{finalizerName}
+			mr.Status.Phase = butlerv1alpha1.MachinePhaseRunning
+			hc := testHarvesterClient()
+			opts, err := vmCreateOptions(mr)
+			Expect(err).NotTo(HaveOccurred())
+			_, _, err = hc.CreateVM(ctx, opts)
+			Expect(err).NotTo(HaveOccurred())
+			r, recorder := testReconciler(mr)
+
+			_, err = r.reconcileRunning(ctx, mr, &butlerv1alpha1.ProviderConfig{}, hc)
+			Expect(err).NotTo(HaveOccurred())
+			got := &butlerv1alpha1.MachineRequest{}
+			Expect(r.Get(ctx, client.ObjectKeyFromObject(mr), got)).To(Succeed())
+			Expect(got.Status.Phase).To(Equal(butlerv1alpha1.MachinePhaseRunning))
+			Expect(recorder.Events).To(Receive(ContainSubstring(event)))
+		},
+		Entry("guest-unresponsive-timeout", "guest-unresponsive-timeout", "soon",
+			`invalid duration "soon"; using the default 5m0s`),
+		Entry("deep-check-interval", "deep-check-interval", "-1m",
+			`invalid duration "-1m"; using the default 5m0s`),
+		Entry("in-place-resize", "in-place-resize", "maybe",
+			`invalid boolean "maybe"; using the default true`),
+	)
+})
+
+var _ = Describe("Teardown ordering", func() {
+	// deletingPeer returns a member of the teardown group being deleted.
+	deletingPeer := func(name, ordinal string) *butlerv1alpha1.MachineRequest {
+		peer := testMachineRequest(map[string]string{"teardown-group": "cluster-a", "teardown-ordinal": ordinal})
+		peer.Name = name
+		peer.UID = types.UID("uid-" + name)
+		peer.Finalizers = []string{finalizerName}
+		now := metav1.Now()
+		peer.DeletionTimestamp = &now
+		return peer
+	}
+
+	DescribeTable("waits for deleting peers with a higher ordinal",
+		func(ordinal string, peers []*butlerv1alpha1.MachineRequest, want []string, warning bool) {
+			mr := testMachineRequest(map[string]string{"teardown-group": "cluster-a", "teardown-ordinal": ordinal})
+			objects := []client.Object{mr}
+			for _, peer := range peers {
+				objects = append(objects, peer)
+			}
+			r, recorder := testReconciler(objects...)
+			pending, err := r.pendingTeardownPeers(context.Background(), mr)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pending).To(ConsistOf(want))
+			if warning {
+				Expect(recorder.Events).To(Receive(ContainSubstring("tearing down as ordinal 0")))
+			} else {
+				Expect(recorder.Events).To(BeEmpty())
+			}
+		},
+		Entry("a higher ordinal", "0", []*butlerv1alpha1.MachineRequest{deletingPeer("worker-1", "1")}, []string{"worker-1"}, false),
+		Entry("a lower ordinal", "1", []*butlerv1alpha1.MachineRequest{deletingPeer("cp-0", "0")}, []string{}, false),
+		Entry("a malformed own ordinal", "first", []*butlerv1alpha1.MachineRequest{
+			deletingPeer("worker-1", "1"), deletingPeer("cp-0", "0"),
+		}, []string{"worker-1"}, true),
+		Entry("a malformed peer ordinal", "0", []*butlerv1alpha1.MachineRequest{deletingPeer("worker-1", "last")}, []string{}, false),
+	)
+
+	It("ignores peers that are not being deleted", func() {
+		mr := testMachineRequest(map[string]string{"teardown-group": "cluster-a"})
+		peer := deletingPeer("worker-1", "1")
+		peer.Finalizers = nil
+		peer.DeletionTimestamp = nil
+		r, _ := testReconciler(mr, peer)
+		pending, err := r.pendingTeardownPeers(context.Background(), mr)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pending).To(BeEmpty())
+	})
+})
+
+var _ = Describe("Restoring a VM from backup", func() {
+	var (
+		ctx context.Context
+		mr  *butlerv1alpha1.MachineRequest
+	)
+
+	// testRestore returns the restore creating the test VM with the given status.
+	testRestore := func(status map[string]interface{}) *unstructured.Unstructured {
+		restore := &unstructured.Unstructured{}
+		restore.SetAPIVersion("harvesterhci.io/v1beta1")
+		restore.SetKind("VirtualMachineRestore")
+		restore.SetNamespace("default")
+		restore.SetName("worker-0-restore")
+		restore.Object["status"] = status
+		return restore
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		mr = testMachineRequest(map[string]string{"restore-from-backup": "nightly"})
+		mr.Spec.Image = ""
+		mr.Finalizers = []string{finalizerName}
+		mr.Status.Phase = butlerv1alpha1.MachinePhaseCreating
+	})
+
+	It("reports the restore progress", func() {
+		r, _ := testReconciler(mr)
+		hc := testHarvesterClient(testRestore(map[string]interface{}{
+			"conditions": []interface{}{map[string]interface{}{"type": "Progressing", "status": "True", "message": "restoring volumes"}},
+		}))
+		_, err := r.reconcileRestore(ctx, mr, hc, time.Hour)
+		Expect(err).NotTo(HaveOccurred())
+		condition := meta.FindStatusCondition(mr.Status.Conditions, butlerv1alpha1.ConditionTypeProgressing)
+		Expect(condition.Reason).To(Equal(ReasonRestoring))
+		Expect(condition.Message).To(Equal("Restoring VM from backup: restoring volumes"))
+		Expect(mr.Status.Phase).To(Equal(butlerv1alpha1.MachinePhaseCreating))
+	})
+
+	It("fails the MachineRequest when the restore fails", func() {
+		r, _ := testReconciler(mr)
+		hc := testHarvesterClient(testRestore(map[string]interface{}{
+			"conditions": []interface{}{map[string]interface{}{"type": "Failure", "status": "True", "message": "backup target unreachable"}},
+		}))
+		_, err := r.reconcileRestore(ctx, mr, hc, time.Hour)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(mr.Status.Phase).To(Equal(butlerv1alpha1.MachinePhaseFailed))
+		Expect(mr.Status.FailureMessage).To(Equal("VM restore failed: backup target unreachable"))
+	})
+
+	It("returns to Pending when the restore is gone", func() {
+		r, _ := testReconciler(mr)
+		_, err := r.reconcileRestore(ctx, mr, testHarvesterClient(), time.Hour)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(mr.Status.Phase).To(Equal(butlerv1alpha1.MachinePhasePending))
+	})
+
+	It("adopts the restored VM once the restore completes", func() {
+		r, recorder := testReconciler(mr)
+		hc := testHarvesterClient(testRestore(map[string]interface{}{"complete": true}))
+		opts, err := vmCreateOptions(testMachineRequest(nil))
+		Expect(err).NotTo(HaveOccurred())
+		opts.OwnerUID = "uid-backed-up"
+		_, _, err = hc.CreateVM(ctx, opts)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = r.reconcileRestore(ctx, mr, hc, time.Hour)
+		Expect(err).NotTo(HaveOccurred())
+		vm, err := hc.GetVM(ctx, "worker-0")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(mr.Status.ProviderID).To(Equal(string(vm.GetUID())))
+		Expect(vm.GetLabels()).To(HaveKeyWithValue(harvester.LabelManagedBy, "butler-provider-harvester"))
+		Expect(vm.GetAnnotations()).To(HaveKeyWithValue(harvester.AnnotationOwnerUID, "uid-1"))
+		Expect(recorder.Events).To(Receive(ContainSubstring("VM restored from backup nightly")))
+	})
+
+	It("deletes a failed restore when retrying", func() {
+		mr.Status.Phase = butlerv1alpha1.MachinePhaseFailed
+		mr.Status.FailureReason = butlerv1alpha1.ReasonProviderError
+		mr.Annotations[AnnotationRetry] = ""
+		r, _ := testReconciler(mr)
+		hc := testHarvesterClient(testRestore(map[string]interface{}{
+			"conditions": []interface{}{map[string]interface{}{"type": "Failure", "status": "True", "message": "backup target unreachable"}},
+		}))
+
+		_, err := r.retryFailed(ctx, mr, hc)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(mr.Status.Phase).To(Equal(butlerv1alpha1.MachinePhasePending))
+		_, err = hc.GetRestoreStatus(ctx, "worker-0")
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+})
+
+var _ = Describe("Create timeout", func() {
+	var (
+		ctx context.Context
+		mr  *butlerv1alpha1.MachineRequest
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		mr = testMachineRequest(nil)
+		mr.Status.Phase = butlerv1alpha1.MachinePhaseCreating
+	})
+
+	DescribeTable("counts from the recorded start time",
+		func(creatingFor time.Duration, expired bool) {
+			if creatingFor > 0 {
+				mr.Annotations[AnnotationCreatingSince] = time.Now().Add(-creatingFor).UTC().Format(time.RFC3339)
+			}
+			r, _ := testReconciler(mr)
+
+			got, err := r.createTimedOut(ctx, mr, 10*time.Minute)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(got).To(Equal(expired))
+			Expect(mr.Annotations).To(HaveKey(AnnotationCreatingSince))
+		},
+		Entry("when no start time is recorded", time.Duration(0), false),
+		Entry("within the timeout", 5*time.Minute, false),
+		Entry("past the timeout", 11*time.Minute, true),
+	)
+
+	It("records the start time when none is recorded", func() {
+		r, _ := testReconciler(mr)
+		_, err := r.createTimedOut(ctx, mr, 10*time.Minute)
+		Expect(err).NotTo(HaveOccurred())
+
+		got := &butlerv1alpha1.MachineRequest{}
+		Expect(r.Get(ctx, client.ObjectKeyFromObject(mr), got)).To(Succeed())
+		since, err := time.Parse(time.RFC3339, got.Annotations[AnnotationCreatingSince])
+		Expect(err).NotTo(HaveOccurred())
+		Expect(since).To(BeTemporally("~", time.Now(), time.Minute))
+	})
+
+	It("fails the MachineRequest naming the VM and root disk state", func() {
+		r, recorder := testReconciler(mr)
+		hc := testHarvesterClient(&corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: rootDiskPVC(mr)},
+			Status:     corev1.PersistentVolumeClaimStatus{Phase: corev1.ClaimBound},
+		})
+
+		_, err := r.failCreateTimeout(ctx, mr, hc, &harvester.VMStatus{Phase: "Scheduling"}, 10*time.Minute)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(mr.Status.Phase).To(Equal(butlerv1alpha1.MachinePhaseFailed))
+		Expect(mr.Status.FailureReason).To(Equal(ReasonCreateTimeout))
+		Expect(mr.Status.FailureMessage).To(Equal("VM did not become ready within 10m0s (VM phase: Scheduling, root disk: Bound)"))
+		Expect(recorder.Events).To(Receive(ContainSubstring(ReasonCreateTimeout)))
+	})
+})
+
+var _ = Describe("Name conflicts", func() {
+	It("reports a VMI of another owner holding the VM name", func() {
+		ctx := context.Background()
+		mr := testMachineRequest(nil)
+		mr.Status.Phase = butlerv1alpha1.MachinePhasePending
+		vmi := &unstructured.Unstructured{}
+		vmi.SetAPIVersion("kubevirt.io/v1")
+		vmi.SetKind("VirtualMachineInstance")
+		vmi.SetNamespace("default")
+		vmi.SetName("worker-0")
+		r, recorder := testReconciler(mr)
+		hc := testHarvesterClient(vmi)
+
+		_, err := r.reconcilePending(ctx, mr, hc)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(mr.Status.Phase).To(Equal(butlerv1alpha1.MachinePhaseFailed))
+		Expect(mr.Status.FailureReason).To(Equal(ReasonNameConflict))
+		Expect(meta.IsStatusConditionTrue(mr.Status.Conditions, ConditionTypeNameConflict)).To(BeTrue())
+		Expect(recorder.Events).To(Receive(ContainSubstring("VMI worker-0 exists without a VM")))
+		_, err = hc.GetVMI(ctx, "worker-0")
+		Expect(err).NotTo(HaveOccurred())
+	})
+})
+
+var _ = Describe("Create duration metric", func() {
+	// createSamples returns the number and sum of create duration samples.
+	createSamples := func() (uint64, float64) {
+		m := &dto.Metric{}
+		Expect(metrics.CreateDuration.Write(m)).To(Succeed())
+		return m.GetHistogram().GetSampleCount(), m.GetHistogram().GetSampleSum()
+	}
+
+	It("observes the time since the MachineRequest entered Pending", func() {
+		ctx := context.Background()
+		mr := testMachineRequest(nil)
+		mr.Annotations[AnnotationPendingSince] = time.Now().Add(-2 * time.Minute).UTC().Format(time.RFC3339)
+		r, _ := testReconciler(mr)
+		count, sum := createSamples()
+
+		Expect(r.observeCreateDuration(ctx, mr)).To(Succeed())
+		gotCount, gotSum := createSamples()
+		Expect(gotCount).To(Equal(count + 1))
+		Expect(gotSum - sum).To(BeNumerically("~", 120, 5))
+		got := &butlerv1alpha1.MachineRequest{}
+		Expect(r.Get(ctx, client.ObjectKeyFromObject(mr), got)).To(Succeed())
+		Expect(got.Annotations).NotTo(HaveKey(AnnotationPendingSince))
+	})
+
+	It("skips MachineRequests without a recorded start time", func() {
+		mr := testMachineRequest(nil)
+		r, _ := testReconciler(mr)
+		count, _ := createSamples()
+
+		Expect(r.observeCreateDuration(context.Background(), mr)).To(Succeed())
+		gotCount, _ := createSamples()
+		Expect(gotCount).To(Equal(count))
+	})
+})
+
+var _ = Describe("Retrying a failed MachineRequest", func() {
+	It("returns it to Pending and records the reset", func() {
+		ctx := context.Background()
+		mr := testMachineRequest(map[string]string{"retry": ""})
+		mr.Annotations[AnnotationCreateFailures] = "3"
+		mr.Status.Phase = butlerv1alpha1.MachinePhaseFailed
+		mr.SetFailure("ProviderConfigError", "ProviderConfig not found")
+		r, recorder := testReconciler(mr)
+
+		result, err := r.retryFailed(ctx, mr, testHarvesterClient())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Requeue).To(BeTrue())
+
+		got := &butlerv1alpha1.MachineRequest{}
+		Expect(r.Get(ctx, client.ObjectKeyFromObject(mr), got)).To(Succeed())
+		Expect(got.Status.Phase).To(Equal(butlerv1alpha1.MachinePhasePending))
+		Expect(got.Status.FailureReason).To(BeEmpty())
+		Expect(got.Status.FailureMessage).To(BeEmpty())
+		Expect(got.Annotations).NotTo(HaveKey(AnnotationRetry))
+		Expect(got.Annotations).NotTo(HaveKey(AnnotationCreateFailures))
+		progressing := meta.FindStatusCondition(got.Status.Conditions, butlerv1alpha1.ConditionTypeProgressing)
+		Expect(progressing).NotTo(BeNil())
+		Expect(progressing.Reason).To(Equal(ReasonRetrying))
+		Expect(recorder.Events).To(Receive(And(
+			ContainSubstring(ReasonRetrying),
+			ContainSubstring("previous failure ProviderConfigError: ProviderConfig not found"))))
+	})
+})
+
+var _ = Describe("Requeue intervals", func() {
+	// expectJittered checks that interval returns values in [base, base*(1+jitter)].
+	expectJittered := func(interval func() time.Duration, base time.Duration) {
+		for range 50 {
+			got := interval()
+			Expect(got).To(BeNumerically(">=", base))
+			Expect(got).To(BeNumerically("<=", base+time.Duration(float64(base)*requeueJitter)))
+		}
+	}
+
+	It("defaults the intervals", func() {
+		r := &MachineRequestReconciler{}
+		expectJittered(r.requeueShort, defaultRequeueShort)
+		expectJittered(r.requeueLong, defaultRequeueLong)
+	})
+
+	It("uses the configured intervals", func() {
+		r := &MachineRequestReconciler{RequeueShort: time.Second, RequeueLong: time.Minute}
+		expectJittered(r.requeueShort, time.Second)
+		expectJittered(r.requeueLong, time.Minute)
+	})
+})
+
+var _ = Describe("Observed VM annotations", func() {
+	DescribeTable("reports what changed on the VM",
+		func(node, guestOS string, status harvester.VMStatus, want map[string]string) {
+			mr := testMachineRequest(map[string]string{"node": node, "guest-os": guestOS})
+			Expect(observedAnnotations(mr, &status)).To(Equal(want))
+		},
+		Entry("nothing changed", "harvester-0", "Ubuntu 24.04",
+			harvester.VMStatus{NodeName: "harvester-0", GuestOS: "Ubuntu 24.04"}, map[string]string{}),
+		Entry("the VM moved to another node", "harvester-0", "Ubuntu 24.04",
+			harvester.VMStatus{NodeName: "harvester-1", GuestOS: "Ubuntu 24.04"},
+			map[string]string{AnnotationNode: "harvester-1"}),
+		Entry("the VM stopped running", "harvester-0", "Ubuntu 24.04",
+			harvester.VMStatus{GuestOS: "Ubuntu 24.04"}, map[string]string{AnnotationNode: ""}),
+		Entry("the guest OS changed", "harvester-0", "Ubuntu 24.04",
+			harvester.VMStatus{NodeName: "harvester-0", GuestOS: "Ubuntu 26.04"},
+			map[string]string{AnnotationGuestOS: "Ubuntu 26.04"}),
+		Entry("the guest agent reports no OS", "harvester-0", "Ubuntu 24.04",
+			harvester.VMStatus{NodeName: "harvester-0"}, map[string]string{}),
+	)
+})
+
+var _ = Describe("Deleting during a create", func() {
+	var (
+		ctx context.Context
+		mr  *butlerv1alpha1.MachineRequest
+	)
+
+	// rootDisk returns a root disk PVC of the test VM created for ownerUID.
+	rootDisk := func(ownerUID string) *corev1.PersistentVolumeClaim {
+		return &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{
+			Namespace:   "default",
+			Name:        rootDiskPVC(mr),
+			Annotations: map[string]string{harvester.AnnotationOwnerUID: ownerUID},
+		}}
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		mr = testMachineRequest(nil)
+		mr.Finalizers = []string{finalizerName}
+		mr.Status.Phase = butlerv1alpha1.MachinePhasePending
+	})
+
+	It("waits for a create that may still be running", func() {
+		mr.Annotations[AnnotationCreateStarted] = time.Now().Add(-10 * time.Second).UTC().Format(time.RFC3339)
+		r, _ := testReconciler(mr)
+		hc := testHarvesterClient(rootDisk("uid-1"))
+
+		result, err := r.reconcileDelete(ctx, mr, hc)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.RequeueAfter).To(BeNumerically("~", createSettleTime-10*time.Second, 5*time.Second))
+		Expect(mr.Finalizers).To(ContainElement(finalizerName))
+		progressing := meta.FindStatusCondition(mr.Status.Conditions, butlerv1alpha1.ConditionTypeProgressing)
+		Expect(progressing.Reason).To(Equal(ReasonWaitingForCreate))
+		_, err = hc.GetPVCStatus(ctx, rootDiskPVC(mr))
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	DescribeTable("cleans up the disk an interrupted create left behind",
+		func(ownerUID string, deleted bool) {
+			mr.Annotations[AnnotationCreateStarted] = time.Now().Add(-2 * createSettleTime).UTC().Format(time.RFC3339)
+			r, _ := testReconciler(mr)
+			hc := testHarvesterClient(rootDisk(ownerUID))
+
+			_, err := r.reconcileDelete(ctx, mr, hc)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mr.Finalizers).NotTo(ContainElement(finalizerName))
+			_, err = hc.GetPVCStatus(ctx, rootDiskPVC(mr))
+			Expect(apierrors.IsNotFound(err)).To(Equal(deleted), "got %v", err)
+		},
+		Entry("created for this MachineRequest", "uid-1", true),
+		Entry("created for another MachineRequest", "uid-2", false),
+	)
+
+	It("leaves disks alone without an interrupted create", func() {
+		r, _ := testReconciler(mr)
+		hc := testHarvesterClient(rootDisk("uid-1"))
+
+		_, err := r.reconcileDelete(ctx, mr, hc)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(mr.Finalizers).NotTo(ContainElement(finalizerName))
+		_, err = hc.GetPVCStatus(ctx, rootDiskPVC(mr))
+		Expect(err).NotTo(HaveOccurred())
+	})
+})
+
+// existingVM returns a VM named name that is stamped with ownerUID, and the
+// VMI of a VM running on harvester-0 and reporting ip when ip is set.
+func existingVM(name, ownerUID, ip string) []runtime.Object {
+	vm := &unstructured.Unstructured{}
+	vm.SetAPIVersion("kubevirt.io/v1")
+	vm.SetKind("VirtualMachine")
+	vm.SetNamespace("default")
+	vm.SetName(name)
+	vm.SetUID("vm-uid")
+	vm.SetAnnotations(map[string]string{
+		harvester.AnnotationOwnerUID: ownerUID,
+		harvester.AnnotationOwner:    "butler-system/other",
+	})
+	if ip == "" {
+		return []runtime.Object{vm}
+	}
+	vmi := &unstructured.Unstructured{}
+	vmi.SetAPIVersion("kubevirt.io/v1")
+	vmi.SetKind("VirtualMachineInstance")
+	vmi.SetNamespace("default")
+	vmi.SetName(name)
+	Expect(unstructured.SetNestedSlice(vmi.Object, []interface{}{map[string]interface{}{
+		"name":      "default",
+		"ipAddress": ip,
+	}}, "status", "interfaces")).To(Succeed())
+	Expect(unstructured.SetNestedField(vmi.Object, "harvester-0", "status", "nodeName")).To(Succeed())
+	return []runtime.Object{vm, vmi}
+}
+
+var _ = Describe("Adopting the VM after status loss", func() {
+	var (
+		ctx context.Context
+		mr  *butlerv1alpha1.MachineRequest
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		mr = testMachineRequest(nil)
+		mr.Finalizers = []string{finalizerName}
+	})
+
+	DescribeTable("rebuilds the status from its own VM",
+		func(ip string, phase butlerv1alpha1.MachinePhase, condition string) {
+			r, recorder := testReconciler(mr)
+
+			_, adopted, err := r.adoptExistingVM(ctx, mr, testHarvesterClient(existingVM("worker-0", "uid-1", ip)...))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(adopted).To(BeTrue())
+			got := &butlerv1alpha1.MachineRequest{}
+			Expect(r.Get(ctx, client.ObjectKeyFromObject(mr), got)).To(Succeed())
+			Expect(got.Status.Phase).To(Equal(phase))
+			Expect(got.Status.ProviderID).To(Equal("vm-uid"))
+			Expect(got.Status.IPAddress).To(Equal(ip))
+			Expect(meta.IsStatusConditionTrue(got.Status.Conditions, condition)).To(BeTrue())
+			Expect(recorder.Events).To(Receive(ContainSubstring("Adopted existing VM after status loss worker-0 (provider ID vm-uid)")))
+		},
+		Entry("running with an IP", "10.0.0.5", butlerv1alpha1.MachinePhaseRunning, butlerv1alpha1.ConditionTypeReady),
+		Entry("still starting", "", butlerv1alpha1.MachinePhaseCreating, butlerv1alpha1.ConditionTypeProgressing),
+	)
+
+	It("looks the VM up under the recorded machine name", func() {
+		mr.Annotations[AnnotationImmutableFields] = `{"spec.machineName":"worker-old"}`
+		r, _ := testReconciler(mr)
+
+		_, adopted, err := r.adoptExistingVM(ctx, mr, testHarvesterClient(existingVM("worker-old", "uid-1", "10.0.0.5")...))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(adopted).To(BeTrue())
+		Expect(mr.Status.ProviderID).To(Equal("vm-uid"))
+	})
+
+	DescribeTable("leaves the create path to handle",
+		func(objects []runtime.Object) {
+			r, _ := testReconciler(mr)
+
+			_, adopted, err := r.adoptExistingVM(ctx, mr, testHarvesterClient(objects...))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(adopted).To(BeFalse())
+			Expect(mr.Status.Phase).To(BeEmpty())
+			Expect(mr.Status.ProviderID).To(BeEmpty())
+		},
+		Entry("no VM", nil),
+		Entry("a VM of another MachineRequest", existingVM("worker-0", "uid-2", "10.0.0.5")),
+		Entry("an unstamped VM", existingVM("worker-0", "", "")),
+	)
+})
+
+var _ = Describe("Recovering an unknown phase", func() {
+	var (
+		ctx context.Context
+		mr  *butlerv1alpha1.MachineRequest
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		mr = testMachineRequest(nil)
+		mr.Finalizers = []string{finalizerName}
+		mr.Status.Phase = "Provisioning"
+	})
+
+	DescribeTable("re-derives the phase from the live VM",
+		func(objects []runtime.Object, phase butlerv1alpha1.MachinePhase) {
+			r, _ := testReconciler(mr)
+
+			_, err := r.recoverUnknownPhase(ctx, mr, testHarvesterClient(objects...))
+			Expect(err).NotTo(HaveOccurred())
+			got := &butlerv1alpha1.MachineRequest{}
+			Expect(r.Get(ctx, client.ObjectKeyFromObject(mr), got)).To(Succeed())
+			Expect(got.Status.Phase).To(Equal(phase))
+		},
+		Entry("no VM", nil, butlerv1alpha1.MachinePhasePending),
+		Entry("a VM without an IP", existingVM("worker-0", "uid-1", ""), butlerv1alpha1.MachinePhaseCreating),
+		Entry("a VM with an IP", existingVM("worker-0", "uid-1", "10.0.0.5"), butlerv1alpha1.MachinePhaseRunning),
+	)
+
+	It("keeps the address and provider ID of a running VM", func() {
+		r, _ := testReconciler(mr)
+
+		_, err := r.recoverUnknownPhase(ctx, mr, testHarvesterClient(existingVM("worker-0", "uid-1", "10.0.0.5")...))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(mr.Status.ProviderID).To(Equal("vm-uid"))
+		Expect(mr.Status.IPAddress).To(Equal("10.0.0.5"))
+		Expect(meta.IsStatusConditionTrue(mr.Status.Conditions, butlerv1alpha1.ConditionTypeReady)).To(BeTrue())
+	})
+
+	It("reports a VM of another MachineRequest as a name conflict", func() {
+		r, recorder := testReconciler(mr)
+
+		_, err := r.recoverUnknownPhase(ctx, mr, testHarvesterClient(existingVM("worker-0", "uid-2", "")...))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(mr.Status.Phase).To(Equal(butlerv1alpha1.MachinePhaseFailed))
+		Expect(mr.Status.FailureReason).To(Equal(ReasonNameConflict))
+		Expect(recorder.Events).To(Receive(ContainSubstring("already managed by MachineRequest butler-system/other (uid uid-2)")))
+	})
+})
+
+var _ = Describe("Root disk storage warnings", func() {
+	It("warns once while the storage problem persists", func() {
+		ctx := context.Background()
+		mr := testMachineRequest(nil)
+		mr.Finalizers = []string{finalizerName}
+		mr.Status.Phase = butlerv1alpha1.MachinePhaseCreating
+		storageClass := "longhorn-image-abc12"
+		pvc := &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: rootDiskPVC(mr)},
+			Spec:       corev1.PersistentVolumeClaimSpec{StorageClassName: &storageClass},
+			Status:     corev1.PersistentVolumeClaimStatus{Phase: corev1.ClaimPending},
+		}
+		r, recorder := testReconciler(mr)
+		hc := testHarvesterClient(append(existingVM("worker-0", "uid-1", ""), pvc)...)
+
+		for range 3 {
+			_, err := r.reconcileCreating(ctx, mr, &butlerv1alpha1.ProviderConfig{}, hc)
+			Expect(err).NotTo(HaveOccurred())
+		}
+		progressing := meta.FindStatusCondition(mr.Status.Conditions, butlerv1alpha1.ConditionTypeProgressing)
+		Expect(progressing.Reason).To(Equal(ReasonWaitingForStorage))
+		Expect(recorder.Events).To(Receive(ContainSubstring(harvester.PVCReasonStorageClassNotFound)))
+		Expect(recorder.Events).NotTo(Receive())
+	})
+})
+
+var _ = Describe("Leftover unpause requests", func() {
+	var (
+		ctx context.Context
+		mr  *butlerv1alpha1.MachineRequest
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		mr = testMachineRequest(map[string]string{"unpause": ""})
+		mr.Finalizers = []string{finalizerName}
+	})
+
+	// unpauseRequested reports whether the stored MachineRequest still asks
+	// for an unpause.
+	unpauseRequested := func(r *MachineRequestReconciler) bool {
+		got := &butlerv1alpha1.MachineRequest{}
+		Expect(r.Get(ctx, client.ObjectKeyFromObject(mr), got)).To(Succeed())
+		_, ok := got.Annotations[AnnotationUnpause]
+		return ok
+	}
+
+	It("are removed once a created VM is not paused", func() {
+		mr.Status.Phase = butlerv1alpha1.MachinePhaseCreating
+		r, _ := testReconciler(mr)
+
+		_, err := r.reconcileCreating(ctx, mr, &butlerv1alpha1.ProviderConfig{}, testHarvesterClient(existingVM("worker-0", "uid-1", "10.0.0.5")...))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(mr.Status.Phase).To(Equal(butlerv1alpha1.MachinePhaseRunning))
+		Expect(unpauseRequested(r)).To(BeFalse())
+	})
+
+	It("are removed from a running VM", func() {
+		mr.Status.Phase = butlerv1alpha1.MachinePhaseRunning
+		mr.Status.IPAddress = "10.0.0.5"
+		r, _ := testReconciler(mr)
+
+		_, err := r.reconcileRunning(ctx, mr, &butlerv1alpha1.ProviderConfig{}, testHarvesterClient(existingVM("worker-0", "uid-1", "10.0.0.5")...))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(unpauseRequested(r)).To(BeFalse())
+		Expect(mr.Status.Phase).To(Equal(butlerv1alpha1.MachinePhaseRunning))
+	})
+})
@@ -0,0 +1,170 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	butlerv1alpha1 "github.com/butlerdotdev/butler-api/api/v1alpha1"
+	"github.com/butlerdotdev/butler-provider-harvester/internal/harvester"
+)
+
+// reconcileMigration starts the live migration requested through
+// AnnotationMigrate and tracks it until it finishes. The MachineRequest API
+// has no migrating phase, so the MachineRequest stays Running and the
+// Migrating condition reports the progress. It reports whether the rest of
+// the running reconcile should wait for a later pass.
+func (r *MachineRequestReconciler) reconcileMigration(
+	ctx context.Context,
+	mr *butlerv1alpha1.MachineRequest,
+	hc *harvester.Client,
+) (ctrl.Result, bool, error) {
+	if name := mr.Annotations[AnnotationMigration]; name != "" {
+		return r.trackMigration(ctx, mr, hc, name)
+	}
+	if _, ok := mr.Annotations[AnnotationMigrate]; !ok {
+		return ctrl.Result{}, false, nil
+	}
+	log := logf.FromContext(ctx)
+
+	log.Info("Starting live migration", "name", mr.Spec.MachineName)
+	name, err := hc.MigrateVM(ctx, mr.Spec.MachineName)
+	if errors.Is(err, harvester.ErrNotMigratable) {
+		log.Info("VM cannot be live migrated", "reason", err.Error())
+		if err := r.patchAnnotations(ctx, mr, map[string]string{AnnotationMigrate: ""}); err != nil {
+			return ctrl.Result{}, true, err
+		}
+		return r.finishMigration(ctx, mr, ReasonNotMigratable, "VM "+err.Error())
+	}
+	if err != nil {
+		log.Error(err, "Failed to start live migration")
+		r.Recorder.Eventf(mr, corev1.EventTypeWarning, ReasonMigrationFailed, "Failed to start live migration: %v", err)
+		return ctrl.Result{RequeueAfter: r.requeueShort()}, true, nil
+	}
+	if err := r.patchAnnotations(ctx, mr, map[string]string{
+		AnnotationMigrate:   "",
+		AnnotationMigration: name,
+	}); err != nil {
+		return ctrl.Result{}, true, err
+	}
+
+	meta.SetStatusCondition(&mr.Status.Conditions, metav1.Condition{
+		Type:               ConditionTypeMigrating,
+		Status:             metav1.ConditionTrue,
+		Reason:             ReasonMigrating,
+		Message:            fmt.Sprintf("Live migration %s started", name),
+		ObservedGeneration: mr.Generation,
+	})
+	if err := r.updateStatus(ctx, mr); err != nil {
+		return ctrl.Result{}, true, err
+	}
+	r.Recorder.Eventf(mr, corev1.EventTypeNormal, ReasonMigrating, "Started live migration %s", name)
+	return ctrl.Result{RequeueAfter: r.requeueShort()}, true, nil
+}
+
+// trackMigration polls the live migration in progress and reports its
+// outcome once it finishes.
+func (r *MachineRequestReconciler) trackMigration(
+	ctx context.Context,
+	mr *butlerv1alpha1.MachineRequest,
+	hc *harvester.Client,
+	name string,
+) (ctrl.Result, bool, error) {
+	status, err := hc.GetMigrationStatus(ctx, name)
+	if apierrors.IsNotFound(err) {
+		if err := r.patchAnnotations(ctx, mr, map[string]string{AnnotationMigration: ""}); err != nil {
+			return ctrl.Result{}, true, err
+		}
+		return r.finishMigration(ctx, mr, ReasonMigrationFailed, fmt.Sprintf("Live migration %s was deleted before it finished", name))
+	}
+	if err != nil {
+		logf.FromContext(ctx).Error(err, "Failed to get live migration status", "migration", name)
+		return ctrl.Result{RequeueAfter: r.requeueShort()}, true, nil
+	}
+	if !status.Finished() {
+		message := fmt.Sprintf("Live migration %s in progress", name)
+		if status.Phase != "" {
+			message += " (" + status.Phase + ")"
+		}
+		if status.TargetNode != "" {
+			message += " to node " + status.TargetNode
+		}
+		if cond := meta.FindStatusCondition(mr.Status.Conditions, ConditionTypeMigrating); cond == nil || cond.Message != message {
+			meta.SetStatusCondition(&mr.Status.Conditions, metav1.Condition{
+				Type:               ConditionTypeMigrating,
+				Status:             metav1.ConditionTrue,
+				Reason:             ReasonMigrating,
+				Message:            message,
+				ObservedGeneration: mr.Generation,
+			})
+			if err := r.updateStatus(ctx, mr); err != nil {
+				return ctrl.Result{}, true, err
+			}
+		}
+		return ctrl.Result{RequeueAfter: r.requeueShort()}, true, nil
+	}
+
+	if err := r.patchAnnotations(ctx, mr, map[string]string{AnnotationMigration: ""}); err != nil {
+		return ctrl.Result{}, true, err
+	}
+	if status.Phase == harvester.MigrationPhaseFailed {
+		message := fmt.Sprintf("Live migration %s failed", name)
+		if status.Message != "" {
+			message += ": " + status.Message
+		}
+		return r.finishMigration(ctx, mr, ReasonMigrationFailed, message)
+	}
+	message := fmt.Sprintf("Live migration %s succeeded", name)
+	if status.SourceNode != "" && status.TargetNode != "" {
+		message = fmt.Sprintf("Live migrated from node %s to node %s", status.SourceNode, status.TargetNode)
+	}
+	return r.finishMigration(ctx, mr, ReasonMigrated, message)
+}
+
+// finishMigration sets the Migrating condition False with the outcome and
+// emits an event, a warning unless the migration succeeded.
+func (r *MachineRequestReconciler) finishMigration(
+	ctx context.Context,
+	mr *butlerv1alpha1.MachineRequest,
+	reason, message string,
+) (ctrl.Result, bool, error) {
+	meta.SetStatusCondition(&mr.Status.Conditions, metav1.Condition{
+		Type:               ConditionTypeMigrating,
+		Status:             metav1.ConditionFalse,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: mr.Generation,
+	})
+	if err := r.updateStatus(ctx, mr); err != nil {
+		return ctrl.Result{}, true, err
+	}
+	eventType := corev1.EventTypeWarning
+	if reason == ReasonMigrated {
+		eventType = corev1.EventTypeNormal
+	}
+	r.Recorder.Event(mr, eventType, reason, message)
+	return ctrl.Result{RequeueAfter: r.requeueShort()}, true, nil
+}
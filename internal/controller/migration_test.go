@@ -0,0 +1,156 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	butlerv1alpha1 "github.com/butlerdotdev/butler-api/api/v1alpha1"
+)
+
+// testVMI returns the VMI of the test MachineRequest with the given
+// LiveMigratable status and the state of its last migration.
+func testVMI(migratable string, migrationState map[string]interface{}) *unstructured.Unstructured {
+	vmi := &unstructured.Unstructured{}
+	vmi.SetAPIVersion("kubevirt.io/v1")
+	vmi.SetKind("VirtualMachineInstance")
+	vmi.SetNamespace("default")
+	vmi.SetName("worker-0")
+	Expect(unstructured.SetNestedSlice(vmi.Object, []interface{}{map[string]interface{}{
+		"type":    "LiveMigratable",
+		"status":  migratable,
+		"message": "cannot migrate VMI: PVC rootdisk is not shared",
+	}}, "status", "conditions")).To(Succeed())
+	if migrationState != nil {
+		Expect(unstructured.SetNestedMap(vmi.Object, migrationState, "status", "migrationState")).To(Succeed())
+	}
+	return vmi
+}
+
+// testMigration returns a migration of the test VMI in the given phase.
+func testMigration(phase string) *unstructured.Unstructured {
+	migration := &unstructured.Unstructured{}
+	migration.SetAPIVersion("kubevirt.io/v1")
+	migration.SetKind("VirtualMachineInstanceMigration")
+	migration.SetNamespace("default")
+	migration.SetName("worker-0-migration-abcde")
+	migration.Object["spec"] = map[string]interface{}{"vmiName": "worker-0"}
+	if phase != "" {
+		migration.Object["status"] = map[string]interface{}{"phase": phase}
+	}
+	return migration
+}
+
+var _ = Describe("Live migration", func() {
+	var ctx context.Context
+
+	BeforeEach(func() {
+		ctx = context.Background()
+	})
+
+	// migratingCondition returns the stored Migrating condition of mr.
+	migratingCondition := func(r *MachineRequestReconciler, mr *butlerv1alpha1.MachineRequest) *metav1.Condition {
+		got := &butlerv1alpha1.MachineRequest{}
+		Expect(r.Get(ctx, client.ObjectKeyFromObject(mr), got)).To(Succeed())
+		return meta.FindStatusCondition(got.Status.Conditions, ConditionTypeMigrating)
+	}
+
+	It("does nothing without a request", func() {
+		mr := testMachineRequest(nil)
+		r, recorder := testReconciler(mr)
+		_, wait, err := r.reconcileMigration(ctx, mr, testHarvesterClient())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(wait).To(BeFalse())
+		Expect(recorder.Events).To(BeEmpty())
+	})
+
+	It("starts a requested migration", func() {
+		mr := testMachineRequest(map[string]string{"migrate": ""})
+		r, recorder := testReconciler(mr)
+		_, wait, err := r.reconcileMigration(ctx, mr, testHarvesterClient(testVMI("True", nil)))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(wait).To(BeTrue())
+		Expect(recorder.Events).To(Receive(ContainSubstring("Started live migration worker-0-migration-")))
+		Expect(mr.Annotations).NotTo(HaveKey(AnnotationMigrate))
+		Expect(mr.Annotations[AnnotationMigration]).To(HavePrefix("worker-0-migration-"))
+		Expect(migratingCondition(r, mr).Status).To(Equal(metav1.ConditionTrue))
+	})
+
+	It("drops the request for a VM that cannot migrate", func() {
+		mr := testMachineRequest(map[string]string{"migrate": ""})
+		r, recorder := testReconciler(mr)
+		_, wait, err := r.reconcileMigration(ctx, mr, testHarvesterClient(testVMI("False", nil)))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(wait).To(BeTrue())
+		Expect(recorder.Events).To(Receive(ContainSubstring(ReasonNotMigratable)))
+		Expect(mr.Annotations).NotTo(HaveKey(AnnotationMigrate))
+		Expect(mr.Annotations).NotTo(HaveKey(AnnotationMigration))
+		cond := migratingCondition(r, mr)
+		Expect(cond.Status).To(Equal(metav1.ConditionFalse))
+		Expect(cond.Message).To(ContainSubstring("PVC rootdisk is not shared"))
+	})
+
+	DescribeTable("tracks the migration in progress",
+		func(objects []runtime.Object, status metav1.ConditionStatus, reason, message string, done bool) {
+			mr := testMachineRequest(map[string]string{"migration": "worker-0-migration-abcde"})
+			r, recorder := testReconciler(mr)
+			_, wait, err := r.reconcileMigration(ctx, mr, testHarvesterClient(objects...))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(wait).To(BeTrue())
+
+			cond := migratingCondition(r, mr)
+			Expect(cond.Status).To(Equal(status))
+			Expect(cond.Reason).To(Equal(reason))
+			Expect(cond.Message).To(Equal(message))
+			if !done {
+				Expect(mr.Annotations).To(HaveKey(AnnotationMigration))
+				Expect(recorder.Events).To(BeEmpty())
+				return
+			}
+			Expect(mr.Annotations).NotTo(HaveKey(AnnotationMigration))
+			Expect(recorder.Events).To(Receive(ContainSubstring(message)))
+		},
+		Entry("scheduling", []runtime.Object{testMigration("Scheduling"), testVMI("True", nil)},
+			metav1.ConditionTrue, ReasonMigrating, "Live migration worker-0-migration-abcde in progress (Scheduling)", false),
+		Entry("running to a known node", []runtime.Object{
+			testMigration("Running"),
+			testVMI("True", map[string]interface{}{"sourceNode": "harvester-0", "targetNode": "harvester-1"}),
+		}, metav1.ConditionTrue, ReasonMigrating,
+			"Live migration worker-0-migration-abcde in progress (Running) to node harvester-1", false),
+		Entry("succeeded", []runtime.Object{
+			testMigration("Succeeded"),
+			testVMI("True", map[string]interface{}{"sourceNode": "harvester-0", "targetNode": "harvester-1"}),
+		}, metav1.ConditionFalse, ReasonMigrated, "Live migrated from node harvester-0 to node harvester-1", true),
+		Entry("succeeded after the VM stopped", []runtime.Object{testMigration("Succeeded")},
+			metav1.ConditionFalse, ReasonMigrated, "Live migration worker-0-migration-abcde succeeded", true),
+		Entry("failed", []runtime.Object{
+			testMigration("Failed"),
+			testVMI("True", map[string]interface{}{"failureReason": "target pod did not start"}),
+		}, metav1.ConditionFalse, ReasonMigrationFailed,
+			"Live migration worker-0-migration-abcde failed: target pod did not start", true),
+		Entry("deleted", nil, metav1.ConditionFalse, ReasonMigrationFailed,
+			"Live migration worker-0-migration-abcde was deleted before it finished", true),
+	)
+})
@@ -0,0 +1,504 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	butlerv1alpha1 "github.com/butlerdotdev/butler-api/api/v1alpha1"
+	"github.com/butlerdotdev/butler-provider-harvester/internal/harvester"
+)
+
+// vmCreateOptions builds the Harvester VM options from the MachineRequest spec
+// and its Harvester-specific annotations.
+func vmCreateOptions(mr *butlerv1alpha1.MachineRequest) (harvester.VMCreateOptions, error) {
+	opts := harvester.VMCreateOptions{
+		Name:        mr.Spec.MachineName,
+		CPU:         mr.Spec.CPU,
+		MemoryMB:    mr.Spec.MemoryMB,
+		DiskGB:      mr.Spec.DiskGB,
+		ImageName:   mr.Spec.Image,
+		UserData:    mr.Spec.UserData,
+		NetworkData: mr.Spec.NetworkData,
+		Labels:      mr.Spec.Labels,
+		OwnerUID:    string(mr.UID),
+		Owner:       mr.Namespace + "/" + mr.Name,
+		VolumeMode:  corev1.PersistentVolumeMode(mr.Annotations[AnnotationVolumeMode]),
+
+		StorageClass: mr.Annotations[AnnotationStorageClass],
+
+		HugepagesPageSize: mr.Annotations[AnnotationHugepagesPageSize],
+
+		FirmwareUUID:       mr.Annotations[AnnotationFirmwareUUID],
+		FirmwareSerial:     mr.Annotations[AnnotationFirmwareSerial],
+		SMBIOSManufacturer: mr.Annotations[AnnotationSMBIOSManufacturer],
+		SMBIOSProduct:      mr.Annotations[AnnotationSMBIOSProduct],
+
+		RootDiskPVCName:  mr.Annotations[AnnotationRootDiskPVCName],
+		RootDiskSerial:   mr.Annotations[AnnotationRootDiskSerial],
+		DiskBus:          harvester.DiskBus(mr.Annotations[AnnotationDiskBus]),
+		RuntimeClassName: mr.Annotations[AnnotationRuntimeClassName],
+		SSHKeySecret:     mr.Annotations[AnnotationSSHKeySecret],
+
+		ImageSelector:      mr.Annotations[AnnotationImageSelector],
+		ImageNamespace:     mr.Annotations[AnnotationImageNamespace],
+		ImageSelectorOrder: mr.Annotations[AnnotationImageSelectorOrder],
+
+		RestoreFromBackup:  mr.Annotations[AnnotationRestoreFromBackup],
+		ContainerDiskImage: mr.Annotations[AnnotationContainerDiskImage],
+		ImagePullSecret:    mr.Annotations[AnnotationImagePullSecret],
+		RootDiskImportURL:  mr.Annotations[AnnotationRootDiskImportURL],
+
+		SSHKeyUsers:   listAnnotation(mr, AnnotationSSHKeyUsers),
+		SSHPublicKeys: linesAnnotation(mr, AnnotationSSHPublicKeys),
+
+		NetworkName: mr.Annotations[AnnotationNetworkName],
+
+		DNSServers:        listAnnotation(mr, AnnotationDNSServers),
+		DNSSearch:         listAnnotation(mr, AnnotationDNSSearch),
+		ClusterDNSDomain:  mr.Annotations[AnnotationClusterDNSDomain],
+		NetworkDataFormat: harvester.NetworkDataFormat(mr.Annotations[AnnotationNetworkDataFormat]),
+		NetworkBinding:    harvester.NetworkBinding(mr.Annotations[AnnotationNetworkBinding]),
+
+		UserDataValidation: harvester.UserDataValidation(mr.Annotations[AnnotationUserDataValidation]),
+
+		InterfacePCIAddress: mr.Annotations[AnnotationInterfacePCIAddress],
+	}
+
+	if value, ok := mr.Annotations[AnnotationRuntimeClassName]; ok && strings.TrimSpace(value) == "" {
+		return opts, fmt.Errorf("annotation %s must not be empty", AnnotationRuntimeClassName)
+	}
+
+	var err error
+	if opts.DedicatedCPUPlacement, err = boolAnnotation(mr, AnnotationDedicatedCPUPlacement); err != nil {
+		return opts, err
+	}
+	if opts.IsolateEmulatorThread, err = boolAnnotation(mr, AnnotationIsolateEmulatorThread); err != nil {
+		return opts, err
+	}
+	if opts.StartPaused, err = boolAnnotation(mr, AnnotationStartPaused); err != nil {
+		return opts, err
+	}
+	if opts.EnableOvercommit, err = boolAnnotation(mr, AnnotationMemoryOvercommit); err != nil {
+		return opts, err
+	}
+	if opts.MemoryRequestPercent, err = intAnnotation(mr, AnnotationMemoryRequestPercent); err != nil {
+		return opts, err
+	}
+	if value, ok := mr.Annotations[AnnotationMemoryOverheadMB]; ok && value != "" {
+		overhead, err := strconv.ParseInt(value, 10, 32)
+		if err != nil {
+			return opts, fmt.Errorf("annotation %s: invalid integer %q", AnnotationMemoryOverheadMB, value)
+		}
+		overheadMB := int32(overhead)
+		opts.MemoryOverheadMB = &overheadMB
+	}
+	if opts.MemoryRequestMB, err = int32Annotation(mr, AnnotationMemoryRequestMB); err != nil {
+		return opts, err
+	}
+	if opts.MemoryLimitMB, err = int32Annotation(mr, AnnotationMemoryLimitMB); err != nil {
+		return opts, err
+	}
+	if opts.CPURequestMillicores, err = int32Annotation(mr, AnnotationCPURequestMillicores); err != nil {
+		return opts, err
+	}
+	if opts.Sockets, err = int32Annotation(mr, AnnotationCPUSockets); err != nil {
+		return opts, err
+	}
+	if opts.Threads, err = int32Annotation(mr, AnnotationCPUThreads); err != nil {
+		return opts, err
+	}
+	if opts.InterfaceACPIIndex, err = int32Annotation(mr, AnnotationInterfaceACPIIndex); err != nil {
+		return opts, err
+	}
+	if opts.EphemeralScratchGB, err = int32Annotation(mr, AnnotationEphemeralScratchGB); err != nil {
+		return opts, err
+	}
+	if opts.NUMACells, err = numaCellsAnnotation(mr, AnnotationNUMACells); err != nil {
+		return opts, err
+	}
+	if opts.PersistentCloudInit, err = boolAnnotation(mr, AnnotationPersistentCloudInit); err != nil {
+		return opts, err
+	}
+	if opts.Routes, err = routesAnnotation(mr, AnnotationRoutes); err != nil {
+		return opts, err
+	}
+	if opts.Networks, err = networksAnnotation(mr, AnnotationNetworks); err != nil {
+		return opts, err
+	}
+	if address := mr.Annotations[AnnotationStaticIP]; address != "" {
+		opts.StaticIP = &harvester.StaticIP{Address: address, Gateway: mr.Annotations[AnnotationStaticIPGateway]}
+	}
+	if opts.DiskSize, err = quantityAnnotation(mr, AnnotationDiskSize); err != nil {
+		return opts, err
+	}
+	if opts.DiskSizeGranularity, err = quantityAnnotation(mr, AnnotationDiskSizeGranularity); err != nil {
+		return opts, err
+	}
+	if opts.CloudInitDiskSize, err = quantityAnnotation(mr, AnnotationCloudInitDiskSize); err != nil {
+		return opts, err
+	}
+	if opts.CACerts, err = pemAnnotation(mr, AnnotationCACerts); err != nil {
+		return opts, err
+	}
+	if opts.FirmwareEFI, err = boolAnnotation(mr, AnnotationFirmwareEFI); err != nil {
+		return opts, err
+	}
+	if opts.FirmwareSecureBoot, err = boolAnnotation(mr, AnnotationSecureBoot); err != nil {
+		return opts, err
+	}
+	if opts.RootDiskPreallocation, err = boolAnnotation(mr, AnnotationRootDiskPreallocation); err != nil {
+		return opts, err
+	}
+	if opts.RootDiskShareable, err = boolAnnotation(mr, AnnotationRootDiskShareable); err != nil {
+		return opts, err
+	}
+	if opts.AttachedDisks, err = attachedDisksAnnotation(mr, AnnotationAttachedDisks); err != nil {
+		return opts, err
+	}
+	if len(mr.Spec.ExtraDisks) > 0 {
+		opts.DataDisks, err = extraDisks(mr, AnnotationDataDisks)
+	} else {
+		opts.DataDisks, err = dataDisksAnnotation(mr, AnnotationDataDisks)
+	}
+	if err != nil {
+		return opts, err
+	}
+	for _, entry := range listAnnotation(mr, AnnotationGPUs) {
+		deviceName, name, _ := strings.Cut(entry, ":")
+		opts.GPUs = append(opts.GPUs, harvester.GPUDevice{DeviceName: deviceName, Name: name})
+	}
+
+	return opts, nil
+}
+
+// durationSetting returns a positive duration from the MachineRequest
+// annotation key, then the ProviderConfig's, then def.
+func durationSetting(pc *butlerv1alpha1.ProviderConfig, mr *butlerv1alpha1.MachineRequest, key string, def time.Duration) (time.Duration, error) {
+	for _, annotations := range []map[string]string{mr.Annotations, pc.Annotations} {
+		value := annotations[key]
+		if value == "" {
+			continue
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil || d <= 0 {
+			return 0, fmt.Errorf("annotation %s: invalid duration %q", key, value)
+		}
+		return d, nil
+	}
+	return def, nil
+}
+
+// boolSetting returns a boolean from the MachineRequest annotation key, then
+// the ProviderConfig's, then def.
+func boolSetting(pc *butlerv1alpha1.ProviderConfig, mr *butlerv1alpha1.MachineRequest, key string, def bool) (bool, error) {
+	for _, annotations := range []map[string]string{mr.Annotations, pc.Annotations} {
+		value := annotations[key]
+		if value == "" {
+			continue
+		}
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return false, fmt.Errorf("annotation %s: invalid boolean %q", key, value)
+		}
+		return b, nil
+	}
+	return def, nil
+}
+
+// rootDiskPVC returns the root disk PVC recorded for the MachineRequest,
+// falling back to the default naming scheme.
+func rootDiskPVC(mr *butlerv1alpha1.MachineRequest) string {
+	if name := mr.Annotations[AnnotationRootDiskPVC]; name != "" {
+		return name
+	}
+	return harvester.RootDiskPVCName(mr.Spec.MachineName)
+}
+
+// userDataHash returns a stable fingerprint of the cloud-init user data.
+func userDataHash(userData string) string {
+	sum := sha256.Sum256([]byte(userData))
+	return hex.EncodeToString(sum[:])
+}
+
+// routesAnnotation parses a comma-separated list of routes in the form
+// "<cidr> via <gateway> [metric <n>]".
+func routesAnnotation(mr *butlerv1alpha1.MachineRequest, key string) ([]harvester.RouteSpec, error) {
+	var routes []harvester.RouteSpec
+	for _, entry := range listAnnotation(mr, key) {
+		fields := strings.Fields(entry)
+		if (len(fields) != 3 && len(fields) != 5) || fields[1] != "via" || (len(fields) == 5 && fields[3] != "metric") {
+			return nil, fmt.Errorf("annotation %s: invalid route %q (want \"<cidr> via <gateway> [metric <n>]\")", key, entry)
+		}
+		route := harvester.RouteSpec{Destination: fields[0], Gateway: fields[2]}
+		if len(fields) == 5 {
+			metric, err := strconv.Atoi(fields[4])
+			if err != nil {
+				return nil, fmt.Errorf("annotation %s: invalid route metric %q", key, fields[4])
+			}
+			route.Metric = metric
+		}
+		routes = append(routes, route)
+	}
+	return routes, nil
+}
+
+// podNetwork names the pod network in the networks annotation.
+const podNetwork = "pod"
+
+// networksAnnotation parses a comma-separated list of network interfaces in
+// the form "<network> [name <name>] [binding <binding>] [mac <mac>]
+// [address <cidr>]... [gateway <ip>]". A network of "pod" is the pod network
+// with masquerade binding.
+func networksAnnotation(mr *butlerv1alpha1.MachineRequest, key string) ([]harvester.NetworkInterface, error) {
+	var interfaces []harvester.NetworkInterface
+	for _, entry := range listAnnotation(mr, key) {
+		fields := strings.Fields(entry)
+		if len(fields)%2 != 1 {
+			return nil, fmt.Errorf("annotation %s: invalid network %q (want \"<network> [name <name>] [binding <binding>] [mac <mac>] [address <cidr>]... [gateway <ip>] [acpi-index <n>] [pci-address <addr>]\")", key, entry)
+		}
+		iface := harvester.NetworkInterface{NetworkName: fields[0]}
+		if fields[0] == podNetwork {
+			iface = harvester.NetworkInterface{Binding: harvester.NetworkBindingMasquerade}
+		}
+		for i := 1; i < len(fields); i += 2 {
+			switch value := fields[i+1]; fields[i] {
+			case "name":
+				iface.Name = value
+			case "binding":
+				iface.Binding = harvester.NetworkBinding(value)
+			case "mac":
+				iface.MACAddress = value
+			case "address":
+				iface.Addresses = append(iface.Addresses, value)
+			case "gateway":
+				iface.Gateway = value
+			case "acpi-index":
+				index, err := strconv.ParseInt(value, 10, 32)
+				if err != nil {
+					return nil, fmt.Errorf("annotation %s: invalid ACPI index %q in %q", key, value, entry)
+				}
+				iface.ACPIIndex = int32(index)
+			case "pci-address":
+				iface.PCIAddress = value
+			default:
+				return nil, fmt.Errorf("annotation %s: unknown network setting %q in %q", key, fields[i], entry)
+			}
+		}
+		interfaces = append(interfaces, iface)
+	}
+	return interfaces, nil
+}
+
+// numaCellsAnnotation parses a comma-separated list of NUMA cells in the form
+// "<cpus>:<memoryMB>".
+func numaCellsAnnotation(mr *butlerv1alpha1.MachineRequest, key string) ([]harvester.NUMACell, error) {
+	var cells []harvester.NUMACell
+	for _, entry := range listAnnotation(mr, key) {
+		cpus, memory, ok := strings.Cut(entry, ":")
+		c, cpuErr := strconv.ParseInt(cpus, 10, 32)
+		m, memErr := strconv.ParseInt(memory, 10, 32)
+		if !ok || cpuErr != nil || memErr != nil {
+			return nil, fmt.Errorf("annotation %s: invalid NUMA cell %q (want \"<cpus>:<memoryMB>\")", key, entry)
+		}
+		cells = append(cells, harvester.NUMACell{CPUs: int32(c), MemoryMB: int32(m)})
+	}
+	return cells, nil
+}
+
+// attachedDisksAnnotation parses a comma-separated list of PVC names, each
+// optionally suffixed with ":shareable".
+func attachedDisksAnnotation(mr *butlerv1alpha1.MachineRequest, key string) ([]harvester.AttachedDisk, error) {
+	var disks []harvester.AttachedDisk
+	for _, entry := range listAnnotation(mr, key) {
+		name, flag, hasFlag := strings.Cut(entry, ":")
+		if hasFlag && flag != "shareable" {
+			return nil, fmt.Errorf("annotation %s: invalid disk %q (want \"<pvc>[:shareable]\")", key, entry)
+		}
+		disks = append(disks, harvester.AttachedDisk{ClaimName: name, Shareable: hasFlag})
+	}
+	return disks, nil
+}
+
+// dataDisksAnnotation parses a comma-separated list of data disks, each
+// "<sizeGB>[:<storageClass>[:<bus>[:<serial>]]]". Empty fields keep their
+// defaults.
+func dataDisksAnnotation(mr *butlerv1alpha1.MachineRequest, key string) ([]harvester.DataDiskSpec, error) {
+	var disks []harvester.DataDiskSpec
+	for _, entry := range listAnnotation(mr, key) {
+		fields := strings.Split(entry, ":")
+		if len(fields) > 4 {
+			return nil, fmt.Errorf("annotation %s: invalid disk %q (want \"<sizeGB>[:<storageClass>[:<bus>[:<serial>]]]\")", key, entry)
+		}
+		size, err := strconv.ParseInt(fields[0], 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("annotation %s: invalid disk size %q: %w", key, fields[0], err)
+		}
+		disk := harvester.DataDiskSpec{SizeGB: int32(size)}
+		if len(fields) > 1 {
+			disk.StorageClass = fields[1]
+		}
+		if len(fields) > 2 {
+			disk.Bus = harvester.DiskBus(fields[2])
+		}
+		if len(fields) > 3 {
+			disk.Serial = fields[3]
+		}
+		disks = append(disks, disk)
+	}
+	return disks, nil
+}
+
+// extraDisks returns spec.extraDisks as data disks. The annotation key may
+// then only add what the spec lacks: its comma-separated entries apply to the
+// disks in order, each "::<bus>[:<serial>]" with the size and storage class
+// left empty. An empty entry keeps the defaults.
+func extraDisks(mr *butlerv1alpha1.MachineRequest, key string) ([]harvester.DataDiskSpec, error) {
+	disks := make([]harvester.DataDiskSpec, len(mr.Spec.ExtraDisks))
+	for i, disk := range mr.Spec.ExtraDisks {
+		disks[i] = harvester.DataDiskSpec{SizeGB: disk.SizeGB, StorageClass: disk.StorageClass}
+	}
+	value := strings.TrimSpace(mr.Annotations[key])
+	if value == "" {
+		return disks, nil
+	}
+	entries := strings.Split(value, ",")
+	if len(entries) > len(disks) {
+		return nil, fmt.Errorf("annotation %s: %d entries for %d spec.extraDisks", key, len(entries), len(disks))
+	}
+	for i, entry := range entries {
+		fields := strings.Split(strings.TrimSpace(entry), ":")
+		if len(fields) > 4 {
+			return nil, fmt.Errorf("annotation %s: invalid disk %q (want \"::<bus>[:<serial>]\")", key, entry)
+		}
+		if fields[0] != "" || (len(fields) > 1 && fields[1] != "") {
+			return nil, fmt.Errorf("annotation %s: disk %d takes its size and storage class from spec.extraDisks; "+
+				"leave them empty, e.g. \"::scsi\"", key, i)
+		}
+		if len(fields) > 2 {
+			disks[i].Bus = harvester.DiskBus(fields[2])
+		}
+		if len(fields) > 3 {
+			disks[i].Serial = fields[3]
+		}
+	}
+	return disks, nil
+}
+
+// pemAnnotation splits a PEM bundle annotation into one PEM string per block.
+func pemAnnotation(mr *butlerv1alpha1.MachineRequest, key string) ([]string, error) {
+	var blocks []string
+	rest := []byte(mr.Annotations[key])
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		blocks = append(blocks, string(pem.EncodeToMemory(block)))
+	}
+	if strings.TrimSpace(string(rest)) != "" {
+		return nil, fmt.Errorf("annotation %s: invalid PEM data", key)
+	}
+	return blocks, nil
+}
+
+// quantityAnnotation parses a resource quantity annotation, returning a zero
+// quantity when it is unset.
+func quantityAnnotation(mr *butlerv1alpha1.MachineRequest, key string) (resource.Quantity, error) {
+	value, ok := mr.Annotations[key]
+	if !ok || value == "" {
+		return resource.Quantity{}, nil
+	}
+	q, err := resource.ParseQuantity(value)
+	if err != nil {
+		return resource.Quantity{}, fmt.Errorf("annotation %s: invalid quantity %q", key, value)
+	}
+	return q, nil
+}
+
+// listAnnotation splits a comma-separated annotation, dropping empty entries.
+func listAnnotation(mr *butlerv1alpha1.MachineRequest, key string) []string {
+	var out []string
+	for _, v := range strings.Split(mr.Annotations[key], ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// linesAnnotation splits a newline-separated annotation, dropping blank lines
+// and "#" comments.
+func linesAnnotation(mr *butlerv1alpha1.MachineRequest, key string) []string {
+	var out []string
+	for _, line := range strings.Split(mr.Annotations[key], "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+			out = append(out, line)
+		}
+	}
+	return out
+}
+
+// intAnnotation parses an integer annotation, returning 0 when it is unset.
+func intAnnotation(mr *butlerv1alpha1.MachineRequest, key string) (int, error) {
+	value, ok := mr.Annotations[key]
+	if !ok || value == "" {
+		return 0, nil
+	}
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("annotation %s: invalid integer %q", key, value)
+	}
+	return i, nil
+}
+
+// int32Annotation parses a 32-bit integer annotation, returning 0 when it is
+// unset. Values out of range are rejected rather than truncated.
+func int32Annotation(mr *butlerv1alpha1.MachineRequest, key string) (int32, error) {
+	value, ok := mr.Annotations[key]
+	if !ok || value == "" {
+		return 0, nil
+	}
+	i, err := strconv.ParseInt(value, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("annotation %s: invalid 32-bit integer %q", key, value)
+	}
+	return int32(i), nil
+}
+
+// boolAnnotation parses a boolean annotation, returning false when it is unset.
+func boolAnnotation(mr *butlerv1alpha1.MachineRequest, key string) (bool, error) {
+	value, ok := mr.Annotations[key]
+	if !ok || value == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("annotation %s: invalid boolean %q", key, value)
+	}
+	return b, nil
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	butlerv1alpha1 "github.com/butlerdotdev/butler-api/api/v1alpha1"
+	"github.com/butlerdotdev/butler-provider-harvester/internal/harvester"
+)
+
+// testMachineRequest returns a MachineRequest with the given Harvester
+// annotations, keyed without the annotation prefix.
+func testMachineRequest(annotations map[string]string) *butlerv1alpha1.MachineRequest {
+	mr := &butlerv1alpha1.MachineRequest{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   "butler-system",
+			Name:        "worker-0",
+			UID:         "uid-1",
+			Generation:  1,
+			Annotations: map[string]string{},
+		},
+		Spec: butlerv1alpha1.MachineRequestSpec{
+			MachineName: "worker-0",
+			CPU:         2,
+			MemoryMB:    4096,
+			DiskGB:      20,
+			Image:       "default/image-abc12",
+		},
+	}
+	for key, value := range annotations {
+		mr.Annotations[annotationPrefix+key] = value
+	}
+	return mr
+}
+
+var _ = Describe("VM create options", func() {
+	DescribeTable("data disks",
+		func(extraDisks []butlerv1alpha1.DiskSpec, annotation string, want []harvester.DataDiskSpec) {
+			mr := testMachineRequest(map[string]string{"data-disks": annotation})
+			mr.Spec.ExtraDisks = extraDisks
+			opts, err := vmCreateOptions(mr)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(opts.DataDisks).To(Equal(want))
+		},
+		Entry("from the annotation alone", nil, "50,200:longhorn-ssd:scsi",
+			[]harvester.DataDiskSpec{{SizeGB: 50}, {SizeGB: 200, StorageClass: "longhorn-ssd", Bus: harvester.DiskBusSCSI}}),
+		Entry("from spec.extraDisks", []butlerv1alpha1.DiskSpec{{SizeGB: 50}, {SizeGB: 200, StorageClass: "longhorn-ssd"}}, "",
+			[]harvester.DataDiskSpec{{SizeGB: 50}, {SizeGB: 200, StorageClass: "longhorn-ssd"}}),
+		Entry("from spec.extraDisks with the annotation adding bus and serial",
+			[]butlerv1alpha1.DiskSpec{{SizeGB: 50}, {SizeGB: 200}, {SizeGB: 10}}, "::scsi:ETCD01,,::sata",
+			[]harvester.DataDiskSpec{
+				{SizeGB: 50, Bus: harvester.DiskBusSCSI, Serial: "ETCD01"},
+				{SizeGB: 200},
+				{SizeGB: 10, Bus: harvester.DiskBusSATA},
+			}),
+	)
+
+	DescribeTable("rejects a data-disks annotation conflicting with spec.extraDisks",
+		func(annotation, message string) {
+			mr := testMachineRequest(map[string]string{"data-disks": annotation})
+			mr.Spec.ExtraDisks = []butlerv1alpha1.DiskSpec{{SizeGB: 50}}
+			_, err := vmCreateOptions(mr)
+			Expect(err).To(MatchError(ContainSubstring(message)))
+		},
+		Entry("with a size", "50::scsi", "takes its size and storage class from spec.extraDisks"),
+		Entry("with a storage class", ":longhorn-ssd", "takes its size and storage class from spec.extraDisks"),
+		Entry("with more entries than disks", "::scsi,::sata", "2 entries for 1 spec.extraDisks"),
+	)
+
+	DescribeTable("rejects integer annotations out of the 32-bit range",
+		func(key string) {
+			_, err := vmCreateOptions(testMachineRequest(map[string]string{key: "4294967296"}))
+			Expect(err).To(MatchError(ContainSubstring(`annotation ` + annotationPrefix + key + `: invalid 32-bit integer "4294967296"`)))
+		},
+		Entry("memory request", "memory-request-mb"),
+		Entry("memory limit", "memory-limit-mb"),
+		Entry("CPU sockets", "cpu-sockets"),
+		Entry("ephemeral scratch", "ephemeral-scratch-gb"),
+	)
+
+	It("pins listed interfaces to an ACPI index and PCI address", func() {
+		mr := testMachineRequest(map[string]string{
+			"networks": "default/mgmt acpi-index 1, default/storage name storage acpi-index 2 pci-address 0000:02:02.0",
+		})
+		opts, err := vmCreateOptions(mr)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(opts.Networks).To(Equal([]harvester.NetworkInterface{
+			{NetworkName: "default/mgmt", ACPIIndex: 1},
+			{NetworkName: "default/storage", Name: "storage", ACPIIndex: 2, PCIAddress: "0000:02:02.0"},
+		}))
+
+		mr.Annotations[AnnotationNetworks] = "default/mgmt acpi-index first"
+		_, err = vmCreateOptions(mr)
+		Expect(err).To(MatchError(ContainSubstring(`invalid ACPI index "first"`)))
+	})
+})
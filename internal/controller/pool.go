@@ -0,0 +1,356 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	butlerv1alpha1 "github.com/butlerdotdev/butler-api/api/v1alpha1"
+	"github.com/butlerdotdev/butler-provider-harvester/internal/harvester"
+	"github.com/butlerdotdev/butler-provider-harvester/internal/metrics"
+)
+
+// isPool reports whether the MachineRequest describes a pool of identical
+// VMs rather than a single machine. A pool stays one once its replicas
+// annotation is removed, so its members are scaled down rather than leaked.
+func isPool(mr *butlerv1alpha1.MachineRequest) bool {
+	_, replicas := mr.Annotations[AnnotationReplicas]
+	_, size := mr.Annotations[AnnotationPoolSize]
+	return replicas || size
+}
+
+// poolMemberName returns the VM name of the pool member with the ordinal.
+func poolMemberName(mr *butlerv1alpha1.MachineRequest, ordinal int) string {
+	return fmt.Sprintf("%s-%d", mr.Spec.MachineName, ordinal)
+}
+
+// poolReplicas returns the desired replica count of the pool, zero once the
+// replicas annotation is removed.
+func poolReplicas(mr *butlerv1alpha1.MachineRequest) (int, error) {
+	replicas, err := intAnnotation(mr, AnnotationReplicas)
+	if err != nil {
+		return 0, err
+	}
+	if replicas < 0 {
+		return 0, fmt.Errorf("annotation %s must not be negative", AnnotationReplicas)
+	}
+	return replicas, nil
+}
+
+// poolSize returns the number of ordinals that may have a VM, which is
+// larger than the replica count while a scale-down is in progress.
+func poolSize(mr *butlerv1alpha1.MachineRequest) int {
+	size, err := intAnnotation(mr, AnnotationPoolSize)
+	if err != nil || size < 0 {
+		return 0
+	}
+	return size
+}
+
+// poolCreateOptions returns the create options shared by every member of
+// the pool, rejecting options that cannot apply to more than one VM.
+func poolCreateOptions(mr *butlerv1alpha1.MachineRequest) (harvester.VMCreateOptions, error) {
+	opts, err := vmCreateOptions(mr)
+	if err != nil {
+		return opts, err
+	}
+	if opts.RestoreFromBackup != "" {
+		return opts, fmt.Errorf("annotation %s cannot be combined with %s", AnnotationRestoreFromBackup, AnnotationReplicas)
+	}
+	if mr.Annotations[AnnotationCloudInitGroup] != "" {
+		return opts, fmt.Errorf("annotation %s cannot be combined with %s", AnnotationCloudInitGroup, AnnotationReplicas)
+	}
+	if opts.FirmwareUUID != "" || opts.FirmwareSerial != "" {
+		return opts, fmt.Errorf("annotations %s and %s cannot be combined with %s", AnnotationFirmwareUUID, AnnotationFirmwareSerial, AnnotationReplicas)
+	}
+	for _, disk := range opts.AttachedDisks {
+		if !disk.Shareable {
+			return opts, fmt.Errorf("annotation %s: disk %s must be shareable when %s is set", AnnotationAttachedDisks, disk.ClaimName, AnnotationReplicas)
+		}
+	}
+	if opts.RootDiskPVCName != "" && !strings.Contains(opts.RootDiskPVCName, "{name}") {
+		return opts, fmt.Errorf("annotation %s must contain {name} when %s is set", AnnotationRootDiskPVCName, AnnotationReplicas)
+	}
+	return opts, nil
+}
+
+// reconcilePool converges a pool MachineRequest on its replica count. Members
+// are named "<machineName>-<ordinal>"; missing ordinals below the replica
+// count are created and ordinals at or above it are deleted, as is the VM of
+// a MachineRequest turned into a pool. The Running phase means every desired
+// member is ready.
+func (r *MachineRequestReconciler) reconcilePool(
+	ctx context.Context,
+	mr *butlerv1alpha1.MachineRequest,
+	hc *harvester.Client,
+) (ctrl.Result, error) {
+	log := logf.FromContext(ctx)
+
+	replicas, err := poolReplicas(mr)
+	if err != nil {
+		return r.updateStatusError(ctx, mr, butlerv1alpha1.ReasonInvalidConfiguration, err.Error())
+	}
+	opts, err := poolCreateOptions(mr)
+	if err != nil {
+		log.Error(err, "Invalid MachineRequest options")
+		return r.updateStatusError(ctx, mr, butlerv1alpha1.ReasonInvalidConfiguration, err.Error())
+	}
+	userData, err := r.userData(ctx, mr)
+	if errors.Is(err, errUserDataSourceNotFound) {
+		return r.setBlocked(ctx, mr, ReasonUserDataNotFound, err.Error())
+	}
+	if err != nil {
+		return r.updateStatusError(ctx, mr, butlerv1alpha1.ReasonInvalidConfiguration, err.Error())
+	}
+	opts.UserData = userData
+	drained := r.Drain.Drained()
+
+	// Record the new size before creating so a crash cannot leak members
+	size := poolSize(mr)
+	if _, ok := mr.Annotations[AnnotationPoolSize]; !ok || replicas > size {
+		size = replicas
+		if err := r.patchAnnotations(ctx, mr, map[string]string{
+			AnnotationPoolSize: strconv.Itoa(size),
+		}); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	// A MachineRequest converted from a single machine still records that
+	// VM's UID; delete the VM rather than leave it running unmanaged
+	if mr.Status.ProviderID != "" && !drained {
+		name := vmName(mr)
+		delay, err := r.deletePoolMember(ctx, mr, hc, name)
+		if err != nil {
+			log.Error(err, "Failed to delete the VM replaced by the pool", "vm", name)
+			return ctrl.Result{RequeueAfter: r.requeueShort()}, nil
+		}
+		if delay == 0 {
+			log.Info("Deleted the VM replaced by the pool", "vm", name)
+			r.Recorder.Eventf(mr, corev1.EventTypeNormal, "Deleted", "Deleted VM %s, which the pool replaces", name)
+			mr.Status.ProviderID = ""
+			mr.Status.IPAddress = ""
+			mr.Status.IPAddresses = nil
+			mr.Status.MACAddress = ""
+		}
+	}
+
+	ready := 0
+	for ordinal := 0; ordinal < replicas; ordinal++ {
+		name := poolMemberName(mr, ordinal)
+		status, err := hc.GetVMStatus(ctx, name)
+		if err != nil && !apierrors.IsNotFound(err) {
+			log.Error(err, "Failed to get pool member status", "vm", name)
+			return ctrl.Result{RequeueAfter: r.requeueShort()}, nil
+		}
+		if err == nil {
+			if isNameConflict(mr, status) {
+				r.Recorder.Eventf(mr, corev1.EventTypeWarning, ReasonNameConflict,
+					"Pool member %s is owned by MachineRequest %s", name, status.Owner)
+			} else if status.Ready && status.IPAddress != "" {
+				ready++
+			}
+			continue
+		}
+		if drained {
+			continue
+		}
+
+		member := opts
+		member.Name = name
+		if _, _, err := hc.CreateVM(ctx, member); err != nil {
+			if apierrors.IsAlreadyExists(err) || errors.Is(err, harvester.ErrPreviousInstanceTerminating) {
+				continue
+			}
+			if errors.Is(err, harvester.ErrInvalidUserData) {
+				return r.updateStatusError(ctx, mr, ReasonInvalidUserData, err.Error())
+			}
+			if errors.Is(err, harvester.ErrInvalidOptions) {
+				return r.updateStatusError(ctx, mr, butlerv1alpha1.ReasonInvalidConfiguration, err.Error())
+			}
+			log.Error(err, "Failed to create pool member", "vm", name)
+			r.Recorder.Eventf(mr, corev1.EventTypeWarning, "CreateFailed", "Failed to create VM %s: %v", name, err)
+			return ctrl.Result{RequeueAfter: r.requeueShort()}, nil
+		}
+		log.Info("Created pool member", "vm", name)
+		r.Recorder.Eventf(mr, corev1.EventTypeNormal, "Created", "VM %s creation initiated", name)
+	}
+
+	// Scale down from the highest ordinal
+	if !drained {
+		for size > replicas {
+			name := poolMemberName(mr, size-1)
+			delay, err := r.deletePoolMember(ctx, mr, hc, name)
+			if err != nil {
+				log.Error(err, "Failed to delete pool member", "vm", name)
+				return ctrl.Result{RequeueAfter: r.requeueShort()}, nil
+			}
+			if delay > 0 {
+				// Retried with the status requeue
+				log.V(1).Info("Pool scale-down paced by the ProviderConfig deletion rate", "retryAfter", delay)
+				break
+			}
+			log.Info("Deleted pool member", "vm", name)
+			size--
+		}
+		if size != poolSize(mr) {
+			if err := r.patchAnnotations(ctx, mr, map[string]string{
+				AnnotationPoolSize: strconv.Itoa(size),
+			}); err != nil {
+				return ctrl.Result{}, err
+			}
+		}
+	}
+
+	return r.updatePoolStatus(ctx, mr, ready, replicas, size)
+}
+
+// updatePoolStatus reports the ready and desired member counts. A pool
+// scaled to zero is Pending and not Ready, since it has no VM to offer.
+func (r *MachineRequestReconciler) updatePoolStatus(ctx context.Context, mr *butlerv1alpha1.MachineRequest, ready, replicas, size int) (ctrl.Result, error) {
+	if value := strconv.Itoa(ready); mr.Annotations[AnnotationReadyReplicas] != value {
+		if err := r.patchAnnotations(ctx, mr, map[string]string{
+			AnnotationReadyReplicas: value,
+		}); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	message := fmt.Sprintf("%d/%d replicas ready", ready, replicas)
+	condition := metav1.Condition{
+		Type:               ConditionTypeReplicasReady,
+		Status:             metav1.ConditionFalse,
+		Reason:             ReasonScaling,
+		Message:            message,
+		ObservedGeneration: mr.Generation,
+	}
+	readyStatus, readyReason := metav1.ConditionFalse, ReasonScaling
+	mr.Status.Phase = butlerv1alpha1.MachinePhaseCreating
+	switch {
+	case replicas == 0 && size == 0:
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = ReasonScaledToZero
+		readyReason = ReasonScaledToZero
+		mr.Status.Phase = butlerv1alpha1.MachinePhasePending
+	case ready == replicas && replicas > 0:
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = butlerv1alpha1.ReasonReady
+		readyStatus, readyReason = metav1.ConditionTrue, butlerv1alpha1.ReasonReady
+		mr.Status.Phase = butlerv1alpha1.MachinePhaseRunning
+	}
+	meta.SetStatusCondition(&mr.Status.Conditions, condition)
+	meta.RemoveStatusCondition(&mr.Status.Conditions, ConditionTypeBlocked)
+	meta.SetStatusCondition(&mr.Status.Conditions, metav1.Condition{
+		Type:               butlerv1alpha1.ConditionTypeReady,
+		Status:             readyStatus,
+		Reason:             readyReason,
+		Message:            message,
+		ObservedGeneration: mr.Generation,
+	})
+	mr.Status.FailureReason = ""
+	mr.Status.FailureMessage = ""
+	now := metav1.Now()
+	mr.Status.LastUpdated = &now
+	mr.Status.ObservedGeneration = mr.Generation
+	if err := r.updateStatus(ctx, mr); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if ready == replicas && size == replicas {
+		return ctrl.Result{RequeueAfter: r.requeueLong()}, nil
+	}
+	return ctrl.Result{RequeueAfter: r.requeueShort()}, nil
+}
+
+// deletePoolMember deletes a pool member VM unless another MachineRequest
+// owns it. A member that is already gone is not an error. When the deletion
+// is paced by the ProviderConfig deletion rate, it returns how long to wait
+// instead.
+func (r *MachineRequestReconciler) deletePoolMember(ctx context.Context, mr *butlerv1alpha1.MachineRequest, hc *harvester.Client, name string) (time.Duration, error) {
+	status, err := hc.GetVMStatus(ctx, name)
+	if err == nil && isNameConflict(mr, status) {
+		r.Recorder.Eventf(mr, corev1.EventTypeWarning, ReasonNameConflict,
+			"Not deleting VM %s: it is owned by MachineRequest %s", name, status.Owner)
+		return 0, nil
+	}
+	if err == nil {
+		if delay := r.deleteDelay(providerConfigKey(mr)); delay > 0 {
+			return delay, nil
+		}
+	}
+	if err := hc.DeleteVM(ctx, name, harvester.DeleteVMOptions{OwnerUID: string(mr.UID)}); err != nil && !apierrors.IsNotFound(err) {
+		return 0, err
+	}
+	return 0, nil
+}
+
+// reconcilePoolDelete deletes every pool member and removes the finalizer.
+func (r *MachineRequestReconciler) reconcilePoolDelete(
+	ctx context.Context,
+	mr *butlerv1alpha1.MachineRequest,
+	hc *harvester.Client,
+) (ctrl.Result, error) {
+	log := logf.FromContext(ctx)
+
+	if mr.Status.Phase != butlerv1alpha1.MachinePhaseDeleting {
+		mr.Status.Phase = butlerv1alpha1.MachinePhaseDeleting
+		now := metav1.Now()
+		mr.Status.LastUpdated = &now
+		if err := r.updateStatus(ctx, mr); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	size := poolSize(mr)
+	if replicas, err := poolReplicas(mr); err == nil && replicas > size {
+		size = replicas
+	}
+	for ordinal := 0; ordinal < size; ordinal++ {
+		name := poolMemberName(mr, ordinal)
+		delay, err := r.deletePoolMember(ctx, mr, hc, name)
+		if err != nil {
+			log.Error(err, "Failed to delete pool member", "vm", name)
+			return ctrl.Result{RequeueAfter: r.requeueShort()}, nil
+		}
+		if delay > 0 {
+			return r.setDeletionThrottled(ctx, mr, delay)
+		}
+	}
+
+	controllerutil.RemoveFinalizer(mr, finalizerName)
+	if err := r.Update(ctx, mr); err != nil {
+		return ctrl.Result{}, err
+	}
+	metrics.ForgetMachine(mr.Namespace, mr.Name)
+
+	log.Info("Pool deleted successfully", "members", size)
+	r.Recorder.Eventf(mr, corev1.EventTypeNormal, "Deleted", "Deleted %d pool VMs", size)
+	return ctrl.Result{}, nil
+}
@@ -0,0 +1,183 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"golang.org/x/time/rate"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	butlerv1alpha1 "github.com/butlerdotdev/butler-api/api/v1alpha1"
+	"github.com/butlerdotdev/butler-provider-harvester/internal/harvester"
+)
+
+var _ = Describe("VM pools", func() {
+	var (
+		ctx     context.Context
+		r       *MachineRequestReconciler
+		hc      *harvester.Client
+		patches int
+	)
+
+	// reconcile runs reconcilePool on mr, counting the metadata patches.
+	reconcile := func(mr *butlerv1alpha1.MachineRequest) {
+		r, _ = testReconciler(mr)
+		r.Client = interceptor.NewClient(r.Client.(client.WithWatch), interceptor.Funcs{
+			Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+				patches++
+				return c.Patch(ctx, obj, patch, opts...)
+			},
+		})
+		_, err := r.reconcilePool(ctx, mr, hc)
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	expectMembers := func(names ...string) {
+		for ordinal := 0; ordinal < 4; ordinal++ {
+			name := poolMemberName(testMachineRequest(nil), ordinal)
+			_, err := hc.GetVMStatus(ctx, name)
+			if ordinal < len(names) {
+				Expect(err).NotTo(HaveOccurred(), name)
+			} else {
+				Expect(apierrors.IsNotFound(err)).To(BeTrue(), name)
+			}
+		}
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		hc = testHarvesterClient()
+		patches = 0
+	})
+
+	It("creates the missing members and records the pool size", func() {
+		mr := testMachineRequest(map[string]string{"replicas": "2"})
+		reconcile(mr)
+
+		expectMembers("worker-0-0", "worker-0-1")
+		Expect(mr.Annotations).To(HaveKeyWithValue(AnnotationPoolSize, "2"))
+		Expect(mr.Annotations).To(HaveKeyWithValue(AnnotationReadyReplicas, "0"))
+		Expect(mr.Status.Phase).To(Equal(butlerv1alpha1.MachinePhaseCreating))
+		condition := meta.FindStatusCondition(mr.Status.Conditions, ConditionTypeReplicasReady)
+		Expect(condition.Reason).To(Equal(ReasonScaling))
+		Expect(condition.Message).To(Equal("0/2 replicas ready"))
+	})
+
+	It("patches the annotations only when they change", func() {
+		mr := testMachineRequest(map[string]string{"replicas": "2"})
+		reconcile(mr)
+		Expect(patches).To(Equal(2))
+
+		patches = 0
+		reconcile(mr)
+		Expect(patches).To(BeZero())
+	})
+
+	It("deletes the highest ordinals when scaling down", func() {
+		mr := testMachineRequest(map[string]string{"replicas": "3"})
+		reconcile(mr)
+		mr.Annotations[AnnotationReplicas] = "1"
+		reconcile(mr)
+
+		expectMembers("worker-0-0")
+		Expect(mr.Annotations).To(HaveKeyWithValue(AnnotationPoolSize, "1"))
+	})
+
+	It("scales to zero when the replicas annotation is removed", func() {
+		mr := testMachineRequest(map[string]string{"replicas": "2"})
+		reconcile(mr)
+		delete(mr.Annotations, AnnotationReplicas)
+		Expect(isPool(mr)).To(BeTrue())
+		reconcile(mr)
+
+		expectMembers()
+		Expect(mr.Annotations).To(HaveKeyWithValue(AnnotationPoolSize, "0"))
+		Expect(mr.Status.Phase).To(Equal(butlerv1alpha1.MachinePhasePending))
+		ready := meta.FindStatusCondition(mr.Status.Conditions, butlerv1alpha1.ConditionTypeReady)
+		Expect(ready.Status).To(BeEquivalentTo("False"))
+		Expect(ready.Reason).To(Equal(ReasonScaledToZero))
+	})
+
+	It("deletes the VM of a single machine turned into a pool", func() {
+		single := testMachineRequest(nil)
+		opts, err := vmCreateOptions(single)
+		Expect(err).NotTo(HaveOccurred())
+		_, _, err = hc.CreateVM(ctx, opts)
+		Expect(err).NotTo(HaveOccurred())
+
+		mr := testMachineRequest(map[string]string{"replicas": "1"})
+		mr.Status.ProviderID = "vm-uid"
+		mr.Status.IPAddress = "10.0.0.5"
+		reconcile(mr)
+
+		_, err = hc.GetVMStatus(ctx, "worker-0")
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+		expectMembers("worker-0-0")
+		Expect(mr.Status.ProviderID).To(BeEmpty())
+		Expect(mr.Status.IPAddress).To(BeEmpty())
+	})
+
+	It("keeps the VM of a single machine turned into a pool while deletions are paced", func() {
+		single := testMachineRequest(nil)
+		opts, err := vmCreateOptions(single)
+		Expect(err).NotTo(HaveOccurred())
+		_, _, err = hc.CreateVM(ctx, opts)
+		Expect(err).NotTo(HaveOccurred())
+
+		mr := testMachineRequest(map[string]string{"replicas": "1"})
+		mr.Status.ProviderID = "vm-uid"
+		r, _ = testReconciler(mr)
+		r.configureDeleteLimiter(providerConfigKey(mr), rate.Every(time.Hour))
+		Expect(r.deleteDelay(providerConfigKey(mr))).To(BeZero())
+		_, err = r.reconcilePool(ctx, mr, hc)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = hc.GetVMStatus(ctx, "worker-0")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(mr.Status.ProviderID).To(Equal("vm-uid"))
+	})
+
+	It("records the size of a pool created with zero replicas", func() {
+		mr := testMachineRequest(map[string]string{"replicas": "0"})
+		reconcile(mr)
+
+		expectMembers()
+		Expect(mr.Annotations).To(HaveKeyWithValue(AnnotationPoolSize, "0"))
+		Expect(mr.Status.Phase).To(Equal(butlerv1alpha1.MachinePhasePending))
+		delete(mr.Annotations, AnnotationReplicas)
+		Expect(isPool(mr)).To(BeTrue())
+	})
+
+	DescribeTable("rejects options that cannot apply to several VMs",
+		func(annotations map[string]string, message string) {
+			annotations["replicas"] = "2"
+			_, err := poolCreateOptions(testMachineRequest(annotations))
+			Expect(err).To(MatchError(ContainSubstring(message)))
+		},
+		Entry("a backup restore", map[string]string{"restore-from-backup": "backup-1"}, "restore-from-backup"),
+		Entry("a cloud-init group", map[string]string{"cloud-init-group": "etcd"}, "cloud-init-group"),
+		Entry("a firmware UUID", map[string]string{"firmware-uuid": "6a1f5c2e-8b0d-4a53-9f4e-2d6c1b7e9a30"}, "firmware-uuid"),
+		Entry("a root disk PVC name without {name}", map[string]string{"root-disk-pvc-name": "rootdisk"}, "must contain {name}"),
+	)
+})
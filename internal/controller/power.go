@@ -0,0 +1,166 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	butlerv1alpha1 "github.com/butlerdotdev/butler-api/api/v1alpha1"
+	"github.com/butlerdotdev/butler-provider-harvester/internal/harvester"
+)
+
+// Power states accepted in AnnotationPowerState.
+const (
+	powerStateRunning = "Running"
+	powerStateStopped = "Stopped"
+)
+
+// desiredPowerState returns the power state requested in AnnotationPowerState.
+func desiredPowerState(mr *butlerv1alpha1.MachineRequest) (string, error) {
+	switch value := mr.Annotations[AnnotationPowerState]; value {
+	case "", powerStateRunning:
+		return powerStateRunning, nil
+	case powerStateStopped:
+		return value, nil
+	default:
+		return "", fmt.Errorf("annotation %s: invalid power state %q (must be %s or %s)",
+			AnnotationPowerState, value, powerStateRunning, powerStateStopped)
+	}
+}
+
+// reconcileStopped stops the VM of a running MachineRequest whose desired
+// power state is Stopped. The MachineRequest stays in the Running phase,
+// which is the closest phase the MachineRequest API allows, with Ready False
+// and no IP address; the Stopped condition reports the power state.
+func (r *MachineRequestReconciler) reconcileStopped(
+	ctx context.Context,
+	mr *butlerv1alpha1.MachineRequest,
+	hc *harvester.Client,
+	status *harvester.VMStatus,
+) (ctrl.Result, error) {
+	log := logf.FromContext(ctx)
+
+	if status.RunStrategy != harvester.RunStrategyHalted {
+		log.Info("Stopping VM", "name", mr.Spec.MachineName)
+		if err := hc.StopVM(ctx, mr.Spec.MachineName); err != nil {
+			log.Error(err, "Failed to stop VM")
+			r.Recorder.Eventf(mr, corev1.EventTypeWarning, "StopFailed", "Failed to stop VM: %v", err)
+			return ctrl.Result{RequeueAfter: r.requeueShort()}, nil
+		}
+		r.Recorder.Eventf(mr, corev1.EventTypeNormal, "Stopping", "Stopping VM for %s %s", AnnotationPowerState, powerStateStopped)
+	}
+	if err := r.updateFootprint(ctx, mr, status); err != nil {
+		log.Error(err, "Failed to update resource footprint")
+	}
+
+	if status.Phase != harvester.VMPhaseStopped {
+		if cond := meta.FindStatusCondition(mr.Status.Conditions, ConditionTypeStopped); cond != nil && cond.Reason == ReasonStopping {
+			return ctrl.Result{RequeueAfter: r.requeueShort()}, nil
+		}
+		meta.SetStatusCondition(&mr.Status.Conditions, metav1.Condition{
+			Type:               ConditionTypeStopped,
+			Status:             metav1.ConditionFalse,
+			Reason:             ReasonStopping,
+			Message:            "Waiting for the guest to shut down",
+			ObservedGeneration: mr.Generation,
+		})
+		if err := r.updateStatus(ctx, mr); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{RequeueAfter: r.requeueShort()}, nil
+	}
+
+	if meta.IsStatusConditionTrue(mr.Status.Conditions, ConditionTypeStopped) {
+		return ctrl.Result{RequeueAfter: r.requeueLong()}, nil
+	}
+	log.Info("VM stopped", "name", mr.Spec.MachineName)
+	meta.SetStatusCondition(&mr.Status.Conditions, metav1.Condition{
+		Type:               ConditionTypeStopped,
+		Status:             metav1.ConditionTrue,
+		Reason:             ReasonPoweredOff,
+		Message:            fmt.Sprintf("VM is stopped; set %s to %s to start it", AnnotationPowerState, powerStateRunning),
+		ObservedGeneration: mr.Generation,
+	})
+	meta.SetStatusCondition(&mr.Status.Conditions, metav1.Condition{
+		Type:               butlerv1alpha1.ConditionTypeReady,
+		Status:             metav1.ConditionFalse,
+		Reason:             ReasonPoweredOff,
+		Message:            "VM is stopped",
+		ObservedGeneration: mr.Generation,
+	})
+	mr.Status.IPAddress = ""
+	mr.Status.IPAddresses = nil
+	mr.Status.MACAddress = ""
+	now := metav1.Now()
+	mr.Status.LastUpdated = &now
+	if err := r.updateStatus(ctx, mr); err != nil {
+		return ctrl.Result{}, err
+	}
+	r.Recorder.Event(mr, corev1.EventTypeNormal, "Stopped", "VM stopped")
+	return ctrl.Result{RequeueAfter: r.requeueLong()}, nil
+}
+
+// startStoppedVM starts the VM of a MachineRequest whose desired power state
+// returned to Running and moves the MachineRequest to Creating, which waits
+// for the guest to report a fresh IP and restarts the create timeout.
+func (r *MachineRequestReconciler) startStoppedVM(
+	ctx context.Context,
+	mr *butlerv1alpha1.MachineRequest,
+	hc *harvester.Client,
+) (ctrl.Result, error) {
+	log := logf.FromContext(ctx)
+
+	log.Info("Starting stopped VM", "name", mr.Spec.MachineName)
+	if err := hc.StartVM(ctx, mr.Spec.MachineName); err != nil {
+		log.Error(err, "Failed to start VM")
+		r.Recorder.Eventf(mr, corev1.EventTypeWarning, "StartFailed", "Failed to start VM: %v", err)
+		return ctrl.Result{RequeueAfter: r.requeueShort()}, nil
+	}
+	if err := r.patchAnnotations(ctx, mr, map[string]string{
+		AnnotationCreatingSince: time.Now().UTC().Format(time.RFC3339),
+	}); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	meta.RemoveStatusCondition(&mr.Status.Conditions, ConditionTypeStopped)
+	meta.SetStatusCondition(&mr.Status.Conditions, metav1.Condition{
+		Type:               butlerv1alpha1.ConditionTypeProgressing,
+		Status:             metav1.ConditionTrue,
+		Reason:             ReasonStarting,
+		Message:            "Waiting for the started VM to report an IP",
+		ObservedGeneration: mr.Generation,
+	})
+	mr.Status.Phase = butlerv1alpha1.MachinePhaseCreating
+	mr.Status.IPAddress = ""
+	mr.Status.IPAddresses = nil
+	mr.Status.MACAddress = ""
+	now := metav1.Now()
+	mr.Status.LastUpdated = &now
+	if err := r.updateStatus(ctx, mr); err != nil {
+		return ctrl.Result{}, err
+	}
+	r.Recorder.Event(mr, corev1.EventTypeNormal, "Starting", "Starting VM")
+	return ctrl.Result{RequeueAfter: r.requeueShort()}, nil
+}
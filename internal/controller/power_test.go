@@ -0,0 +1,123 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	butlerv1alpha1 "github.com/butlerdotdev/butler-api/api/v1alpha1"
+	"github.com/butlerdotdev/butler-provider-harvester/internal/harvester"
+)
+
+var _ = Describe("Power state", func() {
+	var (
+		ctx context.Context
+		mr  *butlerv1alpha1.MachineRequest
+		hc  *harvester.Client
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		mr = testMachineRequest(map[string]string{"power-state": powerStateStopped})
+		mr.Status.Phase = butlerv1alpha1.MachinePhaseRunning
+		mr.Status.IPAddress = "10.0.0.5"
+		mr.Status.IPAddresses = []string{"10.0.0.5"}
+		hc = testHarvesterClient()
+		opts, err := vmCreateOptions(mr)
+		Expect(err).NotTo(HaveOccurred())
+		_, _, err = hc.CreateVM(ctx, opts)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	DescribeTable("reads the desired power state",
+		func(value, want string, valid bool) {
+			state, err := desiredPowerState(testMachineRequest(map[string]string{"power-state": value}))
+			if !valid {
+				Expect(err).To(MatchError(ContainSubstring(AnnotationPowerState)))
+				return
+			}
+			Expect(err).NotTo(HaveOccurred())
+			Expect(state).To(Equal(want))
+		},
+		Entry("unset", "", powerStateRunning, true),
+		Entry("running", "Running", powerStateRunning, true),
+		Entry("stopped", "Stopped", powerStateStopped, true),
+		Entry("another case", "stopped", "", false),
+		Entry("unknown", "Paused", "", false),
+	)
+
+	// vmStatus returns the current status of the test VM.
+	vmStatus := func() *harvester.VMStatus {
+		status, err := hc.GetVMStatus(ctx, mr.Spec.MachineName)
+		Expect(err).NotTo(HaveOccurred())
+		return status
+	}
+
+	It("halts the VM and reports it stopped once the guest is down", func() {
+		r, recorder := testReconciler(mr)
+		status := vmStatus()
+		status.Phase = "Running"
+
+		_, err := r.reconcileStopped(ctx, mr, hc, status)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(vmStatus().RunStrategy).To(Equal(harvester.RunStrategyHalted))
+		Expect(recorder.Events).To(Receive(ContainSubstring("Stopping")))
+		cond := meta.FindStatusCondition(mr.Status.Conditions, ConditionTypeStopped)
+		Expect(cond).NotTo(BeNil())
+		Expect(cond.Reason).To(Equal(ReasonStopping))
+		Expect(mr.Status.IPAddress).To(Equal("10.0.0.5"))
+
+		// KubeVirt reports the halted VM Stopped once the guest is down
+		status = vmStatus()
+		status.Phase = harvester.VMPhaseStopped
+		_, err = r.reconcileStopped(ctx, mr, hc, status)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(recorder.Events).To(Receive(ContainSubstring("VM stopped")))
+		Expect(meta.IsStatusConditionTrue(mr.Status.Conditions, ConditionTypeStopped)).To(BeTrue())
+		Expect(meta.IsStatusConditionFalse(mr.Status.Conditions, butlerv1alpha1.ConditionTypeReady)).To(BeTrue())
+		Expect(mr.Status.IPAddress).To(BeEmpty())
+		Expect(mr.Status.IPAddresses).To(BeNil())
+
+		// A stopped VM is left alone
+		_, err = r.reconcileStopped(ctx, mr, hc, status)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(recorder.Events).To(BeEmpty())
+	})
+
+	It("starts the VM and waits for a fresh IP", func() {
+		Expect(hc.StopVM(ctx, mr.Spec.MachineName)).To(Succeed())
+		mr.Annotations[AnnotationPowerState] = powerStateRunning
+		r, recorder := testReconciler(mr)
+
+		_, err := r.startStoppedVM(ctx, mr, hc)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(vmStatus().RunStrategy).To(Equal(harvester.RunStrategyAlways))
+		Expect(recorder.Events).To(Receive(ContainSubstring("Starting VM")))
+
+		got := &butlerv1alpha1.MachineRequest{}
+		Expect(r.Get(ctx, client.ObjectKeyFromObject(mr), got)).To(Succeed())
+		Expect(got.Status.Phase).To(Equal(butlerv1alpha1.MachinePhaseCreating))
+		Expect(got.Status.IPAddress).To(BeEmpty())
+		Expect(got.Annotations).To(HaveKey(AnnotationCreatingSince))
+		Expect(meta.FindStatusCondition(got.Status.Conditions, ConditionTypeStopped)).To(BeNil())
+	})
+})
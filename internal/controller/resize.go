@@ -0,0 +1,235 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	butlerv1alpha1 "github.com/butlerdotdev/butler-api/api/v1alpha1"
+	"github.com/butlerdotdev/butler-provider-harvester/internal/harvester"
+)
+
+// bootSize returns the size the running instance booted with and that
+// instance's UID, recorded in the boot-size annotation.
+func bootSize(mr *butlerv1alpha1.MachineRequest) (harvester.VMSize, string, bool) {
+	fields := strings.Split(mr.Annotations[AnnotationBootSize], ":")
+	if len(fields) != 3 {
+		return harvester.VMSize{}, "", false
+	}
+	cpu, cpuErr := strconv.ParseInt(fields[0], 10, 32)
+	memory, memErr := strconv.ParseInt(fields[1], 10, 32)
+	if cpuErr != nil || memErr != nil {
+		return harvester.VMSize{}, "", false
+	}
+	return harvester.VMSize{CPU: int32(cpu), MemoryMB: int32(memory)}, fields[2], true
+}
+
+// checkResize applies changes of spec.cpu and spec.memoryMB to the VM.
+// KubeVirt hotplugs the change into the running guest when the VM has
+// hotplug headroom and the new size is not below what the guest booted
+// with; Progressing reports the hotplug until the guest has the new size.
+// Otherwise the RestartRequired condition asks for a restart, after which
+// the guest boots with the new size. It returns whether the status
+// conditions changed.
+func (r *MachineRequestReconciler) checkResize(ctx context.Context, mr *butlerv1alpha1.MachineRequest, hc *harvester.Client) (bool, error) {
+	log := logf.FromContext(ctx)
+
+	rs, err := hc.GetResizeStatus(ctx, vmName(mr))
+	if err != nil {
+		log.Error(err, "Failed to read VM size")
+		return false, nil
+	}
+	desired := harvester.VMSize{CPU: mr.Spec.CPU, MemoryMB: mr.Spec.MemoryMB}
+	if rs.VMIUID == "" {
+		// A stopped VM boots with the template size
+		if desired != rs.Size {
+			r.resize(ctx, mr, hc, desired)
+		}
+		return false, nil
+	}
+
+	boot, instance, ok := bootSize(mr)
+	if !ok || instance != rs.VMIUID {
+		// A new instance booted with the template size
+		boot = rs.Size
+		if err := r.patchAnnotations(ctx, mr, map[string]string{
+			AnnotationBootSize: fmt.Sprintf("%d:%d:%s", boot.CPU, boot.MemoryMB, rs.VMIUID),
+		}); err != nil {
+			return false, err
+		}
+	}
+
+	size := rs.Size
+	resized := false
+	if desired != size {
+		if size, resized = r.resize(ctx, mr, hc, desired); !resized {
+			size = rs.Size
+		}
+	}
+
+	var reasons []string
+	switch {
+	case size.CPU < boot.CPU:
+		reasons = append(reasons, fmt.Sprintf("%d CPUs is below the %d the guest booted with", size.CPU, boot.CPU))
+	case size.CPU != boot.CPU && !rs.CPUHotplug:
+		reasons = append(reasons, "the VM has no CPU hotplug headroom (domain.cpu.maxSockets)")
+	}
+	switch {
+	case size.MemoryMB < boot.MemoryMB:
+		reasons = append(reasons, fmt.Sprintf("%dMi is below the %dMi the guest booted with", size.MemoryMB, boot.MemoryMB))
+	case size.MemoryMB != boot.MemoryMB && !rs.MemoryHotplug:
+		reasons = append(reasons, "the VM has no memory hotplug headroom (domain.memory.maxGuest)")
+	}
+	if len(reasons) == 0 && size != boot && !liveUpdates(ctx, hc) {
+		reasons = append(reasons, "KubeVirt does not live-update running VMs on this cluster (spec.configuration.vmRolloutStrategy)")
+	}
+	if len(reasons) == 0 && rs.RestartRequired && size != boot {
+		reasons = append(reasons, "KubeVirt could not apply the change to the running guest")
+	}
+	if len(reasons) == 0 {
+		changed := meta.RemoveStatusCondition(&mr.Status.Conditions, ConditionTypeRestartRequired)
+		return r.trackHotplug(ctx, mr, size, rs.Live, resized) || changed, nil
+	}
+	changed := r.finishResizing(mr, "")
+
+	message := fmt.Sprintf("VM resized from %d CPUs/%dMi to %d CPUs/%dMi, which takes effect after a restart: %s",
+		boot.CPU, boot.MemoryMB, size.CPU, size.MemoryMB, strings.Join(reasons, "; "))
+	if existing := meta.FindStatusCondition(mr.Status.Conditions, ConditionTypeRestartRequired); existing != nil && existing.Message == message {
+		return changed, nil
+	}
+	log.Info("VM resize requires a restart", "reasons", reasons)
+	meta.SetStatusCondition(&mr.Status.Conditions, metav1.Condition{
+		Type:               ConditionTypeRestartRequired,
+		Status:             metav1.ConditionTrue,
+		Reason:             ReasonResizeRequiresRestart,
+		Message:            message,
+		ObservedGeneration: mr.Generation,
+	})
+	r.Recorder.Event(mr, corev1.EventTypeWarning, ReasonResizeRequiresRestart, message)
+	return true, nil
+}
+
+// liveUpdates reports whether the cluster hotplugs resizes into running
+// guests. When its configuration cannot be read, the hotplug is attempted
+// and RestartRequired follows if KubeVirt does not apply it.
+func liveUpdates(ctx context.Context, hc *harvester.Client) bool {
+	info, err := hc.GetClusterInfo(ctx)
+	if err != nil {
+		logf.FromContext(ctx).V(1).Info("Unable to detect live update support", "error", err.Error())
+		return true
+	}
+	return info.LiveUpdates()
+}
+
+// resize sets the VM template to size and reports the resulting size.
+// Failures are reported in logs and a false result, so the other running
+// checks still run. A size that cannot be applied is retried on every deep
+// check, but its warning event is only emitted on the first check of a
+// spec change.
+func (r *MachineRequestReconciler) resize(ctx context.Context, mr *butlerv1alpha1.MachineRequest, hc *harvester.Client, size harvester.VMSize) (harvester.VMSize, bool) {
+	log := logf.FromContext(ctx)
+	rejected := func(err error) {
+		log.Info("Cannot resize VM", "reason", err.Error())
+		if r.firstDeepCheck(mr) {
+			r.Recorder.Eventf(mr, corev1.EventTypeWarning, "ResizeFailed", "Cannot resize VM: %v", err)
+		}
+	}
+	// Immutable settings such as the memory limit keep their created values
+	opts, err := vmCreateOptions(recordedMachineRequest(mr))
+	if err != nil {
+		rejected(err)
+		return harvester.VMSize{}, false
+	}
+	opts.CPU, opts.MemoryMB = size.CPU, size.MemoryMB
+	resized, err := hc.UpdateVMResources(ctx, vmName(mr), opts)
+	if errors.Is(err, harvester.ErrInvalidOptions) {
+		rejected(err)
+		return resized, false
+	}
+	if err != nil {
+		log.Error(err, "Failed to resize VM")
+		return resized, false
+	}
+	log.Info("Resized VM template", "cpu", resized.CPU, "memoryMB", resized.MemoryMB)
+	return resized, true
+}
+
+// trackHotplug reports a resize KubeVirt applies to the running guest:
+// Progressing is True with reason Resizing until the guest has size, then
+// returns to False. It returns whether the status conditions changed.
+func (r *MachineRequestReconciler) trackHotplug(ctx context.Context, mr *butlerv1alpha1.MachineRequest, size, live harvester.VMSize, resized bool) bool {
+	if resized {
+		r.Recorder.Eventf(mr, corev1.EventTypeNormal, ReasonResizing,
+			"Resizing VM to %d CPUs and %dMi while it runs, no restart needed", size.CPU, size.MemoryMB)
+	}
+	if !resized && live == size {
+		message := fmt.Sprintf("VM resized to %d CPUs and %dMi without a restart", size.CPU, size.MemoryMB)
+		if r.finishResizing(mr, message) {
+			logf.FromContext(ctx).Info("VM resize applied to the running guest")
+			r.Recorder.Event(mr, corev1.EventTypeNormal, "Resized", message)
+			return true
+		}
+		return false
+	}
+	message := fmt.Sprintf("Applying resize to %d CPUs and %dMi to the running guest", size.CPU, size.MemoryMB)
+	if existing := meta.FindStatusCondition(mr.Status.Conditions, butlerv1alpha1.ConditionTypeProgressing); existing != nil &&
+		existing.Reason == ReasonResizing && existing.Message == message {
+		return false
+	}
+	meta.SetStatusCondition(&mr.Status.Conditions, metav1.Condition{
+		Type:               butlerv1alpha1.ConditionTypeProgressing,
+		Status:             metav1.ConditionTrue,
+		Reason:             ReasonResizing,
+		Message:            message,
+		ObservedGeneration: mr.Generation,
+	})
+	return true
+}
+
+// finishResizing ends a resize reported on the Progressing condition, with
+// message or a default one. It returns whether the condition changed.
+func (r *MachineRequestReconciler) finishResizing(mr *butlerv1alpha1.MachineRequest, message string) bool {
+	if !resizing(mr) {
+		return false
+	}
+	if message == "" {
+		message = "VM resize takes effect after a restart"
+	}
+	meta.SetStatusCondition(&mr.Status.Conditions, metav1.Condition{
+		Type:               butlerv1alpha1.ConditionTypeProgressing,
+		Status:             metav1.ConditionFalse,
+		Reason:             butlerv1alpha1.ReasonRunning,
+		Message:            message,
+		ObservedGeneration: mr.Generation,
+	})
+	return true
+}
+
+// resizing reports whether a hotplug resize of the VM is in progress.
+func resizing(mr *butlerv1alpha1.MachineRequest) bool {
+	cond := meta.FindStatusCondition(mr.Status.Conditions, butlerv1alpha1.ConditionTypeProgressing)
+	return cond != nil && cond.Status == metav1.ConditionTrue && cond.Reason == ReasonResizing
+}
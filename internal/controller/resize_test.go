@@ -0,0 +1,129 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/butlerdotdev/butler-provider-harvester/internal/harvester"
+)
+
+var _ = Describe("Resize deep check", func() {
+	var (
+		ctx      context.Context
+		hc       *harvester.Client
+		r        *MachineRequestReconciler
+		recorder *record.FakeRecorder
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		hc = testHarvesterClient()
+		mr := testMachineRequest(map[string]string{"memory-limit-mb": "4096"})
+		opts, err := vmCreateOptions(mr)
+		Expect(err).NotTo(HaveOccurred())
+		_, _, err = hc.CreateVM(ctx, opts)
+		Expect(err).NotTo(HaveOccurred())
+		r, recorder = testReconciler(mr)
+	})
+
+	It("warns about a size the VM cannot take once per spec change", func() {
+		mr := testMachineRequest(map[string]string{"memory-limit-mb": "4096"})
+		mr.Spec.MemoryMB = 8192
+		mr.Generation = 2
+
+		Expect(r.checkResize(ctx, mr, hc)).To(BeFalse())
+		Expect(recorder.Events).To(Receive(ContainSubstring("ResizeFailed")))
+
+		r.recordDeepCheck(mr, time.Now())
+		Expect(r.checkResize(ctx, mr, hc)).To(BeFalse())
+		Expect(recorder.Events).NotTo(Receive())
+
+		mr.Generation = 3
+		Expect(r.checkResize(ctx, mr, hc)).To(BeFalse())
+		Expect(recorder.Events).To(Receive(ContainSubstring("ResizeFailed")))
+	})
+
+	It("resizes the VM template to the spec", func() {
+		mr := testMachineRequest(map[string]string{"memory-limit-mb": "4096"})
+		mr.Spec.CPU = 4
+
+		Expect(r.checkResize(ctx, mr, hc)).To(BeFalse())
+		rs, err := hc.GetResizeStatus(ctx, "worker-0")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rs.Size).To(Equal(harvester.VMSize{CPU: 4, MemoryMB: 4096}))
+		Expect(recorder.Events).NotTo(Receive())
+	})
+
+	It("keeps the memory limit the VM was created with", func() {
+		mr := testMachineRequest(map[string]string{"memory-limit-mb": "4096"})
+		mr.Annotations[AnnotationImmutableFields] = immutableSnapshot(mr)
+		mr.Annotations[AnnotationMemoryLimitMB] = "2048"
+		mr.Spec.MemoryMB = 3072
+
+		Expect(r.checkResize(ctx, mr, hc)).To(BeFalse())
+		Expect(recorder.Events).NotTo(Receive())
+		vm, err := hc.GetVM(ctx, "worker-0")
+		Expect(err).NotTo(HaveOccurred())
+		limit, _, _ := unstructured.NestedString(vm.Object, "spec", "template", "spec", "domain", "resources", "limits", "memory")
+		Expect(limit).To(Equal("4Gi"))
+		rs, err := hc.GetResizeStatus(ctx, "worker-0")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rs.Size.MemoryMB).To(Equal(int32(3072)))
+	})
+
+	DescribeTable("hotplugs a running VM only where KubeVirt live-updates",
+		func(strategy string, restart bool) {
+			mr := testMachineRequest(map[string]string{"memory-limit-mb": "4096"})
+			vm, err := hc.GetVM(ctx, "worker-0")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(unstructured.SetNestedField(vm.Object, int64(8),
+				"spec", "template", "spec", "domain", "cpu", "maxSockets")).To(Succeed())
+			vmi := testVMI("True", nil)
+			vmi.SetUID("vmi-1")
+			kv := &unstructured.Unstructured{}
+			kv.SetAPIVersion("kubevirt.io/v1")
+			kv.SetKind("KubeVirt")
+			kv.SetNamespace("harvester-system")
+			kv.SetName("kubevirt")
+			kv.Object["spec"] = map[string]interface{}{
+				"configuration": map[string]interface{}{"vmRolloutStrategy": strategy},
+			}
+			hc = testHarvesterClient(vm, vmi, kv)
+			mr.Annotations[AnnotationBootSize] = "2:4096:vmi-1"
+			mr.Spec.CPU = 4
+
+			Expect(r.checkResize(ctx, mr, hc)).To(BeTrue())
+			condition := meta.FindStatusCondition(mr.Status.Conditions, ConditionTypeRestartRequired)
+			if restart {
+				Expect(condition).NotTo(BeNil())
+				Expect(condition.Message).To(ContainSubstring("KubeVirt does not live-update running VMs"))
+			} else {
+				Expect(condition).To(BeNil())
+			}
+		},
+		Entry("live updates", "LiveUpdate", false),
+		Entry("staged updates", "Stage", true),
+	)
+})
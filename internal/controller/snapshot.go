@@ -0,0 +1,239 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	butlerv1alpha1 "github.com/butlerdotdev/butler-api/api/v1alpha1"
+	"github.com/butlerdotdev/butler-provider-harvester/internal/harvester"
+)
+
+// snapshotSuffixLayout names snapshots requested without a suffix.
+const snapshotSuffixLayout = "20060102-150405"
+
+// snapshotTimeout bounds how long a snapshot or restore is tracked, and so
+// how long power state changes, deep checks and resizes wait for it.
+const snapshotTimeout = time.Hour
+
+// reconcileSnapshots takes the snapshots and runs the restores requested
+// through AnnotationSnapshot and AnnotationRestoreSnapshot, and tracks them
+// until they finish. It reports whether the rest of the running reconcile,
+// including power state changes, should wait for a later pass.
+func (r *MachineRequestReconciler) reconcileSnapshots(
+	ctx context.Context,
+	mr *butlerv1alpha1.MachineRequest,
+	hc *harvester.Client,
+	status *harvester.VMStatus,
+	power string,
+) (ctrl.Result, bool, error) {
+	if name := mr.Annotations[AnnotationSnapshotRestore]; name != "" {
+		return r.trackSnapshotRestore(ctx, mr, hc, name)
+	}
+	if name := mr.Annotations[AnnotationSnapshotInProgress]; name != "" {
+		return r.trackSnapshot(ctx, mr, hc, name)
+	}
+	if suffix, ok := mr.Annotations[AnnotationSnapshot]; ok {
+		return r.startSnapshot(ctx, mr, hc, suffix)
+	}
+	if suffix, ok := mr.Annotations[AnnotationRestoreSnapshot]; ok {
+		if power == powerStateStopped && status.Phase != harvester.VMPhaseStopped {
+			// Restore once the VM has shut down
+			return ctrl.Result{}, false, nil
+		}
+		return r.startSnapshotRestore(ctx, mr, hc, status, suffix)
+	}
+	return ctrl.Result{}, false, nil
+}
+
+// startSnapshot starts the snapshot requested through AnnotationSnapshot.
+func (r *MachineRequestReconciler) startSnapshot(
+	ctx context.Context,
+	mr *butlerv1alpha1.MachineRequest,
+	hc *harvester.Client,
+	suffix string,
+) (ctrl.Result, bool, error) {
+	log := logf.FromContext(ctx)
+	if suffix == "" {
+		suffix = time.Now().UTC().Format(snapshotSuffixLayout)
+	}
+
+	log.Info("Taking VM snapshot", "name", mr.Spec.MachineName, "suffix", suffix)
+	name, err := hc.CreateVMSnapshot(ctx, mr.Spec.MachineName, suffix)
+	if err != nil {
+		log.Error(err, "Failed to take VM snapshot")
+		r.Recorder.Eventf(mr, corev1.EventTypeWarning, "SnapshotFailed", "Failed to take snapshot: %v", err)
+		if err := r.patchAnnotations(ctx, mr, map[string]string{AnnotationSnapshot: ""}); err != nil {
+			return ctrl.Result{}, true, err
+		}
+		return ctrl.Result{RequeueAfter: r.requeueShort()}, true, nil
+	}
+	if err := r.patchAnnotations(ctx, mr, map[string]string{
+		AnnotationSnapshot:           "",
+		AnnotationSnapshotInProgress: name,
+		AnnotationSnapshotSince:      time.Now().UTC().Format(time.RFC3339),
+	}); err != nil {
+		return ctrl.Result{}, true, err
+	}
+	r.Recorder.Eventf(mr, corev1.EventTypeNormal, "Snapshotting", "Taking snapshot %s", name)
+	return ctrl.Result{RequeueAfter: r.requeueShort()}, true, nil
+}
+
+// trackSnapshot polls the snapshot being taken and reports its outcome once
+// it finishes.
+func (r *MachineRequestReconciler) trackSnapshot(
+	ctx context.Context,
+	mr *butlerv1alpha1.MachineRequest,
+	hc *harvester.Client,
+	name string,
+) (ctrl.Result, bool, error) {
+	status, err := hc.GetVMSnapshotStatus(ctx, name)
+	if err != nil && !apierrors.IsNotFound(err) {
+		logf.FromContext(ctx).Error(err, "Failed to get VM snapshot status", "snapshot", name)
+		return ctrl.Result{RequeueAfter: r.requeueShort()}, true, nil
+	}
+	timedOut := false
+	if err == nil && !status.Finished() {
+		if timedOut, err = r.snapshotTimedOut(ctx, mr); err != nil || !timedOut {
+			return ctrl.Result{RequeueAfter: r.requeueShort()}, true, err
+		}
+	}
+
+	if err := r.patchAnnotations(ctx, mr, map[string]string{
+		AnnotationSnapshotInProgress: "",
+		AnnotationSnapshotSince:      "",
+	}); err != nil {
+		return ctrl.Result{}, true, err
+	}
+	switch {
+	case timedOut:
+		r.Recorder.Eventf(mr, corev1.EventTypeWarning, "SnapshotFailed",
+			"Snapshot %s did not finish within %s; no longer tracking it", name, snapshotTimeout)
+	case status == nil:
+		r.Recorder.Eventf(mr, corev1.EventTypeWarning, "SnapshotFailed", "Snapshot %s was deleted before it finished", name)
+	case status.Phase == harvester.SnapshotPhaseFailed:
+		message := fmt.Sprintf("Snapshot %s failed", name)
+		if status.Message != "" {
+			message += ": " + status.Message
+		}
+		r.Recorder.Event(mr, corev1.EventTypeWarning, "SnapshotFailed", message)
+	default:
+		logf.FromContext(ctx).Info("VM snapshot taken", "snapshot", name)
+		r.Recorder.Eventf(mr, corev1.EventTypeNormal, "SnapshotTaken", "Snapshot %s taken", name)
+	}
+	return ctrl.Result{RequeueAfter: r.requeueShort()}, true, nil
+}
+
+// startSnapshotRestore starts the restore requested through
+// AnnotationRestoreSnapshot. Harvester only restores stopped VMs, so the
+// request is dropped unless the desired power state is Stopped.
+func (r *MachineRequestReconciler) startSnapshotRestore(
+	ctx context.Context,
+	mr *butlerv1alpha1.MachineRequest,
+	hc *harvester.Client,
+	status *harvester.VMStatus,
+	suffix string,
+) (ctrl.Result, bool, error) {
+	log := logf.FromContext(ctx)
+
+	var name string
+	err := fmt.Errorf("VM must be stopped; set %s to %s first", AnnotationPowerState, powerStateStopped)
+	if status.Phase == harvester.VMPhaseStopped {
+		log.Info("Restoring VM snapshot", "name", mr.Spec.MachineName, "suffix", suffix)
+		name, err = hc.RestoreVMSnapshot(ctx, mr.Spec.MachineName, suffix)
+	}
+	if err != nil {
+		log.Error(err, "Failed to restore VM snapshot")
+		r.Recorder.Eventf(mr, corev1.EventTypeWarning, "SnapshotRestoreFailed", "Failed to restore snapshot: %v", err)
+		if err := r.patchAnnotations(ctx, mr, map[string]string{AnnotationRestoreSnapshot: ""}); err != nil {
+			return ctrl.Result{}, true, err
+		}
+		return ctrl.Result{RequeueAfter: r.requeueShort()}, true, nil
+	}
+	if err := r.patchAnnotations(ctx, mr, map[string]string{
+		AnnotationRestoreSnapshot: "",
+		AnnotationSnapshotRestore: name,
+		AnnotationSnapshotSince:   time.Now().UTC().Format(time.RFC3339),
+	}); err != nil {
+		return ctrl.Result{}, true, err
+	}
+	r.Recorder.Eventf(mr, corev1.EventTypeNormal, "SnapshotRestoring", "Restoring snapshot %s",
+		harvester.VMSnapshotName(mr.Spec.MachineName, suffix))
+	return ctrl.Result{RequeueAfter: r.requeueShort()}, true, nil
+}
+
+// trackSnapshotRestore polls the snapshot restore in progress and reports its
+// outcome once it finishes. The VM stays stopped until then.
+func (r *MachineRequestReconciler) trackSnapshotRestore(
+	ctx context.Context,
+	mr *butlerv1alpha1.MachineRequest,
+	hc *harvester.Client,
+	name string,
+) (ctrl.Result, bool, error) {
+	status, err := hc.GetSnapshotRestoreStatus(ctx, name)
+	if err != nil && !apierrors.IsNotFound(err) {
+		logf.FromContext(ctx).Error(err, "Failed to get snapshot restore status", "restore", name)
+		return ctrl.Result{RequeueAfter: r.requeueShort()}, true, nil
+	}
+	timedOut := false
+	if err == nil && !status.Complete && !status.Failed {
+		if timedOut, err = r.snapshotTimedOut(ctx, mr); err != nil || !timedOut {
+			return ctrl.Result{RequeueAfter: r.requeueShort()}, true, err
+		}
+	}
+
+	if err := r.patchAnnotations(ctx, mr, map[string]string{
+		AnnotationSnapshotRestore: "",
+		AnnotationSnapshotSince:   "",
+	}); err != nil {
+		return ctrl.Result{}, true, err
+	}
+	switch {
+	case timedOut:
+		r.Recorder.Eventf(mr, corev1.EventTypeWarning, "SnapshotRestoreFailed",
+			"Snapshot restore %s did not finish within %s; no longer tracking it", name, snapshotTimeout)
+	case status == nil:
+		r.Recorder.Eventf(mr, corev1.EventTypeWarning, "SnapshotRestoreFailed", "Snapshot restore %s was deleted before it finished", name)
+	case status.Failed:
+		r.Recorder.Eventf(mr, corev1.EventTypeWarning, "SnapshotRestoreFailed", "Snapshot restore %s failed: %s", name, status.Message)
+	default:
+		logf.FromContext(ctx).Info("VM snapshot restored", "restore", name)
+		r.Recorder.Eventf(mr, corev1.EventTypeNormal, "SnapshotRestored",
+			"Snapshot restore %s completed; set %s to %s to start the VM", name, AnnotationPowerState, powerStateRunning)
+	}
+	return ctrl.Result{RequeueAfter: r.requeueShort()}, true, nil
+}
+
+// snapshotTimedOut reports whether the snapshot or restore in progress has
+// been tracked for longer than snapshotTimeout. Ones started before the
+// start time was recorded start counting now.
+func (r *MachineRequestReconciler) snapshotTimedOut(ctx context.Context, mr *butlerv1alpha1.MachineRequest) (bool, error) {
+	since, err := time.Parse(time.RFC3339, mr.Annotations[AnnotationSnapshotSince])
+	if err != nil {
+		return false, r.patchAnnotations(ctx, mr, map[string]string{
+			AnnotationSnapshotSince: time.Now().UTC().Format(time.RFC3339),
+		})
+	}
+	return time.Since(since) > snapshotTimeout, nil
+}
@@ -0,0 +1,227 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/butlerdotdev/butler-provider-harvester/internal/harvester"
+)
+
+// testSnapshot returns the Harvester snapshot of the test VM with the given
+// suffix and status.
+func testSnapshot(suffix string, status map[string]interface{}) *unstructured.Unstructured {
+	snapshot := &unstructured.Unstructured{}
+	snapshot.SetAPIVersion("harvesterhci.io/v1beta1")
+	snapshot.SetKind("VirtualMachineBackup")
+	snapshot.SetNamespace("default")
+	snapshot.SetName(harvester.VMSnapshotName("worker-0", suffix))
+	snapshot.Object["spec"] = map[string]interface{}{"type": "snapshot"}
+	if status != nil {
+		snapshot.Object["status"] = status
+	}
+	return snapshot
+}
+
+// testSnapshotRestore returns a restore of the test VM with the given status.
+func testSnapshotRestore(status map[string]interface{}) *unstructured.Unstructured {
+	restore := &unstructured.Unstructured{}
+	restore.SetAPIVersion("harvesterhci.io/v1beta1")
+	restore.SetKind("VirtualMachineRestore")
+	restore.SetNamespace("default")
+	restore.SetName("worker-0-snapshot-nightly-restore-abcde")
+	if status != nil {
+		restore.Object["status"] = status
+	}
+	return restore
+}
+
+var _ = Describe("VM snapshots", func() {
+	var ctx context.Context
+
+	BeforeEach(func() {
+		ctx = context.Background()
+	})
+
+	running := &harvester.VMStatus{Exists: true, Phase: "Running"}
+	stopped := &harvester.VMStatus{Exists: true, Phase: harvester.VMPhaseStopped}
+
+	It("does nothing without a request", func() {
+		mr := testMachineRequest(nil)
+		r, recorder := testReconciler(mr)
+		_, wait, err := r.reconcileSnapshots(ctx, mr, testHarvesterClient(), running, powerStateRunning)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(wait).To(BeFalse())
+		Expect(recorder.Events).To(BeEmpty())
+	})
+
+	DescribeTable("takes a requested snapshot",
+		func(suffix, inProgress, event string) {
+			mr := testMachineRequest(map[string]string{"snapshot": suffix})
+			r, recorder := testReconciler(mr)
+			hc := testHarvesterClient()
+			_, wait, err := r.reconcileSnapshots(ctx, mr, hc, running, powerStateRunning)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(wait).To(BeTrue())
+			Expect(recorder.Events).To(Receive(ContainSubstring(event)))
+			Expect(mr.Annotations).NotTo(HaveKey(AnnotationSnapshot))
+			if inProgress == "" {
+				Expect(mr.Annotations).NotTo(HaveKey(AnnotationSnapshotInProgress))
+				return
+			}
+			Expect(mr.Annotations[AnnotationSnapshotInProgress]).To(MatchRegexp(inProgress))
+			_, err = hc.GetVMSnapshotStatus(ctx, mr.Annotations[AnnotationSnapshotInProgress])
+			Expect(err).NotTo(HaveOccurred())
+		},
+		Entry("with a suffix", "nightly", "^worker-0-snapshot-nightly$", "Taking snapshot worker-0-snapshot-nightly"),
+		Entry("named after the time", "", `^worker-0-snapshot-\d{8}-\d{6}$`, "Taking snapshot worker-0-snapshot-"),
+		Entry("with an invalid suffix", "Nightly_1", "", `invalid snapshot suffix "Nightly_1"`),
+	)
+
+	DescribeTable("tracks the snapshot being taken",
+		func(objects []runtime.Object, event string) {
+			mr := testMachineRequest(map[string]string{"snapshot-in-progress": "worker-0-snapshot-nightly"})
+			r, recorder := testReconciler(mr)
+			_, wait, err := r.reconcileSnapshots(ctx, mr, testHarvesterClient(objects...), running, powerStateRunning)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(wait).To(BeTrue())
+			if event == "" {
+				Expect(recorder.Events).To(BeEmpty())
+				Expect(mr.Annotations).To(HaveKey(AnnotationSnapshotInProgress))
+				return
+			}
+			Expect(recorder.Events).To(Receive(ContainSubstring(event)))
+			Expect(mr.Annotations).NotTo(HaveKey(AnnotationSnapshotInProgress))
+		},
+		Entry("in progress", []runtime.Object{testSnapshot("nightly", nil)}, ""),
+		Entry("taken", []runtime.Object{testSnapshot("nightly", map[string]interface{}{"readyToUse": true})},
+			"Snapshot worker-0-snapshot-nightly taken"),
+		Entry("failed", []runtime.Object{testSnapshot("nightly", map[string]interface{}{
+			"error": map[string]interface{}{"message": "volume snapshot class not found"},
+		})}, "Snapshot worker-0-snapshot-nightly failed: volume snapshot class not found"),
+		Entry("deleted", nil, "Snapshot worker-0-snapshot-nightly was deleted before it finished"),
+	)
+
+	It("stops tracking a snapshot that does not finish", func() {
+		mr := testMachineRequest(map[string]string{
+			"snapshot-in-progress": "worker-0-snapshot-nightly",
+			"snapshot-since":       time.Now().Add(-2 * snapshotTimeout).UTC().Format(time.RFC3339),
+		})
+		r, recorder := testReconciler(mr)
+		hc := testHarvesterClient(testSnapshot("nightly", nil))
+		_, wait, err := r.reconcileSnapshots(ctx, mr, hc, running, powerStateRunning)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(wait).To(BeTrue())
+		Expect(recorder.Events).To(Receive(ContainSubstring("Snapshot worker-0-snapshot-nightly did not finish within 1h0m0s")))
+		Expect(mr.Annotations).NotTo(HaveKey(AnnotationSnapshotInProgress))
+		Expect(mr.Annotations).NotTo(HaveKey(AnnotationSnapshotSince))
+
+		_, wait, err = r.reconcileSnapshots(ctx, mr, hc, running, powerStateRunning)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(wait).To(BeFalse())
+	})
+
+	DescribeTable("restores a requested snapshot of a stopped VM",
+		func(status *harvester.VMStatus, power string, objects []runtime.Object, wait bool, event string) {
+			mr := testMachineRequest(map[string]string{"restore-snapshot": "nightly"})
+			r, recorder := testReconciler(mr)
+			_, waited, err := r.reconcileSnapshots(ctx, mr, testHarvesterClient(objects...), status, power)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(waited).To(Equal(wait))
+			if event == "" {
+				Expect(recorder.Events).To(BeEmpty())
+				Expect(mr.Annotations).To(HaveKey(AnnotationRestoreSnapshot))
+				return
+			}
+			Expect(recorder.Events).To(Receive(ContainSubstring(event)))
+			Expect(mr.Annotations).NotTo(HaveKey(AnnotationRestoreSnapshot))
+		},
+		Entry("a running VM", running, powerStateRunning, nil, true, "VM must be stopped"),
+		Entry("a VM still shutting down", running, powerStateStopped, nil, false, ""),
+		Entry("a snapshot not ready yet", stopped, powerStateStopped,
+			[]runtime.Object{testSnapshot("nightly", nil)}, true, "snapshot not ready"),
+		Entry("a ready snapshot", stopped, powerStateStopped,
+			[]runtime.Object{testSnapshot("nightly", map[string]interface{}{"readyToUse": true})}, true,
+			"Restoring snapshot worker-0-snapshot-nightly"),
+	)
+
+	It("records the restore it started", func() {
+		mr := testMachineRequest(map[string]string{"restore-snapshot": "nightly"})
+		r, _ := testReconciler(mr)
+		hc := testHarvesterClient(testSnapshot("nightly", map[string]interface{}{"readyToUse": true}))
+		_, _, err := r.reconcileSnapshots(ctx, mr, hc, stopped, powerStateStopped)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(mr.Annotations[AnnotationSnapshotRestore]).To(HavePrefix("worker-0-snapshot-nightly-restore-"))
+		_, err = hc.GetSnapshotRestoreStatus(ctx, mr.Annotations[AnnotationSnapshotRestore])
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	DescribeTable("tracks the snapshot restore",
+		func(objects []runtime.Object, event string) {
+			mr := testMachineRequest(map[string]string{"snapshot-restore": "worker-0-snapshot-nightly-restore-abcde"})
+			r, recorder := testReconciler(mr)
+			_, wait, err := r.reconcileSnapshots(ctx, mr, testHarvesterClient(objects...), stopped, powerStateStopped)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(wait).To(BeTrue())
+			if event == "" {
+				Expect(recorder.Events).To(BeEmpty())
+				Expect(mr.Annotations).To(HaveKey(AnnotationSnapshotRestore))
+				return
+			}
+			Expect(recorder.Events).To(Receive(ContainSubstring(event)))
+			Expect(mr.Annotations).NotTo(HaveKey(AnnotationSnapshotRestore))
+		},
+		Entry("in progress", []runtime.Object{testSnapshotRestore(map[string]interface{}{
+			"conditions": []interface{}{map[string]interface{}{"type": "Progressing", "status": "True", "message": "restoring volumes"}},
+		})}, ""),
+		Entry("completed", []runtime.Object{testSnapshotRestore(map[string]interface{}{"complete": true})},
+			"Snapshot restore worker-0-snapshot-nightly-restore-abcde completed"),
+		Entry("failed", []runtime.Object{testSnapshotRestore(map[string]interface{}{
+			"conditions": []interface{}{map[string]interface{}{"type": "Failure", "status": "True", "message": "VM is running"}},
+		})}, "Snapshot restore worker-0-snapshot-nightly-restore-abcde failed: VM is running"),
+		Entry("deleted", nil, "Snapshot restore worker-0-snapshot-nightly-restore-abcde was deleted before it finished"),
+	)
+
+	It("stops tracking a snapshot restore that does not finish", func() {
+		mr := testMachineRequest(map[string]string{
+			"snapshot-restore": "worker-0-snapshot-nightly-restore-abcde",
+			"snapshot-since":   time.Now().Add(-2 * snapshotTimeout).UTC().Format(time.RFC3339),
+		})
+		r, recorder := testReconciler(mr)
+		hc := testHarvesterClient(testSnapshotRestore(nil))
+		_, _, err := r.reconcileSnapshots(ctx, mr, hc, stopped, powerStateStopped)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(recorder.Events).To(Receive(ContainSubstring("did not finish within 1h0m0s")))
+		Expect(mr.Annotations).NotTo(HaveKey(AnnotationSnapshotRestore))
+		Expect(mr.Annotations).NotTo(HaveKey(AnnotationSnapshotSince))
+	})
+
+	It("starts the timeout of a snapshot tracked without a start time", func() {
+		mr := testMachineRequest(map[string]string{"snapshot-in-progress": "worker-0-snapshot-nightly"})
+		r, _ := testReconciler(mr)
+		_, _, err := r.reconcileSnapshots(ctx, mr, testHarvesterClient(testSnapshot("nightly", nil)), running, powerStateRunning)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(mr.Annotations).To(HaveKey(AnnotationSnapshotSince))
+		Expect(mr.Annotations).To(HaveKey(AnnotationSnapshotInProgress))
+	})
+})
@@ -0,0 +1,116 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	butlerv1alpha1 "github.com/butlerdotdev/butler-api/api/v1alpha1"
+	"github.com/butlerdotdev/butler-provider-harvester/internal/harvester"
+)
+
+// sshKeyPropagationSettle is how long after a key rotation was seen the
+// access credentials must still be reported synchronized to confirm it.
+const sshKeyPropagationSettle = time.Minute
+
+// checkSSHKeyRotation tracks rotation of the keys in the ssh-key-secret
+// Secret. KubeVirt propagates changed keys through the guest agent on its
+// own; the controller notices the change, reports it as pending and confirms
+// it once KubeVirt still reports the access credentials synchronized
+// sshKeyPropagationSettle later. It returns whether the status conditions
+// changed.
+func (r *MachineRequestReconciler) checkSSHKeyRotation(
+	ctx context.Context,
+	mr *butlerv1alpha1.MachineRequest,
+	hc *harvester.Client,
+	status *harvester.VMStatus,
+) (bool, error) {
+	secret := mr.Annotations[AnnotationSSHKeySecret]
+	if secret == "" {
+		return meta.RemoveStatusCondition(&mr.Status.Conditions, ConditionTypeSSHKeysRotated), nil
+	}
+
+	log := logf.FromContext(ctx)
+	current, err := hc.SSHKeySecretHash(ctx, secret)
+	if err != nil {
+		log.Error(err, "Failed to read SSH key secret")
+		return false, nil
+	}
+	recorded, ok := mr.Annotations[AnnotationSSHKeySecretHash]
+	if !ok {
+		// The VM booted with the current keys; take them as baseline
+		return false, r.patchAnnotations(ctx, mr, map[string]string{AnnotationSSHKeySecretHash: current})
+	}
+	if recorded == current {
+		return false, nil
+	}
+
+	// KubeVirt only reports changes of the sync status, not each sync, so a
+	// sync of the new keys that succeeds like the previous one leaves the VMI
+	// untouched. Confirm once the condition is still true a settle period
+	// after the rotation was seen, measured on the controller's clock; a
+	// failed propagation turns it false well within that period
+	pending := meta.FindStatusCondition(mr.Status.Conditions, ConditionTypeSSHKeysRotated)
+	wasPending := pending != nil && pending.Reason == ReasonRotationPending
+	settled := wasPending && time.Since(pending.LastTransitionTime.Time) >= sshKeyPropagationSettle
+	if settled && status.AccessCredentialsSynced && status.Phase == "Running" && status.AgentConnected {
+		if err := r.patchAnnotations(ctx, mr, map[string]string{AnnotationSSHKeySecretHash: current}); err != nil {
+			return false, err
+		}
+		message := fmt.Sprintf("Rotated SSH keys from secret %s were propagated to the guest", secret)
+		log.Info("SSH keys rotated", "secret", secret)
+		meta.SetStatusCondition(&mr.Status.Conditions, metav1.Condition{
+			Type:               ConditionTypeSSHKeysRotated,
+			Status:             metav1.ConditionTrue,
+			Reason:             ReasonSSHKeysPropagated,
+			Message:            message,
+			ObservedGeneration: mr.Generation,
+		})
+		r.Recorder.Event(mr, corev1.EventTypeNormal, ConditionTypeSSHKeysRotated, message)
+		return true, nil
+	}
+
+	message := fmt.Sprintf("SSH keys in secret %s changed; waiting for the guest agent to propagate them", secret)
+	switch {
+	case !status.AgentConnected:
+		message += " (guest agent not connected)"
+	case status.AccessCredentialsMessage != "":
+		message += ": " + status.AccessCredentialsMessage
+	}
+	if wasPending && pending.Message == message {
+		return false, nil
+	}
+	if !wasPending {
+		log.Info("SSH key secret changed, waiting for propagation", "secret", secret)
+		r.Recorder.Eventf(mr, corev1.EventTypeNormal, ReasonRotationPending, "SSH keys in secret %s changed", secret)
+	}
+	meta.SetStatusCondition(&mr.Status.Conditions, metav1.Condition{
+		Type:               ConditionTypeSSHKeysRotated,
+		Status:             metav1.ConditionFalse,
+		Reason:             ReasonRotationPending,
+		Message:            message,
+		ObservedGeneration: mr.Generation,
+	})
+	return true, nil
+}
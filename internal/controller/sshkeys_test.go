@@ -0,0 +1,101 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	butlerv1alpha1 "github.com/butlerdotdev/butler-api/api/v1alpha1"
+	"github.com/butlerdotdev/butler-provider-harvester/internal/harvester"
+)
+
+var _ = Describe("SSH key rotation", func() {
+	var (
+		ctx context.Context
+		r   *MachineRequestReconciler
+		hc  *harvester.Client
+		mr  *butlerv1alpha1.MachineRequest
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		hc = testHarvesterClient(&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "ssh-keys"},
+			Data:       map[string][]byte{"key1": []byte("ssh-ed25519 AAAA new")},
+		})
+		mr = testMachineRequest(map[string]string{
+			"ssh-key-secret":      "ssh-keys",
+			"ssh-key-secret-hash": "stale",
+		})
+		r, _ = testReconciler(mr)
+	})
+
+	// pendingFor marks the rotation as seen the given time ago.
+	pendingFor := func(d time.Duration) {
+		meta.SetStatusCondition(&mr.Status.Conditions, metav1.Condition{
+			Type:               ConditionTypeSSHKeysRotated,
+			Status:             metav1.ConditionFalse,
+			Reason:             ReasonRotationPending,
+			Message:            "SSH keys in secret ssh-keys changed; waiting for the guest agent to propagate them",
+			LastTransitionTime: metav1.NewTime(time.Now().Add(-d)),
+		})
+	}
+
+	DescribeTable("confirms a rotation once the sync has settled",
+		func(pending time.Duration, synced, rotated bool) {
+			if pending > 0 {
+				pendingFor(pending)
+			}
+			// The sync status KubeVirt reports may be unchanged since long
+			// before the rotation
+			status := &harvester.VMStatus{
+				Phase:                   "Running",
+				AgentConnected:          true,
+				AccessCredentialsSynced: synced,
+			}
+			_, err := r.checkSSHKeyRotation(ctx, mr, hc, status)
+			Expect(err).NotTo(HaveOccurred())
+			condition := meta.FindStatusCondition(mr.Status.Conditions, ConditionTypeSSHKeysRotated)
+			if rotated {
+				Expect(condition.Reason).To(Equal(ReasonSSHKeysPropagated))
+				Expect(mr.Annotations[AnnotationSSHKeySecretHash]).NotTo(Equal("stale"))
+			} else {
+				Expect(condition.Reason).To(Equal(ReasonRotationPending))
+				Expect(mr.Annotations).To(HaveKeyWithValue(AnnotationSSHKeySecretHash, "stale"))
+			}
+		},
+		Entry("synced past the settle period", 2*sshKeyPropagationSettle, true, true),
+		Entry("within the settle period", sshKeyPropagationSettle/2, true, false),
+		Entry("on first sight", time.Duration(0), true, false),
+		Entry("failed past the settle period", 2*sshKeyPropagationSettle, false, false),
+	)
+
+	It("takes the current keys as baseline on first sight", func() {
+		delete(mr.Annotations, AnnotationSSHKeySecretHash)
+		changed, err := r.checkSSHKeyRotation(ctx, mr, hc, &harvester.VMStatus{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(changed).To(BeFalse())
+		Expect(mr.Annotations[AnnotationSSHKeySecretHash]).NotTo(BeEmpty())
+	})
+})
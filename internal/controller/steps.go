@@ -0,0 +1,138 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	butlerv1alpha1 "github.com/butlerdotdev/butler-api/api/v1alpha1"
+	"github.com/butlerdotdev/butler-provider-harvester/internal/harvester"
+)
+
+// provisioningSteps lists the provisioning step conditions in pipeline order
+// with the message shown while each is pending.
+var provisioningSteps = []struct {
+	conditionType string
+	pending       string
+}{
+	{ConditionTypeImageValidated, "Waiting for the image to be validated"},
+	{ConditionTypePVCCreated, "Waiting for the root disk PVC to be created"},
+	{ConditionTypePVCBound, "Waiting for the root disk PVC to be bound"},
+	{ConditionTypeVMCreated, "Waiting for the VM to be created"},
+	{ConditionTypeVMIScheduled, "Waiting for the VM instance to be scheduled to a node"},
+	{ConditionTypeIPAssigned, "Waiting for the guest to report an IP address"},
+	{ConditionTypeGuestReady, "Waiting for the guest agent to connect"},
+}
+
+// resetProvisioningSteps marks every provisioning step of a VM about to be
+// created as pending. The image and root disk steps are dropped for VMs
+// without a cloned root disk, and the image step for an imported root disk.
+// GuestReady is only tracked for VMs that rely on the guest agent, which is
+// when SSH keys are propagated through it.
+func resetProvisioningSteps(mr *butlerv1alpha1.MachineRequest, opts harvester.VMCreateOptions) {
+	clonesImage := opts.ContainerDiskImage == "" && opts.RestoreFromBackup == ""
+	for _, step := range provisioningSteps {
+		switch step.conditionType {
+		case ConditionTypeImageValidated, ConditionTypePVCCreated, ConditionTypePVCBound:
+			if !clonesImage || (step.conditionType == ConditionTypeImageValidated && opts.RootDiskImportURL != "") {
+				meta.RemoveStatusCondition(&mr.Status.Conditions, step.conditionType)
+				continue
+			}
+		case ConditionTypeGuestReady:
+			if opts.SSHKeySecret == "" {
+				meta.RemoveStatusCondition(&mr.Status.Conditions, step.conditionType)
+				continue
+			}
+		}
+		meta.SetStatusCondition(&mr.Status.Conditions, metav1.Condition{
+			Type:               step.conditionType,
+			Status:             metav1.ConditionFalse,
+			Reason:             ReasonStepPending,
+			Message:            step.pending,
+			ObservedGeneration: mr.Generation,
+		})
+	}
+}
+
+// completeProvisioningStep marks a pending provisioning step completed. Steps
+// that were never reset, such as the root disk steps of a container disk VM,
+// are left unset. It returns whether the condition changed.
+func completeProvisioningStep(mr *butlerv1alpha1.MachineRequest, conditionType, message string) bool {
+	cond := meta.FindStatusCondition(mr.Status.Conditions, conditionType)
+	if cond == nil || cond.Status == metav1.ConditionTrue {
+		return false
+	}
+	meta.SetStatusCondition(&mr.Status.Conditions, metav1.Condition{
+		Type:               conditionType,
+		Status:             metav1.ConditionTrue,
+		Reason:             ReasonStepCompleted,
+		Message:            message,
+		ObservedGeneration: mr.Generation,
+	})
+	return true
+}
+
+// observeProvisioningSteps completes the provisioning steps evident from the
+// VM status: every step up to VMCreated once the VM exists, then scheduling,
+// the IP address and the guest agent connection. It returns whether any
+// condition changed.
+func observeProvisioningSteps(mr *butlerv1alpha1.MachineRequest, status *harvester.VMStatus) bool {
+	if !status.Exists {
+		return false
+	}
+	changed := completeProvisioningStep(mr, ConditionTypeImageValidated, "Image validated")
+	changed = completeProvisioningStep(mr, ConditionTypePVCCreated, "Root disk PVC created") || changed
+	changed = completeProvisioningStep(mr, ConditionTypeVMCreated, fmt.Sprintf("VM %s created", mr.Spec.MachineName)) || changed
+	if status.NodeName != "" {
+		changed = completeProvisioningStep(mr, ConditionTypeVMIScheduled, "VM instance scheduled to node "+status.NodeName) || changed
+	}
+	if status.IPAddress != "" {
+		// A guest with an address has booted from its root disk
+		changed = completeProvisioningStep(mr, ConditionTypePVCBound, "Root disk PVC bound") || changed
+		changed = completeProvisioningStep(mr, ConditionTypeIPAssigned, "Guest reported IP "+status.IPAddress) || changed
+	}
+	if status.AgentConnected {
+		changed = completeProvisioningStep(mr, ConditionTypeGuestReady, "Guest agent connected") || changed
+	}
+	return changed
+}
+
+// clearProvisioningSteps removes the provisioning step conditions of a
+// running VM once none of them is pending, so they only show while creation
+// is in progress. A pending GuestReady only holds them for VMs that rely on
+// the guest agent. It returns whether any condition was removed.
+func clearProvisioningSteps(mr *butlerv1alpha1.MachineRequest) bool {
+	for _, step := range provisioningSteps {
+		if step.conditionType == ConditionTypeGuestReady && mr.Annotations[AnnotationSSHKeySecret] == "" {
+			continue
+		}
+		if meta.IsStatusConditionFalse(mr.Status.Conditions, step.conditionType) {
+			return false
+		}
+	}
+	changed := false
+	for _, step := range provisioningSteps {
+		if meta.FindStatusCondition(mr.Status.Conditions, step.conditionType) != nil {
+			meta.RemoveStatusCondition(&mr.Status.Conditions, step.conditionType)
+			changed = true
+		}
+	}
+	return changed
+}
@@ -0,0 +1,165 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	butlerv1alpha1 "github.com/butlerdotdev/butler-api/api/v1alpha1"
+	"github.com/butlerdotdev/butler-provider-harvester/internal/harvester"
+)
+
+var _ = Describe("Provisioning steps", func() {
+	allSteps := []string{
+		ConditionTypeImageValidated, ConditionTypePVCCreated, ConditionTypePVCBound, ConditionTypeVMCreated,
+		ConditionTypeVMIScheduled, ConditionTypeIPAssigned, ConditionTypeGuestReady,
+	}
+	vmSteps := []string{ConditionTypeVMCreated, ConditionTypeVMIScheduled, ConditionTypeIPAssigned, ConditionTypeGuestReady}
+	// agentMachineRequest returns a MachineRequest whose SSH keys are
+	// propagated through the guest agent.
+	agentMachineRequest := func() *butlerv1alpha1.MachineRequest {
+		return testMachineRequest(map[string]string{"ssh-key-secret": "worker-keys", "ssh-key-users": "ubuntu"})
+	}
+
+	// stepsWithStatus returns the provisioning step conditions of mr that
+	// have the given status.
+	stepsWithStatus := func(conditions []metav1.Condition, status metav1.ConditionStatus) []string {
+		var steps []string
+		for _, step := range allSteps {
+			if cond := meta.FindStatusCondition(conditions, step); cond != nil && cond.Status == status {
+				steps = append(steps, step)
+			}
+		}
+		return steps
+	}
+
+	DescribeTable("resets the steps the root disk source goes through",
+		func(configure func(*harvester.VMCreateOptions), want []string) {
+			mr := agentMachineRequest()
+			// Steps completed for an earlier VM are reset, unused ones removed
+			for _, step := range allSteps {
+				meta.SetStatusCondition(&mr.Status.Conditions, metav1.Condition{
+					Type: step, Status: metav1.ConditionTrue, Reason: ReasonStepCompleted,
+				})
+			}
+			opts, err := vmCreateOptions(mr)
+			Expect(err).NotTo(HaveOccurred())
+			configure(&opts)
+
+			resetProvisioningSteps(mr, opts)
+			Expect(stepsWithStatus(mr.Status.Conditions, metav1.ConditionFalse)).To(Equal(want))
+			Expect(stepsWithStatus(mr.Status.Conditions, metav1.ConditionTrue)).To(BeEmpty())
+		},
+		Entry("a root disk cloned from an image", func(*harvester.VMCreateOptions) {}, allSteps),
+		Entry("an imported root disk", func(o *harvester.VMCreateOptions) {
+			o.RootDiskImportURL = "https://images.example.com/jammy.img"
+		}, allSteps[1:]),
+		Entry("a container disk", func(o *harvester.VMCreateOptions) {
+			o.ContainerDiskImage = "quay.io/containerdisks/ubuntu:22.04"
+		}, vmSteps),
+		Entry("a restore from backup", func(o *harvester.VMCreateOptions) {
+			o.RestoreFromBackup = "worker-0-nightly"
+		}, vmSteps),
+		Entry("a guest without an agent", func(o *harvester.VMCreateOptions) {
+			o.SSHKeySecret = ""
+		}, allSteps[:6]),
+	)
+
+	DescribeTable("completes the steps evident from the VM status",
+		func(status harvester.VMStatus, completed []string) {
+			mr := agentMachineRequest()
+			opts, err := vmCreateOptions(mr)
+			Expect(err).NotTo(HaveOccurred())
+			resetProvisioningSteps(mr, opts)
+
+			Expect(observeProvisioningSteps(mr, &status)).To(Equal(len(completed) > 0))
+			Expect(stepsWithStatus(mr.Status.Conditions, metav1.ConditionTrue)).To(Equal(completed))
+			// A second observation of the same status changes nothing
+			Expect(observeProvisioningSteps(mr, &status)).To(BeFalse())
+		},
+		Entry("no VM yet", harvester.VMStatus{}, nil),
+		Entry("a VM not yet scheduled", harvester.VMStatus{Exists: true},
+			[]string{ConditionTypeImageValidated, ConditionTypePVCCreated, ConditionTypeVMCreated}),
+		Entry("a scheduled VM", harvester.VMStatus{Exists: true, NodeName: "harvester-0"},
+			[]string{ConditionTypeImageValidated, ConditionTypePVCCreated, ConditionTypeVMCreated, ConditionTypeVMIScheduled}),
+		Entry("a guest with an address", harvester.VMStatus{Exists: true, NodeName: "harvester-0", IPAddress: "10.0.0.5"},
+			allSteps[:6]),
+		Entry("a guest with a connected agent", harvester.VMStatus{
+			Exists: true, NodeName: "harvester-0", IPAddress: "10.0.0.5", AgentConnected: true,
+		}, allSteps),
+	)
+
+	It("leaves steps that were never reset unset", func() {
+		mr := testMachineRequest(nil)
+		Expect(completeProvisioningStep(mr, ConditionTypePVCBound, "Root disk PVC bound")).To(BeFalse())
+		Expect(mr.Status.Conditions).To(BeEmpty())
+	})
+
+	DescribeTable("clears the steps of a running VM once none is pending",
+		func(mr *butlerv1alpha1.MachineRequest, status harvester.VMStatus, cleared bool) {
+			opts, err := vmCreateOptions(mr)
+			Expect(err).NotTo(HaveOccurred())
+			resetProvisioningSteps(mr, opts)
+			observeProvisioningSteps(mr, &status)
+
+			Expect(clearProvisioningSteps(mr)).To(Equal(cleared))
+			if cleared {
+				Expect(stepsWithStatus(mr.Status.Conditions, metav1.ConditionTrue)).To(BeEmpty())
+			} else {
+				Expect(stepsWithStatus(mr.Status.Conditions, metav1.ConditionFalse)).NotTo(BeEmpty())
+			}
+		},
+		Entry("a guest without an agent", testMachineRequest(nil),
+			harvester.VMStatus{Exists: true, NodeName: "harvester-0", IPAddress: "10.0.0.5"}, true),
+		Entry("a guest waiting for its agent", agentMachineRequest(),
+			harvester.VMStatus{Exists: true, NodeName: "harvester-0", IPAddress: "10.0.0.5"}, false),
+		Entry("a guest with a connected agent", agentMachineRequest(),
+			harvester.VMStatus{Exists: true, NodeName: "harvester-0", IPAddress: "10.0.0.5", AgentConnected: true}, true),
+		Entry("a guest still starting", testMachineRequest(nil), harvester.VMStatus{Exists: true}, false),
+	)
+
+	It("ignores a pending GuestReady of a guest not relying on the agent", func() {
+		mr := testMachineRequest(nil)
+		meta.SetStatusCondition(&mr.Status.Conditions, metav1.Condition{
+			Type: ConditionTypeGuestReady, Status: metav1.ConditionFalse, Reason: ReasonStepPending,
+		})
+		Expect(clearProvisioningSteps(mr)).To(BeTrue())
+		Expect(mr.Status.Conditions).To(BeEmpty())
+	})
+
+	It("removes the steps when the VM reaches Running", func() {
+		ctx := context.Background()
+		mr := testMachineRequest(nil)
+		mr.Finalizers = []string{finalizerName}
+		mr.Status.Phase = butlerv1alpha1.MachinePhaseCreating
+		opts, err := vmCreateOptions(mr)
+		Expect(err).NotTo(HaveOccurred())
+		resetProvisioningSteps(mr, opts)
+		r, _ := testReconciler(mr)
+
+		_, err = r.reconcileCreating(ctx, mr, &butlerv1alpha1.ProviderConfig{}, testHarvesterClient(existingVM("worker-0", "uid-1", "10.0.0.5")...))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(mr.Status.Phase).To(Equal(butlerv1alpha1.MachinePhaseRunning))
+		Expect(stepsWithStatus(mr.Status.Conditions, metav1.ConditionTrue)).To(BeEmpty())
+		Expect(stepsWithStatus(mr.Status.Conditions, metav1.ConditionFalse)).To(BeEmpty())
+	})
+})
@@ -0,0 +1,81 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"golang.org/x/time/rate"
+	"k8s.io/apimachinery/pkg/types"
+
+	butlerv1alpha1 "github.com/butlerdotdev/butler-api/api/v1alpha1"
+)
+
+// deleteRate returns the ProviderConfig's VM deletion rate, or rate.Inf when
+// deletions are not paced.
+func deleteRate(pc *butlerv1alpha1.ProviderConfig) (rate.Limit, error) {
+	value := pc.Annotations[AnnotationDeletionsPerMinute]
+	if value == "" {
+		return rate.Inf, nil
+	}
+	perMinute, err := strconv.ParseFloat(value, 64)
+	if err != nil || perMinute <= 0 {
+		return rate.Inf, fmt.Errorf("annotation %s: invalid rate %q (want a positive number)", AnnotationDeletionsPerMinute, value)
+	}
+	return rate.Limit(perMinute / 60), nil
+}
+
+// configureDeleteLimiter applies the ProviderConfig's deletion rate to the
+// limiter shared by every MachineRequest on that ProviderConfig. The limiter
+// outlives the ProviderConfig so deletions stay paced after it is removed.
+func (r *MachineRequestReconciler) configureDeleteLimiter(key types.NamespacedName, limit rate.Limit) {
+	r.deleteLimitersMu.Lock()
+	defer r.deleteLimitersMu.Unlock()
+	if lim, ok := r.deleteLimiters[key]; ok {
+		if lim.Limit() != limit {
+			lim.SetLimit(limit)
+		}
+		return
+	}
+	if limit == rate.Inf {
+		return
+	}
+	if r.deleteLimiters == nil {
+		r.deleteLimiters = map[types.NamespacedName]*rate.Limiter{}
+	}
+	r.deleteLimiters[key] = rate.NewLimiter(limit, 1)
+}
+
+// deleteDelay takes a deletion slot for the ProviderConfig. It returns zero
+// when the deletion may proceed, or how long to wait before trying again.
+func (r *MachineRequestReconciler) deleteDelay(key types.NamespacedName) time.Duration {
+	r.deleteLimitersMu.Lock()
+	lim := r.deleteLimiters[key]
+	r.deleteLimitersMu.Unlock()
+	if lim == nil {
+		return 0
+	}
+	reservation := lim.Reserve()
+	if delay := reservation.Delay(); delay > 0 {
+		// Give the slot back; the request retries when it frees up
+		reservation.Cancel()
+		return delay
+	}
+	return 0
+}
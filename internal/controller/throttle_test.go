@@ -0,0 +1,87 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"golang.org/x/time/rate"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	butlerv1alpha1 "github.com/butlerdotdev/butler-api/api/v1alpha1"
+)
+
+var _ = Describe("Deletion pacing", func() {
+	key := types.NamespacedName{Namespace: "butler-system", Name: "harvester"}
+
+	DescribeTable("reads the deletion rate",
+		func(value string, want rate.Limit, valid bool) {
+			pc := &butlerv1alpha1.ProviderConfig{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{}}}
+			if value != "" {
+				pc.Annotations[AnnotationDeletionsPerMinute] = value
+			}
+			limit, err := deleteRate(pc)
+			if valid {
+				Expect(err).NotTo(HaveOccurred())
+			} else {
+				Expect(err).To(MatchError(ContainSubstring(AnnotationDeletionsPerMinute)))
+			}
+			Expect(limit).To(Equal(want))
+		},
+		Entry("unset", "", rate.Inf, true),
+		Entry("six per minute", "6", rate.Limit(0.1), true),
+		Entry("a fraction per minute", "0.5", rate.Limit(0.5/60), true),
+		Entry("zero", "0", rate.Inf, false),
+		Entry("negative", "-1", rate.Inf, false),
+		Entry("not a number", "fast", rate.Inf, false),
+	)
+
+	It("lets one deletion through per interval", func() {
+		r, _ := testReconciler()
+		r.configureDeleteLimiter(key, rate.Every(time.Minute))
+		Expect(r.deleteDelay(key)).To(BeZero())
+		delay := r.deleteDelay(key)
+		Expect(delay).To(BeNumerically(">", 59*time.Second))
+		Expect(delay).To(BeNumerically("<=", time.Minute))
+		// The refused request did not take the next slot
+		Expect(r.deleteDelay(key)).To(BeNumerically("~", delay, time.Second))
+
+		other := types.NamespacedName{Namespace: "butler-system", Name: "other"}
+		Expect(r.deleteDelay(other)).To(BeZero())
+	})
+
+	It("does not pace ProviderConfigs without a rate", func() {
+		r, _ := testReconciler()
+		r.configureDeleteLimiter(key, rate.Inf)
+		for range 10 {
+			Expect(r.deleteDelay(key)).To(BeZero())
+		}
+	})
+
+	It("stops pacing once the rate is removed", func() {
+		r, _ := testReconciler()
+		r.configureDeleteLimiter(key, rate.Every(time.Hour))
+		Expect(r.deleteDelay(key)).To(BeZero())
+		Expect(r.deleteDelay(key)).To(BeNumerically(">", 0))
+
+		r.configureDeleteLimiter(key, rate.Inf)
+		Expect(r.deleteDelay(key)).To(BeZero())
+	})
+})
@@ -0,0 +1,117 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+
+	butlerv1alpha1 "github.com/butlerdotdev/butler-api/api/v1alpha1"
+)
+
+// defaultUserDataKey is the ConfigMap or Secret key read for user data when
+// AnnotationUserDataKey is unset.
+const defaultUserDataKey = "userData"
+
+// errUserDataSourceNotFound is returned while the ConfigMap or Secret named
+// for the user data does not exist yet.
+var errUserDataSourceNotFound = errors.New("user data source not found")
+
+// userData returns the cloud-init user data of mr: spec.userData, or the key
+// of the ConfigMap or Secret named by AnnotationUserDataConfigMap or
+// AnnotationUserDataSecret in the MachineRequest namespace. Setting more than
+// one source is an error rather than silently preferring one.
+func (r *MachineRequestReconciler) userData(ctx context.Context, mr *butlerv1alpha1.MachineRequest) (string, error) {
+	configMap := mr.Annotations[AnnotationUserDataConfigMap]
+	secret := mr.Annotations[AnnotationUserDataSecret]
+	var sources []string
+	if mr.Spec.UserData != "" {
+		sources = append(sources, "spec.userData")
+	}
+	if configMap != "" {
+		sources = append(sources, AnnotationUserDataConfigMap)
+	}
+	if secret != "" {
+		sources = append(sources, AnnotationUserDataSecret)
+	}
+	if len(sources) > 1 {
+		return "", fmt.Errorf("user data is set by both %s and %s; set only one", sources[0], sources[1])
+	}
+
+	key := mr.Annotations[AnnotationUserDataKey]
+	if key == "" {
+		key = defaultUserDataKey
+	}
+	name := types.NamespacedName{Namespace: mr.Namespace}
+	switch {
+	case configMap != "":
+		name.Name = configMap
+		cm := &corev1.ConfigMap{}
+		if err := r.apiReader().Get(ctx, name, cm); err != nil {
+			return "", userDataSourceError("ConfigMap", configMap, err)
+		}
+		if data, ok := cm.Data[key]; ok {
+			return data, nil
+		}
+		if data, ok := cm.BinaryData[key]; ok {
+			return string(data), nil
+		}
+		return "", fmt.Errorf("user data ConfigMap %s does not contain key %s", configMap, key)
+	case secret != "":
+		name.Name = secret
+		s := &corev1.Secret{}
+		if err := r.apiReader().Get(ctx, name, s); err != nil {
+			return "", userDataSourceError("Secret", secret, err)
+		}
+		data, ok := s.Data[key]
+		if !ok {
+			return "", fmt.Errorf("user data Secret %s does not contain key %s", secret, key)
+		}
+		return string(data), nil
+	default:
+		return mr.Spec.UserData, nil
+	}
+}
+
+// userDataSourceError wraps a failure to get a user data ConfigMap or Secret,
+// marking a missing object with errUserDataSourceNotFound.
+func userDataSourceError(kind, name string, err error) error {
+	if apierrors.IsNotFound(err) {
+		return fmt.Errorf("%w: %s %s", errUserDataSourceNotFound, kind, name)
+	}
+	return fmt.Errorf("failed to get user data %s %s: %w", kind, name, err)
+}
+
+// userDataRefs returns the UserDataRefIndex values of mr: one per ConfigMap
+// or Secret its user data is read from.
+func userDataRefs(mr *butlerv1alpha1.MachineRequest) []string {
+	var refs []string
+	if name := mr.Annotations[AnnotationUserDataConfigMap]; name != "" {
+		refs = append(refs, UserDataRefIndexValue("ConfigMap", name))
+	}
+	for _, key := range []string{AnnotationUserDataSecret, AnnotationCloudInitGroupSecret} {
+		if name := mr.Annotations[key]; name != "" {
+			refs = append(refs, UserDataRefIndexValue("Secret", name))
+		}
+	}
+	return refs
+}
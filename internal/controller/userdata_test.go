@@ -0,0 +1,107 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	butlerv1alpha1 "github.com/butlerdotdev/butler-api/api/v1alpha1"
+)
+
+var _ = Describe("User data sources", func() {
+	var ctx context.Context
+
+	sources := []client.Object{
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "butler-system", Name: "cloud-config"},
+			Data:       map[string]string{"userData": "#cloud-config\nhostname: from-data\n"},
+			BinaryData: map[string][]byte{"binary": []byte("#cloud-config\nhostname: from-binary\n")},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "butler-system", Name: "cloud-secret"},
+			Data:       map[string][]byte{"custom": []byte("#cloud-config\nhostname: from-secret\n")},
+		},
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+	})
+
+	DescribeTable("reads the user data",
+		func(userData string, annotations map[string]string, want string) {
+			mr := testMachineRequest(annotations)
+			mr.Spec.UserData = userData
+			r, _ := testReconciler(sources...)
+			Expect(r.userData(ctx, mr)).To(Equal(want))
+		},
+		Entry("from spec.userData", "#cloud-config\n", nil, "#cloud-config\n"),
+		Entry("from a ConfigMap", "", map[string]string{"user-data-configmap": "cloud-config"},
+			"#cloud-config\nhostname: from-data\n"),
+		Entry("from ConfigMap binary data", "", map[string]string{"user-data-configmap": "cloud-config", "user-data-key": "binary"},
+			"#cloud-config\nhostname: from-binary\n"),
+		Entry("from a Secret key", "", map[string]string{"user-data-secret": "cloud-secret", "user-data-key": "custom"},
+			"#cloud-config\nhostname: from-secret\n"),
+	)
+
+	DescribeTable("rejects",
+		func(userData string, annotations map[string]string, message string) {
+			mr := testMachineRequest(annotations)
+			mr.Spec.UserData = userData
+			r, _ := testReconciler(sources...)
+			_, err := r.userData(ctx, mr)
+			Expect(err).To(MatchError(ContainSubstring(message)))
+			Expect(err).NotTo(MatchError(errUserDataSourceNotFound))
+		},
+		Entry("spec.userData with a ConfigMap", "#cloud-config\n", map[string]string{"user-data-configmap": "cloud-config"},
+			"set by both spec.userData and "+AnnotationUserDataConfigMap),
+		Entry("a ConfigMap with a Secret", "", map[string]string{"user-data-configmap": "cloud-config", "user-data-secret": "cloud-secret"},
+			"set by both "+AnnotationUserDataConfigMap+" and "+AnnotationUserDataSecret),
+		Entry("a missing ConfigMap key", "", map[string]string{"user-data-configmap": "cloud-config", "user-data-key": "missing"},
+			"ConfigMap cloud-config does not contain key missing"),
+		Entry("a missing Secret key", "", map[string]string{"user-data-secret": "cloud-secret"},
+			"Secret cloud-secret does not contain key userData"),
+	)
+
+	It("reads the data through the API reader", func() {
+		r, _ := testReconciler()
+		reader, _ := testReconciler(sources...)
+		r.APIReader = reader.Client
+		Expect(r.userData(ctx, testMachineRequest(map[string]string{"user-data-secret": "cloud-secret", "user-data-key": "custom"}))).
+			To(ContainSubstring("from-secret"))
+	})
+
+	It("blocks a pending request until the source exists", func() {
+		mr := testMachineRequest(map[string]string{"user-data-configmap": "not-yet"})
+		mr.Status.Phase = butlerv1alpha1.MachinePhasePending
+		r, _ := testReconciler(mr)
+
+		result, err := r.reconcilePending(ctx, mr, testHarvesterClient())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.RequeueAfter).To(Equal(requeueBlocked))
+		blocked := meta.FindStatusCondition(mr.Status.Conditions, ConditionTypeBlocked)
+		Expect(blocked).NotTo(BeNil())
+		Expect(blocked.Reason).To(Equal(ReasonUserDataNotFound))
+		Expect(blocked.Message).To(ContainSubstring("ConfigMap not-yet"))
+	})
+})
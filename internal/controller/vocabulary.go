@@ -0,0 +1,187 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/yaml"
+
+	butlerv1alpha1 "github.com/butlerdotdev/butler-api/api/v1alpha1"
+)
+
+// conditionTypes lists every condition type the controller sets.
+var conditionTypes = []string{
+	butlerv1alpha1.ConditionTypeReady,
+	butlerv1alpha1.ConditionTypeProgressing,
+	butlerv1alpha1.ConditionTypeDegraded,
+	ConditionTypePaused,
+	ConditionTypeNameConflict,
+	ConditionTypeBlocked,
+	ConditionTypeInsufficientCapacity,
+	ConditionTypeGuestUnresponsive,
+	ConditionTypeCloudInitChanged,
+	ConditionTypeNetworkNotFound,
+	ConditionTypeReplicasReady,
+	ConditionTypeImmutableFieldChanged,
+	ConditionTypeRestartRequired,
+	ConditionTypeSSHKeysRotated,
+	ConditionTypeStopped,
+	ConditionTypeMigrating,
+	ConditionTypeImageValidated,
+	ConditionTypePVCCreated,
+	ConditionTypePVCBound,
+	ConditionTypeVMCreated,
+	ConditionTypeVMIScheduled,
+	ConditionTypeIPAssigned,
+	ConditionTypeGuestReady,
+}
+
+// conditionReasonPattern is the metav1.Condition reason format.
+var conditionReasonPattern = regexp.MustCompile(`^[A-Za-z]([A-Za-z0-9_,:]*[A-Za-z0-9_])?$`)
+
+// ConditionVocabulary renames the condition types and reasons the controller
+// writes on MachineRequests, for deployments whose other Butler components
+// expect different names. Reasons also apply to status.failureReason. Names
+// not listed are written unchanged. The controller keeps working with its
+// own names: conditions are translated on every status write and back when
+// a MachineRequest is read. A nil vocabulary leaves all names unchanged.
+type ConditionVocabulary struct {
+	Types   map[string]string `json:"types,omitempty"`
+	Reasons map[string]string `json:"reasons,omitempty"`
+
+	// fromTypes and fromReasons invert Types and Reasons.
+	fromTypes   map[string]string
+	fromReasons map[string]string
+}
+
+// LoadConditionVocabulary reads and validates a vocabulary from a YAML or
+// JSON file with "types" and "reasons" maps from the controller's names to
+// the deployment's.
+func LoadConditionVocabulary(path string) (*ConditionVocabulary, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	v := &ConditionVocabulary{}
+	if err := yaml.UnmarshalStrict(data, v); err != nil {
+		return nil, fmt.Errorf("invalid condition vocabulary %s: %w", path, err)
+	}
+	if err := v.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid condition vocabulary %s: %w", path, err)
+	}
+	return v, nil
+}
+
+// Validate checks that every renamed type is one the controller sets, that
+// the new names are valid, and that no two names end up the same so
+// conditions read back can be translated unambiguously.
+func (v *ConditionVocabulary) Validate() error {
+	known := map[string]bool{}
+	for _, t := range conditionTypes {
+		known[t] = true
+	}
+	for _, from := range sortedKeys(v.Types) {
+		to := v.Types[from]
+		if !known[from] {
+			return fmt.Errorf("unknown condition type %q", from)
+		}
+		if errs := validation.IsQualifiedName(to); len(errs) > 0 {
+			return fmt.Errorf("invalid condition type %q for %s: %s", to, from, errs[0])
+		}
+	}
+	for _, from := range sortedKeys(v.Reasons) {
+		if to := v.Reasons[from]; !conditionReasonPattern.MatchString(to) || len(to) > 1024 {
+			return fmt.Errorf("invalid condition reason %q for %s", to, from)
+		}
+	}
+
+	var err error
+	if v.fromTypes, err = invertNames("condition type", v.Types, conditionTypes); err != nil {
+		return err
+	}
+	v.fromReasons, err = invertNames("condition reason", v.Reasons, nil)
+	return err
+}
+
+// invertNames inverts a rename table, failing when two names, renamed or
+// one of the unchanged names, would be written the same.
+func invertNames(kind string, names map[string]string, unchanged []string) (map[string]string, error) {
+	inverse := map[string]string{}
+	for _, from := range sortedKeys(names) {
+		to := names[from]
+		if other, ok := inverse[to]; ok {
+			return nil, fmt.Errorf("%s %q is used for both %s and %s", kind, to, other, from)
+		}
+		inverse[to] = from
+	}
+	for _, name := range unchanged {
+		if _, renamed := names[name]; renamed {
+			continue
+		}
+		if other, ok := inverse[name]; ok {
+			return nil, fmt.Errorf("%s %q is used for %s but is also written unchanged", kind, name, other)
+		}
+	}
+	return inverse, nil
+}
+
+// sortedKeys returns the keys of m in order, for deterministic errors.
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// translate renames the condition types and reasons, and the failure reason,
+// of status using the given tables.
+func translate(status *butlerv1alpha1.MachineRequestStatus, types, reasons map[string]string) {
+	rename := func(names map[string]string, name string) string {
+		if to, ok := names[name]; ok {
+			return to
+		}
+		return name
+	}
+	for i := range status.Conditions {
+		status.Conditions[i].Type = rename(types, status.Conditions[i].Type)
+		status.Conditions[i].Reason = rename(reasons, status.Conditions[i].Reason)
+	}
+	if status.FailureReason != "" {
+		status.FailureReason = rename(reasons, status.FailureReason)
+	}
+}
+
+// toExternal translates status from the controller's names.
+func (v *ConditionVocabulary) toExternal(status *butlerv1alpha1.MachineRequestStatus) {
+	if v != nil {
+		translate(status, v.Types, v.Reasons)
+	}
+}
+
+// toInternal translates status back to the controller's names.
+func (v *ConditionVocabulary) toInternal(status *butlerv1alpha1.MachineRequestStatus) {
+	if v != nil {
+		translate(status, v.fromTypes, v.fromReasons)
+	}
+}
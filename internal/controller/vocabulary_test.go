@@ -0,0 +1,112 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	butlerv1alpha1 "github.com/butlerdotdev/butler-api/api/v1alpha1"
+)
+
+var _ = Describe("Condition vocabulary", func() {
+	DescribeTable("validates the rename tables",
+		func(types, reasons map[string]string, want string) {
+			err := (&ConditionVocabulary{Types: types, Reasons: reasons}).Validate()
+			if want == "" {
+				Expect(err).NotTo(HaveOccurred())
+				return
+			}
+			Expect(err).To(MatchError(ContainSubstring(want)))
+		},
+		Entry("empty", nil, nil, ""),
+		Entry("renamed types and reasons",
+			map[string]string{ConditionTypeStopped: "PoweredOff", ConditionTypeBlocked: "example.com/Blocked"},
+			map[string]string{ReasonPoweredOff: "Halted"}, ""),
+		Entry("an unknown type", map[string]string{"Exploded": "Failed"}, nil, `unknown condition type "Exploded"`),
+		Entry("an invalid type", map[string]string{ConditionTypeStopped: "powered off"}, nil, `invalid condition type "powered off"`),
+		Entry("an invalid reason", nil, map[string]string{ReasonPoweredOff: "1Halted"}, `invalid condition reason "1Halted"`),
+		Entry("two types renamed alike",
+			map[string]string{ConditionTypeStopped: "Off", ConditionTypePaused: "Off"}, nil,
+			`condition type "Off" is used for both`),
+		Entry("a type renamed to one written unchanged",
+			map[string]string{ConditionTypeStopped: ConditionTypePaused}, nil,
+			`condition type "Paused" is used for Stopped but is also written unchanged`),
+		Entry("a type swap",
+			map[string]string{ConditionTypeStopped: ConditionTypePaused, ConditionTypePaused: ConditionTypeStopped}, nil, ""),
+		Entry("two reasons renamed alike",
+			nil, map[string]string{ReasonPoweredOff: "Down", ReasonStopping: "Down"}, `condition reason "Down" is used for both`),
+	)
+
+	It("loads a YAML file strictly", func() {
+		dir := GinkgoT().TempDir()
+		path := filepath.Join(dir, "vocabulary.yaml")
+		Expect(os.WriteFile(path, []byte("types:\n  Stopped: PoweredOff\nreasons:\n  PoweredOff: Halted\n"), 0o600)).To(Succeed())
+		v, err := LoadConditionVocabulary(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(v.Types).To(HaveKeyWithValue(ConditionTypeStopped, "PoweredOff"))
+
+		Expect(os.WriteFile(path, []byte("type:\n  Stopped: PoweredOff\n"), 0o600)).To(Succeed())
+		_, err = LoadConditionVocabulary(path)
+		Expect(err).To(MatchError(ContainSubstring("invalid condition vocabulary")))
+	})
+
+	It("writes the renamed conditions and reads them back", func() {
+		ctx := context.Background()
+		v := &ConditionVocabulary{
+			Types:   map[string]string{ConditionTypeStopped: "PoweredOff"},
+			Reasons: map[string]string{ReasonPoweredOff: "Halted"},
+		}
+		Expect(v.Validate()).To(Succeed())
+		mr := testMachineRequest(nil)
+		r, _ := testReconciler(mr)
+		r.Conditions = v
+		meta.SetStatusCondition(&mr.Status.Conditions, metav1.Condition{
+			Type: ConditionTypeStopped, Status: metav1.ConditionTrue, Reason: ReasonPoweredOff,
+		})
+		mr.Status.FailureReason = ReasonPoweredOff
+
+		Expect(r.updateStatus(ctx, mr)).To(Succeed())
+		Expect(meta.IsStatusConditionTrue(mr.Status.Conditions, ConditionTypeStopped)).To(BeTrue())
+
+		stored := &butlerv1alpha1.MachineRequest{}
+		Expect(r.Get(ctx, client.ObjectKeyFromObject(mr), stored)).To(Succeed())
+		Expect(meta.FindStatusCondition(stored.Status.Conditions, ConditionTypeStopped)).To(BeNil())
+		cond := meta.FindStatusCondition(stored.Status.Conditions, "PoweredOff")
+		Expect(cond).NotTo(BeNil())
+		Expect(cond.Reason).To(Equal("Halted"))
+		Expect(stored.Status.FailureReason).To(Equal("Halted"))
+
+		v.toInternal(&stored.Status)
+		Expect(stored.Status.Conditions).To(Equal(mr.Status.Conditions))
+		Expect(stored.Status.FailureReason).To(Equal(ReasonPoweredOff))
+	})
+
+	It("leaves names unchanged without a vocabulary", func() {
+		var v *ConditionVocabulary
+		status := &butlerv1alpha1.MachineRequestStatus{FailureReason: ReasonPoweredOff}
+		v.toExternal(status)
+		Expect(status.FailureReason).To(Equal(ReasonPoweredOff))
+	})
+})
@@ -0,0 +1,176 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"slices"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	butlerv1alpha1 "github.com/butlerdotdev/butler-api/api/v1alpha1"
+)
+
+// CacheOptions returns the manager cache options of an instance reconciling
+// MachineRequests in namespaces, so its informers only list and watch those
+// namespaces. ProviderConfigs and Secrets may live elsewhere and are still
+// cached in every namespace, Secrets by their metadata only. Empty
+// namespaces caches everything.
+func CacheOptions(namespaces []string) cache.Options {
+	if len(namespaces) == 0 {
+		return cache.Options{}
+	}
+	defaults := make(map[string]cache.Config, len(namespaces))
+	for _, ns := range namespaces {
+		defaults[ns] = cache.Config{}
+	}
+	allNamespaces := cache.ByObject{Namespaces: map[string]cache.Config{cache.AllNamespaces: {}}}
+	return cache.Options{
+		DefaultNamespaces: defaults,
+		ByObject: map[client.Object]cache.ByObject{
+			&butlerv1alpha1.ProviderConfig{}: allNamespaces,
+			&corev1.Secret{}:                 allNamespaces,
+		},
+	}
+}
+
+// watchesNamespace reports whether obj is in a namespace this controller
+// reconciles MachineRequests in.
+func (r *MachineRequestReconciler) watchesNamespace(obj client.Object) bool {
+	return len(r.WatchNamespaces) == 0 || slices.Contains(r.WatchNamespaces, obj.GetNamespace())
+}
+
+// machineRequestsForProviderConfig maps a ProviderConfig change to the
+// MachineRequests referencing it, so config edits apply without waiting for
+// the next requeue. A deleted ProviderConfig also drops its cached client
+// unless MachineRequests still need it to delete their VMs.
+func (r *MachineRequestReconciler) machineRequestsForProviderConfig(ctx context.Context, obj client.Object) []reconcile.Request {
+	key := types.NamespacedName{Namespace: obj.GetNamespace(), Name: obj.GetName()}
+	if err := r.Get(ctx, key, &butlerv1alpha1.ProviderConfig{}); apierrors.IsNotFound(err) {
+		if err := r.forgetUnusedClient(ctx, key); err != nil {
+			logf.FromContext(ctx).Error(err, "Failed to drop the client of a deleted ProviderConfig", "providerConfig", key)
+		}
+	}
+	return r.machineRequestsFor(ctx, key)
+}
+
+// machineRequestsForSecret maps a Secret change to the MachineRequests of
+// every ProviderConfig using it as credentials, so rotated credentials are
+// picked up promptly, and to the MachineRequests reading user data or SSH
+// keys from it.
+func (r *MachineRequestReconciler) machineRequestsForSecret(ctx context.Context, obj client.Object) []reconcile.Request {
+	requests := r.machineRequestsForUserData(ctx, "Secret", obj)
+	requests = append(requests, r.machineRequestsForSSHKeySecret(ctx, obj)...)
+	list := &butlerv1alpha1.ProviderConfigList{}
+	if err := r.List(ctx, list); err != nil {
+		logf.FromContext(ctx).Error(err, "Failed to list ProviderConfigs for Secret", "secret", client.ObjectKeyFromObject(obj))
+		return requests
+	}
+	for i := range list.Items {
+		pc := &list.Items[i]
+		ns := pc.Spec.CredentialsRef.Namespace
+		if ns == "" {
+			ns = pc.Namespace
+		}
+		if pc.Spec.CredentialsRef.Name == obj.GetName() && ns == obj.GetNamespace() {
+			requests = append(requests, r.machineRequestsFor(ctx, client.ObjectKeyFromObject(pc))...)
+		}
+	}
+	return requests
+}
+
+// machineRequestsForSSHKeySecret returns a request for each watched
+// MachineRequest whose ssh-key-secret is the Secret, which is only seen when
+// the Harvester cluster is the management cluster. Their deep checks are
+// made due so the rotation is noticed on that reconcile.
+func (r *MachineRequestReconciler) machineRequestsForSSHKeySecret(ctx context.Context, obj client.Object) []reconcile.Request {
+	list := &butlerv1alpha1.MachineRequestList{}
+	if err := r.List(ctx, list, client.MatchingFields{SSHKeySecretIndex: obj.GetName()}); err != nil {
+		logf.FromContext(ctx).Error(err, "Failed to list MachineRequests for SSH key secret", "secret", client.ObjectKeyFromObject(obj))
+		return nil
+	}
+	var requests []reconcile.Request
+	for i := range list.Items {
+		mr := &list.Items[i]
+		if !r.watchesNamespace(mr) {
+			continue
+		}
+		// The Secret is read from the ProviderConfig's Harvester namespace
+		pc, err := r.getProviderConfig(ctx, mr)
+		if err != nil || pc.Spec.Harvester == nil {
+			continue
+		}
+		ns := pc.Spec.Harvester.Namespace
+		if ns == "" {
+			ns = "default"
+		}
+		if ns != obj.GetNamespace() {
+			continue
+		}
+		key := client.ObjectKeyFromObject(mr)
+		r.forgetDeepCheck(key)
+		requests = append(requests, reconcile.Request{NamespacedName: key})
+	}
+	return requests
+}
+
+// machineRequestsForConfigMap maps a ConfigMap change to the MachineRequests
+// reading user data from it.
+func (r *MachineRequestReconciler) machineRequestsForConfigMap(ctx context.Context, obj client.Object) []reconcile.Request {
+	return r.machineRequestsForUserData(ctx, "ConfigMap", obj)
+}
+
+// machineRequestsForUserData returns a request for each watched MachineRequest
+// in the object's namespace that reads its user data from the object.
+func (r *MachineRequestReconciler) machineRequestsForUserData(ctx context.Context, kind string, obj client.Object) []reconcile.Request {
+	list := &butlerv1alpha1.MachineRequestList{}
+	if err := r.List(ctx, list, client.InNamespace(obj.GetNamespace()),
+		client.MatchingFields{UserDataRefIndex: UserDataRefIndexValue(kind, obj.GetName())}); err != nil {
+		logf.FromContext(ctx).Error(err, "Failed to list MachineRequests for user data", "kind", kind, "name", client.ObjectKeyFromObject(obj))
+		return nil
+	}
+	requests := make([]reconcile.Request, 0, len(list.Items))
+	for i := range list.Items {
+		if mr := &list.Items[i]; r.watchesNamespace(mr) {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(mr)})
+		}
+	}
+	return requests
+}
+
+// machineRequestsFor returns a request for each watched MachineRequest that
+// references the ProviderConfig.
+func (r *MachineRequestReconciler) machineRequestsFor(ctx context.Context, providerConfig types.NamespacedName) []reconcile.Request {
+	list := &butlerv1alpha1.MachineRequestList{}
+	if err := r.List(ctx, list, client.MatchingFields{ProviderRefIndex: providerConfig.String()}); err != nil {
+		logf.FromContext(ctx).Error(err, "Failed to list MachineRequests for ProviderConfig", "providerConfig", providerConfig)
+		return nil
+	}
+	requests := make([]reconcile.Request, 0, len(list.Items))
+	for i := range list.Items {
+		if mr := &list.Items[i]; r.watchesNamespace(mr) {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(mr)})
+		}
+	}
+	return requests
+}
@@ -0,0 +1,157 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	butlerv1alpha1 "github.com/butlerdotdev/butler-api/api/v1alpha1"
+	"github.com/butlerdotdev/butler-provider-harvester/internal/harvester"
+)
+
+var _ = Describe("Secret and ConfigMap watches", func() {
+	var (
+		ctx context.Context
+		r   *MachineRequestReconciler
+		mr  *butlerv1alpha1.MachineRequest
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		mr = testMachineRequest(map[string]string{
+			"user-data-secret": "cloud-secret",
+			"ssh-key-secret":   "ssh-keys",
+		})
+		mr.Spec.ProviderRef.Name = "harvester"
+		pc := &butlerv1alpha1.ProviderConfig{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "butler-system", Name: "harvester"},
+			Spec: butlerv1alpha1.ProviderConfigSpec{
+				CredentialsRef: butlerv1alpha1.SecretReference{Name: "harvester-kubeconfig"},
+				Harvester:      &butlerv1alpha1.HarvesterProviderConfig{Namespace: "vms"},
+			},
+		}
+		r, _ = testReconciler(mr, pc)
+	})
+
+	DescribeTable("maps a Secret to the MachineRequests using it",
+		func(namespace, name string, mapped bool) {
+			secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name}}
+			requests := r.machineRequestsForSecret(ctx, secret)
+			if mapped {
+				Expect(requests).To(ConsistOf(reconcile.Request{NamespacedName: client.ObjectKeyFromObject(mr)}))
+			} else {
+				Expect(requests).To(BeEmpty())
+			}
+		},
+		Entry("the ProviderConfig credentials", "butler-system", "harvester-kubeconfig", true),
+		Entry("the user data Secret", "butler-system", "cloud-secret", true),
+		Entry("the SSH key Secret in the Harvester namespace", "vms", "ssh-keys", true),
+		Entry("an SSH key Secret of the same name elsewhere", "butler-system", "ssh-keys", false),
+		Entry("an unrelated Secret", "butler-system", "other", false),
+	)
+
+	It("makes the deep checks due when the SSH key Secret changes", func() {
+		r.recordDeepCheck(mr, time.Now())
+		due, _ := r.deepCheckDue(mr, time.Hour, time.Now())
+		Expect(due).To(BeFalse())
+
+		r.machineRequestsForSecret(ctx, &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "vms", Name: "ssh-keys"}})
+		due, _ = r.deepCheckDue(mr, time.Hour, time.Now())
+		Expect(due).To(BeTrue())
+	})
+
+	DescribeTable("drops the client of a deleted ProviderConfig",
+		func(finalizer, deleted, kept bool) {
+			if finalizer {
+				mr.Finalizers = []string{finalizerName}
+			}
+			objects := []client.Object{mr}
+			pc := &butlerv1alpha1.ProviderConfig{ObjectMeta: metav1.ObjectMeta{Namespace: "butler-system", Name: "harvester"}}
+			if !deleted {
+				objects = append(objects, pc)
+			}
+			r, _ = testReconciler(objects...)
+			key := client.ObjectKeyFromObject(pc)
+			_, err := r.Clients.Get(key, "1", func() (*harvester.Client, error) { return testHarvesterClient(), nil })
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(r.machineRequestsForProviderConfig(ctx, pc)).To(HaveLen(1))
+			if kept {
+				Expect(r.Clients.Last(key)).NotTo(BeNil())
+			} else {
+				Expect(r.Clients.Last(key)).To(BeNil())
+			}
+		},
+		Entry("when no MachineRequest needs it", false, true, false),
+		Entry("not while a MachineRequest still deletes its VM", true, true, true),
+		Entry("not while the ProviderConfig exists", false, false, true),
+	)
+
+	It("maps a ConfigMap to the MachineRequests reading user data from it", func() {
+		mr.Annotations[AnnotationUserDataConfigMap] = "cloud-config"
+		delete(mr.Annotations, AnnotationUserDataSecret)
+		r, _ = testReconciler(mr)
+		Expect(r.machineRequestsForConfigMap(ctx, &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "butler-system", Name: "cloud-config"},
+		})).To(HaveLen(1))
+		Expect(r.machineRequestsForConfigMap(ctx, &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "other", Name: "cloud-config"},
+		})).To(BeEmpty())
+	})
+})
+
+var _ = Describe("Watch namespaces", func() {
+	DescribeTable("validates the namespace list",
+		func(namespaces []string, message string) {
+			err := ValidateWatchNamespaces(namespaces)
+			if message == "" {
+				Expect(err).NotTo(HaveOccurred())
+			} else {
+				Expect(err).To(MatchError(ContainSubstring(message)))
+			}
+		},
+		Entry("empty", nil, ""),
+		Entry("distinct namespaces", []string{"team-a", "team-b"}, ""),
+		Entry("an invalid name", []string{"Team_A"}, `invalid watch namespace "Team_A"`),
+		Entry("an empty name", []string{"team-a", ""}, `invalid watch namespace ""`),
+		Entry("a duplicate", []string{"team-a", "team-b", "team-a"}, `"team-a" listed more than once`),
+	)
+
+	It("caches only the watched namespaces", func() {
+		opts := CacheOptions([]string{"team-a", "team-b"})
+		Expect(opts.DefaultNamespaces).To(HaveLen(2))
+		Expect(opts.DefaultNamespaces).To(HaveKey("team-a"))
+		Expect(opts.DefaultNamespaces).To(HaveKey("team-b"))
+		for obj, byObject := range opts.ByObject {
+			Expect(byObject.Namespaces).To(HaveKey(cache.AllNamespaces), "%T", obj)
+		}
+		Expect(opts.ByObject).To(HaveLen(2))
+	})
+
+	It("caches every namespace without a list", func() {
+		Expect(CacheOptions(nil).DefaultNamespaces).To(BeEmpty())
+	})
+})
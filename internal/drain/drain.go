@@ -0,0 +1,135 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package drain lets operators stop the controller from starting new VM
+// create and delete operations, e.g. ahead of a controller upgrade. In-flight
+// VMs continue to be monitored while drained.
+//
+// Drain mode is toggled by SIGUSR1 (drain) and SIGUSR2 (undrain), or by a
+// ConfigMap whose "drain" key is "true".
+package drain
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/butlerdotdev/butler-provider-harvester/internal/metrics"
+)
+
+const (
+	// ConfigMapKey is the ConfigMap data key that enables drain mode.
+	ConfigMapKey = "drain"
+
+	// pollInterval is how often the drain ConfigMap is re-read.
+	pollInterval = 10 * time.Second
+)
+
+// Switch tracks whether the controller is drained. The zero value is an
+// active (undrained) switch driven only by signals.
+type Switch struct {
+	// Reader reads the drain ConfigMap. Use an uncached reader so no
+	// ConfigMap informer is required.
+	Reader client.Reader
+	// ConfigMap is the optional drain ConfigMap. Empty disables ConfigMap polling.
+	ConfigMap types.NamespacedName
+
+	signalDrained atomic.Bool
+	configDrained atomic.Bool
+}
+
+// Drained reports whether new create/delete operations should be held back.
+// A nil Switch is never drained.
+func (s *Switch) Drained() bool {
+	if s == nil {
+		return false
+	}
+	return s.signalDrained.Load() || s.configDrained.Load()
+}
+
+// Start handles drain signals and polls the drain ConfigMap until ctx is
+// cancelled. It implements manager.Runnable.
+func (s *Switch) Start(ctx context.Context) error {
+	log := logf.FromContext(ctx).WithName("drain")
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGUSR1, syscall.SIGUSR2)
+	defer signal.Stop(signals)
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	s.pollConfigMap(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case sig := <-signals:
+			s.signalDrained.Store(sig == syscall.SIGUSR1)
+			log.Info("Drain state changed by signal", "signal", sig.String(), "drained", s.Drained())
+		case <-ticker.C:
+			s.pollConfigMap(ctx)
+		}
+		s.updateMetric()
+	}
+}
+
+// NeedLeaderElection returns false so every replica honors drain mode.
+func (s *Switch) NeedLeaderElection() bool {
+	return false
+}
+
+// pollConfigMap refreshes the drain state from the ConfigMap. A missing
+// ConfigMap means not drained; read errors keep the previous state.
+func (s *Switch) pollConfigMap(ctx context.Context) {
+	if s.Reader == nil || s.ConfigMap.Name == "" {
+		return
+	}
+
+	cm := &corev1.ConfigMap{}
+	if err := s.Reader.Get(ctx, s.ConfigMap, cm); err != nil {
+		if apierrors.IsNotFound(err) {
+			s.configDrained.Store(false)
+		} else {
+			logf.FromContext(ctx).WithName("drain").Error(err, "Failed to read drain ConfigMap", "configMap", s.ConfigMap)
+		}
+		return
+	}
+
+	drained := cm.Data[ConfigMapKey] == "true"
+	if drained != s.configDrained.Swap(drained) {
+		logf.FromContext(ctx).WithName("drain").Info("Drain state changed by ConfigMap", "configMap", s.ConfigMap, "drained", drained)
+	}
+	s.updateMetric()
+}
+
+// updateMetric publishes the drain state.
+func (s *Switch) updateMetric() {
+	if s.Drained() {
+		metrics.Drained.Set(1)
+	} else {
+		metrics.Drained.Set(0)
+	}
+}
@@ -0,0 +1,258 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package harvester
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var longhornNodeGVR = schema.GroupVersionResource{
+	Group:    "longhorn.io",
+	Version:  "v1beta2",
+	Resource: "nodes",
+}
+
+const (
+	longhornNamespace = "longhorn-system"
+
+	// capacityTTL is how long a capacity snapshot is reused. Clients are
+	// created per reconcile, so snapshots are cached per cluster endpoint.
+	capacityTTL = time.Minute
+)
+
+// ResourceCapacity is the allocatable and allocated amount of one resource.
+type ResourceCapacity struct {
+	Allocatable resource.Quantity
+	Allocated   resource.Quantity
+}
+
+// Free returns the unallocated amount, never negative.
+func (r ResourceCapacity) Free() resource.Quantity {
+	free := r.Allocatable.DeepCopy()
+	free.Sub(r.Allocated)
+	if free.Sign() < 0 {
+		return resource.Quantity{}
+	}
+	return free
+}
+
+// NodeCapacity is the CPU and memory capacity of one schedulable node.
+type NodeCapacity struct {
+	Name   string
+	CPU    ResourceCapacity
+	Memory ResourceCapacity
+}
+
+// ClusterCapacity summarizes schedulable capacity across Harvester nodes.
+type ClusterCapacity struct {
+	Nodes  []NodeCapacity
+	CPU    ResourceCapacity
+	Memory ResourceCapacity
+	// Storage is the Longhorn disk capacity. It is zero when Longhorn
+	// nodes cannot be read.
+	Storage ResourceCapacity
+}
+
+// Fits reports whether a VM with the given CPU cores, memory and root disk
+// fits on a single node and in the remaining storage. A zero Storage
+// allocatable skips the storage check.
+func (c *ClusterCapacity) Fits(cpu int32, memory, disk resource.Quantity) bool {
+	cpuQty := *resource.NewQuantity(int64(cpu), resource.DecimalSI)
+	if !c.Storage.Allocatable.IsZero() {
+		free := c.Storage.Free()
+		if free.Cmp(disk) < 0 {
+			return false
+		}
+	}
+	for _, n := range c.Nodes {
+		freeCPU, freeMemory := n.CPU.Free(), n.Memory.Free()
+		if freeCPU.Cmp(cpuQty) >= 0 && freeMemory.Cmp(memory) >= 0 {
+			return true
+		}
+	}
+	return false
+}
+
+type capacityEntry struct {
+	capacity *ClusterCapacity
+	expires  time.Time
+}
+
+var (
+	capacityCacheMu sync.Mutex
+	capacityCache   = map[string]capacityEntry{}
+)
+
+// GetClusterCapacity returns allocatable and allocated CPU and memory of
+// schedulable nodes, from node status and pod requests, plus Longhorn storage
+// capacity. Results are cached for capacityTTL.
+func (c *Client) GetClusterCapacity(ctx context.Context) (*ClusterCapacity, error) {
+	capacityCacheMu.Lock()
+	entry, ok := capacityCache[c.host]
+	capacityCacheMu.Unlock()
+	if ok && time.Now().Before(entry.expires) {
+		return entry.capacity, nil
+	}
+
+	capacity, err := c.readClusterCapacity(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	capacityCacheMu.Lock()
+	capacityCache[c.host] = capacityEntry{capacity: capacity, expires: time.Now().Add(capacityTTL)}
+	capacityCacheMu.Unlock()
+	return capacity, nil
+}
+
+func (c *Client) readClusterCapacity(ctx context.Context) (*ClusterCapacity, error) {
+	nodes, err := retryResult(ctx, c, func(ctx context.Context) (*corev1.NodeList, error) {
+		return c.clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+
+	pods, err := retryResult(ctx, c, func(ctx context.Context) (*corev1.PodList, error) {
+		return c.clientset.CoreV1().Pods("").List(ctx, metav1.ListOptions{
+			FieldSelector: fields.AndSelectors(
+				fields.OneTermNotEqualSelector("status.phase", string(corev1.PodSucceeded)),
+				fields.OneTermNotEqualSelector("status.phase", string(corev1.PodFailed)),
+			).String(),
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods: %w", err)
+	}
+
+	requested := map[string]corev1.ResourceList{}
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if pod.Spec.NodeName == "" {
+			continue
+		}
+		total, ok := requested[pod.Spec.NodeName]
+		if !ok {
+			total = corev1.ResourceList{}
+			requested[pod.Spec.NodeName] = total
+		}
+		for _, container := range pod.Spec.Containers {
+			addResources(total, container.Resources.Requests)
+		}
+		addResources(total, pod.Spec.Overhead)
+	}
+
+	capacity := &ClusterCapacity{}
+	for i := range nodes.Items {
+		node := &nodes.Items[i]
+		if node.Spec.Unschedulable || !nodeReady(node) {
+			continue
+		}
+		used := requested[node.Name]
+		n := NodeCapacity{
+			Name: node.Name,
+			CPU: ResourceCapacity{
+				Allocatable: node.Status.Allocatable.Cpu().DeepCopy(),
+				Allocated:   used.Cpu().DeepCopy(),
+			},
+			Memory: ResourceCapacity{
+				Allocatable: node.Status.Allocatable.Memory().DeepCopy(),
+				Allocated:   used.Memory().DeepCopy(),
+			},
+		}
+		capacity.CPU.Allocatable.Add(n.CPU.Allocatable)
+		capacity.CPU.Allocated.Add(n.CPU.Allocated)
+		capacity.Memory.Allocatable.Add(n.Memory.Allocatable)
+		capacity.Memory.Allocated.Add(n.Memory.Allocated)
+		capacity.Nodes = append(capacity.Nodes, n)
+	}
+
+	// Storage is best-effort; clusters without Longhorn skip the check
+	if storage, err := c.longhornStorage(ctx); err == nil {
+		capacity.Storage = storage
+	}
+
+	return capacity, nil
+}
+
+// longhornStorage sums schedulable Longhorn disk capacity and the space
+// already scheduled to volumes.
+func (c *Client) longhornStorage(ctx context.Context) (ResourceCapacity, error) {
+	var storage ResourceCapacity
+	nodes, err := retryResult(ctx, c, func(ctx context.Context) (*unstructured.UnstructuredList, error) {
+		return c.dynamic.Resource(longhornNodeGVR).Namespace(longhornNamespace).List(ctx, metav1.ListOptions{})
+	})
+	if err != nil {
+		return storage, err
+	}
+
+	var allocatable, allocated int64
+	for _, node := range nodes.Items {
+		disks, _, _ := unstructured.NestedMap(node.Object, "spec", "disks")
+		diskStatus, _, _ := unstructured.NestedMap(node.Object, "status", "diskStatus")
+		for name, d := range disks {
+			spec, ok := d.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if allow, _, _ := unstructured.NestedBool(spec, "allowScheduling"); !allow {
+				continue
+			}
+			status, ok := diskStatus[name].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			maximum, _, _ := unstructured.NestedInt64(status, "storageMaximum")
+			scheduled, _, _ := unstructured.NestedInt64(status, "storageScheduled")
+			reserved, _, _ := unstructured.NestedInt64(spec, "storageReserved")
+			allocatable += maximum - reserved
+			allocated += scheduled
+		}
+	}
+	storage.Allocatable = *resource.NewQuantity(allocatable, resource.BinarySI)
+	storage.Allocated = *resource.NewQuantity(allocated, resource.BinarySI)
+	return storage, nil
+}
+
+// addResources adds each quantity in add to total.
+func addResources(total, add corev1.ResourceList) {
+	for name, q := range add {
+		sum := total[name]
+		sum.Add(q)
+		total[name] = sum
+	}
+}
+
+// nodeReady reports whether the node has a True Ready condition.
+func nodeReady(node *corev1.Node) bool {
+	for _, cond := range node.Status.Conditions {
+		if cond.Type == corev1.NodeReady {
+			return cond.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
@@ -0,0 +1,173 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package harvester
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+// testNode returns a node with the given allocatable CPU and memory.
+func testNode(name, cpu, memory string, ready bool) *corev1.Node {
+	status := corev1.ConditionFalse
+	if ready {
+		status = corev1.ConditionTrue
+	}
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status: corev1.NodeStatus{
+			Allocatable: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse(cpu),
+				corev1.ResourceMemory: resource.MustParse(memory),
+			},
+			Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: status}},
+		},
+	}
+}
+
+// testPod returns a pod on node requesting the given CPU and memory.
+func testPod(name, node, cpu, memory string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: name},
+		Spec: corev1.PodSpec{
+			NodeName: node,
+			Containers: []corev1.Container{{
+				Name: "compute",
+				Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse(cpu),
+					corev1.ResourceMemory: resource.MustParse(memory),
+				}},
+			}},
+		},
+	}
+}
+
+// resetCapacityCache drops the capacity snapshots cached by earlier specs.
+func resetCapacityCache() {
+	capacityCacheMu.Lock()
+	defer capacityCacheMu.Unlock()
+	capacityCache = map[string]capacityEntry{}
+}
+
+var _ = Describe("Cluster capacity", func() {
+	var ctx context.Context
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		resetCapacityCache()
+	})
+
+	DescribeTable("never reports negative free capacity",
+		func(allocatable, allocated, want string) {
+			r := ResourceCapacity{Allocatable: resource.MustParse(allocatable), Allocated: resource.MustParse(allocated)}
+			free := r.Free()
+			Expect(free.Cmp(resource.MustParse(want))).To(BeZero(), "free %s", free.String())
+		},
+		Entry("room left", "16", "6", "10"),
+		Entry("fully allocated", "16", "16", "0"),
+		Entry("overcommitted", "16", "20", "0"),
+	)
+
+	DescribeTable("fits a VM on a single node and in the remaining storage",
+		func(cpu int32, memory, disk, storage string, fits bool) {
+			capacity := &ClusterCapacity{
+				Nodes: []NodeCapacity{
+					{
+						CPU:    ResourceCapacity{Allocatable: resource.MustParse("8"), Allocated: resource.MustParse("6")},
+						Memory: ResourceCapacity{Allocatable: resource.MustParse("32Gi"), Allocated: resource.MustParse("8Gi")},
+					},
+					{
+						CPU:    ResourceCapacity{Allocatable: resource.MustParse("8"), Allocated: resource.MustParse("2")},
+						Memory: ResourceCapacity{Allocatable: resource.MustParse("32Gi"), Allocated: resource.MustParse("28Gi")},
+					},
+				},
+				Storage: ResourceCapacity{Allocatable: resource.MustParse(storage), Allocated: resource.MustParse("50Gi")},
+			}
+			Expect(capacity.Fits(cpu, resource.MustParse(memory), resource.MustParse(disk))).To(Equal(fits))
+		},
+		Entry("small VM", int32(2), "4Gi", "20Gi", "100Gi", true),
+		Entry("CPU on one node, memory on the other", int32(4), "8Gi", "20Gi", "100Gi", false),
+		Entry("too many CPUs for any node", int32(10), "1Gi", "20Gi", "100Gi", false),
+		Entry("root disk larger than the free storage", int32(2), "4Gi", "60Gi", "100Gi", false),
+		Entry("storage unknown", int32(2), "4Gi", "60Gi", "0", true),
+	)
+
+	It("sums schedulable nodes, pod requests and Longhorn disks", func() {
+		cordoned := testNode("harvester-2", "32", "128Gi", true)
+		cordoned.Spec.Unschedulable = true
+		overhead := testPod("virt-launcher-b", "harvester-0", "1", "2Gi")
+		overhead.Spec.Overhead = corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("256Mi")}
+		c := newTestClient(
+			testNode("harvester-0", "16", "64Gi", true),
+			testNode("harvester-1", "16", "64Gi", false),
+			cordoned,
+			testPod("virt-launcher-a", "harvester-0", "2", "4Gi"),
+			overhead,
+			testPod("pending", "", "8", "8Gi"),
+		)
+		longhornNode := &unstructured.Unstructured{}
+		longhornNode.SetAPIVersion("longhorn.io/v1beta2")
+		longhornNode.SetKind("Node")
+		longhornNode.SetNamespace(longhornNamespace)
+		longhornNode.SetName("harvester-0")
+		longhornNode.Object["spec"] = map[string]interface{}{"disks": map[string]interface{}{
+			"default":  map[string]interface{}{"allowScheduling": true, "storageReserved": int64(10 << 30)},
+			"disabled": map[string]interface{}{"allowScheduling": false},
+		}}
+		longhornNode.Object["status"] = map[string]interface{}{"diskStatus": map[string]interface{}{
+			"default":  map[string]interface{}{"storageMaximum": int64(110 << 30), "storageScheduled": int64(30 << 30)},
+			"disabled": map[string]interface{}{"storageMaximum": int64(500 << 30)},
+		}}
+		Expect(c.dynamic.(*dynamicfake.FakeDynamicClient).Tracker().Create(longhornNodeGVR, longhornNode, longhornNamespace)).To(Succeed())
+
+		capacity, err := c.GetClusterCapacity(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(capacity.Nodes).To(HaveLen(1))
+		Expect(capacity.Nodes[0].Name).To(Equal("harvester-0"))
+		Expect(capacity.CPU.Allocatable.String()).To(Equal("16"))
+		Expect(capacity.CPU.Allocated.String()).To(Equal("3"))
+		Expect(capacity.Memory.Allocated.Cmp(resource.MustParse("6400Mi"))).To(BeZero())
+		Expect(capacity.Storage.Allocatable.String()).To(Equal("100Gi"))
+		Expect(capacity.Storage.Allocated.String()).To(Equal("30Gi"))
+	})
+
+	It("skips the storage check without Longhorn nodes", func() {
+		capacity, err := newTestClient(testNode("harvester-0", "16", "64Gi", true)).GetClusterCapacity(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(capacity.Storage.Allocatable.IsZero()).To(BeTrue())
+		Expect(capacity.Fits(2, resource.MustParse("4Gi"), resource.MustParse("1Ti"))).To(BeTrue())
+	})
+
+	It("reuses the snapshot of the same cluster", func() {
+		c := newTestClient(testNode("harvester-0", "16", "64Gi", true))
+		first, err := c.GetClusterCapacity(ctx)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(c.clientset.CoreV1().Nodes().Delete(ctx, "harvester-0", metav1.DeleteOptions{})).To(Succeed())
+		second, err := c.GetClusterCapacity(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(second).To(BeIdenticalTo(first))
+	})
+})
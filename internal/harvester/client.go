@@ -18,11 +18,21 @@ package harvester
 
 import (
 	"context"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
+	"slices"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
@@ -52,9 +62,39 @@ var (
 // Client provides access to Harvester resources.
 type Client struct {
 	dynamic   dynamic.Interface
-	clientset *kubernetes.Clientset
+	clientset kubernetes.Interface
 	namespace string
 	config    *butlerv1alpha1.HarvesterProviderConfig
+	host      string
+
+	// MaxRetries is how often a transient API error is retried and
+	// RetryBaseDelay the wait before the first retry; see isRetryable.
+	// Zero MaxRetries disables retries.
+	MaxRetries     int
+	RetryBaseDelay time.Duration
+	// CallTimeout bounds each API call attempt. Zero means calls are only
+	// bounded by the caller's context.
+	CallTimeout time.Duration
+	// AllowIPv6 also accepts global unicast IPv6 addresses as VM addresses.
+	// By default only IPv4 addresses are reported.
+	AllowIPv6 bool
+	// MaxCloudInitSize limits the base64-encoded size of user data and
+	// network data placed inline in the NoCloud volume. Zero means
+	// DefaultMaxCloudInitSize.
+	MaxCloudInitSize int
+	// RootDiskStorageClass is the storage class of root disk PVCs whose
+	// options name none. Empty means a cloned root disk uses the image's
+	// storage class and an imported one the cluster default. The provider
+	// config StorageClassName only applies to the persistent cloud-init disk.
+	RootDiskStorageClass string
+	// CloudInitPopulatorImage is the image of the Job that writes the seed
+	// image into a persistent cloud-init PVC. Empty means
+	// DefaultCloudInitPopulatorImage.
+	CloudInitPopulatorImage string
+
+	clusterInfoMu sync.Mutex
+	clusterInfo   *ClusterInfo
+	clusterInfoAt time.Time
 }
 
 // NewClient creates a new Harvester client from kubeconfig data.
@@ -74,6 +114,14 @@ func NewClient(kubeconfigData []byte, config *butlerv1alpha1.HarvesterProviderCo
 		return nil, fmt.Errorf("failed to create clientset: %w", err)
 	}
 
+	c := NewClientFromInterfaces(dynamicClient, clientset, config)
+	c.host = restConfig.Host
+	return c, nil
+}
+
+// NewClientFromInterfaces creates a Harvester client on existing API
+// clients, such as fakes in tests.
+func NewClientFromInterfaces(dynamicClient dynamic.Interface, clientset kubernetes.Interface, config *butlerv1alpha1.HarvesterProviderConfig) *Client {
 	namespace := config.Namespace
 	if namespace == "" {
 		namespace = "default"
@@ -84,7 +132,11 @@ func NewClient(kubeconfigData []byte, config *butlerv1alpha1.HarvesterProviderCo
 		clientset: clientset,
 		namespace: namespace,
 		config:    config,
-	}, nil
+
+		MaxRetries:     DefaultMaxRetries,
+		RetryBaseDelay: DefaultRetryBaseDelay,
+		CallTimeout:    DefaultCallTimeout,
+	}
 }
 
 // VMCreateOptions defines options for creating a VM.
@@ -98,228 +150,1251 @@ type VMCreateOptions struct {
 	UserData    string
 	NetworkData string
 	Labels      map[string]string
+
+	// ImageSelector is a label selector resolved against Harvester images
+	// when ImageName is empty (see ResolveImageSelector). ImageNamespace
+	// limits the search and defaults to the Harvester namespace.
+	ImageSelector  string
+	ImageNamespace string
+	// ImageSelectorOrder picks the newest of several matches, either by
+	// ImageOrderCreationTimestamp or by the version in the named label.
+	ImageSelectorOrder string
+
+	// Sockets and Threads shape the guest CPU topology; the remaining CPUs
+	// become cores per socket. Both default to 1 and must divide CPU evenly.
+	// With NUMACells, Sockets defaults to the number of cells.
+	Sockets int32
+	Threads int32
+
+	// DedicatedCPUPlacement pins each vCPU to a dedicated host CPU.
+	DedicatedCPUPlacement bool
+	// IsolateEmulatorThread pins the QEMU emulator thread to an additional
+	// dedicated host CPU. Requires DedicatedCPUPlacement.
+	IsolateEmulatorThread bool
+
+	// GPUs are passed through to the guest. Each must be listed in the
+	// KubeVirt permittedHostDevices and advertised by a node.
+	GPUs []GPUDevice
+
+	// HugepagesPageSize backs guest memory with hugepages of this size
+	// ("2Mi" or "1Gi"). Nodes must have the hugepages preallocated.
+	HugepagesPageSize string
+
+	// NUMACells describes the guest NUMA topology, one socket per cell. The
+	// cells must be uniform and add up to CPU and MemoryMB. Requires
+	// DedicatedCPUPlacement and hugepages. Defaults to a single flat cell.
+	NUMACells []NUMACell
+
+	// EnableOvercommit requests only MemoryRequestPercent of the guest memory
+	// and excludes the virt-launcher overhead from the pod requests, so more
+	// VMs fit on a node. Guests can be OOM-killed when the node runs out of
+	// memory. Cannot be combined with hugepages or dedicated CPU placement.
+	EnableOvercommit bool
+	// MemoryRequestPercent is the share of guest memory requested when
+	// EnableOvercommit is set. Defaults to 50.
+	MemoryRequestPercent int
+	// MemoryOverheadMB is added on top of guest memory for the memory limit,
+	// leaving room for the virt-launcher overhead before the VM is
+	// OOM-killed. Defaults to defaultMemoryOverheadPercent of MemoryMB.
+	MemoryOverheadMB *int32
+	// MemoryRequestMB and MemoryLimitMB set the pod memory request and limit
+	// directly instead of deriving them from MemoryMB. The request must not
+	// exceed the guest memory, nor the guest memory the limit. A request
+	// below the guest memory overcommits the node. Zero keeps the derived
+	// values.
+	MemoryRequestMB int32
+	MemoryLimitMB   int32
+	// CPURequestMillicores sets the pod CPU request, at most CPU cores. The
+	// limit stays at CPU cores, so a request below it overcommits the node's
+	// CPUs. Zero keeps the default of defaultCPURequest.
+	CPURequestMillicores int32
+
+	// StartPaused starts the guest paused so a console can be attached
+	// before it boots. Resume it with UnpauseVM.
+	StartPaused bool
+
+	// PersistentCloudInit backs the cloud-init disk with a small PVC instead of
+	// an ephemeral NoCloud volume so guest writes survive reboots. The VM is
+	// created halted and started once the disk has been populated.
+	PersistentCloudInit bool
+	// CACerts are PEM certificates added to the guest trust store through
+	// generated cloud-config. Ignored when UserData is set.
+	CACerts []string
+	// SSHPublicKeys are authorized for the default guest user through
+	// ssh_authorized_keys. Without UserData a minimal cloud-config is
+	// generated; otherwise the keys are merged into the #cloud-config UserData.
+	SSHPublicKeys []string
+	// CloudInitDiskSize is the size of the persistent cloud-init seed disk,
+	// for payloads that outgrow the 64Mi default. Requires PersistentCloudInit.
+	CloudInitDiskSize resource.Quantity
+
+	// OwnerUID and Owner identify the MachineRequest that owns the VM. They are
+	// stamped on the VM so other requests resolving to the same name can
+	// detect the conflict.
+	OwnerUID string
+	Owner    string
+
+	// VolumeMode is the root disk PVC volume mode, Block (default) or Filesystem.
+	VolumeMode corev1.PersistentVolumeMode
+	// StorageClass is the storage class of the root disk PVC, overriding the
+	// client's RootDiskStorageClass. When both are empty, a cloned root disk
+	// uses the image's storage class and an imported one the cluster
+	// default. It must be able to provision from the image.
+	StorageClass string
+
+	// DNSServers and DNSSearch configure guest DNS through synthesized
+	// network-data. Ignored when NetworkData is set.
+	DNSServers []string
+	DNSSearch  []string
+	// ClusterDNSDomain is the cluster service domain (e.g. "cluster.local")
+	// appended to the search domains so the guest can resolve services.
+	// It requires the primary interface on the pod network.
+	ClusterDNSDomain string
+	// Routes are static routes added to the guest through synthesized
+	// network-data. Ignored when NetworkData is set.
+	Routes []RouteSpec
+	// StaticIP configures a static IPv4 address on the guest interface
+	// through synthesized network-data instead of DHCP. Cannot be combined
+	// with NetworkData or Networks.
+	StaticIP *StaticIP
+	// NetworkDataFormat selects the format of synthesized network-data.
+	// Defaults to NetworkDataV2.
+	NetworkDataFormat NetworkDataFormat
+	// UserDataValidation selects how strictly UserData is checked before
+	// anything is created. Defaults to UserDataValidationCloudConfig.
+	UserDataValidation UserDataValidation
+
+	// Networks attaches the VM to several multus networks, one interface
+	// each, instead of the single interface on NetworkName. The first entry
+	// is the primary interface.
+	Networks []NetworkInterface
+
+	// NetworkBinding selects the primary interface binding. Defaults to
+	// NetworkBindingBridge.
+	NetworkBinding NetworkBinding
+	// InterfaceACPIIndex and InterfacePCIAddress pin the primary interface to
+	// an ACPI index or a PCI address ("dddd:bb:ss.f") so its name stays
+	// stable across reboots. Unset by default. Other interfaces are pinned
+	// through their NetworkInterface.
+	InterfaceACPIIndex  int32
+	InterfacePCIAddress string
+
+	// DiskSize overrides DiskGB with an arbitrary quantity (e.g. "20500Mi").
+	DiskSize resource.Quantity
+	// DiskSizeGranularity rounds the root disk size up to a multiple of this
+	// quantity. When zero, the StorageClass granularity annotation is used.
+	DiskSizeGranularity resource.Quantity
+
+	// RestoreFromBackup restores the VM from a Harvester VirtualMachineBackup
+	// ("name" or "namespace/name") instead of cloning an image. The VM spec
+	// comes from the backup, so sizing and cloud-init options are ignored.
+	RestoreFromBackup string
+
+	// ContainerDiskImage boots the VM from an ephemeral container disk
+	// instead of a root disk PVC cloned from a Harvester image.
+	ContainerDiskImage string
+	// ImagePullSecret names a docker-config Secret in the VM namespace used to
+	// pull ContainerDiskImage from a private registry.
+	ImagePullSecret string
+
+	// SSHKeySecret names a Secret in the VM namespace whose values are SSH
+	// public keys injected by the guest agent for SSHKeyUsers. Keys can be
+	// rotated by updating the Secret without recreating the VM.
+	SSHKeySecret string
+	SSHKeyUsers  []string
+
+	// RuntimeClassName is the runtime class the virt-launcher pod must run
+	// with (e.g. "kata"). KubeVirt applies one runtime class cluster-wide, so
+	// creation fails unless it matches the KubeVirt default runtime class.
+	RuntimeClassName string
+
+	// FirmwareUUID and FirmwareSerial set the SMBIOS system UUID and serial
+	// number the guest sees. They are random per VM when empty.
+	FirmwareUUID   string
+	FirmwareSerial string
+	// FirmwareEFI boots the guest with UEFI firmware instead of BIOS.
+	// FirmwareSecureBoot also enables Secure Boot and requires FirmwareEFI.
+	FirmwareEFI        bool
+	FirmwareSecureBoot bool
+	// SMBIOSManufacturer and SMBIOSProduct are the SMBIOS system manufacturer
+	// and product the guest must see. KubeVirt sets them cluster-wide, so
+	// creation fails unless they match the KubeVirt SMBIOS configuration.
+	SMBIOSManufacturer string
+	SMBIOSProduct      string
+
+	// RootDiskPVCName overrides the root disk PVC name. A "{name}" placeholder
+	// is replaced with the VM name. Defaults to "<name>-rootdisk".
+	RootDiskPVCName string
+	// RootDiskSerial is the serial number the guest sees on the root disk.
+	RootDiskSerial string
+	// DiskBus is the bus the root, cloud-init and attached disks use, and
+	// the default for data disks. Defaults to DiskBusVirtio; guests without
+	// virtio drivers need DiskBusSATA.
+	DiskBus DiskBus
+	// RootDiskPreallocation asks CDI to preallocate the root disk volume
+	// instead of thin-provisioning it. Requires RootDiskImportURL, since
+	// image clones are populated by Longhorn rather than CDI.
+	RootDiskPreallocation bool
+	// RootDiskShareable lets other VMs attach the root disk while this VM
+	// runs, for clustered filesystems. The guests must coordinate access.
+	RootDiskShareable bool
+	// RootDiskImportURL imports the root disk from an http(s) URL or a
+	// docker:// registry image through a CDI DataVolume declared in the VM's
+	// dataVolumeTemplates, instead of cloning a Harvester image into a PVC
+	// created beforehand. The disk is created atomically with the VM and
+	// garbage collected with it. Requires CDI.
+	RootDiskImportURL string
+
+	// AttachedDisks are existing PVCs attached after the root disk. They are
+	// not owned by the VM and are never deleted with it.
+	AttachedDisks []AttachedDisk
+	// DataDisks are blank disks provisioned with the VM as PVCs named
+	// "<name>-datadisk-<index>" and deleted with it.
+	DataDisks []DataDiskSpec
+	// EphemeralScratchGB adds a blank scratch disk of this many GiB on
+	// node-local storage, after the data disks. It is wiped whenever the VM
+	// stops and needs no PVC. Zero means no scratch disk.
+	EphemeralScratchGB int32
+
+	// sriovResource is the device plugin resource resolved from the SR-IOV
+	// network attachment by CreateVM.
+	sriovResource string
 }
 
 // CreateVM creates a new VirtualMachine in Harvester.
-// This creates a PVC first (Harvester style), then the VM.
-func (c *Client) CreateVM(ctx context.Context, opts VMCreateOptions) (string, error) {
+// This creates a PVC first (Harvester style), then the VM. Besides the VM
+// UID it returns opts with the defaults it applied filled in, for recording
+// what the VM was created with.
+func (c *Client) CreateVM(ctx context.Context, opts VMCreateOptions) (string, VMCreateOptions, error) {
+	if err := validateCreateOptions(opts); err != nil {
+		return "", opts, err
+	}
+
+	// The VM UID is only known once the restore completes, and its spec
+	// comes from the backup rather than from defaults
+	if opts.RestoreFromBackup != "" {
+		return "", opts, c.createRestore(ctx, opts)
+	}
+
+	plan, err := c.planVM(ctx, opts)
+	if err != nil {
+		return "", opts, err
+	}
+	providerID, err := c.createPlannedVM(ctx, plan)
+	if err != nil {
+		return "", opts, err
+	}
+	return providerID, plan.effectiveOptions(), nil
+}
+
+// createPlannedVM creates the disks, the persistent cloud-init seed and the
+// VM for a plan, and returns the VM UID.
+func (c *Client) createPlannedVM(ctx context.Context, plan *vmPlan) (string, error) {
+	opts := plan.opts
+
+	// A previous instance must be fully deleted before its name is reused,
+	// including a VMI that outlived its VM and would be adopted by the new one
+	existing, err := c.GetVM(ctx, opts.Name)
+	if err == nil && existing.GetDeletionTimestamp() != nil {
+		return "", fmt.Errorf("%w: VM %s", ErrPreviousInstanceTerminating, opts.Name)
+	}
+	if apierrors.IsNotFound(err) {
+		deleted, err := c.deleteOrphanedVMI(ctx, opts.Name, opts.OwnerUID)
+		if err != nil {
+			return "", err
+		}
+		if deleted {
+			return "", fmt.Errorf("%w: orphaned VMI %s", ErrPreviousInstanceTerminating, opts.Name)
+		}
+	}
+
+	if err := c.planDisks(ctx, plan); err != nil {
+		return "", err
+	}
+
+	// Create the PVC first (Harvester clones from image via StorageClass).
+	// Container disks are ephemeral and need no PVC, and an imported root
+	// disk is created by CDI together with the VM.
+	pvcName, dataVolume := plan.pvcName, plan.dataVolume
+	if dataVolume == nil && pvcName != "" {
+		if err := c.createImagePVC(ctx, pvcName, plan.imageName, opts); err != nil {
+			return "", fmt.Errorf("failed to create PVC: %w", err)
+		}
+	}
+	deleteRootDisk := func() {
+		if pvcName != "" && dataVolume == nil {
+			_ = c.deletePVC(ctx, pvcName)
+		}
+	}
+	if err := c.createDataDisks(ctx, opts); err != nil {
+		deleteRootDisk()
+		return "", err
+	}
+	deleteDisks := func() {
+		deleteRootDisk()
+		c.deleteDataDisks(ctx, opts.Name, len(opts.DataDisks))
+	}
+
+	if opts.PersistentCloudInit {
+		if err := c.createPersistentCloudInit(ctx, opts, plan.seed); err != nil {
+			deleteDisks()
+			return "", err
+		}
+	}
+
+	// Build and create the VM
+	vm := c.buildPlannedVM(plan)
+	created, err := retryCreate(ctx, c, func(ctx context.Context) (*unstructured.Unstructured, error) {
+		return c.dynamic.Resource(vmGVR).Namespace(c.namespace).Create(ctx, vm, metav1.CreateOptions{})
+	}, func(ctx context.Context) (*unstructured.Unstructured, error) {
+		return c.dynamic.Resource(vmGVR).Namespace(c.namespace).Get(ctx, vm.GetName(), metav1.GetOptions{})
+	})
+	if err != nil {
+		// Clean up PVCs if VM creation fails
+		deleteDisks()
+		if opts.PersistentCloudInit {
+			c.deletePersistentCloudInit(ctx, opts.Name)
+		}
+		return "", fmt.Errorf("failed to create VM: %w", gpuRejection(err, opts.GPUs))
+	}
+
+	return string(created.GetUID()), nil
+}
+
+// vmPlan holds what CreateVM resolved from the options and the cluster
+// before creating anything.
+type vmPlan struct {
+	opts        VMCreateOptions
+	seed        *cloudInitSeed
+	imageName   string
+	networkName string
+	// pvcName is the root disk claim, empty for a container disk.
+	pvcName string
+	// dataVolume is the CDI template importing the root disk, if any.
+	dataVolume map[string]interface{}
+}
+
+// planVM renders the cloud-init payloads, fills in the provider config
+// defaults and checks the referenced Harvester resources, without changing
+// the cluster. opts must already be validated.
+func (c *Client) planVM(ctx context.Context, opts VMCreateOptions) (*vmPlan, error) {
+	// Generate user data, or merge SSH keys into the caller's
+	userData, err := renderUserData(opts)
+	if err != nil {
+		return nil, err
+	}
+	if userData != "" {
+		opts.UserData = userData
+	}
+
+	// Synthesize network-data unless the caller supplied it
+	if opts.NetworkData == "" {
+		networkData, err := renderNetworkData(opts)
+		if err != nil {
+			return nil, err
+		}
+		opts.NetworkData = networkData
+	}
+
+	// Render the seed image up front so oversized payloads fail before
+	// anything is created
+	plan := &vmPlan{}
+	if opts.PersistentCloudInit {
+		if plan.seed, err = buildCloudInitSeed(opts); err != nil {
+			return nil, err
+		}
+	}
+	if opts.UserData == "" || !opts.PersistentCloudInit {
+		if err := c.checkCloudInitSize(opts.UserData, opts.NetworkData); err != nil {
+			return nil, err
+		}
+	}
+
 	// Use image from options or fall back to config default
 	imageName := opts.ImageName
+	if imageName == "" && opts.ImageSelector != "" {
+		resolved, err := c.ResolveImageSelector(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		imageName = resolved
+	}
 	if imageName == "" {
 		imageName = c.config.ImageName
 	}
-	if imageName == "" {
-		return "", fmt.Errorf("no image specified and no default image in provider config")
+	if imageName == "" && opts.ContainerDiskImage == "" && opts.RootDiskImportURL == "" {
+		return nil, fmt.Errorf("no image specified and no default image in provider config")
+	}
+	// A PVC cloning from a missing image never binds
+	storageClassName := opts.StorageClass
+	if storageClassName == "" {
+		storageClassName = c.RootDiskStorageClass
+	}
+	if storageClassName != "" && opts.ContainerDiskImage == "" {
+		if err := c.checkStorageClass(ctx, storageClassName); err != nil {
+			return nil, err
+		}
+	}
+	if opts.ContainerDiskImage == "" && opts.RootDiskImportURL == "" {
+		image, err := c.GetImage(ctx, imageName)
+		if err != nil {
+			return nil, err
+		}
+		if storageClassName == "" {
+			storageClassName = imageStorageClass(ctx, image, imageName)
+		}
+	}
+	opts.StorageClass = storageClassName
+	plan.imageName = imageName
+
+	// Use networks from options or fall back to config
+	networkRefs := vmNetworkRefs(opts, c.config.NetworkName)
+	for _, ref := range networkRefs {
+		if err := c.checkNetwork(ctx, ref); err != nil {
+			return nil, err
+		}
+	}
+	if len(networkRefs) > 0 {
+		plan.networkName = networkRefs[0]
+	}
+
+	if opts.RuntimeClassName != "" {
+		if err := c.checkRuntimeClass(ctx, opts.RuntimeClassName); err != nil {
+			return nil, err
+		}
+	}
+	if opts.SMBIOSManufacturer != "" || opts.SMBIOSProduct != "" {
+		if err := c.checkSMBIOS(ctx, opts); err != nil {
+			return nil, err
+		}
 	}
 
-	// Use network from options or fall back to config
-	networkName := opts.NetworkName
-	if networkName == "" {
-		networkName = c.config.NetworkName
+	if opts.SSHKeySecret != "" {
+		if err := c.checkSecretExists(ctx, opts.SSHKeySecret); err != nil {
+			return nil, err
+		}
+	}
+	if opts.ImagePullSecret != "" {
+		if err := c.checkPullSecret(ctx, opts.ImagePullSecret); err != nil {
+			return nil, err
+		}
 	}
 
-	// Create the PVC first (Harvester clones from image via StorageClass)
-	pvcName := opts.Name + "-rootdisk"
-	if err := c.createImagePVC(ctx, pvcName, imageName, opts.DiskGB); err != nil {
-		return "", fmt.Errorf("failed to create PVC: %w", err)
+	if len(opts.GPUs) > 0 {
+		if err := c.checkGPUs(ctx, opts.GPUs); err != nil {
+			return nil, err
+		}
 	}
 
-	// Build and create the VM
-	vm := c.buildVM(opts, pvcName, networkName)
+	if opts.NetworkBinding == NetworkBindingSRIOV {
+		resourceName, err := c.sriovResourceName(ctx, plan.networkName)
+		if err != nil {
+			return nil, err
+		}
+		opts.sriovResource = resourceName
+	}
 
-	created, err := c.dynamic.Resource(vmGVR).Namespace(c.namespace).Create(ctx, vm, metav1.CreateOptions{})
-	if err != nil {
-		// Clean up PVC if VM creation fails
-		_ = c.clientset.CoreV1().PersistentVolumeClaims(c.namespace).Delete(ctx, pvcName, metav1.DeleteOptions{})
-		return "", fmt.Errorf("failed to create VM: %w", err)
+	plan.opts = opts
+	return plan, nil
+}
+
+// planDisks checks the attached disks and that the root disk claim is free,
+// and builds the CDI template of an imported root disk.
+func (c *Client) planDisks(ctx context.Context, plan *vmPlan) error {
+	opts := plan.opts
+	if err := c.checkAttachedDisks(ctx, opts.AttachedDisks); err != nil {
+		return err
+	}
+	if opts.ContainerDiskImage == "" {
+		plan.pvcName = ResolveRootDiskPVCName(opts)
+		if err := c.checkRootDiskPVCAvailable(ctx, plan.pvcName, opts); err != nil {
+			return err
+		}
+	}
+	if opts.RootDiskImportURL != "" {
+		dataVolume, err := c.rootDiskDataVolume(ctx, plan.pvcName, opts)
+		if err != nil {
+			return err
+		}
+		plan.dataVolume = dataVolume
 	}
+	return nil
+}
 
-	return string(created.GetUID()), nil
+// buildPlannedVM constructs the VirtualMachine object for a plan.
+func (c *Client) buildPlannedVM(plan *vmPlan) *unstructured.Unstructured {
+	vm := c.buildVM(plan.opts, plan.pvcName, plan.networkName)
+	if plan.dataVolume != nil {
+		setRootDataVolume(vm, plan.dataVolume)
+	}
+	return vm
 }
 
-// createImagePVC creates a PVC that clones from a Harvester image.
-func (c *Client) createImagePVC(ctx context.Context, name, imageName string, sizeGB int32) error {
+// createImagePVC creates a PVC that clones from a Harvester image through
+// opts.StorageClass.
+func (c *Client) createImagePVC(ctx context.Context, name, imageName string, opts VMCreateOptions) error {
+	pvc, err := c.imagePVC(ctx, name, imageName, opts)
+	if err != nil {
+		return err
+	}
+	_, err = retryCreate(ctx, c, func(ctx context.Context) (*corev1.PersistentVolumeClaim, error) {
+		return c.clientset.CoreV1().PersistentVolumeClaims(c.namespace).Create(ctx, pvc, metav1.CreateOptions{})
+	}, func(ctx context.Context) (*corev1.PersistentVolumeClaim, error) {
+		return c.clientset.CoreV1().PersistentVolumeClaims(c.namespace).Get(ctx, pvc.GetName(), metav1.GetOptions{})
+	})
+	return err
+}
+
+// imagePVC builds the root disk PVC cloning from a Harvester image.
+func (c *Client) imagePVC(ctx context.Context, name, imageName string, opts VMCreateOptions) (*corev1.PersistentVolumeClaim, error) {
 	imageID := imageName // e.g., "default/image-prn78"
-	storageClassName := fmt.Sprintf("longhorn-%s", parseName(imageName))
+	storageClassName := opts.StorageClass
 
-	blockMode := corev1.PersistentVolumeBlock
+	volumeMode := opts.VolumeMode
+	if volumeMode == "" {
+		volumeMode = corev1.PersistentVolumeBlock
+	}
+
+	size, err := c.rootDiskSize(ctx, opts, storageClassName)
+	if err != nil {
+		return nil, err
+	}
 	pvc := &corev1.PersistentVolumeClaim{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      name,
 			Namespace: c.namespace,
 			Annotations: map[string]string{
 				"harvesterhci.io/imageId": imageID,
+				AnnotationOwnerUID:        opts.OwnerUID,
 			},
 			Labels: map[string]string{
-				"butler.butlerlabs.dev/managed-by": "butler-provider-harvester",
+				LabelManagedBy: managedByValue,
 			},
 		},
 		Spec: corev1.PersistentVolumeClaimSpec{
 			AccessModes: []corev1.PersistentVolumeAccessMode{
 				corev1.ReadWriteMany,
 			},
-			VolumeMode:       &blockMode,
+			VolumeMode:       &volumeMode,
 			StorageClassName: &storageClassName,
 			Resources: corev1.VolumeResourceRequirements{
 				Requests: corev1.ResourceList{
-					corev1.ResourceStorage: resource.MustParse(fmt.Sprintf("%dGi", sizeGB)),
+					corev1.ResourceStorage: size,
 				},
 			},
 		},
 	}
-
-	_, err := c.clientset.CoreV1().PersistentVolumeClaims(c.namespace).Create(ctx, pvc, metav1.CreateOptions{})
-	return err
+	return pvc, nil
 }
 
 // buildVM constructs the VirtualMachine object.
 func (c *Client) buildVM(opts VMCreateOptions, pvcName, networkName string) *unstructured.Unstructured {
-	labels := map[string]interface{}{
-		"butler.butlerlabs.dev/managed-by": "butler-provider-harvester",
-	}
-	for k, v := range opts.Labels {
+	managed := desiredLabels(opts.Labels)
+	labels := map[string]interface{}{}
+	for k, v := range managed {
 		labels[k] = v
 	}
 
 	// Build volumes list
-	volumes := []interface{}{
-		map[string]interface{}{
-			"name": "rootdisk",
-			"persistentVolumeClaim": map[string]interface{}{
-				"claimName": pvcName,
-			},
+	rootVolume := map[string]interface{}{
+		"name": "rootdisk",
+		"persistentVolumeClaim": map[string]interface{}{
+			"claimName": pvcName,
 		},
 	}
+	if opts.ContainerDiskImage != "" {
+		containerDisk := map[string]interface{}{
+			"image": opts.ContainerDiskImage,
+		}
+		if opts.ImagePullSecret != "" {
+			containerDisk["imagePullSecret"] = opts.ImagePullSecret
+		}
+		rootVolume = map[string]interface{}{
+			"name":          "rootdisk",
+			"containerDisk": containerDisk,
+		}
+	}
+	volumes := []interface{}{rootVolume}
 
 	// Build disks list
-	disks := []interface{}{
-		map[string]interface{}{
-			"name":      "rootdisk",
-			"bootOrder": int64(1),
-			"disk": map[string]interface{}{
-				"bus": "virtio",
-			},
+	bus := string(resolveDiskBus(opts.DiskBus, ""))
+	rootDisk := map[string]interface{}{
+		"name":      "rootdisk",
+		"bootOrder": int64(1),
+		"disk": map[string]interface{}{
+			"bus": bus,
 		},
 	}
+	if opts.RootDiskSerial != "" {
+		rootDisk["serial"] = opts.RootDiskSerial
+	}
+	if opts.RootDiskShareable {
+		rootDisk["shareable"] = true
+	}
+	disks := []interface{}{rootDisk}
+	for i, attached := range opts.AttachedDisks {
+		volume, disk := attachedDiskDevice(i, attached, opts.DiskBus)
+		volumes = append(volumes, volume)
+		disks = append(disks, disk)
+	}
+	for i, spec := range opts.DataDisks {
+		volume, disk := dataDiskDevice(opts.Name, i, spec, opts.DiskBus)
+		volumes = append(volumes, volume)
+		disks = append(disks, disk)
+	}
+	if opts.EphemeralScratchGB > 0 {
+		volume, disk := scratchDiskDevice(opts.EphemeralScratchGB, opts.DiskBus)
+		volumes = append(volumes, volume)
+		disks = append(disks, disk)
+	}
 
 	// Add cloud-init if userData is provided
-	if opts.UserData != "" {
-		cloudInitVolume := map[string]interface{}{
+	if opts.UserData != "" && opts.PersistentCloudInit {
+		volumes = append(volumes, persistentCloudInitVolume(opts.Name))
+		disks = append(disks, map[string]interface{}{
 			"name": "cloudinit",
-			"cloudInitNoCloud": map[string]interface{}{
-				"userDataBase64": base64.StdEncoding.EncodeToString([]byte(opts.UserData)),
+			"disk": map[string]interface{}{
+				"bus": bus,
 			},
+		})
+	} else if opts.UserData != "" || opts.NetworkData != "" {
+		noCloud := map[string]interface{}{}
+		if opts.UserData != "" {
+			noCloud["userDataBase64"] = base64.StdEncoding.EncodeToString([]byte(opts.UserData))
 		}
 		if opts.NetworkData != "" {
-			cloudInitVolume["cloudInitNoCloud"].(map[string]interface{})["networkDataBase64"] = base64.StdEncoding.EncodeToString([]byte(opts.NetworkData))
+			noCloud["networkDataBase64"] = base64.StdEncoding.EncodeToString([]byte(opts.NetworkData))
+		}
+		cloudInitVolume := map[string]interface{}{
+			"name":             "cloudinit",
+			"cloudInitNoCloud": noCloud,
 		}
 		volumes = append(volumes, cloudInitVolume)
 		disks = append(disks, map[string]interface{}{
 			"name": "cloudinit",
 			"disk": map[string]interface{}{
-				"bus": "virtio",
+				"bus": bus,
 			},
 		})
 	}
 
+	interfaces, networks := buildNetworks(opts, networkName)
+	templateSpec := map[string]interface{}{
+		"domain": map[string]interface{}{
+			"cpu":       buildCPU(opts),
+			"memory":    buildMemory(opts),
+			"resources": buildResources(opts),
+			"devices": map[string]interface{}{
+				"disks":      disks,
+				"interfaces": interfaces,
+			},
+		},
+		"networks": networks,
+		"volumes":  volumes,
+	}
+	if len(opts.GPUs) > 0 {
+		templateSpec["domain"].(map[string]interface{})["devices"].(map[string]interface{})["gpus"] = buildGPUs(opts.GPUs)
+	}
+	if opts.StartPaused {
+		templateSpec["startStrategy"] = "Paused"
+	}
+	if firmware := buildFirmware(opts); firmware != nil {
+		templateSpec["domain"].(map[string]interface{})["firmware"] = firmware
+	}
+	if opts.FirmwareSecureBoot {
+		// KubeVirt only accepts Secure Boot with SMM enabled
+		templateSpec["domain"].(map[string]interface{})["features"] = map[string]interface{}{
+			"smm": map[string]interface{}{"enabled": true},
+		}
+	}
+	if opts.SSHKeySecret != "" {
+		templateSpec["accessCredentials"] = buildAccessCredentials(opts)
+	}
+
+	// A persistent cloud-init disk must be populated before the guest boots
+	runStrategy := RunStrategyAlways
+	annotations := map[string]interface{}{}
+	if opts.PersistentCloudInit {
+		runStrategy = RunStrategyHalted
+		annotations[AnnotationPersistentCloudInit] = "pending"
+	}
+	annotations[AnnotationManagedLabels] = managedKeys(managed)
+	templateMetadata := map[string]interface{}{
+		"labels": labels,
+	}
+	if opts.OwnerUID != "" {
+		annotations[AnnotationOwnerUID] = opts.OwnerUID
+		annotations[AnnotationOwner] = opts.Owner
+		// Carried to the VMI, so deleteOrphanedVMI can tell whose it is
+		templateMetadata["annotations"] = map[string]interface{}{AnnotationOwnerUID: opts.OwnerUID}
+	}
+
 	vm := &unstructured.Unstructured{
 		Object: map[string]interface{}{
 			"apiVersion": "kubevirt.io/v1",
 			"kind":       "VirtualMachine",
 			"metadata": map[string]interface{}{
-				"name":      opts.Name,
-				"namespace": c.namespace,
-				"labels":    labels,
-				"annotations": map[string]interface{}{
-					"harvesterhci.io/vmRunStrategy": "Always",
-				},
+				"name":        opts.Name,
+				"namespace":   c.namespace,
+				"labels":      labels,
+				"annotations": annotations,
 			},
 			"spec": map[string]interface{}{
-				"runStrategy": "Always",
 				"template": map[string]interface{}{
-					"metadata": map[string]interface{}{
-						"labels": labels,
+					"metadata": templateMetadata,
+					"spec":     templateSpec,
+				},
+			},
+		},
+	}
+	setRunStrategy(vm, runStrategy)
+
+	return vm
+}
+
+// buildAccessCredentials returns the accessCredentials propagating SSH keys
+// from opts.SSHKeySecret through the QEMU guest agent.
+func buildAccessCredentials(opts VMCreateOptions) []interface{} {
+	users := make([]interface{}, 0, len(opts.SSHKeyUsers))
+	for _, u := range opts.SSHKeyUsers {
+		users = append(users, u)
+	}
+	return []interface{}{
+		map[string]interface{}{
+			"sshPublicKey": map[string]interface{}{
+				"source": map[string]interface{}{
+					"secret": map[string]interface{}{
+						"secretName": opts.SSHKeySecret,
 					},
-					"spec": map[string]interface{}{
-						"domain": map[string]interface{}{
-							"cpu": map[string]interface{}{
-								"cores":   int64(opts.CPU),
-								"sockets": int64(1),
-								"threads": int64(1),
-							},
-							"memory": map[string]interface{}{
-								"guest": fmt.Sprintf("%dMi", opts.MemoryMB),
-							},
-							"resources": map[string]interface{}{
-								"limits": map[string]interface{}{
-									"cpu":    fmt.Sprintf("%d", opts.CPU),
-									"memory": fmt.Sprintf("%dMi", opts.MemoryMB),
-								},
-								"requests": map[string]interface{}{
-									"cpu":    "125m",
-									"memory": fmt.Sprintf("%dMi", opts.MemoryMB),
-								},
-							},
-							"devices": map[string]interface{}{
-								"disks": disks,
-								"interfaces": []interface{}{
-									map[string]interface{}{
-										"name":   "default",
-										"bridge": map[string]interface{}{},
-									},
-								},
-							},
-						},
-						"networks": []interface{}{
-							map[string]interface{}{
-								"name": "default",
-								"multus": map[string]interface{}{
-									"networkName": networkName,
-								},
-							},
-						},
-						"volumes": volumes,
+				},
+				"propagationMethod": map[string]interface{}{
+					"qemuGuestAgent": map[string]interface{}{
+						"users": users,
 					},
 				},
 			},
 		},
 	}
+}
 
-	return vm
+// SSHKeySecretHash returns a fingerprint of the SSH public keys in the named
+// Secret, so callers can tell when the keys were rotated.
+func (c *Client) SSHKeySecretHash(ctx context.Context, name string) (string, error) {
+	secret, err := c.getSecret(ctx, name)
+	if err != nil {
+		return "", fmt.Errorf("failed to get secret %s: %w", name, err)
+	}
+	keys := make([]string, 0, len(secret.Data))
+	for k := range secret.Data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	h := sha256.New()
+	for _, k := range keys {
+		fmt.Fprintf(h, "%s\x00%s\x00", k, secret.Data[k])
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// checkSecretExists fails with ErrInvalidOptions when the named Secret does
+// not exist in the VM namespace.
+func (c *Client) checkSecretExists(ctx context.Context, name string) error {
+	_, err := c.getSecret(ctx, name)
+	if apierrors.IsNotFound(err) {
+		return invalidOptionsf("secret %s/%s not found", c.namespace, name)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get secret %s: %w", name, err)
+	}
+	return nil
+}
+
+// checkPullSecret fails with ErrInvalidOptions when the named Secret does
+// not exist or does not hold docker registry credentials.
+func (c *Client) checkPullSecret(ctx context.Context, name string) error {
+	secret, err := c.getSecret(ctx, name)
+	if apierrors.IsNotFound(err) {
+		return invalidOptionsf("image pull secret %s/%s not found", c.namespace, name)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get secret %s: %w", name, err)
+	}
+	if secret.Type != corev1.SecretTypeDockerConfigJson && secret.Type != corev1.SecretTypeDockercfg {
+		return invalidOptionsf("image pull secret %s has type %q (must be %s or %s)",
+			name, secret.Type, corev1.SecretTypeDockerConfigJson, corev1.SecretTypeDockercfg)
+	}
+	return nil
+}
+
+// buildCPU constructs the domain.cpu section of the VM template.
+func buildCPU(opts VMCreateOptions) map[string]interface{} {
+	sockets, cores, threads := cpuTopology(opts)
+	cpu := map[string]interface{}{
+		"cores":   int64(cores),
+		"sockets": int64(sockets),
+		"threads": int64(threads),
+	}
+	if len(opts.NUMACells) > 0 {
+		cpu["numa"] = map[string]interface{}{
+			"guestMappingPassthrough": map[string]interface{}{},
+		}
+	}
+	if opts.DedicatedCPUPlacement {
+		cpu["dedicatedCpuPlacement"] = true
+	}
+	if opts.IsolateEmulatorThread {
+		cpu["isolateEmulatorThread"] = true
+	}
+	return cpu
+}
+
+// cpuTopology returns the sockets, cores per socket and threads per core of
+// the guest. Guest NUMA cells map to one socket each.
+func cpuTopology(opts VMCreateOptions) (sockets, cores, threads int32) {
+	sockets, threads = max(opts.Sockets, 1), max(opts.Threads, 1)
+	if len(opts.NUMACells) > 0 && opts.Sockets == 0 {
+		sockets = int32(len(opts.NUMACells))
+	}
+	// Computed in int64 so huge sockets and threads cannot overflow to zero
+	return sockets, int32(int64(opts.CPU) / (int64(sockets) * int64(threads))), threads
+}
+
+// buildFirmware constructs the domain.firmware section of the VM template,
+// or returns nil when no firmware identity is requested.
+func buildFirmware(opts VMCreateOptions) map[string]interface{} {
+	if opts.FirmwareUUID == "" && opts.FirmwareSerial == "" && !opts.FirmwareEFI {
+		return nil
+	}
+	firmware := map[string]interface{}{}
+	if opts.FirmwareUUID != "" {
+		firmware["uuid"] = strings.ToLower(opts.FirmwareUUID)
+	}
+	if opts.FirmwareSerial != "" {
+		firmware["serial"] = opts.FirmwareSerial
+	}
+	if opts.FirmwareEFI {
+		firmware["bootloader"] = map[string]interface{}{
+			"efi": map[string]interface{}{
+				"secureBoot": opts.FirmwareSecureBoot,
+			},
+		}
+	}
+	return firmware
+}
+
+// buildMemory constructs the domain.memory section of the VM template.
+func buildMemory(opts VMCreateOptions) map[string]interface{} {
+	memory := map[string]interface{}{
+		"guest": fmt.Sprintf("%dMi", opts.MemoryMB),
+	}
+	if opts.HugepagesPageSize != "" {
+		memory["hugepages"] = map[string]interface{}{
+			"pageSize": opts.HugepagesPageSize,
+		}
+	}
+	return memory
+}
+
+// defaultMemoryRequestPercent is the share of guest memory requested for
+// overcommitted VMs.
+const defaultMemoryRequestPercent = 50
+
+// defaultMemoryOverheadPercent is the share of guest memory added to the
+// memory limit when MemoryOverheadMB is unset.
+const defaultMemoryOverheadPercent = 2
+
+// MemoryLimit returns the VM memory limit: MemoryLimitMB, or the guest memory
+// plus MemoryOverheadMB, or defaultMemoryOverheadPercent of it rounded up.
+func MemoryLimit(opts VMCreateOptions) resource.Quantity {
+	mib := int64(opts.MemoryMB)
+	if opts.MemoryLimitMB > 0 {
+		mib = int64(opts.MemoryLimitMB)
+	} else if opts.MemoryOverheadMB != nil {
+		mib += int64(*opts.MemoryOverheadMB)
+	} else {
+		mib += (mib*defaultMemoryOverheadPercent + 99) / 100
+	}
+	return *resource.NewQuantity(mib*1024*1024, resource.BinarySI)
+}
+
+// MemoryRequest returns the memory the VM requests from the scheduler:
+// MemoryRequestMB, or the guest memory, which is reduced for overcommitted
+// VMs. Dedicated CPU placement requires Guaranteed QoS, so those VMs request
+// their full limit.
+func MemoryRequest(opts VMCreateOptions) resource.Quantity {
+	if opts.DedicatedCPUPlacement {
+		return MemoryLimit(opts)
+	}
+	mib := int64(opts.MemoryMB)
+	if opts.MemoryRequestMB > 0 {
+		mib = int64(opts.MemoryRequestMB)
+	} else if opts.EnableOvercommit {
+		percent := opts.MemoryRequestPercent
+		if percent == 0 {
+			percent = defaultMemoryRequestPercent
+		}
+		mib = max(mib*int64(percent)/100, 1)
+	}
+	return *resource.NewQuantity(mib*1024*1024, resource.BinarySI)
+}
+
+// defaultCPURequest is the CPU request of VMs without dedicated CPU placement.
+const defaultCPURequest = "125m"
+
+// buildResources constructs the domain.resources section of the VM template.
+func buildResources(opts VMCreateOptions) map[string]interface{} {
+	cpuLimit := int64(opts.CPU)
+	cpuRequest := defaultCPURequest
+	if opts.CPURequestMillicores > 0 {
+		cpuRequest = fmt.Sprintf("%dm", opts.CPURequestMillicores)
+	}
+	if opts.DedicatedCPUPlacement {
+		// Dedicated placement requires Guaranteed QoS, so requests must match limits.
+		// The isolated emulator thread consumes one extra whole core.
+		if opts.IsolateEmulatorThread {
+			cpuLimit++
+		}
+		cpuRequest = fmt.Sprintf("%d", cpuLimit)
+	}
+
+	memoryLimit := MemoryLimit(opts)
+	limits := map[string]interface{}{
+		"cpu":    fmt.Sprintf("%d", cpuLimit),
+		"memory": memoryLimit.String(),
+	}
+	memoryRequest := MemoryRequest(opts)
+	requests := map[string]interface{}{
+		"cpu":    cpuRequest,
+		"memory": memoryRequest.String(),
+	}
+	// Allocate the SR-IOV virtual function from its device plugin
+	if opts.sriovResource != "" {
+		limits[opts.sriovResource] = "1"
+		requests[opts.sriovResource] = "1"
+	}
+
+	resources := map[string]interface{}{
+		"limits":   limits,
+		"requests": requests,
+	}
+	if opts.EnableOvercommit {
+		resources["overcommitGuestOverhead"] = true
+	}
+	return resources
+}
+
+// buildNetworks constructs the VM interfaces and the multus networks they
+// attach to. Without opts.Networks the VM has a single interface on
+// networkName.
+func buildNetworks(opts VMCreateOptions, networkName string) (interfaces, networks []interface{}) {
+	attached := opts.Networks
+	if len(attached) == 0 {
+		attached = []NetworkInterface{{NetworkName: networkName}}
+	}
+	for i, spec := range attached {
+		name := interfaceName(i, spec)
+		binding := interfaceBinding(opts, i, spec)
+		iface := map[string]interface{}{
+			string(binding): map[string]interface{}{},
+		}
+		if i == 0 {
+			iface = buildInterface(opts)
+		}
+		iface["name"] = name
+		acpiIndex, pciAddress := interfacePinning(opts, i, spec)
+		if acpiIndex != 0 {
+			iface["acpiIndex"] = int64(acpiIndex)
+		}
+		if pciAddress != "" {
+			iface["pciAddress"] = pciAddress
+		}
+		if spec.MACAddress != "" {
+			iface["macAddress"] = spec.MACAddress
+		}
+		interfaces = append(interfaces, iface)
+		network := map[string]interface{}{
+			"name": name,
+			"multus": map[string]interface{}{
+				"networkName": spec.NetworkName,
+			},
+		}
+		if binding == NetworkBindingMasquerade {
+			network = map[string]interface{}{
+				"name": name,
+				"pod":  map[string]interface{}{},
+			}
+		}
+		networks = append(networks, network)
+	}
+	return interfaces, networks
+}
+
+// buildInterface constructs the primary VM interface.
+func buildInterface(opts VMCreateOptions) map[string]interface{} {
+	binding := networkBinding(opts)
+	if len(opts.Networks) > 0 {
+		binding = interfaceBinding(opts, 0, opts.Networks[0])
+	}
+	return map[string]interface{}{
+		"name":          defaultInterfaceName,
+		string(binding): map[string]interface{}{},
+	}
+}
+
+// networkBinding returns the interface binding, defaulting to bridge.
+func networkBinding(opts VMCreateOptions) NetworkBinding {
+	if opts.NetworkBinding == "" {
+		return NetworkBindingBridge
+	}
+	return opts.NetworkBinding
 }
 
 // GetVM retrieves a VirtualMachine by name.
 func (c *Client) GetVM(ctx context.Context, name string) (*unstructured.Unstructured, error) {
-	return c.dynamic.Resource(vmGVR).Namespace(c.namespace).Get(ctx, name, metav1.GetOptions{})
+	return retryResult(ctx, c, func(ctx context.Context) (*unstructured.Unstructured, error) {
+		return c.dynamic.Resource(vmGVR).Namespace(c.namespace).Get(ctx, name, metav1.GetOptions{})
+	})
 }
 
 // GetVMI retrieves a VirtualMachineInstance by name.
 func (c *Client) GetVMI(ctx context.Context, name string) (*unstructured.Unstructured, error) {
-	return c.dynamic.Resource(vmiGVR).Namespace(c.namespace).Get(ctx, name, metav1.GetOptions{})
+	return retryResult(ctx, c, func(ctx context.Context) (*unstructured.Unstructured, error) {
+		return c.dynamic.Resource(vmiGVR).Namespace(c.namespace).Get(ctx, name, metav1.GetOptions{})
+	})
+}
+
+// DeleteVMOptions controls what DeleteVM removes besides the VM and its disks.
+type DeleteVMOptions struct {
+	// Snapshots also deletes the snapshots CreateVMSnapshot took of the VM,
+	// which otherwise outlive it.
+	Snapshots bool
+	// OwnerUID, when set, limits the cleanup of a VMI that outlived its VM
+	// to one created for that MachineRequest.
+	OwnerUID string
 }
 
 // DeleteVM deletes a VirtualMachine and its associated PVC.
-func (c *Client) DeleteVM(ctx context.Context, name string) error {
+func (c *Client) DeleteVM(ctx context.Context, name string, opts DeleteVMOptions) error {
+	// Stop any restore still creating the VM
+	c.deleteRestore(ctx, name)
+
+	if opts.Snapshots {
+		if err := c.DeleteVMSnapshots(ctx, name); err != nil {
+			return err
+		}
+	}
+
+	// The root disk PVC name may be customized, so read it from the VM
+	vm, err := c.GetVM(ctx, name)
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return err
+		}
+		// Clean up a VMI that outlived its VM, leaving one that is not ours
+		deleted, vmiErr := c.deleteOrphanedVMI(ctx, name, opts.OwnerUID)
+		if errors.Is(vmiErr, ErrVMINotOwned) {
+			return err
+		}
+		if vmiErr != nil || deleted {
+			return vmiErr
+		}
+		return err
+	}
+	pvcName := rootDiskClaimName(vm)
+	dataDisks := dataDiskClaimNames(vm)
+
 	// Delete the VM first
-	err := c.dynamic.Resource(vmGVR).Namespace(c.namespace).Delete(ctx, name, metav1.DeleteOptions{})
+	err = c.retry(ctx, func(ctx context.Context) error {
+		return c.dynamic.Resource(vmGVR).Namespace(c.namespace).Delete(ctx, name, metav1.DeleteOptions{})
+	})
 	if err != nil {
 		return err
 	}
 
 	// Delete the associated PVC
-	pvcName := name + "-rootdisk"
-	_ = c.clientset.CoreV1().PersistentVolumeClaims(c.namespace).Delete(ctx, pvcName, metav1.DeleteOptions{})
+	if pvcName != "" {
+		_ = c.deletePVC(ctx, pvcName)
+	}
+	for _, claim := range dataDisks {
+		_ = c.deletePVC(ctx, claim)
+	}
+
+	// Delete the persistent cloud-init disk, if any
+	c.deletePersistentCloudInit(ctx, name)
 
 	return nil
 }
 
+// deleteOrphanedVMI deletes a VMI left behind by a VM that no longer exists.
+// It reports whether there was one. Like DeleteCreateLeftovers it only
+// deletes what this provider created: the VMI must carry the managed-by label
+// and, when both are known, ownerUID. Otherwise ErrVMINotOwned is returned.
+func (c *Client) deleteOrphanedVMI(ctx context.Context, name, ownerUID string) (bool, error) {
+	vmi, err := c.GetVMI(ctx, name)
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	vmiOwner := vmi.GetAnnotations()[AnnotationOwnerUID]
+	if vmi.GetLabels()[LabelManagedBy] != managedByValue || (ownerUID != "" && vmiOwner != "" && vmiOwner != ownerUID) {
+		return false, fmt.Errorf("%w: VMI %s exists without a VM; delete it to reuse the name", ErrVMINotOwned, name)
+	}
+
+	uid := vmi.GetUID()
+	err = c.retry(ctx, func(ctx context.Context) error {
+		return c.dynamic.Resource(vmiGVR).Namespace(c.namespace).Delete(ctx, name, metav1.DeleteOptions{
+			Preconditions: &metav1.Preconditions{UID: &uid},
+		})
+	})
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to delete orphaned VMI %s: %w", name, err)
+	}
+	return true, nil
+}
+
+// DeleteCreateLeftovers removes resources a CreateVM that never finished may
+// have left behind without a VM: a pending restore, the root and data disk
+// PVCs and the persistent cloud-init disk. Disk PVCs are only deleted when
+// they were created for ownerUID. Missing resources are ignored.
+func (c *Client) DeleteCreateLeftovers(ctx context.Context, name, pvcName, ownerUID string) error {
+	c.deleteRestore(ctx, name)
+
+	if pvcName != "" {
+		pvc, err := c.getPVC(ctx, pvcName)
+		switch {
+		case apierrors.IsNotFound(err):
+		case err != nil:
+			return err
+		case ownerUID != "" && pvc.Annotations[AnnotationOwnerUID] == ownerUID:
+			if err := c.deletePVC(ctx, pvcName); err != nil && !apierrors.IsNotFound(err) {
+				return err
+			}
+		}
+	}
+	if err := c.deleteOwnedDataDisks(ctx, name, ownerUID); err != nil {
+		return err
+	}
+
+	c.deletePersistentCloudInit(ctx, name)
+	return nil
+}
+
+// getSecret retrieves a Secret in the VM namespace.
+func (c *Client) getSecret(ctx context.Context, name string) (*corev1.Secret, error) {
+	return retryResult(ctx, c, func(ctx context.Context) (*corev1.Secret, error) {
+		return c.clientset.CoreV1().Secrets(c.namespace).Get(ctx, name, metav1.GetOptions{})
+	})
+}
+
+// getPVC retrieves a PersistentVolumeClaim in the VM namespace.
+func (c *Client) getPVC(ctx context.Context, name string) (*corev1.PersistentVolumeClaim, error) {
+	return retryResult(ctx, c, func(ctx context.Context) (*corev1.PersistentVolumeClaim, error) {
+		return c.clientset.CoreV1().PersistentVolumeClaims(c.namespace).Get(ctx, name, metav1.GetOptions{})
+	})
+}
+
+// deletePVC deletes a PersistentVolumeClaim in the VM namespace.
+func (c *Client) deletePVC(ctx context.Context, name string) error {
+	return c.retry(ctx, func(ctx context.Context) error {
+		return c.clientset.CoreV1().PersistentVolumeClaims(c.namespace).Delete(ctx, name, metav1.DeleteOptions{})
+	})
+}
+
+// UnpauseVM resumes a paused VirtualMachineInstance.
+func (c *Client) UnpauseVM(ctx context.Context, name string) error {
+	return c.retry(ctx, func(ctx context.Context) error {
+		return c.clientset.CoreV1().RESTClient().Put().
+			AbsPath("/apis/subresources.kubevirt.io/v1/namespaces", c.namespace, "virtualmachineinstances", name, "unpause").
+			Do(ctx).
+			Error()
+	})
+}
+
+// GetGuestHostname asks the guest agent of a VMI for the guest hostname
+// through the KubeVirt guestosinfo subresource, which the VMI status does not
+// carry. It makes a single attempt, as the hostname is best effort.
+func (c *Client) GetGuestHostname(ctx context.Context, name string) (string, error) {
+	var data []byte
+	err := c.call(ctx, func(ctx context.Context) error {
+		var err error
+		data, err = c.clientset.CoreV1().RESTClient().Get().
+			AbsPath("/apis/subresources.kubevirt.io/v1/namespaces", c.namespace, "virtualmachineinstances", name, "guestosinfo").
+			DoRaw(ctx)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	var info struct {
+		Hostname string `json:"hostname"`
+	}
+	if err := json.Unmarshal(data, &info); err != nil {
+		return "", err
+	}
+	return info.Hostname, nil
+}
+
+// guestOS describes the guest operating system from the guest agent info
+// KubeVirt copies into the VMI status.
+func guestOS(vmi *unstructured.Unstructured) string {
+	info, _, _ := unstructured.NestedStringMap(vmi.Object, "status", "guestOSInfo")
+	if pretty := info["prettyName"]; pretty != "" {
+		return pretty
+	}
+	return strings.TrimSpace(info["name"] + " " + info["version"])
+}
+
+// VMPhaseOrphanedVMI is the VMStatus phase of a VMI whose VM no longer exists.
+const VMPhaseOrphanedVMI = "OrphanedVMI"
+
+// VMPhaseStopped is the VMStatus phase of a VM that was stopped and whose
+// guest has shut down.
+const VMPhaseStopped = "Stopped"
+
 // VMStatus represents the status of a VM.
 type VMStatus struct {
 	Exists     bool
 	Ready      bool
+	Paused     bool
 	Phase      string
 	IPAddress  string
 	MACAddress string
+	// IPAddresses lists every usable address of every interface, in
+	// interface order. IPAddress stays the first usable primary address of
+	// an interface, which may come later in the list, or else the first
+	// other usable address of the primary interface.
+	IPAddresses []string
+	// RunStrategy is the spec.runStrategy of the VM.
+	RunStrategy RunStrategy
+	// NodeName is the node the VMI is scheduled to, empty until scheduled.
+	NodeName string
+	// AgentConnected reports whether the QEMU guest agent is connected.
+	AgentConnected bool
+	// GuestOS is reported by the guest agent, e.g. "Ubuntu 22.04.4 LTS".
+	// It is empty without an agent. The hostname needs GetGuestHostname.
+	GuestOS string
+	// AccessCredentialsSynced reports whether KubeVirt's last propagation of
+	// the accessCredentials SSH keys into the guest succeeded, and
+	// AccessCredentialsMessage explains a failed propagation.
+	AccessCredentialsSynced  bool
+	AccessCredentialsMessage string
+	// OrphanedVMI reports that the VM is gone but its VMI still exists.
+	// GetVMStatus then returns the VM NotFound error with Phase set to
+	// VMPhaseOrphanedVMI; DeleteVM removes the stray VMI.
+	OrphanedVMI bool
+
+	// OwnerUID and Owner identify the MachineRequest recorded on the VM.
+	// Both are empty for VMs created before ownership was stamped.
+	OwnerUID string
+	Owner    string
+
+	// UID is the VM UID, which CreateVM returns as the provider ID.
+	UID string
 }
 
 // GetVMStatus returns the current status of a VM.
@@ -329,9 +1404,19 @@ func (c *Client) GetVMStatus(ctx context.Context, name string) (*VMStatus, error
 	// Check if VM exists
 	vm, err := c.GetVM(ctx, name)
 	if err != nil {
+		// A VMI can outlive its VM during abnormal teardown
+		if apierrors.IsNotFound(err) {
+			if _, vmiErr := c.GetVMI(ctx, name); vmiErr == nil {
+				status.OrphanedVMI = true
+				status.Phase = VMPhaseOrphanedVMI
+			}
+		}
 		return status, err
 	}
 	status.Exists = true
+	status.UID = string(vm.GetUID())
+	status.OwnerUID = vm.GetAnnotations()[AnnotationOwnerUID]
+	status.Owner = vm.GetAnnotations()[AnnotationOwner]
 
 	// Get VM ready status
 	ready, found, _ := unstructured.NestedBool(vm.Object, "status", "ready")
@@ -341,28 +1426,52 @@ func (c *Client) GetVMStatus(ctx context.Context, name string) (*VMStatus, error
 
 	printableStatus, _, _ := unstructured.NestedString(vm.Object, "status", "printableStatus")
 	status.Phase = printableStatus
+	strategy, _, _ := unstructured.NestedString(vm.Object, "spec", "runStrategy")
+	status.RunStrategy = RunStrategy(strategy)
 
 	// Get VMI for IP address
 	vmi, err := c.GetVMI(ctx, name)
+	if IsCallTimeout(err) {
+		return status, err
+	}
 	if err != nil {
 		// VMI might not exist yet if VM is still starting
 		return status, nil
 	}
 
-	// Extract IP from VMI interfaces
-	interfaces, found, _ := unstructured.NestedSlice(vmi.Object, "status", "interfaces")
-	if found && len(interfaces) > 0 {
-		for _, iface := range interfaces {
-			ifaceMap, ok := iface.(map[string]interface{})
-			if !ok {
+	status.NodeName, _, _ = unstructured.NestedString(vmi.Object, "status", "nodeName")
+	status.Paused = hasTrueCondition(vmi, "Paused")
+	status.AgentConnected = hasTrueCondition(vmi, "AgentConnected")
+	status.GuestOS = guestOS(vmi)
+	status.AccessCredentialsSynced = hasTrueCondition(vmi, "AccessCredentialsSynchronized")
+	if !status.AccessCredentialsSynced {
+		status.AccessCredentialsMessage = conditionMessage(vmi, "AccessCredentialsSynchronized")
+	}
+
+	// Extract IPs from VMI interfaces. Only the primary interface provides
+	// the VM address, so the address of a secondary NIC never becomes it;
+	// IPv6 guests often report a link-local primary address, in which case
+	// its first other usable address is taken
+	primary := primaryInterfaceName(vm)
+	interfaces, _, _ := unstructured.NestedSlice(vmi.Object, "status", "interfaces")
+	for _, iface := range interfaces {
+		ifaceMap, ok := iface.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		ip, _, _ := unstructured.NestedString(ifaceMap, "ipAddress")
+		addresses, _, _ := unstructured.NestedStringSlice(ifaceMap, "ipAddresses")
+		name, _, _ := unstructured.NestedString(ifaceMap, "name")
+		for _, addr := range append([]string{ip}, addresses...) {
+			if addr == "" || !isUsableIP(addr, c.AllowIPv6) {
 				continue
 			}
-			ip, _, _ := unstructured.NestedString(ifaceMap, "ipAddress")
-			if ip != "" && isUsableIP(ip) {
-				status.IPAddress = ip
-				mac, _, _ := unstructured.NestedString(ifaceMap, "mac")
-				status.MACAddress = mac
-				break
+			if !slices.Contains(status.IPAddresses, addr) {
+				status.IPAddresses = append(status.IPAddresses, addr)
+			}
+			if name == primary && status.IPAddress == "" {
+				status.IPAddress = addr
+				status.MACAddress, _, _ = unstructured.NestedString(ifaceMap, "mac")
 			}
 		}
 	}
@@ -370,6 +1479,113 @@ func (c *Client) GetVMStatus(ctx context.Context, name string) (*VMStatus, error
 	return status, nil
 }
 
+// primaryInterfaceName returns the name of the first interface of the VM
+// template, which buildVM makes the primary one.
+func primaryInterfaceName(vm *unstructured.Unstructured) string {
+	interfaces, _, _ := unstructured.NestedSlice(vm.Object, "spec", "template", "spec", "domain", "devices", "interfaces")
+	if len(interfaces) > 0 {
+		if iface, ok := interfaces[0].(map[string]interface{}); ok {
+			if name, _ := iface["name"].(string); name != "" {
+				return name
+			}
+		}
+	}
+	return interfaceName(0, NetworkInterface{})
+}
+
+// RefreshVMStatus re-reads the VM and VMI from the API server and, when the
+// guest agent is connected, re-derives the IP from the addresses it reports.
+// Use it when the primary address on the VMI lags a network change made
+// inside the guest.
+func (c *Client) RefreshVMStatus(ctx context.Context, name string) (*VMStatus, error) {
+	status, err := c.GetVMStatus(ctx, name)
+	if err != nil {
+		return status, err
+	}
+
+	// The dynamic client is uncached, so this reads the latest VMI
+	vmi, err := c.GetVMI(ctx, name)
+	if err != nil {
+		return status, nil
+	}
+	if !hasTrueCondition(vmi, "AgentConnected") {
+		return status, nil
+	}
+
+	interfaces, _, _ := unstructured.NestedSlice(vmi.Object, "status", "interfaces")
+	for _, iface := range interfaces {
+		ifaceMap, ok := iface.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		source, _, _ := unstructured.NestedString(ifaceMap, "infoSource")
+		if !strings.Contains(source, "guest-agent") {
+			continue
+		}
+		addresses, _, _ := unstructured.NestedStringSlice(ifaceMap, "ipAddresses")
+		for _, ip := range addresses {
+			if isUsableIP(ip, c.AllowIPv6) {
+				status.IPAddress = ip
+				status.MACAddress, _, _ = unstructured.NestedString(ifaceMap, "mac")
+				return status, nil
+			}
+		}
+	}
+	return status, nil
+}
+
+// hasTrueCondition reports whether the object has a status condition of the
+// given type with status "True".
+func hasTrueCondition(obj *unstructured.Unstructured, condType string) bool {
+	conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	for _, c := range conditions {
+		condMap, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		t, _, _ := unstructured.NestedString(condMap, "type")
+		st, _, _ := unstructured.NestedString(condMap, "status")
+		if t == condType && st == "True" {
+			return true
+		}
+	}
+	return false
+}
+
+// conditionStatus returns the status of the object's status condition of the
+// given type, or an empty string when it has none.
+func conditionStatus(obj *unstructured.Unstructured, condType string) string {
+	conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	for _, c := range conditions {
+		condMap, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if t, _, _ := unstructured.NestedString(condMap, "type"); t == condType {
+			status, _, _ := unstructured.NestedString(condMap, "status")
+			return status
+		}
+	}
+	return ""
+}
+
+// conditionMessage returns the message of the object's status condition of
+// the given type, or an empty string when it has none.
+func conditionMessage(obj *unstructured.Unstructured, condType string) string {
+	conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	for _, c := range conditions {
+		condMap, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if t, _, _ := unstructured.NestedString(condMap, "type"); t == condType {
+			message, _, _ := unstructured.NestedString(condMap, "message")
+			return message
+		}
+	}
+	return ""
+}
+
 // parseName extracts name from "namespace/name" format.
 func parseName(ref string) string {
 	for i := 0; i < len(ref); i++ {
@@ -380,15 +1596,16 @@ func parseName(ref string) string {
 	return ref
 }
 
-// isUsableIP returns true if the IP is a routable IPv4 address
-func isUsableIP(ip string) bool {
-	// Skip IPv6 (contains colons)
-	if strings.Contains(ip, ":") {
+// isUsableIP returns true if the IP is a routable IPv4 address or, with
+// allowIPv6, a routable IPv6 address. Malformed addresses are rejected.
+func isUsableIP(ip string, allowIPv6 bool) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
 		return false
 	}
-	// Skip IPv4 link-local (169.254.x.x)
-	if strings.HasPrefix(ip, "169.254.") {
+	// To4 also converts IPv4-mapped IPv6 addresses, so go by the text
+	if strings.Contains(ip, ":") && !allowIPv6 {
 		return false
 	}
-	return true
+	return !parsed.IsLinkLocalUnicast() && !parsed.IsLoopback() && !parsed.IsUnspecified() && !parsed.IsMulticast()
 }
@@ -0,0 +1,105 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package harvester
+
+import (
+	"sync"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// ClientCache holds one Client per ProviderConfig so reconciles reuse it
+// instead of parsing the kubeconfig and building API clients every time.
+// Each entry records the version of the inputs it was built from; a
+// different version, e.g. after the credentials rotate, builds a fresh
+// client. The zero value is ready to use and safe for concurrent use.
+type ClientCache struct {
+	mu      sync.Mutex
+	entries map[types.NamespacedName]*cachedClient
+}
+
+// cachedClient is guarded by the cache mutex, except for build, which
+// serializes the builds for one key without holding up the other keys.
+type cachedClient struct {
+	build   sync.Mutex
+	version string
+	client  *Client
+}
+
+// Get returns the cached client for key when it was built from version, and
+// otherwise builds one with build and caches it. Concurrent calls for the
+// same key wait for a single build; other keys are not blocked by it. A
+// failed build leaves the previous client in place for Last.
+func (c *ClientCache) Get(key types.NamespacedName, version string, build func() (*Client, error)) (*Client, error) {
+	entry, client := c.lookup(key, version)
+	if client != nil {
+		return client, nil
+	}
+	entry.build.Lock()
+	defer entry.build.Unlock()
+	// Another call may have built it while this one waited
+	if _, client := c.lookup(key, version); client != nil {
+		return client, nil
+	}
+
+	client, err := build()
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry.version, entry.client = version, client
+	return client, nil
+}
+
+// lookup returns the entry for key, adding an empty one if needed, and its
+// client when it was built from version.
+func (c *ClientCache) lookup(key types.NamespacedName, version string) (*cachedClient, *Client) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok {
+		if c.entries == nil {
+			c.entries = map[types.NamespacedName]*cachedClient{}
+		}
+		entry = &cachedClient{}
+		c.entries[key] = entry
+	}
+	if entry.client != nil && entry.version == version {
+		return entry, entry.client
+	}
+	return entry, nil
+}
+
+// Last returns the most recently built client for key regardless of its
+// version, or nil. It lets VMs be cleaned up after their ProviderConfig or
+// credentials are gone.
+func (c *ClientCache) Last(key types.NamespacedName) *Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if entry, ok := c.entries[key]; ok {
+		return entry.client
+	}
+	return nil
+}
+
+// Forget drops the client cached for key.
+func (c *ClientCache) Forget(key types.NamespacedName) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, key)
+}
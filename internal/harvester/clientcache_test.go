@@ -0,0 +1,132 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package harvester
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+var _ = Describe("Client cache", func() {
+	var (
+		cache  *ClientCache
+		key    types.NamespacedName
+		builds int
+	)
+
+	BeforeEach(func() {
+		cache = &ClientCache{}
+		key = types.NamespacedName{Namespace: "butler", Name: "harvester"}
+		builds = 0
+	})
+
+	build := func() (*Client, error) {
+		builds++
+		return newTestClient(), nil
+	}
+
+	It("reuses the client while the credentials are unchanged", func() {
+		first, err := cache.Get(key, "100", build)
+		Expect(err).NotTo(HaveOccurred())
+		second, err := cache.Get(key, "100", build)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(second).To(BeIdenticalTo(first))
+		Expect(builds).To(Equal(1))
+	})
+
+	It("builds a fresh client when the credentials secret is rotated", func() {
+		first, err := cache.Get(key, "100", build)
+		Expect(err).NotTo(HaveOccurred())
+		rotated, err := cache.Get(key, "101", build)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rotated).NotTo(BeIdenticalTo(first))
+		Expect(builds).To(Equal(2))
+		Expect(cache.Last(key)).To(BeIdenticalTo(rotated))
+	})
+
+	It("keeps the last client when a rebuild fails", func() {
+		first, err := cache.Get(key, "100", build)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = cache.Get(key, "101", func() (*Client, error) { return nil, errors.New("bad kubeconfig") })
+		Expect(err).To(MatchError("bad kubeconfig"))
+		Expect(cache.Last(key)).To(BeIdenticalTo(first))
+	})
+
+	It("forgets the client of a deleted ProviderConfig", func() {
+		_, err := cache.Get(key, "100", build)
+		Expect(err).NotTo(HaveOccurred())
+		cache.Forget(key)
+		Expect(cache.Last(key)).To(BeNil())
+		_, err = cache.Get(key, "100", build)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(builds).To(Equal(2))
+	})
+
+	It("does not hold up other ProviderConfigs while building a client", func() {
+		release := make(chan struct{})
+		started := make(chan struct{})
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+			_, err := cache.Get(key, "100", func() (*Client, error) {
+				close(started)
+				<-release
+				return newTestClient(), nil
+			})
+			Expect(err).NotTo(HaveOccurred())
+		}()
+		<-started
+
+		other := types.NamespacedName{Namespace: "butler", Name: "other"}
+		Expect(cache.Get(other, "100", build)).NotTo(BeNil())
+		Expect(cache.Last(key)).To(BeNil())
+		close(release)
+		<-done
+		Expect(cache.Last(key)).NotTo(BeNil())
+	})
+
+	It("builds a client once for concurrent callers", func() {
+		var concurrentBuilds atomic.Int32
+		var wg sync.WaitGroup
+		clients := make([]*Client, 8)
+		for i := range clients {
+			wg.Add(1)
+			go func() {
+				defer GinkgoRecover()
+				defer wg.Done()
+				var err error
+				clients[i], err = cache.Get(key, "100", func() (*Client, error) {
+					concurrentBuilds.Add(1)
+					return newTestClient(), nil
+				})
+				Expect(err).NotTo(HaveOccurred())
+			}()
+		}
+		wg.Wait()
+		Expect(concurrentBuilds.Load()).To(BeEquivalentTo(1))
+		for _, client := range clients {
+			Expect(client).To(BeIdenticalTo(clients[0]))
+		}
+	})
+})
@@ -0,0 +1,432 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package harvester
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// DefaultCloudInitPopulatorImage is the default image used to write the seed
+// image into the persistent cloud-init PVC. Any image with a POSIX shell and
+// zcat, truncate, sync, mv and touch works.
+const DefaultCloudInitPopulatorImage = "busybox:1.36"
+
+const (
+	// cloudInitPVCSize is the minimum size of the persistent cloud-init PVC.
+	cloudInitPVCSize = "1Gi"
+
+	// defaultCloudInitDiskSize is the size of the seed image in bytes when
+	// no size is requested.
+	defaultCloudInitDiskSize = 64 << 20
+	// minCloudInitDiskSize and maxCloudInitDiskSize bound the seed image
+	// size; the upper limit is the largest FAT16 volume.
+	minCloudInitDiskSize = defaultCloudInitDiskSize
+	maxCloudInitDiskSize = 2047 << 20
+
+	// cidataDiskFile is the image file KubeVirt expects on a filesystem PVC.
+	cidataDiskFile = "disk.img"
+	// cidataImageKey is the Secret key holding the gzipped seed image.
+	cidataImageKey = "disk.img.gz"
+	// cidataPopulatedFile marks a PVC whose seed image is complete, so a
+	// retried populator pod leaves it alone.
+	cidataPopulatedFile = ".populated"
+	// maxSeedSecretSize leaves headroom below the 1MiB Secret size limit.
+	maxSeedSecretSize = 1000 << 10
+	// maxSeedSecrets bounds how many Secrets a compressed seed image is
+	// split across, and so the compressed payload to about 16MiB.
+	maxSeedSecrets = 16
+	// labelCloudInitSeed names the VM whose seed image a Secret carries, so
+	// every chunk is found on cleanup.
+	labelCloudInitSeed = "harvester.butler.butlerlabs.dev/cloud-init-seed"
+)
+
+// DefaultMaxCloudInitSize is the default limit, in base64-encoded bytes, on
+// user data and network data placed inline in a NoCloud volume. KubeVirt
+// rejects larger NoCloud payloads.
+const DefaultMaxCloudInitSize = 2 << 10
+
+// checkCloudInitSize rejects user data or network data whose base64 encoding
+// exceeds the inline NoCloud limit, before Harvester rejects the VM with a
+// less helpful message. Persistent cloud-init payloads go into the seed disk
+// instead and are bounded by buildCloudInitSeed.
+func (c *Client) checkCloudInitSize(userData, networkData string) error {
+	limit := c.MaxCloudInitSize
+	if limit <= 0 {
+		limit = DefaultMaxCloudInitSize
+	}
+	for _, payload := range []struct{ name, data string }{
+		{"user data", userData},
+		{"network data", networkData},
+	} {
+		if size := base64.StdEncoding.EncodedLen(len(payload.data)); size > limit {
+			return invalidOptionsf("%s is %d bytes base64-encoded, over the %d byte NoCloud limit; "+
+				"shrink it, move it to a persistent cloud-init disk, or raise the limit on the ProviderConfig",
+				payload.name, size, limit)
+		}
+	}
+	return nil
+}
+
+// cloudInitSeed is a rendered NoCloud seed image.
+type cloudInitSeed struct {
+	// compressed is the gzipped image up to its last used cluster.
+	compressed []byte
+	// size is the full size of the image in bytes.
+	size int64
+}
+
+// cloudInitDiskSize returns the requested seed image size in bytes.
+func cloudInitDiskSize(opts VMCreateOptions) int64 {
+	if opts.CloudInitDiskSize.IsZero() {
+		return defaultCloudInitDiskSize
+	}
+	return opts.CloudInitDiskSize.Value()
+}
+
+// chunks splits the compressed image into the pieces carried by the seed
+// Secrets, in order.
+func (s *cloudInitSeed) chunks() [][]byte {
+	var chunks [][]byte
+	for data := s.compressed; len(data) > 0; {
+		n := min(len(data), maxSeedSecretSize)
+		chunks = append(chunks, data[:n])
+		data = data[n:]
+	}
+	return chunks
+}
+
+// buildCloudInitSeed renders the NoCloud seed image for a persistent
+// cloud-init disk. Payloads that do not fit the image, or whose compressed
+// image exceeds what the seed Secrets can carry to the populator, are
+// rejected.
+func buildCloudInitSeed(opts VMCreateOptions) (*cloudInitSeed, error) {
+	files := []cidataFile{
+		{name: "meta-data", shortName: "META-D~1", data: []byte(fmt.Sprintf("instance-id: %s\nlocal-hostname: %s\n", opts.Name, opts.Name))},
+		{name: "user-data", shortName: "USER-D~1", data: []byte(opts.UserData)},
+	}
+	if opts.NetworkData != "" {
+		files = append(files, cidataFile{name: "network-data", shortName: "NETWOR~1", data: []byte(opts.NetworkData)})
+	}
+
+	geometry := newFATGeometry(cloudInitDiskSize(opts))
+	image, err := buildCIDataImage(geometry, files)
+	if err != nil {
+		return nil, invalidOptionsf("cloud-init payload does not fit a %dMi seed disk: %v", geometry.size()>>20, err)
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(image); err != nil {
+		return nil, fmt.Errorf("failed to compress cloud-init seed image: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress cloud-init seed image: %w", err)
+	}
+	if limit := maxSeedSecretSize * maxSeedSecrets; buf.Len() > limit {
+		return nil, invalidOptionsf("cloud-init payload compresses to %d bytes, more than the %d bytes the seed Secrets can carry",
+			buf.Len(), limit)
+	}
+	return &cloudInitSeed{compressed: buf.Bytes(), size: geometry.size()}, nil
+}
+
+// cloudInitPVCQuantity returns the PVC size for a seed image of size bytes,
+// leaving room for filesystem overhead on large images.
+func cloudInitPVCQuantity(size int64) resource.Quantity {
+	q := resource.MustParse(cloudInitPVCSize)
+	if needed := size + size/4; needed > q.Value() {
+		return *resource.NewQuantity((needed+(1<<20)-1)>>20<<20, resource.BinarySI)
+	}
+	return q
+}
+
+// populatorScript returns the populator Job's shell script, which joins the
+// chunks mounted under /seed/<index>. The image is written to a temporary file
+// and renamed into place, and the PVC marked populated, so a retried or re-run
+// pod neither rewrites a complete image nor leaves a truncated one behind.
+func populatorScript(size int64, chunks int) string {
+	parts := make([]string, chunks)
+	for i := range parts {
+		parts[i] = fmt.Sprintf("/seed/%d/%s", i, cidataImageKey)
+	}
+	return fmt.Sprintf(
+		"[ -f /disk/%[4]s ] && exit 0; "+
+			"cat %[1]s | zcat > /disk/%[2]s.tmp && truncate -s %[3]d /disk/%[2]s.tmp && sync && "+
+			"mv /disk/%[2]s.tmp /disk/%[2]s && touch /disk/%[4]s && sync",
+		strings.Join(parts, " "), cidataDiskFile, size, cidataPopulatedFile)
+}
+
+// seedSecretName returns the name of the Secret carrying chunk index of a
+// seed image. The first chunk keeps the PVC's name.
+func seedSecretName(name string, index int) string {
+	if index == 0 {
+		return name
+	}
+	return fmt.Sprintf("%s-%d", name, index)
+}
+
+// populatorImage returns the image of the populator Job.
+func (c *Client) populatorImage() string {
+	if c.CloudInitPopulatorImage != "" {
+		return c.CloudInitPopulatorImage
+	}
+	return DefaultCloudInitPopulatorImage
+}
+
+// cloudInitPVCName returns the name of the persistent cloud-init PVC for a VM.
+// The populator Job and first seed Secret share the same name.
+func cloudInitPVCName(vmName string) string {
+	return vmName + "-cloudinit"
+}
+
+// createPersistentCloudInit creates the PVC backing a persistent cloud-init
+// disk along with the Secrets and Job that populate it with a NoCloud seed
+// image. The VM must not start until the Job succeeds.
+func (c *Client) createPersistentCloudInit(ctx context.Context, opts VMCreateOptions, seed *cloudInitSeed) error {
+	name := cloudInitPVCName(opts.Name)
+
+	labels := map[string]string{
+		LabelManagedBy: managedByValue,
+	}
+
+	chunks := seed.chunks()
+	seedVolumes := make([]corev1.Volume, len(chunks))
+	seedMounts := make([]corev1.VolumeMount, len(chunks))
+	for i, chunk := range chunks {
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      seedSecretName(name, i),
+				Namespace: c.namespace,
+				Labels:    map[string]string{LabelManagedBy: managedByValue, labelCloudInitSeed: opts.Name},
+			},
+			Data: map[string][]byte{cidataImageKey: chunk},
+		}
+		if _, err := retryCreate(ctx, c, func(ctx context.Context) (*corev1.Secret, error) {
+			return c.clientset.CoreV1().Secrets(c.namespace).Create(ctx, secret, metav1.CreateOptions{})
+		}, func(ctx context.Context) (*corev1.Secret, error) {
+			return c.clientset.CoreV1().Secrets(c.namespace).Get(ctx, secret.GetName(), metav1.GetOptions{})
+		}); err != nil {
+			if i > 0 {
+				c.deletePersistentCloudInit(ctx, opts.Name)
+			}
+			return fmt.Errorf("failed to create cloud-init secret: %w", err)
+		}
+		volume := fmt.Sprintf("seed-%d", i)
+		seedVolumes[i] = corev1.Volume{Name: volume, VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: secret.Name}}}
+		seedMounts[i] = corev1.VolumeMount{Name: volume, MountPath: fmt.Sprintf("/seed/%d", i), ReadOnly: true}
+	}
+
+	fsMode := corev1.PersistentVolumeFilesystem
+	pvc := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: c.namespace, Labels: labels},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+			VolumeMode:  &fsMode,
+			Resources: corev1.VolumeResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceStorage: cloudInitPVCQuantity(seed.size),
+				},
+			},
+		},
+	}
+	if c.config.StorageClassName != "" {
+		pvc.Spec.StorageClassName = &c.config.StorageClassName
+	}
+	if _, err := retryCreate(ctx, c, func(ctx context.Context) (*corev1.PersistentVolumeClaim, error) {
+		return c.clientset.CoreV1().PersistentVolumeClaims(c.namespace).Create(ctx, pvc, metav1.CreateOptions{})
+	}, func(ctx context.Context) (*corev1.PersistentVolumeClaim, error) {
+		return c.clientset.CoreV1().PersistentVolumeClaims(c.namespace).Get(ctx, pvc.GetName(), metav1.GetOptions{})
+	}); err != nil {
+		c.deletePersistentCloudInit(ctx, opts.Name)
+		return fmt.Errorf("failed to create cloud-init PVC: %w", err)
+	}
+
+	backoff := int32(3)
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: c.namespace, Labels: labels},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backoff,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec: corev1.PodSpec{
+					RestartPolicy: corev1.RestartPolicyNever,
+					Containers: []corev1.Container{{
+						Name:    "populate",
+						Image:   c.populatorImage(),
+						Command: []string{"sh", "-c", populatorScript(seed.size, len(chunks))},
+						VolumeMounts: append(seedMounts,
+							corev1.VolumeMount{Name: "disk", MountPath: "/disk"}),
+					}},
+					Volumes: append(seedVolumes,
+						corev1.Volume{Name: "disk", VolumeSource: corev1.VolumeSource{PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: name}}}),
+				},
+			},
+		},
+	}
+	if _, err := retryCreate(ctx, c, func(ctx context.Context) (*batchv1.Job, error) {
+		return c.clientset.BatchV1().Jobs(c.namespace).Create(ctx, job, metav1.CreateOptions{})
+	}, func(ctx context.Context) (*batchv1.Job, error) {
+		return c.clientset.BatchV1().Jobs(c.namespace).Get(ctx, job.GetName(), metav1.GetOptions{})
+	}); err != nil {
+		c.deletePersistentCloudInit(ctx, opts.Name)
+		return fmt.Errorf("failed to create cloud-init populator job: %w", err)
+	}
+
+	return nil
+}
+
+// deletePersistentCloudInit removes the persistent cloud-init PVC, seed
+// Secrets and populator Job for a VM. Missing resources are ignored.
+func (c *Client) deletePersistentCloudInit(ctx context.Context, vmName string) {
+	name := cloudInitPVCName(vmName)
+	propagation := metav1.DeletePropagationBackground
+	_ = c.retry(ctx, func(ctx context.Context) error {
+		return c.clientset.BatchV1().Jobs(c.namespace).Delete(ctx, name, metav1.DeleteOptions{PropagationPolicy: &propagation})
+	})
+	c.deleteSeedSecrets(ctx, vmName)
+	_ = c.deletePVC(ctx, name)
+}
+
+// deleteSeedSecrets removes the Secrets carrying a VM's seed image. Secrets
+// from before the seed was split carry no labelCloudInitSeed and are
+// deleted by name.
+func (c *Client) deleteSeedSecrets(ctx context.Context, vmName string) {
+	names := map[string]bool{cloudInitPVCName(vmName): true}
+	secrets, err := retryResult(ctx, c, func(ctx context.Context) (*corev1.SecretList, error) {
+		return c.clientset.CoreV1().Secrets(c.namespace).List(ctx, metav1.ListOptions{
+			LabelSelector: labelCloudInitSeed + "=" + vmName,
+		})
+	})
+	if err == nil {
+		for _, secret := range secrets.Items {
+			names[secret.Name] = true
+		}
+	}
+	for name := range names {
+		_ = c.retry(ctx, func(ctx context.Context) error {
+			return c.clientset.CoreV1().Secrets(c.namespace).Delete(ctx, name, metav1.DeleteOptions{})
+		})
+	}
+}
+
+// UpdateCloudInitUserData replaces the NoCloud user data of an existing VM,
+// merging in sshPublicKeys as CreateVM does. The change takes effect the next
+// time the VMI starts; VMs with a persistent cloud-init disk are not supported.
+func (c *Client) UpdateCloudInitUserData(ctx context.Context, name, userData string, sshPublicKeys []string) error {
+	userData, err := mergeSSHKeys(userData, sshPublicKeys)
+	if err != nil {
+		return err
+	}
+	if err := c.checkCloudInitSize(userData, ""); err != nil {
+		return err
+	}
+	vm, err := c.GetVM(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	volumes, _, _ := unstructured.NestedSlice(vm.Object, "spec", "template", "spec", "volumes")
+	updated := false
+	for _, v := range volumes {
+		volume, ok := v.(map[string]interface{})
+		if !ok || volume["name"] != "cloudinit" {
+			continue
+		}
+		noCloud, ok := volume["cloudInitNoCloud"].(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("VM %s has no NoCloud cloud-init volume", name)
+		}
+		noCloud["userDataBase64"] = base64.StdEncoding.EncodeToString([]byte(userData))
+		updated = true
+	}
+	if !updated {
+		return fmt.Errorf("VM %s has no cloud-init volume", name)
+	}
+	if err := unstructured.SetNestedSlice(vm.Object, volumes, "spec", "template", "spec", "volumes"); err != nil {
+		return err
+	}
+
+	_, err = retryResult(ctx, c, func(ctx context.Context) (*unstructured.Unstructured, error) {
+		return c.dynamic.Resource(vmGVR).Namespace(c.namespace).Update(ctx, vm, metav1.UpdateOptions{})
+	})
+	return err
+}
+
+// EnsureCloudInitPopulated starts a VM with a persistent cloud-init disk once
+// its populator Job has succeeded. It returns true when the VM has no pending
+// cloud-init population, and an error if population failed.
+func (c *Client) EnsureCloudInitPopulated(ctx context.Context, name string) (bool, error) {
+	vm, err := c.GetVM(ctx, name)
+	if err != nil {
+		return false, err
+	}
+	if vm.GetAnnotations()[AnnotationPersistentCloudInit] != "pending" {
+		return true, nil
+	}
+
+	job, err := retryResult(ctx, c, func(ctx context.Context) (*batchv1.Job, error) {
+		return c.clientset.BatchV1().Jobs(c.namespace).Get(ctx, cloudInitPVCName(name), metav1.GetOptions{})
+	})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, fmt.Errorf("cloud-init populator job %s not found", cloudInitPVCName(name))
+		}
+		return false, err
+	}
+	for _, cond := range job.Status.Conditions {
+		if cond.Type == batchv1.JobFailed && cond.Status == corev1.ConditionTrue {
+			return false, fmt.Errorf("cloud-init populator job failed: %s", cond.Message)
+		}
+	}
+	if job.Status.Succeeded == 0 {
+		return false, nil
+	}
+
+	if err := c.patchRunStrategy(ctx, name, RunStrategyAlways, map[string]string{AnnotationPersistentCloudInit: "done"}); err != nil {
+		return false, fmt.Errorf("failed to start VM after cloud-init population: %w", err)
+	}
+
+	// The populator is no longer needed once the VM owns the disk
+	propagation := metav1.DeletePropagationBackground
+	_ = c.retry(ctx, func(ctx context.Context) error {
+		return c.clientset.BatchV1().Jobs(c.namespace).Delete(ctx, job.Name, metav1.DeleteOptions{PropagationPolicy: &propagation})
+	})
+	c.deleteSeedSecrets(ctx, name)
+
+	return true, nil
+}
+
+// persistentCloudInitVolume returns the VM volume for a persistent cloud-init disk.
+func persistentCloudInitVolume(vmName string) map[string]interface{} {
+	return map[string]interface{}{
+		"name": "cloudinit",
+		"persistentVolumeClaim": map[string]interface{}{
+			"claimName": cloudInitPVCName(vmName),
+		},
+	}
+}
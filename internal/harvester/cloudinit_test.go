@@ -0,0 +1,163 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package harvester
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"math/rand"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+var _ = Describe("Persistent cloud-init populator", func() {
+	var ctx context.Context
+
+	BeforeEach(func() {
+		ctx = context.Background()
+	})
+
+	It("runs the default image idempotently", func() {
+		c := newTestClient()
+		opts := testCreateOptions()
+		seed, err := buildCloudInitSeed(opts)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(c.createPersistentCloudInit(ctx, opts, seed)).To(Succeed())
+
+		job, err := c.clientset.BatchV1().Jobs(testNamespace).Get(ctx, cloudInitPVCName(opts.Name), metav1.GetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		container := job.Spec.Template.Spec.Containers[0]
+		Expect(container.Image).To(Equal(DefaultCloudInitPopulatorImage))
+		Expect(container.Command).To(Equal([]string{"sh", "-c",
+			"[ -f /disk/.populated ] && exit 0; " +
+				"cat /seed/0/disk.img.gz | zcat > /disk/disk.img.tmp && truncate -s 67108864 /disk/disk.img.tmp && sync && " +
+				"mv /disk/disk.img.tmp /disk/disk.img && touch /disk/.populated && sync"}))
+	})
+
+	It("uses the configured image", func() {
+		c := newTestClient()
+		c.CloudInitPopulatorImage = "registry.local/busybox:1.36"
+		opts := testCreateOptions()
+		seed, err := buildCloudInitSeed(opts)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(c.createPersistentCloudInit(ctx, opts, seed)).To(Succeed())
+
+		job, err := c.clientset.BatchV1().Jobs(testNamespace).Get(ctx, cloudInitPVCName(opts.Name), metav1.GetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(job.Spec.Template.Spec.Containers[0].Image).To(Equal("registry.local/busybox:1.36"))
+	})
+
+	It("splits a seed larger than one Secret across several", func() {
+		c := newTestClient()
+		opts := testCreateOptions()
+		// Random user data does not compress, so the seed needs three Secrets
+		payload := make([]byte, 2*maxSeedSecretSize+maxSeedSecretSize/2)
+		rand.New(rand.NewSource(1)).Read(payload)
+		opts.UserData = string(payload)
+		seed, err := buildCloudInitSeed(opts)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(c.createPersistentCloudInit(ctx, opts, seed)).To(Succeed())
+
+		var joined []byte
+		for _, name := range []string{"worker-0-cloudinit", "worker-0-cloudinit-1", "worker-0-cloudinit-2"} {
+			secret, err := c.clientset.CoreV1().Secrets(testNamespace).Get(ctx, name, metav1.GetOptions{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(secret.Labels).To(HaveKeyWithValue(labelCloudInitSeed, "worker-0"))
+			joined = append(joined, secret.Data[cidataImageKey]...)
+		}
+		Expect(bytes.Equal(joined, seed.compressed)).To(BeTrue())
+
+		job, err := c.clientset.BatchV1().Jobs(testNamespace).Get(ctx, cloudInitPVCName(opts.Name), metav1.GetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(job.Spec.Template.Spec.Volumes).To(HaveLen(4))
+		Expect(job.Spec.Template.Spec.Containers[0].Command[2]).To(ContainSubstring(
+			"cat /seed/0/disk.img.gz /seed/1/disk.img.gz /seed/2/disk.img.gz | zcat"))
+
+		c.deletePersistentCloudInit(ctx, opts.Name)
+		secrets, err := c.clientset.CoreV1().Secrets(testNamespace).List(ctx, metav1.ListOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(secrets.Items).To(BeEmpty())
+	})
+
+	It("rejects a seed larger than the seed Secrets can carry", func() {
+		opts := testCreateOptions()
+		payload := make([]byte, maxSeedSecrets*maxSeedSecretSize+1)
+		rand.New(rand.NewSource(1)).Read(payload)
+		opts.UserData = string(payload)
+		_, err := buildCloudInitSeed(opts)
+		Expect(err).To(MatchError(ContainSubstring("more than the 16384000 bytes the seed Secrets can carry")))
+	})
+})
+
+var _ = Describe("NoCloud user data update", func() {
+	var (
+		ctx context.Context
+		c   *Client
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		c = newTestClient()
+		opts := testCreateOptions()
+		opts.UserData = "#cloud-config\nhostname: worker-0\n"
+		_, _, err := c.CreateVM(ctx, opts)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	noCloudUserData := func() string {
+		vm, err := c.GetVM(ctx, "worker-0")
+		Expect(err).NotTo(HaveOccurred())
+		volumes, _, _ := unstructured.NestedSlice(vm.Object, "spec", "template", "spec", "volumes")
+		for _, v := range volumes {
+			if volume := v.(map[string]interface{}); volume["name"] == "cloudinit" {
+				encoded, _, _ := unstructured.NestedString(volume, "cloudInitNoCloud", "userDataBase64")
+				decoded, err := base64.StdEncoding.DecodeString(encoded)
+				Expect(err).NotTo(HaveOccurred())
+				return string(decoded)
+			}
+		}
+		return ""
+	}
+
+	It("replaces the user data and merges the SSH keys", func() {
+		Expect(c.UpdateCloudInitUserData(ctx, "worker-0", "#cloud-config\nhostname: worker-0-renamed\n",
+			[]string{"ssh-ed25519 AAAA ops"})).To(Succeed())
+		userData := noCloudUserData()
+		Expect(userData).To(ContainSubstring("hostname: worker-0-renamed"))
+		Expect(userData).To(ContainSubstring("ssh-ed25519 AAAA ops"))
+	})
+
+	It("rejects SSH keys with user data that is not cloud-config", func() {
+		err := c.UpdateCloudInitUserData(ctx, "worker-0", "#!/bin/sh\necho hi\n", []string{"ssh-ed25519 AAAA ops"})
+		Expect(err).To(MatchError(ErrInvalidOptions))
+		Expect(noCloudUserData()).To(ContainSubstring("hostname: worker-0\n"))
+	})
+
+	It("fails for a VM without a cloud-init volume", func() {
+		vm, err := c.GetVM(ctx, "worker-0")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(unstructured.SetNestedSlice(vm.Object, []interface{}{}, "spec", "template", "spec", "volumes")).To(Succeed())
+		_, err = c.dynamic.Resource(vmGVR).Namespace(testNamespace).Update(ctx, vm, metav1.UpdateOptions{})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(c.UpdateCloudInitUserData(ctx, "worker-0", "#cloud-config\n", nil)).To(MatchError(ContainSubstring("has no cloud-init volume")))
+	})
+})
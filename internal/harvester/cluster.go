@@ -0,0 +1,255 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package harvester
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var (
+	kubevirtGVR = schema.GroupVersionResource{
+		Group:    "kubevirt.io",
+		Version:  "v1",
+		Resource: "kubevirts",
+	}
+
+	settingGVR = schema.GroupVersionResource{
+		Group:    "harvesterhci.io",
+		Version:  "v1beta1",
+		Resource: "settings",
+	}
+)
+
+const (
+	// kubevirtNamespace is where Harvester installs the KubeVirt CR.
+	kubevirtNamespace = "harvester-system"
+	// serverVersionSetting is the Harvester setting holding the server version.
+	serverVersionSetting = "server-version"
+	// clusterInfoTTL is how long GetClusterInfo reuses a detection, so
+	// upgrades and configuration changes are noticed by long-lived clients.
+	clusterInfoTTL = 10 * time.Minute
+)
+
+// ClusterInfo describes the versions and enabled KubeVirt feature gates of
+// the target Harvester cluster.
+type ClusterInfo struct {
+	HarvesterVersion string
+	KubeVirtVersion  string
+	FeatureGates     []string
+	// DefaultRuntimeClass is the runtime class KubeVirt sets on every
+	// virt-launcher pod. Empty means the cluster default runtime.
+	DefaultRuntimeClass string
+	// SMBIOSManufacturer and SMBIOSProduct are the SMBIOS system fields
+	// KubeVirt presents to every guest. Empty means the KubeVirt defaults.
+	SMBIOSManufacturer string
+	SMBIOSProduct      string
+	// PermittedHostDevices lists the PCI and mediated device resources VMs
+	// may request. Nil means KubeVirt does not restrict host devices.
+	PermittedHostDevices []string
+	// VMRolloutStrategy is how KubeVirt applies VM template changes to
+	// running instances, "LiveUpdate" or "Stage". Empty means unset.
+	VMRolloutStrategy string
+	// HugepagesPageSizes lists the hugepages sizes (e.g. "2Mi") a ready
+	// schedulable node has preallocated. Nil means the nodes were not
+	// readable.
+	HugepagesPageSizes []string
+}
+
+// LiveUpdates reports whether KubeVirt hotplugs CPU and memory changes into
+// running guests. Older KubeVirt releases without a rollout strategy only do
+// so with the VMLiveUpdateFeatures gate.
+func (i *ClusterInfo) LiveUpdates() bool {
+	if i.VMRolloutStrategy != "" {
+		return i.VMRolloutStrategy == "LiveUpdate"
+	}
+	return i.HasFeatureGate("VMLiveUpdateFeatures")
+}
+
+// HasFeatureGate reports whether the KubeVirt feature gate is enabled.
+func (i *ClusterInfo) HasFeatureGate(gate string) bool {
+	return slices.Contains(i.FeatureGates, gate)
+}
+
+// featureGateRequirement ties a VM option to the KubeVirt feature gate it needs.
+type featureGateRequirement struct {
+	feature string
+	gate    string
+	enabled func(opts VMCreateOptions) bool
+}
+
+// featureGateRequirements lists options that only work when the target
+// cluster has the corresponding KubeVirt feature gate enabled.
+var featureGateRequirements = []featureGateRequirement{
+	{
+		feature: "dedicated CPU placement",
+		gate:    "CPUManager",
+		enabled: func(opts VMCreateOptions) bool { return opts.DedicatedCPUPlacement },
+	},
+	{
+		feature: "guest NUMA topology",
+		gate:    "NUMA",
+		enabled: func(opts VMCreateOptions) bool { return len(opts.NUMACells) > 0 },
+	},
+}
+
+// UnsupportedFeatures returns a description of each requested option whose
+// KubeVirt feature gate is not enabled on the cluster.
+func (i *ClusterInfo) UnsupportedFeatures(opts VMCreateOptions) []string {
+	var unsupported []string
+	for _, req := range featureGateRequirements {
+		if req.enabled(opts) && !i.HasFeatureGate(req.gate) {
+			unsupported = append(unsupported, fmt.Sprintf("%s (feature gate %s)", req.feature, req.gate))
+		}
+	}
+	if size := opts.HugepagesPageSize; size != "" && i.HugepagesPageSizes != nil && !slices.Contains(i.HugepagesPageSizes, size) {
+		unsupported = append(unsupported, fmt.Sprintf("%s hugepages (no ready node has %s%s allocatable)",
+			size, corev1.ResourceHugePagesPrefix, size))
+	}
+	return unsupported
+}
+
+// checkRuntimeClass verifies the virt-launcher pod will run with the requested
+// runtime class. KubeVirt has no per-VM runtimeClassName; the runtime class is
+// set cluster-wide through the KubeVirt CR, so a mismatch cannot be fixed by
+// the VM spec and is rejected rather than launching under another runtime.
+func (c *Client) checkRuntimeClass(ctx context.Context, runtimeClass string) error {
+	info, err := c.GetClusterInfo(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to verify runtime class %s: %w", runtimeClass, err)
+	}
+	if info.DefaultRuntimeClass != runtimeClass {
+		return invalidOptionsf("runtime class %q requested but KubeVirt launches VMs with %q (spec.configuration.defaultRuntimeClass)",
+			runtimeClass, info.DefaultRuntimeClass)
+	}
+	return nil
+}
+
+// checkSMBIOS verifies the guest will see the requested SMBIOS manufacturer
+// and product. Like the runtime class, KubeVirt only sets these cluster-wide
+// through the KubeVirt CR, so a mismatch is rejected.
+func (c *Client) checkSMBIOS(ctx context.Context, opts VMCreateOptions) error {
+	info, err := c.GetClusterInfo(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to verify SMBIOS configuration: %w", err)
+	}
+	if opts.SMBIOSManufacturer != "" && info.SMBIOSManufacturer != opts.SMBIOSManufacturer {
+		return invalidOptionsf("SMBIOS manufacturer %q requested but KubeVirt presents %q (spec.configuration.smbios.manufacturer)",
+			opts.SMBIOSManufacturer, info.SMBIOSManufacturer)
+	}
+	if opts.SMBIOSProduct != "" && info.SMBIOSProduct != opts.SMBIOSProduct {
+		return invalidOptionsf("SMBIOS product %q requested but KubeVirt presents %q (spec.configuration.smbios.product)",
+			opts.SMBIOSProduct, info.SMBIOSProduct)
+	}
+	return nil
+}
+
+// GetClusterInfo detects the Harvester and KubeVirt versions, the enabled
+// KubeVirt feature gates and the hugepages the nodes offer. The result is
+// cached for clusterInfoTTL.
+func (c *Client) GetClusterInfo(ctx context.Context) (*ClusterInfo, error) {
+	c.clusterInfoMu.Lock()
+	defer c.clusterInfoMu.Unlock()
+	if c.clusterInfo != nil && time.Since(c.clusterInfoAt) < clusterInfoTTL {
+		return c.clusterInfo, nil
+	}
+
+	kubevirts, err := retryResult(ctx, c, func(ctx context.Context) (*unstructured.UnstructuredList, error) {
+		return c.dynamic.Resource(kubevirtGVR).Namespace(kubevirtNamespace).List(ctx, metav1.ListOptions{})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list KubeVirt resources: %w", err)
+	}
+	if len(kubevirts.Items) == 0 {
+		return nil, fmt.Errorf("no KubeVirt resource found in namespace %s", kubevirtNamespace)
+	}
+
+	kv := kubevirts.Items[0]
+	info := &ClusterInfo{}
+	info.KubeVirtVersion, _, _ = unstructured.NestedString(kv.Object, "status", "observedKubeVirtVersion")
+	info.FeatureGates, _, _ = unstructured.NestedStringSlice(kv.Object,
+		"spec", "configuration", "developerConfiguration", "featureGates")
+	info.DefaultRuntimeClass, _, _ = unstructured.NestedString(kv.Object,
+		"spec", "configuration", "defaultRuntimeClass")
+	info.SMBIOSManufacturer, _, _ = unstructured.NestedString(kv.Object,
+		"spec", "configuration", "smbios", "manufacturer")
+	info.SMBIOSProduct, _, _ = unstructured.NestedString(kv.Object,
+		"spec", "configuration", "smbios", "product")
+	info.VMRolloutStrategy, _, _ = unstructured.NestedString(kv.Object,
+		"spec", "configuration", "vmRolloutStrategy")
+	if permitted, ok, _ := unstructured.NestedMap(kv.Object, "spec", "configuration", "permittedHostDevices"); ok {
+		info.PermittedHostDevices = []string{}
+		for _, kind := range []string{"pciHostDevices", "mediatedDevices"} {
+			devices, _, _ := unstructured.NestedSlice(permitted, kind)
+			for _, d := range devices {
+				if device, ok := d.(map[string]interface{}); ok {
+					if name, _ := device["resourceName"].(string); name != "" {
+						info.PermittedHostDevices = append(info.PermittedHostDevices, name)
+					}
+				}
+			}
+		}
+	}
+
+	// The Harvester version is informational; clusters running plain KubeVirt
+	// have no Harvester settings.
+	if setting, err := retryResult(ctx, c, func(ctx context.Context) (*unstructured.Unstructured, error) {
+		return c.dynamic.Resource(settingGVR).Get(ctx, serverVersionSetting, metav1.GetOptions{})
+	}); err == nil {
+		info.HarvesterVersion, _, _ = unstructured.NestedString(setting.Object, "value")
+	}
+
+	// Hugepages are best-effort too; nil leaves them unchecked
+	if nodes, err := retryResult(ctx, c, func(ctx context.Context) (*corev1.NodeList, error) {
+		return c.clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{
+			LabelSelector:   labelKubeVirtSchedulable + "=true",
+			ResourceVersion: "0",
+		})
+	}); err == nil {
+		info.HugepagesPageSizes = hugepagesPageSizes(nodes.Items)
+	}
+
+	c.clusterInfo = info
+	c.clusterInfoAt = time.Now()
+	return info, nil
+}
+
+// hugepagesPageSizes returns the hugepages sizes preallocated on a ready node.
+func hugepagesPageSizes(nodes []corev1.Node) []string {
+	sizes := []string{}
+	for i := range nodes {
+		if !nodeReady(&nodes[i]) {
+			continue
+		}
+		for name, quantity := range nodes[i].Status.Allocatable {
+			size, ok := strings.CutPrefix(string(name), corev1.ResourceHugePagesPrefix)
+			if ok && quantity.Sign() > 0 && !slices.Contains(sizes, size) {
+				sizes = append(sizes, size)
+			}
+		}
+	}
+	slices.Sort(sizes)
+	return sizes
+}
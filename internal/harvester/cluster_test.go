@@ -0,0 +1,220 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package harvester
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+// addKubeVirt registers the KubeVirt CR Harvester installs, configured by
+// the given spec.configuration.
+func addKubeVirt(c *Client, configuration map[string]interface{}) {
+	kv := &unstructured.Unstructured{}
+	kv.SetAPIVersion("kubevirt.io/v1")
+	kv.SetKind("KubeVirt")
+	kv.SetNamespace(kubevirtNamespace)
+	kv.SetName("kubevirt")
+	Expect(unstructured.SetNestedMap(kv.Object, configuration, "spec", "configuration")).To(Succeed())
+	Expect(unstructured.SetNestedField(kv.Object, "v1.1.0", "status", "observedKubeVirtVersion")).To(Succeed())
+	Expect(c.dynamic.(*dynamicfake.FakeDynamicClient).Tracker().Create(kubevirtGVR, kv, kubevirtNamespace)).To(Succeed())
+}
+
+// setClusterInfo caches info as if it had just been detected.
+func setClusterInfo(c *Client, info *ClusterInfo) {
+	c.clusterInfo = info
+	c.clusterInfoAt = time.Now()
+}
+
+var _ = Describe("Cluster info", func() {
+	var (
+		ctx context.Context
+		c   *Client
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		c = newTestClient()
+	})
+
+	It("reads versions and configuration from the KubeVirt CR", func() {
+		addKubeVirt(c, map[string]interface{}{
+			"developerConfiguration": map[string]interface{}{
+				"featureGates": []interface{}{"CPUManager", "LiveMigration"},
+			},
+			"defaultRuntimeClass": "kata",
+			"smbios": map[string]interface{}{
+				"manufacturer": "Butler",
+				"product":      "Harvester VM",
+			},
+			"permittedHostDevices": map[string]interface{}{
+				"pciHostDevices":  []interface{}{map[string]interface{}{"resourceName": "nvidia.com/A100"}},
+				"mediatedDevices": []interface{}{map[string]interface{}{"resourceName": "nvidia.com/GRID_T4-4Q"}},
+			},
+		})
+		setting := &unstructured.Unstructured{}
+		setting.SetAPIVersion("harvesterhci.io/v1beta1")
+		setting.SetKind("Setting")
+		setting.SetName(serverVersionSetting)
+		setting.Object["value"] = "v1.3.1"
+		Expect(c.dynamic.(*dynamicfake.FakeDynamicClient).Tracker().Create(settingGVR, setting, "")).To(Succeed())
+
+		info, err := c.GetClusterInfo(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.HarvesterVersion).To(Equal("v1.3.1"))
+		Expect(info.KubeVirtVersion).To(Equal("v1.1.0"))
+		Expect(info.FeatureGates).To(ConsistOf("CPUManager", "LiveMigration"))
+		Expect(info.DefaultRuntimeClass).To(Equal("kata"))
+		Expect(info.SMBIOSManufacturer).To(Equal("Butler"))
+		Expect(info.SMBIOSProduct).To(Equal("Harvester VM"))
+		Expect(info.PermittedHostDevices).To(ConsistOf("nvidia.com/A100", "nvidia.com/GRID_T4-4Q"))
+	})
+
+	It("leaves the Harvester version and host device list unset on plain KubeVirt", func() {
+		addKubeVirt(c, map[string]interface{}{})
+
+		info, err := c.GetClusterInfo(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.HarvesterVersion).To(BeEmpty())
+		Expect(info.PermittedHostDevices).To(BeNil())
+	})
+
+	It("caches the result until it expires", func() {
+		addKubeVirt(c, map[string]interface{}{})
+		first, err := c.GetClusterInfo(ctx)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(c.dynamic.Resource(kubevirtGVR).Namespace(kubevirtNamespace).
+			Delete(ctx, "kubevirt", metav1.DeleteOptions{})).To(Succeed())
+		second, err := c.GetClusterInfo(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(second).To(BeIdenticalTo(first))
+
+		c.clusterInfoAt = time.Now().Add(-clusterInfoTTL)
+		_, err = c.GetClusterInfo(ctx)
+		Expect(err).To(MatchError(ContainSubstring("no KubeVirt resource found")))
+	})
+
+	It("detects the hugepages ready schedulable nodes offer", func() {
+		node := func(name string, ready corev1.ConditionStatus, allocatable corev1.ResourceList) *corev1.Node {
+			return &corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{labelKubeVirtSchedulable: "true"}},
+				Status: corev1.NodeStatus{
+					Allocatable: allocatable,
+					Conditions:  []corev1.NodeCondition{{Type: corev1.NodeReady, Status: ready}},
+				},
+			}
+		}
+		c = newTestClient(
+			node("node-1", corev1.ConditionTrue, corev1.ResourceList{
+				"hugepages-2Mi": resource.MustParse("4Gi"),
+				"hugepages-1Gi": resource.MustParse("0"),
+			}),
+			node("node-2", corev1.ConditionFalse, corev1.ResourceList{"hugepages-1Gi": resource.MustParse("8Gi")}),
+		)
+		addKubeVirt(c, map[string]interface{}{})
+
+		info, err := c.GetClusterInfo(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.HugepagesPageSizes).To(Equal([]string{"2Mi"}))
+	})
+
+	DescribeTable("reports whether KubeVirt live-updates running VMs",
+		func(strategy string, gates []string, live bool) {
+			info := &ClusterInfo{VMRolloutStrategy: strategy, FeatureGates: gates}
+			Expect(info.LiveUpdates()).To(Equal(live))
+		},
+		Entry("LiveUpdate strategy", "LiveUpdate", nil, true),
+		Entry("Stage strategy", "Stage", []string{"VMLiveUpdateFeatures"}, false),
+		Entry("no strategy with the gate", "", []string{"VMLiveUpdateFeatures"}, true),
+		Entry("no strategy without the gate", "", nil, false),
+	)
+
+	It("fails without a KubeVirt CR and retries on the next call", func() {
+		_, err := c.GetClusterInfo(ctx)
+		Expect(err).To(MatchError(ContainSubstring("no KubeVirt resource found")))
+
+		addKubeVirt(c, map[string]interface{}{})
+		_, err = c.GetClusterInfo(ctx)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	DescribeTable("reports requested features whose gate is disabled",
+		func(gates []string, opts func(*VMCreateOptions), expected []string) {
+			info := &ClusterInfo{FeatureGates: gates}
+			o := testCreateOptions()
+			opts(&o)
+			Expect(info.UnsupportedFeatures(o)).To(Equal(expected))
+		},
+		Entry("nothing requested", nil, func(*VMCreateOptions) {}, nil),
+		Entry("dedicated CPUs without CPUManager", []string{"LiveMigration"},
+			func(o *VMCreateOptions) { o.DedicatedCPUPlacement = true },
+			[]string{"dedicated CPU placement (feature gate CPUManager)"}),
+		Entry("dedicated CPUs with CPUManager", []string{"CPUManager"},
+			func(o *VMCreateOptions) { o.DedicatedCPUPlacement = true }, nil),
+		Entry("both without gates", nil,
+			func(o *VMCreateOptions) {
+				o.DedicatedCPUPlacement = true
+				o.NUMACells = []NUMACell{{CPUs: 2, MemoryMB: 4096}}
+			},
+			[]string{"dedicated CPU placement (feature gate CPUManager)", "guest NUMA topology (feature gate NUMA)"}),
+	)
+
+	DescribeTable("reports requested hugepages no node offers",
+		func(sizes []string, expected []string) {
+			info := &ClusterInfo{HugepagesPageSizes: sizes}
+			o := testCreateOptions()
+			o.HugepagesPageSize = "1Gi"
+			Expect(info.UnsupportedFeatures(o)).To(Equal(expected))
+		},
+		Entry("offered", []string{"1Gi", "2Mi"}, nil),
+		Entry("another size offered", []string{"2Mi"}, []string{"1Gi hugepages (no ready node has hugepages-1Gi allocatable)"}),
+		Entry("nodes not readable", nil, nil),
+	)
+
+	DescribeTable("checks the cluster-wide launcher settings before creating a VM",
+		func(opts func(*VMCreateOptions), expected string) {
+			setClusterInfo(c, &ClusterInfo{DefaultRuntimeClass: "kata", SMBIOSManufacturer: "Butler"})
+			o := testCreateOptions()
+			opts(&o)
+			_, _, err := c.CreateVM(ctx, o)
+			if expected == "" {
+				Expect(err).NotTo(HaveOccurred())
+				return
+			}
+			Expect(err).To(MatchError(ErrInvalidOptions))
+			Expect(err).To(MatchError(ContainSubstring(expected)))
+		},
+		Entry("matching runtime class", func(o *VMCreateOptions) { o.RuntimeClassName = "kata" }, ""),
+		Entry("other runtime class", func(o *VMCreateOptions) { o.RuntimeClassName = "gvisor" },
+			`runtime class "gvisor" requested but KubeVirt launches VMs with "kata"`),
+		Entry("matching SMBIOS manufacturer", func(o *VMCreateOptions) { o.SMBIOSManufacturer = "Butler" }, ""),
+		Entry("other SMBIOS manufacturer", func(o *VMCreateOptions) { o.SMBIOSManufacturer = "Acme" },
+			`SMBIOS manufacturer "Acme" requested but KubeVirt presents "Butler"`),
+		Entry("SMBIOS product left at the default", func(o *VMCreateOptions) { o.SMBIOSProduct = "Server" },
+			`SMBIOS product "Server" requested but KubeVirt presents ""`),
+	)
+})
@@ -0,0 +1,257 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package harvester
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// DiskBus is the bus a disk is attached to the guest with.
+type DiskBus string
+
+const (
+	// DiskBusVirtio is the paravirtualized virtio-blk bus (default).
+	DiskBusVirtio DiskBus = "virtio"
+	// DiskBusSCSI attaches the disk to a virtio-scsi controller.
+	DiskBusSCSI DiskBus = "scsi"
+	// DiskBusSATA emulates a SATA disk, for guests without virtio drivers.
+	DiskBusSATA DiskBus = "sata"
+)
+
+// validDiskBus reports whether bus is empty or a supported disk bus.
+func validDiskBus(bus DiskBus) bool {
+	switch bus {
+	case "", DiskBusVirtio, DiskBusSCSI, DiskBusSATA:
+		return true
+	}
+	return false
+}
+
+// resolveDiskBus returns bus, falling back to def and then to DiskBusVirtio.
+func resolveDiskBus(bus, def DiskBus) DiskBus {
+	if bus != "" {
+		return bus
+	}
+	if def != "" {
+		return def
+	}
+	return DiskBusVirtio
+}
+
+// dataDiskVolumePrefix prefixes the VM volume names of data disks.
+const dataDiskVolumePrefix = "datadisk-"
+
+// DataDiskSpec describes a blank data disk provisioned and deleted with the VM.
+type DataDiskSpec struct {
+	// SizeGB is the disk size in GiB.
+	SizeGB int32
+	// StorageClass overrides the cluster default StorageClass.
+	StorageClass string
+	// Bus defaults to the VM DiskBus.
+	Bus DiskBus
+	// Serial is the serial number the guest sees on the disk.
+	Serial string
+}
+
+// DataDiskPVCName returns the name of the PVC backing a VM's data disk.
+func DataDiskPVCName(vmName string, index int) string {
+	return fmt.Sprintf("%s-datadisk-%d", vmName, index)
+}
+
+// validateDataDisks checks the data disk specs.
+func validateDataDisks(disks []DataDiskSpec) error {
+	for i, disk := range disks {
+		if disk.SizeGB <= 0 {
+			return invalidOptionsf("data disk %d size must be positive", i)
+		}
+		if !validDiskBus(disk.Bus) {
+			return invalidOptionsf("data disk %d: unsupported bus %q (must be virtio, scsi or sata)", i, disk.Bus)
+		}
+		if disk.StorageClass != "" {
+			if errs := validation.IsDNS1123Subdomain(disk.StorageClass); len(errs) > 0 {
+				return invalidOptionsf("data disk %d: invalid storage class %q: %s", i, disk.StorageClass, errs[0])
+			}
+		}
+		if disk.Serial != "" {
+			if err := validateDiskSerial(disk.Serial); err != nil {
+				return invalidOptionsf("data disk %d: invalid serial %q: %v", i, disk.Serial, err)
+			}
+		}
+	}
+	return nil
+}
+
+// createDataDisks creates one blank PVC per data disk. On failure the PVCs it
+// already created are deleted again.
+func (c *Client) createDataDisks(ctx context.Context, opts VMCreateOptions) error {
+	for i, pvc := range c.dataDiskPVCs(opts) {
+		if err := c.checkDiskPVCAvailable(ctx, "data disk", pvc.Name, opts); err != nil {
+			c.deleteDataDisks(ctx, opts.Name, i)
+			return err
+		}
+		if _, err := retryCreate(ctx, c, func(ctx context.Context) (*corev1.PersistentVolumeClaim, error) {
+			return c.clientset.CoreV1().PersistentVolumeClaims(c.namespace).Create(ctx, pvc, metav1.CreateOptions{})
+		}, func(ctx context.Context) (*corev1.PersistentVolumeClaim, error) {
+			return c.clientset.CoreV1().PersistentVolumeClaims(c.namespace).Get(ctx, pvc.GetName(), metav1.GetOptions{})
+		}); err != nil {
+			c.deleteDataDisks(ctx, opts.Name, i)
+			return fmt.Errorf("failed to create data disk PVC %s: %w", pvc.Name, err)
+		}
+	}
+	return nil
+}
+
+// dataDiskPVCs builds the blank PVC of each data disk.
+func (c *Client) dataDiskPVCs(opts VMCreateOptions) []*corev1.PersistentVolumeClaim {
+	volumeMode := opts.VolumeMode
+	if volumeMode == "" {
+		volumeMode = corev1.PersistentVolumeBlock
+	}
+	pvcs := make([]*corev1.PersistentVolumeClaim, 0, len(opts.DataDisks))
+	for i, disk := range opts.DataDisks {
+		pvc := &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      DataDiskPVCName(opts.Name, i),
+				Namespace: c.namespace,
+				Annotations: map[string]string{
+					AnnotationOwnerUID: opts.OwnerUID,
+				},
+				Labels: map[string]string{
+					LabelManagedBy: managedByValue,
+				},
+			},
+			Spec: corev1.PersistentVolumeClaimSpec{
+				AccessModes: []corev1.PersistentVolumeAccessMode{
+					corev1.ReadWriteMany,
+				},
+				VolumeMode: &volumeMode,
+				Resources: corev1.VolumeResourceRequirements{
+					Requests: corev1.ResourceList{
+						corev1.ResourceStorage: *resource.NewQuantity(int64(disk.SizeGB)<<30, resource.BinarySI),
+					},
+				},
+			},
+		}
+		if disk.StorageClass != "" {
+			pvc.Spec.StorageClassName = &disk.StorageClass
+		}
+		pvcs = append(pvcs, pvc)
+	}
+	return pvcs
+}
+
+// deleteDataDisks deletes the PVCs of the first count data disks of a VM.
+func (c *Client) deleteDataDisks(ctx context.Context, vmName string, count int) {
+	for i := 0; i < count; i++ {
+		_ = c.deletePVC(ctx, DataDiskPVCName(vmName, i))
+	}
+}
+
+// deleteOwnedDataDisks deletes the data disk PVCs of a VM created for
+// ownerUID, for when the VM spec listing them is not available.
+func (c *Client) deleteOwnedDataDisks(ctx context.Context, vmName, ownerUID string) error {
+	pvcs, err := retryResult(ctx, c, func(ctx context.Context) (*corev1.PersistentVolumeClaimList, error) {
+		return c.clientset.CoreV1().PersistentVolumeClaims(c.namespace).List(ctx, metav1.ListOptions{
+			LabelSelector: labels.SelectorFromSet(labels.Set{LabelManagedBy: managedByValue}).String(),
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list data disk PVCs: %w", err)
+	}
+	for _, pvc := range pvcs.Items {
+		if !strings.HasPrefix(pvc.Name, vmName+"-datadisk-") || ownerUID == "" || pvc.Annotations[AnnotationOwnerUID] != ownerUID {
+			continue
+		}
+		if err := c.deletePVC(ctx, pvc.Name); err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// dataDiskDevice returns the volume and disk entries for data disk i. Disks
+// without a bus use defaultBus.
+func dataDiskDevice(vmName string, i int, spec DataDiskSpec, defaultBus DiskBus) (volume, disk map[string]interface{}) {
+	name := fmt.Sprintf("%s%d", dataDiskVolumePrefix, i)
+	volume = map[string]interface{}{
+		"name": name,
+		"persistentVolumeClaim": map[string]interface{}{
+			"claimName": DataDiskPVCName(vmName, i),
+		},
+	}
+	disk = map[string]interface{}{
+		"name": name,
+		"disk": map[string]interface{}{
+			"bus": string(resolveDiskBus(spec.Bus, defaultBus)),
+		},
+	}
+	if spec.Serial != "" {
+		disk["serial"] = spec.Serial
+	}
+	return volume, disk
+}
+
+// scratchVolumeName is the VM volume name of the ephemeral scratch disk.
+const scratchVolumeName = "scratch"
+
+// scratchDiskDevice returns the volume and disk entries for an ephemeral
+// scratch disk of sizeGB GiB. KubeVirt backs it with a sparse image on the
+// node, created when the VMI starts and discarded when it stops.
+func scratchDiskDevice(sizeGB int32, bus DiskBus) (volume, disk map[string]interface{}) {
+	volume = map[string]interface{}{
+		"name": scratchVolumeName,
+		"emptyDisk": map[string]interface{}{
+			"capacity": fmt.Sprintf("%dGi", sizeGB),
+		},
+	}
+	disk = map[string]interface{}{
+		"name": scratchVolumeName,
+		"disk": map[string]interface{}{
+			"bus": string(resolveDiskBus(bus, "")),
+		},
+	}
+	return volume, disk
+}
+
+// dataDiskClaimNames returns the PVCs backing the VM's data disk volumes.
+func dataDiskClaimNames(vm *unstructured.Unstructured) []string {
+	var claims []string
+	volumes, _, _ := unstructured.NestedSlice(vm.Object, "spec", "template", "spec", "volumes")
+	for _, v := range volumes {
+		volume, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if name, _ := volume["name"].(string); !strings.HasPrefix(name, dataDiskVolumePrefix) {
+			continue
+		}
+		if claim, _, _ := unstructured.NestedString(volume, "persistentVolumeClaim", "claimName"); claim != "" {
+			claims = append(claims, claim)
+		}
+	}
+	return claims
+}
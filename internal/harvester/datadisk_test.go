@@ -0,0 +1,200 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package harvester
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+var _ = Describe("Data disks", func() {
+	var ctx context.Context
+
+	BeforeEach(func() {
+		ctx = context.Background()
+	})
+
+	getPVC := func(c *Client, name string) error {
+		_, err := c.clientset.CoreV1().PersistentVolumeClaims(testNamespace).Get(ctx, name, metav1.GetOptions{})
+		return err
+	}
+
+	It("provisions and attaches each data disk after the boot disk", func() {
+		c := newTestClient()
+		opts := testCreateOptions()
+		opts.DataDisks = []DataDiskSpec{
+			{SizeGB: 50},
+			{SizeGB: 200, StorageClass: "longhorn-ssd", Bus: DiskBusSCSI, Serial: "data1"},
+		}
+
+		_, _, err := c.CreateVM(ctx, opts)
+		Expect(err).NotTo(HaveOccurred())
+
+		pvc, err := c.clientset.CoreV1().PersistentVolumeClaims(testNamespace).Get(ctx, "worker-0-datadisk-1", metav1.GetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pvc.Spec.StorageClassName).To(HaveValue(Equal("longhorn-ssd")))
+		Expect(pvc.Spec.Resources.Requests.Storage().String()).To(Equal("200Gi"))
+		Expect(pvc.Annotations).To(HaveKeyWithValue(AnnotationOwnerUID, opts.OwnerUID))
+		Expect(getPVC(c, "worker-0-datadisk-0")).To(Succeed())
+
+		vm, err := c.GetVM(ctx, opts.Name)
+		Expect(err).NotTo(HaveOccurred())
+		disks, _, _ := unstructured.NestedSlice(vm.Object, "spec", "template", "spec", "domain", "devices", "disks")
+		Expect(disks).To(ContainElement(And(
+			HaveKeyWithValue("name", "datadisk-1"),
+			HaveKeyWithValue("serial", "data1"),
+			HaveKeyWithValue("disk", HaveKeyWithValue("bus", "scsi")),
+			Not(HaveKey("bootOrder")),
+		)))
+		Expect(dataDiskClaimNames(vm)).To(Equal([]string{"worker-0-datadisk-0", "worker-0-datadisk-1"}))
+
+		Expect(c.DeleteVM(ctx, opts.Name, DeleteVMOptions{})).To(Succeed())
+		Expect(apierrors.IsNotFound(getPVC(c, "worker-0-datadisk-0"))).To(BeTrue())
+		Expect(apierrors.IsNotFound(getPVC(c, "worker-0-datadisk-1"))).To(BeTrue())
+	})
+
+	It("rolls back every created PVC when a data disk fails", func() {
+		c := newTestClient()
+		c.clientset.(*kubefake.Clientset).PrependReactor("create", "persistentvolumeclaims",
+			func(action k8stesting.Action) (bool, runtime.Object, error) {
+				pvc := action.(k8stesting.CreateAction).GetObject().(*corev1.PersistentVolumeClaim)
+				if pvc.Name == "worker-0-datadisk-1" {
+					return true, nil, errors.New("quota exceeded")
+				}
+				return false, nil, nil
+			})
+		opts := testCreateOptions()
+		opts.DataDisks = []DataDiskSpec{{SizeGB: 50}, {SizeGB: 50}}
+
+		_, _, err := c.CreateVM(ctx, opts)
+		Expect(err).To(MatchError(ContainSubstring("quota exceeded")))
+		Expect(apierrors.IsNotFound(getPVC(c, "worker-0-datadisk-0"))).To(BeTrue())
+		Expect(apierrors.IsNotFound(getPVC(c, ResolveRootDiskPVCName(opts)))).To(BeTrue())
+		_, err = c.GetVM(ctx, opts.Name)
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+
+	It("deletes data disks left behind by an unfinished create", func() {
+		c := newTestClient()
+		opts := testCreateOptions()
+		opts.DataDisks = []DataDiskSpec{{SizeGB: 50}}
+		Expect(c.createDataDisks(ctx, opts)).To(Succeed())
+
+		Expect(c.DeleteCreateLeftovers(ctx, opts.Name, "", "other-uid")).To(Succeed())
+		Expect(getPVC(c, "worker-0-datadisk-0")).To(Succeed())
+		Expect(c.DeleteCreateLeftovers(ctx, opts.Name, "", opts.OwnerUID)).To(Succeed())
+		Expect(apierrors.IsNotFound(getPVC(c, "worker-0-datadisk-0"))).To(BeTrue())
+	})
+
+	It("rejects invalid data disks", func() {
+		opts := testCreateOptions()
+		opts.DataDisks = []DataDiskSpec{{SizeGB: 0}}
+		Expect(validateCreateOptions(opts)).To(MatchError(ErrInvalidOptions))
+		opts.DataDisks = []DataDiskSpec{{SizeGB: 10, Bus: "ide"}}
+		Expect(validateCreateOptions(opts)).To(MatchError(ErrInvalidOptions))
+	})
+})
+
+var _ = Describe("Disk bus", func() {
+	busOf := func(vm *unstructured.Unstructured) map[string]interface{} {
+		buses := map[string]interface{}{}
+		disks, _, _ := unstructured.NestedSlice(vm.Object, "spec", "template", "spec", "domain", "devices", "disks")
+		for _, d := range disks {
+			disk := d.(map[string]interface{})
+			buses[disk["name"].(string)], _, _ = unstructured.NestedString(disk, "disk", "bus")
+		}
+		return buses
+	}
+
+	It("defaults every disk to virtio", func() {
+		opts := testCreateOptions()
+		opts.UserData = "#cloud-config\n"
+		vm := newTestClient().buildVM(opts, "worker-0-rootdisk", "default/vlan1")
+		Expect(busOf(vm)).To(Equal(map[string]interface{}{"rootdisk": "virtio", "cloudinit": "virtio"}))
+	})
+
+	It("applies the VM disk bus to every disk without its own bus", func() {
+		opts := testCreateOptions()
+		opts.UserData = "#cloud-config\n"
+		opts.DiskBus = DiskBusSATA
+		opts.AttachedDisks = []AttachedDisk{{ClaimName: "shared"}}
+		opts.DataDisks = []DataDiskSpec{{SizeGB: 10}, {SizeGB: 10, Bus: DiskBusSCSI}}
+		Expect(validateCreateOptions(opts)).To(Succeed())
+
+		vm := newTestClient().buildVM(opts, "worker-0-rootdisk", "default/vlan1")
+		Expect(busOf(vm)).To(Equal(map[string]interface{}{
+			"rootdisk":   "sata",
+			"cloudinit":  "sata",
+			"attached-0": "sata",
+			"datadisk-0": "sata",
+			"datadisk-1": "scsi",
+		}))
+	})
+
+	It("rejects an unknown bus", func() {
+		opts := testCreateOptions()
+		opts.DiskBus = "ide"
+		err := validateCreateOptions(opts)
+		Expect(errors.Is(err, ErrInvalidOptions)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring(`unsupported disk bus "ide"`))
+	})
+})
+
+var _ = Describe("Ephemeral scratch disk", func() {
+	It("attaches an emptyDisk after the data disks", func() {
+		c := newTestClient()
+		opts := testCreateOptions()
+		opts.DataDisks = []DataDiskSpec{{SizeGB: 10}}
+		opts.EphemeralScratchGB = 40
+		_, _, err := c.CreateVM(context.Background(), opts)
+		Expect(err).NotTo(HaveOccurred())
+
+		vm, err := c.GetVM(context.Background(), opts.Name)
+		Expect(err).NotTo(HaveOccurred())
+		volumes, _, _ := unstructured.NestedSlice(vm.Object, "spec", "template", "spec", "volumes")
+		Expect(volumes).To(ContainElement(map[string]interface{}{
+			"name":      "scratch",
+			"emptyDisk": map[string]interface{}{"capacity": "40Gi"},
+		}))
+		disks, _, _ := unstructured.NestedSlice(vm.Object, "spec", "template", "spec", "domain", "devices", "disks")
+		Expect(disks[2]).To(Equal(map[string]interface{}{
+			"name": "scratch",
+			"disk": map[string]interface{}{"bus": "virtio"},
+		}))
+
+		pvcs, err := c.clientset.CoreV1().PersistentVolumeClaims(testNamespace).List(context.Background(), metav1.ListOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pvcs.Items).To(HaveLen(2), "root and data disk only")
+	})
+
+	It("rejects a negative size", func() {
+		opts := testCreateOptions()
+		opts.EphemeralScratchGB = -1
+		Expect(errors.Is(validateCreateOptions(opts), ErrInvalidOptions)).To(BeTrue())
+	})
+})
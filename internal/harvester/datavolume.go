@@ -0,0 +1,105 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package harvester
+
+import (
+	"context"
+	"net/url"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// dataVolumeSource returns the CDI DataVolume source importing the root disk
+// from an http(s) URL or a docker:// registry image.
+func dataVolumeSource(importURL string) (map[string]interface{}, error) {
+	u, err := url.Parse(importURL)
+	if err != nil || u.Host == "" {
+		return nil, invalidOptionsf("invalid root disk import URL %q", importURL)
+	}
+	switch u.Scheme {
+	case "http", "https":
+		return map[string]interface{}{"http": map[string]interface{}{"url": importURL}}, nil
+	case "docker":
+		return map[string]interface{}{"registry": map[string]interface{}{"url": importURL}}, nil
+	}
+	return nil, invalidOptionsf("unsupported root disk import URL scheme %q (must be http, https or docker)", u.Scheme)
+}
+
+// rootDiskDataVolume returns the dataVolumeTemplates entry importing the root
+// disk from opts.RootDiskImportURL into a PVC named name. CDI creates the
+// DataVolume with the VM and KubeVirt makes the VM its owner, so the disk is
+// garbage collected with the VM instead of being deleted by the provider.
+func (c *Client) rootDiskDataVolume(ctx context.Context, name string, opts VMCreateOptions) (map[string]interface{}, error) {
+	source, err := dataVolumeSource(opts.RootDiskImportURL)
+	if err != nil {
+		return nil, err
+	}
+	volumeMode := opts.VolumeMode
+	if volumeMode == "" {
+		volumeMode = corev1.PersistentVolumeBlock
+	}
+	size, err := c.rootDiskSize(ctx, opts, opts.StorageClass)
+	if err != nil {
+		return nil, err
+	}
+
+	pvc := map[string]interface{}{
+		"accessModes": []interface{}{string(corev1.ReadWriteMany)},
+		"volumeMode":  string(volumeMode),
+		"resources": map[string]interface{}{
+			"requests": map[string]interface{}{"storage": size.String()},
+		},
+	}
+	if opts.StorageClass != "" {
+		pvc["storageClassName"] = opts.StorageClass
+	}
+	spec := map[string]interface{}{
+		"source": source,
+		"pvc":    pvc,
+	}
+	if opts.RootDiskPreallocation {
+		spec["preallocation"] = true
+	}
+	return map[string]interface{}{
+		"apiVersion": DataVolumeAPIVersion,
+		"kind":       DataVolumeKind,
+		"metadata": map[string]interface{}{
+			"name":        name,
+			"labels":      map[string]interface{}{LabelManagedBy: managedByValue},
+			"annotations": map[string]interface{}{AnnotationOwnerUID: opts.OwnerUID},
+		},
+		"spec": spec,
+	}, nil
+}
+
+// setRootDataVolume backs the root disk of a VM built by buildVM with the
+// DataVolume template instead of a PVC.
+func setRootDataVolume(vm *unstructured.Unstructured, template map[string]interface{}) {
+	name, _, _ := unstructured.NestedString(template, "metadata", "name")
+	volumes, _, _ := unstructured.NestedSlice(vm.Object, "spec", "template", "spec", "volumes")
+	for i, v := range volumes {
+		if volume, ok := v.(map[string]interface{}); ok && volume["name"] == "rootdisk" {
+			volumes[i] = map[string]interface{}{
+				"name":       "rootdisk",
+				"dataVolume": map[string]interface{}{"name": name},
+			}
+		}
+	}
+	_ = unstructured.SetNestedSlice(vm.Object, volumes, "spec", "template", "spec", "volumes")
+	_ = unstructured.SetNestedSlice(vm.Object, []interface{}{template}, "spec", "dataVolumeTemplates")
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package harvester
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+var _ = Describe("Root disk DataVolume", func() {
+	var (
+		ctx  context.Context
+		c    *Client
+		opts VMCreateOptions
+	)
+
+	nestedString := func(obj map[string]interface{}, fields ...string) string {
+		value, _, _ := unstructured.NestedString(obj, fields...)
+		return value
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		c = newTestClient()
+		opts = testCreateOptions()
+		opts.RootDiskImportURL = "https://images.example.com/jammy.qcow2"
+	})
+
+	It("declares the root disk in dataVolumeTemplates instead of creating a PVC", func() {
+		_, _, err := c.CreateVM(ctx, opts)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = c.getPVC(ctx, "worker-0-rootdisk")
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+
+		vm, err := c.GetVM(ctx, "worker-0")
+		Expect(err).NotTo(HaveOccurred())
+		templates, _, _ := unstructured.NestedSlice(vm.Object, "spec", "dataVolumeTemplates")
+		Expect(templates).To(HaveLen(1))
+		template := templates[0].(map[string]interface{})
+		Expect(nestedString(template, "metadata", "name")).To(Equal("worker-0-rootdisk"))
+		Expect(nestedString(template, "spec", "source", "http", "url")).To(Equal(opts.RootDiskImportURL))
+		Expect(nestedString(template, "spec", "pvc", "resources", "requests", "storage")).To(Equal("20Gi"))
+		Expect(nestedString(template, "spec", "pvc", "volumeMode")).To(Equal("Block"))
+
+		volumes, _, _ := unstructured.NestedSlice(vm.Object, "spec", "template", "spec", "volumes")
+		root := volumes[0].(map[string]interface{})
+		Expect(nestedString(root, "dataVolume", "name")).To(Equal("worker-0-rootdisk"))
+		Expect(root).NotTo(HaveKey("persistentVolumeClaim"))
+		Expect(rootDiskClaimName(vm)).To(BeEmpty())
+	})
+
+	It("imports registry images", func() {
+		opts.RootDiskImportURL = "docker://registry.example.com/disks/jammy:latest"
+		_, _, err := c.CreateVM(ctx, opts)
+		Expect(err).NotTo(HaveOccurred())
+
+		vm, err := c.GetVM(ctx, "worker-0")
+		Expect(err).NotTo(HaveOccurred())
+		templates, _, _ := unstructured.NestedSlice(vm.Object, "spec", "dataVolumeTemplates")
+		Expect(nestedString(templates[0].(map[string]interface{}), "spec", "source", "registry", "url")).To(Equal(opts.RootDiskImportURL))
+	})
+
+	It("rejects unsupported URLs and conflicting sources", func() {
+		opts.RootDiskImportURL = "ftp://images.example.com/jammy.qcow2"
+		Expect(validateCreateOptions(opts)).To(MatchError(ErrInvalidOptions))
+
+		opts.RootDiskImportURL = "https://images.example.com/jammy.qcow2"
+		opts.ContainerDiskImage = "quay.io/containerdisks/ubuntu:22.04"
+		Expect(validateCreateOptions(opts)).To(MatchError(ErrInvalidOptions))
+	})
+})
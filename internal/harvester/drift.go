@@ -0,0 +1,114 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package harvester
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// VMMetadata is the VM metadata derived from the MachineRequest: the request
+// labels and the owner recorded by buildVM.
+type VMMetadata struct {
+	Labels   map[string]string
+	OwnerUID string
+	Owner    string
+}
+
+// EnsureVMMetadata re-applies the metadata buildVM sets on a VM where it was
+// edited since. The comparison is deliberately narrow so it never fights
+// fields Harvester or KubeVirt mutate. Only these paths are reconciled:
+//
+//   - metadata.labels: the managed-by label and the request labels, and
+//     removal of labels the provider previously managed that are no longer
+//     requested. Labels added by users are left alone.
+//   - metadata.annotations[AnnotationManagedLabels]: the keys of those labels.
+//   - metadata.annotations[AnnotationOwnerUID] and [AnnotationOwner]: restored
+//     only when missing, since a different owner is a name conflict.
+//
+// spec.template.metadata.labels is not reconciled, as changing the template
+// would require a restart, and neither are Harvester annotations such as
+// the network IPs. It returns the corrected field paths.
+func (c *Client) EnsureVMMetadata(ctx context.Context, name string, metadata VMMetadata) ([]string, error) {
+	vm, err := c.GetVM(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	desired := desiredLabels(metadata.Labels)
+	current := vm.GetLabels()
+	currentAnnotations := vm.GetAnnotations()
+	patchLabels := map[string]interface{}{}
+	patchAnnotations := map[string]interface{}{}
+
+	for k, v := range desired {
+		if current[k] != v {
+			patchLabels[k] = v
+		}
+	}
+	if previous := currentAnnotations[AnnotationManagedLabels]; previous != "" {
+		for _, k := range strings.Split(previous, ",") {
+			if _, ok := desired[k]; !ok {
+				if _, exists := current[k]; exists {
+					patchLabels[k] = nil
+				}
+			}
+		}
+	}
+	if keys := managedKeys(desired); currentAnnotations[AnnotationManagedLabels] != keys {
+		patchAnnotations[AnnotationManagedLabels] = keys
+	}
+	if metadata.OwnerUID != "" && currentAnnotations[AnnotationOwnerUID] == "" {
+		patchAnnotations[AnnotationOwnerUID] = metadata.OwnerUID
+		patchAnnotations[AnnotationOwner] = metadata.Owner
+	}
+	if len(patchLabels) == 0 && len(patchAnnotations) == 0 {
+		return nil, nil
+	}
+
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"labels":      patchLabels,
+			"annotations": patchAnnotations,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := retryResult(ctx, c, func(ctx context.Context) (*unstructured.Unstructured, error) {
+		return c.dynamic.Resource(vmGVR).Namespace(c.namespace).Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{})
+	}); err != nil {
+		return nil, fmt.Errorf("failed to patch VM metadata: %w", err)
+	}
+
+	fields := make([]string, 0, len(patchLabels)+len(patchAnnotations))
+	for k := range patchLabels {
+		fields = append(fields, fmt.Sprintf("metadata.labels[%s]", k))
+	}
+	for k := range patchAnnotations {
+		fields = append(fields, fmt.Sprintf("metadata.annotations[%s]", k))
+	}
+	sort.Strings(fields)
+	return fields, nil
+}
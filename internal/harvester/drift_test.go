@@ -0,0 +1,101 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package harvester
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("VM metadata drift", func() {
+	var (
+		ctx      context.Context
+		c        *Client
+		metadata VMMetadata
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		c = newTestClient()
+		opts := testCreateOptions()
+		opts.Labels = map[string]string{"env": "prod"}
+		_, _, err := c.CreateVM(ctx, opts)
+		Expect(err).NotTo(HaveOccurred())
+		metadata = VMMetadata{Labels: opts.Labels, OwnerUID: opts.OwnerUID, Owner: opts.Owner}
+	})
+
+	// editVM applies edit to the stored VM, as a user editing it by hand.
+	editVM := func(edit func(labels, annotations map[string]string)) {
+		vm, err := c.GetVM(ctx, "worker-0")
+		Expect(err).NotTo(HaveOccurred())
+		labels, annotations := vm.GetLabels(), vm.GetAnnotations()
+		edit(labels, annotations)
+		vm.SetLabels(labels)
+		vm.SetAnnotations(annotations)
+		_, err = c.dynamic.Resource(vmGVR).Namespace(testNamespace).Update(ctx, vm, metav1.UpdateOptions{})
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	It("leaves a VM built from the same metadata alone", func() {
+		Expect(c.EnsureVMMetadata(ctx, "worker-0", metadata)).To(BeEmpty())
+	})
+
+	It("restores edited labels and missing owner annotations", func() {
+		editVM(func(labels, annotations map[string]string) {
+			labels["env"] = "dev"
+			labels["team"] = "infra"
+			delete(labels, LabelManagedBy)
+			delete(annotations, AnnotationOwnerUID)
+			delete(annotations, AnnotationOwner)
+		})
+
+		Expect(c.EnsureVMMetadata(ctx, "worker-0", metadata)).To(Equal([]string{
+			"metadata.annotations[" + AnnotationOwnerUID + "]",
+			"metadata.annotations[" + AnnotationOwner + "]",
+			"metadata.labels[" + LabelManagedBy + "]",
+			"metadata.labels[env]",
+		}))
+
+		vm, err := c.GetVM(ctx, "worker-0")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(vm.GetLabels()).To(Equal(map[string]string{LabelManagedBy: managedByValue, "env": "prod", "team": "infra"}))
+		Expect(vm.GetAnnotations()).To(HaveKeyWithValue(AnnotationOwnerUID, "uid-1"))
+		Expect(vm.GetAnnotations()).To(HaveKeyWithValue(AnnotationOwner, "butler/worker-0"))
+		Expect(c.EnsureVMMetadata(ctx, "worker-0", metadata)).To(BeEmpty())
+	})
+
+	It("removes labels that are no longer requested", func() {
+		metadata.Labels = nil
+		Expect(c.EnsureVMMetadata(ctx, "worker-0", metadata)).To(Equal([]string{
+			"metadata.annotations[" + AnnotationManagedLabels + "]",
+			"metadata.labels[env]",
+		}))
+		vm, err := c.GetVM(ctx, "worker-0")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(vm.GetLabels()).NotTo(HaveKey("env"))
+	})
+
+	It("does not overwrite a different owner", func() {
+		editVM(func(_, annotations map[string]string) {
+			annotations[AnnotationOwnerUID] = "uid-2"
+		})
+		Expect(c.EnsureVMMetadata(ctx, "worker-0", metadata)).To(BeEmpty())
+	})
+})
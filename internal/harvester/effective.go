@@ -0,0 +1,95 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package harvester
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// effectiveOptions returns the planned options with what CreateVM resolved
+// filled in: the image and network, the root disk PVC name and volume mode,
+// disk bus, network binding and network-data format, and the memory
+// overhead and request percentage. The storage class and rendered
+// cloud-init are already resolved in plan.opts.
+func (plan *vmPlan) effectiveOptions() VMCreateOptions {
+	opts := plan.opts
+	if opts.ContainerDiskImage == "" && opts.RootDiskImportURL == "" {
+		opts.ImageName = plan.imageName
+	}
+	if len(opts.Networks) == 0 && opts.NetworkName == "" {
+		opts.NetworkName = plan.networkName
+	}
+	if plan.pvcName != "" {
+		opts.RootDiskPVCName = plan.pvcName
+		if opts.VolumeMode == "" {
+			opts.VolumeMode = corev1.PersistentVolumeBlock
+		}
+	}
+	opts.DiskBus = resolveDiskBus(opts.DiskBus, "")
+	opts.NetworkBinding = networkBinding(opts)
+	if opts.NetworkDataFormat == "" {
+		opts.NetworkDataFormat = NetworkDataV2
+	}
+	if opts.MemoryOverheadMB == nil && opts.MemoryLimitMB == 0 {
+		limit := MemoryLimit(opts)
+		overhead := int32(limit.Value()>>20) - opts.MemoryMB
+		opts.MemoryOverheadMB = &overhead
+	}
+	if opts.EnableOvercommit && opts.MemoryRequestPercent == 0 && opts.MemoryRequestMB == 0 {
+		opts.MemoryRequestPercent = defaultMemoryRequestPercent
+	}
+	return opts
+}
+
+// RedactedOptionsJSON serializes the set fields of opts as a JSON object
+// keyed by field name, for recording what a VM was created with. The user
+// data and network data are replaced with their size and SHA-256, since
+// cloud-init payloads routinely carry credentials.
+func RedactedOptionsJSON(opts VMCreateOptions) (string, error) {
+	opts.UserData = redactPayload(opts.UserData)
+	opts.NetworkData = redactPayload(opts.NetworkData)
+
+	fields := map[string]interface{}{}
+	v := reflect.ValueOf(opts)
+	for i := range v.NumField() {
+		field := v.Type().Field(i)
+		if !field.IsExported() || v.Field(i).IsZero() {
+			continue
+		}
+		fields[field.Name] = v.Field(i).Interface()
+	}
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return "", fmt.Errorf("failed to serialize VM options: %w", err)
+	}
+	return string(data), nil
+}
+
+// redactPayload replaces a cloud-init payload with a fingerprint.
+func redactPayload(payload string) string {
+	if payload == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(payload))
+	return fmt.Sprintf("<redacted: %d bytes, sha256 %s>", len(payload), hex.EncodeToString(sum[:]))
+}
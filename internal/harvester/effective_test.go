@@ -0,0 +1,64 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package harvester
+
+import (
+	"context"
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Effective create options", func() {
+	It("returns the defaults CreateVM applied", func() {
+		c := newTestClient()
+		c.config.ImageName = "default/image-abc12"
+		c.config.NetworkName = "default/vlan1"
+		opts := testCreateOptions()
+		opts.ImageName = ""
+		opts.NetworkName = ""
+
+		_, effective, err := c.CreateVM(context.Background(), opts)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(effective.ImageName).To(Equal("default/image-abc12"))
+		Expect(effective.NetworkName).To(Equal("default/vlan1"))
+		Expect(effective.StorageClass).To(Equal("longhorn-image-abc12"))
+		Expect(effective.RootDiskPVCName).To(Equal("worker-0-rootdisk"))
+		Expect(effective.VolumeMode).To(BeEquivalentTo("Block"))
+		Expect(effective.DiskBus).To(Equal(DiskBusVirtio))
+		Expect(effective.NetworkBinding).To(Equal(NetworkBindingBridge))
+		Expect(effective.NetworkDataFormat).To(Equal(NetworkDataV2))
+		Expect(*effective.MemoryOverheadMB).To(BeEquivalentTo(82))
+	})
+
+	It("serializes the set fields with cloud-init redacted", func() {
+		opts := testCreateOptions()
+		opts.UserData = "#cloud-config\npassword: hunter2\n"
+		data, err := RedactedOptionsJSON(opts)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(data).NotTo(ContainSubstring("hunter2"))
+
+		var fields map[string]interface{}
+		Expect(json.Unmarshal([]byte(data), &fields)).To(Succeed())
+		Expect(fields).To(HaveKeyWithValue("Name", "worker-0"))
+		Expect(fields).To(HaveKeyWithValue("CPU", BeEquivalentTo(2)))
+		Expect(fields["UserData"]).To(HavePrefix("<redacted: 32 bytes, sha256 "))
+		Expect(fields).NotTo(HaveKey("NetworkData"))
+		Expect(fields).NotTo(HaveKey("DiskSize"))
+	})
+})
@@ -0,0 +1,244 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package harvester
+
+import (
+	"encoding/binary"
+	"fmt"
+	"unicode/utf16"
+)
+
+// FAT16 layout used for persistent NoCloud seed disks. The image declares a
+// volume of the requested size so the guest has room to write back, but
+// buildCIDataImage only materializes the metadata and file clusters; the
+// populator extends the file to its full size with zeros.
+const (
+	fatBytesPerSector  = 512
+	fatReservedSectors = 1
+	fatNumFATs         = 2
+	fatRootEntries     = 512
+	fatRootDirSectors  = fatRootEntries * 32 / fatBytesPerSector
+	// fatMaxClusters is the largest cluster count a FAT16 volume may have.
+	fatMaxClusters = 65524
+	// fatMinSectorsPerCluster keeps the cluster size of small volumes at 2KiB.
+	fatMinSectorsPerCluster = 4
+	// fatMaxSectorsPerCluster gives the largest FAT16 cluster size, 32KiB.
+	fatMaxSectorsPerCluster = 64
+
+	// cidataVolumeLabel is the label cloud-init's NoCloud datasource looks for.
+	cidataVolumeLabel = "CIDATA"
+)
+
+// fatGeometry describes a FAT16 volume.
+type fatGeometry struct {
+	totalSectors      int
+	sectorsPerCluster int
+	sectorsPerFAT     int
+}
+
+// newFATGeometry returns the FAT16 layout for a volume of size bytes, using
+// the smallest cluster size that keeps the cluster count within FAT16 limits.
+func newFATGeometry(size int64) fatGeometry {
+	g := fatGeometry{
+		totalSectors:      int(size / fatBytesPerSector),
+		sectorsPerCluster: fatMinSectorsPerCluster,
+	}
+	for g.totalSectors/g.sectorsPerCluster > fatMaxClusters && g.sectorsPerCluster < fatMaxSectorsPerCluster {
+		g.sectorsPerCluster *= 2
+	}
+	// Two bytes per cluster, plus the two reserved entries
+	entries := g.totalSectors/g.sectorsPerCluster + 2
+	g.sectorsPerFAT = (entries*2 + fatBytesPerSector - 1) / fatBytesPerSector
+	return g
+}
+
+// size returns the size of the volume in bytes.
+func (g fatGeometry) size() int64 {
+	return int64(g.totalSectors) * fatBytesPerSector
+}
+
+// clusterSize returns the size of a cluster in bytes.
+func (g fatGeometry) clusterSize() int {
+	return g.sectorsPerCluster * fatBytesPerSector
+}
+
+// dataStartSector returns the first sector of the data region.
+func (g fatGeometry) dataStartSector() int {
+	return fatReservedSectors + fatNumFATs*g.sectorsPerFAT + fatRootDirSectors
+}
+
+// dataClusters returns the number of clusters available for file data.
+func (g fatGeometry) dataClusters() int {
+	return (g.totalSectors - g.dataStartSector()) / g.sectorsPerCluster
+}
+
+// cidataFile is a file to place in the root directory of a seed image.
+type cidataFile struct {
+	name      string
+	data      []byte
+	shortName string
+}
+
+// buildCIDataImage renders a FAT16 filesystem labeled CIDATA containing the
+// given files. The returned bytes cover the image up to the last used data
+// cluster; the remainder of the volume is implicitly zero.
+func buildCIDataImage(g fatGeometry, files []cidataFile) ([]byte, error) {
+	clusterSize := g.clusterSize()
+	clustersNeeded := 0
+	for _, f := range files {
+		clustersNeeded += (len(f.data) + clusterSize - 1) / clusterSize
+	}
+	if clustersNeeded > g.dataClusters() {
+		return nil, fmt.Errorf("cloud-init payload needs %d clusters, image holds %d", clustersNeeded, g.dataClusters())
+	}
+
+	dataStart := g.dataStartSector() * fatBytesPerSector
+	img := make([]byte, dataStart+clustersNeeded*clusterSize)
+
+	writeBootSector(img[:fatBytesPerSector], g)
+
+	fat := make([]byte, g.sectorsPerFAT*fatBytesPerSector)
+	binary.LittleEndian.PutUint16(fat[0:], 0xFFF8)
+	binary.LittleEndian.PutUint16(fat[2:], 0xFFFF)
+
+	rootDir := make([]byte, fatRootDirSectors*fatBytesPerSector)
+	entry := 0
+	putShortEntry(rootDir[entry*32:], padShortName(cidataVolumeLabel), 0x08, 0, 0)
+	entry++
+
+	cluster := 2
+	for _, f := range files {
+		short := padShortName(f.shortName)
+		for _, lfn := range longNameEntries(f.name, short) {
+			copy(rootDir[entry*32:], lfn)
+			entry++
+		}
+
+		first := 0
+		n := (len(f.data) + clusterSize - 1) / clusterSize
+		if n > 0 {
+			first = cluster
+			for i := 0; i < n; i++ {
+				next := uint16(0xFFFF)
+				if i < n-1 {
+					next = uint16(cluster + 1)
+				}
+				binary.LittleEndian.PutUint16(fat[cluster*2:], next)
+				offset := dataStart + (cluster-2)*clusterSize
+				end := (i + 1) * clusterSize
+				if end > len(f.data) {
+					end = len(f.data)
+				}
+				copy(img[offset:], f.data[i*clusterSize:end])
+				cluster++
+			}
+		}
+		putShortEntry(rootDir[entry*32:], short, 0x20, uint16(first), uint32(len(f.data)))
+		entry++
+	}
+
+	for i := 0; i < fatNumFATs; i++ {
+		copy(img[(fatReservedSectors+i*g.sectorsPerFAT)*fatBytesPerSector:], fat)
+	}
+	copy(img[(fatReservedSectors+fatNumFATs*g.sectorsPerFAT)*fatBytesPerSector:], rootDir)
+
+	return img, nil
+}
+
+// writeBootSector fills in the FAT16 boot sector and BIOS parameter block.
+func writeBootSector(b []byte, g fatGeometry) {
+	copy(b[0:], []byte{0xEB, 0x3C, 0x90})
+	copy(b[3:], "BUTLER  ")
+	binary.LittleEndian.PutUint16(b[11:], fatBytesPerSector)
+	b[13] = byte(g.sectorsPerCluster)
+	binary.LittleEndian.PutUint16(b[14:], fatReservedSectors)
+	b[16] = fatNumFATs
+	binary.LittleEndian.PutUint16(b[17:], fatRootEntries)
+	// Total sectors exceed 16 bits, so use the 32-bit field.
+	binary.LittleEndian.PutUint16(b[19:], 0)
+	b[21] = 0xF8
+	binary.LittleEndian.PutUint16(b[22:], uint16(g.sectorsPerFAT))
+	binary.LittleEndian.PutUint16(b[24:], 32)
+	binary.LittleEndian.PutUint16(b[26:], 64)
+	binary.LittleEndian.PutUint32(b[32:], uint32(g.totalSectors))
+	b[36] = 0x80
+	b[38] = 0x29
+	binary.LittleEndian.PutUint32(b[39:], 0x42544C52)
+	label := padShortName(cidataVolumeLabel)
+	copy(b[43:], label[:])
+	copy(b[54:], "FAT16   ")
+	b[510] = 0x55
+	b[511] = 0xAA
+}
+
+// putShortEntry writes an 8.3 directory entry.
+func putShortEntry(b []byte, name [11]byte, attr byte, cluster uint16, size uint32) {
+	copy(b[0:11], name[:])
+	b[11] = attr
+	binary.LittleEndian.PutUint16(b[26:], cluster)
+	binary.LittleEndian.PutUint32(b[28:], size)
+}
+
+// padShortName space-pads an upper-case 8.3 name to 11 bytes.
+func padShortName(name string) [11]byte {
+	var out [11]byte
+	for i := range out {
+		out[i] = ' '
+	}
+	copy(out[:], name)
+	return out
+}
+
+// longNameEntries returns the VFAT long-file-name entries for name, in the
+// on-disk order (last fragment first).
+func longNameEntries(name string, short [11]byte) [][]byte {
+	var sum byte
+	for _, c := range short {
+		sum = ((sum & 1) << 7) + (sum >> 1) + c
+	}
+
+	chars := utf16.Encode([]rune(name))
+	count := (len(chars) + 12) / 13
+	entries := make([][]byte, count)
+	for seq := 1; seq <= count; seq++ {
+		e := make([]byte, 32)
+		e[0] = byte(seq)
+		if seq == count {
+			e[0] |= 0x40
+		}
+		e[11] = 0x0F
+		e[13] = sum
+
+		// Character slots: 5 at offset 1, 6 at 14, 2 at 28.
+		slots := []int{1, 3, 5, 7, 9, 14, 16, 18, 20, 22, 24, 28, 30}
+		for i, off := range slots {
+			idx := (seq-1)*13 + i
+			var ch uint16
+			switch {
+			case idx < len(chars):
+				ch = chars[idx]
+			case idx == len(chars):
+				ch = 0x0000
+			default:
+				ch = 0xFFFF
+			}
+			binary.LittleEndian.PutUint16(e[off:], ch)
+		}
+		entries[count-seq] = e
+	}
+	return entries
+}
@@ -0,0 +1,178 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package harvester
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"unicode/utf16"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// readCIDataFile reads a file from the root directory of a seed image by its
+// long name, following its cluster chain through both FAT copies, which must
+// agree. It returns the data and the 8.3 name the file was stored under.
+func readCIDataFile(img []byte, g fatGeometry, name string) ([]byte, string) {
+	fatStart := fatReservedSectors * fatBytesPerSector
+	fatSize := g.sectorsPerFAT * fatBytesPerSector
+	fat := img[fatStart : fatStart+fatSize]
+	Expect(img[fatStart+fatSize:fatStart+2*fatSize]).To(Equal(fat), "FAT copies differ")
+
+	rootStart := (fatReservedSectors + fatNumFATs*g.sectorsPerFAT) * fatBytesPerSector
+	dataStart := g.dataStartSector() * fatBytesPerSector
+	var long []uint16
+	for off := rootStart; off < dataStart && img[off] != 0; off += 32 {
+		e := img[off : off+32]
+		if e[11] == 0x0F {
+			// On-disk order is last fragment first, so prepend each one.
+			var chars []uint16
+			for _, slot := range []int{1, 3, 5, 7, 9, 14, 16, 18, 20, 22, 24, 28, 30} {
+				chars = append(chars, binary.LittleEndian.Uint16(e[slot:]))
+			}
+			long = append(chars, long...)
+			continue
+		}
+		if end := indexUint16(long, 0); end >= 0 {
+			long = long[:end]
+		}
+		entryName := string(utf16.Decode(long))
+		long = nil
+		if e[11] != 0x20 || entryName != name {
+			continue
+		}
+
+		size := int(binary.LittleEndian.Uint32(e[28:]))
+		var data []byte
+		for cluster := int(binary.LittleEndian.Uint16(e[26:])); cluster >= 2 && cluster < 0xFFF8; cluster = int(binary.LittleEndian.Uint16(fat[cluster*2:])) {
+			offset := dataStart + (cluster-2)*g.clusterSize()
+			data = append(data, img[offset:offset+g.clusterSize()]...)
+		}
+		Expect(len(data)).To(BeNumerically(">=", size))
+		return data[:size], strings.TrimRight(string(e[0:11]), " ")
+	}
+	Fail("no file " + name + " in the seed image")
+	return nil, ""
+}
+
+func indexUint16(s []uint16, v uint16) int {
+	for i, c := range s {
+		if c == v {
+			return i
+		}
+	}
+	return -1
+}
+
+var _ = Describe("FAT seed image", func() {
+	It("sizes the geometry to stay within FAT16 limits", func() {
+		small := newFATGeometry(64 << 20)
+		Expect(small).To(Equal(fatGeometry{totalSectors: 131072, sectorsPerCluster: 4, sectorsPerFAT: 129}))
+		Expect(small.size()).To(Equal(int64(64 << 20)))
+
+		large := newFATGeometry(maxCloudInitDiskSize)
+		Expect(large).To(Equal(fatGeometry{totalSectors: 4192256, sectorsPerCluster: 64, sectorsPerFAT: 256}))
+		Expect(large.dataClusters()).To(BeNumerically("<=", fatMaxClusters))
+	})
+
+	It("writes a CIDATA FAT16 boot sector", func() {
+		g := newFATGeometry(64 << 20)
+		img, err := buildCIDataImage(g, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(img).To(HaveLen(g.dataStartSector() * fatBytesPerSector))
+
+		boot := img[:fatBytesPerSector]
+		Expect(boot[0:3]).To(Equal([]byte{0xEB, 0x3C, 0x90}))
+		Expect(binary.LittleEndian.Uint16(boot[11:])).To(Equal(uint16(512)))
+		Expect(boot[13]).To(Equal(byte(4)))
+		Expect(binary.LittleEndian.Uint16(boot[14:])).To(Equal(uint16(1)))
+		Expect(boot[16]).To(Equal(byte(2)))
+		Expect(binary.LittleEndian.Uint16(boot[17:])).To(Equal(uint16(512)))
+		Expect(binary.LittleEndian.Uint16(boot[19:])).To(BeZero())
+		Expect(boot[21]).To(Equal(byte(0xF8)))
+		Expect(binary.LittleEndian.Uint16(boot[22:])).To(Equal(uint16(129)))
+		Expect(binary.LittleEndian.Uint32(boot[32:])).To(Equal(uint32(131072)))
+		Expect(boot[38]).To(Equal(byte(0x29)))
+		Expect(string(boot[43:54])).To(Equal("CIDATA     "))
+		Expect(string(boot[54:62])).To(Equal("FAT16   "))
+		Expect(boot[510:512]).To(Equal([]byte{0x55, 0xAA}))
+
+		// The media descriptor and end-of-chain marker lead both FATs, and
+		// the root directory opens with the volume label.
+		for i := 0; i < fatNumFATs; i++ {
+			fat := img[(fatReservedSectors+i*g.sectorsPerFAT)*fatBytesPerSector:]
+			Expect(fat[0:4]).To(Equal([]byte{0xF8, 0xFF, 0xFF, 0xFF}))
+		}
+		root := img[(fatReservedSectors+fatNumFATs*g.sectorsPerFAT)*fatBytesPerSector:]
+		Expect(string(root[0:11])).To(Equal("CIDATA     "))
+		Expect(root[11]).To(Equal(byte(0x08)))
+	})
+
+	It("stores files under their long names with chained clusters", func() {
+		g := newFATGeometry(64 << 20)
+		userData := bytes.Repeat([]byte("#cloud-config\n"), 400)
+		Expect(len(userData)).To(BeNumerically(">", 2*g.clusterSize()))
+		img, err := buildCIDataImage(g, []cidataFile{
+			{name: "meta-data", shortName: "META-D~1", data: []byte("instance-id: worker-0\n")},
+			{name: "user-data", shortName: "USER-D~1", data: userData},
+			{name: "network-data", shortName: "NETWOR~1"},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		// Image covers the metadata plus one cluster of meta-data and three of user-data
+		Expect(img).To(HaveLen(g.dataStartSector()*fatBytesPerSector + 4*g.clusterSize()))
+
+		data, short := readCIDataFile(img, g, "meta-data")
+		Expect(string(data)).To(Equal("instance-id: worker-0\n"))
+		Expect(short).To(Equal("META-D~1"))
+
+		data, short = readCIDataFile(img, g, "user-data")
+		Expect(data).To(Equal(userData))
+		Expect(short).To(Equal("USER-D~1"))
+
+		data, _ = readCIDataFile(img, g, "network-data")
+		Expect(data).To(BeEmpty())
+	})
+
+	It("checksums long name entries against their short name", func() {
+		short := padShortName("USER-D~1")
+		var sum byte
+		for _, c := range short {
+			sum = ((sum & 1) << 7) + (sum >> 1) + c
+		}
+		entries := longNameEntries("user-data", short)
+		Expect(entries).To(HaveLen(1))
+		Expect(entries[0][0]).To(Equal(byte(0x41)))
+		Expect(entries[0][11]).To(Equal(byte(0x0F)))
+		Expect(entries[0][13]).To(Equal(sum))
+
+		// Names longer than 13 characters span fragments, last one first
+		entries = longNameEntries("a-very-long-network-data", padShortName("A-VERY~1"))
+		Expect(entries).To(HaveLen(2))
+		Expect(entries[0][0]).To(Equal(byte(0x42)))
+		Expect(entries[1][0]).To(Equal(byte(0x01)))
+	})
+
+	It("rejects payloads that do not fit the volume", func() {
+		g := newFATGeometry(1 << 20)
+		_, err := buildCIDataImage(g, []cidataFile{
+			{name: "user-data", shortName: "USER-D~1", data: make([]byte, 2<<20)},
+		})
+		Expect(err).To(MatchError(ContainSubstring("clusters, image holds")))
+	})
+})
@@ -0,0 +1,86 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package harvester
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Firmware", func() {
+	It("keeps BIOS firmware by default", func() {
+		Expect(buildFirmware(testCreateOptions())).To(BeNil())
+	})
+
+	It("boots with EFI and Secure Boot", func() {
+		opts := testCreateOptions()
+		opts.FirmwareEFI = true
+		opts.FirmwareSecureBoot = true
+		Expect(validateCreateOptions(opts)).To(Succeed())
+		Expect(buildFirmware(opts)).To(HaveKeyWithValue("bootloader",
+			map[string]interface{}{"efi": map[string]interface{}{"secureBoot": true}}))
+
+		opts.FirmwareSecureBoot = false
+		Expect(buildFirmware(opts)).To(HaveKeyWithValue("bootloader",
+			map[string]interface{}{"efi": map[string]interface{}{"secureBoot": false}}))
+	})
+
+	It("rejects Secure Boot without EFI", func() {
+		opts := testCreateOptions()
+		opts.FirmwareSecureBoot = true
+		Expect(validateCreateOptions(opts)).To(MatchError(ContainSubstring("secure boot requires EFI firmware")))
+	})
+
+	It("lower-cases the UUID and keeps the serial as given", func() {
+		opts := testCreateOptions()
+		opts.FirmwareUUID = "5D307CA9-B3EF-428C-8861-06E72D69F223"
+		opts.FirmwareSerial = "Butler-SN-0001"
+		Expect(validateCreateOptions(opts)).To(Succeed())
+		Expect(buildFirmware(opts)).To(Equal(map[string]interface{}{
+			"uuid":   "5d307ca9-b3ef-428c-8861-06e72d69f223",
+			"serial": "Butler-SN-0001",
+		}))
+
+		opts.FirmwareUUID = "5d307ca9-b3ef-428c-8861"
+		Expect(validateCreateOptions(opts)).To(MatchError(ErrInvalidOptions))
+	})
+
+	DescribeTable("checks the SMBIOS strings against the KubeVirt configuration",
+		func(manufacturer, product, wantErr string) {
+			c := newTestClient()
+			setClusterInfo(c, &ClusterInfo{SMBIOSManufacturer: "Butler Labs", SMBIOSProduct: "Butler VM"})
+			opts := testCreateOptions()
+			opts.SMBIOSManufacturer = manufacturer
+			opts.SMBIOSProduct = product
+
+			err := c.checkSMBIOS(context.Background(), opts)
+			if wantErr == "" {
+				Expect(err).NotTo(HaveOccurred())
+			} else {
+				Expect(err).To(MatchError(ErrInvalidOptions))
+				Expect(err).To(MatchError(ContainSubstring(wantErr)))
+			}
+		},
+		Entry("unset", "", "", ""),
+		Entry("matching", "Butler Labs", "Butler VM", ""),
+		Entry("only the product set", "", "Butler VM", ""),
+		Entry("another manufacturer", "KubeVirt", "Butler VM", `SMBIOS manufacturer "KubeVirt" requested but KubeVirt presents "Butler Labs"`),
+		Entry("another product", "Butler Labs", "None", `SMBIOS product "None" requested but KubeVirt presents "Butler VM"`),
+	)
+})
@@ -0,0 +1,173 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package harvester
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// ErrGPUUnavailable is returned when a requested GPU device can never be
+// given to the VM as configured: KubeVirt does not permit it, or Harvester
+// rejected the VM because of it.
+var ErrGPUUnavailable = errors.New("GPU device unavailable")
+
+// ErrGPUCapacity is returned when no ready node currently offers a requested
+// GPU device. The device may appear once a node recovers or is added, so the
+// caller should retry later.
+var ErrGPUCapacity = errors.New("no node offers the GPU device")
+
+// labelKubeVirtSchedulable marks the nodes virt-handler can run VMs on.
+const labelKubeVirtSchedulable = "kubevirt.io/schedulable"
+
+// GPUDevice is a GPU passed through to the guest, either a full PCI device
+// or a mediated vGPU.
+type GPUDevice struct {
+	// DeviceName is the device plugin resource advertised for the GPU, as
+	// listed in the KubeVirt permittedHostDevices (e.g.
+	// "nvidia.com/GP104_GEFORCE_GTX_1070").
+	DeviceName string
+	// Name is the name of the device in the VM. Defaults to "gpu-<index>".
+	Name string
+}
+
+// gpuName returns the name of GPU i in the VM.
+func gpuName(i int, gpu GPUDevice) string {
+	if gpu.Name != "" {
+		return gpu.Name
+	}
+	return fmt.Sprintf("gpu-%d", i)
+}
+
+// validateGPUs checks the GPU device names and that the VM names are unique.
+func validateGPUs(gpus []GPUDevice) error {
+	seen := map[string]bool{}
+	for i, gpu := range gpus {
+		if gpu.DeviceName == "" {
+			return invalidOptionsf("GPU %d has no device name", i)
+		}
+		if errs := validation.IsQualifiedName(gpu.DeviceName); len(errs) > 0 {
+			return invalidOptionsf("invalid GPU device name %q: %s", gpu.DeviceName, errs[0])
+		}
+		name := gpuName(i, gpu)
+		if errs := validation.IsDNS1123Label(name); len(errs) > 0 {
+			return invalidOptionsf("invalid GPU name %q: %s", name, errs[0])
+		}
+		if seen[name] {
+			return invalidOptionsf("GPU name %s used more than once", name)
+		}
+		seen[name] = true
+	}
+	return nil
+}
+
+// buildGPUs constructs the domain.devices.gpus section of the VM template.
+func buildGPUs(gpus []GPUDevice) []interface{} {
+	entries := make([]interface{}, 0, len(gpus))
+	for i, gpu := range gpus {
+		entries = append(entries, map[string]interface{}{
+			"name":       gpuName(i, gpu),
+			"deviceName": gpu.DeviceName,
+		})
+	}
+	return entries
+}
+
+// checkGPUs verifies that KubeVirt permits each requested GPU and that at
+// least one ready node KubeVirt can schedule on advertises it. Only those
+// nodes are listed, from the API server's watch cache. GPUs busy with other
+// VMs are not detected here; the VM then fails to schedule (see
+// GPUSchedulingFailure).
+func (c *Client) checkGPUs(ctx context.Context, gpus []GPUDevice) error {
+	info, err := c.GetClusterInfo(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to verify GPU devices: %w", err)
+	}
+	nodes, err := retryResult(ctx, c, func(ctx context.Context) (*corev1.NodeList, error) {
+		return c.clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{
+			LabelSelector:   labelKubeVirtSchedulable + "=true",
+			ResourceVersion: "0",
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list nodes: %w", err)
+	}
+	for _, gpu := range gpus {
+		if info.PermittedHostDevices != nil && !slices.Contains(info.PermittedHostDevices, gpu.DeviceName) {
+			return fmt.Errorf("%w: %s is not in the KubeVirt permittedHostDevices", ErrGPUUnavailable, gpu.DeviceName)
+		}
+		offered := slices.ContainsFunc(nodes.Items, func(node corev1.Node) bool {
+			allocatable := node.Status.Allocatable[corev1.ResourceName(gpu.DeviceName)]
+			return nodeReady(&node) && allocatable.Sign() > 0
+		})
+		if !offered {
+			return fmt.Errorf("%w: no ready node offers %s", ErrGPUCapacity, gpu.DeviceName)
+		}
+	}
+	return nil
+}
+
+// gpuRejection wraps a VM create error with ErrGPUUnavailable when the
+// KubeVirt webhook rejected one of the requested GPUs.
+func gpuRejection(err error, gpus []GPUDevice) error {
+	if !apierrors.IsInvalid(err) && !apierrors.IsForbidden(err) && !apierrors.IsBadRequest(err) {
+		return err
+	}
+	for _, gpu := range gpus {
+		if strings.Contains(err.Error(), gpu.DeviceName) {
+			return fmt.Errorf("%w: %s: %v", ErrGPUUnavailable, gpu.DeviceName, err)
+		}
+	}
+	return err
+}
+
+// GPUSchedulingFailure returns the scheduler message when the VM cannot be
+// scheduled because no node has one of its GPUs free, or "" otherwise.
+func (c *Client) GPUSchedulingFailure(ctx context.Context, name string) (string, error) {
+	vm, err := c.GetVM(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	gpus, _, _ := unstructured.NestedSlice(vm.Object, "spec", "template", "spec", "domain", "devices", "gpus")
+	if len(gpus) == 0 {
+		return "", nil
+	}
+	conditions, _, _ := unstructured.NestedSlice(vm.Object, "status", "conditions")
+	for _, cond := range conditions {
+		condMap, ok := cond.(map[string]interface{})
+		if !ok || condMap["type"] != "PodScheduled" || condMap["status"] != "False" || condMap["reason"] != "Unschedulable" {
+			continue
+		}
+		message, _ := condMap["message"].(string)
+		for _, gpu := range gpus {
+			deviceName, _, _ := unstructured.NestedString(gpu.(map[string]interface{}), "deviceName")
+			if deviceName != "" && strings.Contains(message, "Insufficient "+deviceName) {
+				return fmt.Sprintf("no node has a free %s: %s", deviceName, message), nil
+			}
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,128 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package harvester
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const testGPU = "nvidia.com/GP104_GEFORCE"
+
+// gpuNode returns a ready node advertising count GPUs.
+func gpuNode(count int64) *corev1.Node {
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1", Labels: map[string]string{labelKubeVirtSchedulable: "true"}},
+		Status: corev1.NodeStatus{
+			Allocatable: corev1.ResourceList{testGPU: *resource.NewQuantity(count, resource.DecimalSI)},
+			Conditions:  []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionTrue}},
+		},
+	}
+}
+
+var _ = Describe("GPU passthrough", func() {
+	var (
+		ctx  context.Context
+		opts VMCreateOptions
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		opts = testCreateOptions()
+		opts.GPUs = []GPUDevice{{DeviceName: testGPU}}
+	})
+
+	It("adds the GPUs to the VM", func() {
+		c := newTestClient(gpuNode(1))
+		setClusterInfo(c, &ClusterInfo{PermittedHostDevices: []string{testGPU}})
+
+		_, _, err := c.CreateVM(ctx, opts)
+		Expect(err).NotTo(HaveOccurred())
+		vm, err := c.GetVM(ctx, opts.Name)
+		Expect(err).NotTo(HaveOccurred())
+		gpus, _, _ := unstructured.NestedSlice(vm.Object, "spec", "template", "spec", "domain", "devices", "gpus")
+		Expect(gpus).To(ConsistOf(map[string]interface{}{"name": "gpu-0", "deviceName": testGPU}))
+	})
+
+	It("rejects a GPU KubeVirt does not permit", func() {
+		c := newTestClient(gpuNode(1))
+		setClusterInfo(c, &ClusterInfo{PermittedHostDevices: []string{}})
+
+		_, _, err := c.CreateVM(ctx, opts)
+		Expect(errors.Is(err, ErrGPUUnavailable)).To(BeTrue(), "got %v", err)
+		_, err = c.GetVM(ctx, opts.Name)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("reports a GPU no node offers as a capacity problem", func() {
+		c := newTestClient(gpuNode(0))
+		setClusterInfo(c, &ClusterInfo{})
+
+		_, _, err := c.CreateVM(ctx, opts)
+		Expect(errors.Is(err, ErrGPUCapacity)).To(BeTrue(), "got %v", err)
+		Expect(errors.Is(err, ErrGPUUnavailable)).To(BeFalse())
+	})
+
+	It("ignores nodes KubeVirt cannot schedule on", func() {
+		node := gpuNode(1)
+		node.Labels = nil
+		c := newTestClient(node)
+		setClusterInfo(c, &ClusterInfo{})
+
+		_, _, err := c.CreateVM(ctx, opts)
+		Expect(errors.Is(err, ErrGPUCapacity)).To(BeTrue(), "got %v", err)
+	})
+
+	It("reports a VM that cannot be scheduled for lack of a free GPU", func() {
+		c := newTestClient(gpuNode(1))
+		setClusterInfo(c, &ClusterInfo{})
+		_, _, err := c.CreateVM(ctx, opts)
+		Expect(err).NotTo(HaveOccurred())
+
+		message, err := c.GPUSchedulingFailure(ctx, opts.Name)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(message).To(BeEmpty())
+
+		vm, err := c.GetVM(ctx, opts.Name)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(unstructured.SetNestedSlice(vm.Object, []interface{}{map[string]interface{}{
+			"type":    "PodScheduled",
+			"status":  "False",
+			"reason":  "Unschedulable",
+			"message": "0/1 nodes are available: 1 Insufficient " + testGPU + ".",
+		}}, "status", "conditions")).To(Succeed())
+		_, err = c.dynamic.Resource(vmGVR).Namespace(testNamespace).Update(ctx, vm, metav1.UpdateOptions{})
+		Expect(err).NotTo(HaveOccurred())
+
+		message, err = c.GPUSchedulingFailure(ctx, opts.Name)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(message).To(ContainSubstring("no node has a free " + testGPU))
+	})
+
+	It("rejects duplicate GPU names", func() {
+		opts.GPUs = append(opts.GPUs, GPUDevice{DeviceName: testGPU, Name: "gpu-0"})
+		Expect(validateCreateOptions(opts)).To(MatchError(ErrInvalidOptions))
+	})
+})
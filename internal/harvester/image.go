@@ -0,0 +1,188 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package harvester
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// ImageOrderCreationTimestamp orders selector matches by creation time.
+const ImageOrderCreationTimestamp = "creationTimestamp"
+
+// ErrNoMatchingImage is returned when an image selector matches no imported
+// image.
+var ErrNoMatchingImage = errors.New("no image matches selector")
+
+var (
+	// ErrImageNotFound is returned when the image a root disk clones from
+	// does not exist.
+	ErrImageNotFound = errors.New("image not found")
+	// ErrImageImportFailed is returned when the image a root disk clones
+	// from failed to import.
+	ErrImageImportFailed = errors.New("image failed to import")
+	// ErrImageNotReady is returned while the image a root disk clones from
+	// is still importing.
+	ErrImageNotReady = errors.New("image not ready")
+)
+
+// imageStorageClassName returns the storage class Harvester conventionally
+// creates for the image ("namespace/name"), "longhorn-<name>".
+func imageStorageClassName(imageID string) string {
+	return fmt.Sprintf("longhorn-%s", parseName(imageID))
+}
+
+// imageStorageClass returns the storage class root disk PVCs clone the image
+// through, as reported in status.storageClassName. Images that do not report
+// one fall back to the naming convention.
+func imageStorageClass(ctx context.Context, image *unstructured.Unstructured, imageID string) string {
+	if name, _, _ := unstructured.NestedString(image.Object, "status", "storageClassName"); name != "" {
+		return name
+	}
+	fallback := imageStorageClassName(imageID)
+	logf.FromContext(ctx).Info("Image reports no storage class, falling back to the Harvester naming convention",
+		"image", imageID, "storageClass", fallback)
+	return fallback
+}
+
+// GetImage returns the Harvester image ("namespace/name", defaulting to the
+// Harvester namespace) once it has been imported. It fails with
+// ErrImageNotFound, ErrImageImportFailed or ErrImageNotReady otherwise.
+func (c *Client) GetImage(ctx context.Context, imageID string) (*unstructured.Unstructured, error) {
+	ns, name := splitRef(imageID, c.namespace)
+	image, err := retryResult(ctx, c, func(ctx context.Context) (*unstructured.Unstructured, error) {
+		return c.dynamic.Resource(imageGVR).Namespace(ns).Get(ctx, name, metav1.GetOptions{})
+	})
+	if apierrors.IsNotFound(err) || (err == nil && image.GetDeletionTimestamp() != nil) {
+		return nil, fmt.Errorf("%w: %s/%s (root disk storage class would be %s)", ErrImageNotFound, ns, name, imageStorageClassName(imageID))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get image %s/%s: %w", ns, name, err)
+	}
+	switch conditionStatus(image, "Imported") {
+	case "True":
+		return image, nil
+	case "False":
+		return nil, fmt.Errorf("%w: %s/%s: %s", ErrImageImportFailed, ns, name, conditionMessage(image, "Imported"))
+	default:
+		return nil, fmt.Errorf("%w: %s/%s is still importing", ErrImageNotReady, ns, name)
+	}
+}
+
+// ResolveImageSelector returns the "namespace/name" of the image matched by
+// opts.ImageSelector in opts.ImageNamespace, defaulting to the Harvester
+// namespace. Images that have not finished importing are ignored. Without
+// ImageSelectorOrder exactly one image must match; otherwise the newest match
+// by creation time or by the version in the ImageSelectorOrder label wins,
+// and a tie for newest is an error.
+func (c *Client) ResolveImageSelector(ctx context.Context, opts VMCreateOptions) (string, error) {
+	ns := opts.ImageNamespace
+	if ns == "" {
+		ns = c.namespace
+	}
+	list, err := retryResult(ctx, c, func(ctx context.Context) (*unstructured.UnstructuredList, error) {
+		return c.dynamic.Resource(imageGVR).Namespace(ns).List(ctx, metav1.ListOptions{LabelSelector: opts.ImageSelector})
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to list images: %w", err)
+	}
+
+	var matches []unstructured.Unstructured
+	for _, image := range list.Items {
+		if image.GetDeletionTimestamp() == nil && hasTrueCondition(&image, "Imported") {
+			matches = append(matches, image)
+		}
+	}
+	if len(matches) == 0 {
+		return "", fmt.Errorf("%w: %q in namespace %s", ErrNoMatchingImage, opts.ImageSelector, ns)
+	}
+	if len(matches) > 1 {
+		if opts.ImageSelectorOrder == "" {
+			return "", invalidOptionsf("image selector %q matches %d images (%s); refine it or set an order",
+				opts.ImageSelector, len(matches), strings.Join(imageNames(matches), ", "))
+		}
+		newer := imageOrder(opts.ImageSelectorOrder)
+		sort.SliceStable(matches, func(i, j int) bool { return newer(&matches[i], &matches[j]) > 0 })
+		if newer(&matches[0], &matches[1]) == 0 {
+			return "", invalidOptionsf("image selector %q matches %s and %s, which tie on %s",
+				opts.ImageSelector, matches[0].GetName(), matches[1].GetName(), opts.ImageSelectorOrder)
+		}
+	}
+	return ns + "/" + matches[0].GetName(), nil
+}
+
+// imageOrder returns a comparison reporting whether image a is newer (>0),
+// older (<0) or tied (0) with b under the given order.
+func imageOrder(order string) func(a, b *unstructured.Unstructured) int {
+	if order == ImageOrderCreationTimestamp {
+		return func(a, b *unstructured.Unstructured) int {
+			return a.GetCreationTimestamp().Compare(b.GetCreationTimestamp().Time)
+		}
+	}
+	return func(a, b *unstructured.Unstructured) int {
+		return compareVersions(a.GetLabels()[order], b.GetLabels()[order])
+	}
+}
+
+// compareVersions compares dotted versions such as "22.04.3" numerically
+// field by field, falling back to string comparison for non-numeric fields.
+// An optional leading "v" is ignored and a missing version is oldest.
+func compareVersions(a, b string) int {
+	if a == "" || b == "" {
+		return strings.Compare(a, b)
+	}
+	as := strings.Split(strings.TrimPrefix(a, "v"), ".")
+	bs := strings.Split(strings.TrimPrefix(b, "v"), ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		if i >= len(as) {
+			return -1
+		}
+		if i >= len(bs) {
+			return 1
+		}
+		an, aErr := strconv.Atoi(as[i])
+		bn, bErr := strconv.Atoi(bs[i])
+		var c int
+		if aErr == nil && bErr == nil {
+			c = an - bn
+		} else {
+			c = strings.Compare(as[i], bs[i])
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// imageNames returns the names of the images.
+func imageNames(images []unstructured.Unstructured) []string {
+	names := make([]string, 0, len(images))
+	for _, image := range images {
+		names = append(names, image.GetName())
+	}
+	return names
+}
@@ -0,0 +1,176 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package harvester
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+// addImage registers an imported Harvester image in the test namespace.
+func addImage(c *Client, name string, created time.Time, labels map[string]string) {
+	image := &unstructured.Unstructured{}
+	image.SetAPIVersion("harvesterhci.io/v1beta1")
+	image.SetKind("VirtualMachineImage")
+	image.SetNamespace(testNamespace)
+	image.SetName(name)
+	image.SetLabels(labels)
+	image.SetCreationTimestamp(metav1.NewTime(created))
+	Expect(unstructured.SetNestedSlice(image.Object, []interface{}{
+		map[string]interface{}{"type": "Imported", "status": "True"},
+	}, "status", "conditions")).To(Succeed())
+	Expect(c.dynamic.(*dynamicfake.FakeDynamicClient).Tracker().Create(imageGVR, image, testNamespace)).To(Succeed())
+}
+
+var _ = Describe("Image selector", func() {
+	var (
+		ctx  context.Context
+		c    *Client
+		opts VMCreateOptions
+		now  = time.Now()
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		c = newTestClient()
+		opts = VMCreateOptions{ImageSelector: "os=ubuntu"}
+		addImage(c, "ubuntu-a", now.Add(-time.Hour), map[string]string{"os": "ubuntu", "version": "22.04.10"})
+		addImage(c, "ubuntu-b", now, map[string]string{"os": "ubuntu", "version": "22.04.9"})
+		addImage(c, "debian", now, map[string]string{"os": "debian"})
+	})
+
+	It("rejects several matches without an order", func() {
+		_, err := c.ResolveImageSelector(ctx, opts)
+		Expect(errors.Is(err, ErrInvalidOptions)).To(BeTrue(), "got %v", err)
+	})
+
+	It("picks the newest match by creation time", func() {
+		opts.ImageSelectorOrder = ImageOrderCreationTimestamp
+		Expect(c.ResolveImageSelector(ctx, opts)).To(Equal(testNamespace + "/ubuntu-b"))
+	})
+
+	It("picks the newest match by version label", func() {
+		opts.ImageSelectorOrder = "version"
+		Expect(c.ResolveImageSelector(ctx, opts)).To(Equal(testNamespace + "/ubuntu-a"))
+	})
+
+	It("reports when nothing matches", func() {
+		opts.ImageSelector = "os=fedora"
+		_, err := c.ResolveImageSelector(ctx, opts)
+		Expect(errors.Is(err, ErrNoMatchingImage)).To(BeTrue(), "got %v", err)
+	})
+})
+
+var _ = Describe("Image validation", func() {
+	var (
+		ctx  context.Context
+		c    *Client
+		opts VMCreateOptions
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		c = newTestClient()
+		opts = testCreateOptions()
+	})
+
+	// addImportingImage registers an image whose Imported condition has the
+	// given status.
+	addImportingImage := func(name, status, message string) {
+		image := &unstructured.Unstructured{}
+		image.SetAPIVersion("harvesterhci.io/v1beta1")
+		image.SetKind("VirtualMachineImage")
+		image.SetNamespace(testNamespace)
+		image.SetName(name)
+		Expect(unstructured.SetNestedSlice(image.Object, []interface{}{
+			map[string]interface{}{"type": "Imported", "status": status, "message": message},
+		}, "status", "conditions")).To(Succeed())
+		Expect(c.dynamic.(*dynamicfake.FakeDynamicClient).Tracker().Create(imageGVR, image, testNamespace)).To(Succeed())
+	}
+
+	expectNoRootDisk := func() {
+		pvcs, err := c.clientset.CoreV1().PersistentVolumeClaims(testNamespace).List(ctx, metav1.ListOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pvcs.Items).To(BeEmpty())
+	}
+
+	It("returns an imported image", func() {
+		image, err := c.GetImage(ctx, "default/image-abc12")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(image.GetName()).To(Equal("image-abc12"))
+	})
+
+	It("refuses to create a VM from a missing image", func() {
+		opts.ImageName = "default/image-missing"
+		_, _, err := c.CreateVM(ctx, opts)
+		Expect(errors.Is(err, ErrImageNotFound)).To(BeTrue(), "got %v", err)
+		Expect(err.Error()).To(ContainSubstring("longhorn-image-missing"))
+		expectNoRootDisk()
+	})
+
+	It("refuses to create a VM from an image that failed to import", func() {
+		addImportingImage("broken", "False", "download failed")
+		opts.ImageName = testNamespace + "/broken"
+		_, _, err := c.CreateVM(ctx, opts)
+		Expect(errors.Is(err, ErrImageImportFailed)).To(BeTrue(), "got %v", err)
+		Expect(err.Error()).To(ContainSubstring("download failed"))
+		expectNoRootDisk()
+	})
+
+	rootDiskStorageClass := func() string {
+		pvc, err := c.clientset.CoreV1().PersistentVolumeClaims(testNamespace).Get(ctx, RootDiskPVCName(opts.Name), metav1.GetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pvc.Spec.StorageClassName).NotTo(BeNil())
+		return *pvc.Spec.StorageClassName
+	}
+
+	It("clones the root disk through the storage class the image reports", func() {
+		addImportingImage("ubuntu", "True", "")
+		image, err := c.dynamic.Resource(imageGVR).Namespace(testNamespace).Get(ctx, "ubuntu", metav1.GetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(unstructured.SetNestedField(image.Object, "longhorn-image-x7k2p", "status", "storageClassName")).To(Succeed())
+		_, err = c.dynamic.Resource(imageGVR).Namespace(testNamespace).Update(ctx, image, metav1.UpdateOptions{})
+		Expect(err).NotTo(HaveOccurred())
+
+		opts.ImageName = testNamespace + "/ubuntu"
+		_, _, err = c.CreateVM(ctx, opts)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rootDiskStorageClass()).To(Equal("longhorn-image-x7k2p"))
+	})
+
+	It("falls back to the naming convention when the image reports no storage class", func() {
+		_, _, err := c.CreateVM(ctx, opts)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rootDiskStorageClass()).To(Equal("longhorn-image-abc12"))
+	})
+
+	It("waits for an image that is still importing", func() {
+		addImportingImage("downloading", "Unknown", "")
+		opts.ImageName = testNamespace + "/downloading"
+		_, _, err := c.CreateVM(ctx, opts)
+		Expect(errors.Is(err, ErrImageNotReady)).To(BeTrue(), "got %v", err)
+		expectNoRootDisk()
+	})
+})
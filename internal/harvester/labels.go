@@ -0,0 +1,49 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package harvester
+
+import (
+	"sort"
+	"strings"
+)
+
+// LabelManagedBy marks Harvester resources created by this provider.
+const LabelManagedBy = "butler.butlerlabs.dev/managed-by"
+
+// managedByValue is the LabelManagedBy value for this provider.
+const managedByValue = "butler-provider-harvester"
+
+// desiredLabels returns the labels the provider manages on a VM: the
+// managed-by label plus the request labels.
+func desiredLabels(requestLabels map[string]string) map[string]string {
+	labels := map[string]string{LabelManagedBy: managedByValue}
+	for k, v := range requestLabels {
+		labels[k] = v
+	}
+	return labels
+}
+
+// managedKeys returns the sorted, comma-separated keys of labels, as stored in
+// AnnotationManagedLabels.
+func managedKeys(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, ",")
+}
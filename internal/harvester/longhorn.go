@@ -0,0 +1,140 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package harvester
+
+import (
+	"context"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var (
+	longhornVolumeGVR = schema.GroupVersionResource{
+		Group:    "longhorn.io",
+		Version:  "v1beta2",
+		Resource: "volumes",
+	}
+
+	longhornReplicaGVR = schema.GroupVersionResource{
+		Group:    "longhorn.io",
+		Version:  "v1beta2",
+		Resource: "replicas",
+	}
+)
+
+const (
+	// longhornCSIDriver is the CSI driver of Longhorn-backed volumes.
+	longhornCSIDriver = "driver.longhorn.io"
+	// longhornVolumeLabel is set on each Longhorn replica to its volume name.
+	longhornVolumeLabel = "longhornvolume"
+)
+
+// StorageBackend describes the Longhorn volume backing a disk.
+type StorageBackend struct {
+	// Volume is the Longhorn volume name.
+	Volume string
+	// Robustness is the Longhorn volume robustness ("healthy", "degraded",
+	// "faulted" or "unknown").
+	Robustness string
+	// Replicas is the desired replica count and HealthyReplicas the number
+	// of replicas that are running and have finished rebuilding.
+	Replicas        int
+	HealthyReplicas int
+	// Nodes are the nodes holding a replica, sorted.
+	Nodes []string
+	// ReplicasUnknown is set when the replicas could not be read, e.g. for
+	// lack of permission. HealthyReplicas and Nodes are then empty and say
+	// nothing about the volume's health.
+	ReplicasUnknown bool
+}
+
+// GetStorageBackend reports the Longhorn volume and replicas backing the PVC.
+// Details are best-effort: it returns nil without an error when the PVC is
+// not bound or not backed by Longhorn, or when the PV or Longhorn resources
+// are missing or not readable with the provider credentials.
+func (c *Client) GetStorageBackend(ctx context.Context, pvcName string) (*StorageBackend, error) {
+	pvc, err := c.getPVC(ctx, pvcName)
+	if err != nil {
+		return nil, ignoreInaccessible(err)
+	}
+	if pvc.Spec.VolumeName == "" {
+		return nil, nil
+	}
+	pv, err := retryResult(ctx, c, func(ctx context.Context) (*corev1.PersistentVolume, error) {
+		return c.clientset.CoreV1().PersistentVolumes().Get(ctx, pvc.Spec.VolumeName, metav1.GetOptions{})
+	})
+	if err != nil {
+		return nil, ignoreInaccessible(err)
+	}
+	if pv.Spec.CSI == nil || pv.Spec.CSI.Driver != longhornCSIDriver {
+		return nil, nil
+	}
+
+	name := pv.Spec.CSI.VolumeHandle
+	volume, err := retryResult(ctx, c, func(ctx context.Context) (*unstructured.Unstructured, error) {
+		return c.dynamic.Resource(longhornVolumeGVR).Namespace(longhornNamespace).Get(ctx, name, metav1.GetOptions{})
+	})
+	if err != nil {
+		return nil, ignoreInaccessible(err)
+	}
+	backend := &StorageBackend{Volume: name}
+	backend.Robustness, _, _ = unstructured.NestedString(volume.Object, "status", "robustness")
+	replicas, _, _ := unstructured.NestedInt64(volume.Object, "spec", "numberOfReplicas")
+	backend.Replicas = int(replicas)
+
+	list, err := retryResult(ctx, c, func(ctx context.Context) (*unstructured.UnstructuredList, error) {
+		return c.dynamic.Resource(longhornReplicaGVR).Namespace(longhornNamespace).List(ctx, metav1.ListOptions{
+			LabelSelector: longhornVolumeLabel + "=" + name,
+		})
+	})
+	if err != nil {
+		if err := ignoreInaccessible(err); err != nil {
+			return nil, err
+		}
+		backend.ReplicasUnknown = true
+		return backend, nil
+	}
+	for _, replica := range list.Items {
+		if node, _, _ := unstructured.NestedString(replica.Object, "spec", "nodeID"); node != "" {
+			backend.Nodes = append(backend.Nodes, node)
+		}
+		state, _, _ := unstructured.NestedString(replica.Object, "status", "currentState")
+		healthyAt, _, _ := unstructured.NestedString(replica.Object, "spec", "healthyAt")
+		failedAt, _, _ := unstructured.NestedString(replica.Object, "spec", "failedAt")
+		if state == "running" && healthyAt != "" && failedAt == "" {
+			backend.HealthyReplicas++
+		}
+	}
+	sort.Strings(backend.Nodes)
+	return backend, nil
+}
+
+// ignoreInaccessible drops errors caused by a resource being absent or not
+// readable, which is expected on clusters without Longhorn or with
+// restricted credentials.
+func ignoreInaccessible(err error) error {
+	if apierrors.IsNotFound(err) || apierrors.IsForbidden(err) || meta.IsNoMatchError(err) {
+		return nil
+	}
+	return err
+}
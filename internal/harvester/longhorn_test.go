@@ -0,0 +1,129 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package harvester
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// longhornObject returns a Longhorn object of the given kind in
+// longhorn-system.
+func longhornObject(kind, name string, labels map[string]string, fields map[string]interface{}) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: fields}
+	obj.SetAPIVersion("longhorn.io/v1beta2")
+	obj.SetKind(kind)
+	obj.SetNamespace(longhornNamespace)
+	obj.SetName(name)
+	obj.SetLabels(labels)
+	return obj
+}
+
+// longhornReplica returns a replica of volume on node, healthy unless failed.
+func longhornReplica(name, volume, node string, failed bool) *unstructured.Unstructured {
+	spec := map[string]interface{}{"nodeID": node, "healthyAt": "2026-01-01T00:00:00Z"}
+	if failed {
+		spec["failedAt"] = "2026-01-02T00:00:00Z"
+	}
+	return longhornObject("Replica", name, map[string]string{longhornVolumeLabel: volume}, map[string]interface{}{
+		"spec":   spec,
+		"status": map[string]interface{}{"currentState": "running"},
+	})
+}
+
+var _ = Describe("Longhorn storage backend", func() {
+	var (
+		ctx context.Context
+		c   *Client
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		pvc := &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: "worker-0-rootdisk", Namespace: testNamespace},
+			Spec:       corev1.PersistentVolumeClaimSpec{VolumeName: "pvc-1"},
+		}
+		pv := &corev1.PersistentVolume{
+			ObjectMeta: metav1.ObjectMeta{Name: "pvc-1"},
+			Spec: corev1.PersistentVolumeSpec{PersistentVolumeSource: corev1.PersistentVolumeSource{
+				CSI: &corev1.CSIPersistentVolumeSource{Driver: longhornCSIDriver, VolumeHandle: "pvc-1"},
+			}},
+		}
+		c = newTestClient(pvc, pv)
+		tracker := c.dynamic.(*dynamicfake.FakeDynamicClient).Tracker()
+		Expect(tracker.Create(longhornVolumeGVR, longhornObject("Volume", "pvc-1", nil, map[string]interface{}{
+			"spec":   map[string]interface{}{"numberOfReplicas": int64(3)},
+			"status": map[string]interface{}{"robustness": "degraded"},
+		}), longhornNamespace)).To(Succeed())
+		Expect(tracker.Create(longhornReplicaGVR, longhornReplica("r-1", "pvc-1", "node-2", false), longhornNamespace)).To(Succeed())
+		Expect(tracker.Create(longhornReplicaGVR, longhornReplica("r-2", "pvc-1", "node-1", false), longhornNamespace)).To(Succeed())
+		Expect(tracker.Create(longhornReplicaGVR, longhornReplica("r-3", "pvc-1", "node-3", true), longhornNamespace)).To(Succeed())
+		Expect(tracker.Create(longhornReplicaGVR, longhornReplica("r-4", "pvc-2", "node-4", false), longhornNamespace)).To(Succeed())
+	})
+
+	It("counts the healthy replicas of the volume", func() {
+		backend, err := c.GetStorageBackend(ctx, "worker-0-rootdisk")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(*backend).To(Equal(StorageBackend{
+			Volume:          "pvc-1",
+			Robustness:      "degraded",
+			Replicas:        3,
+			HealthyReplicas: 2,
+			Nodes:           []string{"node-1", "node-2", "node-3"},
+		}))
+	})
+
+	It("reports the replicas as unknown when they cannot be listed", func() {
+		c.dynamic.(*dynamicfake.FakeDynamicClient).PrependReactor("list", "replicas",
+			func(k8stesting.Action) (bool, runtime.Object, error) {
+				return true, nil, apierrors.NewForbidden(schema.GroupResource{Group: "longhorn.io", Resource: "replicas"}, "", nil)
+			})
+
+		backend, err := c.GetStorageBackend(ctx, "worker-0-rootdisk")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(backend.ReplicasUnknown).To(BeTrue())
+		Expect(backend.Replicas).To(Equal(3))
+		Expect(backend.HealthyReplicas).To(BeZero())
+	})
+
+	It("reports nothing when the volume cannot be read", func() {
+		c.dynamic.(*dynamicfake.FakeDynamicClient).PrependReactor("get", "volumes",
+			func(k8stesting.Action) (bool, runtime.Object, error) {
+				return true, nil, apierrors.NewForbidden(schema.GroupResource{Group: "longhorn.io", Resource: "volumes"}, "pvc-1", nil)
+			})
+
+		backend, err := c.GetStorageBackend(ctx, "worker-0-rootdisk")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(backend).To(BeNil())
+	})
+
+	It("reports nothing for a missing PVC", func() {
+		backend, err := c.GetStorageBackend(ctx, "missing")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(backend).To(BeNil())
+	})
+})
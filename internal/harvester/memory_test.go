@@ -0,0 +1,129 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package harvester
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Explicit memory requests and limits", func() {
+	It("derives the request and limit from guest memory when unset", func() {
+		opts := testCreateOptions()
+		request := MemoryRequest(opts)
+		limit := MemoryLimit(opts)
+		Expect(request.String()).To(Equal("4Gi"))
+		Expect(limit.String()).To(Equal("4178Mi"))
+	})
+
+	It("uses the explicit request and limit", func() {
+		opts := testCreateOptions()
+		opts.MemoryRequestMB = 2048
+		opts.MemoryLimitMB = 4608
+		Expect(validateCreateOptions(opts)).To(Succeed())
+
+		request := MemoryRequest(opts)
+		limit := MemoryLimit(opts)
+		Expect(request.String()).To(Equal("2Gi"))
+		Expect(limit.String()).To(Equal("4608Mi"))
+	})
+
+	It("keeps the explicit values in the effective options", func() {
+		opts := testCreateOptions()
+		opts.EnableOvercommit = true
+		opts.MemoryRequestMB = 1024
+		opts.MemoryLimitMB = 4096
+
+		_, effective, err := newTestClient().CreateVM(context.Background(), opts)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(effective.MemoryOverheadMB).To(BeNil())
+		Expect(effective.MemoryRequestPercent).To(BeZero())
+		request := MemoryRequest(effective)
+		Expect(request.String()).To(Equal("1Gi"))
+	})
+
+	DescribeTable("rejects inconsistent values",
+		func(mutate func(*VMCreateOptions), message string) {
+			opts := testCreateOptions()
+			mutate(&opts)
+			err := validateCreateOptions(opts)
+			Expect(errors.Is(err, ErrInvalidOptions)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring(message))
+		},
+		Entry("negative request", func(o *VMCreateOptions) { o.MemoryRequestMB = -1 }, "must not be negative"),
+		Entry("request above guest memory", func(o *VMCreateOptions) { o.MemoryRequestMB = 8192 }, "exceeds the guest memory"),
+		Entry("limit below guest memory", func(o *VMCreateOptions) { o.MemoryLimitMB = 2048 }, "below the guest memory"),
+		Entry("request with request percent", func(o *VMCreateOptions) {
+			o.MemoryRequestMB = 2048
+			o.EnableOvercommit = true
+			o.MemoryRequestPercent = 50
+		}, "memory request percent"),
+		Entry("limit with overhead", func(o *VMCreateOptions) {
+			overhead := int32(100)
+			o.MemoryLimitMB = 4608
+			o.MemoryOverheadMB = &overhead
+		}, "memory overhead"),
+		Entry("overcommitted request with hugepages", func(o *VMCreateOptions) {
+			o.MemoryRequestMB = 2048
+			o.HugepagesPageSize = "2Mi"
+		}, "hugepages"),
+		Entry("request with dedicated CPU placement", func(o *VMCreateOptions) {
+			o.MemoryRequestMB = 4096
+			o.DedicatedCPUPlacement = true
+		}, "dedicatedCpuPlacement"),
+	)
+})
+
+var _ = Describe("Explicit CPU request", func() {
+	cpuRequest := func(opts VMCreateOptions) interface{} {
+		requests := buildResources(opts)["requests"].(map[string]interface{})
+		return requests["cpu"]
+	}
+
+	It("requests a small share of a core when unset", func() {
+		Expect(cpuRequest(testCreateOptions())).To(Equal(defaultCPURequest))
+	})
+
+	It("uses the explicit request and keeps the limit at the CPU count", func() {
+		opts := testCreateOptions()
+		opts.CPURequestMillicores = 1500
+		Expect(validateCreateOptions(opts)).To(Succeed())
+
+		resources := buildResources(opts)
+		Expect(cpuRequest(opts)).To(Equal("1500m"))
+		Expect(resources["limits"].(map[string]interface{})["cpu"]).To(Equal("2"))
+	})
+
+	DescribeTable("rejects inconsistent values",
+		func(mutate func(*VMCreateOptions), message string) {
+			opts := testCreateOptions()
+			mutate(&opts)
+			err := validateCreateOptions(opts)
+			Expect(errors.Is(err, ErrInvalidOptions)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring(message))
+		},
+		Entry("negative request", func(o *VMCreateOptions) { o.CPURequestMillicores = -1 }, "must not be negative"),
+		Entry("request above the CPU limit", func(o *VMCreateOptions) { o.CPURequestMillicores = 3000 }, "exceeds the 2 CPU limit"),
+		Entry("request with dedicated CPU placement", func(o *VMCreateOptions) {
+			o.CPURequestMillicores = 1000
+			o.DedicatedCPUPlacement = true
+		}, "dedicatedCpuPlacement"),
+	)
+})
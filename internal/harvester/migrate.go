@@ -0,0 +1,226 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package harvester
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilrand "k8s.io/apimachinery/pkg/util/rand"
+)
+
+var vmimGVR = schema.GroupVersionResource{
+	Group:    "kubevirt.io",
+	Version:  "v1",
+	Resource: "virtualmachineinstancemigrations",
+}
+
+// ErrNotMigratable is returned when KubeVirt cannot live migrate a VM, e.g.
+// one with a ReadWriteOnce disk, an SR-IOV interface or a GPU.
+var ErrNotMigratable = errors.New("not live migratable")
+
+// Migration phases reported by KubeVirt that end a migration.
+const (
+	MigrationPhaseSucceeded = "Succeeded"
+	MigrationPhaseFailed    = "Failed"
+)
+
+// MigrationStatus describes a live migration started by MigrateVM.
+type MigrationStatus struct {
+	Name string
+	// Phase is the KubeVirt migration phase, e.g. "Scheduling", "Running",
+	// "Succeeded" or "Failed". Empty until KubeVirt picks the migration up.
+	Phase string
+	// SourceNode and TargetNode are the nodes the VM moves between, once
+	// KubeVirt reports them.
+	SourceNode string
+	TargetNode string
+	// Message explains a failed migration when KubeVirt reports why.
+	Message string
+}
+
+// Finished reports whether the migration succeeded or failed.
+func (s *MigrationStatus) Finished() bool {
+	return s.Phase == MigrationPhaseSucceeded || s.Phase == MigrationPhaseFailed
+}
+
+// EvacuationFailure is a VM EvacuateNode could not migrate.
+type EvacuationFailure struct {
+	VM     string
+	Reason string
+}
+
+// NodeEvacuation reports the outcome of EvacuateNode.
+type NodeEvacuation struct {
+	Node string
+	// Migrating lists the VMs now being live migrated off the node,
+	// including those whose migration was already in progress.
+	Migrating []string
+	// Failed lists the VMs that stay on the node and why.
+	Failed []EvacuationFailure
+}
+
+// MigrateVM starts a live migration of the running VM to another node and
+// returns the name of the migration, for GetMigrationStatus. It fails with
+// ErrNotMigratable when KubeVirt reports the VM cannot be live migrated.
+func (c *Client) MigrateVM(ctx context.Context, name string) (string, error) {
+	vmi, err := c.GetVMI(ctx, name)
+	if err != nil {
+		return "", fmt.Errorf("failed to get VMI %s: %w", name, err)
+	}
+	return c.migrate(ctx, vmi)
+}
+
+// migrate starts a live migration of the VMI once KubeVirt reports it live
+// migratable.
+func (c *Client) migrate(ctx context.Context, vmi *unstructured.Unstructured) (string, error) {
+	name := vmi.GetName()
+	if ok, reason := liveMigratable(vmi); !ok {
+		return "", fmt.Errorf("%w: %s", ErrNotMigratable, reason)
+	}
+	migration := &unstructured.Unstructured{}
+	migration.SetAPIVersion("kubevirt.io/v1")
+	migration.SetKind("VirtualMachineInstanceMigration")
+	migration.SetNamespace(c.namespace)
+	migration.SetName(fmt.Sprintf("%s-migration-%s", name, utilrand.String(5)))
+	migration.SetLabels(map[string]string{LabelManagedBy: managedByValue})
+	if err := unstructured.SetNestedField(migration.Object, name, "spec", "vmiName"); err != nil {
+		return "", err
+	}
+	if _, err := retryCreate(ctx, c, func(ctx context.Context) (*unstructured.Unstructured, error) {
+		return c.dynamic.Resource(vmimGVR).Namespace(c.namespace).Create(ctx, migration, metav1.CreateOptions{})
+	}, func(ctx context.Context) (*unstructured.Unstructured, error) {
+		return c.dynamic.Resource(vmimGVR).Namespace(c.namespace).Get(ctx, migration.GetName(), metav1.GetOptions{})
+	}); err != nil {
+		return "", fmt.Errorf("failed to migrate VM %s: %w", name, err)
+	}
+	return migration.GetName(), nil
+}
+
+// GetMigrationStatus returns the progress of the named migration. The nodes
+// and failure message come from the migration state of the VMI, which only
+// describes its most recent migration.
+func (c *Client) GetMigrationStatus(ctx context.Context, name string) (*MigrationStatus, error) {
+	migration, err := retryResult(ctx, c, func(ctx context.Context) (*unstructured.Unstructured, error) {
+		return c.dynamic.Resource(vmimGVR).Namespace(c.namespace).Get(ctx, name, metav1.GetOptions{})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get migration %s: %w", name, err)
+	}
+	status := &MigrationStatus{Name: name}
+	status.Phase, _, _ = unstructured.NestedString(migration.Object, "status", "phase")
+
+	vmiName, _, _ := unstructured.NestedString(migration.Object, "spec", "vmiName")
+	vmi, err := c.GetVMI(ctx, vmiName)
+	if err != nil {
+		// The VMI is informational; a VM stopped mid-migration has none
+		return status, nil
+	}
+	state, _, _ := unstructured.NestedMap(vmi.Object, "status", "migrationState")
+	if uid, _ := state["migrationUid"].(string); uid != "" && uid != string(migration.GetUID()) {
+		return status, nil
+	}
+	status.SourceNode, _ = state["sourceNode"].(string)
+	status.TargetNode, _ = state["targetNode"].(string)
+	status.Message, _ = state["failureReason"].(string)
+	return status, nil
+}
+
+// migrationsInProgress returns the VMIs with a migration that has not
+// finished yet.
+func (c *Client) migrationsInProgress(ctx context.Context) (map[string]bool, error) {
+	migrations, err := retryResult(ctx, c, func(ctx context.Context) (*unstructured.UnstructuredList, error) {
+		return c.dynamic.Resource(vmimGVR).Namespace(c.namespace).List(ctx, metav1.ListOptions{})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list migrations: %w", err)
+	}
+	inProgress := map[string]bool{}
+	for _, m := range migrations.Items {
+		phase, _, _ := unstructured.NestedString(m.Object, "status", "phase")
+		if phase == MigrationPhaseSucceeded || phase == MigrationPhaseFailed {
+			continue
+		}
+		vmi, _, _ := unstructured.NestedString(m.Object, "spec", "vmiName")
+		inProgress[vmi] = true
+	}
+	return inProgress, nil
+}
+
+// liveMigratable reports whether KubeVirt can live migrate the VMI and, if
+// not, why.
+func liveMigratable(vmi *unstructured.Unstructured) (bool, string) {
+	conditions, _, _ := unstructured.NestedSlice(vmi.Object, "status", "conditions")
+	for _, cond := range conditions {
+		condMap, ok := cond.(map[string]interface{})
+		if !ok || condMap["type"] != "LiveMigratable" {
+			continue
+		}
+		if condMap["status"] == "True" {
+			return true, ""
+		}
+		message, _ := condMap["message"].(string)
+		if message == "" {
+			message, _ = condMap["reason"].(string)
+		}
+		return false, message
+	}
+	return false, "KubeVirt has not reported the VM as live migratable"
+}
+
+// EvacuateNode live migrates every VM managed by this provider off the node.
+// VMs that cannot be live migrated, e.g. with SR-IOV interfaces, GPUs or
+// ReadWriteOnce disks, are left running and reported in Failed. Migrations
+// complete asynchronously; call it again to re-check the node.
+func (c *Client) EvacuateNode(ctx context.Context, nodeName string) (*NodeEvacuation, error) {
+	vmis, err := retryResult(ctx, c, func(ctx context.Context) (*unstructured.UnstructuredList, error) {
+		return c.dynamic.Resource(vmiGVR).Namespace(c.namespace).List(ctx, metav1.ListOptions{
+			LabelSelector: labels.SelectorFromSet(labels.Set{LabelManagedBy: managedByValue}).String(),
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list VMIs: %w", err)
+	}
+	inProgress, err := c.migrationsInProgress(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	evacuation := &NodeEvacuation{Node: nodeName}
+	for i := range vmis.Items {
+		vmi := &vmis.Items[i]
+		if node, _, _ := unstructured.NestedString(vmi.Object, "status", "nodeName"); node != nodeName || vmi.GetDeletionTimestamp() != nil {
+			continue
+		}
+		name := vmi.GetName()
+		if inProgress[name] {
+			evacuation.Migrating = append(evacuation.Migrating, name)
+			continue
+		}
+		if _, err := c.migrate(ctx, vmi); err != nil {
+			evacuation.Failed = append(evacuation.Failed, EvacuationFailure{VM: name, Reason: err.Error()})
+			continue
+		}
+		evacuation.Migrating = append(evacuation.Migrating, name)
+	}
+	return evacuation, nil
+}
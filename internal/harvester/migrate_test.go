@@ -0,0 +1,173 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package harvester
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+// addNodeVMI registers a managed VMI running on node with the given
+// LiveMigratable condition status.
+func addNodeVMI(c *Client, name, node, migratable string) {
+	vmi := &unstructured.Unstructured{}
+	vmi.SetAPIVersion("kubevirt.io/v1")
+	vmi.SetKind("VirtualMachineInstance")
+	vmi.SetNamespace(testNamespace)
+	vmi.SetName(name)
+	vmi.SetLabels(map[string]string{LabelManagedBy: managedByValue})
+	Expect(unstructured.SetNestedField(vmi.Object, node, "status", "nodeName")).To(Succeed())
+	Expect(unstructured.SetNestedSlice(vmi.Object, []interface{}{map[string]interface{}{
+		"type":    "LiveMigratable",
+		"status":  migratable,
+		"reason":  "DisksNotLiveMigratable",
+		"message": "cannot migrate VMI: PVC rootdisk is not shared",
+	}}, "status", "conditions")).To(Succeed())
+	Expect(c.dynamic.(*dynamicfake.FakeDynamicClient).Tracker().Create(vmiGVR, vmi, testNamespace)).To(Succeed())
+}
+
+var _ = Describe("Node evacuation", func() {
+	var (
+		ctx context.Context
+		c   *Client
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		c = newTestClient()
+	})
+
+	migrations := func() []unstructured.Unstructured {
+		list, err := c.dynamic.Resource(vmimGVR).Namespace(testNamespace).List(ctx, metav1.ListOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		return list.Items
+	}
+
+	It("migrates the migratable VMs on the node and reports the others", func() {
+		addNodeVMI(c, "worker-0", "node-1", "True")
+		addNodeVMI(c, "worker-1", "node-1", "False")
+		addNodeVMI(c, "worker-2", "node-2", "True")
+
+		evacuation, err := c.EvacuateNode(ctx, "node-1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(evacuation.Migrating).To(Equal([]string{"worker-0"}))
+		Expect(evacuation.Failed).To(ConsistOf(EvacuationFailure{
+			VM:     "worker-1",
+			Reason: "not live migratable: cannot migrate VMI: PVC rootdisk is not shared",
+		}))
+
+		Expect(migrations()).To(HaveLen(1))
+		vmiName, _, _ := unstructured.NestedString(migrations()[0].Object, "spec", "vmiName")
+		Expect(vmiName).To(Equal("worker-0"))
+	})
+
+	It("does not start a second migration while one is in progress", func() {
+		addNodeVMI(c, "worker-0", "node-1", "True")
+		_, err := c.EvacuateNode(ctx, "node-1")
+		Expect(err).NotTo(HaveOccurred())
+
+		evacuation, err := c.EvacuateNode(ctx, "node-1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(evacuation.Migrating).To(Equal([]string{"worker-0"}))
+		Expect(migrations()).To(HaveLen(1))
+	})
+})
+
+var _ = Describe("Live migration", func() {
+	var (
+		ctx context.Context
+		c   *Client
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		c = newTestClient()
+	})
+
+	// setMigration sets the phase of the migration and the VMI's migration
+	// state the way KubeVirt reports them.
+	setMigration := func(name, vmiName, phase string, state map[string]interface{}) {
+		tracker := c.dynamic.(*dynamicfake.FakeDynamicClient).Tracker()
+		obj, err := tracker.Get(vmimGVR, testNamespace, name)
+		Expect(err).NotTo(HaveOccurred())
+		migration := obj.(*unstructured.Unstructured)
+		migration.SetUID("migration-uid")
+		Expect(unstructured.SetNestedField(migration.Object, phase, "status", "phase")).To(Succeed())
+		Expect(tracker.Update(vmimGVR, migration, testNamespace)).To(Succeed())
+
+		obj, err = tracker.Get(vmiGVR, testNamespace, vmiName)
+		Expect(err).NotTo(HaveOccurred())
+		vmi := obj.(*unstructured.Unstructured)
+		Expect(unstructured.SetNestedMap(vmi.Object, state, "status", "migrationState")).To(Succeed())
+		Expect(tracker.Update(vmiGVR, vmi, testNamespace)).To(Succeed())
+	}
+
+	It("starts a migration and reports its progress", func() {
+		addNodeVMI(c, "worker-0", "node-1", "True")
+		name, err := c.MigrateVM(ctx, "worker-0")
+		Expect(err).NotTo(HaveOccurred())
+
+		status, err := c.GetMigrationStatus(ctx, name)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(status.Phase).To(BeEmpty())
+		Expect(status.Finished()).To(BeFalse())
+
+		setMigration(name, "worker-0", MigrationPhaseSucceeded, map[string]interface{}{
+			"migrationUid": "migration-uid",
+			"sourceNode":   "node-1",
+			"targetNode":   "node-2",
+		})
+		status, err = c.GetMigrationStatus(ctx, name)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(status.Finished()).To(BeTrue())
+		Expect(status.SourceNode).To(Equal("node-1"))
+		Expect(status.TargetNode).To(Equal("node-2"))
+	})
+
+	It("reports why a migration failed", func() {
+		addNodeVMI(c, "worker-0", "node-1", "True")
+		name, err := c.MigrateVM(ctx, "worker-0")
+		Expect(err).NotTo(HaveOccurred())
+		setMigration(name, "worker-0", MigrationPhaseFailed, map[string]interface{}{
+			"migrationUid":  "migration-uid",
+			"failureReason": "target pod could not be scheduled",
+		})
+
+		status, err := c.GetMigrationStatus(ctx, name)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(status.Phase).To(Equal(MigrationPhaseFailed))
+		Expect(status.Message).To(Equal("target pod could not be scheduled"))
+	})
+
+	It("refuses to migrate a VM with a ReadWriteOnce disk", func() {
+		addNodeVMI(c, "worker-0", "node-1", "False")
+		_, err := c.MigrateVM(ctx, "worker-0")
+		Expect(errors.Is(err, ErrNotMigratable)).To(BeTrue(), "got %v", err)
+		Expect(err.Error()).To(ContainSubstring("PVC rootdisk is not shared"))
+
+		list, err := c.dynamic.Resource(vmimGVR).Namespace(testNamespace).List(ctx, metav1.ListOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(list.Items).To(BeEmpty())
+	})
+})
@@ -0,0 +1,379 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package harvester
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"slices"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+var nadGVR = schema.GroupVersionResource{
+	Group:    "k8s.cni.cncf.io",
+	Version:  "v1",
+	Resource: "network-attachment-definitions",
+}
+
+// AnnotationNADResourceName is the multus annotation naming the device plugin
+// resource backing a NetworkAttachmentDefinition.
+const AnnotationNADResourceName = "k8s.v1.cni.cncf.io/resourceName"
+
+// ErrNetworkNotFound is returned by CreateVM when the network attachment the
+// VM would be connected to does not exist.
+var ErrNetworkNotFound = errors.New("network not found")
+
+// NetworkBinding selects how the VM interface is bound to its network.
+type NetworkBinding string
+
+const (
+	// NetworkBindingBridge connects the interface through a Linux bridge. This is the default.
+	NetworkBindingBridge NetworkBinding = "bridge"
+	// NetworkBindingSRIOV passes an SR-IOV virtual function through to the
+	// guest. VMs with SR-IOV interfaces cannot be live migrated.
+	NetworkBindingSRIOV NetworkBinding = "sriov"
+	// NetworkBindingMasquerade connects the interface to the pod network
+	// behind NAT. The guest gets its address from KubeVirt over DHCP.
+	NetworkBindingMasquerade NetworkBinding = "masquerade"
+)
+
+// defaultInterfaceName is the name of the VM interface on the primary network.
+const defaultInterfaceName = "default"
+
+// NetworkInterface is a VM interface on a multus network or the pod network.
+type NetworkInterface struct {
+	// Name is the interface name in the VM spec. Defaults to "default" for
+	// the first interface and "nic-<index>" for the others.
+	Name string
+	// NetworkName is the multus network, "namespace/name" or "name". It must
+	// be empty for masquerade interfaces, which use the pod network.
+	NetworkName string
+	// Binding is NetworkBindingBridge or NetworkBindingMasquerade. Defaults
+	// to bridge, or to the VM NetworkBinding for the first interface.
+	Binding NetworkBinding
+	// MACAddress is the interface MAC address. KubeVirt assigns a random one
+	// when empty. Synthesized network-data matches the interface by it.
+	MACAddress string
+	// Addresses are static addresses in CIDR notation. The interface uses
+	// DHCP when empty. Requires MACAddress.
+	Addresses []string
+	// Gateway is the default gateway reached through the interface.
+	// Requires Addresses.
+	Gateway string
+	// ACPIIndex and PCIAddress pin the interface to an ACPI index or a PCI
+	// address ("dddd:bb:ss.f") so its guest name stays stable. The first
+	// interface defaults to the VM InterfaceACPIIndex and InterfacePCIAddress.
+	ACPIIndex  int32
+	PCIAddress string
+}
+
+// interfaceName returns the VM spec name of the interface at index i.
+func interfaceName(i int, iface NetworkInterface) string {
+	if iface.Name != "" {
+		return iface.Name
+	}
+	if i == 0 {
+		return defaultInterfaceName
+	}
+	return fmt.Sprintf("nic-%d", i)
+}
+
+// interfacePinning returns the ACPI index and PCI address of the interface
+// at index i.
+func interfacePinning(opts VMCreateOptions, i int, iface NetworkInterface) (int32, string) {
+	acpiIndex, pciAddress := iface.ACPIIndex, iface.PCIAddress
+	if i == 0 {
+		if acpiIndex == 0 {
+			acpiIndex = opts.InterfaceACPIIndex
+		}
+		if pciAddress == "" {
+			pciAddress = opts.InterfacePCIAddress
+		}
+	}
+	return acpiIndex, pciAddress
+}
+
+// interfaceBinding returns the binding of the interface at index i.
+func interfaceBinding(opts VMCreateOptions, i int, iface NetworkInterface) NetworkBinding {
+	if iface.Binding != "" {
+		return iface.Binding
+	}
+	if i == 0 {
+		return networkBinding(opts)
+	}
+	return NetworkBindingBridge
+}
+
+// onPodNetwork reports whether the primary interface is on the pod network.
+func onPodNetwork(opts VMCreateOptions) bool {
+	if len(opts.Networks) == 0 {
+		return networkBinding(opts) == NetworkBindingMasquerade
+	}
+	return interfaceBinding(opts, 0, opts.Networks[0]) == NetworkBindingMasquerade
+}
+
+// validateNetworkInterfaces checks the interfaces in opts.Networks. MAC
+// addresses must be unique, and with several interfaces each needs a MAC
+// whenever network-data is synthesized, because guest interface names are
+// not predictable enough to address them otherwise.
+func validateNetworkInterfaces(opts VMCreateOptions) error {
+	if len(opts.Networks) == 0 {
+		return nil
+	}
+	if opts.NetworkName != "" {
+		return invalidOptionsf("network name %q cannot be combined with multiple networks", opts.NetworkName)
+	}
+
+	names := map[string]bool{}
+	macs := map[string]string{}
+	var podInterface string
+	for i, iface := range opts.Networks {
+		name := interfaceName(i, iface)
+		if errs := validation.IsDNS1123Label(name); len(errs) > 0 {
+			return invalidOptionsf("invalid interface name %q: %s", name, errs[0])
+		}
+		if names[name] {
+			return invalidOptionsf("duplicate interface name %q", name)
+		}
+		names[name] = true
+
+		switch iface.Binding {
+		case "", NetworkBindingBridge, NetworkBindingMasquerade:
+		default:
+			return invalidOptionsf("unsupported binding %q on interface %s (must be bridge or masquerade)", iface.Binding, name)
+		}
+		if i == 0 && iface.Binding != "" && opts.NetworkBinding != "" && iface.Binding != opts.NetworkBinding {
+			return invalidOptionsf("interface %s binding %s conflicts with network binding %s", name, iface.Binding, opts.NetworkBinding)
+		}
+		if interfaceBinding(opts, i, iface) == NetworkBindingMasquerade {
+			if iface.NetworkName != "" {
+				return invalidOptionsf("masquerade interface %s uses the pod network and cannot name network %s", name, iface.NetworkName)
+			}
+			if podInterface != "" {
+				return invalidOptionsf("interfaces %s and %s both use the pod network", podInterface, name)
+			}
+			if len(iface.Addresses) > 0 {
+				return invalidOptionsf("masquerade interface %s gets its address from KubeVirt and cannot have static addresses", name)
+			}
+			podInterface = name
+		} else {
+			if iface.NetworkName == "" {
+				return invalidOptionsf("interface %s has no network", name)
+			}
+			if err := validateNetworkRef(iface.NetworkName); err != nil {
+				return err
+			}
+		}
+
+		if iface.MACAddress != "" {
+			mac, err := net.ParseMAC(iface.MACAddress)
+			if err != nil || len(mac) != 6 {
+				return invalidOptionsf("invalid MAC address %q on interface %s", iface.MACAddress, name)
+			}
+			if mac[0]&1 != 0 {
+				return invalidOptionsf("MAC address %s on interface %s is a multicast address", iface.MACAddress, name)
+			}
+			if other, ok := macs[mac.String()]; ok {
+				return invalidOptionsf("MAC address %s is used by interfaces %s and %s", iface.MACAddress, other, name)
+			}
+			macs[mac.String()] = name
+		}
+
+		for _, addr := range iface.Addresses {
+			if _, _, err := net.ParseCIDR(addr); err != nil {
+				return invalidOptionsf("invalid address %q on interface %s (want CIDR notation)", addr, name)
+			}
+		}
+		if len(iface.Addresses) > 0 && iface.MACAddress == "" {
+			return invalidOptionsf("interface %s has static addresses but no MAC address to match it in network-data", name)
+		}
+		if iface.Gateway != "" {
+			if len(iface.Addresses) == 0 {
+				return invalidOptionsf("interface %s has a gateway but no static addresses", name)
+			}
+			if net.ParseIP(iface.Gateway) == nil {
+				return invalidOptionsf("invalid gateway %q on interface %s", iface.Gateway, name)
+			}
+			if !slices.ContainsFunc(iface.Addresses, func(addr string) bool { return defaultRoute(addr) == defaultRoute(iface.Gateway) }) {
+				return invalidOptionsf("gateway %s on interface %s has no address in the same family", iface.Gateway, name)
+			}
+		}
+	}
+
+	if len(opts.Networks) > 1 && opts.NetworkData == "" && guestNetworkFor(opts) != nil && len(macs) < len(opts.Networks) {
+		return invalidOptionsf("every interface needs a MAC address when network-data is synthesized for multiple networks")
+	}
+	return nil
+}
+
+// vmNetworkRefs returns the multus networks the VM is attached to, primary
+// first. defaultNetwork is used when the options name none. Interfaces on
+// the pod network are skipped.
+func vmNetworkRefs(opts VMCreateOptions, defaultNetwork string) []string {
+	if len(opts.Networks) == 0 {
+		if opts.NetworkBinding == NetworkBindingMasquerade {
+			return nil
+		}
+		if opts.NetworkName != "" {
+			return []string{opts.NetworkName}
+		}
+		return []string{defaultNetwork}
+	}
+	refs := make([]string, 0, len(opts.Networks))
+	for _, iface := range opts.Networks {
+		if iface.NetworkName != "" {
+			refs = append(refs, iface.NetworkName)
+		}
+	}
+	return refs
+}
+
+// maxACPIIndex is the highest ACPI index QEMU accepts on a device.
+const maxACPIIndex = 16383
+
+// pciAddressPattern matches a PCI address in domain:bus:slot.function form.
+var pciAddressPattern = regexp.MustCompile(`^[0-9a-fA-F]{4}:[0-9a-fA-F]{2}:[0-1][0-9a-fA-F]\.[0-7]$`)
+
+// validateInterfaceNaming checks the ACPI index and PCI address of each VM
+// interface. No two interfaces may share one; other devices get their
+// addresses assigned around the pinned interfaces.
+func validateInterfaceNaming(opts VMCreateOptions) error {
+	attached := opts.Networks
+	if len(attached) == 0 {
+		attached = []NetworkInterface{{}}
+	}
+	if first := attached[0]; len(opts.Networks) > 0 {
+		name := interfaceName(0, first)
+		if first.ACPIIndex != 0 && opts.InterfaceACPIIndex != 0 && first.ACPIIndex != opts.InterfaceACPIIndex {
+			return invalidOptionsf("interface %s ACPI index %d conflicts with interface ACPI index %d", name, first.ACPIIndex, opts.InterfaceACPIIndex)
+		}
+		if first.PCIAddress != "" && opts.InterfacePCIAddress != "" && !strings.EqualFold(first.PCIAddress, opts.InterfacePCIAddress) {
+			return invalidOptionsf("interface %s PCI address %s conflicts with interface PCI address %s", name, first.PCIAddress, opts.InterfacePCIAddress)
+		}
+	}
+
+	acpiIndexes := map[int32]string{}
+	pciAddresses := map[string]string{}
+	for i, iface := range attached {
+		name := interfaceName(i, iface)
+		acpiIndex, pciAddress := interfacePinning(opts, i, iface)
+		if acpiIndex < 0 || acpiIndex > maxACPIIndex {
+			return invalidOptionsf("ACPI index %d on interface %s must be between 1 and %d", acpiIndex, name, maxACPIIndex)
+		}
+		if acpiIndex != 0 {
+			if other, ok := acpiIndexes[acpiIndex]; ok {
+				return invalidOptionsf("ACPI index %d is used by interfaces %s and %s", acpiIndex, other, name)
+			}
+			acpiIndexes[acpiIndex] = name
+		}
+		if pciAddress == "" {
+			continue
+		}
+		if !pciAddressPattern.MatchString(pciAddress) {
+			return invalidOptionsf("invalid PCI address %q on interface %s (want dddd:bb:ss.f with slot at most 1f)", pciAddress, name)
+		}
+		if other, ok := pciAddresses[strings.ToLower(pciAddress)]; ok {
+			return invalidOptionsf("PCI address %s is used by interfaces %s and %s", pciAddress, other, name)
+		}
+		pciAddresses[strings.ToLower(pciAddress)] = name
+	}
+	return nil
+}
+
+// splitRef splits a "namespace/name" reference, defaulting the namespace.
+func splitRef(ref, defaultNamespace string) (string, string) {
+	if ns, name, ok := strings.Cut(ref, "/"); ok {
+		return ns, name
+	}
+	return defaultNamespace, ref
+}
+
+// getNetworkAttachment fetches a multus NetworkAttachmentDefinition by
+// "namespace/name" reference.
+func (c *Client) getNetworkAttachment(ctx context.Context, ref string) (*unstructured.Unstructured, error) {
+	ns, name := splitRef(ref, c.namespace)
+	return retryResult(ctx, c, func(ctx context.Context) (*unstructured.Unstructured, error) {
+		return c.dynamic.Resource(nadGVR).Namespace(ns).Get(ctx, name, metav1.GetOptions{})
+	})
+}
+
+// validateNetworkRef checks that a "namespace/name" or "name" network
+// reference is well formed.
+func validateNetworkRef(ref string) error {
+	ns, name, namespaced := strings.Cut(ref, "/")
+	if !namespaced {
+		ns, name = "", ref
+	} else if errs := validation.IsDNS1123Label(ns); len(errs) > 0 {
+		return invalidOptionsf("invalid network %q: namespace %q: %s", ref, ns, errs[0])
+	}
+	if errs := validation.IsDNS1123Subdomain(name); len(errs) > 0 {
+		return invalidOptionsf("invalid network %q: name %q: %s", ref, name, errs[0])
+	}
+	return nil
+}
+
+// checkNetwork verifies the referenced NetworkAttachmentDefinition exists so
+// a misspelled network fails before a VM without connectivity is created.
+func (c *Client) checkNetwork(ctx context.Context, ref string) error {
+	if ref == "" {
+		return invalidOptionsf("no network specified and no default network in provider config")
+	}
+	if err := validateNetworkRef(ref); err != nil {
+		return err
+	}
+	if _, err := c.getNetworkAttachment(ctx, ref); err != nil {
+		if apierrors.IsNotFound(err) {
+			ns, name := splitRef(ref, c.namespace)
+			return fmt.Errorf("%w: NetworkAttachmentDefinition %s not found in namespace %s", ErrNetworkNotFound, name, ns)
+		}
+		return fmt.Errorf("failed to get network %s: %w", ref, err)
+	}
+	return nil
+}
+
+// sriovResourceName verifies the referenced network is an SR-IOV network and
+// returns the device plugin resource that allocates its virtual functions.
+func (c *Client) sriovResourceName(ctx context.Context, ref string) (string, error) {
+	nad, err := c.getNetworkAttachment(ctx, ref)
+	if err != nil {
+		return "", fmt.Errorf("failed to get network %s: %w", ref, err)
+	}
+
+	configJSON, _, _ := unstructured.NestedString(nad.Object, "spec", "config")
+	var config struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal([]byte(configJSON), &config); err != nil || config.Type != "sriov" {
+		return "", invalidOptionsf("network %s is not an SR-IOV network", ref)
+	}
+
+	resourceName := nad.GetAnnotations()[AnnotationNADResourceName]
+	if resourceName == "" {
+		return "", invalidOptionsf("SR-IOV network %s has no %s annotation", ref, AnnotationNADResourceName)
+	}
+	return resourceName, nil
+}
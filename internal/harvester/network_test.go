@@ -0,0 +1,494 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package harvester
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"sigs.k8s.io/yaml"
+)
+
+// twoNICOptions returns options for a VM with a statically addressed
+// management NIC and storage NIC.
+func twoNICOptions() VMCreateOptions {
+	opts := testCreateOptions()
+	opts.NetworkName = ""
+	opts.DNSServers = []string{"10.0.0.2"}
+	opts.Networks = []NetworkInterface{
+		{
+			NetworkName: "default/vlan1",
+			MACAddress:  "52:54:00:AA:00:01",
+			Addresses:   []string{"10.0.0.10/24"},
+			Gateway:     "10.0.0.1",
+		},
+		{
+			Name:        "storage",
+			NetworkName: "default/storage",
+			MACAddress:  "52:54:00:aa:00:02",
+			Addresses:   []string{"192.168.50.10/24"},
+		},
+	}
+	return opts
+}
+
+var _ = Describe("Network validation", func() {
+	var ctx context.Context
+
+	BeforeEach(func() {
+		ctx = context.Background()
+	})
+
+	It("defers creation while the network does not exist", func() {
+		c := newTestClient()
+		opts := testCreateOptions()
+		opts.NetworkName = "default/vlan2"
+
+		_, _, err := c.CreateVM(ctx, opts)
+		Expect(errors.Is(err, ErrNetworkNotFound)).To(BeTrue(), "got %v", err)
+
+		_, err = c.GetVM(ctx, opts.Name)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("rejects a malformed namespace", func() {
+		c := newTestClient()
+		opts := testCreateOptions()
+		opts.NetworkName = "Default/vlan1"
+
+		_, _, err := c.CreateVM(ctx, opts)
+		Expect(errors.Is(err, ErrInvalidOptions)).To(BeTrue(), "got %v", err)
+	})
+
+	It("falls back to the ProviderConfig network", func() {
+		c := newTestClient()
+		c.config.NetworkName = "missing"
+		opts := testCreateOptions()
+		opts.NetworkName = ""
+
+		_, _, err := c.CreateVM(ctx, opts)
+		Expect(errors.Is(err, ErrNetworkNotFound)).To(BeTrue(), "got %v", err)
+		Expect(err.Error()).To(ContainSubstring("namespace " + testNamespace))
+	})
+
+	It("pins the interface to an ACPI index and PCI address", func() {
+		opts := testCreateOptions()
+		opts.InterfaceACPIIndex = 1
+		opts.InterfacePCIAddress = "0000:02:01.0"
+		Expect(validateCreateOptions(opts)).To(Succeed())
+
+		interfaces, _ := buildNetworks(opts, "default/vlan1")
+		Expect(interfaces).To(ConsistOf(And(
+			HaveKeyWithValue("acpiIndex", int64(1)),
+			HaveKeyWithValue("pciAddress", "0000:02:01.0"),
+		)))
+		interfaces, _ = buildNetworks(testCreateOptions(), "default/vlan1")
+		Expect(interfaces).To(ConsistOf(Not(HaveKey("acpiIndex"))))
+	})
+
+	It("pins each listed interface", func() {
+		opts := testCreateOptions()
+		opts.NetworkName = ""
+		opts.InterfaceACPIIndex = 1
+		opts.Networks = []NetworkInterface{
+			{NetworkName: "default/vlan1"},
+			{NetworkName: "default/storage", ACPIIndex: 2, PCIAddress: "0000:02:02.0"},
+			{NetworkName: "default/backup"},
+		}
+		Expect(validateCreateOptions(opts)).To(Succeed())
+
+		interfaces, _ := buildNetworks(opts, "")
+		Expect(interfaces).To(HaveLen(3))
+		Expect(interfaces[0]).To(HaveKeyWithValue("acpiIndex", int64(1)))
+		Expect(interfaces[0]).NotTo(HaveKey("pciAddress"))
+		Expect(interfaces[1]).To(HaveKeyWithValue("acpiIndex", int64(2)))
+		Expect(interfaces[1]).To(HaveKeyWithValue("pciAddress", "0000:02:02.0"))
+		Expect(interfaces[2]).NotTo(HaveKey("acpiIndex"))
+	})
+
+	DescribeTable("rejects colliding interface pins",
+		func(mutate func(opts *VMCreateOptions), message string) {
+			opts := testCreateOptions()
+			opts.NetworkName = ""
+			opts.Networks = []NetworkInterface{
+				{NetworkName: "default/vlan1", ACPIIndex: 1, PCIAddress: "0000:02:01.0"},
+				{NetworkName: "default/storage"},
+			}
+			mutate(&opts)
+			err := validateCreateOptions(opts)
+			Expect(errors.Is(err, ErrInvalidOptions)).To(BeTrue(), "got %v", err)
+			Expect(err).To(MatchError(ContainSubstring(message)))
+		},
+		Entry("shared ACPI index", func(opts *VMCreateOptions) { opts.Networks[1].ACPIIndex = 1 },
+			"ACPI index 1 is used by interfaces default and nic-1"),
+		Entry("shared PCI address", func(opts *VMCreateOptions) { opts.Networks[1].PCIAddress = "0000:02:01.0" },
+			"PCI address 0000:02:01.0 is used by interfaces default and nic-1"),
+		Entry("primary ACPI index conflict", func(opts *VMCreateOptions) { opts.InterfaceACPIIndex = 3 },
+			"interface default ACPI index 1 conflicts with interface ACPI index 3"),
+		Entry("primary PCI address conflict", func(opts *VMCreateOptions) { opts.InterfacePCIAddress = "0000:02:03.0" },
+			"interface default PCI address 0000:02:01.0 conflicts"),
+		Entry("ACPI index out of range", func(opts *VMCreateOptions) { opts.Networks[1].ACPIIndex = 16384 },
+			"ACPI index 16384 on interface nic-1"),
+		Entry("malformed PCI address", func(opts *VMCreateOptions) { opts.Networks[1].PCIAddress = "02:01.0" },
+			`invalid PCI address "02:01.0" on interface nic-1`),
+	)
+
+	It("puts a masquerade VM on the pod network instead of the ProviderConfig network", func() {
+		c := newTestClient()
+		c.config.NetworkName = "missing"
+		opts := testCreateOptions()
+		opts.NetworkName = ""
+		opts.NetworkBinding = NetworkBindingMasquerade
+
+		_, effective, err := c.CreateVM(ctx, opts)
+		Expect(err).NotTo(HaveOccurred())
+		vm, err := c.GetVM(ctx, opts.Name)
+		Expect(err).NotTo(HaveOccurred())
+		interfaces, _, _ := unstructured.NestedSlice(vm.Object, "spec", "template", "spec", "domain", "devices", "interfaces")
+		Expect(interfaces).To(Equal([]interface{}{
+			map[string]interface{}{"name": defaultInterfaceName, "masquerade": map[string]interface{}{}},
+		}))
+		networks, _, _ := unstructured.NestedSlice(vm.Object, "spec", "template", "spec", "networks")
+		Expect(networks).To(Equal([]interface{}{
+			map[string]interface{}{"name": defaultInterfaceName, "pod": map[string]interface{}{}},
+		}))
+		Expect(effective.NetworkName).To(BeEmpty())
+	})
+
+	It("rejects a masquerade VM naming a multus network or a static IP", func() {
+		opts := testCreateOptions()
+		opts.NetworkBinding = NetworkBindingMasquerade
+		Expect(validateCreateOptions(opts)).To(MatchError(ContainSubstring("cannot be combined with network default/vlan1")))
+
+		opts.NetworkName = ""
+		opts.StaticIP = &StaticIP{Address: "10.0.0.10/24"}
+		Expect(validateCreateOptions(opts)).To(MatchError(ContainSubstring("cannot be combined with a static IP")))
+	})
+
+	It("rejects a malformed PCI address", func() {
+		opts := testCreateOptions()
+		opts.InterfacePCIAddress = "02:01.0"
+		Expect(errors.Is(validateCreateOptions(opts), ErrInvalidOptions)).To(BeTrue())
+		opts.InterfacePCIAddress = "0000:02:20.0"
+		Expect(errors.Is(validateCreateOptions(opts), ErrInvalidOptions)).To(BeTrue())
+	})
+
+	Context("with multiple networks", func() {
+		It("creates a two-NIC VM with static addresses", func() {
+			c := newTestClient()
+			storage := testNetwork()
+			storage.SetName("storage")
+			Expect(c.dynamic.(*dynamicfake.FakeDynamicClient).Tracker().Create(nadGVR, storage, "default")).To(Succeed())
+
+			_, _, err := c.CreateVM(ctx, twoNICOptions())
+			Expect(err).NotTo(HaveOccurred())
+			vm, err := c.GetVM(ctx, "worker-0")
+			Expect(err).NotTo(HaveOccurred())
+
+			spec := vm.Object["spec"].(map[string]interface{})["template"].(map[string]interface{})["spec"].(map[string]interface{})
+			interfaces, _, _ := unstructured.NestedSlice(spec, "domain", "devices", "interfaces")
+			Expect(interfaces).To(Equal([]interface{}{
+				map[string]interface{}{"name": "default", "bridge": map[string]interface{}{}, "macAddress": "52:54:00:AA:00:01"},
+				map[string]interface{}{"name": "storage", "bridge": map[string]interface{}{}, "macAddress": "52:54:00:aa:00:02"},
+			}))
+			networks, _, _ := unstructured.NestedSlice(spec, "networks")
+			Expect(networks).To(Equal([]interface{}{
+				map[string]interface{}{"name": "default", "multus": map[string]interface{}{"networkName": "default/vlan1"}},
+				map[string]interface{}{"name": "storage", "multus": map[string]interface{}{"networkName": "default/storage"}},
+			}))
+
+			volumes, _, _ := unstructured.NestedSlice(spec, "volumes")
+			var encoded string
+			for _, v := range volumes {
+				if data, ok, _ := unstructured.NestedString(v.(map[string]interface{}), "cloudInitNoCloud", "networkDataBase64"); ok {
+					encoded = data
+				}
+			}
+			networkData, err := base64.StdEncoding.DecodeString(encoded)
+			Expect(err).NotTo(HaveOccurred())
+			var doc map[string]interface{}
+			Expect(yaml.Unmarshal(networkData, &doc)).To(Succeed())
+			Expect(doc["ethernets"]).To(Equal(map[string]interface{}{
+				"default": map[string]interface{}{
+					"match":       map[string]interface{}{"macaddress": "52:54:00:aa:00:01"},
+					"addresses":   []interface{}{"10.0.0.10/24"},
+					"routes":      []interface{}{map[string]interface{}{"to": "0.0.0.0/0", "via": "10.0.0.1"}},
+					"nameservers": map[string]interface{}{"addresses": []interface{}{"10.0.0.2"}},
+				},
+				"storage": map[string]interface{}{
+					"match":     map[string]interface{}{"macaddress": "52:54:00:aa:00:02"},
+					"addresses": []interface{}{"192.168.50.10/24"},
+				},
+			}))
+		})
+
+		It("renders static addresses in version 1 format", func() {
+			opts := twoNICOptions()
+			opts.NetworkDataFormat = NetworkDataV1
+			v1, err := renderNetworkData(opts)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(v1).To(ContainSubstring("mac_address: 52:54:00:aa:00:02"))
+			Expect(v1).To(ContainSubstring("name: eth1"))
+			Expect(v1).To(ContainSubstring("gateway: 10.0.0.1"))
+		})
+
+		It("attaches a version 1 gateway to the subnet of its family", func() {
+			opts := twoNICOptions()
+			opts.NetworkDataFormat = NetworkDataV1
+			opts.Networks[0].Addresses = []string{"fd00::10/64", "10.0.0.10/24"}
+			v1, err := renderNetworkData(opts)
+			Expect(err).NotTo(HaveOccurred())
+
+			var doc map[string]interface{}
+			Expect(yaml.Unmarshal([]byte(v1), &doc)).To(Succeed())
+			primary := doc["config"].([]interface{})[0].(map[string]interface{})
+			Expect(primary["subnets"]).To(Equal([]interface{}{
+				map[string]interface{}{"type": "static", "address": "fd00::10/64"},
+				map[string]interface{}{"type": "static", "address": "10.0.0.10/24", "gateway": "10.0.0.1"},
+			}))
+		})
+
+		DescribeTable("installs one default route per address family",
+			func(format NetworkDataFormat, storageGateway, want string, routes int) {
+				opts := twoNICOptions()
+				opts.NetworkDataFormat = format
+				opts.Networks[1].Addresses = append(opts.Networks[1].Addresses, "fd00:50::10/64")
+				opts.Networks[1].Gateway = storageGateway
+				data, err := renderNetworkData(opts)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(strings.Count(data, want)).To(Equal(routes))
+				Expect(data).To(ContainSubstring("10.0.0.1"))
+			},
+			Entry("v2 with two IPv4 gateways", NetworkDataV2, "192.168.50.1", "0.0.0.0/0", 1),
+			Entry("v2 with an IPv4 and an IPv6 gateway", NetworkDataV2, "fd00:50::1", "via: fd00:50::1", 1),
+			Entry("v1 with two IPv4 gateways", NetworkDataV1, "192.168.50.1", "gateway:", 1),
+			Entry("v1 with an IPv4 and an IPv6 gateway", NetworkDataV1, "fd00:50::1", "gateway:", 2),
+		)
+
+		It("rejects ENI, which NoCloud does not read from network-config", func() {
+			opts := twoNICOptions()
+			opts.NetworkDataFormat = "eni"
+			err := validateCreateOptions(opts)
+			Expect(errors.Is(err, ErrInvalidOptions)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("must be v1 or v2"))
+		})
+
+		It("checks every network exists", func() {
+			c := newTestClient()
+			_, _, err := c.CreateVM(ctx, twoNICOptions())
+			Expect(errors.Is(err, ErrNetworkNotFound)).To(BeTrue(), "got %v", err)
+			Expect(err.Error()).To(ContainSubstring("storage"))
+		})
+
+		It("rejects a MAC address used twice", func() {
+			opts := twoNICOptions()
+			opts.Networks[1].MACAddress = "52:54:00:aa:00:01"
+			err := validateCreateOptions(opts)
+			Expect(errors.Is(err, ErrInvalidOptions)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("used by interfaces default and storage"))
+		})
+
+		It("rejects malformed and multicast MAC addresses", func() {
+			opts := twoNICOptions()
+			opts.Networks[1].MACAddress = "52:54:00:aa:00"
+			Expect(errors.Is(validateCreateOptions(opts), ErrInvalidOptions)).To(BeTrue())
+			opts.Networks[1].MACAddress = "01:00:5e:00:00:01"
+			Expect(errors.Is(validateCreateOptions(opts), ErrInvalidOptions)).To(BeTrue())
+		})
+
+		It("requires a MAC address to address an interface", func() {
+			opts := twoNICOptions()
+			opts.Networks[1].MACAddress = ""
+			Expect(validateCreateOptions(opts)).To(MatchError(ContainSubstring("no MAC address")))
+
+			opts.Networks[1].Addresses = nil
+			Expect(validateCreateOptions(opts)).To(MatchError(ContainSubstring("every interface needs a MAC address")))
+		})
+
+		It("rejects invalid addresses and gateways", func() {
+			opts := twoNICOptions()
+			opts.Networks[0].Addresses = []string{"10.0.0.10"}
+			Expect(errors.Is(validateCreateOptions(opts), ErrInvalidOptions)).To(BeTrue())
+
+			opts = twoNICOptions()
+			opts.Networks[0].Gateway = "fd00::1"
+			Expect(validateCreateOptions(opts)).To(MatchError(ContainSubstring("same family")))
+		})
+
+		It("cannot be combined with a network name", func() {
+			opts := twoNICOptions()
+			opts.NetworkName = "default/vlan1"
+			Expect(errors.Is(validateCreateOptions(opts), ErrInvalidOptions)).To(BeTrue())
+		})
+
+		It("attaches masquerade interfaces to the pod network", func() {
+			opts := testCreateOptions()
+			opts.NetworkName = ""
+			opts.Networks = []NetworkInterface{
+				{Name: "mgmt", Binding: NetworkBindingMasquerade},
+				{Name: "storage", NetworkName: "default/storage"},
+			}
+			Expect(validateCreateOptions(opts)).To(Succeed())
+			Expect(vmNetworkRefs(opts, "default/vlan1")).To(Equal([]string{"default/storage"}))
+
+			interfaces, networks := buildNetworks(opts, "")
+			Expect(interfaces).To(Equal([]interface{}{
+				map[string]interface{}{"name": "mgmt", "masquerade": map[string]interface{}{}},
+				map[string]interface{}{"name": "storage", "bridge": map[string]interface{}{}},
+			}))
+			Expect(networks).To(Equal([]interface{}{
+				map[string]interface{}{"name": "mgmt", "pod": map[string]interface{}{}},
+				map[string]interface{}{"name": "storage", "multus": map[string]interface{}{"networkName": "default/storage"}},
+			}))
+		})
+
+		It("rejects invalid interface bindings", func() {
+			for _, networks := range [][]NetworkInterface{
+				{{NetworkName: "default/vlan1", Binding: "sriov"}},
+				{{NetworkName: "default/vlan1", Binding: NetworkBindingMasquerade}},
+				{{Binding: NetworkBindingMasquerade}, {Name: "second", Binding: NetworkBindingMasquerade}},
+				{{Binding: NetworkBindingMasquerade, MACAddress: "52:54:00:aa:00:01", Addresses: []string{"10.0.2.5/24"}}},
+				{{}},
+			} {
+				opts := testCreateOptions()
+				opts.NetworkName = ""
+				opts.Networks = networks
+				Expect(errors.Is(validateCreateOptions(opts), ErrInvalidOptions)).To(BeTrue(), "networks %+v", networks)
+			}
+
+			opts := testCreateOptions()
+			opts.NetworkName = ""
+			opts.NetworkBinding = NetworkBindingSRIOV
+			opts.Networks = []NetworkInterface{{Binding: NetworkBindingMasquerade}}
+			Expect(validateCreateOptions(opts)).To(MatchError(ContainSubstring("conflicts with network binding sriov")))
+		})
+	})
+
+	Context("with a cluster DNS domain", func() {
+		podOptions := func() VMCreateOptions {
+			opts := testCreateOptions()
+			opts.NetworkName = ""
+			opts.NetworkBinding = NetworkBindingMasquerade
+			opts.DNSSearch = []string{"example.com"}
+			opts.ClusterDNSDomain = "cluster.local"
+			return opts
+		}
+
+		It("appends it to the search domains on the pod network", func() {
+			opts := podOptions()
+			Expect(validateCreateOptions(opts)).To(Succeed())
+			networkData, err := renderNetworkData(opts)
+			Expect(err).NotTo(HaveOccurred())
+			var doc map[string]interface{}
+			Expect(yaml.Unmarshal([]byte(networkData), &doc)).To(Succeed())
+			Expect(doc).To(HaveKeyWithValue("ethernets", HaveKeyWithValue("primary",
+				HaveKeyWithValue("nameservers", map[string]interface{}{"search": []interface{}{"example.com", "cluster.local"}}))))
+		})
+
+		It("is not repeated when already a search domain", func() {
+			opts := podOptions()
+			opts.DNSSearch = []string{"cluster.local"}
+			Expect(guestNetworkFor(opts).search).To(Equal([]string{"cluster.local"}))
+		})
+
+		It("requires the pod network", func() {
+			opts := podOptions()
+			opts.NetworkName = "default/vlan1"
+			opts.NetworkBinding = ""
+			Expect(validateCreateOptions(opts)).To(MatchError(ContainSubstring("requires the primary interface on the pod network")))
+
+			opts = podOptions()
+			opts.NetworkBinding = ""
+			opts.Networks = []NetworkInterface{{Binding: NetworkBindingMasquerade}}
+			Expect(validateCreateOptions(opts)).To(Succeed())
+		})
+
+		It("rejects a malformed domain", func() {
+			opts := podOptions()
+			opts.ClusterDNSDomain = "Cluster_Local"
+			Expect(validateCreateOptions(opts)).To(MatchError(ContainSubstring("invalid cluster DNS domain")))
+		})
+	})
+
+	Context("with a static IP", func() {
+		staticOptions := func() VMCreateOptions {
+			opts := testCreateOptions()
+			opts.StaticIP = &StaticIP{Address: "10.0.0.10", Prefix: 24, Gateway: "10.0.0.1", DNSServers: []string{"10.0.0.2"}}
+			return opts
+		}
+
+		It("writes the address to netplan network-data", func() {
+			c := newTestClient()
+			_, _, err := c.CreateVM(ctx, staticOptions())
+			Expect(err).NotTo(HaveOccurred())
+			vm, err := c.GetVM(ctx, "worker-0")
+			Expect(err).NotTo(HaveOccurred())
+
+			volumes, _, _ := unstructured.NestedSlice(vm.Object, "spec", "template", "spec", "volumes")
+			encoded, _, _ := unstructured.NestedString(volumes[len(volumes)-1].(map[string]interface{}), "cloudInitNoCloud", "networkDataBase64")
+			networkData, err := base64.StdEncoding.DecodeString(encoded)
+			Expect(err).NotTo(HaveOccurred())
+			var doc map[string]interface{}
+			Expect(yaml.Unmarshal(networkData, &doc)).To(Succeed())
+			Expect(doc).To(Equal(map[string]interface{}{
+				"version": float64(2),
+				"ethernets": map[string]interface{}{
+					"primary": map[string]interface{}{
+						"match":       map[string]interface{}{"name": "e*"},
+						"addresses":   []interface{}{"10.0.0.10/24"},
+						"routes":      []interface{}{map[string]interface{}{"to": "0.0.0.0/0", "via": "10.0.0.1"}},
+						"nameservers": map[string]interface{}{"addresses": []interface{}{"10.0.0.2"}},
+					},
+				},
+			}))
+		})
+
+		It("accepts an address in CIDR notation", func() {
+			opts := staticOptions()
+			opts.StaticIP.Address, opts.StaticIP.Prefix = "10.0.0.10/24", 0
+			Expect(validateCreateOptions(opts)).To(Succeed())
+			Expect(renderNetworkData(opts)).To(ContainSubstring("10.0.0.10/24"))
+		})
+
+		It("rejects invalid addresses", func() {
+			for _, s := range []StaticIP{
+				{Address: "10.0.0.300", Prefix: 24},
+				{Address: "10.0.0.10"},
+				{Address: "10.0.0.10/24", Prefix: 16},
+				{Address: "fd00::10/64"},
+				{Address: "10.0.0.10/24", Gateway: "10.0.1.1"},
+				{Address: "10.0.0.10/24", DNSServers: []string{"dns"}},
+			} {
+				opts := testCreateOptions()
+				opts.StaticIP = &s
+				Expect(errors.Is(validateCreateOptions(opts), ErrInvalidOptions)).To(BeTrue(), "static IP %+v", s)
+			}
+		})
+
+		It("cannot be combined with caller network data", func() {
+			opts := staticOptions()
+			opts.NetworkData = "version: 2\n"
+			Expect(errors.Is(validateCreateOptions(opts), ErrInvalidOptions)).To(BeTrue())
+		})
+	})
+})
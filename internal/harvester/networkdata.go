@@ -0,0 +1,362 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package harvester
+
+import (
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// NetworkDataFormat selects the cloud-init network configuration format used
+// when the provider synthesizes network-data.
+type NetworkDataFormat string
+
+const (
+	// NetworkDataV1 is cloud-init networking config version 1.
+	NetworkDataV1 NetworkDataFormat = "v1"
+	// NetworkDataV2 is cloud-init networking config version 2 (netplan). This is the default.
+	NetworkDataV2 NetworkDataFormat = "v2"
+)
+
+// guestInterfaceName is the guest name of the primary interface, used by
+// formats that cannot match interfaces by pattern.
+const guestInterfaceName = "eth0"
+
+// RouteSpec is a static route added to the guest interface.
+type RouteSpec struct {
+	// Destination is the route destination in CIDR notation.
+	Destination string
+	// Gateway is the next-hop address.
+	Gateway string
+	// Metric is the route metric. Zero leaves it to the guest default.
+	Metric int
+}
+
+// StaticIP is a static IPv4 configuration for the guest interface, for
+// networks without a DHCP server.
+type StaticIP struct {
+	// Address is the IPv4 address, optionally in CIDR notation.
+	Address string
+	// Prefix is the prefix length, required unless Address is in CIDR notation.
+	Prefix int
+	// Gateway is the default gateway. It must be inside the subnet.
+	Gateway string
+	// DNSServers are added to the guest nameservers.
+	DNSServers []string
+}
+
+// cidr returns the address in CIDR notation, or an error when the address
+// or prefix is invalid.
+func (s *StaticIP) cidr() (*net.IPNet, string, error) {
+	address := s.Address
+	if _, prefix, ok := strings.Cut(address, "/"); !ok {
+		address = fmt.Sprintf("%s/%d", address, s.Prefix)
+	} else if s.Prefix != 0 && prefix != strconv.Itoa(s.Prefix) {
+		return nil, "", fmt.Errorf("prefix %d does not match address %s", s.Prefix, s.Address)
+	}
+	ip, subnet, err := net.ParseCIDR(address)
+	if err != nil || ip.To4() == nil {
+		return nil, "", fmt.Errorf("address %q with prefix %d is not a valid IPv4 address", s.Address, s.Prefix)
+	}
+	if ones, _ := subnet.Mask.Size(); ones == 0 {
+		return nil, "", fmt.Errorf("prefix length of %s must be between 1 and 32", s.Address)
+	}
+	return subnet, address, nil
+}
+
+// validateStaticIP checks the static IP configuration in opts.
+func validateStaticIP(opts VMCreateOptions) error {
+	s := opts.StaticIP
+	if s == nil {
+		return nil
+	}
+	if len(opts.Networks) > 0 {
+		return invalidOptionsf("static IP cannot be combined with multiple networks; set addresses on the networks instead")
+	}
+	if opts.NetworkData != "" {
+		return invalidOptionsf("static IP cannot be combined with network data supplied by the caller")
+	}
+	subnet, _, err := s.cidr()
+	if err != nil {
+		return invalidOptionsf("invalid static IP: %v", err)
+	}
+	if s.Gateway != "" {
+		gateway := net.ParseIP(s.Gateway)
+		if gateway == nil || gateway.To4() == nil {
+			return invalidOptionsf("invalid static IP gateway %q", s.Gateway)
+		}
+		if !subnet.Contains(gateway) {
+			return invalidOptionsf("static IP gateway %s is outside subnet %s", s.Gateway, subnet)
+		}
+	}
+	for _, server := range s.DNSServers {
+		if net.ParseIP(server) == nil {
+			return invalidOptionsf("invalid static IP DNS server address %q", server)
+		}
+	}
+	return nil
+}
+
+// guestNetwork is the provider's model of the guest network configuration,
+// rendered into network-data in the requested format. DNS settings and
+// routes apply to the first interface.
+type guestNetwork struct {
+	interfaces  []guestInterface
+	nameservers []string
+	search      []string
+	routes      []RouteSpec
+}
+
+// guestInterface is a guest network interface. Interfaces without a MAC
+// address are matched by name pattern, and without addresses use DHCP.
+type guestInterface struct {
+	id        string
+	guestName string
+	mac       string
+	addresses []string
+	gateway   string
+}
+
+// guestNetworkFor returns the guest network configuration requested by the
+// options, or nil when nothing needs to be synthesized.
+func guestNetworkFor(opts VMCreateOptions) *guestNetwork {
+	search := opts.DNSSearch
+	if opts.ClusterDNSDomain != "" && !slices.Contains(search, opts.ClusterDNSDomain) {
+		search = append(slices.Clone(search), opts.ClusterDNSDomain)
+	}
+	nameservers := opts.DNSServers
+	interfaces := guestInterfacesFor(opts)
+	if opts.StaticIP != nil && interfaces == nil {
+		primary := guestInterface{id: "primary", guestName: guestInterfaceName, gateway: opts.StaticIP.Gateway}
+		if _, address, err := opts.StaticIP.cidr(); err == nil {
+			primary.addresses = []string{address}
+		}
+		interfaces = []guestInterface{primary}
+		for _, server := range opts.StaticIP.DNSServers {
+			if !slices.Contains(nameservers, server) {
+				nameservers = append(slices.Clone(nameservers), server)
+			}
+		}
+	}
+	if len(nameservers) == 0 && len(search) == 0 && len(opts.Routes) == 0 && interfaces == nil {
+		return nil
+	}
+	if interfaces == nil {
+		interfaces = []guestInterface{{id: "primary", guestName: guestInterfaceName}}
+	}
+	return &guestNetwork{
+		interfaces:  interfaces,
+		nameservers: nameservers,
+		search:      search,
+		routes:      opts.Routes,
+	}
+}
+
+// guestInterfacesFor returns one guest interface per entry in opts.Networks,
+// or nil when none has a MAC address to match it by.
+func guestInterfacesFor(opts VMCreateOptions) []guestInterface {
+	if !slices.ContainsFunc(opts.Networks, func(iface NetworkInterface) bool { return iface.MACAddress != "" }) {
+		return nil
+	}
+	interfaces := make([]guestInterface, 0, len(opts.Networks))
+	for i, iface := range opts.Networks {
+		interfaces = append(interfaces, guestInterface{
+			id:        interfaceName(i, iface),
+			guestName: fmt.Sprintf("eth%d", i),
+			mac:       strings.ToLower(iface.MACAddress),
+			addresses: iface.Addresses,
+			gateway:   iface.Gateway,
+		})
+	}
+	return interfaces
+}
+
+// renderNetworkData synthesizes cloud-init network-data from the options. It
+// returns an empty string when the caller supplied its own network-data or no
+// network options are set.
+func renderNetworkData(opts VMCreateOptions) (string, error) {
+	if opts.NetworkData != "" {
+		return "", nil
+	}
+	network := guestNetworkFor(opts)
+	if network == nil {
+		return "", nil
+	}
+
+	switch opts.NetworkDataFormat {
+	case NetworkDataV1:
+		return network.renderV1()
+	case "", NetworkDataV2:
+		return network.renderV2()
+	default:
+		return "", invalidOptionsf("unsupported network data format %q", opts.NetworkDataFormat)
+	}
+}
+
+// renderV2 renders the configuration as netplan-compatible version 2.
+func (n *guestNetwork) renderV2() (string, error) {
+	gateways := n.defaultGateways()
+	ethernets := map[string]interface{}{}
+	for i, iface := range n.interfaces {
+		ethernet := map[string]interface{}{
+			"match": map[string]interface{}{"name": "e*"},
+		}
+		if iface.mac != "" {
+			ethernet["match"] = map[string]interface{}{"macaddress": iface.mac}
+		}
+		var routes []interface{}
+		if len(iface.addresses) > 0 {
+			ethernet["addresses"] = iface.addresses
+			if gateways[iface.id] {
+				routes = append(routes, map[string]interface{}{"to": defaultRoute(iface.gateway), "via": iface.gateway})
+			}
+		} else {
+			ethernet["dhcp4"] = true
+		}
+		if i == 0 {
+			if nameservers := n.nameserversMap("addresses"); nameservers != nil {
+				ethernet["nameservers"] = nameservers
+			}
+			for _, r := range n.routes {
+				route := map[string]interface{}{"to": r.Destination, "via": r.Gateway}
+				if r.Metric > 0 {
+					route["metric"] = r.Metric
+				}
+				routes = append(routes, route)
+			}
+		}
+		if len(routes) > 0 {
+			ethernet["routes"] = routes
+		}
+		ethernets[iface.id] = ethernet
+	}
+
+	return marshalNetworkData(map[string]interface{}{
+		"version":   2,
+		"ethernets": ethernets,
+	})
+}
+
+// renderV1 renders the configuration as cloud-init networking config version 1.
+func (n *guestNetwork) renderV1() (string, error) {
+	gateways := n.defaultGateways()
+	config := make([]interface{}, 0, len(n.interfaces))
+	for _, iface := range n.interfaces {
+		var subnets []interface{}
+		gateway := gateways[iface.id]
+		for _, addr := range iface.addresses {
+			subnet := map[string]interface{}{"type": "static", "address": addr}
+			// The gateway belongs to the first subnet of its family
+			if gateway && defaultRoute(addr) == defaultRoute(iface.gateway) {
+				subnet["gateway"] = iface.gateway
+				gateway = false
+			}
+			subnets = append(subnets, subnet)
+		}
+		if len(subnets) == 0 {
+			subnets = []interface{}{map[string]interface{}{"type": "dhcp"}}
+		}
+		physical := map[string]interface{}{
+			"type":    "physical",
+			"name":    iface.guestName,
+			"subnets": subnets,
+		}
+		if iface.mac != "" {
+			physical["mac_address"] = iface.mac
+		}
+		config = append(config, physical)
+	}
+	if nameserver := n.nameserversMap("address"); nameserver != nil {
+		nameserver["type"] = "nameserver"
+		config = append(config, nameserver)
+	}
+	for _, r := range n.routes {
+		route := map[string]interface{}{
+			"type":        "route",
+			"destination": r.Destination,
+			"gateway":     r.Gateway,
+		}
+		if r.Metric > 0 {
+			route["metric"] = r.Metric
+		}
+		config = append(config, route)
+	}
+
+	return marshalNetworkData(map[string]interface{}{
+		"version": 1,
+		"config":  config,
+	})
+}
+
+// defaultGateways returns the IDs of the interfaces whose gateway becomes a
+// default route: the first interface with a gateway of each address family,
+// so several gateways of one family do not compete for the default route.
+func (n *guestNetwork) defaultGateways() map[string]bool {
+	gateways := map[string]bool{}
+	families := map[string]bool{}
+	for _, iface := range n.interfaces {
+		if iface.gateway == "" || len(iface.addresses) == 0 || families[defaultRoute(iface.gateway)] {
+			continue
+		}
+		families[defaultRoute(iface.gateway)] = true
+		gateways[iface.id] = true
+	}
+	return gateways
+}
+
+// defaultRoute returns the default route destination for the address family
+// of addr, which may be a plain address or in CIDR notation.
+func defaultRoute(addr string) string {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		ip, _, _ = net.ParseCIDR(addr)
+	}
+	if ip != nil && ip.To4() == nil {
+		return "::/0"
+	}
+	return "0.0.0.0/0"
+}
+
+// nameserversMap returns the nameserver addresses (under addressKey) and
+// search domains, or nil when neither is set.
+func (n *guestNetwork) nameserversMap(addressKey string) map[string]interface{} {
+	if len(n.nameservers) == 0 && len(n.search) == 0 {
+		return nil
+	}
+	m := map[string]interface{}{}
+	if len(n.nameservers) > 0 {
+		m[addressKey] = n.nameservers
+	}
+	if len(n.search) > 0 {
+		m["search"] = n.search
+	}
+	return m
+}
+
+// marshalNetworkData renders a network-data document as YAML.
+func marshalNetworkData(doc map[string]interface{}) (string, error) {
+	out, err := yaml.Marshal(doc)
+	if err != nil {
+		return "", fmt.Errorf("failed to render network data: %w", err)
+	}
+	return string(out), nil
+}
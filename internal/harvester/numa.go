@@ -0,0 +1,58 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package harvester
+
+// NUMACell is one guest NUMA cell.
+type NUMACell struct {
+	// CPUs is the number of vCPUs in the cell.
+	CPUs int32
+	// MemoryMB is the guest memory of the cell in MiB.
+	MemoryMB int32
+}
+
+// validateNUMACells checks that the cells add up to the VM size and can be
+// expressed by KubeVirt. KubeVirt has no explicit cell layout; it mirrors the
+// host NUMA nodes backing the dedicated CPUs and hugepages into the guest
+// (guestMappingPassthrough), so the cells must be uniform and both dedicated
+// CPU placement and hugepages are required.
+func validateNUMACells(opts VMCreateOptions) error {
+	if len(opts.NUMACells) == 0 {
+		return nil
+	}
+	if !opts.DedicatedCPUPlacement || opts.HugepagesPageSize == "" {
+		return invalidOptionsf("guest NUMA cells require dedicatedCpuPlacement and hugepages")
+	}
+	var cpus, memory int32
+	for i, cell := range opts.NUMACells {
+		if cell.CPUs <= 0 || cell.MemoryMB <= 0 {
+			return invalidOptionsf("NUMA cell %d must have at least one CPU and some memory", i)
+		}
+		if cell != opts.NUMACells[0] {
+			return invalidOptionsf("NUMA cell %d (%d CPUs, %dMi) differs from cell 0 (%d CPUs, %dMi); cells must be uniform",
+				i, cell.CPUs, cell.MemoryMB, opts.NUMACells[0].CPUs, opts.NUMACells[0].MemoryMB)
+		}
+		cpus += cell.CPUs
+		memory += cell.MemoryMB
+	}
+	if cpus != opts.CPU {
+		return invalidOptionsf("NUMA cells have %d CPUs in total but the VM has %d", cpus, opts.CPU)
+	}
+	if memory != opts.MemoryMB {
+		return invalidOptionsf("NUMA cells have %dMi of memory in total but the VM has %dMi", memory, opts.MemoryMB)
+	}
+	return nil
+}
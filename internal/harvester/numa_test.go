@@ -0,0 +1,120 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package harvester
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+var _ = Describe("Guest NUMA topology", func() {
+	var (
+		ctx  context.Context
+		c    *Client
+		opts VMCreateOptions
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		c = newTestClient()
+		opts = testCreateOptions()
+		opts.DedicatedCPUPlacement = true
+		opts.HugepagesPageSize = "1Gi"
+		opts.NUMACells = []NUMACell{{CPUs: 1, MemoryMB: 2048}, {CPUs: 1, MemoryMB: 2048}}
+	})
+
+	It("renders one socket per cell with host NUMA passthrough", func() {
+		_, _, err := c.CreateVM(ctx, opts)
+		Expect(err).NotTo(HaveOccurred())
+		vm, err := c.GetVM(ctx, opts.Name)
+		Expect(err).NotTo(HaveOccurred())
+
+		cpu, _, _ := unstructured.NestedMap(vm.Object, "spec", "template", "spec", "domain", "cpu")
+		Expect(cpu).To(HaveKeyWithValue("sockets", int64(2)))
+		Expect(cpu).To(HaveKeyWithValue("cores", int64(1)))
+		Expect(cpu).To(HaveKey("numa"))
+	})
+
+	It("rejects cells that do not add up to the VM size", func() {
+		opts.NUMACells[1].MemoryMB = 1024
+		opts.NUMACells[0].MemoryMB = 1024
+
+		_, _, err := c.CreateVM(ctx, opts)
+		Expect(err).To(MatchError(ErrInvalidOptions))
+		Expect(err.Error()).To(ContainSubstring("2048Mi of memory in total but the VM has 4096Mi"))
+	})
+
+	It("rejects cells that are not uniform", func() {
+		opts.NUMACells = []NUMACell{{CPUs: 1, MemoryMB: 1024}, {CPUs: 1, MemoryMB: 3072}}
+
+		_, _, err := c.CreateVM(ctx, opts)
+		Expect(err).To(MatchError(ErrInvalidOptions))
+	})
+
+	It("requires dedicated CPUs and hugepages", func() {
+		opts.HugepagesPageSize = ""
+
+		_, _, err := c.CreateVM(ctx, opts)
+		Expect(err).To(MatchError(ErrInvalidOptions))
+	})
+})
+
+var _ = Describe("CPU topology", func() {
+	It("splits the vCPUs into the requested sockets and threads", func() {
+		opts := testCreateOptions()
+		opts.CPU = 8
+		opts.Sockets = 2
+		opts.Threads = 2
+		Expect(validateCreateOptions(opts)).To(Succeed())
+
+		cpu := buildCPU(opts)
+		Expect(cpu).To(HaveKeyWithValue("sockets", int64(2)))
+		Expect(cpu).To(HaveKeyWithValue("cores", int64(2)))
+		Expect(cpu).To(HaveKeyWithValue("threads", int64(2)))
+	})
+
+	It("defaults to a single socket of single-threaded cores", func() {
+		cpu := buildCPU(testCreateOptions())
+		Expect(cpu).To(HaveKeyWithValue("sockets", int64(1)))
+		Expect(cpu).To(HaveKeyWithValue("cores", int64(2)))
+		Expect(cpu).To(HaveKeyWithValue("threads", int64(1)))
+	})
+
+	It("rejects a topology that does not match the vCPU count", func() {
+		opts := testCreateOptions()
+		opts.Sockets = 3
+
+		err := validateCreateOptions(opts)
+		Expect(err).To(MatchError(ErrInvalidOptions))
+		Expect(err.Error()).To(ContainSubstring("2 vCPUs cannot be split into 3 sockets"))
+	})
+
+	It("rejects sockets and threads whose product overflows", func() {
+		opts := testCreateOptions()
+		opts.Sockets = 65536
+		opts.Threads = 65536
+
+		err := validateCreateOptions(opts)
+		Expect(err).To(MatchError(ErrInvalidOptions))
+		Expect(err.Error()).To(ContainSubstring("2 vCPUs cannot be split into 65536 sockets of 65536 threads"))
+		_, cores, _ := cpuTopology(opts)
+		Expect(cores).To(BeZero())
+	})
+})
@@ -0,0 +1,123 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package harvester
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+// RenderedVM holds the objects CreateVM would submit for a set of options.
+type RenderedVM struct {
+	VM *unstructured.Unstructured
+	// PVCs are the root disk claim cloned from the image, if any, followed
+	// by the data disk claims. An imported root disk is part of the VM as
+	// a dataVolumeTemplates entry. The persistent cloud-init disk, its seed
+	// Secret and populator Job are not rendered.
+	PVCs []*corev1.PersistentVolumeClaim
+}
+
+// RenderVM returns the VirtualMachine and PVCs CreateVM would create for opts
+// without creating anything. It runs the same validation, defaulting and
+// checks against the cluster as CreateVM, so errors match what creating the
+// VM would report, except that a terminating previous instance is not
+// detected. Like CreateVM, it reports disk PVCs a previous instance left
+// behind without a VM as ErrStaleDisk and keeps them.
+func (c *Client) RenderVM(ctx context.Context, opts VMCreateOptions) (*RenderedVM, error) {
+	if err := validateCreateOptions(opts); err != nil {
+		return nil, err
+	}
+	if opts.RestoreFromBackup != "" {
+		return nil, invalidOptionsf("a VM restored from a backup cannot be rendered")
+	}
+
+	plan, err := c.planVM(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.planDisks(ctx, plan); err != nil {
+		return nil, err
+	}
+	dataDisks := c.dataDiskPVCs(plan.opts)
+	for _, pvc := range dataDisks {
+		if err := c.checkDiskPVCAvailable(ctx, "data disk", pvc.Name, plan.opts); err != nil {
+			return nil, err
+		}
+	}
+
+	rendered := &RenderedVM{VM: c.buildPlannedVM(plan)}
+	if plan.dataVolume == nil && plan.pvcName != "" {
+		pvc, err := c.imagePVC(ctx, plan.pvcName, plan.imageName, plan.opts)
+		if err != nil {
+			return nil, err
+		}
+		rendered.PVCs = append(rendered.PVCs, pvc)
+	}
+	rendered.PVCs = append(rendered.PVCs, dataDisks...)
+	for _, pvc := range rendered.PVCs {
+		pvc.APIVersion, pvc.Kind = "v1", "PersistentVolumeClaim"
+	}
+	return rendered, nil
+}
+
+// RedactedYAML returns the rendered objects as a multi-document YAML stream,
+// PVCs first. Like RedactedOptionsJSON, the cloud-init user data and network
+// data are replaced with their size and SHA-256.
+func (r *RenderedVM) RedactedYAML() (string, error) {
+	vm := r.VM.DeepCopy()
+	volumes, _, _ := unstructured.NestedSlice(vm.Object, "spec", "template", "spec", "volumes")
+	for _, v := range volumes {
+		volume, _ := v.(map[string]interface{})
+		noCloud, ok := volume["cloudInitNoCloud"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for _, key := range []string{"userDataBase64", "networkDataBase64"} {
+			if encoded, ok := noCloud[key].(string); ok {
+				payload, err := base64.StdEncoding.DecodeString(encoded)
+				if err != nil {
+					return "", fmt.Errorf("failed to decode %s: %w", key, err)
+				}
+				noCloud[key] = redactPayload(string(payload))
+			}
+		}
+	}
+	if err := unstructured.SetNestedSlice(vm.Object, volumes, "spec", "template", "spec", "volumes"); err != nil {
+		return "", err
+	}
+
+	docs := make([]string, 0, len(r.PVCs)+1)
+	for _, pvc := range r.PVCs {
+		out, err := yaml.Marshal(pvc)
+		if err != nil {
+			return "", fmt.Errorf("failed to render PVC %s: %w", pvc.Name, err)
+		}
+		docs = append(docs, string(out))
+	}
+	out, err := yaml.Marshal(vm.Object)
+	if err != nil {
+		return "", fmt.Errorf("failed to render VM %s: %w", vm.GetName(), err)
+	}
+	docs = append(docs, string(out))
+	return strings.Join(docs, "---\n"), nil
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package harvester
+
+import (
+	"context"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+var _ = Describe("Rendering a VM", func() {
+	var ctx context.Context
+
+	BeforeEach(func() {
+		ctx = context.Background()
+	})
+
+	It("renders what CreateVM creates without creating anything", func() {
+		c := newTestClient()
+		opts := testCreateOptions()
+		opts.DataDisks = []DataDiskSpec{{SizeGB: 50}}
+
+		rendered, err := c.RenderVM(ctx, opts)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rendered.PVCs).To(HaveLen(2))
+		Expect(rendered.PVCs[0].Name).To(Equal(ResolveRootDiskPVCName(opts)))
+		Expect(rendered.PVCs[1].Name).To(Equal(DataDiskPVCName(opts.Name, 0)))
+
+		pvcs, err := c.clientset.CoreV1().PersistentVolumeClaims(testNamespace).List(ctx, metav1.ListOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pvcs.Items).To(BeEmpty())
+		_, err = c.GetVM(ctx, opts.Name)
+		Expect(err).To(HaveOccurred())
+
+		_, _, err = c.CreateVM(ctx, opts)
+		Expect(err).NotTo(HaveOccurred())
+		vm, err := c.GetVM(ctx, opts.Name)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rendered.VM.Object["spec"]).To(Equal(vm.Object["spec"]))
+		root, err := c.clientset.CoreV1().PersistentVolumeClaims(testNamespace).Get(ctx, rendered.PVCs[0].Name, metav1.GetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(root.Spec).To(Equal(rendered.PVCs[0].Spec))
+	})
+
+	It("reports the errors CreateVM would", func() {
+		c := newTestClient()
+		opts := testCreateOptions()
+		opts.ImageName = "default/missing"
+		_, err := c.RenderVM(ctx, opts)
+		Expect(err).To(MatchError(ErrImageNotFound))
+	})
+
+	It("reports stale disk PVCs without deleting them", func() {
+		opts := testCreateOptions()
+		opts.DataDisks = []DataDiskSpec{{SizeGB: 50}}
+		for _, name := range []string{ResolveRootDiskPVCName(opts), DataDiskPVCName(opts.Name, 0)} {
+			c := newTestClient(&corev1.PersistentVolumeClaim{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        name,
+					Namespace:   testNamespace,
+					Annotations: map[string]string{AnnotationOwnerUID: opts.OwnerUID},
+				},
+			})
+
+			_, err := c.RenderVM(ctx, opts)
+			Expect(err).To(MatchError(ErrStaleDisk))
+			Expect(err.Error()).To(ContainSubstring(name))
+			_, err = c.clientset.CoreV1().PersistentVolumeClaims(testNamespace).Get(ctx, name, metav1.GetOptions{})
+			Expect(err).NotTo(HaveOccurred(), "PVC %s is kept", name)
+		}
+	})
+
+	It("redacts the cloud-init payloads", func() {
+		c := newTestClient()
+		opts := testCreateOptions()
+		opts.UserData = "#cloud-config\npassword: hunter2\n"
+
+		rendered, err := c.RenderVM(ctx, opts)
+		Expect(err).NotTo(HaveOccurred())
+		out, err := rendered.RedactedYAML()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(out).NotTo(ContainSubstring("hunter2"))
+		Expect(out).To(ContainSubstring("userDataBase64: '<redacted: 32 bytes, sha256"))
+		Expect(strings.Count(out, "---\n")).To(Equal(1))
+		Expect(out).To(ContainSubstring("kind: PersistentVolumeClaim"))
+
+		volumes, _, _ := unstructured.NestedSlice(rendered.VM.Object, "spec", "template", "spec", "volumes")
+		noCloud, _, _ := unstructured.NestedString(volumes[len(volumes)-1].(map[string]interface{}), "cloudInitNoCloud", "userDataBase64")
+		Expect(noCloud).NotTo(HavePrefix("<redacted"), "the rendered VM itself is left intact")
+	})
+})
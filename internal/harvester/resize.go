@@ -0,0 +1,251 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package harvester
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// VMSize is the CPU count and guest memory of a VM.
+type VMSize struct {
+	CPU      int32
+	MemoryMB int32
+}
+
+// ResizeStatus describes the size of a VM and whether it can change while
+// the guest runs.
+type ResizeStatus struct {
+	// Size is the size in the VM template.
+	Size VMSize
+	// CPUHotplug and MemoryHotplug report whether the template reserves
+	// hotplug headroom (domain.cpu.maxSockets, domain.memory.maxGuest), as
+	// Harvester does when CPU and memory hotplug is enabled for the VM.
+	CPUHotplug    bool
+	MemoryHotplug bool
+	// MaxSize is the largest size the guest can be hotplugged up to. Each
+	// dimension is zero without hotplug headroom.
+	MaxSize VMSize
+	// Live is the size of the running guest, which lags Size while a
+	// hotplug is in progress. Zero when the VM is stopped.
+	Live VMSize
+	// VMIUID identifies the running instance. Empty when the VM is stopped.
+	VMIUID string
+	// RestartRequired reports the KubeVirt RestartRequired condition: a
+	// template change could not be applied to the running guest.
+	RestartRequired bool
+}
+
+// templateDomain returns the domain of the VM template.
+func templateDomain(vm *unstructured.Unstructured) map[string]interface{} {
+	domain, _, _ := unstructured.NestedMap(vm.Object, "spec", "template", "spec", "domain")
+	return domain
+}
+
+// templateSize returns the CPU count and guest memory of the VM template.
+func templateSize(domain map[string]interface{}) (VMSize, error) {
+	var size VMSize
+	cpus := int64(1)
+	for _, field := range []string{"sockets", "cores", "threads"} {
+		if n, ok, _ := unstructured.NestedInt64(domain, "cpu", field); ok && n > 0 {
+			cpus *= n
+		}
+	}
+	size.CPU = int32(cpus)
+
+	guest, _, _ := unstructured.NestedString(domain, "memory", "guest")
+	q, err := resource.ParseQuantity(guest)
+	if err != nil {
+		return size, fmt.Errorf("invalid guest memory %q: %w", guest, err)
+	}
+	size.MemoryMB = int32(q.Value() >> 20)
+	return size, nil
+}
+
+// GetResizeStatus returns the size of the VM and its hotplug capabilities.
+func (c *Client) GetResizeStatus(ctx context.Context, name string) (*ResizeStatus, error) {
+	vm, err := c.GetVM(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	domain := templateDomain(vm)
+	size, err := templateSize(domain)
+	if err != nil {
+		return nil, err
+	}
+	status := &ResizeStatus{Size: size, RestartRequired: hasTrueCondition(vm, "RestartRequired")}
+	if maxSockets, ok, _ := unstructured.NestedInt64(domain, "cpu", "maxSockets"); ok && maxSockets > 0 {
+		status.CPUHotplug = true
+		perSocket := int64(1)
+		for _, field := range []string{"cores", "threads"} {
+			if n, ok, _ := unstructured.NestedInt64(domain, "cpu", field); ok && n > 0 {
+				perSocket *= n
+			}
+		}
+		status.MaxSize.CPU = int32(maxSockets * perSocket)
+	}
+	if maxGuest, ok, _ := unstructured.NestedString(domain, "memory", "maxGuest"); ok && maxGuest != "" {
+		status.MemoryHotplug = true
+		if q, err := resource.ParseQuantity(maxGuest); err == nil {
+			status.MaxSize.MemoryMB = int32(q.Value() >> 20)
+		}
+	}
+	if vmi, err := c.GetVMI(ctx, name); err == nil {
+		status.VMIUID = string(vmi.GetUID())
+		status.Live = liveSize(vmi)
+	}
+	return status, nil
+}
+
+// liveSize returns the size of a running guest: the current CPU topology
+// and guest memory KubeVirt reports, falling back to the VMI spec on
+// KubeVirt versions that do not report them.
+func liveSize(vmi *unstructured.Unstructured) VMSize {
+	spec, _, _ := unstructured.NestedMap(vmi.Object, "spec", "domain")
+	size, _ := templateSize(spec)
+	if topology, ok, _ := unstructured.NestedMap(vmi.Object, "status", "currentCPUTopology"); ok {
+		cpus := int64(1)
+		for _, field := range []string{"sockets", "cores", "threads"} {
+			if n, ok, _ := unstructured.NestedInt64(topology, field); ok && n > 0 {
+				cpus *= n
+			}
+		}
+		size.CPU = int32(cpus)
+	}
+	if current, ok, _ := unstructured.NestedString(vmi.Object, "status", "memory", "guestCurrent"); ok {
+		if q, err := resource.ParseQuantity(current); err == nil {
+			size.MemoryMB = int32(q.Value() >> 20)
+		}
+	}
+	return size
+}
+
+// UpdateVMResources sets the CPU count and guest memory in the VM template
+// to opts.CPU and opts.MemoryMB, along with the matching resource limits and
+// requests. The memory request and limit are computed from opts as
+// MemoryRequest and MemoryLimit do at creation, so opts must carry the
+// memory settings the VM was created with. With CPU hotplug headroom, CPUs
+// are added and removed in whole sockets so KubeVirt can plug them; KubeVirt
+// applies the change to the running guest where it can and otherwise sets
+// RestartRequired. It returns the resulting template size.
+func (c *Client) UpdateVMResources(ctx context.Context, name string, opts VMCreateOptions) (VMSize, error) {
+	size := VMSize{CPU: opts.CPU, MemoryMB: opts.MemoryMB}
+	vm, err := c.GetVM(ctx, name)
+	if err != nil {
+		return VMSize{}, err
+	}
+	domain := templateDomain(vm)
+	current, err := templateSize(domain)
+	if err != nil {
+		return VMSize{}, err
+	}
+	if size == current {
+		return current, nil
+	}
+	if size.CPU <= 0 || size.MemoryMB <= 0 {
+		return current, invalidOptionsf("cannot resize VM %s to %d CPUs and %dMi", name, size.CPU, size.MemoryMB)
+	}
+	if maxGuest, _, _ := unstructured.NestedString(domain, "memory", "maxGuest"); maxGuest != "" && size.MemoryMB > current.MemoryMB {
+		if q, err := resource.ParseQuantity(maxGuest); err == nil && int64(size.MemoryMB) > q.Value()>>20 {
+			return current, invalidOptionsf("cannot resize VM %s to %dMi: above its maximum guest memory %s", name, size.MemoryMB, maxGuest)
+		}
+	}
+
+	cpuPatch := map[string]interface{}{}
+	limits := map[string]interface{}{}
+	requests := map[string]interface{}{}
+	if size.CPU != current.CPU {
+		if _, ok, _ := unstructured.NestedMap(domain, "cpu", "numa"); ok {
+			return current, invalidOptionsf("cannot change the CPU count of VM %s, which has guest NUMA cells", name)
+		}
+		sockets, _, _ := unstructured.NestedInt64(domain, "cpu", "sockets")
+		cores, _, _ := unstructured.NestedInt64(domain, "cpu", "cores")
+		threads, _, _ := unstructured.NestedInt64(domain, "cpu", "threads")
+		sockets, cores, threads = max(sockets, 1), max(cores, 1), max(threads, 1)
+		maxSockets, _, _ := unstructured.NestedInt64(domain, "cpu", "maxSockets")
+		field, perUnit := "cores", sockets*threads
+		if maxSockets > 0 {
+			field, perUnit = "sockets", cores*threads
+		}
+		if int64(size.CPU)%perUnit != 0 {
+			return current, invalidOptionsf("cannot resize VM %s to %d CPUs: must be a multiple of %d", name, size.CPU, perUnit)
+		}
+		if maxSockets > 0 && int64(size.CPU)/perUnit > maxSockets {
+			return current, invalidOptionsf("cannot resize VM %s to %d CPUs: above its maximum of %d sockets", name, size.CPU, maxSockets)
+		}
+		cpuPatch[field] = int64(size.CPU) / perUnit
+
+		// Limits carry the extra core of an isolated emulator thread
+		removed := int64(current.CPU - size.CPU)
+		resources, _, _ := unstructured.NestedMap(domain, "resources")
+		limit, _, _ := unstructured.NestedString(resources, "limits", "cpu")
+		request, _, _ := unstructured.NestedString(resources, "requests", "cpu")
+		if q, err := resource.ParseQuantity(limit); err == nil && limit != "" {
+			newLimit := fmt.Sprintf("%d", max(q.Value()-removed, 1))
+			limits["cpu"] = newLimit
+			// An explicit request above the new limit is lowered to it
+			rq, err := resource.ParseQuantity(request)
+			if request == limit || (err == nil && rq.Cmp(resource.MustParse(newLimit)) > 0) {
+				requests["cpu"] = newLimit
+			}
+		}
+	}
+
+	memoryPatch := map[string]interface{}{}
+	if size.MemoryMB != current.MemoryMB {
+		// An explicit request or limit must still bound the new guest memory
+		if err := validateMemoryBounds(opts); err != nil {
+			return current, fmt.Errorf("cannot resize VM %s to %dMi: %w", name, size.MemoryMB, err)
+		}
+		memoryPatch["guest"] = fmt.Sprintf("%dMi", size.MemoryMB)
+		limit, request := MemoryLimit(opts), MemoryRequest(opts)
+		limits["memory"] = limit.String()
+		requests["memory"] = request.String()
+	}
+
+	patchDomain := map[string]interface{}{
+		"resources": map[string]interface{}{"limits": limits, "requests": requests},
+	}
+	if len(cpuPatch) > 0 {
+		patchDomain["cpu"] = cpuPatch
+	}
+	if len(memoryPatch) > 0 {
+		patchDomain["memory"] = memoryPatch
+	}
+	patch, err := json.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"spec": map[string]interface{}{"domain": patchDomain},
+			},
+		},
+	})
+	if err != nil {
+		return current, err
+	}
+	if _, err := retryResult(ctx, c, func(ctx context.Context) (*unstructured.Unstructured, error) {
+		return c.dynamic.Resource(vmGVR).Namespace(c.namespace).Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{})
+	}); err != nil {
+		return current, fmt.Errorf("failed to resize VM %s: %w", name, err)
+	}
+	return size, nil
+}
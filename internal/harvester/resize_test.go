@@ -0,0 +1,232 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package harvester
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+var _ = Describe("VM resize", func() {
+	var (
+		ctx  context.Context
+		c    *Client
+		opts VMCreateOptions
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		c = newTestClient()
+		opts = testCreateOptions()
+		_, _, err := c.CreateVM(ctx, opts)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	resized := func(cpu, memoryMB int32) VMCreateOptions {
+		resized := opts
+		resized.CPU, resized.MemoryMB = cpu, memoryMB
+		return resized
+	}
+
+	domainField := func(fields ...string) interface{} {
+		vm, err := c.GetVM(ctx, opts.Name)
+		Expect(err).NotTo(HaveOccurred())
+		value, _, _ := unstructured.NestedFieldNoCopy(vm.Object, append([]string{"spec", "template", "spec", "domain"}, fields...)...)
+		return value
+	}
+
+	It("lowers CPU, memory and the matching resources", func() {
+		size, err := c.UpdateVMResources(ctx, opts.Name, resized(1, 2048))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(size).To(Equal(VMSize{CPU: 1, MemoryMB: 2048}))
+
+		Expect(domainField("cpu", "cores")).To(BeEquivalentTo(1))
+		Expect(domainField("memory", "guest")).To(Equal("2048Mi"))
+		Expect(domainField("resources", "limits", "cpu")).To(Equal("1"))
+		// The default overhead is a share of the new guest memory
+		Expect(domainField("resources", "limits", "memory")).To(Equal("2089Mi"))
+		Expect(domainField("resources", "requests", "memory")).To(Equal("2Gi"))
+
+		status, err := c.GetResizeStatus(ctx, opts.Name)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(status.Size).To(Equal(size))
+		Expect(status.CPUHotplug).To(BeFalse())
+	})
+
+	It("raises CPU, memory and the matching resources", func() {
+		size, err := c.UpdateVMResources(ctx, opts.Name, resized(4, 8192))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(size).To(Equal(VMSize{CPU: 4, MemoryMB: 8192}))
+
+		Expect(domainField("cpu", "cores")).To(BeEquivalentTo(4))
+		Expect(domainField("memory", "guest")).To(Equal("8192Mi"))
+		Expect(domainField("resources", "limits", "cpu")).To(Equal("4"))
+		Expect(domainField("resources", "limits", "memory")).To(Equal("8356Mi"))
+	})
+
+	It("keeps an explicit memory request and limit", func() {
+		opts.Name = "worker-1"
+		opts.MemoryRequestMB = 2048
+		opts.MemoryLimitMB = 8192
+		_, _, err := c.CreateVM(ctx, opts)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = c.UpdateVMResources(ctx, opts.Name, resized(2, 6144))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(domainField("memory", "guest")).To(Equal("6144Mi"))
+		Expect(domainField("resources", "limits", "memory")).To(Equal("8Gi"))
+		Expect(domainField("resources", "requests", "memory")).To(Equal("2Gi"))
+
+		_, err = c.UpdateVMResources(ctx, opts.Name, resized(2, 16384))
+		Expect(err).To(MatchError(ErrInvalidOptions))
+		Expect(err).To(MatchError(ContainSubstring("memory limit 8192Mi is below the guest memory 16384Mi")))
+		Expect(domainField("memory", "guest")).To(Equal("6144Mi"))
+
+		opts.MemoryLimitMB = 0
+		_, err = c.UpdateVMResources(ctx, opts.Name, resized(2, 1024))
+		Expect(err).To(MatchError(ContainSubstring("memory request 2048Mi exceeds the guest memory 1024Mi")))
+	})
+
+	It("lowers an explicit CPU request above the new CPU count", func() {
+		opts.Name = "worker-1"
+		opts.CPU = 4
+		opts.CPURequestMillicores = 3000
+		_, _, err := c.CreateVM(ctx, opts)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = c.UpdateVMResources(ctx, opts.Name, resized(2, 4096))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(domainField("resources", "limits", "cpu")).To(Equal("2"))
+		Expect(domainField("resources", "requests", "cpu")).To(Equal("2"))
+	})
+
+	It("keeps the overcommit share of the memory request", func() {
+		opts.Name = "worker-1"
+		opts.EnableOvercommit = true
+		opts.MemoryRequestPercent = 25
+		_, _, err := c.CreateVM(ctx, opts)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = c.UpdateVMResources(ctx, opts.Name, resized(2, 8192))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(domainField("resources", "requests", "memory")).To(Equal("2Gi"))
+	})
+
+	It("leaves an unchanged size alone", func() {
+		size, err := c.UpdateVMResources(ctx, opts.Name, resized(2, 4096))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(size).To(Equal(VMSize{CPU: 2, MemoryMB: 4096}))
+		Expect(domainField("memory", "guest")).To(Equal("4096Mi"))
+	})
+
+	It("removes whole sockets from a VM with CPU hotplug headroom", func() {
+		vm, err := c.GetVM(ctx, opts.Name)
+		Expect(err).NotTo(HaveOccurred())
+		cpu := map[string]interface{}{"sockets": int64(4), "cores": int64(2), "threads": int64(1), "maxSockets": int64(8)}
+		Expect(unstructured.SetNestedMap(vm.Object, cpu, "spec", "template", "spec", "domain", "cpu")).To(Succeed())
+		_, err = c.dynamic.Resource(vmGVR).Namespace(testNamespace).Update(ctx, vm, metav1.UpdateOptions{})
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = c.UpdateVMResources(ctx, opts.Name, resized(3, 4096))
+		Expect(err).To(MatchError(ErrInvalidOptions))
+
+		size, err := c.UpdateVMResources(ctx, opts.Name, resized(4, 4096))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(size.CPU).To(BeEquivalentTo(4))
+		Expect(domainField("cpu", "sockets")).To(BeEquivalentTo(2))
+
+		_, err = c.UpdateVMResources(ctx, opts.Name, resized(18, 4096))
+		Expect(err).To(MatchError(ErrInvalidOptions))
+
+		status, err := c.GetResizeStatus(ctx, opts.Name)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(status.MaxSize.CPU).To(BeEquivalentTo(16))
+	})
+
+	It("rejects memory above the hotplug maximum", func() {
+		vm, err := c.GetVM(ctx, opts.Name)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(unstructured.SetNestedField(vm.Object, "8Gi", "spec", "template", "spec", "domain", "memory", "maxGuest")).To(Succeed())
+		_, err = c.dynamic.Resource(vmGVR).Namespace(testNamespace).Update(ctx, vm, metav1.UpdateOptions{})
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = c.UpdateVMResources(ctx, opts.Name, resized(2, 16384))
+		Expect(err).To(MatchError(ErrInvalidOptions))
+		size, err := c.UpdateVMResources(ctx, opts.Name, resized(2, 8192))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(size.MemoryMB).To(BeEquivalentTo(8192))
+
+		status, err := c.GetResizeStatus(ctx, opts.Name)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(status.MemoryHotplug).To(BeTrue())
+		Expect(status.MaxSize.MemoryMB).To(BeEquivalentTo(8192))
+	})
+
+	It("reports the size of the running guest", func() {
+		addVMI(c, opts.Name)
+		vmi, err := c.GetVMI(ctx, opts.Name)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(unstructured.SetNestedField(vmi.Object, "4Gi", "spec", "domain", "memory", "guest")).To(Succeed())
+		topology := map[string]interface{}{"sockets": int64(1), "cores": int64(2), "threads": int64(1)}
+		Expect(unstructured.SetNestedMap(vmi.Object, topology, "status", "currentCPUTopology")).To(Succeed())
+		Expect(unstructured.SetNestedField(vmi.Object, "3Gi", "status", "memory", "guestCurrent")).To(Succeed())
+		_, err = c.dynamic.Resource(vmiGVR).Namespace(testNamespace).Update(ctx, vmi, metav1.UpdateOptions{})
+		Expect(err).NotTo(HaveOccurred())
+
+		status, err := c.GetResizeStatus(ctx, opts.Name)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(status.Live).To(Equal(VMSize{CPU: 2, MemoryMB: 3072}))
+	})
+})
+
+var _ = Describe("Memory limit", func() {
+	mib := func(q resource.Quantity) int64 { return q.Value() >> 20 }
+
+	It("adds a percentage of guest memory by default", func() {
+		opts := testCreateOptions()
+		Expect(mib(MemoryLimit(opts))).To(BeEquivalentTo(4178))
+		Expect(mib(MemoryRequest(opts))).To(BeEquivalentTo(4096))
+	})
+
+	It("adds the requested overhead", func() {
+		opts := testCreateOptions()
+		overhead := int32(512)
+		opts.MemoryOverheadMB = &overhead
+		Expect(mib(MemoryLimit(opts))).To(BeEquivalentTo(4608))
+
+		overhead = 0
+		Expect(mib(MemoryLimit(opts))).To(BeEquivalentTo(4096))
+	})
+
+	It("requests the full limit for dedicated CPU placement", func() {
+		opts := testCreateOptions()
+		opts.DedicatedCPUPlacement = true
+		Expect(mib(MemoryRequest(opts))).To(Equal(mib(MemoryLimit(opts))))
+	})
+
+	It("rejects a negative overhead", func() {
+		opts := testCreateOptions()
+		overhead := int32(-1)
+		opts.MemoryOverheadMB = &overhead
+		Expect(validateCreateOptions(opts)).To(MatchError(ErrInvalidOptions))
+	})
+})
@@ -0,0 +1,175 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package harvester
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+var restoreGVR = schema.GroupVersionResource{
+	Group:    "harvesterhci.io",
+	Version:  "v1beta1",
+	Resource: "virtualmachinerestores",
+}
+
+// RestoreStatus reports the progress of a VM restore from a backup or snapshot.
+type RestoreStatus struct {
+	Complete bool
+	Failed   bool
+	// Message is the latest progress or failure message reported by Harvester.
+	Message string
+}
+
+// restoreName returns the name of the VirtualMachineRestore for a VM.
+func restoreName(vmName string) string {
+	return vmName + "-restore"
+}
+
+// createRestore creates a VirtualMachineRestore that restores
+// opts.RestoreFromBackup into a new VM named opts.Name. Harvester creates the
+// VM and its volumes once the restore completes.
+func (c *Client) createRestore(ctx context.Context, opts VMCreateOptions) error {
+	backupNamespace, backupName := splitRef(opts.RestoreFromBackup, c.namespace)
+
+	restore := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "harvesterhci.io/v1beta1",
+			"kind":       "VirtualMachineRestore",
+			"metadata": map[string]interface{}{
+				"name":      restoreName(opts.Name),
+				"namespace": c.namespace,
+				"labels": map[string]interface{}{
+					LabelManagedBy: managedByValue,
+				},
+				"annotations": map[string]interface{}{
+					AnnotationOwnerUID: opts.OwnerUID,
+					AnnotationOwner:    opts.Owner,
+				},
+			},
+			"spec": map[string]interface{}{
+				"target": map[string]interface{}{
+					"apiGroup": "kubevirt.io",
+					"kind":     "VirtualMachine",
+					"name":     opts.Name,
+				},
+				"virtualMachineBackupName":      backupName,
+				"virtualMachineBackupNamespace": backupNamespace,
+				"newVM":                         true,
+			},
+		},
+	}
+
+	if _, err := retryCreate(ctx, c, func(ctx context.Context) (*unstructured.Unstructured, error) {
+		return c.dynamic.Resource(restoreGVR).Namespace(c.namespace).Create(ctx, restore, metav1.CreateOptions{})
+	}, func(ctx context.Context) (*unstructured.Unstructured, error) {
+		return c.dynamic.Resource(restoreGVR).Namespace(c.namespace).Get(ctx, restore.GetName(), metav1.GetOptions{})
+	}); err != nil {
+		return fmt.Errorf("failed to create VM restore: %w", err)
+	}
+	return nil
+}
+
+// GetRestoreStatus returns the progress of the restore for a VM.
+func (c *Client) GetRestoreStatus(ctx context.Context, vmName string) (*RestoreStatus, error) {
+	restore, err := retryResult(ctx, c, func(ctx context.Context) (*unstructured.Unstructured, error) {
+		return c.dynamic.Resource(restoreGVR).Namespace(c.namespace).Get(ctx, restoreName(vmName), metav1.GetOptions{})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return restoreStatus(restore), nil
+}
+
+// restoreStatus reads the progress of a Harvester or KubeVirt
+// VirtualMachineRestore, which report it the same way.
+func restoreStatus(restore *unstructured.Unstructured) *RestoreStatus {
+	status := &RestoreStatus{}
+	status.Complete, _, _ = unstructured.NestedBool(restore.Object, "status", "complete")
+	conditions, _, _ := unstructured.NestedSlice(restore.Object, "status", "conditions")
+	for _, cond := range conditions {
+		condMap, ok := cond.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		t, _, _ := unstructured.NestedString(condMap, "type")
+		st, _, _ := unstructured.NestedString(condMap, "status")
+		msg, _, _ := unstructured.NestedString(condMap, "message")
+		switch {
+		case t == "Failure" && st == "True":
+			status.Failed = true
+			status.Message = msg
+			return status
+		case t == "Progressing" && msg != "":
+			status.Message = msg
+		}
+	}
+	return status
+}
+
+// AdoptRestoredVM stamps the restored VM with the managed-by label and the
+// requesting MachineRequest, replacing any ownership carried over from the
+// backed-up VM, and returns its UID.
+func (c *Client) AdoptRestoredVM(ctx context.Context, name, ownerUID, owner string) (string, error) {
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"labels": map[string]interface{}{
+				LabelManagedBy: managedByValue,
+			},
+			"annotations": map[string]interface{}{
+				AnnotationOwnerUID: ownerUID,
+				AnnotationOwner:    owner,
+			},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	vm, err := retryResult(ctx, c, func(ctx context.Context) (*unstructured.Unstructured, error) {
+		return c.dynamic.Resource(vmGVR).Namespace(c.namespace).Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{})
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to adopt restored VM: %w", err)
+	}
+	return string(vm.GetUID()), nil
+}
+
+// DeleteRestore removes the restore object of a VM, so a failed restore
+// from backup can be started again.
+func (c *Client) DeleteRestore(ctx context.Context, vmName string) error {
+	err := c.retry(ctx, func(ctx context.Context) error {
+		return c.dynamic.Resource(restoreGVR).Namespace(c.namespace).Delete(ctx, restoreName(vmName), metav1.DeleteOptions{})
+	})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete VM restore: %w", err)
+	}
+	return nil
+}
+
+// deleteRestore removes the restore object of a VM, if any.
+func (c *Client) deleteRestore(ctx context.Context, vmName string) {
+	_ = c.retry(ctx, func(ctx context.Context) error {
+		return c.dynamic.Resource(restoreGVR).Namespace(c.namespace).Delete(ctx, restoreName(vmName), metav1.DeleteOptions{})
+	})
+}
@@ -0,0 +1,115 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package harvester
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+var _ = Describe("VM restore from backup", func() {
+	var (
+		ctx  context.Context
+		c    *Client
+		opts VMCreateOptions
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		c = newTestClient()
+		opts = testCreateOptions()
+		opts.ImageName = ""
+		opts.RestoreFromBackup = "backups/nightly"
+	})
+
+	getRestore := func() (*unstructured.Unstructured, error) {
+		return c.dynamic.Resource(restoreGVR).Namespace(testNamespace).Get(ctx, restoreName(opts.Name), metav1.GetOptions{})
+	}
+
+	It("creates a restore into a new VM instead of the VM", func() {
+		providerID, _, err := c.CreateVM(ctx, opts)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(providerID).To(BeEmpty())
+
+		restore, err := getRestore()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(restore.GetLabels()).To(HaveKeyWithValue(LabelManagedBy, managedByValue))
+		Expect(restore.GetAnnotations()).To(HaveKeyWithValue(AnnotationOwnerUID, "uid-1"))
+		spec, _, _ := unstructured.NestedMap(restore.Object, "spec")
+		Expect(spec).To(HaveKeyWithValue("virtualMachineBackupNamespace", "backups"))
+		Expect(spec).To(HaveKeyWithValue("virtualMachineBackupName", "nightly"))
+		Expect(spec).To(HaveKeyWithValue("newVM", true))
+		_, err = c.GetVM(ctx, opts.Name)
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+
+	DescribeTable("reads the restore progress",
+		func(status map[string]interface{}, expected RestoreStatus) {
+			restore := &unstructured.Unstructured{Object: map[string]interface{}{"status": status}}
+			Expect(*restoreStatus(restore)).To(Equal(expected))
+		},
+		Entry("in progress", map[string]interface{}{
+			"conditions": []interface{}{map[string]interface{}{"type": "Progressing", "status": "True", "message": "restoring volumes"}},
+		}, RestoreStatus{Message: "restoring volumes"}),
+		Entry("complete", map[string]interface{}{"complete": true}, RestoreStatus{Complete: true}),
+		Entry("failed", map[string]interface{}{
+			"conditions": []interface{}{
+				map[string]interface{}{"type": "Progressing", "status": "False", "message": "restoring volumes"},
+				map[string]interface{}{"type": "Failure", "status": "True", "message": "backup target unreachable"},
+			},
+		}, RestoreStatus{Failed: true, Message: "backup target unreachable"}),
+	)
+
+	It("adopts the restored VM as a managed VM of the MachineRequest", func() {
+		created := testCreateOptions()
+		created.OwnerUID = "uid-backed-up"
+		_, _, err := c.CreateVM(ctx, created)
+		Expect(err).NotTo(HaveOccurred())
+		vm, err := c.GetVM(ctx, created.Name)
+		Expect(err).NotTo(HaveOccurred())
+		labels := vm.GetLabels()
+		delete(labels, LabelManagedBy)
+		vm.SetLabels(labels)
+		_, err = c.dynamic.Resource(vmGVR).Namespace(testNamespace).Update(ctx, vm, metav1.UpdateOptions{})
+		Expect(err).NotTo(HaveOccurred())
+
+		uid, err := c.AdoptRestoredVM(ctx, created.Name, "uid-2", "butler-system/worker-0")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(uid).To(Equal(string(vm.GetUID())))
+		vm, err = c.GetVM(ctx, created.Name)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(vm.GetLabels()).To(HaveKeyWithValue(LabelManagedBy, managedByValue))
+		Expect(vm.GetAnnotations()).To(HaveKeyWithValue(AnnotationOwnerUID, "uid-2"))
+		Expect(vm.GetAnnotations()).To(HaveKeyWithValue(AnnotationOwner, "butler-system/worker-0"))
+	})
+
+	It("deletes the restore so it can be started again", func() {
+		_, _, err := c.CreateVM(ctx, opts)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(c.DeleteRestore(ctx, opts.Name)).To(Succeed())
+		_, err = getRestore()
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+		Expect(c.DeleteRestore(ctx, opts.Name)).To(Succeed())
+	})
+})
@@ -0,0 +1,148 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package harvester
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	utilnet "k8s.io/apimachinery/pkg/util/net"
+)
+
+const (
+	// DefaultMaxRetries is how often NewClient clients retry a transient
+	// API error before giving up.
+	DefaultMaxRetries = 4
+	// DefaultRetryBaseDelay is the wait before the first retry. It doubles
+	// with every further attempt up to maxRetryDelay.
+	DefaultRetryBaseDelay = 250 * time.Millisecond
+	// DefaultCallTimeout bounds each API call of NewClient clients.
+	DefaultCallTimeout = 30 * time.Second
+
+	maxRetryDelay = 10 * time.Second
+)
+
+// ErrCallTimeout is wrapped by errors of API calls that did not finish
+// within the client's CallTimeout. The Harvester API server may be hung, so
+// the call's outcome is unknown; it must not be read as NotFound.
+var ErrCallTimeout = errors.New("harvester API call timed out")
+
+// IsCallTimeout reports whether err is an API call timeout.
+func IsCallTimeout(err error) bool {
+	return errors.Is(err, ErrCallTimeout)
+}
+
+// call runs a single API call with a context bounded by c.CallTimeout.
+// Zero CallTimeout leaves the caller's context as is.
+func (c *Client) call(ctx context.Context, op func(ctx context.Context) error) error {
+	if c.CallTimeout <= 0 {
+		return op(ctx)
+	}
+	callCtx, cancel := context.WithTimeout(ctx, c.CallTimeout)
+	defer cancel()
+	err := op(callCtx)
+	if err != nil && ctx.Err() == nil && errors.Is(callCtx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%w after %s: %w", ErrCallTimeout, c.CallTimeout, err)
+	}
+	return err
+}
+
+// isRetryable reports whether err is a transient API server or connection
+// error, as seen while Harvester is being upgraded. NotFound and
+// AlreadyExists are never retried: callers depend on seeing them.
+func isRetryable(err error) bool {
+	switch {
+	// A timed out call is not retried so a hung server cannot hold a
+	// reconcile for several timeouts
+	case err == nil, IsCallTimeout(err), apierrors.IsNotFound(err), apierrors.IsAlreadyExists(err):
+		return false
+	case apierrors.IsServerTimeout(err), apierrors.IsTooManyRequests(err),
+		apierrors.IsInternalError(err), apierrors.IsServiceUnavailable(err):
+		return true
+	case utilnet.IsConnectionReset(err), utilnet.IsConnectionRefused(err), utilnet.IsProbableEOF(err):
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// retryDelay returns the wait before retry attempt (counting from zero):
+// the base delay doubled per attempt, capped, with up to half of it as
+// random jitter so clients do not retry in lockstep.
+func retryDelay(base time.Duration, attempt int) time.Duration {
+	delay := base
+	for i := 0; i < attempt && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	delay = min(delay, maxRetryDelay)
+	if half := int64(delay / 2); half > 0 {
+		delay = time.Duration(half + rand.Int64N(half+1))
+	}
+	return delay
+}
+
+// retry runs the API call op, retrying transient errors up to c.MaxRetries
+// times with capped exponential backoff. Every attempt gets its own
+// CallTimeout. It returns op's last error, also when ctx ends while waiting
+// for the next attempt.
+func (c *Client) retry(ctx context.Context, op func(ctx context.Context) error) error {
+	err := c.call(ctx, op)
+	for attempt := 0; attempt < c.MaxRetries && isRetryable(err); attempt++ {
+		timer := time.NewTimer(retryDelay(c.RetryBaseDelay, attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		err = c.call(ctx, op)
+	}
+	return err
+}
+
+// retryResult is retry for operations returning a value.
+func retryResult[T any](ctx context.Context, c *Client, op func(ctx context.Context) (T, error)) (T, error) {
+	var result T
+	err := c.retry(ctx, func(ctx context.Context) error {
+		var err error
+		result, err = op(ctx)
+		return err
+	})
+	return result, err
+}
+
+// retryCreate is retryResult for creates, which are not idempotent. An
+// attempt that failed with a connection error may still have created the
+// object, so AlreadyExists from a retried attempt means an earlier attempt
+// landed: the object is read back with get and returned instead of the
+// error. AlreadyExists from the first attempt is returned as is.
+func retryCreate[T any](ctx context.Context, c *Client, create, get func(ctx context.Context) (T, error)) (T, error) {
+	attempts := 0
+	result, err := retryResult(ctx, c, func(ctx context.Context) (T, error) {
+		attempts++
+		return create(ctx)
+	})
+	if attempts > 1 && apierrors.IsAlreadyExists(err) {
+		return retryResult(ctx, c, get)
+	}
+	return result, err
+}
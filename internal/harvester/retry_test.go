@@ -0,0 +1,179 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package harvester
+
+import (
+	"context"
+	"errors"
+	"syscall"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+var _ = Describe("API retries", func() {
+	var (
+		ctx   context.Context
+		c     *Client
+		opts  VMCreateOptions
+		calls int
+	)
+
+	// failGets makes the first n VM gets fail with err.
+	failGets := func(n int, err error) {
+		c.dynamic.(*dynamicfake.FakeDynamicClient).PrependReactor("get", "virtualmachines",
+			func(k8stesting.Action) (bool, runtime.Object, error) {
+				calls++
+				if calls <= n {
+					return true, nil, err
+				}
+				return false, nil, nil
+			})
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		c = newTestClient()
+		c.MaxRetries = 3
+		c.RetryBaseDelay = time.Millisecond
+		opts = testCreateOptions()
+		calls = 0
+		_, _, err := c.CreateVM(ctx, opts)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("retries transient server errors", func() {
+		failGets(2, apierrors.NewServerTimeout(vmGVR.GroupResource(), "get", 1))
+
+		_, err := c.GetVM(ctx, opts.Name)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(calls).To(Equal(3))
+	})
+
+	It("retries connection resets", func() {
+		failGets(1, syscall.ECONNRESET)
+
+		_, err := c.GetVM(ctx, opts.Name)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(calls).To(Equal(2))
+	})
+
+	It("gives up after MaxRetries", func() {
+		failGets(10, apierrors.NewTooManyRequests("slow down", 1))
+
+		_, err := c.GetVM(ctx, opts.Name)
+		Expect(apierrors.IsTooManyRequests(err)).To(BeTrue(), "got %v", err)
+		Expect(calls).To(Equal(4))
+	})
+
+	It("does not retry NotFound", func() {
+		failGets(10, apierrors.NewNotFound(vmGVR.GroupResource(), opts.Name))
+
+		_, err := c.GetVM(ctx, opts.Name)
+		Expect(apierrors.IsNotFound(err)).To(BeTrue(), "got %v", err)
+		Expect(calls).To(Equal(1))
+	})
+
+	It("does not retry AlreadyExists", func() {
+		c.dynamic.(*dynamicfake.FakeDynamicClient).PrependReactor("delete", "virtualmachines",
+			func(k8stesting.Action) (bool, runtime.Object, error) {
+				calls++
+				return true, nil, apierrors.NewAlreadyExists(vmGVR.GroupResource(), opts.Name)
+			})
+
+		Expect(apierrors.IsAlreadyExists(c.DeleteVM(ctx, opts.Name, DeleteVMOptions{}))).To(BeTrue())
+		Expect(calls).To(Equal(1))
+	})
+
+	It("reads back a create that landed before its connection reset", func() {
+		fake := c.dynamic.(*dynamicfake.FakeDynamicClient)
+		fake.PrependReactor("create", "virtualmachines",
+			func(action k8stesting.Action) (bool, runtime.Object, error) {
+				calls++
+				if calls == 1 {
+					// The VM is stored, but the response never arrives
+					obj := action.(k8stesting.CreateAction).GetObject()
+					Expect(fake.Tracker().Create(vmGVR, obj, testNamespace)).To(Succeed())
+					return true, nil, syscall.ECONNRESET
+				}
+				return false, nil, nil
+			})
+		second := testCreateOptions()
+		second.Name = "worker-1"
+
+		_, _, err := c.CreateVM(ctx, second)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(calls).To(Equal(2))
+		// The root disk under the landed VM is kept
+		_, err = c.clientset.CoreV1().PersistentVolumeClaims(testNamespace).Get(ctx, ResolveRootDiskPVCName(second), metav1.GetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("returns AlreadyExists from a first create attempt", func() {
+		_, _, err := c.CreateVM(ctx, opts)
+		Expect(apierrors.IsAlreadyExists(err)).To(BeTrue(), "got %v", err)
+	})
+
+	It("does not retry without MaxRetries", func() {
+		c.MaxRetries = 0
+		failGets(1, apierrors.NewInternalError(errors.New("upgrading")))
+
+		_, err := c.GetVM(ctx, opts.Name)
+		Expect(apierrors.IsInternalError(err)).To(BeTrue(), "got %v", err)
+		Expect(calls).To(Equal(1))
+	})
+
+	It("times out hung calls without retrying them", func() {
+		c.CallTimeout = 10 * time.Millisecond
+		c.dynamic.(*dynamicfake.FakeDynamicClient).PrependReactor("get", "virtualmachines",
+			func(k8stesting.Action) (bool, runtime.Object, error) {
+				calls++
+				// The fake ignores the context, so emulate a hung server
+				time.Sleep(20 * time.Millisecond)
+				return true, nil, context.DeadlineExceeded
+			})
+
+		_, err := c.GetVMStatus(ctx, opts.Name)
+		Expect(IsCallTimeout(err)).To(BeTrue(), "got %v", err)
+		Expect(apierrors.IsNotFound(err)).To(BeFalse())
+		Expect(err.Error()).To(ContainSubstring("timed out after 10ms"))
+		Expect(calls).To(Equal(1))
+	})
+
+	It("does not report a cancelled caller as a timeout", func() {
+		c.CallTimeout = time.Minute
+		cancelled, cancel := context.WithCancel(ctx)
+		cancel()
+		failGets(1, context.Canceled)
+
+		_, err := c.GetVM(cancelled, opts.Name)
+		Expect(err).To(MatchError(context.Canceled))
+		Expect(IsCallTimeout(err)).To(BeFalse())
+	})
+
+	It("caps the backoff", func() {
+		Expect(retryDelay(time.Second, 0)).To(BeNumerically("<=", time.Second))
+		Expect(retryDelay(time.Second, 2)).To(BeNumerically(">=", 2*time.Second))
+		Expect(retryDelay(time.Second, 20)).To(BeNumerically("<=", maxRetryDelay))
+	})
+})
@@ -0,0 +1,116 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package harvester
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// AnnotationVMRunStrategy is the Harvester annotation mirroring the VM run
+// strategy. The Harvester UI reads it, while KubeVirt acts on
+// spec.runStrategy, so the two must always be written together.
+const AnnotationVMRunStrategy = "harvesterhci.io/vmRunStrategy"
+
+// RunStrategy is a KubeVirt VM run strategy.
+type RunStrategy string
+
+const (
+	// RunStrategyAlways keeps a VMI running, restarting it when it stops.
+	RunStrategyAlways RunStrategy = "Always"
+	// RunStrategyHalted keeps the VM stopped.
+	RunStrategyHalted RunStrategy = "Halted"
+)
+
+// setRunStrategy sets the run strategy and its Harvester annotation on a VM
+// object.
+func setRunStrategy(vm *unstructured.Unstructured, strategy RunStrategy) {
+	annotations := vm.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[AnnotationVMRunStrategy] = string(strategy)
+	vm.SetAnnotations(annotations)
+	_ = unstructured.SetNestedField(vm.Object, string(strategy), "spec", "runStrategy")
+}
+
+// runStrategyPatch returns a merge patch setting the run strategy and its
+// Harvester annotation, plus any extra annotations.
+func runStrategyPatch(strategy RunStrategy, annotations map[string]string) ([]byte, error) {
+	patchAnnotations := map[string]interface{}{AnnotationVMRunStrategy: string(strategy)}
+	for k, v := range annotations {
+		patchAnnotations[k] = v
+	}
+	return json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{"annotations": patchAnnotations},
+		"spec":     map[string]interface{}{"runStrategy": string(strategy)},
+	})
+}
+
+// patchRunStrategy sets the run strategy of a VM together with its Harvester
+// annotation and any extra annotations.
+func (c *Client) patchRunStrategy(ctx context.Context, name string, strategy RunStrategy, annotations map[string]string) error {
+	patch, err := runStrategyPatch(strategy, annotations)
+	if err != nil {
+		return err
+	}
+	if _, err := retryResult(ctx, c, func(ctx context.Context) (*unstructured.Unstructured, error) {
+		return c.dynamic.Resource(vmGVR).Namespace(c.namespace).Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{})
+	}); err != nil {
+		return fmt.Errorf("failed to set run strategy of VM %s to %s: %w", name, strategy, err)
+	}
+	return nil
+}
+
+// StartVM starts a stopped VM.
+func (c *Client) StartVM(ctx context.Context, name string) error {
+	return c.patchRunStrategy(ctx, name, RunStrategyAlways, nil)
+}
+
+// StopVM stops a VM. KubeVirt shuts the guest down and keeps the VM and its
+// disks.
+func (c *Client) StopVM(ctx context.Context, name string) error {
+	return c.patchRunStrategy(ctx, name, RunStrategyHalted, nil)
+}
+
+// EnsureRunStrategy corrects the Harvester run strategy annotation of a VM
+// when it disagrees with spec.runStrategy, which KubeVirt acts on. It
+// returns the stale annotation value, or an empty string when nothing was
+// corrected.
+func (c *Client) EnsureRunStrategy(ctx context.Context, name string) (string, error) {
+	vm, err := c.GetVM(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	strategy, _, _ := unstructured.NestedString(vm.Object, "spec", "runStrategy")
+	annotated, ok := vm.GetAnnotations()[AnnotationVMRunStrategy]
+	if strategy == "" || (ok && annotated == strategy) {
+		return "", nil
+	}
+	if err := c.patchRunStrategy(ctx, name, RunStrategy(strategy), nil); err != nil {
+		return "", err
+	}
+	if !ok {
+		annotated = "<unset>"
+	}
+	return annotated, nil
+}
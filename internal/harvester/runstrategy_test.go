@@ -0,0 +1,91 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package harvester
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+var _ = Describe("Run strategy", func() {
+	var (
+		ctx context.Context
+		c   *Client
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		c = newTestClient()
+		_, _, err := c.CreateVM(ctx, testCreateOptions())
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	// runStrategyOf returns spec.runStrategy and the Harvester annotation.
+	runStrategyOf := func() (string, string) {
+		vm, err := c.GetVM(ctx, "worker-0")
+		Expect(err).NotTo(HaveOccurred())
+		strategy, _, _ := unstructured.NestedString(vm.Object, "spec", "runStrategy")
+		return strategy, vm.GetAnnotations()[AnnotationVMRunStrategy]
+	}
+
+	It("creates the VM with both set to Always", func() {
+		strategy, annotated := runStrategyOf()
+		Expect(strategy).To(Equal("Always"))
+		Expect(annotated).To(Equal("Always"))
+	})
+
+	It("keeps both consistent across stop and start", func() {
+		Expect(c.StopVM(ctx, "worker-0")).To(Succeed())
+		strategy, annotated := runStrategyOf()
+		Expect(strategy).To(Equal("Halted"))
+		Expect(annotated).To(Equal("Halted"))
+
+		Expect(c.StartVM(ctx, "worker-0")).To(Succeed())
+		strategy, annotated = runStrategyOf()
+		Expect(strategy).To(Equal("Always"))
+		Expect(annotated).To(Equal("Always"))
+
+		Expect(c.EnsureRunStrategy(ctx, "worker-0")).To(BeEmpty())
+	})
+
+	It("reports the run strategy in the VM status", func() {
+		status, err := c.GetVMStatus(ctx, "worker-0")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(status.RunStrategy).To(Equal(RunStrategyAlways))
+
+		Expect(c.StopVM(ctx, "worker-0")).To(Succeed())
+		status, err = c.GetVMStatus(ctx, "worker-0")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(status.RunStrategy).To(Equal(RunStrategyHalted))
+	})
+
+	It("corrects an annotation that diverged from the spec", func() {
+		patch := []byte(`{"spec":{"runStrategy":"Halted"}}`)
+		_, err := c.dynamic.Resource(vmGVR).Namespace(testNamespace).Patch(ctx, "worker-0", types.MergePatchType, patch, metav1.PatchOptions{})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(c.EnsureRunStrategy(ctx, "worker-0")).To(Equal("Always"))
+		strategy, annotated := runStrategyOf()
+		Expect(strategy).To(Equal("Halted"))
+		Expect(annotated).To(Equal("Halted"))
+	})
+})
@@ -0,0 +1,243 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package harvester
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilrand "k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// vmBackupGVR is the Harvester VirtualMachineBackup, which takes snapshots
+// with type "snapshot". Harvester tracks them next to its backups, shows them
+// in its UI and restores them with its VirtualMachineRestore, unlike KubeVirt
+// VirtualMachineSnapshots created behind its back.
+var vmBackupGVR = schema.GroupVersionResource{
+	Group:    "harvesterhci.io",
+	Version:  "v1beta1",
+	Resource: "virtualmachinebackups",
+}
+
+// backupTypeSnapshot is the VirtualMachineBackup type that snapshots the
+// volumes in the cluster instead of copying them to the backup target.
+const backupTypeSnapshot = "snapshot"
+
+// labelSnapshotVM marks the snapshots and snapshot restores of a VM, so
+// DeleteVMSnapshots finds them.
+const labelSnapshotVM = "butler.butlerlabs.dev/snapshot-vm"
+
+// Snapshot phases, derived from the readiness and error Harvester reports.
+const (
+	SnapshotPhaseInProgress = "InProgress"
+	SnapshotPhaseSucceeded  = "Succeeded"
+	SnapshotPhaseFailed     = "Failed"
+)
+
+// ErrSnapshotNotReady is returned when restoring a snapshot Harvester has not
+// finished taking.
+var ErrSnapshotNotReady = errors.New("snapshot not ready")
+
+// SnapshotStatus describes a snapshot taken by CreateVMSnapshot.
+type SnapshotStatus struct {
+	Name string
+	// Phase is SnapshotPhaseInProgress until the snapshot is ready to use
+	// or Harvester reports an error.
+	Phase string
+	// ReadyToUse reports whether the snapshot can be restored.
+	ReadyToUse bool
+	// Message explains a failed snapshot when Harvester reports why.
+	Message string
+}
+
+// Finished reports whether the snapshot succeeded or failed.
+func (s *SnapshotStatus) Finished() bool {
+	return s.Phase == SnapshotPhaseSucceeded || s.Phase == SnapshotPhaseFailed
+}
+
+// VMSnapshotName returns the name of the snapshot of a VM with the given
+// suffix.
+func VMSnapshotName(vmName, suffix string) string {
+	return vmName + "-snapshot-" + suffix
+}
+
+// snapshotName validates the suffix and returns the snapshot name.
+func snapshotName(vmName, suffix string) (string, error) {
+	if errs := validation.IsDNS1123Label(suffix); len(errs) > 0 {
+		return "", fmt.Errorf("invalid snapshot suffix %q: %s", suffix, errs[0])
+	}
+	name := VMSnapshotName(vmName, suffix)
+	if errs := validation.IsDNS1123Subdomain(name); len(errs) > 0 {
+		return "", fmt.Errorf("invalid snapshot name %q: %s", name, errs[0])
+	}
+	return name, nil
+}
+
+// snapshotLabels returns the labels of the snapshots and snapshot restores
+// of a VM.
+func snapshotLabels(vmName string) map[string]string {
+	return map[string]string{LabelManagedBy: managedByValue, labelSnapshotVM: vmName}
+}
+
+// CreateVMSnapshot takes a Harvester snapshot of the VM's disks and returns
+// its name, VMSnapshotName(vmName, suffix). The snapshot completes
+// asynchronously; poll GetVMSnapshotStatus. A snapshot of a running VM is
+// crash consistent unless the guest agent can freeze the filesystems.
+func (c *Client) CreateVMSnapshot(ctx context.Context, vmName, suffix string) (string, error) {
+	name, err := snapshotName(vmName, suffix)
+	if err != nil {
+		return "", err
+	}
+	snapshot := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "harvesterhci.io/v1beta1",
+			"kind":       "VirtualMachineBackup",
+			"metadata": map[string]interface{}{
+				"name":      name,
+				"namespace": c.namespace,
+			},
+			"spec": map[string]interface{}{
+				"source": map[string]interface{}{
+					"apiGroup": "kubevirt.io",
+					"kind":     "VirtualMachine",
+					"name":     vmName,
+				},
+				"type": backupTypeSnapshot,
+			},
+		},
+	}
+	snapshot.SetLabels(snapshotLabels(vmName))
+	if _, err := retryCreate(ctx, c, func(ctx context.Context) (*unstructured.Unstructured, error) {
+		return c.dynamic.Resource(vmBackupGVR).Namespace(c.namespace).Create(ctx, snapshot, metav1.CreateOptions{})
+	}, func(ctx context.Context) (*unstructured.Unstructured, error) {
+		return c.dynamic.Resource(vmBackupGVR).Namespace(c.namespace).Get(ctx, snapshot.GetName(), metav1.GetOptions{})
+	}); err != nil {
+		return "", fmt.Errorf("failed to create snapshot %s: %w", name, err)
+	}
+	return name, nil
+}
+
+// GetVMSnapshotStatus returns the progress of the named snapshot.
+func (c *Client) GetVMSnapshotStatus(ctx context.Context, name string) (*SnapshotStatus, error) {
+	snapshot, err := retryResult(ctx, c, func(ctx context.Context) (*unstructured.Unstructured, error) {
+		return c.dynamic.Resource(vmBackupGVR).Namespace(c.namespace).Get(ctx, name, metav1.GetOptions{})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get snapshot %s: %w", name, err)
+	}
+	status := &SnapshotStatus{Name: name, Phase: SnapshotPhaseInProgress}
+	status.ReadyToUse, _, _ = unstructured.NestedBool(snapshot.Object, "status", "readyToUse")
+	status.Message, _, _ = unstructured.NestedString(snapshot.Object, "status", "error", "message")
+	switch {
+	case status.ReadyToUse:
+		status.Phase = SnapshotPhaseSucceeded
+	case status.Message != "":
+		status.Phase = SnapshotPhaseFailed
+	}
+	return status, nil
+}
+
+// RestoreVMSnapshot restores the VM's disks from its snapshot with the given
+// suffix and returns the name of the restore, for GetSnapshotRestoreStatus.
+// Harvester only restores stopped VMs, and deletes the replaced volumes. It fails with ErrSnapshotNotReady when
+// the snapshot cannot be restored yet.
+func (c *Client) RestoreVMSnapshot(ctx context.Context, vmName, suffix string) (string, error) {
+	name, err := snapshotName(vmName, suffix)
+	if err != nil {
+		return "", err
+	}
+	snapshot, err := c.GetVMSnapshotStatus(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	if !snapshot.ReadyToUse {
+		return "", fmt.Errorf("%w: %s", ErrSnapshotNotReady, name)
+	}
+
+	restore := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "harvesterhci.io/v1beta1",
+			"kind":       "VirtualMachineRestore",
+			"metadata": map[string]interface{}{
+				"name":      fmt.Sprintf("%s-restore-%s", name, utilrand.String(5)),
+				"namespace": c.namespace,
+			},
+			"spec": map[string]interface{}{
+				"target": map[string]interface{}{
+					"apiGroup": "kubevirt.io",
+					"kind":     "VirtualMachine",
+					"name":     vmName,
+				},
+				"virtualMachineBackupName":      name,
+				"virtualMachineBackupNamespace": c.namespace,
+				"newVM":                         false,
+				"deletionPolicy":                "delete",
+			},
+		},
+	}
+	restore.SetLabels(snapshotLabels(vmName))
+	if _, err := retryCreate(ctx, c, func(ctx context.Context) (*unstructured.Unstructured, error) {
+		return c.dynamic.Resource(restoreGVR).Namespace(c.namespace).Create(ctx, restore, metav1.CreateOptions{})
+	}, func(ctx context.Context) (*unstructured.Unstructured, error) {
+		return c.dynamic.Resource(restoreGVR).Namespace(c.namespace).Get(ctx, restore.GetName(), metav1.GetOptions{})
+	}); err != nil {
+		return "", fmt.Errorf("failed to restore snapshot %s: %w", name, err)
+	}
+	return restore.GetName(), nil
+}
+
+// GetSnapshotRestoreStatus returns the progress of the named snapshot
+// restore.
+func (c *Client) GetSnapshotRestoreStatus(ctx context.Context, name string) (*RestoreStatus, error) {
+	restore, err := retryResult(ctx, c, func(ctx context.Context) (*unstructured.Unstructured, error) {
+		return c.dynamic.Resource(restoreGVR).Namespace(c.namespace).Get(ctx, name, metav1.GetOptions{})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get snapshot restore %s: %w", name, err)
+	}
+	return restoreStatus(restore), nil
+}
+
+// DeleteVMSnapshots deletes the snapshots CreateVMSnapshot took of the VM and
+// their restores. Harvester deletes the volume snapshots with them.
+func (c *Client) DeleteVMSnapshots(ctx context.Context, vmName string) error {
+	selector := labels.SelectorFromSet(snapshotLabels(vmName)).String()
+	for _, gvr := range []schema.GroupVersionResource{restoreGVR, vmBackupGVR} {
+		list, err := retryResult(ctx, c, func(ctx context.Context) (*unstructured.UnstructuredList, error) {
+			return c.dynamic.Resource(gvr).Namespace(c.namespace).List(ctx, metav1.ListOptions{LabelSelector: selector})
+		})
+		if err != nil {
+			return fmt.Errorf("failed to list %s of VM %s: %w", gvr.Resource, vmName, err)
+		}
+		for _, item := range list.Items {
+			err := c.retry(ctx, func(ctx context.Context) error {
+				return c.dynamic.Resource(gvr).Namespace(c.namespace).Delete(ctx, item.GetName(), metav1.DeleteOptions{})
+			})
+			if err != nil && !apierrors.IsNotFound(err) {
+				return fmt.Errorf("failed to delete %s %s: %w", gvr.Resource, item.GetName(), err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package harvester
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var _ = Describe("VM snapshots", func() {
+	var (
+		ctx context.Context
+		c   *Client
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		c = newTestClient()
+	})
+
+	list := func(gvr schema.GroupVersionResource) []unstructured.Unstructured {
+		list, err := c.dynamic.Resource(gvr).Namespace(testNamespace).List(ctx, metav1.ListOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		return list.Items
+	}
+
+	setStatus := func(gvr schema.GroupVersionResource, name string, status map[string]interface{}) {
+		obj, err := c.dynamic.Resource(gvr).Namespace(testNamespace).Get(ctx, name, metav1.GetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		obj.Object["status"] = status
+		_, err = c.dynamic.Resource(gvr).Namespace(testNamespace).Update(ctx, obj, metav1.UpdateOptions{})
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	It("names snapshots after the VM and the suffix", func() {
+		name, err := c.CreateVMSnapshot(ctx, "worker-0", "before-upgrade")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(name).To(Equal("worker-0-snapshot-before-upgrade"))
+
+		snapshots := list(vmBackupGVR)
+		Expect(snapshots).To(HaveLen(1))
+		Expect(snapshots[0].GetName()).To(Equal(name))
+		Expect(snapshots[0].GetLabels()).To(HaveKeyWithValue(LabelManagedBy, managedByValue))
+		Expect(snapshots[0].GetLabels()).To(HaveKeyWithValue(labelSnapshotVM, "worker-0"))
+		source, _, _ := unstructured.NestedString(snapshots[0].Object, "spec", "source", "name")
+		Expect(source).To(Equal("worker-0"))
+		backupType, _, _ := unstructured.NestedString(snapshots[0].Object, "spec", "type")
+		Expect(backupType).To(Equal("snapshot"))
+	})
+
+	It("rejects suffixes that are not DNS labels", func() {
+		_, err := c.CreateVMSnapshot(ctx, "worker-0", "Before_Upgrade")
+		Expect(err).To(MatchError(ContainSubstring(`invalid snapshot suffix "Before_Upgrade"`)))
+		Expect(list(vmBackupGVR)).To(BeEmpty())
+	})
+
+	It("reports snapshot progress and failures", func() {
+		name, err := c.CreateVMSnapshot(ctx, "worker-0", "s1")
+		Expect(err).NotTo(HaveOccurred())
+
+		status, err := c.GetVMSnapshotStatus(ctx, name)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(status.Finished()).To(BeFalse())
+
+		Expect(status.Phase).To(Equal(SnapshotPhaseInProgress))
+
+		setStatus(vmBackupGVR, name, map[string]interface{}{
+			"readyToUse": false,
+			"error":      map[string]interface{}{"message": "volume snapshot class not found"},
+		})
+		status, err = c.GetVMSnapshotStatus(ctx, name)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(status.Finished()).To(BeTrue())
+		Expect(status.Phase).To(Equal(SnapshotPhaseFailed))
+		Expect(status.ReadyToUse).To(BeFalse())
+		Expect(status.Message).To(Equal("volume snapshot class not found"))
+	})
+
+	It("restores a snapshot only once it is ready", func() {
+		name, err := c.CreateVMSnapshot(ctx, "worker-0", "s1")
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = c.RestoreVMSnapshot(ctx, "worker-0", "s1")
+		Expect(errors.Is(err, ErrSnapshotNotReady)).To(BeTrue())
+
+		setStatus(vmBackupGVR, name, map[string]interface{}{"readyToUse": true})
+		restoreName, err := c.RestoreVMSnapshot(ctx, "worker-0", "s1")
+		Expect(err).NotTo(HaveOccurred())
+
+		restores := list(restoreGVR)
+		Expect(restores).To(HaveLen(1))
+		Expect(restores[0].GetName()).To(Equal(restoreName))
+		target, _, _ := unstructured.NestedString(restores[0].Object, "spec", "target", "name")
+		Expect(target).To(Equal("worker-0"))
+		Expect(restores[0].GetAPIVersion()).To(Equal("harvesterhci.io/v1beta1"))
+		snapshot, _, _ := unstructured.NestedString(restores[0].Object, "spec", "virtualMachineBackupName")
+		Expect(snapshot).To(Equal(name))
+		newVM, _, _ := unstructured.NestedBool(restores[0].Object, "spec", "newVM")
+		Expect(newVM).To(BeFalse())
+
+		status, err := c.GetSnapshotRestoreStatus(ctx, restoreName)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(status.Complete).To(BeFalse())
+		setStatus(restoreGVR, restoreName, map[string]interface{}{"complete": true})
+		status, err = c.GetSnapshotRestoreStatus(ctx, restoreName)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(status.Complete).To(BeTrue())
+	})
+
+	It("deletes the VM's snapshots with the VM only when asked", func() {
+		opts := testCreateOptions()
+		_, _, err := c.CreateVM(ctx, opts)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = c.CreateVMSnapshot(ctx, opts.Name, "s1")
+		Expect(err).NotTo(HaveOccurred())
+		_, err = c.CreateVMSnapshot(ctx, "worker-1", "s1")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(c.DeleteVM(ctx, opts.Name, DeleteVMOptions{})).To(Succeed())
+		Expect(list(vmBackupGVR)).To(HaveLen(2))
+
+		_, _, err = c.CreateVM(ctx, opts)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(c.DeleteVM(ctx, opts.Name, DeleteVMOptions{Snapshots: true})).To(Succeed())
+		snapshots := list(vmBackupGVR)
+		Expect(snapshots).To(HaveLen(1))
+		Expect(snapshots[0].GetName()).To(Equal("worker-1-snapshot-s1"))
+	})
+})
@@ -0,0 +1,377 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package harvester
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// PVC binding diagnostic reasons.
+const (
+	// PVCReasonBound indicates the PVC is bound.
+	PVCReasonBound = "Bound"
+	// PVCReasonWaitForFirstConsumer indicates the StorageClass delays binding
+	// until the VM is scheduled. Pending is expected in this state.
+	PVCReasonWaitForFirstConsumer = "WaitForFirstConsumer"
+	// PVCReasonProvisioningFailed indicates the provisioner reported a failure.
+	PVCReasonProvisioningFailed = "ProvisioningFailed"
+	// PVCReasonStorageClassNotFound indicates the PVC references a missing StorageClass.
+	PVCReasonStorageClassNotFound = "StorageClassNotFound"
+	// PVCReasonProvisioning indicates provisioning is in progress.
+	PVCReasonProvisioning = "Provisioning"
+	// PVCReasonImageNotFound indicates the Harvester image the PVC clones from
+	// does not exist.
+	PVCReasonImageNotFound = "ImageNotFound"
+	// PVCReasonImageImportFailed indicates the Harvester image the PVC clones
+	// from failed to import.
+	PVCReasonImageImportFailed = "ImageImportFailed"
+)
+
+var imageGVR = schema.GroupVersionResource{
+	Group:    "harvesterhci.io",
+	Version:  "v1beta1",
+	Resource: "virtualmachineimages",
+}
+
+// PVCStatus describes the binding state of a PVC and, when it is not bound,
+// why.
+type PVCStatus struct {
+	Phase corev1.PersistentVolumeClaimPhase
+	// Reason is one of the PVCReason constants.
+	Reason  string
+	Message string
+}
+
+// Expected reports whether the PVC state is normal, i.e. bound, provisioning,
+// or waiting for the VM to be scheduled.
+func (s *PVCStatus) Expected() bool {
+	switch s.Reason {
+	case PVCReasonBound, PVCReasonWaitForFirstConsumer, PVCReasonProvisioning:
+		return true
+	}
+	return false
+}
+
+// Permanent reports whether the PVC cannot bind without outside action, such
+// as re-uploading a failed image, so polling it is pointless.
+func (s *PVCStatus) Permanent() bool {
+	return s.Reason == PVCReasonImageNotFound || s.Reason == PVCReasonImageImportFailed
+}
+
+// AnnotationSizeGranularity on a StorageClass declares the allocation
+// granularity (a quantity such as "2Mi") that volume sizes are rounded up to.
+const AnnotationSizeGranularity = "harvester.butler.butlerlabs.dev/size-granularity"
+
+// rootDiskSize returns the requested root disk size rounded up to the
+// allocation granularity of the options or, when named, the StorageClass.
+func (c *Client) rootDiskSize(ctx context.Context, opts VMCreateOptions, storageClassName string) (resource.Quantity, error) {
+	requested := opts.DiskSize
+	if requested.IsZero() {
+		requested = resource.MustParse(fmt.Sprintf("%dGi", opts.DiskGB))
+	}
+
+	granularity := opts.DiskSizeGranularity
+	if granularity.IsZero() && storageClassName != "" {
+		sc, err := retryResult(ctx, c, func(ctx context.Context) (*storagev1.StorageClass, error) {
+			return c.clientset.StorageV1().StorageClasses().Get(ctx, storageClassName, metav1.GetOptions{})
+		})
+		if err == nil {
+			if value := sc.Annotations[AnnotationSizeGranularity]; value != "" {
+				q, err := resource.ParseQuantity(value)
+				if err != nil {
+					return resource.Quantity{}, fmt.Errorf("StorageClass %s has invalid %s annotation %q", storageClassName, AnnotationSizeGranularity, value)
+				}
+				granularity = q
+			}
+		}
+	}
+
+	rounded := roundUpQuantity(requested, granularity)
+	if rounded.Cmp(requested) != 0 {
+		logf.FromContext(ctx).Info("Rounded disk size up to allocation granularity",
+			"requested", requested.String(), "granularity", granularity.String(), "size", rounded.String())
+	}
+	return rounded, nil
+}
+
+// roundUpQuantity rounds q up to a multiple of granularity. A zero
+// granularity leaves q unchanged.
+func roundUpQuantity(q, granularity resource.Quantity) resource.Quantity {
+	g := granularity.Value()
+	if g <= 0 {
+		return q
+	}
+	v := q.Value()
+	if rem := v % g; rem != 0 {
+		v += g - rem
+	}
+	return *resource.NewQuantity(v, resource.BinarySI)
+}
+
+// RootDiskPVCName returns the default name of the root disk PVC for a VM.
+func RootDiskPVCName(vmName string) string {
+	return vmName + "-rootdisk"
+}
+
+// ResolveRootDiskPVCName returns the root disk PVC name for the options. A
+// "{name}" placeholder in RootDiskPVCName is replaced with the VM name.
+func ResolveRootDiskPVCName(opts VMCreateOptions) string {
+	if opts.RootDiskPVCName == "" {
+		return RootDiskPVCName(opts.Name)
+	}
+	return strings.ReplaceAll(opts.RootDiskPVCName, "{name}", opts.Name)
+}
+
+// ErrPreviousInstanceTerminating is returned by CreateVM while the VM or root
+// disk PVC of a previous instance with the same name is still being deleted.
+// The caller should retry later.
+var ErrPreviousInstanceTerminating = errors.New("previous VM instance is still being deleted")
+
+// ErrVMINotOwned is returned by CreateVM when a VMI with the VM's name exists
+// without a VM but was not created by this provider for the same owner. It
+// is left in place, since the new VM would otherwise adopt it.
+var ErrVMINotOwned = errors.New("VMI not owned by this MachineRequest")
+
+// ErrStaleDisk is returned when a disk PVC created for this owner exists
+// without a VM, left behind by a previous instance.
+var ErrStaleDisk = errors.New("disk PVC of a previous VM instance exists without a VM")
+
+// checkRootDiskPVCAvailable fails when the root disk PVC name is already used
+// by a PVC that does not belong to this VM's owner. A PVC of the same owner
+// backing an existing VM is reported as AlreadyExists so the caller can adopt
+// the VM. A PVC left behind without a VM is reported as ErrStaleDisk and
+// kept, since it may hold the data of a VM deleted outside the provider;
+// DeleteCreateLeftovers removes it when the VM is deliberately recreated.
+func (c *Client) checkRootDiskPVCAvailable(ctx context.Context, name string, opts VMCreateOptions) error {
+	return c.checkDiskPVCAvailable(ctx, "root disk", name, opts)
+}
+
+// checkDiskPVCAvailable implements checkRootDiskPVCAvailable for any disk
+// PVC the VM owns; kind names the disk in errors.
+func (c *Client) checkDiskPVCAvailable(ctx context.Context, kind, name string, opts VMCreateOptions) error {
+	existing, err := c.getPVC(ctx, name)
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check %s PVC %s: %w", kind, name, err)
+	}
+	if opts.OwnerUID == "" || existing.Annotations[AnnotationOwnerUID] != opts.OwnerUID {
+		return invalidOptionsf("%s PVC %s already exists and is not owned by this machine", kind, name)
+	}
+
+	vm, err := c.GetVM(ctx, opts.Name)
+	switch {
+	case err == nil && vm.GetDeletionTimestamp() == nil && existing.DeletionTimestamp == nil:
+		return apierrors.NewAlreadyExists(corev1.Resource("persistentvolumeclaims"), name)
+	case err == nil, existing.DeletionTimestamp != nil:
+		return fmt.Errorf("%w: %s PVC %s", ErrPreviousInstanceTerminating, kind, name)
+	case !apierrors.IsNotFound(err):
+		return fmt.Errorf("failed to get VM %s: %w", opts.Name, err)
+	}
+	return fmt.Errorf("%w: %s PVC %s", ErrStaleDisk, kind, name)
+}
+
+// AttachedDisk is an existing PVC attached to the VM as an additional disk.
+type AttachedDisk struct {
+	// ClaimName is the PVC in the VM namespace.
+	ClaimName string
+	// Shareable lets several VMs attach the disk at once, for clustered
+	// filesystems. The PVC must be ReadWriteMany and the guests must
+	// coordinate access themselves.
+	Shareable bool
+}
+
+// checkStorageClass fails with ErrInvalidOptions when the named StorageClass
+// does not exist.
+func (c *Client) checkStorageClass(ctx context.Context, name string) error {
+	_, err := retryResult(ctx, c, func(ctx context.Context) (*storagev1.StorageClass, error) {
+		return c.clientset.StorageV1().StorageClasses().Get(ctx, name, metav1.GetOptions{})
+	})
+	if apierrors.IsNotFound(err) {
+		return invalidOptionsf("storage class %s not found", name)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to verify storage class %s: %w", name, err)
+	}
+	return nil
+}
+
+// checkAttachedDisks verifies that every attached disk exists and that
+// shareable disks can be mounted by several nodes.
+func (c *Client) checkAttachedDisks(ctx context.Context, disks []AttachedDisk) error {
+	for _, disk := range disks {
+		pvc, err := c.getPVC(ctx, disk.ClaimName)
+		if apierrors.IsNotFound(err) {
+			return invalidOptionsf("attached disk PVC %s does not exist in namespace %s", disk.ClaimName, c.namespace)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to check attached disk PVC %s: %w", disk.ClaimName, err)
+		}
+		if disk.Shareable && !slices.Contains(pvc.Spec.AccessModes, corev1.ReadWriteMany) {
+			return invalidOptionsf("shareable disk PVC %s must have access mode %s, has %v",
+				disk.ClaimName, corev1.ReadWriteMany, pvc.Spec.AccessModes)
+		}
+	}
+	return nil
+}
+
+// attachedDiskDevice returns the volume and disk entries for the attached
+// disk at index i, attached with the given bus.
+func attachedDiskDevice(i int, attached AttachedDisk, bus DiskBus) (volume, disk map[string]interface{}) {
+	name := fmt.Sprintf("attached-%d", i)
+	volume = map[string]interface{}{
+		"name": name,
+		"persistentVolumeClaim": map[string]interface{}{
+			"claimName": attached.ClaimName,
+		},
+	}
+	disk = map[string]interface{}{
+		"name": name,
+		"disk": map[string]interface{}{
+			"bus": string(resolveDiskBus(bus, "")),
+		},
+	}
+	if attached.Shareable {
+		disk["shareable"] = true
+	}
+	return volume, disk
+}
+
+// rootDiskClaimName returns the PVC backing the VM's root disk volume. It is
+// empty for a root disk declared in dataVolumeTemplates, which is garbage
+// collected with the VM.
+func rootDiskClaimName(vm *unstructured.Unstructured) string {
+	volumes, _, _ := unstructured.NestedSlice(vm.Object, "spec", "template", "spec", "volumes")
+	for _, v := range volumes {
+		volume, ok := v.(map[string]interface{})
+		if !ok || volume["name"] != "rootdisk" {
+			continue
+		}
+		claim, _, _ := unstructured.NestedString(volume, "persistentVolumeClaim", "claimName")
+		return claim
+	}
+	return ""
+}
+
+// GetPVCStatus returns the binding state of a PVC, diagnosing pending claims
+// from the StorageClass binding mode and the PVC's events.
+func (c *Client) GetPVCStatus(ctx context.Context, name string) (*PVCStatus, error) {
+	pvc, err := c.getPVC(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &PVCStatus{Phase: pvc.Status.Phase}
+	if pvc.Status.Phase == corev1.ClaimBound {
+		status.Reason = PVCReasonBound
+		return status, nil
+	}
+
+	// Provisioning failures are only reported through events
+	if event := c.latestWarningEvent(ctx, pvc); event != nil && event.Reason == PVCReasonProvisioningFailed {
+		status.Reason = PVCReasonProvisioningFailed
+		status.Message = event.Message
+		return status, nil
+	}
+
+	if pvc.Spec.StorageClassName != nil && *pvc.Spec.StorageClassName != "" {
+		sc, err := retryResult(ctx, c, func(ctx context.Context) (*storagev1.StorageClass, error) {
+			return c.clientset.StorageV1().StorageClasses().Get(ctx, *pvc.Spec.StorageClassName, metav1.GetOptions{})
+		})
+		switch {
+		case apierrors.IsNotFound(err):
+			// Harvester creates the per-image StorageClass, so a missing
+			// class usually means the image itself is missing or broken
+			if imageStatus := c.imageProblem(ctx, pvc.Annotations["harvesterhci.io/imageId"]); imageStatus != nil {
+				imageStatus.Phase = pvc.Status.Phase
+				return imageStatus, nil
+			}
+			status.Reason = PVCReasonStorageClassNotFound
+			status.Message = fmt.Sprintf("StorageClass %s not found", *pvc.Spec.StorageClassName)
+			return status, nil
+		case err == nil && sc.VolumeBindingMode != nil && *sc.VolumeBindingMode == storagev1.VolumeBindingWaitForFirstConsumer:
+			status.Reason = PVCReasonWaitForFirstConsumer
+			status.Message = "Volume binding waits until the VM is scheduled"
+			return status, nil
+		}
+	}
+
+	status.Reason = PVCReasonProvisioning
+	status.Message = "Waiting for the volume to be provisioned"
+	return status, nil
+}
+
+// imageProblem returns a PVCStatus when the referenced Harvester image
+// ("namespace/name") is missing or failed to import, nil otherwise.
+func (c *Client) imageProblem(ctx context.Context, imageID string) *PVCStatus {
+	if imageID == "" {
+		return nil
+	}
+	_, err := c.GetImage(ctx, imageID)
+	switch {
+	case errors.Is(err, ErrImageNotFound):
+		return &PVCStatus{Reason: PVCReasonImageNotFound, Message: err.Error()}
+	case errors.Is(err, ErrImageImportFailed):
+		return &PVCStatus{Reason: PVCReasonImageImportFailed, Message: err.Error()}
+	}
+	return nil
+}
+
+// latestWarningEvent returns the most recent Warning event for the PVC, or nil.
+func (c *Client) latestWarningEvent(ctx context.Context, pvc *corev1.PersistentVolumeClaim) *corev1.Event {
+	selector := fields.Set{
+		"involvedObject.kind": "PersistentVolumeClaim",
+		"involvedObject.name": pvc.Name,
+		"involvedObject.uid":  string(pvc.UID),
+	}.AsSelector().String()
+	events, err := retryResult(ctx, c, func(ctx context.Context) (*corev1.EventList, error) {
+		return c.clientset.CoreV1().Events(c.namespace).List(ctx, metav1.ListOptions{FieldSelector: selector})
+	})
+	if err != nil {
+		return nil
+	}
+
+	var warnings []corev1.Event
+	for _, e := range events.Items {
+		if e.Type == corev1.EventTypeWarning {
+			warnings = append(warnings, e)
+		}
+	}
+	if len(warnings) == 0 {
+		return nil
+	}
+	sort.Slice(warnings, func(i, j int) bool {
+		return warnings[i].LastTimestamp.Before(&warnings[j].LastTimestamp)
+	})
+	return &warnings[len(warnings)-1]
+}
@@ -0,0 +1,464 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package harvester
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+
+	butlerv1alpha1 "github.com/butlerdotdev/butler-api/api/v1alpha1"
+)
+
+const testNamespace = "vms"
+
+// testNetwork returns the NetworkAttachmentDefinition referenced by
+// testCreateOptions.
+func testNetwork() *unstructured.Unstructured {
+	nad := &unstructured.Unstructured{}
+	nad.SetAPIVersion("k8s.cni.cncf.io/v1")
+	nad.SetKind("NetworkAttachmentDefinition")
+	nad.SetNamespace("default")
+	nad.SetName("vlan1")
+	return nad
+}
+
+// testImage returns the imported image referenced by testCreateOptions.
+func testImage() *unstructured.Unstructured {
+	image := &unstructured.Unstructured{}
+	image.SetAPIVersion("harvesterhci.io/v1beta1")
+	image.SetKind("VirtualMachineImage")
+	image.SetNamespace("default")
+	image.SetName("image-abc12")
+	Expect(unstructured.SetNestedSlice(image.Object, []interface{}{
+		map[string]interface{}{"type": "Imported", "status": "True"},
+	}, "status", "conditions")).To(Succeed())
+	return image
+}
+
+func newTestClient(objects ...runtime.Object) *Client {
+	listKinds := map[schema.GroupVersionResource]string{
+		vmGVR:              "VirtualMachineList",
+		vmiGVR:             "VirtualMachineInstanceList",
+		nadGVR:             "NetworkAttachmentDefinitionList",
+		imageGVR:           "VirtualMachineImageList",
+		vmimGVR:            "VirtualMachineInstanceMigrationList",
+		vmBackupGVR:        "VirtualMachineBackupList",
+		restoreGVR:         "VirtualMachineRestoreList",
+		longhornReplicaGVR: "ReplicaList",
+		kubevirtGVR:        "KubeVirtList",
+		longhornNodeGVR:    "NodeList",
+	}
+	dynamic := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), listKinds)
+	// Register under the multus resource name, which the fake cannot guess
+	Expect(dynamic.Tracker().Create(nadGVR, testNetwork(), "default")).To(Succeed())
+	Expect(dynamic.Tracker().Create(imageGVR, testImage(), "default")).To(Succeed())
+	return &Client{
+		dynamic:   dynamic,
+		clientset: kubefake.NewClientset(objects...),
+		namespace: testNamespace,
+		config:    &butlerv1alpha1.HarvesterProviderConfig{Namespace: testNamespace},
+	}
+}
+
+func testCreateOptions() VMCreateOptions {
+	return VMCreateOptions{
+		Name:        "worker-0",
+		CPU:         2,
+		MemoryMB:    4096,
+		DiskGB:      20,
+		ImageName:   "default/image-abc12",
+		NetworkName: "default/vlan1",
+		OwnerUID:    "uid-1",
+		Owner:       "butler/worker-0",
+	}
+}
+
+// bindPVC simulates provisioning by binding the PVC to a volume.
+func bindPVC(ctx context.Context, c *Client, name, volume string) {
+	pvc, err := c.clientset.CoreV1().PersistentVolumeClaims(testNamespace).Get(ctx, name, metav1.GetOptions{})
+	Expect(err).NotTo(HaveOccurred())
+	pvc.Spec.VolumeName = volume
+	pvc.Annotations["pv.kubernetes.io/bind-completed"] = "yes"
+	pvc.Status.Phase = corev1.ClaimBound
+	_, err = c.clientset.CoreV1().PersistentVolumeClaims(testNamespace).Update(ctx, pvc, metav1.UpdateOptions{})
+	Expect(err).NotTo(HaveOccurred())
+}
+
+func expectFreshClone(ctx context.Context, c *Client, name string) {
+	pvc, err := c.clientset.CoreV1().PersistentVolumeClaims(testNamespace).Get(ctx, name, metav1.GetOptions{})
+	Expect(err).NotTo(HaveOccurred())
+	Expect(pvc.Annotations).To(HaveKeyWithValue("harvesterhci.io/imageId", "default/image-abc12"))
+	Expect(pvc.Annotations).To(HaveKeyWithValue(AnnotationOwnerUID, "uid-1"))
+	Expect(pvc.Annotations).NotTo(HaveKey("pv.kubernetes.io/bind-completed"))
+	Expect(pvc.Spec.VolumeName).To(BeEmpty())
+	Expect(pvc.Spec.DataSource).To(BeNil())
+	Expect(pvc.Status.Phase).To(BeEmpty())
+}
+
+var _ = Describe("Root disk recreate", func() {
+	var ctx context.Context
+
+	BeforeEach(func() {
+		ctx = context.Background()
+	})
+
+	It("clones a fresh root disk after delete and recreate", func() {
+		c := newTestClient()
+		opts := testCreateOptions()
+		pvcName := ResolveRootDiskPVCName(opts)
+
+		_, _, err := c.CreateVM(ctx, opts)
+		Expect(err).NotTo(HaveOccurred())
+		bindPVC(ctx, c, pvcName, "pv-old")
+
+		Expect(c.DeleteVM(ctx, opts.Name, DeleteVMOptions{})).To(Succeed())
+		_, err = c.clientset.CoreV1().PersistentVolumeClaims(testNamespace).Get(ctx, pvcName, metav1.GetOptions{})
+		Expect(err).To(HaveOccurred())
+
+		_, _, err = c.CreateVM(ctx, opts)
+		Expect(err).NotTo(HaveOccurred())
+		expectFreshClone(ctx, c, pvcName)
+	})
+
+	It("keeps a root disk left behind without a VM until the leftovers are deleted", func() {
+		stale := &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      RootDiskPVCName("worker-0"),
+				Namespace: testNamespace,
+				Annotations: map[string]string{
+					"harvesterhci.io/imageId":         "default/image-abc12",
+					"pv.kubernetes.io/bind-completed": "yes",
+					AnnotationOwnerUID:                "uid-1",
+				},
+			},
+			Spec: corev1.PersistentVolumeClaimSpec{VolumeName: "pv-old"},
+		}
+		c := newTestClient(stale)
+		opts := testCreateOptions()
+
+		_, _, err := c.CreateVM(ctx, opts)
+		Expect(err).To(MatchError(ErrStaleDisk))
+		pvc, err := c.clientset.CoreV1().PersistentVolumeClaims(testNamespace).Get(ctx, stale.Name, metav1.GetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pvc.Spec.VolumeName).To(Equal("pv-old"))
+
+		Expect(c.DeleteCreateLeftovers(ctx, opts.Name, stale.Name, opts.OwnerUID)).To(Succeed())
+		_, _, err = c.CreateVM(ctx, opts)
+		Expect(err).NotTo(HaveOccurred())
+		expectFreshClone(ctx, c, stale.Name)
+	})
+
+	It("refuses a root disk owned by another machine", func() {
+		other := &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        RootDiskPVCName("worker-0"),
+				Namespace:   testNamespace,
+				Annotations: map[string]string{AnnotationOwnerUID: "uid-2"},
+			},
+		}
+		c := newTestClient(other)
+
+		_, _, err := c.CreateVM(ctx, testCreateOptions())
+		Expect(err).To(MatchError(ErrInvalidOptions))
+	})
+})
+
+// sharedPVC returns an existing PVC with the given access mode.
+func sharedPVC(name string, mode corev1.PersistentVolumeAccessMode) *corev1.PersistentVolumeClaim {
+	return &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: testNamespace},
+		Spec:       corev1.PersistentVolumeClaimSpec{AccessModes: []corev1.PersistentVolumeAccessMode{mode}},
+	}
+}
+
+var _ = Describe("Attached disks", func() {
+	var ctx context.Context
+
+	BeforeEach(func() {
+		ctx = context.Background()
+	})
+
+	It("attaches a shareable ReadWriteMany disk", func() {
+		c := newTestClient(sharedPVC("gfs", corev1.ReadWriteMany))
+		opts := testCreateOptions()
+		opts.AttachedDisks = []AttachedDisk{{ClaimName: "gfs", Shareable: true}}
+
+		_, _, err := c.CreateVM(ctx, opts)
+		Expect(err).NotTo(HaveOccurred())
+		vm, err := c.GetVM(ctx, opts.Name)
+		Expect(err).NotTo(HaveOccurred())
+		disks, _, _ := unstructured.NestedSlice(vm.Object, "spec", "template", "spec", "domain", "devices", "disks")
+		Expect(disks).To(ContainElement(HaveKeyWithValue("shareable", true)))
+
+		// The shared disk outlives the VM
+		Expect(c.DeleteVM(ctx, opts.Name, DeleteVMOptions{})).To(Succeed())
+		_, err = c.clientset.CoreV1().PersistentVolumeClaims(testNamespace).Get(ctx, "gfs", metav1.GetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("rejects a shareable disk that is not ReadWriteMany", func() {
+		c := newTestClient(sharedPVC("gfs", corev1.ReadWriteOnce))
+		opts := testCreateOptions()
+		opts.AttachedDisks = []AttachedDisk{{ClaimName: "gfs", Shareable: true}}
+
+		_, _, err := c.CreateVM(ctx, opts)
+		Expect(err).To(MatchError(ErrInvalidOptions))
+		_, err = c.GetVM(ctx, opts.Name)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("attaches an existing disk that is never the boot device", func() {
+		c := newTestClient(sharedPVC("data", corev1.ReadWriteOnce))
+		opts := testCreateOptions()
+		opts.AttachedDisks = []AttachedDisk{{ClaimName: "data"}}
+
+		_, _, err := c.CreateVM(ctx, opts)
+		Expect(err).NotTo(HaveOccurred())
+		vm, err := c.GetVM(ctx, opts.Name)
+		Expect(err).NotTo(HaveOccurred())
+		disks, _, _ := unstructured.NestedSlice(vm.Object, "spec", "template", "spec", "domain", "devices", "disks")
+		Expect(disks).To(ContainElement(And(HaveKeyWithValue("name", "attached-0"), Not(HaveKey("bootOrder")))))
+	})
+
+	It("fails before creating anything when an attached disk is missing", func() {
+		c := newTestClient()
+		opts := testCreateOptions()
+		opts.AttachedDisks = []AttachedDisk{{ClaimName: "data"}}
+
+		_, _, err := c.CreateVM(ctx, opts)
+		Expect(err).To(MatchError(ContainSubstring("attached disk PVC data does not exist")))
+		pvcs, err := c.clientset.CoreV1().PersistentVolumeClaims(testNamespace).List(ctx, metav1.ListOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pvcs.Items).To(BeEmpty())
+	})
+})
+
+var _ = Describe("Root disk storage class", func() {
+	var (
+		ctx  context.Context
+		c    *Client
+		opts VMCreateOptions
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		c = newTestClient(
+			&storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: "longhorn-ssd"}},
+			&storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: "longhorn-nvme"}},
+		)
+		opts = testCreateOptions()
+	})
+
+	rootDiskStorageClass := func() string {
+		pvc, err := c.clientset.CoreV1().PersistentVolumeClaims(testNamespace).Get(ctx, ResolveRootDiskPVCName(opts), metav1.GetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pvc.Spec.StorageClassName).NotTo(BeNil())
+		return *pvc.Spec.StorageClassName
+	}
+
+	It("uses the storage class from the options", func() {
+		opts.StorageClass = "longhorn-ssd"
+		_, _, err := c.CreateVM(ctx, opts)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rootDiskStorageClass()).To(Equal("longhorn-ssd"))
+	})
+
+	It("defaults to the client root disk storage class", func() {
+		c.RootDiskStorageClass = "longhorn-nvme"
+		_, _, err := c.CreateVM(ctx, opts)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rootDiskStorageClass()).To(Equal("longhorn-nvme"))
+	})
+
+	It("clones through the image storage class despite the provider config storage class", func() {
+		// storageClassName predates root disk overrides and is the
+		// persistent cloud-init disk's storage class only
+		c.config.StorageClassName = "longhorn-nvme"
+		_, _, err := c.CreateVM(ctx, opts)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rootDiskStorageClass()).To(Equal(imageStorageClassName(opts.ImageName)))
+	})
+
+	It("prefers the options over the client default", func() {
+		c.RootDiskStorageClass = "longhorn-nvme"
+		opts.StorageClass = "longhorn-ssd"
+		_, _, err := c.CreateVM(ctx, opts)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rootDiskStorageClass()).To(Equal("longhorn-ssd"))
+	})
+
+	It("rejects a storage class that does not exist", func() {
+		opts.StorageClass = "longhorn-missing"
+		_, _, err := c.CreateVM(ctx, opts)
+		Expect(errors.Is(err, ErrInvalidOptions)).To(BeTrue(), "got %v", err)
+		pvcs, err := c.clientset.CoreV1().PersistentVolumeClaims(testNamespace).List(ctx, metav1.ListOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pvcs.Items).To(BeEmpty())
+	})
+
+	It("rejects a storage class with a container disk", func() {
+		opts.StorageClass = "longhorn-ssd"
+		opts.ContainerDiskImage = "quay.io/containerdisks/ubuntu:22.04"
+		_, _, err := c.CreateVM(ctx, opts)
+		Expect(errors.Is(err, ErrInvalidOptions)).To(BeTrue(), "got %v", err)
+	})
+})
+
+var _ = Describe("Root disk sharing and preallocation", func() {
+	var ctx context.Context
+
+	BeforeEach(func() {
+		ctx = context.Background()
+	})
+
+	It("marks a shareable root disk on the VM", func() {
+		c := newTestClient()
+		opts := testCreateOptions()
+		opts.RootDiskShareable = true
+
+		_, _, err := c.CreateVM(ctx, opts)
+		Expect(err).NotTo(HaveOccurred())
+		vm, err := c.GetVM(ctx, opts.Name)
+		Expect(err).NotTo(HaveOccurred())
+		disks, _, _ := unstructured.NestedSlice(vm.Object, "spec", "template", "spec", "domain", "devices", "disks")
+		Expect(disks).To(ContainElement(SatisfyAll(
+			HaveKeyWithValue("name", "rootdisk"),
+			HaveKeyWithValue("shareable", true),
+		)))
+	})
+
+	It("rejects a shareable container disk", func() {
+		opts := testCreateOptions()
+		opts.ContainerDiskImage = "quay.io/containerdisks/fedora:40"
+		opts.RootDiskShareable = true
+
+		_, _, err := newTestClient().CreateVM(ctx, opts)
+		Expect(err).To(MatchError(ErrInvalidOptions))
+	})
+
+	It("rejects preallocation of a root disk cloned from an image", func() {
+		opts := testCreateOptions()
+		opts.RootDiskPreallocation = true
+
+		_, _, err := newTestClient().CreateVM(ctx, opts)
+		Expect(err).To(MatchError(ContainSubstring("imported by CDI")))
+	})
+
+	It("requests preallocation of an imported root disk", func() {
+		c := newTestClient()
+		opts := testCreateOptions()
+		opts.RootDiskImportURL = "https://images.example.com/jammy.qcow2"
+		opts.RootDiskPreallocation = true
+
+		_, _, err := c.CreateVM(ctx, opts)
+		Expect(err).NotTo(HaveOccurred())
+		vm, err := c.GetVM(ctx, opts.Name)
+		Expect(err).NotTo(HaveOccurred())
+		templates, _, _ := unstructured.NestedSlice(vm.Object, "spec", "dataVolumeTemplates")
+		Expect(templates[0]).To(HaveKeyWithValue("spec", HaveKeyWithValue("preallocation", true)))
+	})
+})
+
+var _ = Describe("Root disk PVC diagnostics", func() {
+	const pvcName = "worker-0-rootdisk"
+
+	// pendingPVC returns an unbound root disk PVC cloned from imageID
+	// through storageClass.
+	pendingPVC := func(storageClass, imageID string) *corev1.PersistentVolumeClaim {
+		return &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   testNamespace,
+				Name:        pvcName,
+				UID:         "pvc-uid",
+				Annotations: map[string]string{"harvesterhci.io/imageId": imageID},
+			},
+			Spec:   corev1.PersistentVolumeClaimSpec{StorageClassName: &storageClass},
+			Status: corev1.PersistentVolumeClaimStatus{Phase: corev1.ClaimPending},
+		}
+	}
+	storageClass := func(name string, mode storagev1.VolumeBindingMode) *storagev1.StorageClass {
+		return &storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: name}, VolumeBindingMode: &mode}
+	}
+	warning := func(reason string, minute int) *corev1.Event {
+		return &corev1.Event{
+			ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: reason},
+			InvolvedObject: corev1.ObjectReference{
+				Kind: "PersistentVolumeClaim", Name: pvcName, UID: "pvc-uid",
+			},
+			Type:          corev1.EventTypeWarning,
+			Reason:        reason,
+			Message:       reason + " reported by the provisioner",
+			LastTimestamp: metav1.Date(2026, 1, 1, 0, minute, 0, 0, metav1.Now().Location()),
+		}
+	}
+	bound := pendingPVC("longhorn", "default/image-abc12")
+	bound.Status.Phase = corev1.ClaimBound
+
+	DescribeTable("reports why the root disk is not bound",
+		func(objects []runtime.Object, reason string, expected, permanent bool) {
+			c := newTestClient(objects...)
+			status, err := c.GetPVCStatus(context.Background(), pvcName)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(status.Reason).To(Equal(reason))
+			Expect(status.Expected()).To(Equal(expected))
+			Expect(status.Permanent()).To(Equal(permanent))
+		},
+		Entry("bound", []runtime.Object{bound}, PVCReasonBound, true, false),
+		Entry("immediate binding in progress", []runtime.Object{
+			pendingPVC("longhorn", "default/image-abc12"),
+			storageClass("longhorn", storagev1.VolumeBindingImmediate),
+		}, PVCReasonProvisioning, true, false),
+		Entry("binding delayed until the VM is scheduled", []runtime.Object{
+			pendingPVC("longhorn", "default/image-abc12"),
+			storageClass("longhorn", storagev1.VolumeBindingWaitForFirstConsumer),
+		}, PVCReasonWaitForFirstConsumer, true, false),
+		Entry("provisioner failure", []runtime.Object{
+			pendingPVC("longhorn", "default/image-abc12"),
+			storageClass("longhorn", storagev1.VolumeBindingWaitForFirstConsumer),
+			warning(PVCReasonProvisioningFailed, 1),
+		}, PVCReasonProvisioningFailed, false, false),
+		Entry("provisioner failure superseded by a later warning", []runtime.Object{
+			pendingPVC("longhorn", "default/image-abc12"),
+			storageClass("longhorn", storagev1.VolumeBindingImmediate),
+			warning(PVCReasonProvisioningFailed, 1),
+			warning("ExternalProvisioning", 2),
+		}, PVCReasonProvisioning, true, false),
+		Entry("missing storage class of an imported image", []runtime.Object{
+			pendingPVC("longhorn-image-abc12", "default/image-abc12"),
+		}, PVCReasonStorageClassNotFound, false, false),
+		Entry("missing storage class of a missing image", []runtime.Object{
+			pendingPVC("longhorn-image-gone", "default/image-gone"),
+		}, PVCReasonImageNotFound, false, true),
+	)
+
+	It("returns the lookup error for a missing PVC", func() {
+		_, err := newTestClient().GetPVCStatus(context.Background(), pvcName)
+		Expect(err).To(HaveOccurred())
+	})
+})
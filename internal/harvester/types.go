@@ -38,4 +38,16 @@ const (
 
 	// Harvester network annotation for VM networks.
 	AnnotationNetworks = "k8s.v1.cni.cncf.io/networks"
+
+	// AnnotationPersistentCloudInit tracks population of a persistent
+	// cloud-init disk on the VM ("pending" until the VM is started, then "done").
+	AnnotationPersistentCloudInit = "harvester.butler.butlerlabs.dev/persistent-cloud-init"
+
+	// AnnotationOwnerUID records the UID of the MachineRequest that created the VM.
+	AnnotationOwnerUID = "harvester.butler.butlerlabs.dev/owner-uid"
+	// AnnotationOwner records the namespace/name of the MachineRequest that created the VM.
+	AnnotationOwner = "harvester.butler.butlerlabs.dev/owner"
+	// AnnotationManagedLabels lists the comma-separated label keys the
+	// provider manages on the VM.
+	AnnotationManagedLabels = "harvester.butler.butlerlabs.dev/managed-labels"
 )
@@ -0,0 +1,227 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package harvester
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+
+	yamlv3 "go.yaml.in/yaml/v3"
+	"sigs.k8s.io/yaml"
+)
+
+// cloudConfigHeader is the first line of cloud-config user data.
+const cloudConfigHeader = "#cloud-config"
+
+// ErrInvalidUserData is returned when the user data fails validation. Errors
+// wrapping it also match ErrInvalidOptions.
+var ErrInvalidUserData = errors.New("invalid user data")
+
+// UserDataValidation selects how strictly user data is checked before the VM
+// is created.
+type UserDataValidation string
+
+const (
+	// UserDataValidationCloudConfig checks that #cloud-config user data is a
+	// YAML mapping and passes other formats, such as shell scripts, through.
+	// This is the default.
+	UserDataValidationCloudConfig UserDataValidation = "cloud-config"
+	// UserDataValidationStrict also requires user data without a
+	// #cloud-config header, such as a Talos machine config, to be a YAML
+	// mapping. Scripts and other formats are rejected.
+	UserDataValidationStrict UserDataValidation = "strict"
+	// UserDataValidationNone skips user data validation.
+	UserDataValidationNone UserDataValidation = "none"
+)
+
+// invalidUserDataf returns an error wrapping ErrInvalidOptions and
+// ErrInvalidUserData.
+func invalidUserDataf(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %w: %s", ErrInvalidOptions, ErrInvalidUserData, fmt.Sprintf(format, args...))
+}
+
+// validateUserData checks that user data will parse in the guest, so a typo
+// fails the MachineRequest instead of a cloud-init run nobody can see.
+func validateUserData(userData string, mode UserDataValidation) error {
+	switch mode {
+	case UserDataValidationNone:
+		return nil
+	case "", UserDataValidationCloudConfig, UserDataValidationStrict:
+	default:
+		return invalidOptionsf("unsupported user data validation %q", mode)
+	}
+	if userData == "" {
+		return nil
+	}
+	header, _, _ := strings.Cut(userData, "\n")
+	isCloudConfig := strings.TrimSpace(header) == cloudConfigHeader
+	if !isCloudConfig && mode != UserDataValidationStrict {
+		return nil
+	}
+
+	// The header is a YAML comment, so parsing the whole payload keeps the
+	// reported line numbers right
+	var doc interface{}
+	if err := yaml.Unmarshal([]byte(userData), &doc); err != nil {
+		return invalidUserDataf("not valid YAML: %v", err)
+	}
+	if _, ok := doc.(map[string]interface{}); !ok && doc != nil {
+		if isCloudConfig {
+			return invalidUserDataf("%s must be a YAML mapping", cloudConfigHeader)
+		}
+		return invalidUserDataf("must be %s or a YAML mapping when validation is %s", cloudConfigHeader, UserDataValidationStrict)
+	}
+	return nil
+}
+
+// cloudConfig is the subset of cloud-config generated when the caller
+// supplies no user data.
+type cloudConfig struct {
+	CACerts           *cloudConfigCACerts `json:"ca_certs,omitempty"`
+	SSHAuthorizedKeys []string            `json:"ssh_authorized_keys,omitempty"`
+}
+
+// cloudConfigCACerts configures the cloud-init ca_certs module, which installs
+// the certificates and refreshes the trust store on Debian, Ubuntu, RHEL,
+// SUSE and Alpine family distributions.
+type cloudConfigCACerts struct {
+	Trusted []string `json:"trusted"`
+}
+
+// renderUserData generates cloud-config for options that need it. It returns
+// an empty string when nothing needs generating. When the caller supplied
+// user data, SSH public keys are merged into it instead.
+func renderUserData(opts VMCreateOptions) (string, error) {
+	if opts.UserData != "" {
+		return mergeSSHKeys(opts.UserData, opts.SSHPublicKeys)
+	}
+	if len(opts.CACerts) == 0 && len(opts.SSHPublicKeys) == 0 {
+		return "", nil
+	}
+	config := cloudConfig{SSHAuthorizedKeys: opts.SSHPublicKeys}
+	if len(opts.CACerts) > 0 {
+		config.CACerts = &cloudConfigCACerts{Trusted: opts.CACerts}
+	}
+	out, err := yaml.Marshal(config)
+	if err != nil {
+		return "", fmt.Errorf("failed to render user data: %w", err)
+	}
+	return cloudConfigHeader + "\n" + string(out), nil
+}
+
+// mergeSSHKeys adds keys to the ssh_authorized_keys of cloud-config user
+// data, skipping keys it already lists. The keys are added to the parsed
+// node tree rather than a decoded map, so comments, key order and scalars
+// such as "on" or octal file modes come out as the user wrote them.
+func mergeSSHKeys(userData string, keys []string) (string, error) {
+	if len(keys) == 0 {
+		return userData, nil
+	}
+	header, body, _ := strings.Cut(userData, "\n")
+	if strings.TrimSpace(header) != cloudConfigHeader {
+		return "", invalidOptionsf("SSH public keys can only be merged into %s user data", cloudConfigHeader)
+	}
+
+	var doc yamlv3.Node
+	if err := yamlv3.Unmarshal([]byte(body), &doc); err != nil {
+		return "", invalidOptionsf("cannot merge SSH public keys into user data: %v", err)
+	}
+	if doc.Kind == 0 {
+		doc = yamlv3.Node{Kind: yamlv3.DocumentNode, Content: []*yamlv3.Node{{Kind: yamlv3.MappingNode, Tag: "!!map"}}}
+	}
+	root := doc.Content[0]
+	if root.Kind != yamlv3.MappingNode {
+		return "", invalidOptionsf("cannot merge SSH public keys into user data: %s is not a YAML mapping", cloudConfigHeader)
+	}
+
+	var authorized *yamlv3.Node
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value == "ssh_authorized_keys" {
+			authorized = root.Content[i+1]
+			break
+		}
+	}
+	switch {
+	case authorized == nil:
+		authorized = &yamlv3.Node{Kind: yamlv3.SequenceNode, Tag: "!!seq"}
+		root.Content = append(root.Content, &yamlv3.Node{Kind: yamlv3.ScalarNode, Tag: "!!str", Value: "ssh_authorized_keys"}, authorized)
+	case authorized.Kind == yamlv3.ScalarNode && authorized.Tag == "!!null":
+		*authorized = yamlv3.Node{Kind: yamlv3.SequenceNode, Tag: "!!seq", LineComment: authorized.LineComment}
+	case authorized.Kind != yamlv3.SequenceNode:
+		return "", invalidOptionsf("cannot merge SSH public keys into user data: ssh_authorized_keys is not a list")
+	}
+	listed := make(map[string]bool, len(authorized.Content))
+	for _, item := range authorized.Content {
+		listed[item.Value] = true
+	}
+	for _, key := range keys {
+		if !listed[key] {
+			authorized.Content = append(authorized.Content, &yamlv3.Node{Kind: yamlv3.ScalarNode, Tag: "!!str", Value: key})
+			listed[key] = true
+		}
+	}
+
+	var out bytes.Buffer
+	enc := yamlv3.NewEncoder(&out)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return "", fmt.Errorf("failed to render user data: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return "", fmt.Errorf("failed to render user data: %w", err)
+	}
+	return cloudConfigHeader + "\n" + out.String(), nil
+}
+
+// validateSSHPublicKey checks that key is a single authorized_keys line
+// holding a key type and base64-encoded key data.
+func validateSSHPublicKey(key string) error {
+	if strings.ContainsAny(key, "\r\n") {
+		return fmt.Errorf("must be a single line")
+	}
+	fields := strings.Fields(key)
+	if len(fields) < 2 {
+		return fmt.Errorf("want \"<type> <base64 key> [comment]\"")
+	}
+	if !strings.HasPrefix(fields[0], "ssh-") && !strings.HasPrefix(fields[0], "ecdsa-") && !strings.HasPrefix(fields[0], "sk-") {
+		return fmt.Errorf("unknown key type %q", fields[0])
+	}
+	if _, err := base64.StdEncoding.DecodeString(fields[1]); err != nil {
+		return fmt.Errorf("key data is not base64: %v", err)
+	}
+	return nil
+}
+
+// validateCACert checks that cert holds exactly one PEM-encoded certificate.
+func validateCACert(cert string) error {
+	block, rest := pem.Decode([]byte(cert))
+	if block == nil || block.Type != "CERTIFICATE" {
+		return fmt.Errorf("not a PEM certificate")
+	}
+	if strings.TrimSpace(string(rest)) != "" {
+		return fmt.Errorf("must contain a single certificate")
+	}
+	if _, err := x509.ParseCertificate(block.Bytes); err != nil {
+		return err
+	}
+	return nil
+}
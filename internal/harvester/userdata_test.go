@@ -0,0 +1,235 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package harvester
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	testSSHKey      = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIGJ1dGxlcg== ops@example"
+	otherTestSSHKey = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIHByb3ZpZGVy ci@example"
+)
+
+// parseCloudConfig checks the cloud-config header and decodes the document.
+func parseCloudConfig(userData string) map[string]interface{} {
+	Expect(userData).To(HavePrefix("#cloud-config\n"))
+	var doc map[string]interface{}
+	Expect(yaml.Unmarshal([]byte(userData), &doc)).To(Succeed())
+	return doc
+}
+
+var _ = Describe("SSH public keys", func() {
+	It("generates cloud-config holding only the keys", func() {
+		opts := testCreateOptions()
+		opts.SSHPublicKeys = []string{testSSHKey}
+
+		userData, err := renderUserData(opts)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(parseCloudConfig(userData)).To(Equal(map[string]interface{}{
+			"ssh_authorized_keys": []interface{}{testSSHKey},
+		}))
+	})
+
+	It("merges the keys into cloud-config user data", func() {
+		opts := testCreateOptions()
+		opts.UserData = "#cloud-config\nhostname: worker-0\nssh_authorized_keys:\n  - " + otherTestSSHKey + "\n"
+		opts.SSHPublicKeys = []string{testSSHKey, otherTestSSHKey}
+
+		userData, err := renderUserData(opts)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(parseCloudConfig(userData)).To(Equal(map[string]interface{}{
+			"hostname":            "worker-0",
+			"ssh_authorized_keys": []interface{}{otherTestSSHKey, testSSHKey},
+		}))
+	})
+
+	It("keeps comments, key order and scalars of the user data", func() {
+		opts := testCreateOptions()
+		opts.UserData = `#cloud-config
+# managed by the platform team
+hostname: worker-0 # short name
+package_upgrade: on
+write_files:
+  - path: /etc/motd
+    permissions: 0644
+    content: hello
+ssh_authorized_keys:
+  - ` + otherTestSSHKey + `
+bootcmd:
+  - [sh, -c, "echo 12345678901234567890"]
+`
+		opts.SSHPublicKeys = []string{testSSHKey}
+
+		userData, err := renderUserData(opts)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(userData).To(Equal(`#cloud-config
+# managed by the platform team
+hostname: worker-0 # short name
+package_upgrade: on
+write_files:
+  - path: /etc/motd
+    permissions: 0644
+    content: hello
+ssh_authorized_keys:
+  - ` + otherTestSSHKey + `
+  - ` + testSSHKey + `
+bootcmd:
+  - [sh, -c, "echo 12345678901234567890"]
+`))
+	})
+
+	DescribeTable("adds the keys to user data without a list",
+		func(userData string) {
+			opts := testCreateOptions()
+			opts.UserData = userData
+			opts.SSHPublicKeys = []string{testSSHKey}
+
+			merged, err := renderUserData(opts)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(parseCloudConfig(merged)).To(HaveKeyWithValue("ssh_authorized_keys", []interface{}{testSSHKey}))
+		},
+		Entry("empty", "#cloud-config\n"),
+		Entry("without ssh_authorized_keys", "#cloud-config\nhostname: worker-0\n"),
+		Entry("with an empty ssh_authorized_keys", "#cloud-config\nssh_authorized_keys:\nhostname: worker-0\n"),
+	)
+
+	It("leaves user data untouched without keys", func() {
+		opts := testCreateOptions()
+		opts.UserData = "#!/bin/sh\necho hello\n"
+		Expect(renderUserData(opts)).To(Equal(opts.UserData))
+	})
+
+	It("refuses to merge into user data that is not cloud-config", func() {
+		opts := testCreateOptions()
+		opts.UserData = "#!/bin/sh\necho hello\n"
+		opts.SSHPublicKeys = []string{testSSHKey}
+		_, err := renderUserData(opts)
+		Expect(errors.Is(err, ErrInvalidOptions)).To(BeTrue(), "got %v", err)
+
+		opts.UserData = "#cloud-config\nssh_authorized_keys: " + otherTestSSHKey + "\n"
+		_, err = renderUserData(opts)
+		Expect(err).To(MatchError(ContainSubstring("not a list")))
+
+		opts.UserData = "#cloud-config\n- " + otherTestSSHKey + "\n"
+		_, err = renderUserData(opts)
+		Expect(err).To(MatchError(ContainSubstring("not a YAML mapping")))
+	})
+
+	It("base64-encodes the generated user data on the VM", func() {
+		c := newTestClient()
+		opts := testCreateOptions()
+		opts.SSHPublicKeys = []string{testSSHKey}
+		_, _, err := c.CreateVM(context.Background(), opts)
+		Expect(err).NotTo(HaveOccurred())
+
+		vm, err := c.GetVM(context.Background(), opts.Name)
+		Expect(err).NotTo(HaveOccurred())
+		volumes, _, _ := unstructured.NestedSlice(vm.Object, "spec", "template", "spec", "volumes")
+		encoded, _, _ := unstructured.NestedString(volumes[len(volumes)-1].(map[string]interface{}), "cloudInitNoCloud", "userDataBase64")
+		userData, err := base64.StdEncoding.DecodeString(encoded)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(parseCloudConfig(string(userData))).To(HaveKeyWithValue("ssh_authorized_keys", []interface{}{testSSHKey}))
+	})
+
+	It("rejects malformed keys", func() {
+		opts := testCreateOptions()
+		for _, key := range []string{"AAAAC3NzaC1lZDI1NTE5", "rsa AAAA", "ssh-rsa not-base64!", testSSHKey + "\n" + otherTestSSHKey} {
+			opts.SSHPublicKeys = []string{key}
+			Expect(errors.Is(validateCreateOptions(opts), ErrInvalidOptions)).To(BeTrue(), "key %q", key)
+		}
+	})
+})
+
+var _ = Describe("User data validation", func() {
+	DescribeTable("checks user data before creating the VM",
+		func(userData string, mode UserDataValidation, valid bool) {
+			opts := testCreateOptions()
+			opts.UserData = userData
+			opts.UserDataValidation = mode
+			err := validateCreateOptions(opts)
+			if valid {
+				Expect(err).NotTo(HaveOccurred())
+				return
+			}
+			Expect(err).To(MatchError(ErrInvalidUserData))
+			Expect(err).To(MatchError(ErrInvalidOptions))
+		},
+		Entry("cloud-config mapping", "#cloud-config\nhostname: worker-0\n", UserDataValidation(""), true),
+		Entry("empty cloud-config", "#cloud-config\n", UserDataValidation(""), true),
+		Entry("malformed cloud-config", "#cloud-config\nhostname: [worker-0\n", UserDataValidation(""), false),
+		Entry("cloud-config list", "#cloud-config\n- hostname\n", UserDataValidationCloudConfig, false),
+		Entry("shell script", "#!/bin/bash\necho hi: [\n", UserDataValidation(""), true),
+		Entry("shell script in strict mode", "#!/bin/bash\necho hi\n", UserDataValidationStrict, false),
+		Entry("machine config in strict mode", "version: v1alpha1\nmachine:\n  type: worker\n", UserDataValidationStrict, true),
+		Entry("malformed cloud-config without validation", "#cloud-config\nhostname: [\n", UserDataValidationNone, true),
+	)
+
+	It("rejects an unknown validation mode", func() {
+		opts := testCreateOptions()
+		opts.UserDataValidation = "lenient"
+		err := validateCreateOptions(opts)
+		Expect(err).To(MatchError(ErrInvalidOptions))
+		Expect(err).NotTo(MatchError(ErrInvalidUserData))
+	})
+
+	It("reports the line of a YAML error", func() {
+		c := newTestClient()
+		opts := testCreateOptions()
+		opts.UserData = "#cloud-config\nhostname: worker-0\nruncmd: [\n"
+		_, _, err := c.CreateVM(context.Background(), opts)
+		Expect(err).To(MatchError(ErrInvalidUserData))
+		Expect(err).To(MatchError(ContainSubstring("line 3")))
+	})
+})
+
+var _ = Describe("Cloud-init size limit", func() {
+	It("rejects user data over the NoCloud limit with both sizes", func() {
+		c := newTestClient()
+		opts := testCreateOptions()
+		opts.UserData = "#cloud-config\nwrite_files:\n  - content: " + strings.Repeat("x", 2<<10) + "\n"
+		_, _, err := c.CreateVM(context.Background(), opts)
+		Expect(err).To(MatchError(ErrInvalidOptions))
+		Expect(err).To(MatchError(ContainSubstring("user data is 2788 bytes base64-encoded, over the 2048 byte NoCloud limit")))
+	})
+
+	It("rejects network data over the limit", func() {
+		c := newTestClient()
+		c.MaxCloudInitSize = 64
+		opts := testCreateOptions()
+		opts.NetworkData = "version: 2\nethernets:\n  eth0:\n    dhcp4: true\n    dhcp6: false\n"
+		_, _, err := c.CreateVM(context.Background(), opts)
+		Expect(err).To(MatchError(ContainSubstring("network data is 84 bytes base64-encoded, over the 64 byte")))
+	})
+
+	It("accepts user data under a raised limit", func() {
+		c := newTestClient()
+		c.MaxCloudInitSize = 8 << 10
+		opts := testCreateOptions()
+		opts.UserData = "#cloud-config\nwrite_files:\n  - content: " + strings.Repeat("x", 2<<10) + "\n"
+		_, _, err := c.CreateVM(context.Background(), opts)
+		Expect(err).NotTo(HaveOccurred())
+	})
+})
@@ -0,0 +1,377 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package harvester
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// ErrInvalidOptions is returned when VMCreateOptions fail validation.
+var ErrInvalidOptions = errors.New("invalid VM options")
+
+// invalidOptionsf returns an error wrapping ErrInvalidOptions.
+func invalidOptionsf(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrInvalidOptions, fmt.Sprintf(format, args...))
+}
+
+// maxDiskSerialLength is the longest serial KubeVirt accepts on a disk.
+const maxDiskSerialLength = 36
+
+// diskSerialPattern matches the characters KubeVirt accepts in a disk serial.
+var diskSerialPattern = regexp.MustCompile(`^[A-Za-z0-9_.+-]+$`)
+
+// validateDiskSerial checks a disk serial against the KubeVirt webhook rules.
+func validateDiskSerial(serial string) error {
+	if len(serial) > maxDiskSerialLength {
+		return fmt.Errorf("must be at most %d characters", maxDiskSerialLength)
+	}
+	if !diskSerialPattern.MatchString(serial) {
+		return errors.New("must contain only letters, digits, '_', '.', '+' and '-'")
+	}
+	return nil
+}
+
+// validateCPUTopology checks that the sockets and threads add up to the
+// requested vCPU count.
+func validateCPUTopology(opts VMCreateOptions) error {
+	if opts.Sockets < 0 || opts.Threads < 0 {
+		return invalidOptionsf("CPU sockets and threads must not be negative")
+	}
+	if len(opts.NUMACells) > 0 && opts.Sockets != 0 && int(opts.Sockets) != len(opts.NUMACells) {
+		return invalidOptionsf("%d CPU sockets requested but the guest has %d NUMA cells, one per socket", opts.Sockets, len(opts.NUMACells))
+	}
+	sockets, cores, threads := cpuTopology(opts)
+	if cores == 0 || int64(sockets)*int64(cores)*int64(threads) != int64(opts.CPU) {
+		return invalidOptionsf("%d vCPUs cannot be split into %d sockets of %d threads per core (cores * sockets * threads must equal the vCPU count)",
+			opts.CPU, sockets, threads)
+	}
+	return nil
+}
+
+// uuidPattern matches a UUID in its canonical textual form.
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// maxSMBIOSStringLength bounds SMBIOS string fields.
+const maxSMBIOSStringLength = 64
+
+// validateSMBIOSString checks that an SMBIOS field is short printable ASCII.
+func validateSMBIOSString(value string) error {
+	if len(value) > maxSMBIOSStringLength {
+		return fmt.Errorf("must be at most %d characters", maxSMBIOSStringLength)
+	}
+	for _, r := range value {
+		if r < ' ' || r > '~' {
+			return errors.New("must contain only printable ASCII characters")
+		}
+	}
+	return nil
+}
+
+// validateMemoryBounds checks the explicit memory request and limit against
+// the guest memory and the options that also derive them.
+func validateMemoryBounds(opts VMCreateOptions) error {
+	if opts.MemoryRequestMB < 0 || opts.MemoryLimitMB < 0 {
+		return invalidOptionsf("memory request and limit must not be negative")
+	}
+	if opts.MemoryRequestMB > 0 {
+		if opts.MemoryRequestMB > opts.MemoryMB {
+			return invalidOptionsf("memory request %dMi exceeds the guest memory %dMi", opts.MemoryRequestMB, opts.MemoryMB)
+		}
+		if opts.MemoryRequestPercent != 0 {
+			return invalidOptionsf("memory request cannot be combined with memory request percent")
+		}
+		if opts.MemoryRequestMB < opts.MemoryMB && opts.HugepagesPageSize != "" {
+			return invalidOptionsf("a memory request below the guest memory cannot be combined with hugepages, which are allocated up front")
+		}
+		if opts.DedicatedCPUPlacement {
+			return invalidOptionsf("memory request cannot be combined with dedicatedCpuPlacement, which requests the full limit")
+		}
+	}
+	if opts.MemoryLimitMB > 0 {
+		if opts.MemoryLimitMB < opts.MemoryMB {
+			return invalidOptionsf("memory limit %dMi is below the guest memory %dMi", opts.MemoryLimitMB, opts.MemoryMB)
+		}
+		if opts.MemoryOverheadMB != nil {
+			return invalidOptionsf("memory limit cannot be combined with memory overhead")
+		}
+	}
+	return nil
+}
+
+// validateCreateOptions checks VMCreateOptions for unsupported combinations
+// before any Harvester resources are created.
+func validateCreateOptions(opts VMCreateOptions) error {
+	if opts.IsolateEmulatorThread && !opts.DedicatedCPUPlacement {
+		return invalidOptionsf("isolateEmulatorThread requires dedicatedCpuPlacement")
+	}
+	if err := validateUserData(opts.UserData, opts.UserDataValidation); err != nil {
+		return err
+	}
+	switch opts.HugepagesPageSize {
+	case "", "2Mi", "1Gi":
+	default:
+		return invalidOptionsf("unsupported hugepages page size %q (must be 2Mi or 1Gi)", opts.HugepagesPageSize)
+	}
+	if opts.EnableOvercommit {
+		if opts.HugepagesPageSize != "" {
+			return invalidOptionsf("memory overcommit cannot be combined with hugepages, which are allocated up front")
+		}
+		if opts.DedicatedCPUPlacement {
+			return invalidOptionsf("memory overcommit cannot be combined with dedicatedCpuPlacement, which requires Guaranteed QoS")
+		}
+	}
+	if err := validateCPUTopology(opts); err != nil {
+		return err
+	}
+	if err := validateGPUs(opts.GPUs); err != nil {
+		return err
+	}
+	if err := validateNUMACells(opts); err != nil {
+		return err
+	}
+	if opts.MemoryRequestPercent != 0 && !opts.EnableOvercommit {
+		return invalidOptionsf("memory request percent requires memory overcommit")
+	}
+	if opts.MemoryRequestPercent < 0 || opts.MemoryRequestPercent > 100 {
+		return invalidOptionsf("memory request percent must be between 1 and 100")
+	}
+	if opts.MemoryOverheadMB != nil && *opts.MemoryOverheadMB < 0 {
+		return invalidOptionsf("memory overhead must not be negative")
+	}
+	if err := validateMemoryBounds(opts); err != nil {
+		return err
+	}
+	if opts.CPURequestMillicores < 0 {
+		return invalidOptionsf("CPU request must not be negative")
+	}
+	if opts.CPURequestMillicores > 0 {
+		if int64(opts.CPURequestMillicores) > int64(opts.CPU)*1000 {
+			return invalidOptionsf("CPU request %dm exceeds the %d CPU limit", opts.CPURequestMillicores, opts.CPU)
+		}
+		if opts.DedicatedCPUPlacement {
+			return invalidOptionsf("CPU request cannot be combined with dedicatedCpuPlacement, which requests whole cores")
+		}
+	}
+	if opts.PersistentCloudInit && opts.UserData == "" && len(opts.CACerts) == 0 && len(opts.SSHPublicKeys) == 0 {
+		return invalidOptionsf("persistent cloud-init requires user data")
+	}
+	for i, key := range opts.SSHPublicKeys {
+		if err := validateSSHPublicKey(key); err != nil {
+			return invalidOptionsf("invalid SSH public key %d: %v", i+1, err)
+		}
+	}
+	for i, cert := range opts.CACerts {
+		if err := validateCACert(cert); err != nil {
+			return invalidOptionsf("invalid CA certificate %d: %v", i+1, err)
+		}
+	}
+	if !opts.CloudInitDiskSize.IsZero() {
+		if !opts.PersistentCloudInit {
+			return invalidOptionsf("cloud-init disk size requires persistent cloud-init")
+		}
+		if size := opts.CloudInitDiskSize.Value(); size < minCloudInitDiskSize || size > maxCloudInitDiskSize {
+			return invalidOptionsf("cloud-init disk size %s must be between %dMi and %dMi",
+				opts.CloudInitDiskSize.String(), minCloudInitDiskSize>>20, maxCloudInitDiskSize>>20)
+		}
+	}
+	switch opts.NetworkDataFormat {
+	case "", NetworkDataV1, NetworkDataV2:
+	default:
+		return invalidOptionsf("unsupported network data format %q (must be v1 or v2)", opts.NetworkDataFormat)
+	}
+	for _, server := range opts.DNSServers {
+		if net.ParseIP(server) == nil {
+			return invalidOptionsf("invalid DNS server address %q", server)
+		}
+	}
+	if opts.ClusterDNSDomain != "" {
+		if errs := validation.IsDNS1123Subdomain(opts.ClusterDNSDomain); len(errs) > 0 {
+			return invalidOptionsf("invalid cluster DNS domain %q: %s", opts.ClusterDNSDomain, errs[0])
+		}
+		// Cluster services only resolve through the pod network
+		if !onPodNetwork(opts) {
+			return invalidOptionsf("cluster DNS domain requires the primary interface on the pod network (masquerade binding)")
+		}
+	}
+	if err := validateStaticIP(opts); err != nil {
+		return err
+	}
+	for _, route := range opts.Routes {
+		if _, _, err := net.ParseCIDR(route.Destination); err != nil {
+			return invalidOptionsf("invalid route destination %q (must be CIDR)", route.Destination)
+		}
+		if net.ParseIP(route.Gateway) == nil {
+			return invalidOptionsf("invalid route gateway %q", route.Gateway)
+		}
+		if route.Metric < 0 {
+			return invalidOptionsf("route metric for %s must not be negative", route.Destination)
+		}
+	}
+	if opts.NetworkName != "" {
+		if err := validateNetworkRef(opts.NetworkName); err != nil {
+			return err
+		}
+	}
+	switch opts.NetworkBinding {
+	case "", NetworkBindingBridge, NetworkBindingSRIOV, NetworkBindingMasquerade:
+	default:
+		return invalidOptionsf("unsupported network binding %q (must be bridge, masquerade or sriov)", opts.NetworkBinding)
+	}
+	if opts.NetworkBinding == NetworkBindingMasquerade && len(opts.Networks) == 0 {
+		if opts.NetworkName != "" {
+			return invalidOptionsf("masquerade binding uses the pod network and cannot be combined with network %s", opts.NetworkName)
+		}
+		if opts.StaticIP != nil {
+			return invalidOptionsf("masquerade binding gets its address from KubeVirt and cannot be combined with a static IP")
+		}
+	}
+	if err := validateInterfaceNaming(opts); err != nil {
+		return err
+	}
+	if err := validateNetworkInterfaces(opts); err != nil {
+		return err
+	}
+	if opts.DiskSize.Sign() < 0 {
+		return invalidOptionsf("disk size must not be negative")
+	}
+	if opts.DiskSizeGranularity.Sign() < 0 {
+		return invalidOptionsf("disk size granularity must not be negative")
+	}
+	if opts.RuntimeClassName != "" {
+		if errs := validation.IsDNS1123Subdomain(opts.RuntimeClassName); len(errs) > 0 {
+			return invalidOptionsf("invalid runtime class name %q: %s", opts.RuntimeClassName, errs[0])
+		}
+	}
+	if opts.RootDiskPVCName != "" {
+		if errs := validation.IsDNS1123Subdomain(ResolveRootDiskPVCName(opts)); len(errs) > 0 {
+			return invalidOptionsf("invalid root disk PVC name %q: %s", ResolveRootDiskPVCName(opts), errs[0])
+		}
+	}
+	if opts.FirmwareSecureBoot && !opts.FirmwareEFI {
+		return invalidOptionsf("secure boot requires EFI firmware")
+	}
+	if opts.FirmwareUUID != "" && !uuidPattern.MatchString(opts.FirmwareUUID) {
+		return invalidOptionsf("invalid firmware UUID %q (want xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx)", opts.FirmwareUUID)
+	}
+	for _, field := range []struct{ name, value string }{
+		{"firmware serial", opts.FirmwareSerial},
+		{"SMBIOS manufacturer", opts.SMBIOSManufacturer},
+		{"SMBIOS product", opts.SMBIOSProduct},
+	} {
+		if err := validateSMBIOSString(field.value); err != nil {
+			return invalidOptionsf("invalid %s %q: %v", field.name, field.value, err)
+		}
+	}
+	if opts.RootDiskSerial != "" {
+		if err := validateDiskSerial(opts.RootDiskSerial); err != nil {
+			return invalidOptionsf("invalid root disk serial %q: %v", opts.RootDiskSerial, err)
+		}
+	}
+	if !validDiskBus(opts.DiskBus) {
+		return invalidOptionsf("unsupported disk bus %q (must be virtio, scsi or sata)", opts.DiskBus)
+	}
+	seen := map[string]bool{}
+	for _, disk := range opts.AttachedDisks {
+		if errs := validation.IsDNS1123Subdomain(disk.ClaimName); len(errs) > 0 {
+			return invalidOptionsf("invalid attached disk PVC name %q: %s", disk.ClaimName, errs[0])
+		}
+		if opts.ContainerDiskImage == "" && disk.ClaimName == ResolveRootDiskPVCName(opts) {
+			return invalidOptionsf("attached disk PVC %s is the root disk PVC", disk.ClaimName)
+		}
+		if seen[disk.ClaimName] {
+			return invalidOptionsf("attached disk PVC %s listed more than once", disk.ClaimName)
+		}
+		seen[disk.ClaimName] = true
+	}
+	if err := validateDataDisks(opts.DataDisks); err != nil {
+		return err
+	}
+	// Only CDI honors preallocation; image clones are populated by Longhorn
+	if opts.RootDiskPreallocation && opts.RootDiskImportURL == "" {
+		return invalidOptionsf("root disk preallocation requires a root disk imported by CDI from a URL")
+	}
+	if opts.RootDiskShareable && opts.ContainerDiskImage != "" {
+		return invalidOptionsf("a shareable root disk requires a ReadWriteMany root disk PVC, not a container disk")
+	}
+	if opts.ImageSelector != "" {
+		if _, err := labels.Parse(opts.ImageSelector); err != nil {
+			return invalidOptionsf("invalid image selector %q: %v", opts.ImageSelector, err)
+		}
+		if opts.ImageName != "" || opts.ContainerDiskImage != "" || opts.RestoreFromBackup != "" {
+			return invalidOptionsf("image selector cannot be combined with an image, container disk or restore")
+		}
+	}
+	if (opts.ImageNamespace != "" || opts.ImageSelectorOrder != "") && opts.ImageSelector == "" {
+		return invalidOptionsf("image namespace and order require an image selector")
+	}
+	if opts.ImageSelectorOrder != "" && opts.ImageSelectorOrder != ImageOrderCreationTimestamp {
+		if errs := validation.IsQualifiedName(opts.ImageSelectorOrder); len(errs) > 0 {
+			return invalidOptionsf("invalid image selector order %q (must be %s or a label key): %s",
+				opts.ImageSelectorOrder, ImageOrderCreationTimestamp, errs[0])
+		}
+	}
+	if opts.RestoreFromBackup != "" && (opts.ImageName != "" || opts.ContainerDiskImage != "") {
+		return invalidOptionsf("restore from backup cannot be combined with an image or container disk")
+	}
+	if opts.RestoreFromBackup != "" && len(opts.DataDisks) > 0 {
+		return invalidOptionsf("restore from backup cannot be combined with data disks, which the backup already holds")
+	}
+	if opts.EphemeralScratchGB < 0 {
+		return invalidOptionsf("ephemeral scratch size must not be negative")
+	}
+	if opts.RestoreFromBackup != "" && opts.EphemeralScratchGB > 0 {
+		return invalidOptionsf("restore from backup cannot be combined with ephemeral scratch space")
+	}
+	if opts.RootDiskImportURL != "" {
+		if opts.ContainerDiskImage != "" || opts.RestoreFromBackup != "" || opts.ImageSelector != "" {
+			return invalidOptionsf("root disk import URL cannot be combined with a container disk, restore or image selector")
+		}
+		if _, err := dataVolumeSource(opts.RootDiskImportURL); err != nil {
+			return err
+		}
+	}
+	if opts.ImagePullSecret != "" && opts.ContainerDiskImage == "" {
+		return invalidOptionsf("image pull secret requires a container disk image")
+	}
+	if opts.SSHKeySecret != "" && len(opts.SSHKeyUsers) == 0 {
+		return invalidOptionsf("SSH key secret requires at least one guest user")
+	}
+	if opts.SSHKeySecret == "" && len(opts.SSHKeyUsers) > 0 {
+		return invalidOptionsf("SSH key users require an SSH key secret")
+	}
+	if opts.StorageClass != "" {
+		if opts.ContainerDiskImage != "" || opts.RestoreFromBackup != "" {
+			return invalidOptionsf("root disk storage class cannot be combined with a container disk or restore, which have no root disk PVC")
+		}
+		if errs := validation.IsDNS1123Subdomain(opts.StorageClass); len(errs) > 0 {
+			return invalidOptionsf("invalid root disk storage class %q: %s", opts.StorageClass, errs[0])
+		}
+	}
+	switch opts.VolumeMode {
+	case "", corev1.PersistentVolumeBlock, corev1.PersistentVolumeFilesystem:
+	default:
+		return invalidOptionsf("unsupported volume mode %q (must be Block or Filesystem)", opts.VolumeMode)
+	}
+	return nil
+}
@@ -0,0 +1,363 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package harvester
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// addVMI registers a running VirtualMachineInstance in the test namespace,
+// labeled as a VM created from testCreateOptions would label it.
+func addVMI(c *Client, name string) *unstructured.Unstructured {
+	vmi := &unstructured.Unstructured{}
+	vmi.SetAPIVersion("kubevirt.io/v1")
+	vmi.SetKind("VirtualMachineInstance")
+	vmi.SetNamespace(testNamespace)
+	vmi.SetName(name)
+	vmi.SetLabels(map[string]string{LabelManagedBy: managedByValue})
+	vmi.SetAnnotations(map[string]string{AnnotationOwnerUID: testCreateOptions().OwnerUID})
+	Expect(unstructured.SetNestedField(vmi.Object, "Running", "status", "phase")).To(Succeed())
+	Expect(c.dynamic.(*dynamicfake.FakeDynamicClient).Tracker().Create(vmiGVR, vmi, testNamespace)).To(Succeed())
+	return vmi
+}
+
+// updateVMI stores changes made to a VMI returned by addVMI.
+func updateVMI(c *Client, vmi *unstructured.Unstructured) {
+	Expect(c.dynamic.(*dynamicfake.FakeDynamicClient).Tracker().Update(vmiGVR, vmi, testNamespace)).To(Succeed())
+}
+
+var _ = Describe("VM status", func() {
+	var (
+		ctx  context.Context
+		c    *Client
+		opts VMCreateOptions
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		c = newTestClient()
+		opts = testCreateOptions()
+	})
+
+	It("reports a VM whose VMI does not exist yet", func() {
+		providerID, _, err := c.CreateVM(ctx, opts)
+		Expect(err).NotTo(HaveOccurred())
+
+		status, err := c.GetVMStatus(ctx, opts.Name)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(status.Exists).To(BeTrue())
+		Expect(status.UID).To(Equal(providerID))
+		Expect(status.OrphanedVMI).To(BeFalse())
+		Expect(status.IPAddress).To(BeEmpty())
+	})
+
+	It("reports a VMI whose VM is gone as orphaned", func() {
+		addVMI(c, opts.Name)
+
+		status, err := c.GetVMStatus(ctx, opts.Name)
+		Expect(apierrors.IsNotFound(err)).To(BeTrue(), "got %v", err)
+		Expect(status.Exists).To(BeFalse())
+		Expect(status.OrphanedVMI).To(BeTrue())
+		Expect(status.Phase).To(Equal(VMPhaseOrphanedVMI))
+	})
+
+	It("deletes an orphaned VMI", func() {
+		addVMI(c, opts.Name)
+
+		Expect(c.DeleteVM(ctx, opts.Name, DeleteVMOptions{})).To(Succeed())
+		_, err := c.GetVMI(ctx, opts.Name)
+		Expect(apierrors.IsNotFound(err)).To(BeTrue(), "got %v", err)
+
+		// Nothing is left to delete
+		Expect(apierrors.IsNotFound(c.DeleteVM(ctx, opts.Name, DeleteVMOptions{}))).To(BeTrue())
+	})
+
+	It("clears an orphaned VMI before reusing its name", func() {
+		addVMI(c, opts.Name)
+
+		_, _, err := c.CreateVM(ctx, opts)
+		Expect(err).To(MatchError(ErrPreviousInstanceTerminating))
+		_, err = c.GetVMI(ctx, opts.Name)
+		Expect(apierrors.IsNotFound(err)).To(BeTrue(), "got %v", err)
+
+		_, _, err = c.CreateVM(ctx, opts)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = c.dynamic.Resource(vmGVR).Namespace(testNamespace).Get(ctx, opts.Name, metav1.GetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("leaves an orphaned VMI this provider did not create", func() {
+		vmi := addVMI(c, opts.Name)
+		vmi.SetLabels(nil)
+		updateVMI(c, vmi)
+
+		Expect(apierrors.IsNotFound(c.DeleteVM(ctx, opts.Name, DeleteVMOptions{OwnerUID: opts.OwnerUID}))).To(BeTrue())
+		_, _, err := c.CreateVM(ctx, opts)
+		Expect(err).To(MatchError(ErrVMINotOwned))
+		_, err = c.GetVMI(ctx, opts.Name)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("leaves an orphaned VMI of another MachineRequest", func() {
+		vmi := addVMI(c, opts.Name)
+		vmi.SetAnnotations(map[string]string{AnnotationOwnerUID: "uid-2"})
+		updateVMI(c, vmi)
+
+		Expect(apierrors.IsNotFound(c.DeleteVM(ctx, opts.Name, DeleteVMOptions{OwnerUID: opts.OwnerUID}))).To(BeTrue())
+		_, _, err := c.CreateVM(ctx, opts)
+		Expect(err).To(MatchError(ErrVMINotOwned))
+		_, err = c.GetVMI(ctx, opts.Name)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("carries the owner UID to the VMI", func() {
+		_, _, err := c.CreateVM(ctx, opts)
+		Expect(err).NotTo(HaveOccurred())
+		vm, err := c.GetVM(ctx, opts.Name)
+		Expect(err).NotTo(HaveOccurred())
+		annotations, _, _ := unstructured.NestedStringMap(vm.Object, "spec", "template", "metadata", "annotations")
+		Expect(annotations).To(HaveKeyWithValue(AnnotationOwnerUID, opts.OwnerUID))
+	})
+
+	It("reports the node a VMI is scheduled to", func() {
+		_, _, err := c.CreateVM(ctx, opts)
+		Expect(err).NotTo(HaveOccurred())
+		addVMI(c, opts.Name)
+		vmi, err := c.GetVMI(ctx, opts.Name)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(unstructured.SetNestedField(vmi.Object, "harvester-node-1", "status", "nodeName")).To(Succeed())
+		Expect(c.dynamic.(*dynamicfake.FakeDynamicClient).Tracker().Update(vmiGVR, vmi, testNamespace)).To(Succeed())
+
+		status, err := c.GetVMStatus(ctx, opts.Name)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(status.NodeName).To(Equal("harvester-node-1"))
+	})
+
+	It("reports every usable address of a multi-homed VM", func() {
+		_, _, err := c.CreateVM(ctx, opts)
+		Expect(err).NotTo(HaveOccurred())
+		addVMI(c, opts.Name)
+		vmi, err := c.GetVMI(ctx, opts.Name)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(unstructured.SetNestedSlice(vmi.Object, []interface{}{
+			map[string]interface{}{
+				"name":        "default",
+				"mac":         "52:54:00:00:00:01",
+				"ipAddress":   "169.254.0.5",
+				"ipAddresses": []interface{}{"169.254.0.5", "10.0.0.5", "fe80::1"},
+			},
+			map[string]interface{}{
+				"name":        "storage",
+				"mac":         "52:54:00:00:00:02",
+				"ipAddress":   "192.168.50.5",
+				"ipAddresses": []interface{}{"192.168.50.5", "10.0.0.5"},
+			},
+		}, "status", "interfaces")).To(Succeed())
+		Expect(c.dynamic.(*dynamicfake.FakeDynamicClient).Tracker().Update(vmiGVR, vmi, testNamespace)).To(Succeed())
+
+		status, err := c.GetVMStatus(ctx, opts.Name)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(status.IPAddress).To(Equal("10.0.0.5"))
+		Expect(status.MACAddress).To(Equal("52:54:00:00:00:01"))
+		Expect(status.IPAddresses).To(Equal([]string{"10.0.0.5", "192.168.50.5"}))
+	})
+
+	It("reports IPv6 addresses only when allowed", func() {
+		_, _, err := c.CreateVM(ctx, opts)
+		Expect(err).NotTo(HaveOccurred())
+		addVMI(c, opts.Name)
+		vmi, err := c.GetVMI(ctx, opts.Name)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(unstructured.SetNestedSlice(vmi.Object, []interface{}{
+			map[string]interface{}{
+				"name":        "default",
+				"mac":         "52:54:00:00:00:01",
+				"ipAddress":   "fe80::1",
+				"ipAddresses": []interface{}{"fe80::1", "::1", "2001:db8::5"},
+			},
+		}, "status", "interfaces")).To(Succeed())
+		Expect(c.dynamic.(*dynamicfake.FakeDynamicClient).Tracker().Update(vmiGVR, vmi, testNamespace)).To(Succeed())
+
+		status, err := c.GetVMStatus(ctx, opts.Name)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(status.IPAddress).To(BeEmpty())
+		Expect(status.IPAddresses).To(BeEmpty())
+
+		c.AllowIPv6 = true
+		status, err = c.GetVMStatus(ctx, opts.Name)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(status.IPAddress).To(Equal("2001:db8::5"))
+		Expect(status.MACAddress).To(Equal("52:54:00:00:00:01"))
+		Expect(status.IPAddresses).To(Equal([]string{"2001:db8::5"}))
+	})
+
+	It("does not fall back to the address of a secondary interface", func() {
+		_, _, err := c.CreateVM(ctx, opts)
+		Expect(err).NotTo(HaveOccurred())
+		addVMI(c, opts.Name)
+		vmi, err := c.GetVMI(ctx, opts.Name)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(unstructured.SetNestedSlice(vmi.Object, []interface{}{
+			map[string]interface{}{
+				"name":        "default",
+				"mac":         "52:54:00:00:00:01",
+				"ipAddress":   "169.254.0.5",
+				"ipAddresses": []interface{}{"169.254.0.5"},
+			},
+			map[string]interface{}{
+				"name":        "storage",
+				"mac":         "52:54:00:00:00:02",
+				"ipAddress":   "192.168.50.5",
+				"ipAddresses": []interface{}{"192.168.50.5"},
+			},
+		}, "status", "interfaces")).To(Succeed())
+		Expect(c.dynamic.(*dynamicfake.FakeDynamicClient).Tracker().Update(vmiGVR, vmi, testNamespace)).To(Succeed())
+
+		status, err := c.GetVMStatus(ctx, opts.Name)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(status.IPAddress).To(BeEmpty())
+		Expect(status.IPAddresses).To(Equal([]string{"192.168.50.5"}))
+	})
+
+	It("asks the guest agent for the hostname", func() {
+		var paths []string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			paths = append(paths, r.URL.Path)
+			if r.URL.Path != "/apis/subresources.kubevirt.io/v1/namespaces/"+testNamespace+"/virtualmachineinstances/worker-0/guestosinfo" {
+				http.NotFound(w, r)
+				return
+			}
+			_, _ = w.Write([]byte(`{"hostname":"worker-0.example.com","os":{"prettyName":"Ubuntu 22.04.4 LTS"}}`))
+		}))
+		DeferCleanup(server.Close)
+		c.clientset = kubernetes.NewForConfigOrDie(&rest.Config{Host: server.URL})
+
+		hostname, err := c.GetGuestHostname(ctx, "worker-0")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(hostname).To(Equal("worker-0.example.com"))
+
+		_, err = c.GetGuestHostname(ctx, "worker-1")
+		Expect(err).To(HaveOccurred())
+		Expect(paths).To(HaveLen(2))
+	})
+
+	It("reports the guest OS from the guest agent info", func() {
+		_, _, err := c.CreateVM(ctx, opts)
+		Expect(err).NotTo(HaveOccurred())
+		addVMI(c, opts.Name)
+
+		status, err := c.GetVMStatus(ctx, opts.Name)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(status.GuestOS).To(BeEmpty())
+
+		vmi, err := c.GetVMI(ctx, opts.Name)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(unstructured.SetNestedStringMap(vmi.Object, map[string]string{
+			"name":    "Ubuntu",
+			"version": "22.04.4 LTS (Jammy Jellyfish)",
+		}, "status", "guestOSInfo")).To(Succeed())
+		Expect(c.dynamic.(*dynamicfake.FakeDynamicClient).Tracker().Update(vmiGVR, vmi, testNamespace)).To(Succeed())
+		status, err = c.GetVMStatus(ctx, opts.Name)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(status.GuestOS).To(Equal("Ubuntu 22.04.4 LTS (Jammy Jellyfish)"))
+
+		Expect(unstructured.SetNestedField(vmi.Object, "Ubuntu 22.04.4 LTS", "status", "guestOSInfo", "prettyName")).To(Succeed())
+		Expect(c.dynamic.(*dynamicfake.FakeDynamicClient).Tracker().Update(vmiGVR, vmi, testNamespace)).To(Succeed())
+		status, err = c.GetVMStatus(ctx, opts.Name)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(status.GuestOS).To(Equal("Ubuntu 22.04.4 LTS"))
+	})
+
+	It("reports failed access credential propagation", func() {
+		_, _, err := c.CreateVM(ctx, opts)
+		Expect(err).NotTo(HaveOccurred())
+		addVMI(c, opts.Name)
+		vmi, err := c.GetVMI(ctx, opts.Name)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(unstructured.SetNestedSlice(vmi.Object, []interface{}{
+			map[string]interface{}{
+				"type":               "AccessCredentialsSynchronized",
+				"status":             "False",
+				"message":            "guest agent rejected key",
+				"lastTransitionTime": "2026-10-14T09:30:00Z",
+			},
+		}, "status", "conditions")).To(Succeed())
+		Expect(c.dynamic.(*dynamicfake.FakeDynamicClient).Tracker().Update(vmiGVR, vmi, testNamespace)).To(Succeed())
+
+		status, err := c.GetVMStatus(ctx, opts.Name)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(status.AccessCredentialsSynced).To(BeFalse())
+		Expect(status.AccessCredentialsMessage).To(Equal("guest agent rejected key"))
+	})
+})
+
+var _ = Describe("SSH key secret hash", func() {
+	It("changes when the keys are rotated", func() {
+		ctx := context.Background()
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "keys", Namespace: testNamespace},
+			Data:       map[string][]byte{"alice": []byte("ssh-ed25519 AAAA alice")},
+		}
+		c := newTestClient(secret)
+		before, err := c.SSHKeySecretHash(ctx, "keys")
+		Expect(err).NotTo(HaveOccurred())
+		again, err := c.SSHKeySecretHash(ctx, "keys")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(again).To(Equal(before))
+
+		secret.Data["alice"] = []byte("ssh-ed25519 BBBB alice")
+		_, err = c.clientset.CoreV1().Secrets(testNamespace).Update(ctx, secret, metav1.UpdateOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		after, err := c.SSHKeySecretHash(ctx, "keys")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(after).NotTo(Equal(before))
+	})
+})
+
+var _ = DescribeTable("Usable VM addresses",
+	func(ip string, allowIPv6, usable bool) {
+		Expect(isUsableIP(ip, allowIPv6)).To(Equal(usable))
+	},
+	Entry("private IPv4", "10.0.0.5", false, true),
+	Entry("public IPv4", "203.0.113.7", false, true),
+	Entry("IPv4 link-local", "169.254.12.3", false, false),
+	Entry("IPv4 loopback", "127.0.0.1", false, false),
+	Entry("IPv4 unspecified", "0.0.0.0", false, false),
+	Entry("IPv4 multicast", "224.0.0.1", false, false),
+	Entry("malformed", "10.0.0", false, false),
+	Entry("empty", "", false, false),
+	Entry("IPv6 by default", "2001:db8::5", false, false),
+	Entry("IPv4-mapped IPv6 by default", "::ffff:10.0.0.5", false, false),
+	Entry("IPv4-mapped IPv6", "::ffff:10.0.0.5", true, true),
+	Entry("public IPv6", "2001:db8::5", true, true),
+	Entry("unique local IPv6", "fd00::5", true, true),
+	Entry("IPv6 link-local", "fe80::1", true, false),
+	Entry("IPv6 loopback", "::1", true, false),
+	Entry("IPv6 unspecified", "::", true, false),
+)
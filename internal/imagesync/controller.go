@@ -68,6 +68,10 @@ type Reconciler struct {
 	client.Client
 	Scheme   *runtime.Scheme
 	Recorder record.EventRecorder
+
+	// APIReader reads credentials Secrets from the API server, since the
+	// manager caches only Secret metadata. Defaults to the client.
+	APIReader client.Reader
 }
 
 // +kubebuilder:rbac:groups=butler.butlerlabs.dev,resources=imagesyncs,verbs=get;list;watch;update;patch
@@ -401,8 +405,12 @@ func (r *Reconciler) getProviderCredentials(ctx context.Context, pc *butlerv1alp
 	if ns == "" {
 		ns = pc.Namespace
 	}
+	reader := r.APIReader
+	if reader == nil {
+		reader = r.Client
+	}
 	secret := &corev1.Secret{}
-	if err := r.Get(ctx, types.NamespacedName{Name: pc.Spec.CredentialsRef.Name, Namespace: ns}, secret); err != nil {
+	if err := reader.Get(ctx, types.NamespacedName{Name: pc.Spec.CredentialsRef.Name, Namespace: ns}, secret); err != nil {
 		return nil, fmt.Errorf("failed to get secret %s/%s: %w", ns, pc.Spec.CredentialsRef.Name, err)
 	}
 	return secret.Data, nil
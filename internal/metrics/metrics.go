@@ -0,0 +1,129 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package metrics defines the Prometheus metrics exported by the Harvester
+// provider. Metrics are registered with the controller-runtime registry so
+// they are served on the manager's /metrics endpoint.
+package metrics
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const namespace = "butler_provider_harvester"
+
+var (
+	// Drained is 1 while the controller is drained and not starting new
+	// create or delete operations, 0 otherwise.
+	Drained = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "drained",
+		Help:      "Whether the controller is drained (1) or active (0).",
+	})
+
+	// CreateDuration observes the time from a MachineRequest first entering
+	// Pending until its VM reports an IP and it moves to Running.
+	CreateDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "vm_create_duration_seconds",
+		Help:      "Time from a MachineRequest entering Pending until it is Running.",
+		Buckets:   prometheus.ExponentialBuckets(15, 2, 9),
+	})
+
+	// DeleteDuration observes the time from a MachineRequest's deletion
+	// timestamp until its VM is deleted and the finalizer removed.
+	DeleteDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "vm_delete_duration_seconds",
+		Help:      "Time from a MachineRequest being deleted until its finalizer is removed.",
+		Buckets:   prometheus.ExponentialBuckets(1, 2, 10),
+	})
+
+	// ProviderErrors counts MachineRequests marked Failed, by failure reason.
+	ProviderErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "provider_errors_total",
+		Help:      "MachineRequests marked Failed, by failure reason.",
+	}, []string{"reason"})
+
+	// MachinePhases is the number of MachineRequests in each phase, by
+	// namespace. Update it through SetMachinePhase and ForgetMachine.
+	MachinePhases = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "machine_requests",
+		Help:      "MachineRequests by phase and namespace.",
+	}, []string{"phase", "namespace"})
+)
+
+// machineKey identifies a MachineRequest in phases.
+type machineKey struct {
+	namespace, name string
+}
+
+var (
+	phasesMu sync.Mutex
+	// phases holds the phase each MachineRequest is counted under in
+	// MachinePhases, so a transition moves it from one series to another.
+	phases = map[machineKey]string{}
+)
+
+// SetMachinePhase counts the MachineRequest under phase, moving it out of
+// the phase it was previously counted under.
+func SetMachinePhase(namespace, name, phase string) {
+	phasesMu.Lock()
+	defer phasesMu.Unlock()
+	key := machineKey{namespace, name}
+	old, ok := phases[key]
+	if ok && old == phase {
+		return
+	}
+	if ok {
+		MachinePhases.WithLabelValues(old, namespace).Dec()
+	}
+	phases[key] = phase
+	MachinePhases.WithLabelValues(phase, namespace).Inc()
+}
+
+// ForgetMachine stops counting a deleted MachineRequest.
+func ForgetMachine(namespace, name string) {
+	phasesMu.Lock()
+	defer phasesMu.Unlock()
+	key := machineKey{namespace, name}
+	if old, ok := phases[key]; ok {
+		MachinePhases.WithLabelValues(old, namespace).Dec()
+		delete(phases, key)
+	}
+}
+
+func init() {
+	for _, c := range []prometheus.Collector{Drained, CreateDuration, DeleteDuration, ProviderErrors, MachinePhases} {
+		register(c)
+	}
+}
+
+// register adds c to the controller-runtime registry. A collector that is
+// already registered, e.g. by an earlier manager in the same test binary, is
+// left in place rather than panicking.
+func register(c prometheus.Collector) {
+	var already prometheus.AlreadyRegisteredError
+	if err := metrics.Registry.Register(c); err != nil && !errors.As(err, &already) {
+		panic(err)
+	}
+}
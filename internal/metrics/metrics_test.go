@@ -0,0 +1,61 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+var _ = Describe("Metrics", func() {
+	// machines returns how many MachineRequests are counted under phase.
+	machines := func(phase string) float64 {
+		return testutil.ToFloat64(MachinePhases.WithLabelValues(phase, "metrics-test"))
+	}
+
+	AfterEach(func() {
+		ForgetMachine("metrics-test", "worker-0")
+		ForgetMachine("metrics-test", "worker-1")
+	})
+
+	It("moves a MachineRequest between phases", func() {
+		SetMachinePhase("metrics-test", "worker-0", "Pending")
+		SetMachinePhase("metrics-test", "worker-1", "Pending")
+		Expect(machines("Pending")).To(Equal(2.0))
+
+		SetMachinePhase("metrics-test", "worker-0", "Running")
+		SetMachinePhase("metrics-test", "worker-0", "Running")
+		Expect(machines("Pending")).To(Equal(1.0))
+		Expect(machines("Running")).To(Equal(1.0))
+	})
+
+	It("stops counting a forgotten MachineRequest", func() {
+		SetMachinePhase("metrics-test", "worker-0", "Failed")
+		ForgetMachine("metrics-test", "worker-0")
+		ForgetMachine("metrics-test", "worker-0")
+		Expect(machines("Failed")).To(BeZero())
+	})
+
+	It("tolerates registering a collector twice", func() {
+		c := prometheus.NewCounter(prometheus.CounterOpts{Namespace: namespace, Name: "metrics_test_total", Help: "Test counter."})
+		Expect(func() { register(c) }).NotTo(Panic())
+		Expect(func() { register(c) }).NotTo(Panic())
+		Expect(func() { register(MachinePhases) }).NotTo(Panic())
+	})
+})
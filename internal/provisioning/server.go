@@ -0,0 +1,325 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package provisioning serves an optional HTTP endpoint that creates a
+// MachineRequest and streams its status until the VM is Running or Failed,
+// for tooling that prefers an imperative, blocking interface over watching
+// the CRD. The VM itself is still created by the MachineRequest controller.
+//
+// Callers authenticate with a Kubernetes bearer token, which is checked with
+// a TokenReview, and must be allowed to create MachineRequests in the target
+// namespace, which is checked with a SubjectAccessReview. Tokens are only
+// accepted over TLS, or over plain HTTP on a loopback address.
+package provisioning
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	butlerv1alpha1 "github.com/butlerdotdev/butler-api/api/v1alpha1"
+)
+
+const (
+	// Path is the endpoint accepting create requests.
+	Path = "/v1/machinerequests"
+
+	// defaultTimeout bounds how long a request streams status.
+	defaultTimeout = 30 * time.Minute
+	// pollInterval is how often the MachineRequest status is re-read.
+	pollInterval = 2 * time.Second
+	// maxBodyBytes bounds the size of a create request body.
+	maxBodyBytes = 1 << 20
+)
+
+// +kubebuilder:rbac:groups=butler.butlerlabs.dev,resources=machinerequests,verbs=create
+// +kubebuilder:rbac:groups=authentication.k8s.io,resources=tokenreviews,verbs=create
+// +kubebuilder:rbac:groups=authorization.k8s.io,resources=subjectaccessreviews,verbs=create
+
+// CreateRequest is the body of a create request.
+type CreateRequest struct {
+	Namespace   string                            `json:"namespace"`
+	Name        string                            `json:"name"`
+	Labels      map[string]string                 `json:"labels,omitempty"`
+	Annotations map[string]string                 `json:"annotations,omitempty"`
+	Spec        butlerv1alpha1.MachineRequestSpec `json:"spec"`
+}
+
+// StatusUpdate is one line of the streamed response, written whenever the
+// MachineRequest status changes.
+type StatusUpdate struct {
+	Phase          butlerv1alpha1.MachinePhase `json:"phase"`
+	ProviderID     string                      `json:"providerID,omitempty"`
+	IPAddress      string                      `json:"ipAddress,omitempty"`
+	FailureReason  string                      `json:"failureReason,omitempty"`
+	FailureMessage string                      `json:"failureMessage,omitempty"`
+	Error          string                      `json:"error,omitempty"`
+}
+
+// Server serves Path. It implements manager.Runnable.
+type Server struct {
+	// Client creates and reads MachineRequests and creates the TokenReviews
+	// and SubjectAccessReviews that authorize callers.
+	Client client.Client
+	// Addr is the listen address, e.g. "127.0.0.1:8090".
+	Addr string
+	// CertFile and KeyFile serve the endpoint over TLS. Without them Addr
+	// must be a loopback address, since callers send bearer tokens.
+	CertFile string
+	KeyFile  string
+}
+
+// Start serves until ctx is cancelled.
+func (s *Server) Start(ctx context.Context) error {
+	log := logf.FromContext(ctx).WithName("provisioning")
+	serveTLS := s.CertFile != "" || s.KeyFile != ""
+	if !serveTLS && !loopbackAddr(s.Addr) {
+		return fmt.Errorf("provisioning endpoint on %s would accept bearer tokens over plain HTTP; "+
+			"bind it to a loopback address or configure a TLS certificate", s.Addr)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(Path, s.handleCreate)
+	srv := &http.Server{
+		Addr:              s.Addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+		TLSConfig:         &tls.Config{MinVersion: tls.VersionTLS12},
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		log.Info("Serving provisioning endpoint", "addr", s.Addr, "path", Path, "tls", serveTLS)
+		if serveTLS {
+			errCh <- srv.ListenAndServeTLS(s.CertFile, s.KeyFile)
+			return
+		}
+		errCh <- srv.ListenAndServe()
+	}()
+
+	select {
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		return srv.Shutdown(shutdownCtx)
+	case err := <-errCh:
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return err
+	}
+}
+
+// loopbackAddr reports whether addr only listens on a loopback interface.
+// An empty host listens on every interface.
+func loopbackAddr(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// NeedLeaderElection returns false so every replica can accept requests.
+func (s *Server) NeedLeaderElection() bool {
+	return false
+}
+
+// handleCreate creates the MachineRequest and streams newline-delimited JSON
+// StatusUpdates until it is Running or Failed, the optional "timeout" query
+// parameter (a Go duration) expires, or the client disconnects. The
+// MachineRequest is kept in all cases.
+func (s *Server) handleCreate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	timeout := defaultTimeout
+	if value := r.URL.Query().Get("timeout"); value != "" {
+		d, err := time.ParseDuration(value)
+		if err != nil || d <= 0 {
+			http.Error(w, "invalid timeout", http.StatusBadRequest)
+			return
+		}
+		timeout = d
+	}
+
+	var req CreateRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes)).Decode(&req); err != nil {
+		status := http.StatusBadRequest
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		http.Error(w, "invalid request body: "+err.Error(), status)
+		return
+	}
+	if req.Namespace == "" || req.Name == "" {
+		http.Error(w, "namespace and name are required", http.StatusBadRequest)
+		return
+	}
+	if status, msg := s.authorize(r, req.Namespace); status != http.StatusOK {
+		http.Error(w, msg, status)
+		return
+	}
+
+	mr := &butlerv1alpha1.MachineRequest{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   req.Namespace,
+			Name:        req.Name,
+			Labels:      req.Labels,
+			Annotations: req.Annotations,
+		},
+		Spec: req.Spec,
+	}
+	if err := s.Client.Create(r.Context(), mr); err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case apierrors.IsAlreadyExists(err):
+			status = http.StatusConflict
+		case apierrors.IsInvalid(err), apierrors.IsBadRequest(err):
+			status = http.StatusBadRequest
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	logf.FromContext(r.Context()).WithName("provisioning").Info("Created MachineRequest", "namespace", mr.Namespace, "name", mr.Name)
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusAccepted)
+	s.streamStatus(r.Context(), w, types.NamespacedName{Namespace: mr.Namespace, Name: mr.Name}, timeout)
+}
+
+// authorize checks that the request carries a valid bearer token whose user
+// may create MachineRequests in namespace. It returns http.StatusOK or the
+// status and message to reply with.
+func (s *Server) authorize(r *http.Request, namespace string) (int, string) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return http.StatusUnauthorized, "bearer token required"
+	}
+
+	review := &authenticationv1.TokenReview{Spec: authenticationv1.TokenReviewSpec{Token: token}}
+	if err := s.Client.Create(r.Context(), review); err != nil {
+		return http.StatusInternalServerError, "reviewing token: " + err.Error()
+	}
+	if !review.Status.Authenticated {
+		return http.StatusUnauthorized, "invalid bearer token"
+	}
+
+	user := review.Status.User
+	extra := make(map[string]authorizationv1.ExtraValue, len(user.Extra))
+	for key, value := range user.Extra {
+		extra[key] = authorizationv1.ExtraValue(value)
+	}
+	access := &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			User:   user.Username,
+			UID:    user.UID,
+			Groups: user.Groups,
+			Extra:  extra,
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace: namespace,
+				Verb:      "create",
+				Group:     butlerv1alpha1.GroupVersion.Group,
+				Resource:  "machinerequests",
+			},
+		},
+	}
+	if err := s.Client.Create(r.Context(), access); err != nil {
+		return http.StatusInternalServerError, "reviewing access: " + err.Error()
+	}
+	if !access.Status.Allowed {
+		return http.StatusForbidden, user.Username + " cannot create MachineRequests in namespace " + namespace
+	}
+	return http.StatusOK, ""
+}
+
+// streamStatus writes a StatusUpdate whenever the MachineRequest status
+// changes until it reaches Running or Failed.
+func (s *Server) streamStatus(ctx context.Context, w http.ResponseWriter, key types.NamespacedName, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	enc := json.NewEncoder(w)
+	flusher, _ := w.(http.Flusher)
+	write := func(update StatusUpdate) {
+		_ = enc.Encode(update)
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	var last StatusUpdate
+	first := true
+	for {
+		mr := &butlerv1alpha1.MachineRequest{}
+		if err := s.Client.Get(ctx, key, mr); err != nil && !apierrors.IsNotFound(err) {
+			// A Get cut short by the deadline is reported as the timeout below.
+			if ctx.Err() == nil {
+				write(StatusUpdate{Phase: last.Phase, Error: err.Error()})
+				return
+			}
+		} else if err == nil {
+			update := StatusUpdate{
+				Phase:          mr.Status.Phase,
+				ProviderID:     mr.Status.ProviderID,
+				IPAddress:      mr.Status.IPAddress,
+				FailureReason:  mr.Status.FailureReason,
+				FailureMessage: mr.Status.FailureMessage,
+			}
+			if first || update != last {
+				write(update)
+				last, first = update, false
+			}
+			if update.Phase == butlerv1alpha1.MachinePhaseRunning || update.Phase == butlerv1alpha1.MachinePhaseFailed {
+				return
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				write(StatusUpdate{Phase: last.Phase, Error: "timed out waiting for the machine"})
+			}
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,238 @@
+/*
+Copyright 2026 The Butler Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioning
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	butlerv1alpha1 "github.com/butlerdotdev/butler-api/api/v1alpha1"
+)
+
+const testToken = "valid-token"
+
+// testServer returns a Server whose TokenReviews accept testToken and whose
+// SubjectAccessReviews are answered by allowed. The MachineRequest read by
+// the status stream is reported in phase.
+func testServer(allowed func(*authorizationv1.SubjectAccessReview) bool, phase butlerv1alpha1.MachinePhase, objects ...client.Object) (*Server, client.Client) {
+	scheme := runtime.NewScheme()
+	Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+	Expect(butlerv1alpha1.AddToScheme(scheme)).To(Succeed())
+
+	c := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(objects...).
+		WithInterceptorFuncs(interceptor.Funcs{
+			Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+				switch review := obj.(type) {
+				case *authenticationv1.TokenReview:
+					if review.Spec.Token == testToken {
+						review.Status.Authenticated = true
+						review.Status.User = authenticationv1.UserInfo{Username: "alice", Groups: []string{"provisioners"}}
+					}
+					return nil
+				case *authorizationv1.SubjectAccessReview:
+					review.Status.Allowed = allowed(review)
+					return nil
+				}
+				return c.Create(ctx, obj, opts...)
+			},
+			Get: func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+				if err := c.Get(ctx, key, obj, opts...); err != nil {
+					return err
+				}
+				if mr, ok := obj.(*butlerv1alpha1.MachineRequest); ok {
+					mr.Status.Phase = phase
+				}
+				return nil
+			},
+		}).
+		Build()
+	return &Server{Client: c}, c
+}
+
+func allowAll(*authorizationv1.SubjectAccessReview) bool { return true }
+
+func createRequest(token, query string) *http.Request {
+	body, err := json.Marshal(CreateRequest{
+		Namespace: "butler-system",
+		Name:      "worker-0",
+		Spec:      butlerv1alpha1.MachineRequestSpec{MachineName: "worker-0"},
+	})
+	Expect(err).NotTo(HaveOccurred())
+	req := httptest.NewRequest(http.MethodPost, Path+query, strings.NewReader(string(body)))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return req
+}
+
+func statusUpdates(rec *httptest.ResponseRecorder) []StatusUpdate {
+	var updates []StatusUpdate
+	scanner := bufio.NewScanner(rec.Body)
+	for scanner.Scan() {
+		var update StatusUpdate
+		Expect(json.Unmarshal(scanner.Bytes(), &update)).To(Succeed())
+		updates = append(updates, update)
+	}
+	return updates
+}
+
+func machineRequestExists(c client.Client) bool {
+	err := c.Get(context.Background(), types.NamespacedName{Namespace: "butler-system", Name: "worker-0"}, &butlerv1alpha1.MachineRequest{})
+	return err == nil
+}
+
+var _ = Describe("Provisioning endpoint", func() {
+	It("rejects requests without a bearer token", func() {
+		s, c := testServer(allowAll, butlerv1alpha1.MachinePhaseRunning)
+		rec := httptest.NewRecorder()
+		s.handleCreate(rec, createRequest("", ""))
+
+		Expect(rec.Code).To(Equal(http.StatusUnauthorized))
+		Expect(machineRequestExists(c)).To(BeFalse())
+	})
+
+	It("rejects tokens the TokenReview does not authenticate", func() {
+		s, c := testServer(allowAll, butlerv1alpha1.MachinePhaseRunning)
+		rec := httptest.NewRecorder()
+		s.handleCreate(rec, createRequest("forged-token", ""))
+
+		Expect(rec.Code).To(Equal(http.StatusUnauthorized))
+		Expect(machineRequestExists(c)).To(BeFalse())
+	})
+
+	It("rejects users who cannot create MachineRequests in the namespace", func() {
+		var review *authorizationv1.SubjectAccessReview
+		s, c := testServer(func(r *authorizationv1.SubjectAccessReview) bool {
+			review = r
+			return false
+		}, butlerv1alpha1.MachinePhaseRunning)
+		rec := httptest.NewRecorder()
+		s.handleCreate(rec, createRequest(testToken, ""))
+
+		Expect(rec.Code).To(Equal(http.StatusForbidden))
+		Expect(machineRequestExists(c)).To(BeFalse())
+		Expect(review.Spec.User).To(Equal("alice"))
+		Expect(review.Spec.Groups).To(ConsistOf("provisioners"))
+		Expect(*review.Spec.ResourceAttributes).To(Equal(authorizationv1.ResourceAttributes{
+			Namespace: "butler-system",
+			Verb:      "create",
+			Group:     "butler.butlerlabs.dev",
+			Resource:  "machinerequests",
+		}))
+	})
+
+	It("creates the MachineRequest and streams until it is Running", func() {
+		s, c := testServer(allowAll, butlerv1alpha1.MachinePhaseRunning)
+		rec := httptest.NewRecorder()
+		s.handleCreate(rec, createRequest(testToken, ""))
+
+		Expect(rec.Code).To(Equal(http.StatusAccepted))
+		Expect(machineRequestExists(c)).To(BeTrue())
+		Expect(statusUpdates(rec)).To(Equal([]StatusUpdate{{Phase: butlerv1alpha1.MachinePhaseRunning}}))
+	})
+
+	It("reports a timeout when the machine is not Running in time", func() {
+		s, _ := testServer(allowAll, butlerv1alpha1.MachinePhasePending)
+		rec := httptest.NewRecorder()
+		s.handleCreate(rec, createRequest(testToken, "?timeout=10ms"))
+
+		Expect(rec.Code).To(Equal(http.StatusAccepted))
+		Expect(statusUpdates(rec)).To(Equal([]StatusUpdate{
+			{Phase: butlerv1alpha1.MachinePhasePending},
+			{Phase: butlerv1alpha1.MachinePhasePending, Error: "timed out waiting for the machine"},
+		}))
+	})
+
+	It("reports a timeout rather than a read cut short by the deadline", func() {
+		s, c := testServer(allowAll, butlerv1alpha1.MachinePhasePending)
+		s.Client = interceptor.NewClient(c.(client.WithWatch), interceptor.Funcs{
+			Get: func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+				<-ctx.Done()
+				return ctx.Err()
+			},
+		})
+		rec := httptest.NewRecorder()
+		s.handleCreate(rec, createRequest(testToken, "?timeout=10ms"))
+
+		Expect(statusUpdates(rec)).To(Equal([]StatusUpdate{{Error: "timed out waiting for the machine"}}))
+	})
+
+	It("returns a conflict for an existing MachineRequest", func() {
+		existing := &butlerv1alpha1.MachineRequest{ObjectMeta: metav1.ObjectMeta{Namespace: "butler-system", Name: "worker-0"}}
+		s, _ := testServer(allowAll, butlerv1alpha1.MachinePhaseRunning, existing)
+		rec := httptest.NewRecorder()
+		s.handleCreate(rec, createRequest(testToken, ""))
+
+		Expect(rec.Code).To(Equal(http.StatusConflict))
+	})
+
+	It("rejects an oversized body", func() {
+		s, c := testServer(allowAll, butlerv1alpha1.MachinePhaseRunning)
+		body := `{"namespace": "butler-system", "name": "worker-0", "annotations": {"x": "` + strings.Repeat("a", maxBodyBytes) + `"}}`
+		req := httptest.NewRequest(http.MethodPost, Path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+testToken)
+		rec := httptest.NewRecorder()
+		s.handleCreate(rec, req)
+
+		Expect(rec.Code).To(Equal(http.StatusRequestEntityTooLarge))
+		Expect(machineRequestExists(c)).To(BeFalse())
+	})
+
+	It("refuses to serve tokens over plain HTTP on a non-loopback address", func() {
+		s, _ := testServer(allowAll, butlerv1alpha1.MachinePhaseRunning)
+		s.Addr = ":8090"
+		Expect(s.Start(context.Background())).To(MatchError(ContainSubstring("plain HTTP")))
+	})
+
+	DescribeTable("recognizes loopback bind addresses",
+		func(addr string, loopback bool) {
+			Expect(loopbackAddr(addr)).To(Equal(loopback))
+		},
+		Entry("IPv4 loopback", "127.0.0.1:8090", true),
+		Entry("IPv6 loopback", "[::1]:8090", true),
+		Entry("localhost", "localhost:8090", true),
+		Entry("every interface", ":8090", false),
+		Entry("a routable address", "10.0.0.5:8090", false),
+		Entry("a missing port", "127.0.0.1", false),
+	)
+
+	It("rejects an invalid timeout", func() {
+		s, _ := testServer(allowAll, butlerv1alpha1.MachinePhaseRunning)
+		rec := httptest.NewRecorder()
+		s.handleCreate(rec, createRequest(testToken, "?timeout=soon"))
+
+		Expect(rec.Code).To(Equal(http.StatusBadRequest))
+	})
+})